@@ -52,6 +52,15 @@ const (
 	GangModeStrict    = "Strict"
 	GangModeNonStrict = "NonStrict"
 
+	// AnnotationGangTimeoutAction defines the Gang Scheduling operation when a waiting pod times out in Permit Stage
+	// Support GangTimeoutActionReject and GangTimeoutActionRequeue, default is GangTimeoutActionReject
+	AnnotationGangTimeoutAction = AnnotationGangPrefix + "/timeout-action"
+	// GangTimeoutActionReject rejects all the waiting pods of the gang group
+	GangTimeoutActionReject = "Reject"
+	// GangTimeoutActionRequeue only requeues the timed-out pod for another wait cycle,
+	// the other waiting pods of the gang group keep waiting
+	GangTimeoutActionRequeue = "Requeue"
+
 	// AnnotationGangMatchPolicy defines the Gang Scheduling operation of taking which status pod into account
 	// Support GangMatchPolicyOnlyWaiting, GangMatchPolicyWaitingAndRunning, GangMatchPolicyOnceSatisfied, default is GangMatchPolicyOnceSatisfied
 	AnnotationGangMatchPolicy        = AnnotationGangPrefix + "/match-policy"
@@ -19,6 +19,7 @@ package extension
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
 
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apiserver/pkg/quota/v1"
@@ -42,6 +43,7 @@ const (
 	LabelQuotaIgnoreDefaultTree          = QuotaKoordinatorPrefix + "/ignore-default-tree"
 	LabelPreemptible                     = QuotaKoordinatorPrefix + "/preemptible"
 	LabelAllowForceUpdate                = QuotaKoordinatorPrefix + "/allow-force-update"
+	LabelQuotaSuspend                    = QuotaKoordinatorPrefix + "/suspend"
 	AnnotationSharedWeight               = QuotaKoordinatorPrefix + "/shared-weight"
 	AnnotationRuntime                    = QuotaKoordinatorPrefix + "/runtime"
 	AnnotationRequest                    = QuotaKoordinatorPrefix + "/request"
@@ -56,8 +58,127 @@ const (
 	AnnotationNonPreemptibleUsed         = QuotaKoordinatorPrefix + "/non-preemptible-used"
 	AnnotationAdmission                  = QuotaKoordinatorPrefix + "/admission"
 	AnnotationMaxStrictCheckResourceKeys = QuotaKoordinatorPrefix + "/max-strict-check-resource-keys"
+	AnnotationSchedulingStrategy         = QuotaKoordinatorPrefix + "/scheduling-strategy"
+	AnnotationAntiAffinityQuotas         = QuotaKoordinatorPrefix + "/anti-affinity-quotas"
+	AnnotationMinScheduleWindows         = QuotaKoordinatorPrefix + "/min-schedule-windows"
+	AnnotationIdleMinReclaim             = QuotaKoordinatorPrefix + "/idle-min-reclaim"
+	AnnotationReserved                   = QuotaKoordinatorPrefix + "/reserved"
+	AnnotationMinPriority                = QuotaKoordinatorPrefix + "/min-priority"
+	AnnotationRuntimeRefreshStrategy     = QuotaKoordinatorPrefix + "/runtime-refresh-strategy"
+	AnnotationRequiredPodLabels          = QuotaKoordinatorPrefix + "/required-pod-labels"
+	AnnotationRuntimeDistribution        = QuotaKoordinatorPrefix + "/runtime-distribution"
+	AnnotationPreemptionPolicy           = QuotaKoordinatorPrefix + "/preemption-policy"
+	AnnotationAllowCascadingDelete       = QuotaKoordinatorPrefix + "/allow-cascading-delete"
+	AnnotationChildrenOrder              = QuotaKoordinatorPrefix + "/children-order"
+	AnnotationSharingPolicy              = QuotaKoordinatorPrefix + "/sharing-policy"
+	AnnotationPinQuota                   = QuotaKoordinatorPrefix + "/pin-quota"
+	AnnotationSuspended                  = QuotaKoordinatorPrefix + "/suspended"
+	AnnotationLenderTrees                = QuotaKoordinatorPrefix + "/lender-trees"
+	AnnotationResourceGroups             = QuotaKoordinatorPrefix + "/resource-groups"
+	AnnotationOvercommitRatio            = QuotaKoordinatorPrefix + "/overcommit-ratio"
+
+	// FinalizerQuotaReparentChildren is added to the parent quotas by the quota reparent controller, it holds the deletion
+	// of the parent quota until its children are moved to its parent.
+	FinalizerQuotaReparentChildren = QuotaKoordinatorPrefix + "/reparent-children"
+
+	// ResourceClaimQuotaResourceSuffix is the suffix of the quota dimension which counts the resource claims of a resource class.
+	ResourceClaimQuotaResourceSuffix = ".resourceclass.resource.k8s.io/claims"
+)
+
+// QuotaSchedulingStrategy indicates how the pods of a quota prefer to be placed on nodes.
+type QuotaSchedulingStrategy string
+
+const (
+	// QuotaSchedulingStrategyBinpack prefers to pack the pods onto the most allocated nodes.
+	QuotaSchedulingStrategyBinpack QuotaSchedulingStrategy = "Binpack"
+	// QuotaSchedulingStrategySpread prefers to spread the pods onto the least allocated nodes.
+	QuotaSchedulingStrategySpread QuotaSchedulingStrategy = "Spread"
+)
+
+// QuotaRuntimeRefreshStrategy indicates when the runtime of the quotas in a quota tree is refreshed.
+type QuotaRuntimeRefreshStrategy string
+
+const (
+	// QuotaRuntimeRefreshStrategyLazy refreshes the runtime of a quota only when it's read, e.g. in PreFilter.
+	QuotaRuntimeRefreshStrategyLazy QuotaRuntimeRefreshStrategy = "Lazy"
+	// QuotaRuntimeRefreshStrategyEager refreshes the runtime of a quota on every pod event of the quota,
+	// which keeps the runtime fresh at the cost of throughput.
+	QuotaRuntimeRefreshStrategyEager QuotaRuntimeRefreshStrategy = "Eager"
+)
+
+// QuotaRuntimeDistribution indicates how the runtime of a parent quota is distributed to its children.
+type QuotaRuntimeDistribution string
+
+const (
+	// QuotaRuntimeDistributionWeighted distributes each resource dimension independently by the shared weights.
+	QuotaRuntimeDistributionWeighted QuotaRuntimeDistribution = "Weighted"
+	// QuotaRuntimeDistributionDRF distributes all resource dimensions together by the dominant resource fairness,
+	// which equalizes the dominant shares of the children.
+	QuotaRuntimeDistributionDRF QuotaRuntimeDistribution = "DRF"
+)
+
+// QuotaResourceGroup groups the resource dimensions, a quota declaring resource groups only enforces the
+// dimensions in its groups and leaves the others to the quotas of the other groups.
+type QuotaResourceGroup string
+
+const (
+	// QuotaResourceGroupCompute includes the resources other than the accelerators, e.g. cpu and memory.
+	QuotaResourceGroupCompute QuotaResourceGroup = "compute"
+	// QuotaResourceGroupAccelerator includes the accelerator resources, e.g. gpu, rdma and fpga.
+	QuotaResourceGroupAccelerator QuotaResourceGroup = "accelerator"
 )
 
+var acceleratorResources = map[corev1.ResourceName]struct{}{
+	ResourceNvidiaGPU:      {},
+	ResourceHygonDCU:       {},
+	ResourceAMDGPU:         {},
+	ResourceRDMA:           {},
+	ResourceFPGA:           {},
+	ResourceGPU:            {},
+	ResourceGPUShared:      {},
+	ResourceGPUCore:        {},
+	ResourceGPUMemory:      {},
+	ResourceGPUMemoryRatio: {},
+}
+
+// GetQuotaResourceGroup returns the resource group which the resource belongs to.
+func GetQuotaResourceGroup(resourceName corev1.ResourceName) QuotaResourceGroup {
+	if _, ok := acceleratorResources[resourceName]; ok {
+		return QuotaResourceGroupAccelerator
+	}
+	return QuotaResourceGroupCompute
+}
+
+// QuotaMinScheduleWindow elevates or lowers the quota's min during a daily time window.
+type QuotaMinScheduleWindow struct {
+	// Start is the start time of the window in the format of "15:04" in UTC, inclusive.
+	Start string `json:"start"`
+	// End is the end time of the window in the format of "15:04", exclusive.
+	// If End is earlier than Start, the window crosses midnight.
+	End string `json:"end"`
+	// Min replaces the quota's spec.min during the window, it's capped by the quota's spec.max.
+	Min corev1.ResourceList `json:"min"`
+}
+
+// QuotaSharingPolicy decides how the parent quota shares a resource dimension to its children.
+type QuotaSharingPolicy string
+
+const (
+	// QuotaSharingPolicyWeighted shares the resource in proportion to the children's shared weight.
+	QuotaSharingPolicyWeighted QuotaSharingPolicy = "weighted"
+	// QuotaSharingPolicyEqual shares the resource equally to the children regardless of their shared weight.
+	QuotaSharingPolicyEqual QuotaSharingPolicy = "equal"
+)
+
+// QuotaIdleMinReclaim reclaims part of the quota's min to its siblings when the quota has been idle,
+// i.e. without any request, for the threshold. The min is restored once the quota has new demand.
+type QuotaIdleMinReclaim struct {
+	// IdleThreshold is how long the quota must be idle before its min is reclaimed, e.g. "30m".
+	IdleThreshold string `json:"idleThreshold"`
+	// Ratio is the fraction of the min to reclaim, in (0, 1].
+	Ratio float64 `json:"ratio"`
+}
+
 func GetParentQuotaName(quota *v1alpha1.ElasticQuota) string {
 	parentName := quota.Labels[LabelQuotaParent]
 	if parentName == "" && quota.Name != RootQuotaName {
@@ -78,6 +199,17 @@ func IsAllowForceUpdate(quota *v1alpha1.ElasticQuota) bool {
 	return quota.Labels[LabelAllowForceUpdate] == "true"
 }
 
+// IsAllowCascadingDelete returns true if the quota can be deleted together with its subtree when no pods are in the subtree.
+func IsAllowCascadingDelete(quota *v1alpha1.ElasticQuota) bool {
+	return quota.Annotations[AnnotationAllowCascadingDelete] == "true"
+}
+
+// IsQuotaSuspended returns whether the quota is suspended, i.e. it admits no new pods while its running pods
+// are still accounted.
+func IsQuotaSuspended(quota *v1alpha1.ElasticQuota) bool {
+	return quota.Labels[LabelQuotaSuspend] == "true"
+}
+
 func IsTreeRootQuota(quota *v1alpha1.ElasticQuota) bool {
 	return quota.Labels[LabelQuotaIsRoot] == "true"
 }
@@ -86,6 +218,22 @@ func IsPodNonPreemptible(pod *corev1.Pod) bool {
 	return pod.Labels[LabelPreemptible] == "false"
 }
 
+// IsPodQuotaPinned returns whether the pod is pinned to its current quota, i.e. the pod is never migrated
+// out of the default quota even if a matching quota is created.
+func IsPodQuotaPinned(pod *corev1.Pod) bool {
+	return pod.Annotations[AnnotationPinQuota] == "true"
+}
+
+// IsPodNonPreemptibleWithDefault is like IsPodNonPreemptible, but the pod without LabelPreemptible
+// is classified by nonPreemptibleByDefault.
+func IsPodNonPreemptibleWithDefault(pod *corev1.Pod, nonPreemptibleByDefault bool) bool {
+	value, ok := pod.Labels[LabelPreemptible]
+	if !ok {
+		return nonPreemptibleByDefault
+	}
+	return value == "false"
+}
+
 func GetQuotaTreeID(quota *v1alpha1.ElasticQuota) string {
 	return quota.Labels[LabelQuotaTreeID]
 }
@@ -111,6 +259,40 @@ func IsForbiddenModify(quota *v1alpha1.ElasticQuota) (bool, error) {
 	return false, nil
 }
 
+// GetResourceClaimQuotaResourceName returns the quota dimension which counts the resource claims of the resource class.
+func GetResourceClaimQuotaResourceName(resourceClassName string) corev1.ResourceName {
+	return corev1.ResourceName(resourceClassName + ResourceClaimQuotaResourceSuffix)
+}
+
+// GetSchedulingStrategy returns the scheduling strategy declared by the quota itself.
+// It returns an empty strategy if the quota doesn't declare one or the declared one is unknown.
+func GetSchedulingStrategy(quota *v1alpha1.ElasticQuota) QuotaSchedulingStrategy {
+	strategy := QuotaSchedulingStrategy(quota.Annotations[AnnotationSchedulingStrategy])
+	switch strategy {
+	case QuotaSchedulingStrategyBinpack, QuotaSchedulingStrategySpread:
+		return strategy
+	}
+	return ""
+}
+
+// GetRuntimeRefreshStrategy returns the runtime refresh strategy declared by the quota.
+// It returns the lazy strategy if the quota doesn't declare one or the declared one is unknown.
+func GetRuntimeRefreshStrategy(quota *v1alpha1.ElasticQuota) QuotaRuntimeRefreshStrategy {
+	if QuotaRuntimeRefreshStrategy(quota.Annotations[AnnotationRuntimeRefreshStrategy]) == QuotaRuntimeRefreshStrategyEager {
+		return QuotaRuntimeRefreshStrategyEager
+	}
+	return QuotaRuntimeRefreshStrategyLazy
+}
+
+// GetRuntimeDistribution returns the runtime distribution declared by the quota.
+// It returns the weighted distribution if the quota doesn't declare one or the declared one is unknown.
+func GetRuntimeDistribution(quota *v1alpha1.ElasticQuota) QuotaRuntimeDistribution {
+	if QuotaRuntimeDistribution(quota.Annotations[AnnotationRuntimeDistribution]) == QuotaRuntimeDistributionDRF {
+		return QuotaRuntimeDistributionDRF
+	}
+	return QuotaRuntimeDistributionWeighted
+}
+
 func GetQuotaName(pod *corev1.Pod) string {
 	return pod.Labels[LabelQuotaName]
 }
@@ -130,6 +312,151 @@ func GetAnnotationQuotaNamespaces(quota *v1alpha1.ElasticQuota) []string {
 	return namespaces
 }
 
+// GetAntiAffinityQuotas returns the quotas whose pods the quota's pods should avoid.
+func GetAntiAffinityQuotas(quota *v1alpha1.ElasticQuota) []string {
+	if quota.Annotations[AnnotationAntiAffinityQuotas] == "" {
+		return nil
+	}
+
+	var quotaNames []string
+	if err := json.Unmarshal([]byte(quota.Annotations[AnnotationAntiAffinityQuotas]), &quotaNames); err != nil {
+		return nil
+	}
+	return quotaNames
+}
+
+// GetChildrenOrder returns the children of the parent quota in the order they receive the shared runtime
+// when they contend for it, e.g. ["prod","staging"]. The children absent from the list share what's left.
+func GetChildrenOrder(quota *v1alpha1.ElasticQuota) []string {
+	if quota.Annotations[AnnotationChildrenOrder] == "" {
+		return nil
+	}
+
+	var quotaNames []string
+	if err := json.Unmarshal([]byte(quota.Annotations[AnnotationChildrenOrder]), &quotaNames); err != nil {
+		return nil
+	}
+	return quotaNames
+}
+
+// GetLenderTrees returns the ids of the quota trees which lend their idle capacity to the tree of the root quota,
+// e.g. ["tree-b","tree-c"]. The trees are borrowed from in the declared order.
+func GetLenderTrees(quota *v1alpha1.ElasticQuota) []string {
+	if quota.Annotations[AnnotationLenderTrees] == "" {
+		return nil
+	}
+
+	var treeIDs []string
+	if err := json.Unmarshal([]byte(quota.Annotations[AnnotationLenderTrees]), &treeIDs); err != nil {
+		return nil
+	}
+	return treeIDs
+}
+
+// GetResourceGroups returns the resource groups whose dimensions the quota enforces, e.g. ["accelerator"].
+// The unknown groups are ignored, and the quota enforces all the dimensions if no group is declared.
+func GetResourceGroups(quota *v1alpha1.ElasticQuota) []QuotaResourceGroup {
+	if quota.Annotations[AnnotationResourceGroups] == "" {
+		return nil
+	}
+
+	var groups []QuotaResourceGroup
+	if err := json.Unmarshal([]byte(quota.Annotations[AnnotationResourceGroups]), &groups); err != nil {
+		return nil
+	}
+	var resourceGroups []QuotaResourceGroup
+	for _, group := range []QuotaResourceGroup{QuotaResourceGroupCompute, QuotaResourceGroupAccelerator} {
+		for _, g := range groups {
+			if g == group {
+				resourceGroups = append(resourceGroups, group)
+				break
+			}
+		}
+	}
+	return resourceGroups
+}
+
+// GetRequiredPodLabels returns the labels which the pods of the quota must carry,
+// an empty value only requires the label key to be present.
+func GetRequiredPodLabels(quota *v1alpha1.ElasticQuota) map[string]string {
+	if quota.Annotations[AnnotationRequiredPodLabels] == "" {
+		return nil
+	}
+
+	var requiredLabels map[string]string
+	if err := json.Unmarshal([]byte(quota.Annotations[AnnotationRequiredPodLabels]), &requiredLabels); err != nil {
+		return nil
+	}
+	return requiredLabels
+}
+
+// GetMinScheduleWindows returns the scheduled min windows of the quota.
+func GetMinScheduleWindows(quota *v1alpha1.ElasticQuota) ([]QuotaMinScheduleWindow, error) {
+	if quota.Annotations[AnnotationMinScheduleWindows] == "" {
+		return nil, nil
+	}
+
+	var windows []QuotaMinScheduleWindow
+	if err := json.Unmarshal([]byte(quota.Annotations[AnnotationMinScheduleWindows]), &windows); err != nil {
+		return nil, err
+	}
+	return windows, nil
+}
+
+// GetSharingPolicies returns the sharing policy of each resource dimension of the parent quota,
+// the dimensions not set are shared by weight.
+func GetSharingPolicies(quota *v1alpha1.ElasticQuota) (map[corev1.ResourceName]QuotaSharingPolicy, error) {
+	if quota.Annotations[AnnotationSharingPolicy] == "" {
+		return nil, nil
+	}
+
+	var policies map[corev1.ResourceName]QuotaSharingPolicy
+	if err := json.Unmarshal([]byte(quota.Annotations[AnnotationSharingPolicy]), &policies); err != nil {
+		return nil, err
+	}
+	for resourceName, policy := range policies {
+		if policy != QuotaSharingPolicyWeighted && policy != QuotaSharingPolicyEqual {
+			return nil, fmt.Errorf("unknown sharing policy %q of resource %v", policy, resourceName)
+		}
+	}
+	return policies, nil
+}
+
+// GetOvercommitRatios returns the overcommit ratio of each resource dimension of the quota, which admits up to
+// its max multiplied by the ratio, e.g. {"cpu": 1.5}. The dimensions not set aren't overcommitted.
+func GetOvercommitRatios(quota *v1alpha1.ElasticQuota) (map[corev1.ResourceName]float64, error) {
+	if quota.Annotations[AnnotationOvercommitRatio] == "" {
+		return nil, nil
+	}
+
+	var ratios map[corev1.ResourceName]float64
+	if err := json.Unmarshal([]byte(quota.Annotations[AnnotationOvercommitRatio]), &ratios); err != nil {
+		return nil, err
+	}
+	for resourceName, ratio := range ratios {
+		if ratio < 1 {
+			return nil, fmt.Errorf("overcommit ratio %v of resource %v is less than 1", ratio, resourceName)
+		}
+	}
+	return ratios, nil
+}
+
+// GetIdleMinReclaim returns the idle min reclamation policy of the quota, nil if it's not set.
+func GetIdleMinReclaim(quota *v1alpha1.ElasticQuota) (*QuotaIdleMinReclaim, error) {
+	if quota.Annotations[AnnotationIdleMinReclaim] == "" {
+		return nil, nil
+	}
+
+	reclaim := &QuotaIdleMinReclaim{}
+	if err := json.Unmarshal([]byte(quota.Annotations[AnnotationIdleMinReclaim]), reclaim); err != nil {
+		return nil, err
+	}
+	if reclaim.Ratio <= 0 || reclaim.Ratio > 1 {
+		return nil, fmt.Errorf("invalid ratio %v of idle min reclaim, should be in (0, 1]", reclaim.Ratio)
+	}
+	return reclaim, nil
+}
+
 func GetNonPreemptibleRequest(quota *v1alpha1.ElasticQuota) (corev1.ResourceList, error) {
 	nonPreemptibleRequest := corev1.ResourceList{}
 	if quota.Annotations[AnnotationNonPreemptibleRequest] != "" {
@@ -170,6 +497,16 @@ func GetAllocated(quota *v1alpha1.ElasticQuota) (corev1.ResourceList, error) {
 	return allocated, nil
 }
 
+func GetTotalResource(quota *v1alpha1.ElasticQuota) (corev1.ResourceList, error) {
+	total := corev1.ResourceList{}
+	if quota.Annotations[AnnotationTotalResource] != "" {
+		if err := json.Unmarshal([]byte(quota.Annotations[AnnotationTotalResource]), &total); err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
 func GetRuntime(quota *v1alpha1.ElasticQuota) (corev1.ResourceList, error) {
 	runtime := corev1.ResourceList{}
 	if quota.Annotations[AnnotationRuntime] != "" {
@@ -220,6 +557,41 @@ func GetAdmission(quota *v1alpha1.ElasticQuota) (corev1.ResourceList, error) {
 	return admission, nil
 }
 
+// GetReserved returns the resources the quota reserves for its own system overhead,
+// which are not available to the workloads of the quota.
+func GetReserved(quota *v1alpha1.ElasticQuota) (corev1.ResourceList, error) {
+	reserved := corev1.ResourceList{}
+	if quota.Annotations[AnnotationReserved] != "" {
+		if err := json.Unmarshal([]byte(quota.Annotations[AnnotationReserved]), &reserved); err != nil {
+			return reserved, err
+		}
+	}
+	return reserved, nil
+}
+
+// GetPreemptionPolicy returns the preemption policy of the pods of the quota, the pods of the quota with the Never
+// policy never preempt others even if they have high priority. Defaults to PreemptLowerPriority.
+func GetPreemptionPolicy(quota *v1alpha1.ElasticQuota) corev1.PreemptionPolicy {
+	if corev1.PreemptionPolicy(quota.Annotations[AnnotationPreemptionPolicy]) == corev1.PreemptNever {
+		return corev1.PreemptNever
+	}
+	return corev1.PreemptLowerPriority
+}
+
+// GetMinPriority returns the priority to preserve the min of the quota when the total resource can't
+// satisfy the mins of all quotas. The quota with higher priority keeps its min first. Defaults to 0.
+func GetMinPriority(quota *v1alpha1.ElasticQuota) int32 {
+	value := quota.Annotations[AnnotationMinPriority]
+	if value == "" {
+		return 0
+	}
+	priority, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return 0
+	}
+	return int32(priority)
+}
+
 func GetMaxStrictCheckResourceKeys(quota *v1alpha1.ElasticQuota) ([]corev1.ResourceName, error) {
 	if quota.Annotations[AnnotationMaxStrictCheckResourceKeys] == "" {
 		return nil, nil
@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -38,6 +39,15 @@ type ElasticQuotaProfileSpec struct {
 }
 
 type ElasticQuotaProfileStatus struct {
+	// MatchedNodeCount is the number of the nodes selected by the NodeSelector.
+	MatchedNodeCount int32 `json:"matchedNodeCount,omitempty"`
+	// TotalResource is the total allocatable of the selected nodes multiplied by the ResourceRatio.
+	TotalResource corev1.ResourceList `json:"totalResource,omitempty"`
+	// LastUpdateTime is the last time the MatchedNodeCount or TotalResource changed.
+	LastUpdateTime metav1.Time `json:"lastUpdateTime,omitempty"`
+	// Conditions is the list of conditions representing the status of the profile,
+	// e.g. whether the ResourceRatio can be parsed.
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 //  ElasticQuotaProfile is the Schema for the ElasticQuotaProfile API
@@ -46,6 +56,7 @@ type ElasticQuotaProfileStatus struct {
 // +genclient
 // +kubebuilder:resource:shortName=eqp
 // +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
 
 type ElasticQuotaProfile struct {
 	metav1.TypeMeta   `json:",inline"`
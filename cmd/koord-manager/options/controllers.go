@@ -22,6 +22,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
 	"github.com/koordinator-sh/koordinator/pkg/quota-controller/profile"
+	"github.com/koordinator-sh/koordinator/pkg/quota-controller/reparent"
 	"github.com/koordinator-sh/koordinator/pkg/slo-controller/nodemetric"
 	"github.com/koordinator-sh/koordinator/pkg/slo-controller/noderesource"
 	"github.com/koordinator-sh/koordinator/pkg/slo-controller/nodeslo"
@@ -29,6 +30,7 @@ import (
 
 var controllerInitFlags = map[string]func(*flag.FlagSet){
 	noderesource.Name: noderesource.InitFlags,
+	reparent.Name:     reparent.InitFlags,
 }
 
 var controllerAddFuncs = map[string]func(manager.Manager) error{
@@ -36,4 +38,5 @@ var controllerAddFuncs = map[string]func(manager.Manager) error{
 	noderesource.Name: noderesource.Add,
 	nodeslo.Name:      nodeslo.Add,
 	profile.Name:      profile.Add,
+	reparent.Name:     reparent.Add,
 }
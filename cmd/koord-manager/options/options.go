@@ -31,11 +31,13 @@ type Options struct {
 	ControllerAddFuncs  map[string]func(manager.Manager) error
 	Controllers         []string
 	ControllerInitFlags map[string]func(*flag.FlagSet)
+	WebhookInitFlags    []func(*flag.FlagSet)
 }
 
 func NewOptions() *Options {
 	return &Options{
 		ControllerInitFlags: controllerInitFlags,
+		WebhookInitFlags:    webhookInitFlags,
 		ControllerAddFuncs:  controllerAddFuncs,
 		Controllers:         sets.StringKeySet(controllerAddFuncs).List(),
 	}
@@ -50,6 +52,9 @@ func (o *Options) InitFlags(fs *flag.FlagSet) {
 	for _, initFlagsFn := range o.ControllerInitFlags {
 		initFlagsFn(fs)
 	}
+	for _, initFlagsFn := range o.WebhookInitFlags {
+		initFlagsFn(fs)
+	}
 }
 
 func (o *Options) ApplyTo(m manager.Manager) error {
@@ -17,6 +17,7 @@ limitations under the License.
 package options
 
 import (
+	"flag"
 	"fmt"
 	"sort"
 	"testing"
@@ -55,7 +56,7 @@ func TestOptions(t *testing.T) {
 			"",
 			"--controllers=noderesource,nodemetric",
 		}
-		opt.InitFlags(nil)
+		opt.InitFlags(flag.NewFlagSet(args[0], flag.ContinueOnError))
 		pflag.NewFlagSet(args[0], pflag.ExitOnError)
 		err := pflag.CommandLine.Parse(args[1:])
 		assert.NoError(t, err)
@@ -63,6 +63,10 @@ const (
 	// ElasticQuotaImmediateIgnoreTerminatingPod ignore the terminating pod immediately.
 	ElasticQuotaImmediateIgnoreTerminatingPod featuregate.Feature = "ElasticQuotaImmediateIgnoreTerminatingPod"
 
+	// ElasticQuotaIgnoreSchedulingGatedPod ignores the request of the pod gated by scheduling gates in the quota request,
+	// the gated pod isn't schedulable yet. The gated pod is counted by default since it represents the committed demand.
+	ElasticQuotaIgnoreSchedulingGatedPod featuregate.Feature = "ElasticQuotaIgnoreSchedulingGatedPod"
+
 	// ElasticQuotaGuaranteeUsage enable guarantee the quota usage
 	// In some specific scenarios, resources that have been allocated to users are considered
 	// to belong to the users and will not be preempted back.
@@ -79,6 +83,14 @@ const (
 	// SupportParentQuotaSubmitPod enables parent Quota submit pod
 	SupportParentQuotaSubmitPod featuregate.Feature = "SupportParentQuotaSubmitPod"
 
+	// ElasticQuotaResourceClaims counts the pod's resource claims (DRA) toward the quota, each claim is
+	// accounted as one unit of the "<resourceClassName>.resourceclass.resource.k8s.io/claims" dimension.
+	ElasticQuotaResourceClaims featuregate.Feature = "ElasticQuotaResourceClaims"
+
+	// ElasticQuotaFullRuntimeRefresh recomputes the runtime of all the sibling quotas whenever a stale runtime
+	// is read and pushes the parent runtime down even if it's unchanged, instead of refreshing incrementally.
+	ElasticQuotaFullRuntimeRefresh featuregate.Feature = "ElasticQuotaFullRuntimeRefresh"
+
 	// EnableQuotaAdmission enables quota admission.
 	EnableQuotaAdmission featuregate.Feature = "EnableQuotaAdmission"
 
@@ -0,0 +1,111 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package profile
+
+import (
+	"context"
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+	nodeutil "k8s.io/kubernetes/pkg/util/node"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/koordinator-sh/koordinator/apis/quota/v1alpha1"
+	utilclient "github.com/koordinator-sh/koordinator/pkg/util/client"
+)
+
+var _ handler.EventHandler = &EnqueueRequestForNode{}
+
+// EnqueueRequestForNode enqueues the profiles selecting a node when the node joins, leaves or changes,
+// so that the quotas generated from the profiles keep in sync with the cluster capacity.
+type EnqueueRequestForNode struct {
+	client.Client
+}
+
+func (n *EnqueueRequestForNode) Create(ctx context.Context, e event.CreateEvent, q workqueue.RateLimitingInterface) {
+	if node, ok := e.Object.(*corev1.Node); ok {
+		n.enqueueProfiles(q, node.Labels)
+	}
+}
+
+func (n *EnqueueRequestForNode) Update(ctx context.Context, e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	newNode, ok := e.ObjectNew.(*corev1.Node)
+	if !ok {
+		return
+	}
+	oldNode, ok := e.ObjectOld.(*corev1.Node)
+	if !ok {
+		return
+	}
+	if !isNodeUpdated(newNode, oldNode) {
+		return
+	}
+	n.enqueueProfiles(q, oldNode.Labels, newNode.Labels)
+}
+
+func (n *EnqueueRequestForNode) Delete(ctx context.Context, e event.DeleteEvent, q workqueue.RateLimitingInterface) {
+	if node, ok := e.Object.(*corev1.Node); ok {
+		n.enqueueProfiles(q, node.Labels)
+	}
+}
+
+func (n *EnqueueRequestForNode) Generic(ctx context.Context, e event.GenericEvent, q workqueue.RateLimitingInterface) {
+}
+
+// enqueueProfiles enqueues the profiles whose node selector matches any of the given node labels.
+func (n *EnqueueRequestForNode) enqueueProfiles(q workqueue.RateLimitingInterface, nodeLabels ...map[string]string) {
+	profileList := &v1alpha1.ElasticQuotaProfileList{}
+	if err := n.Client.List(context.TODO(), profileList, utilclient.DisableDeepCopy); err != nil {
+		klog.Errorf("failed to list quota profiles, err: %v", err)
+		return
+	}
+	for i := range profileList.Items {
+		profile := &profileList.Items[i]
+		selector, err := metav1.LabelSelectorAsSelector(profile.Spec.NodeSelector)
+		if err != nil {
+			continue
+		}
+		for _, l := range nodeLabels {
+			if selector.Matches(labels.Set(l)) {
+				q.Add(reconcile.Request{
+					NamespacedName: types.NamespacedName{
+						Namespace: profile.Namespace,
+						Name:      profile.Name,
+					},
+				})
+				break
+			}
+		}
+	}
+}
+
+// isNodeUpdated returns whether the node changes in a way that affects the profile's quota, i.e. the
+// allocatable, the labels or the schedulable state.
+func isNodeUpdated(newNode *corev1.Node, oldNode *corev1.Node) bool {
+	return !reflect.DeepEqual(oldNode.Status.Allocatable, newNode.Status.Allocatable) ||
+		!reflect.DeepEqual(oldNode.Labels, newNode.Labels) ||
+		oldNode.Spec.Unschedulable != newNode.Spec.Unschedulable ||
+		nodeutil.IsNodeReady(oldNode) != nodeutil.IsNodeReady(newNode)
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package profile
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	quotav1alpha1 "github.com/koordinator-sh/koordinator/apis/quota/v1alpha1"
+)
+
+func TestEnqueueRequestForNode(t *testing.T) {
+	scheme := runtime.NewScheme()
+	clientgoscheme.AddToScheme(scheme)
+	quotav1alpha1.AddToScheme(scheme)
+
+	zoneA := map[string]string{"topology.kubernetes.io/zone": "cn-hangzhou-a"}
+	zoneB := map[string]string{"topology.kubernetes.io/zone": "cn-hangzhou-b"}
+	newProfile := func(name string, selector map[string]string) *quotav1alpha1.ElasticQuotaProfile {
+		return &quotav1alpha1.ElasticQuotaProfile{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+			},
+			Spec: quotav1alpha1.ElasticQuotaProfileSpec{
+				QuotaName:    name + "-root",
+				NodeSelector: &metav1.LabelSelector{MatchLabels: selector},
+			},
+		}
+	}
+	h := &EnqueueRequestForNode{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			newProfile("profile-a", zoneA),
+			newProfile("profile-b", zoneB),
+		).Build(),
+	}
+	drain := func(q workqueue.RateLimitingInterface) []string {
+		var names []string
+		for q.Len() > 0 {
+			item, _ := q.Get()
+			names = append(names, item.(reconcile.Request).Name)
+			q.Done(item)
+		}
+		return names
+	}
+
+	node := defaultCreateNode("node1", zoneA, createResourceList(10, 1000))
+
+	// a node joins
+	q := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	h.Create(context.TODO(), event.CreateEvent{Object: node}, q)
+	assert.Equal(t, []string{"profile-a"}, drain(q))
+
+	// nothing relevant changes
+	h.Update(context.TODO(), event.UpdateEvent{ObjectOld: node, ObjectNew: node.DeepCopy()}, q)
+	assert.Empty(t, drain(q))
+
+	// the node becomes unschedulable
+	unschedulable := node.DeepCopy()
+	unschedulable.Spec.Unschedulable = true
+	h.Update(context.TODO(), event.UpdateEvent{ObjectOld: node, ObjectNew: unschedulable}, q)
+	assert.Equal(t, []string{"profile-a"}, drain(q))
+
+	// the node moves to another zone, both the old and new profiles are enqueued
+	moved := node.DeepCopy()
+	moved.Labels = zoneB
+	h.Update(context.TODO(), event.UpdateEvent{ObjectOld: node, ObjectNew: moved}, q)
+	assert.ElementsMatch(t, []string{"profile-a", "profile-b"}, drain(q))
+
+	// the node leaves
+	h.Delete(context.TODO(), event.DeleteEvent{Object: moved}, q)
+	assert.Equal(t, []string{"profile-b"}, drain(q))
+
+	// not a node
+	h.Create(context.TODO(), event.CreateEvent{Object: &corev1.Pod{}}, q)
+	assert.Empty(t, drain(q))
+}
@@ -23,11 +23,14 @@ import (
 	"hash/fnv"
 	"math"
 	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -55,6 +58,14 @@ const (
 	ReasonUpdateQuotaFailed = "UpdateQuotaFailed"
 )
 
+const (
+	// ConditionResourceRatioValid indicates whether the ResourceRatio of the profile can be parsed.
+	ConditionResourceRatioValid = "ResourceRatioValid"
+
+	ReasonResourceRatioParsed  = "ResourceRatioParsed"
+	ReasonInvalidResourceRatio = "InvalidResourceRatio"
+)
+
 var resourceDecorators = []func(profile *v1alpha1.ElasticQuotaProfile, total corev1.ResourceList){
 	DecorateResourceByResourceRatio,
 }
@@ -150,6 +161,14 @@ func (r *QuotaProfileReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	decorateTotalResource(profile, totalResource)
 	decorateTotalResource(profile, unschedulableResource)
 
+	_, ratioErr := ParseResourceRatio(profile)
+	if ratioErr != nil {
+		klog.Warningf("failed to parse resource ratio of profile %v, error: %v", req.NamespacedName, ratioErr)
+	}
+	if err := r.updateProfileStatus(profile, int32(len(nodeList.Items)), totalResource, ratioErr); err != nil {
+		klog.Errorf("failed to update status of profile %v, error: %v", req.NamespacedName, err)
+	}
+
 	resourceKeys := []string{"cpu", "memory"}
 	raw, ok := profile.Annotations[extension.AnnotationResourceKeys]
 	if ok {
@@ -169,9 +188,16 @@ func (r *QuotaProfileReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		}
 
 		min[resourceName] = quantity
-		max[resourceName] = *resource.NewQuantity(math.MaxInt64/2000, resource.DecimalSI)
+		if profile.Spec.ResourceRatio != nil && ratioErr == nil {
+			// the max tracks the ratio of the selected nodes' capacity
+			max[resourceName] = quantity
+		} else {
+			max[resourceName] = *resource.NewQuantity(math.MaxInt64/2000, resource.DecimalSI)
+		}
 	}
 
+	r.detectCapacityDrift(profile, quotaTreeID, oldQuota, quotav1.Mask(totalResource, quotav1.ResourceNames(min)))
+
 	// update min and max
 	quota.Spec.Min = min
 	quota.Spec.Max = max
@@ -232,6 +258,83 @@ func (r *QuotaProfileReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
 }
 
+// updateProfileStatus records the nodes matched by the profile and the capacity they resolve to, and surfaces
+// whether the profile's ResourceRatio can be parsed in the profile status.
+func (r *QuotaProfileReconciler) updateProfileStatus(profile *v1alpha1.ElasticQuotaProfile, matchedNodeCount int32,
+	totalResource corev1.ResourceList, ratioErr error) error {
+	condition := metav1.Condition{
+		Type:               ConditionResourceRatioValid,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonResourceRatioParsed,
+		ObservedGeneration: profile.Generation,
+	}
+	if ratioErr != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = ReasonInvalidResourceRatio
+		condition.Message = ratioErr.Error()
+	}
+
+	oldStatus := profile.Status.DeepCopy()
+	if profile.Status.MatchedNodeCount != matchedNodeCount || !quotav1.Equals(profile.Status.TotalResource, totalResource) {
+		profile.Status.MatchedNodeCount = matchedNodeCount
+		profile.Status.TotalResource = totalResource.DeepCopy()
+		profile.Status.LastUpdateTime = metav1.Now()
+	}
+	meta.SetStatusCondition(&profile.Status.Conditions, condition)
+	if reflect.DeepEqual(oldStatus, &profile.Status) {
+		return nil
+	}
+	return r.Client.Status().Update(context.TODO(), profile)
+}
+
+// detectCapacityDrift logs when the capacity observed from the nodes drifts from the capacity recorded in the
+// profile's quota, and when the configured max of the quotas in the profile's tree exceeds the observed capacity,
+// which helps to catch stale sizing. It returns the quotas whose max exceeds the observed capacity.
+func (r *QuotaProfileReconciler) detectCapacityDrift(profile *v1alpha1.ElasticQuotaProfile, quotaTreeID string,
+	rootQuota *schedv1alpha1.ElasticQuota, observed corev1.ResourceList) []string {
+	if raw := rootQuota.Annotations[extension.AnnotationTotalResource]; raw != "" {
+		recorded := corev1.ResourceList{}
+		if err := json.Unmarshal([]byte(raw), &recorded); err == nil {
+			recorded = quotav1.Mask(recorded, quotav1.ResourceNames(observed))
+			if !quotav1.Equals(recorded, observed) {
+				klog.Infof("observed capacity of profile %v/%v drifts from %v to %v",
+					profile.Namespace, profile.Name, printResourceList(recorded), printResourceList(observed))
+			}
+		}
+	}
+
+	quotaList := &schedv1alpha1.ElasticQuotaList{}
+	if err := r.Client.List(context.TODO(), quotaList, client.MatchingLabels{extension.LabelQuotaTreeID: quotaTreeID},
+		utilclient.DisableDeepCopy); err != nil {
+		klog.Errorf("failed to list quotas of profile %v/%v, err: %v", profile.Namespace, profile.Name, err)
+		return nil
+	}
+	var drifted []string
+	for i := range quotaList.Items {
+		quota := &quotaList.Items[i]
+		if quota.Name == profile.Spec.QuotaName {
+			continue
+		}
+		if ok, exceeded := quotav1.LessThanOrEqual(quotav1.Mask(quota.Spec.Max, quotav1.ResourceNames(observed)), observed); !ok {
+			klog.Warningf("configured max of quota %v/%v exceeds the observed capacity of profile %v/%v on %v, max: %v, observed: %v",
+				quota.Namespace, quota.Name, profile.Namespace, profile.Name, exceeded,
+				printResourceList(quota.Spec.Max), printResourceList(observed))
+			drifted = append(drifted, quota.Name)
+		}
+	}
+	sort.Strings(drifted)
+	return drifted
+}
+
+func printResourceList(rl corev1.ResourceList) string {
+	res := make([]string, 0, len(rl))
+	for k, v := range rl {
+		res = append(res, fmt.Sprintf("%v:%v", k, v.String()))
+	}
+	sort.Strings(res)
+	return strings.Join(res, ",")
+}
+
 func Add(mgr ctrl.Manager) error {
 	reconciler := QuotaProfileReconciler{
 		Client:   mgr.GetClient(),
@@ -244,6 +347,7 @@ func Add(mgr ctrl.Manager) error {
 func (r *QuotaProfileReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&v1alpha1.ElasticQuotaProfile{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&corev1.Node{}, &EnqueueRequestForNode{Client: r.Client}).
 		Named(Name).
 		Complete(r)
 }
@@ -270,16 +374,28 @@ func hash(s string) string {
 	return strconv.FormatUint(h.Sum64(), 10)
 }
 
+// ParseResourceRatio parses the ResourceRatio of the profile, which must be in (0, 1].
+// It returns 1.0 if the ratio is not set or invalid.
+func ParseResourceRatio(profile *v1alpha1.ElasticQuotaProfile) (float64, error) {
+	if profile.Spec.ResourceRatio == nil {
+		return 1.0, nil
+	}
+	val, err := strconv.ParseFloat(*profile.Spec.ResourceRatio, 64)
+	if err != nil {
+		return 1.0, fmt.Errorf("invalid resource ratio %q: %v", *profile.Spec.ResourceRatio, err)
+	}
+	if val <= 0 || val > 1.0 {
+		return 1.0, fmt.Errorf("invalid resource ratio %q: must be in (0, 1]", *profile.Spec.ResourceRatio)
+	}
+	return val, nil
+}
+
 func DecorateResourceByResourceRatio(profile *v1alpha1.ElasticQuotaProfile, total corev1.ResourceList) {
 	if profile.Spec.ResourceRatio == nil {
 		return
 	}
 
-	ratio := 1.0
-	val, err := strconv.ParseFloat(*profile.Spec.ResourceRatio, 64)
-	if err == nil && val > 0 && val <= 1.0 {
-		ratio = val
-	}
+	ratio, _ := ParseResourceRatio(profile)
 
 	for resourceName, quantity := range total {
 		total[resourceName] = MultiplyQuantity(quantity, resourceName, ratio)
@@ -20,16 +20,19 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	quotav1 "k8s.io/apiserver/pkg/quota/v1"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
@@ -314,7 +317,7 @@ func TestQuotaProfileReconciler_Reconciler_CreateQuota(t *testing.T) {
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			r := &QuotaProfileReconciler{
-				Client: fake.NewClientBuilder().WithScheme(scheme).Build(),
+				Client: fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&quotav1alpha1.ElasticQuotaProfile{}).Build(),
 				Scheme: scheme,
 			}
 			// create node
@@ -349,6 +352,121 @@ func TestQuotaProfileReconciler_Reconciler_CreateQuota(t *testing.T) {
 			assert.True(t, quotav1.Equals(tc.expectTotalResource, total))
 			assert.True(t, quotav1.Equals(tc.expectUnschedulableResource, unschedulable))
 			assert.Equal(t, tc.expectQuotaLabels, quota.Labels)
+
+			profile := &quotav1alpha1.ElasticQuotaProfile{}
+			err = r.Client.Get(context.TODO(), profileReq.NamespacedName, profile)
+			assert.NoError(t, err)
+			assert.True(t, quotav1.Equals(tc.expectTotalResource, profile.Status.TotalResource))
+		})
+	}
+}
+
+func TestQuotaProfileReconciler_Reconciler_ResourceRatioMax(t *testing.T) {
+	scheme := runtime.NewScheme()
+	clientgoscheme.AddToScheme(scheme)
+	quotav1alpha1.AddToScheme(scheme)
+	schedv1alpha1.AddToScheme(scheme)
+
+	zoneA := map[string]string{"topology.kubernetes.io/zone": "cn-hangzhou-a"}
+	zoneB := map[string]string{"topology.kubernetes.io/zone": "cn-hangzhou-b"}
+	newProfile := func(ratio string) *quotav1alpha1.ElasticQuotaProfile {
+		return &quotav1alpha1.ElasticQuotaProfile{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "profile1",
+			},
+			Spec: quotav1alpha1.ElasticQuotaProfileSpec{
+				QuotaName:     "profile1-root",
+				QuotaLabels:   map[string]string{"a": "a"},
+				ResourceRatio: &ratio,
+				NodeSelector:  &metav1.LabelSelector{MatchLabels: zoneA},
+			},
+		}
+	}
+	reconcile := func(t *testing.T, r *QuotaProfileReconciler) (*schedv1alpha1.ElasticQuota, *quotav1alpha1.ElasticQuotaProfile) {
+		_, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{Name: "profile1"}})
+		assert.NoError(t, err)
+		quota := &schedv1alpha1.ElasticQuota{}
+		assert.NoError(t, r.Client.Get(context.TODO(), types.NamespacedName{Name: "profile1-root"}, quota))
+		profile := &quotav1alpha1.ElasticQuotaProfile{}
+		assert.NoError(t, r.Client.Get(context.TODO(), types.NamespacedName{Name: "profile1"}, profile))
+		return quota, profile
+	}
+
+	t.Run("max follows the nodes", func(t *testing.T) {
+		r := &QuotaProfileReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&quotav1alpha1.ElasticQuotaProfile{}).WithObjects(
+				newProfile("0.5"),
+				defaultCreateNode("node1", zoneA, createResourceList(10, 1000)),
+				defaultCreateNode("node2", zoneB, createResourceList(10, 1000)),
+			).Build(),
+			Scheme: scheme,
+		}
+		quota, profile := reconcile(t, r)
+		assert.True(t, quotav1.Equals(createResourceList(5, 500), quota.Spec.Max))
+		assert.True(t, quotav1.Equals(createResourceList(5, 500), quota.Spec.Min))
+		assert.Equal(t, "a", quota.Labels["a"])
+		assert.True(t, meta.IsStatusConditionTrue(profile.Status.Conditions, ConditionResourceRatioValid))
+		assert.Equal(t, int32(1), profile.Status.MatchedNodeCount)
+		assert.True(t, quotav1.Equals(createResourceList(5, 500), profile.Status.TotalResource))
+		assert.False(t, profile.Status.LastUpdateTime.IsZero())
+
+		// a node joins
+		node3 := defaultCreateNode("node3", zoneA, createResourceList(20, 2000))
+		assert.NoError(t, r.Client.Create(context.TODO(), node3))
+		quota, profile = reconcile(t, r)
+		assert.True(t, quotav1.Equals(createResourceList(15, 1500), quota.Spec.Max))
+		assert.Equal(t, int32(2), profile.Status.MatchedNodeCount)
+		assert.True(t, quotav1.Equals(createResourceList(15, 1500), profile.Status.TotalResource))
+
+		// the node leaves
+		assert.NoError(t, r.Client.Delete(context.TODO(), node3))
+		quota, profile = reconcile(t, r)
+		assert.True(t, quotav1.Equals(createResourceList(5, 500), quota.Spec.Max))
+		assert.Equal(t, int32(1), profile.Status.MatchedNodeCount)
+		assert.True(t, quotav1.Equals(createResourceList(5, 500), profile.Status.TotalResource))
+	})
+
+	t.Run("invalid ratio", func(t *testing.T) {
+		r := &QuotaProfileReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&quotav1alpha1.ElasticQuotaProfile{}).WithObjects(
+				newProfile("abc"),
+				defaultCreateNode("node1", zoneA, createResourceList(10, 1000)),
+			).Build(),
+			Scheme: scheme,
+		}
+		quota, profile := reconcile(t, r)
+		assert.True(t, quotav1.Equals(createResourceList(10, 1000), quota.Spec.Min))
+		assert.True(t, quotav1.Equals(quotav1.Mask(quota.Spec.Max, []corev1.ResourceName{corev1.ResourceCPU}),
+			corev1.ResourceList{corev1.ResourceCPU: *resource.NewQuantity(math.MaxInt64/2000, resource.DecimalSI)}))
+		condition := meta.FindStatusCondition(profile.Status.Conditions, ConditionResourceRatioValid)
+		assert.NotNil(t, condition)
+		assert.Equal(t, metav1.ConditionFalse, condition.Status)
+		assert.Equal(t, ReasonInvalidResourceRatio, condition.Reason)
+		assert.Contains(t, condition.Message, "abc")
+	})
+}
+
+func TestParseResourceRatio(t *testing.T) {
+	tests := []struct {
+		name        string
+		ratio       *string
+		expectRatio float64
+		expectErr   bool
+	}{
+		{name: "not set", ratio: nil, expectRatio: 1.0},
+		{name: "valid", ratio: pointer.String("0.9"), expectRatio: 0.9},
+		{name: "not a number", ratio: pointer.String("abc"), expectRatio: 1.0, expectErr: true},
+		{name: "zero", ratio: pointer.String("0"), expectRatio: 1.0, expectErr: true},
+		{name: "greater than one", ratio: pointer.String("1.5"), expectRatio: 1.0, expectErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			profile := &quotav1alpha1.ElasticQuotaProfile{
+				Spec: quotav1alpha1.ElasticQuotaProfileSpec{ResourceRatio: tc.ratio},
+			}
+			ratio, err := ParseResourceRatio(profile)
+			assert.Equal(t, tc.expectRatio, ratio)
+			assert.Equal(t, tc.expectErr, err != nil)
 		})
 	}
 }
@@ -397,3 +515,60 @@ func TestMultiplyQuantity(t *testing.T) {
 		})
 	}
 }
+
+func TestQuotaProfileReconciler_DetectCapacityDrift(t *testing.T) {
+	scheme := runtime.NewScheme()
+	clientgoscheme.AddToScheme(scheme)
+	quotav1alpha1.AddToScheme(scheme)
+	schedv1alpha1.AddToScheme(scheme)
+
+	profile := &quotav1alpha1.ElasticQuotaProfile{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "profile1",
+		},
+		Spec: quotav1alpha1.ElasticQuotaProfileSpec{
+			QuotaName: "profile1-root",
+			NodeSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"topology.kubernetes.io/zone": "cn-hangzhou-a"},
+			},
+		},
+	}
+	treeID := hash(fmt.Sprintf("%s/%s", "", "profile1"))
+	newTreeQuota := func(name string, max corev1.ResourceList) *schedv1alpha1.ElasticQuota {
+		return &schedv1alpha1.ElasticQuota{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: map[string]string{extension.LabelQuotaTreeID: treeID},
+			},
+			Spec: schedv1alpha1.ElasticQuotaSpec{Max: max},
+		}
+	}
+	rootQuota := newTreeQuota("profile1-root", createResourceList(1000, 100000))
+	rootQuota.Annotations = map[string]string{extension.AnnotationTotalResource: `{"cpu":"20","memory":"2000"}`}
+
+	r := &QuotaProfileReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			rootQuota,
+			newTreeQuota("child-a", createResourceList(15, 1000)),
+			newTreeQuota("child-b", createResourceList(30, 3000)),
+			newTreeQuota("other-tree", createResourceList(100, 10000)),
+		).Build(),
+		Scheme: scheme,
+	}
+	otherTreeQuota := &schedv1alpha1.ElasticQuota{}
+	assert.NoError(t, r.Client.Get(context.TODO(), types.NamespacedName{Name: "other-tree"}, otherTreeQuota))
+	otherTreeQuota.Labels[extension.LabelQuotaTreeID] = "other"
+	assert.NoError(t, r.Client.Update(context.TODO(), otherTreeQuota))
+
+	// the nodes shrink, the max of child-b exceeds the observed capacity
+	drifted := r.detectCapacityDrift(profile, treeID, rootQuota, createResourceList(20, 2000))
+	assert.Equal(t, []string{"child-b"}, drifted)
+
+	// the nodes shrink further, both children drift
+	drifted = r.detectCapacityDrift(profile, treeID, rootQuota, createResourceList(10, 1000))
+	assert.Equal(t, []string{"child-a", "child-b"}, drifted)
+
+	// the nodes grow, no drift
+	drifted = r.detectCapacityDrift(profile, treeID, rootQuota, createResourceList(30, 3000))
+	assert.Empty(t, drifted)
+}
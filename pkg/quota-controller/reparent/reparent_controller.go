@@ -0,0 +1,189 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reparent
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	"github.com/koordinator-sh/koordinator/apis/thirdparty/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+)
+
+const Name = "quotareparent"
+
+const (
+	ReasonReparentQuotaFailed = "ReparentQuotaFailed"
+)
+
+var (
+	// reparentChildren moves the children of a deleted parent quota to its parent, instead of rejecting the deletion.
+	reparentChildren = false
+)
+
+func InitFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&reparentChildren, "elastic-quota-delete-reparent-children", reparentChildren,
+		"Whether to move the children of a deleted parent ElasticQuota to its parent (or root), instead of rejecting the deletion. "+
+			"The parent quotas are held by a finalizer until their children are moved.")
+}
+
+// QuotaReparentReconciler holds the deletion of the parent quotas by the finalizer, and moves their children to
+// the grandparent (or root) before the finalizer is removed. The webhook allows the deletion of a parent quota
+// only if it holds the finalizer, so no writes are issued during the admission.
+type QuotaReparentReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+// +kubebuilder:rbac:groups=scheduling.sigs.k8s.io,resources=elasticquotas,verbs=get;list;watch;update;patch
+
+func (r *QuotaReparentReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	_ = log.FromContext(ctx, "quota-reparent-reconciler", req.NamespacedName)
+
+	quota := &v1alpha1.ElasticQuota{}
+	if err := r.Client.Get(context.TODO(), req.NamespacedName, quota); err != nil {
+		if !errors.IsNotFound(err) {
+			klog.Errorf("failed to find quota %v, error: %v", req.NamespacedName, err)
+			return ctrl.Result{Requeue: true}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	children, err := r.listChildren(quota.Name)
+	if err != nil {
+		klog.Errorf("failed to list the children of quota %v, error: %v", req.NamespacedName, err)
+		return ctrl.Result{Requeue: true}, err
+	}
+
+	if !quota.DeletionTimestamp.IsZero() {
+		if !controllerutil.ContainsFinalizer(quota, extension.FinalizerQuotaReparentChildren) {
+			return ctrl.Result{}, nil
+		}
+		// the subtree is deleted together, no need to move the children.
+		if !extension.IsAllowCascadingDelete(quota) {
+			if err := r.reparentChildren(quota, children); err != nil {
+				r.Recorder.Eventf(quota, corev1.EventTypeWarning, ReasonReparentQuotaFailed, "failed to reparent children, err: %v", err)
+				return ctrl.Result{Requeue: true}, err
+			}
+		}
+		return ctrl.Result{}, r.updateFinalizer(quota, false)
+	}
+
+	// the finalizer is removed once the option is disabled, so the quotas aren't held by it anymore.
+	needFinalizer := reparentChildren && len(children) > 0 && !extension.IsAllowCascadingDelete(quota)
+	return ctrl.Result{}, r.updateFinalizer(quota, needFinalizer)
+}
+
+func (r *QuotaReparentReconciler) listChildren(quotaName string) ([]*v1alpha1.ElasticQuota, error) {
+	quotaList := &v1alpha1.ElasticQuotaList{}
+	if err := r.Client.List(context.TODO(), quotaList, client.MatchingLabels{extension.LabelQuotaParent: quotaName}); err != nil {
+		return nil, err
+	}
+	children := make([]*v1alpha1.ElasticQuota, 0, len(quotaList.Items))
+	for i := range quotaList.Items {
+		children = append(children, &quotaList.Items[i])
+	}
+	return children, nil
+}
+
+// reparentChildren moves the children of the quota to its parent, or root for the top-level quota.
+// The updates are validated by the webhook, the failed ones are retried by the requeue.
+func (r *QuotaReparentReconciler) reparentChildren(quota *v1alpha1.ElasticQuota, children []*v1alpha1.ElasticQuota) error {
+	newParentName := extension.GetParentQuotaName(quota)
+	for _, child := range children {
+		newChild := child.DeepCopy()
+		newChild.Labels[extension.LabelQuotaParent] = newParentName
+		if err := r.Client.Update(context.TODO(), newChild); err != nil {
+			return fmt.Errorf("failed reparent quota %v to %v, err: %v", child.Name, newParentName, err)
+		}
+		klog.Infof("reparent quota %v from %v to %v since its parent is deleted", child.Name, quota.Name, newParentName)
+	}
+	return nil
+}
+
+func (r *QuotaReparentReconciler) updateFinalizer(quota *v1alpha1.ElasticQuota, needFinalizer bool) error {
+	if controllerutil.ContainsFinalizer(quota, extension.FinalizerQuotaReparentChildren) == needFinalizer {
+		return nil
+	}
+	newQuota := quota.DeepCopy()
+	if needFinalizer {
+		controllerutil.AddFinalizer(newQuota, extension.FinalizerQuotaReparentChildren)
+	} else {
+		controllerutil.RemoveFinalizer(newQuota, extension.FinalizerQuotaReparentChildren)
+	}
+	if err := r.Client.Update(context.TODO(), newQuota); err != nil {
+		klog.Errorf("failed to update the finalizer of quota %v/%v, error: %v", quota.Namespace, quota.Name, err)
+		return err
+	}
+	return nil
+}
+
+// enqueueParent maps the quota to its parent, so the finalizer of the parent follows its children.
+func (r *QuotaReparentReconciler) enqueueParent(ctx context.Context, obj client.Object) []reconcile.Request {
+	quota, ok := obj.(*v1alpha1.ElasticQuota)
+	if !ok {
+		return nil
+	}
+	parentName := quota.Labels[extension.LabelQuotaParent]
+	if parentName == "" || parentName == extension.RootQuotaName {
+		return nil
+	}
+	// the quota names are unique in the cluster, while the parent is referenced by the name only.
+	quotaList := &v1alpha1.ElasticQuotaList{}
+	if err := r.Client.List(ctx, quotaList); err != nil {
+		klog.Errorf("failed to list quotas to find the parent %v of quota %v, error: %v", parentName, quota.Name, err)
+		return nil
+	}
+	for i := range quotaList.Items {
+		if quotaList.Items[i].Name == parentName {
+			return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: quotaList.Items[i].Namespace, Name: parentName}}}
+		}
+	}
+	return nil
+}
+
+func Add(mgr ctrl.Manager) error {
+	reconciler := &QuotaReparentReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("quotareparent-controller"),
+	}
+	return reconciler.SetupWithManager(mgr)
+}
+
+func (r *QuotaReparentReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.ElasticQuota{}).
+		Watches(&v1alpha1.ElasticQuota{}, handler.EnqueueRequestsFromMapFunc(r.enqueueParent)).
+		Named(Name).
+		Complete(r)
+}
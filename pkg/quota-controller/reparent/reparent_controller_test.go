@@ -0,0 +1,170 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reparent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	schedv1alpha1 "github.com/koordinator-sh/koordinator/apis/thirdparty/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+)
+
+func newQuota(name, parentName string, finalizers ...string) *schedv1alpha1.ElasticQuota {
+	quota := &schedv1alpha1.ElasticQuota{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:  "default",
+			Name:       name,
+			Labels:     map[string]string{},
+			Finalizers: finalizers,
+		},
+	}
+	if parentName != "" {
+		quota.Labels[extension.LabelQuotaParent] = parentName
+	}
+	return quota
+}
+
+func newReconciler(objs ...client.Object) *QuotaReparentReconciler {
+	scheme := runtime.NewScheme()
+	clientgoscheme.AddToScheme(scheme)
+	schedv1alpha1.AddToScheme(scheme)
+	return &QuotaReparentReconciler{
+		Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(),
+		Scheme:   scheme,
+		Recorder: record.NewFakeRecorder(10),
+	}
+}
+
+func TestQuotaReparentReconciler_Finalizer(t *testing.T) {
+	tests := []struct {
+		name             string
+		reparentChildren bool
+		quota            *schedv1alpha1.ElasticQuota
+		children         []*schedv1alpha1.ElasticQuota
+		expectFinalizer  bool
+	}{
+		{
+			name:             "the finalizer is added to the parent",
+			reparentChildren: true,
+			quota:            newQuota("parent", ""),
+			children:         []*schedv1alpha1.ElasticQuota{newQuota("child", "parent")},
+			expectFinalizer:  true,
+		},
+		{
+			name:             "the finalizer is removed from the quota without children",
+			reparentChildren: true,
+			quota:            newQuota("parent", "", extension.FinalizerQuotaReparentChildren),
+			expectFinalizer:  false,
+		},
+		{
+			name:             "the finalizer is removed if the option is disabled",
+			reparentChildren: false,
+			quota:            newQuota("parent", "", extension.FinalizerQuotaReparentChildren),
+			children:         []*schedv1alpha1.ElasticQuota{newQuota("child", "parent")},
+			expectFinalizer:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldReparentChildren := reparentChildren
+			defer func() {
+				reparentChildren = oldReparentChildren
+			}()
+			reparentChildren = tt.reparentChildren
+
+			objs := []client.Object{tt.quota}
+			for _, child := range tt.children {
+				objs = append(objs, child)
+			}
+			r := newReconciler(objs...)
+			key := types.NamespacedName{Namespace: tt.quota.Namespace, Name: tt.quota.Name}
+			_, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: key})
+			assert.NoError(t, err)
+
+			quota := &schedv1alpha1.ElasticQuota{}
+			assert.NoError(t, r.Client.Get(context.TODO(), key, quota))
+			assert.Equal(t, tt.expectFinalizer, controllerutil.ContainsFinalizer(quota, extension.FinalizerQuotaReparentChildren))
+		})
+	}
+}
+
+func TestQuotaReparentReconciler_ReparentOnDelete(t *testing.T) {
+	tests := []struct {
+		name             string
+		parentName       string
+		expectParentName string
+	}{
+		{
+			name:             "the children of the top-level quota are moved to root",
+			expectParentName: extension.RootQuotaName,
+		},
+		{
+			name:             "the children are moved to the grandparent",
+			parentName:       "grandparent",
+			expectParentName: "grandparent",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quota := newQuota("parent", tt.parentName, extension.FinalizerQuotaReparentChildren)
+			now := metav1.Now()
+			quota.DeletionTimestamp = &now
+			r := newReconciler(quota, newQuota("sub-1", "parent"), newQuota("sub-2", "parent"), newQuota("other", ""))
+
+			key := types.NamespacedName{Namespace: "default", Name: "parent"}
+			_, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: key})
+			assert.NoError(t, err)
+
+			// the quota is gone once the finalizer is removed
+			err = r.Client.Get(context.TODO(), key, &schedv1alpha1.ElasticQuota{})
+			assert.True(t, errors.IsNotFound(err))
+			for _, name := range []string{"sub-1", "sub-2"} {
+				child := &schedv1alpha1.ElasticQuota{}
+				assert.NoError(t, r.Client.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: name}, child))
+				assert.Equal(t, tt.expectParentName, child.Labels[extension.LabelQuotaParent])
+			}
+			other := &schedv1alpha1.ElasticQuota{}
+			assert.NoError(t, r.Client.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: "other"}, other))
+			assert.Equal(t, "", other.Labels[extension.LabelQuotaParent])
+		})
+	}
+}
+
+func TestQuotaReparentReconciler_EnqueueParent(t *testing.T) {
+	parent := newQuota("parent", "")
+	parent.Namespace = "team"
+	r := newReconciler(parent)
+
+	assert.Equal(t, []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: "team", Name: "parent"}}},
+		r.enqueueParent(context.TODO(), newQuota("child", "parent")))
+	assert.Nil(t, r.enqueueParent(context.TODO(), newQuota("child", extension.RootQuotaName)))
+	assert.Nil(t, r.enqueueParent(context.TODO(), newQuota("child", "missing")))
+}
@@ -214,6 +214,99 @@ type ElasticQuotaArgs struct {
 
 	// HookPlugins is expected to be configured with enabled hook plugins
 	HookPlugins []HookPluginConf `json:"hookPlugins,omitempty"`
+
+	// BypassNamespaces are the namespaces whose pods bypass the quota enforcement,
+	// their pods are still accounted in the SystemQuotaGroup.
+	BypassNamespaces []string `json:"bypassNamespaces,omitempty"`
+
+	// ExceedTolerancePercent is the percentage by which the used of a quota may marginally exceed its runtime
+	// in PreFilter, e.g. due to rounding. 0 means no tolerance.
+	ExceedTolerancePercent *int64 `json:"exceedTolerancePercent,omitempty"`
+
+	// EnableAntiAffinityAwareRuntime discounts the runtime of a quota by the capacity of the nodes which its pods
+	// can't use due to the quota anti-affinity, avoiding granting unusable runtime.
+	EnableAntiAffinityAwareRuntime *bool `json:"enableAntiAffinityAwareRuntime,omitempty"`
+
+	// PodReplacementHandoffDuration is how long the quota used of a deleted pod is kept for the replacement pod
+	// of the same workload, so the slot isn't grabbed by other workloads in the meantime. 0 disables the handoff.
+	PodReplacementHandoffDuration *metav1.Duration `json:"podReplacementHandoffDuration,omitempty"`
+
+	// TerminatingQuotaPolicy is how the quota which has a deletion timestamp is treated, Ignore or Drain.
+	TerminatingQuotaPolicy string `json:"terminatingQuotaPolicy,omitempty"`
+
+	// DefaultParentQuotaName is the parent of the quotas lacking the parent label, e.g. a catch-all quota which
+	// the teams are placed under. The quotas fall back to the root quota if it's empty or doesn't exist.
+	DefaultParentQuotaName string `json:"defaultParentQuotaName,omitempty"`
+
+	// AdmissionLogSampleRate logs the details of 1 in AdmissionLogSampleRate admission decisions in PreFilter,
+	// keeping the logs manageable in high-throughput clusters. 0 disables the sampled logging.
+	AdmissionLogSampleRate *int64 `json:"admissionLogSampleRate,omitempty"`
+
+	// EnableNodeFitPreCheck rejects the pod early in PreFilter if its request exceeds the max allocatable
+	// of the nodes, which can't fit any node even if the quota admits it.
+	EnableNodeFitPreCheck *bool `json:"enableNodeFitPreCheck,omitempty"`
+
+	// StatusUsedPrecision rounds the used written to the status of quotas to the multiple of the precision of each
+	// resource, e.g. 100m cpu, which avoids the noisy status updates from the tiny changes. Not rounded if absent.
+	StatusUsedPrecision corev1.ResourceList `json:"statusUsedPrecision,omitempty"`
+
+	// ExceedDimensionOrder is the order the exceeded dimensions are reported in the rejection of PreFilter, e.g.
+	// [memory, cpu] surfaces the memory shortage first. The dimensions absent from the list follow in name order.
+	ExceedDimensionOrder []string `json:"exceedDimensionOrder,omitempty"`
+
+	// RuntimeQuotaDisabledResources are the resources checked against the max instead of the runtime in PreFilter
+	// when EnableRuntimeQuota is true, e.g. enforce the runtime of cpu and memory but let gpu be max-only.
+	// The resources absent from the max of a quota aren't checked at all, whether in the list or not.
+	RuntimeQuotaDisabledResources []string `json:"runtimeQuotaDisabledResources,omitempty"`
+
+	// EnableGangGroupQuotaReservation checks the quotas of all the pending members of a gang group together
+	// in PreFilter, and reserves the quotas for all of them at once if every involved quota has the headroom,
+	// so a gang group spanning multiple quotas won't proceed with only part of its members admitted.
+	EnableGangGroupQuotaReservation *bool `json:"enableGangGroupQuotaReservation,omitempty"`
+
+	// CapacityAlertWebhook is the url the plugin posts the capacity alerts to when the utilization of a quota
+	// crosses the CapacityAlertThresholds, so external alerting or autoscaling reacts without scraping metrics.
+	// Empty disables the capacity alerts.
+	CapacityAlertWebhook *string `json:"capacityAlertWebhook,omitempty"`
+
+	// CapacityAlertThresholds are the utilization levels in percent of the used to the max of a quota,
+	// the capacity alert is sent when the highest level the quota reaches changes.
+	CapacityAlertThresholds []int64 `json:"capacityAlertThresholds,omitempty"`
+
+	// DefaultNonPreemptibleQuotas are the quotas, e.g. the production quotas, whose pods are non-preemptible
+	// by default. A pod overrides it by setting the label quota.scheduling.koordinator.sh/preemptible explicitly.
+	DefaultNonPreemptibleQuotas []string `json:"defaultNonPreemptibleQuotas,omitempty"`
+
+	// GuaranteeMinRuntime keeps the runtime of every quota at least its min in each resource dimension even if
+	// the quota requests less, so a burst is admitted immediately. The floor is counted in the request of the
+	// parent quotas, so the floors of the children are distributed consistently under their parent.
+	GuaranteeMinRuntime *bool `json:"guaranteeMinRuntime,omitempty"`
+
+	// UnlabeledPodPolicies decide which quota the pods without the quota label go to, keyed by the namespace,
+	// when the namespace isn't bound to any quota. The pods go to the DefaultQuotaGroup if the namespace is absent.
+	// The policy is one of Default, System or Reject.
+	UnlabeledPodPolicies map[string]string `json:"unlabeledPodPolicies,omitempty"`
+
+	// AllowNonPreemptibleBeyondMin admits the non-preemptible pods beyond the min of their quota up to the max
+	// as long as the runtime has the headroom, instead of rejecting them. The non-preemptible pods beyond the min
+	// become the first to be revoked when the quota is over its runtime, e.g. another quota claims its min.
+	AllowNonPreemptibleBeyondMin *bool `json:"allowNonPreemptibleBeyondMin,omitempty"`
+
+	// RejectPodsWithoutRequests rejects the pods requesting none of the dimensions tracked by their quota, which
+	// occupy the scheduling without being accounted, so the teams are forced to set the requests.
+	// The pods of the system quota are exempt.
+	RejectPodsWithoutRequests *bool `json:"rejectPodsWithoutRequests,omitempty"`
+
+	// RuntimeShrinkDelay is how long the runtime of a quota has to stay lower before the admission follows it
+	// down, so the pods aren't admitted and rejected alternately as the requests fluctuate. The growth of the
+	// runtime takes effect at once and the runtime never exceeds the max. Zero disables the delay.
+	RuntimeShrinkDelay *metav1.Duration `json:"runtimeShrinkDelay,omitempty"`
+
+	// EnableFairSharePreemption lets the pod of a quota below its min preempt the pods of the sibling quotas
+	// borrowing beyond their min when their common ancestor is full, so the starved quota reaches its min.
+	// The victims are taken first from the sibling whose used most exceeds its fair share by the shared weight.
+	// Only the pods with lower priority than the preemptor are preempted.
+	EnableFairSharePreemption *bool `json:"enableFairSharePreemption,omitempty"`
 }
 
 // HookPluginConf define configuration for a single hook plugin
@@ -243,6 +336,28 @@ type CoschedulingArgs struct {
 	// Skip check schedule cycle
 	// default is false
 	SkipCheckScheduleCycle *bool `json:"skipCheckScheduleCycle,omitempty"`
+	// DeadlockDetectionThreshold is the number of the consecutive failed scheduling cycles of a gang holding
+	// the waiting pods, after which the gang is regarded as deadlocked and its waiting pods are released.
+	// It only applies to the NonStrict gangs, the Strict gang already releases its waiting pods on every failure.
+	// default is 10, and 0 disables the detection
+	DeadlockDetectionThreshold *int64 `json:"deadlockDetectionThreshold,omitempty"`
+	// GangOwnerKinds are the kinds of the controllers which the gangs of their pods are derived from, so the pods
+	// controlled by them join the gang named after the controller without the gang annotations.
+	GangOwnerKinds []GangOwnerKind `json:"gangOwnerKinds,omitempty"`
+}
+
+// GangOwnerKind is the kind of the controller which the gang is derived from.
+type GangOwnerKind struct {
+	// Group, Version and Kind match the controller reference of the pods.
+	Group   string `json:"group,omitempty"`
+	Version string `json:"version,omitempty"`
+	Kind    string `json:"kind,omitempty"`
+	// Resource is the plural resource name to get the controller, e.g. "jobs".
+	Resource string `json:"resource,omitempty"`
+	// ReplicasPath is the dotted path of the replica count in the controller, which is the total children number
+	// of the gang, and also the min number unless the controller has the gang min-available annotation.
+	// default is "spec.replicas"
+	ReplicasPath string `json:"replicasPath,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
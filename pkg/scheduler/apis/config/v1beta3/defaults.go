@@ -74,8 +74,9 @@ var (
 	defaultEnableRuntimeQuota            = pointer.Bool(true)
 	defaultDisableDefaultQuotaPreemption = pointer.Bool(true)
 
-	defaultTimeout           = 600 * time.Second
-	defaultControllerWorkers = 1
+	defaultTimeout                    = 600 * time.Second
+	defaultControllerWorkers          = 1
+	defaultDeadlockDetectionThreshold = 10
 )
 
 // SetDefaults_LoadAwareSchedulingArgs sets the default parameters for LoadAwareScheduling plugin.
@@ -205,6 +206,9 @@ func SetDefaults_CoschedulingArgs(obj *CoschedulingArgs) {
 	if obj.ControllerWorkers == nil {
 		obj.ControllerWorkers = pointer.Int64(int64(defaultControllerWorkers))
 	}
+	if obj.DeadlockDetectionThreshold == nil {
+		obj.DeadlockDetectionThreshold = pointer.Int64(int64(defaultDeadlockDetectionThreshold))
+	}
 }
 
 func SetDefaults_DeviceShareArgs(obj *DeviceShareArgs) {
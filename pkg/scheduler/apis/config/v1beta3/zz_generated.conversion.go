@@ -71,6 +71,16 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddGeneratedConversionFunc((*GangOwnerKind)(nil), (*config.GangOwnerKind)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta3_GangOwnerKind_To_config_GangOwnerKind(a.(*GangOwnerKind), b.(*config.GangOwnerKind), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*config.GangOwnerKind)(nil), (*GangOwnerKind)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_config_GangOwnerKind_To_v1beta3_GangOwnerKind(a.(*config.GangOwnerKind), b.(*GangOwnerKind), scope)
+	}); err != nil {
+		return err
+	}
 	if err := s.AddGeneratedConversionFunc((*HookPluginConf)(nil), (*config.HookPluginConf)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1beta3_HookPluginConf_To_config_HookPluginConf(a.(*HookPluginConf), b.(*config.HookPluginConf), scope)
 	}); err != nil {
@@ -174,6 +184,10 @@ func autoConvert_v1beta3_CoschedulingArgs_To_config_CoschedulingArgs(in *Cosched
 	if err := v1.Convert_Pointer_bool_To_bool(&in.SkipCheckScheduleCycle, &out.SkipCheckScheduleCycle, s); err != nil {
 		return err
 	}
+	if err := v1.Convert_Pointer_int64_To_int64(&in.DeadlockDetectionThreshold, &out.DeadlockDetectionThreshold, s); err != nil {
+		return err
+	}
+	out.GangOwnerKinds = *(*[]config.GangOwnerKind)(unsafe.Pointer(&in.GangOwnerKinds))
 	return nil
 }
 
@@ -192,6 +206,10 @@ func autoConvert_config_CoschedulingArgs_To_v1beta3_CoschedulingArgs(in *config.
 	if err := v1.Convert_bool_To_Pointer_bool(&in.SkipCheckScheduleCycle, &out.SkipCheckScheduleCycle, s); err != nil {
 		return err
 	}
+	if err := v1.Convert_int64_To_Pointer_int64(&in.DeadlockDetectionThreshold, &out.DeadlockDetectionThreshold, s); err != nil {
+		return err
+	}
+	out.GangOwnerKinds = *(*[]GangOwnerKind)(unsafe.Pointer(&in.GangOwnerKinds))
 	return nil
 }
 
@@ -247,6 +265,51 @@ func autoConvert_v1beta3_ElasticQuotaArgs_To_config_ElasticQuotaArgs(in *Elastic
 		return err
 	}
 	out.HookPlugins = *(*[]config.HookPluginConf)(unsafe.Pointer(&in.HookPlugins))
+	out.BypassNamespaces = *(*[]string)(unsafe.Pointer(&in.BypassNamespaces))
+	if err := v1.Convert_Pointer_int64_To_int64(&in.ExceedTolerancePercent, &out.ExceedTolerancePercent, s); err != nil {
+		return err
+	}
+	if err := v1.Convert_Pointer_bool_To_bool(&in.EnableAntiAffinityAwareRuntime, &out.EnableAntiAffinityAwareRuntime, s); err != nil {
+		return err
+	}
+	if err := v1.Convert_Pointer_v1_Duration_To_v1_Duration(&in.PodReplacementHandoffDuration, &out.PodReplacementHandoffDuration, s); err != nil {
+		return err
+	}
+	out.TerminatingQuotaPolicy = config.TerminatingQuotaPolicy(in.TerminatingQuotaPolicy)
+	out.DefaultParentQuotaName = in.DefaultParentQuotaName
+	if err := v1.Convert_Pointer_int64_To_int64(&in.AdmissionLogSampleRate, &out.AdmissionLogSampleRate, s); err != nil {
+		return err
+	}
+	if err := v1.Convert_Pointer_bool_To_bool(&in.EnableNodeFitPreCheck, &out.EnableNodeFitPreCheck, s); err != nil {
+		return err
+	}
+	out.StatusUsedPrecision = *(*corev1.ResourceList)(unsafe.Pointer(&in.StatusUsedPrecision))
+	out.ExceedDimensionOrder = *(*[]string)(unsafe.Pointer(&in.ExceedDimensionOrder))
+	out.RuntimeQuotaDisabledResources = *(*[]string)(unsafe.Pointer(&in.RuntimeQuotaDisabledResources))
+	if err := v1.Convert_Pointer_bool_To_bool(&in.EnableGangGroupQuotaReservation, &out.EnableGangGroupQuotaReservation, s); err != nil {
+		return err
+	}
+	if err := v1.Convert_Pointer_string_To_string(&in.CapacityAlertWebhook, &out.CapacityAlertWebhook, s); err != nil {
+		return err
+	}
+	out.CapacityAlertThresholds = *(*[]int64)(unsafe.Pointer(&in.CapacityAlertThresholds))
+	out.DefaultNonPreemptibleQuotas = *(*[]string)(unsafe.Pointer(&in.DefaultNonPreemptibleQuotas))
+	if err := v1.Convert_Pointer_bool_To_bool(&in.GuaranteeMinRuntime, &out.GuaranteeMinRuntime, s); err != nil {
+		return err
+	}
+	out.UnlabeledPodPolicies = *(*map[string]config.UnlabeledPodPolicy)(unsafe.Pointer(&in.UnlabeledPodPolicies))
+	if err := v1.Convert_Pointer_bool_To_bool(&in.AllowNonPreemptibleBeyondMin, &out.AllowNonPreemptibleBeyondMin, s); err != nil {
+		return err
+	}
+	if err := v1.Convert_Pointer_bool_To_bool(&in.RejectPodsWithoutRequests, &out.RejectPodsWithoutRequests, s); err != nil {
+		return err
+	}
+	if err := v1.Convert_Pointer_v1_Duration_To_v1_Duration(&in.RuntimeShrinkDelay, &out.RuntimeShrinkDelay, s); err != nil {
+		return err
+	}
+	if err := v1.Convert_Pointer_bool_To_bool(&in.EnableFairSharePreemption, &out.EnableFairSharePreemption, s); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -278,6 +341,51 @@ func autoConvert_config_ElasticQuotaArgs_To_v1beta3_ElasticQuotaArgs(in *config.
 		return err
 	}
 	out.HookPlugins = *(*[]HookPluginConf)(unsafe.Pointer(&in.HookPlugins))
+	out.BypassNamespaces = *(*[]string)(unsafe.Pointer(&in.BypassNamespaces))
+	if err := v1.Convert_int64_To_Pointer_int64(&in.ExceedTolerancePercent, &out.ExceedTolerancePercent, s); err != nil {
+		return err
+	}
+	if err := v1.Convert_bool_To_Pointer_bool(&in.EnableAntiAffinityAwareRuntime, &out.EnableAntiAffinityAwareRuntime, s); err != nil {
+		return err
+	}
+	if err := v1.Convert_v1_Duration_To_Pointer_v1_Duration(&in.PodReplacementHandoffDuration, &out.PodReplacementHandoffDuration, s); err != nil {
+		return err
+	}
+	out.TerminatingQuotaPolicy = string(in.TerminatingQuotaPolicy)
+	out.DefaultParentQuotaName = in.DefaultParentQuotaName
+	if err := v1.Convert_int64_To_Pointer_int64(&in.AdmissionLogSampleRate, &out.AdmissionLogSampleRate, s); err != nil {
+		return err
+	}
+	if err := v1.Convert_bool_To_Pointer_bool(&in.EnableNodeFitPreCheck, &out.EnableNodeFitPreCheck, s); err != nil {
+		return err
+	}
+	out.StatusUsedPrecision = *(*corev1.ResourceList)(unsafe.Pointer(&in.StatusUsedPrecision))
+	out.ExceedDimensionOrder = *(*[]string)(unsafe.Pointer(&in.ExceedDimensionOrder))
+	out.RuntimeQuotaDisabledResources = *(*[]string)(unsafe.Pointer(&in.RuntimeQuotaDisabledResources))
+	if err := v1.Convert_bool_To_Pointer_bool(&in.EnableGangGroupQuotaReservation, &out.EnableGangGroupQuotaReservation, s); err != nil {
+		return err
+	}
+	if err := v1.Convert_string_To_Pointer_string(&in.CapacityAlertWebhook, &out.CapacityAlertWebhook, s); err != nil {
+		return err
+	}
+	out.CapacityAlertThresholds = *(*[]int64)(unsafe.Pointer(&in.CapacityAlertThresholds))
+	out.DefaultNonPreemptibleQuotas = *(*[]string)(unsafe.Pointer(&in.DefaultNonPreemptibleQuotas))
+	if err := v1.Convert_bool_To_Pointer_bool(&in.GuaranteeMinRuntime, &out.GuaranteeMinRuntime, s); err != nil {
+		return err
+	}
+	out.UnlabeledPodPolicies = *(*map[string]string)(unsafe.Pointer(&in.UnlabeledPodPolicies))
+	if err := v1.Convert_bool_To_Pointer_bool(&in.AllowNonPreemptibleBeyondMin, &out.AllowNonPreemptibleBeyondMin, s); err != nil {
+		return err
+	}
+	if err := v1.Convert_bool_To_Pointer_bool(&in.RejectPodsWithoutRequests, &out.RejectPodsWithoutRequests, s); err != nil {
+		return err
+	}
+	if err := v1.Convert_v1_Duration_To_Pointer_v1_Duration(&in.RuntimeShrinkDelay, &out.RuntimeShrinkDelay, s); err != nil {
+		return err
+	}
+	if err := v1.Convert_bool_To_Pointer_bool(&in.EnableFairSharePreemption, &out.EnableFairSharePreemption, s); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -286,6 +394,34 @@ func Convert_config_ElasticQuotaArgs_To_v1beta3_ElasticQuotaArgs(in *config.Elas
 	return autoConvert_config_ElasticQuotaArgs_To_v1beta3_ElasticQuotaArgs(in, out, s)
 }
 
+func autoConvert_v1beta3_GangOwnerKind_To_config_GangOwnerKind(in *GangOwnerKind, out *config.GangOwnerKind, s conversion.Scope) error {
+	out.Group = in.Group
+	out.Version = in.Version
+	out.Kind = in.Kind
+	out.Resource = in.Resource
+	out.ReplicasPath = in.ReplicasPath
+	return nil
+}
+
+// Convert_v1beta3_GangOwnerKind_To_config_GangOwnerKind is an autogenerated conversion function.
+func Convert_v1beta3_GangOwnerKind_To_config_GangOwnerKind(in *GangOwnerKind, out *config.GangOwnerKind, s conversion.Scope) error {
+	return autoConvert_v1beta3_GangOwnerKind_To_config_GangOwnerKind(in, out, s)
+}
+
+func autoConvert_config_GangOwnerKind_To_v1beta3_GangOwnerKind(in *config.GangOwnerKind, out *GangOwnerKind, s conversion.Scope) error {
+	out.Group = in.Group
+	out.Version = in.Version
+	out.Kind = in.Kind
+	out.Resource = in.Resource
+	out.ReplicasPath = in.ReplicasPath
+	return nil
+}
+
+// Convert_config_GangOwnerKind_To_v1beta3_GangOwnerKind is an autogenerated conversion function.
+func Convert_config_GangOwnerKind_To_v1beta3_GangOwnerKind(in *config.GangOwnerKind, out *GangOwnerKind, s conversion.Scope) error {
+	return autoConvert_config_GangOwnerKind_To_v1beta3_GangOwnerKind(in, out, s)
+}
+
 func autoConvert_v1beta3_HookPluginConf_To_config_HookPluginConf(in *HookPluginConf, out *config.HookPluginConf, s conversion.Scope) error {
 	out.Key = in.Key
 	out.FactoryKey = in.FactoryKey
@@ -47,6 +47,16 @@ func (in *CoschedulingArgs) DeepCopyInto(out *CoschedulingArgs) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.DeadlockDetectionThreshold != nil {
+		in, out := &in.DeadlockDetectionThreshold, &out.DeadlockDetectionThreshold
+		*out = new(int64)
+		**out = **in
+	}
+	if in.GangOwnerKinds != nil {
+		in, out := &in.GangOwnerKinds, &out.GangOwnerKinds
+		*out = make([]GangOwnerKind, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -151,6 +161,105 @@ func (in *ElasticQuotaArgs) DeepCopyInto(out *ElasticQuotaArgs) {
 		*out = make([]HookPluginConf, len(*in))
 		copy(*out, *in)
 	}
+	if in.BypassNamespaces != nil {
+		in, out := &in.BypassNamespaces, &out.BypassNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExceedTolerancePercent != nil {
+		in, out := &in.ExceedTolerancePercent, &out.ExceedTolerancePercent
+		*out = new(int64)
+		**out = **in
+	}
+	if in.EnableAntiAffinityAwareRuntime != nil {
+		in, out := &in.EnableAntiAffinityAwareRuntime, &out.EnableAntiAffinityAwareRuntime
+		*out = new(bool)
+		**out = **in
+	}
+	if in.PodReplacementHandoffDuration != nil {
+		in, out := &in.PodReplacementHandoffDuration, &out.PodReplacementHandoffDuration
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.AdmissionLogSampleRate != nil {
+		in, out := &in.AdmissionLogSampleRate, &out.AdmissionLogSampleRate
+		*out = new(int64)
+		**out = **in
+	}
+	if in.EnableNodeFitPreCheck != nil {
+		in, out := &in.EnableNodeFitPreCheck, &out.EnableNodeFitPreCheck
+		*out = new(bool)
+		**out = **in
+	}
+	if in.StatusUsedPrecision != nil {
+		in, out := &in.StatusUsedPrecision, &out.StatusUsedPrecision
+		*out = make(corev1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.ExceedDimensionOrder != nil {
+		in, out := &in.ExceedDimensionOrder, &out.ExceedDimensionOrder
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RuntimeQuotaDisabledResources != nil {
+		in, out := &in.RuntimeQuotaDisabledResources, &out.RuntimeQuotaDisabledResources
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.EnableGangGroupQuotaReservation != nil {
+		in, out := &in.EnableGangGroupQuotaReservation, &out.EnableGangGroupQuotaReservation
+		*out = new(bool)
+		**out = **in
+	}
+	if in.CapacityAlertWebhook != nil {
+		in, out := &in.CapacityAlertWebhook, &out.CapacityAlertWebhook
+		*out = new(string)
+		**out = **in
+	}
+	if in.CapacityAlertThresholds != nil {
+		in, out := &in.CapacityAlertThresholds, &out.CapacityAlertThresholds
+		*out = make([]int64, len(*in))
+		copy(*out, *in)
+	}
+	if in.DefaultNonPreemptibleQuotas != nil {
+		in, out := &in.DefaultNonPreemptibleQuotas, &out.DefaultNonPreemptibleQuotas
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.GuaranteeMinRuntime != nil {
+		in, out := &in.GuaranteeMinRuntime, &out.GuaranteeMinRuntime
+		*out = new(bool)
+		**out = **in
+	}
+	if in.UnlabeledPodPolicies != nil {
+		in, out := &in.UnlabeledPodPolicies, &out.UnlabeledPodPolicies
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.AllowNonPreemptibleBeyondMin != nil {
+		in, out := &in.AllowNonPreemptibleBeyondMin, &out.AllowNonPreemptibleBeyondMin
+		*out = new(bool)
+		**out = **in
+	}
+	if in.RejectPodsWithoutRequests != nil {
+		in, out := &in.RejectPodsWithoutRequests, &out.RejectPodsWithoutRequests
+		*out = new(bool)
+		**out = **in
+	}
+	if in.RuntimeShrinkDelay != nil {
+		in, out := &in.RuntimeShrinkDelay, &out.RuntimeShrinkDelay
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.EnableFairSharePreemption != nil {
+		in, out := &in.EnableFairSharePreemption, &out.EnableFairSharePreemption
+		*out = new(bool)
+		**out = **in
+	}
 	return
 }
 
@@ -172,6 +281,22 @@ func (in *ElasticQuotaArgs) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GangOwnerKind) DeepCopyInto(out *GangOwnerKind) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GangOwnerKind.
+func (in *GangOwnerKind) DeepCopy() *GangOwnerKind {
+	if in == nil {
+		return nil
+	}
+	out := new(GangOwnerKind)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HookPluginConf) DeepCopyInto(out *HookPluginConf) {
 	*out = *in
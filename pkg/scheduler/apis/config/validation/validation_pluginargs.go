@@ -18,12 +18,15 @@ package validation
 
 import (
 	"fmt"
+	"net/url"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	schedconfig "k8s.io/kubernetes/pkg/scheduler/apis/config"
 
+	"github.com/koordinator-sh/koordinator/apis/extension"
 	"github.com/koordinator-sh/koordinator/pkg/scheduler/apis/config"
 )
 
@@ -117,6 +120,81 @@ func ValidateElasticQuotaArgs(elasticArgs *config.ElasticQuotaArgs) error {
 		return fmt.Errorf("elasticQuotaArgs error, RevokePodCycle should be a positive value")
 	}
 
+	if elasticArgs.PodReplacementHandoffDuration.Duration < 0 {
+		return fmt.Errorf("elasticQuotaArgs error, PodReplacementHandoffDuration should be a non-negative value")
+	}
+
+	if elasticArgs.RuntimeShrinkDelay.Duration < 0 {
+		return fmt.Errorf("elasticQuotaArgs error, RuntimeShrinkDelay should be a non-negative value")
+	}
+
+	if elasticArgs.ExceedTolerancePercent < 0 || elasticArgs.ExceedTolerancePercent > 100 {
+		return fmt.Errorf("elasticQuotaArgs error, ExceedTolerancePercent should be in [0, 100], got %v",
+			elasticArgs.ExceedTolerancePercent)
+	}
+
+	for resName, q := range elasticArgs.StatusUsedPrecision {
+		if q.Sign() < 0 {
+			return fmt.Errorf("elasticQuotaArgs error, StatusUsedPrecision should be a non-negative value, resourceName:%v, got %v",
+				resName, q)
+		}
+	}
+
+	for _, resourceName := range elasticArgs.RuntimeQuotaDisabledResources {
+		if resourceName == "" {
+			return fmt.Errorf("elasticQuotaArgs error, RuntimeQuotaDisabledResources should not contain an empty resource name")
+		}
+	}
+	exceedDimensions := sets.NewString()
+	for _, resourceName := range elasticArgs.ExceedDimensionOrder {
+		if resourceName == "" {
+			return fmt.Errorf("elasticQuotaArgs error, ExceedDimensionOrder should not contain an empty resource name")
+		}
+		if exceedDimensions.Has(resourceName) {
+			return fmt.Errorf("elasticQuotaArgs error, ExceedDimensionOrder contains duplicated resource name %v", resourceName)
+		}
+		exceedDimensions.Insert(resourceName)
+	}
+
+	if elasticArgs.CapacityAlertWebhook != "" {
+		if _, err := url.ParseRequestURI(elasticArgs.CapacityAlertWebhook); err != nil {
+			return fmt.Errorf("elasticQuotaArgs error, CapacityAlertWebhook is invalid, err: %v", err)
+		}
+	}
+	for _, threshold := range elasticArgs.CapacityAlertThresholds {
+		if threshold <= 0 || threshold > 100 {
+			return fmt.Errorf("elasticQuotaArgs error, CapacityAlertThresholds should be in (0, 100], got %v", threshold)
+		}
+	}
+	for _, quotaName := range elasticArgs.DefaultNonPreemptibleQuotas {
+		if quotaName == "" {
+			return fmt.Errorf("elasticQuotaArgs error, DefaultNonPreemptibleQuotas should not contain empty quota name")
+		}
+	}
+
+	if elasticArgs.AdmissionLogSampleRate < 0 {
+		return fmt.Errorf("elasticQuotaArgs error, AdmissionLogSampleRate should be a non-negative value")
+	}
+
+	if elasticArgs.DefaultParentQuotaName == extension.SystemQuotaName || elasticArgs.DefaultParentQuotaName == extension.DefaultQuotaName {
+		return fmt.Errorf("elasticQuotaArgs error, DefaultParentQuotaName should not be %v", elasticArgs.DefaultParentQuotaName)
+	}
+
+	switch elasticArgs.TerminatingQuotaPolicy {
+	case "", config.TerminatingQuotaPolicyIgnore, config.TerminatingQuotaPolicyDrain:
+	default:
+		return fmt.Errorf("elasticQuotaArgs error, TerminatingQuotaPolicy should be Ignore or Drain, got %v",
+			elasticArgs.TerminatingQuotaPolicy)
+	}
+	for namespace, policy := range elasticArgs.UnlabeledPodPolicies {
+		switch policy {
+		case config.UnlabeledPodPolicyDefault, config.UnlabeledPodPolicySystem, config.UnlabeledPodPolicyReject:
+		default:
+			return fmt.Errorf("elasticQuotaArgs error, UnlabeledPodPolicies of namespace %v should be Default, System or Reject, got %v",
+				namespace, policy)
+		}
+	}
+
 	return nil
 }
 
@@ -127,6 +205,14 @@ func ValidateCoschedulingArgs(coeSchedulingArgs *config.CoschedulingArgs) error
 	if coeSchedulingArgs.ControllerWorkers < 1 {
 		return fmt.Errorf("coeSchedulingArgs ControllerWorkers invalid")
 	}
+	if coeSchedulingArgs.DeadlockDetectionThreshold < 0 {
+		return fmt.Errorf("coeSchedulingArgs DeadlockDetectionThreshold invalid")
+	}
+	for _, ownerKind := range coeSchedulingArgs.GangOwnerKinds {
+		if ownerKind.Version == "" || ownerKind.Kind == "" || ownerKind.Resource == "" {
+			return fmt.Errorf("coeSchedulingArgs GangOwnerKinds invalid, version, kind and resource are required, got %+v", ownerKind)
+		}
+	}
 	return nil
 }
 
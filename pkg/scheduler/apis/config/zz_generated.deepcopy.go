@@ -32,6 +32,11 @@ func (in *CoschedulingArgs) DeepCopyInto(out *CoschedulingArgs) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	out.DefaultTimeout = in.DefaultTimeout
+	if in.GangOwnerKinds != nil {
+		in, out := &in.GangOwnerKinds, &out.GangOwnerKinds
+		*out = make([]GangOwnerKind, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -108,6 +113,47 @@ func (in *ElasticQuotaArgs) DeepCopyInto(out *ElasticQuotaArgs) {
 		*out = make([]HookPluginConf, len(*in))
 		copy(*out, *in)
 	}
+	if in.BypassNamespaces != nil {
+		in, out := &in.BypassNamespaces, &out.BypassNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	out.PodReplacementHandoffDuration = in.PodReplacementHandoffDuration
+	if in.StatusUsedPrecision != nil {
+		in, out := &in.StatusUsedPrecision, &out.StatusUsedPrecision
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.ExceedDimensionOrder != nil {
+		in, out := &in.ExceedDimensionOrder, &out.ExceedDimensionOrder
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RuntimeQuotaDisabledResources != nil {
+		in, out := &in.RuntimeQuotaDisabledResources, &out.RuntimeQuotaDisabledResources
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.CapacityAlertThresholds != nil {
+		in, out := &in.CapacityAlertThresholds, &out.CapacityAlertThresholds
+		*out = make([]int64, len(*in))
+		copy(*out, *in)
+	}
+	if in.DefaultNonPreemptibleQuotas != nil {
+		in, out := &in.DefaultNonPreemptibleQuotas, &out.DefaultNonPreemptibleQuotas
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.UnlabeledPodPolicies != nil {
+		in, out := &in.UnlabeledPodPolicies, &out.UnlabeledPodPolicies
+		*out = make(map[string]UnlabeledPodPolicy, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	out.RuntimeShrinkDelay = in.RuntimeShrinkDelay
 	return
 }
 
@@ -129,6 +175,22 @@ func (in *ElasticQuotaArgs) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GangOwnerKind) DeepCopyInto(out *GangOwnerKind) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GangOwnerKind.
+func (in *GangOwnerKind) DeepCopy() *GangOwnerKind {
+	if in == nil {
+		return nil
+	}
+	out := new(GangOwnerKind)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HookPluginConf) DeepCopyInto(out *HookPluginConf) {
 	*out = *in
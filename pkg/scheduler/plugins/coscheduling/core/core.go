@@ -24,7 +24,10 @@ import (
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
 	"k8s.io/client-go/informers"
 	listerv1 "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
@@ -75,6 +78,9 @@ type Manager interface {
 	GetGangSummaries() map[string]*GangSummary
 
 	GetBoundPodNumber(gangId string) int32
+
+	GetGangNameByPod(pod *corev1.Pod) string
+	IsPodNeedGang(pod *corev1.Pod) bool
 }
 
 // PodGroupManager defines the scheduling operation called
@@ -104,6 +110,16 @@ func NewPodGroupManager(
 	pgInformer := pgSharedInformerFactory.Scheduling().V1alpha1().PodGroups()
 	podInformer := sharedInformerFactory.Core().V1().Pods()
 	gangCache := NewGangCache(args, podInformer.Lister(), pgInformer.Lister(), pgClient, handle)
+	if len(args.GangOwnerKinds) > 0 && handle.KubeConfig() != nil {
+		// the controllers are listed from the informers, so the pod events don't wait for the apiserver
+		ownerInformerFactory := dynamicinformer.NewDynamicSharedInformerFactory(dynamic.NewForConfigOrDie(handle.KubeConfig()), 0)
+		for _, kind := range args.GangOwnerKinds {
+			gvr := schema.GroupVersionResource{Group: kind.Group, Version: kind.Version, Resource: kind.Resource}
+			gangCache.ownerListers[gvr] = ownerInformerFactory.ForResource(gvr).Lister()
+		}
+		ownerInformerFactory.Start(context.TODO().Done())
+		ownerInformerFactory.WaitForCacheSync(context.TODO().Done())
+	}
 	pgMgr := &PodGroupManager{
 		handle:    handle,
 		args:      args,
@@ -177,12 +193,12 @@ func (pgMgr *PodGroupManager) SucceedGangScheduling() {
 // PreEnqueue
 // TODO Turning it on may result in no Pod scheduling events, and an external check should be done through the controller later.
 func (pgMgr *PodGroupManager) PreEnqueue(ctx context.Context, pod *corev1.Pod) (err error) {
-	if !util.IsPodNeedGang(pod) {
+	if !pgMgr.IsPodNeedGang(pod) {
 		return nil
 	}
 	gang := pgMgr.GetGangByPod(pod)
 	if gang == nil {
-		return fmt.Errorf("can't find gang, gangName: %v, podName: %v", util.GetId(pod.Namespace, util.GetGangNameByPod(pod)),
+		return fmt.Errorf("can't find gang, gangName: %v, podName: %v", util.GetId(pod.Namespace, pgMgr.GetGangNameByPod(pod)),
 			util.GetId(pod.Namespace, pod.Name))
 	}
 
@@ -220,7 +236,8 @@ func (pgMgr *PodGroupManager) basicGangRequirementsCheck(gang *Gang, pod *corev1
 			gangsOfGangNotInit = append(gangsOfGangNotInit, gangID)
 			continue
 		}
-		if gang.getChildrenNum() < gang.getGangMinNum() {
+		// every member gang must collect its own min children
+		if gangTmp.getChildrenNum() < gangTmp.getGangMinNum() {
 			gangsOfMinNumUnSatisfied = append(gangsOfMinNumUnSatisfied, gangID)
 			continue
 		}
@@ -233,7 +250,7 @@ func (pgMgr *PodGroupManager) basicGangRequirementsCheck(gang *Gang, pod *corev1
 		failedMsg = append(failedMsg, fmt.Sprintf("memberGangs %+v has not init", gangsOfGangNotInit))
 	}
 	if len(gangsOfMinNumUnSatisfied) > 0 {
-		failedMsg = append(failedMsg, fmt.Sprintf("memberGangs %+v child pod not collect enough", gangGroup))
+		failedMsg = append(failedMsg, fmt.Sprintf("memberGangs %+v child pod not collect enough", gangsOfMinNumUnSatisfied))
 	}
 	if len(failedMsg) > 0 {
 		return fmt.Errorf("gangGroup %v basic check: %s, current gang: %s, podName: %v",
@@ -247,12 +264,12 @@ func (pgMgr *PodGroupManager) basicGangRequirementsCheck(gang *Gang, pod *corev1
 }
 
 func (pgMgr *PodGroupManager) PreFilter(ctx context.Context, _ *framework.CycleState, pod *corev1.Pod) (err error) {
-	if !util.IsPodNeedGang(pod) {
+	if !pgMgr.IsPodNeedGang(pod) {
 		return nil
 	}
 	gang := pgMgr.GetGangByPod(pod)
 	if gang == nil {
-		return fmt.Errorf("can't find gang, gangName: %v, podName: %v", util.GetId(pod.Namespace, util.GetGangNameByPod(pod)),
+		return fmt.Errorf("can't find gang, gangName: %v, podName: %v", util.GetId(pod.Namespace, pgMgr.GetGangNameByPod(pod)),
 			util.GetId(pod.Namespace, pod.Name))
 	}
 
@@ -282,15 +299,16 @@ func (pgMgr *PodGroupManager) PreFilter(ctx context.Context, _ *framework.CycleS
 }
 
 // PostFilter
-// i. If strict-mode, we will set scheduleCycleValid to false and release all assumed pods.
-// ii. If non-strict mode, we will do nothing.
+// i. If the non-strict gang group holds the waiting pods but fails too many scheduling cycles, we regard it as deadlocked and release all assumed pods.
+// ii. If strict-mode, we will set scheduleCycleValid to false and release all assumed pods.
+// iii. If non-strict mode, we will do nothing.
 func (pgMgr *PodGroupManager) PostFilter(ctx context.Context, state *framework.CycleState, pod *corev1.Pod, handle framework.Handle, pluginName string, filteredNodeStatusMap framework.NodeToStatusMap) (*framework.PostFilterResult, *framework.Status) {
-	if !util.IsPodNeedGang(pod) {
+	if !pgMgr.IsPodNeedGang(pod) {
 		return &framework.PostFilterResult{}, framework.NewStatus(framework.Unschedulable)
 	}
 	gang := pgMgr.GetGangByPod(pod)
 	if gang == nil {
-		message := fmt.Sprintf("Pod %q cannot find Gang %q", klog.KObj(pod), util.GetGangNameByPod(pod))
+		message := fmt.Sprintf("Pod %q cannot find Gang %q", klog.KObj(pod), pgMgr.GetGangNameByPod(pod))
 		klog.Warningf(message)
 		return &framework.PostFilterResult{}, framework.NewStatus(framework.Unschedulable, message)
 	}
@@ -298,6 +316,16 @@ func (pgMgr *PodGroupManager) PostFilter(ctx context.Context, state *framework.C
 		return &framework.PostFilterResult{}, framework.NewStatus(framework.Unschedulable)
 	}
 
+	schedulingFailures := gang.recordSchedulingFailure()
+	if pgMgr.isGangDeadlocked(gang, schedulingFailures) {
+		gang.setDeadlocked()
+		message := fmt.Sprintf("Gang %q gets rejected due to deadlock, the gangGroup holds waiting pods but failed %d scheduling cycles",
+			gang.Name, schedulingFailures)
+		gang.clearWaitingGang()
+		pgMgr.rejectGangGroupById(handle, pluginName, gang.Name, message)
+		return &framework.PostFilterResult{}, framework.NewStatus(framework.Unschedulable, message)
+	}
+
 	nodeInfos, _ := handle.SnapshotSharedLister().NodeInfos().List()
 	fitErr := &framework.FitError{
 		Pod:         pod,
@@ -324,11 +352,22 @@ func (pgMgr *PodGroupManager) PostFilter(ctx context.Context, state *framework.C
 	return &framework.PostFilterResult{}, framework.NewStatus(framework.Unschedulable)
 }
 
+// isGangDeadlocked checks whether the NonStrict gang group holds the resources of the waiting pods, but the gang fails
+// DeadlockDetectionThreshold scheduling cycles in a row. The gang group merely waiting within WaitTime isn't deadlocked.
+// The Strict gang is never regarded as deadlocked, since PostFilter releases its waiting pods on every failure.
+func (pgMgr *PodGroupManager) isGangDeadlocked(gang *Gang, schedulingFailures int) bool {
+	threshold := pgMgr.args.DeadlockDetectionThreshold
+	if threshold <= 0 || int64(schedulingFailures) < threshold || gang.getGangMode() != extension.GangModeNonStrict {
+		return false
+	}
+	return gang.isGangGroupWaiting()
+}
+
 // Permit
 // we will calculate all Gangs in GangGroup whether the current number of assumed-pods in each Gang meets the Gang's minimum requirement.
 // and decide whether we should let the pod wait in Permit stage or let the whole gangGroup go binding
 func (pgMgr *PodGroupManager) Permit(ctx context.Context, pod *corev1.Pod) (time.Duration, Status) {
-	if !util.IsPodNeedGang(pod) {
+	if !pgMgr.IsPodNeedGang(pod) {
 		return 0, PodGroupNotSpecified
 	}
 	gang := pgMgr.GetGangByPod(pod)
@@ -359,9 +398,10 @@ func (pgMgr *PodGroupManager) Permit(ctx context.Context, pod *corev1.Pod) (time
 
 // Unreserve
 // if gang is resourceSatisfied, we only delAssumedPod
+// if the pod times out and the gang's timeout action is Requeue, we only delAssumedPod, the pod is requeued for another wait cycle
 // if gang is not resourceSatisfied and is in StrictMode, we release all the assumed pods
 func (pgMgr *PodGroupManager) Unreserve(ctx context.Context, state *framework.CycleState, pod *corev1.Pod, nodeName string, handle framework.Handle, pluginName string) {
-	if !util.IsPodNeedGang(pod) {
+	if !pgMgr.IsPodNeedGang(pod) {
 		return
 	}
 	gang := pgMgr.GetGangByPod(pod)
@@ -370,7 +410,11 @@ func (pgMgr *PodGroupManager) Unreserve(ctx context.Context, state *framework.Cy
 		return
 	}
 	// first delete the pod from gang's waitingFroBindChildren map
-	gang.delAssumedPod(pod)
+	timeout := gang.delAssumedPod(pod)
+	if timeout && gang.getGangTimeoutAction() == extension.GangTimeoutActionRequeue {
+		klog.V(4).Infof("Pod %q of gang %q times out in Permit, requeue it without rejecting the gang group", klog.KObj(pod), gang.Name)
+		return
+	}
 
 	// TODO we should record failed message when current pod is the first failed pod of gang, now we just let it go, so quick fail is not supported
 
@@ -396,7 +440,7 @@ func (pgMgr *PodGroupManager) rejectGangGroupById(handle framework.Handle, plugi
 func (pgMgr *PodGroupManager) rejectGangGroup(handle framework.Handle, gangSet sets.Set[string], message string) {
 	if handle != nil {
 		handle.IterateOverWaitingPods(func(waitingPod framework.WaitingPod) {
-			waitingGangId := util.GetId(waitingPod.GetPod().Namespace, util.GetGangNameByPod(waitingPod.GetPod()))
+			waitingGangId := util.GetId(waitingPod.GetPod().Namespace, pgMgr.GetGangNameByPod(waitingPod.GetPod()))
 			if gangSet.Has(waitingGangId) {
 				klog.V(1).InfoS("GangGroup gets rejected due to",
 					"waitingGang", waitingGangId,
@@ -411,7 +455,7 @@ func (pgMgr *PodGroupManager) rejectGangGroup(handle framework.Handle, gangSet s
 
 // PostBind updates a PodGroup's status.
 func (pgMgr *PodGroupManager) PostBind(ctx context.Context, pod *corev1.Pod, nodeName string) {
-	if !util.IsPodNeedGang(pod) {
+	if !pgMgr.IsPodNeedGang(pod) {
 		return
 	}
 	gang := pgMgr.GetGangByPod(pod)
@@ -431,9 +475,14 @@ func (pgMgr *PodGroupManager) AllowGangGroup(pod *corev1.Pod, handle framework.H
 	}
 
 	gangSlices := gang.getGangGroup()
+	for _, gangIdTmp := range gangSlices {
+		if gangTmp := pgMgr.cache.getGangFromCacheByGangId(gangIdTmp, false); gangTmp != nil {
+			gangTmp.resetSchedulingFailures()
+		}
+	}
 
 	handle.IterateOverWaitingPods(func(waitingPod framework.WaitingPod) {
-		podGangId := util.GetId(waitingPod.GetPod().Namespace, util.GetGangNameByPod(waitingPod.GetPod()))
+		podGangId := util.GetId(waitingPod.GetPod().Namespace, pgMgr.GetGangNameByPod(waitingPod.GetPod()))
 		for _, gangIdTmp := range gangSlices {
 			if podGangId == gangIdTmp {
 				klog.V(4).InfoS("Permit allows pod from gang", "gang", podGangId, "pod", klog.KObj(waitingPod.GetPod()))
@@ -448,7 +497,7 @@ func (pgMgr *PodGroupManager) AllowGangGroup(pod *corev1.Pod, handle framework.H
 }
 
 func (pgMgr *PodGroupManager) GetGangByPod(pod *corev1.Pod) *Gang {
-	gangName := util.GetGangNameByPod(pod)
+	gangName := pgMgr.GetGangNameByPod(pod)
 	if gangName == "" {
 		return nil
 	}
@@ -472,19 +521,45 @@ func (pgMgr *PodGroupManager) GetGangSummary(gangId string) (*GangSummary, bool)
 	if gang == nil {
 		return nil, false
 	}
-	return gang.GetGangSummary(), true
+	return pgMgr.fillGangGroupMembers(gang.GetGangSummary()), true
 }
 
 func (pgMgr *PodGroupManager) GetGangSummaries() map[string]*GangSummary {
 	result := make(map[string]*GangSummary)
 	allGangs := pgMgr.cache.getAllGangsFromCache()
 	for gangName, gang := range allGangs {
-		result[gangName] = gang.GetGangSummary()
+		result[gangName] = pgMgr.fillGangGroupMembers(gang.GetGangSummary())
 	}
 
 	return result
 }
 
+// fillGangGroupMembers fills the satisfaction of each member gang if the gang belongs to a gang group of several gangs.
+func (pgMgr *PodGroupManager) fillGangGroupMembers(gangSummary *GangSummary) *GangSummary {
+	if len(gangSummary.GangGroup) <= 1 {
+		return gangSummary
+	}
+	for _, gangID := range gangSummary.GangGroup {
+		member := pgMgr.cache.getGangFromCacheByGangId(gangID, false)
+		if member == nil {
+			gangSummary.GangGroupMembers = append(gangSummary.GangGroupMembers, &GangGroupMemberSummary{Name: gangID})
+			continue
+		}
+		gangSummary.GangGroupMembers = append(gangSummary.GangGroupMembers, member.getGangGroupMemberSummary())
+	}
+	return gangSummary
+}
+
+// GetGangNameByPod returns the gang name of the pod, including the gang derived from the controller of the pod.
+func (pgMgr *PodGroupManager) GetGangNameByPod(pod *corev1.Pod) string {
+	return pgMgr.cache.getGangNameByPod(pod)
+}
+
+// IsPodNeedGang returns whether the pod belongs to a gang, including the gang derived from the controller of the pod.
+func (pgMgr *PodGroupManager) IsPodNeedGang(pod *corev1.Pod) bool {
+	return pgMgr.GetGangNameByPod(pod) != ""
+}
+
 func (pgMgr *PodGroupManager) GetBoundPodNumber(gangId string) int32 {
 	gang := pgMgr.cache.getGangFromCacheByGangId(gangId, false)
 	if gang == nil {
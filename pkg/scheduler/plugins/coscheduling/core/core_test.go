@@ -27,6 +27,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/informers"
 	clientsetfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
 	st "k8s.io/kubernetes/pkg/scheduler/testing"
 
 	"github.com/koordinator-sh/koordinator/apis/extension"
@@ -467,3 +468,187 @@ func TestPermit(t *testing.T) {
 		})
 	}
 }
+
+func TestPlugin_PreEnqueueGangGroupMemberMin(t *testing.T) {
+	mgr := NewManagerForTest().pgMgr
+	createdTime := time.Now()
+	// the worker gang requires 2 pods and the ps gang requires 1 pod
+	workerPg := makePg("worker", "default", 2, &createdTime, nil)
+	psPg := makePg("ps", "default", 1, &createdTime, nil)
+	for _, pg := range []*v1alpha1.PodGroup{workerPg, psPg} {
+		pg.Annotations = map[string]string{extension.AnnotationGangGroups: "[\"default/worker\",\"default/ps\"]"}
+		mgr.cache.onPodGroupAdd(pg)
+	}
+
+	worker1 := st.MakePod().Name("worker-1").UID("worker-1").Namespace("default").Label(v1alpha1.PodGroupLabel, "worker").Obj()
+	worker2 := st.MakePod().Name("worker-2").UID("worker-2").Namespace("default").Label(v1alpha1.PodGroupLabel, "worker").Obj()
+	ps1 := st.MakePod().Name("ps-1").UID("ps-1").Namespace("default").Label(v1alpha1.PodGroupLabel, "ps").Obj()
+	mgr.cache.onPodAdd(worker1)
+	mgr.cache.onPodAdd(ps1)
+
+	// the ps gang collects its min, but the worker gang doesn't
+	err := mgr.PreEnqueue(context.TODO(), ps1)
+	assert.EqualError(t, err, "gangGroup [default/worker default/ps] basic check: memberGangs [default/worker] child pod not collect enough, "+
+		"current gang: default/ps, podName: default/ps-1")
+
+	summary, ok := mgr.GetGangSummary("default/ps")
+	assert.True(t, ok)
+	assert.Equal(t, []*GangGroupMemberSummary{
+		{Name: "default/worker", Exists: true, MinRequiredNumber: 2, ChildrenNum: 1},
+		{Name: "default/ps", Exists: true, MinRequiredNumber: 1, ChildrenNum: 1, ChildrenNumSatisfied: true},
+	}, summary.GangGroupMembers)
+
+	// both gangs collect their own min
+	mgr.cache.onPodAdd(worker2)
+	assert.NoError(t, mgr.PreEnqueue(context.TODO(), ps1))
+	summary, ok = mgr.GetGangSummary("default/worker")
+	assert.True(t, ok)
+	assert.Equal(t, 2, len(summary.GangGroupMembers))
+	for _, member := range summary.GangGroupMembers {
+		assert.True(t, member.ChildrenNumSatisfied, member.Name)
+	}
+}
+
+type fakeWaitingPod struct {
+	framework.WaitingPod
+	pod      *corev1.Pod
+	rejected bool
+}
+
+func (w *fakeWaitingPod) GetPod() *corev1.Pod {
+	return w.pod
+}
+
+func (w *fakeWaitingPod) Reject(pluginName, msg string) {
+	w.rejected = true
+}
+
+type fakeWaitingPodsHandle struct {
+	framework.Handle
+	waitingPods []*fakeWaitingPod
+}
+
+func (h *fakeWaitingPodsHandle) IterateOverWaitingPods(callback func(framework.WaitingPod)) {
+	for _, waitingPod := range h.waitingPods {
+		callback(waitingPod)
+	}
+}
+
+func TestUnreserveTimeoutAction(t *testing.T) {
+	preTimeNowFn := timeNowFn
+	defer func() {
+		timeNowFn = preTimeNowFn
+	}()
+	now := time.Now()
+	timeNowFn = func() time.Time {
+		return now
+	}
+
+	tests := []struct {
+		name          string
+		timeoutAction string
+		elapsed       time.Duration
+		wantRejected  bool
+	}{
+		{
+			name:         "reject the gang group on timeout by default",
+			elapsed:      10 * time.Second,
+			wantRejected: true,
+		},
+		{
+			name:          "requeue the timed-out pod only",
+			timeoutAction: extension.GangTimeoutActionRequeue,
+			elapsed:       10 * time.Second,
+			wantRejected:  false,
+		},
+		{
+			name:          "reject the gang group if the pod is unreserved before timeout",
+			timeoutAction: extension.GangTimeoutActionRequeue,
+			elapsed:       5 * time.Second,
+			wantRejected:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = time.Now()
+			mgr := NewManagerForTest().pgMgr
+			pg := makePg("gangA", "default", 3, nil, nil)
+			if tt.timeoutAction != "" {
+				pg.Annotations = map[string]string{extension.AnnotationGangTimeoutAction: tt.timeoutAction}
+			}
+			mgr.cache.onPodGroupAdd(pg)
+			gang := mgr.cache.getGangFromCacheByGangId("default/gangA", false)
+			assert.NotNil(t, gang)
+			wantTimeoutAction := tt.timeoutAction
+			if wantTimeoutAction == "" {
+				wantTimeoutAction = extension.GangTimeoutActionReject
+			}
+			gangSummary, ok := mgr.GetGangSummary("default/gangA")
+			assert.True(t, ok)
+			assert.Equal(t, wantTimeoutAction, gangSummary.TimeoutAction)
+
+			pod1 := st.MakePod().Name("pod1").UID("pod1").Namespace("default").Label(v1alpha1.PodGroupLabel, "gangA").Obj()
+			pod2 := st.MakePod().Name("pod2").UID("pod2").Namespace("default").Label(v1alpha1.PodGroupLabel, "gangA").Obj()
+			ctx := context.TODO()
+			for _, pod := range []*corev1.Pod{pod1, pod2} {
+				mgr.cache.onPodAdd(pod)
+				_, status := mgr.Permit(ctx, pod)
+				assert.Equal(t, Wait, status)
+			}
+			handle := &fakeWaitingPodsHandle{waitingPods: []*fakeWaitingPod{{pod: pod2}}}
+
+			now = now.Add(tt.elapsed)
+			mgr.Unreserve(ctx, nil, pod1, "", handle, "Coscheduling")
+			assert.Equal(t, tt.wantRejected, handle.waitingPods[0].rejected)
+			assert.Equal(t, 1, gang.getGangWaitingPods())
+		})
+	}
+}
+
+func TestGangPhaseTransitionTime(t *testing.T) {
+	preTimeNowFn := timeNowFn
+	defer func() {
+		timeNowFn = preTimeNowFn
+	}()
+	now := time.Now()
+	timeNowFn = func() time.Time {
+		return now
+	}
+
+	mgr := NewManagerForTest().pgMgr
+	mgr.cache.onPodGroupAdd(makePg("gangA", "default", 2, nil, nil))
+	pod1 := st.MakePod().Name("pod1").UID("pod1").Namespace("default").Label(v1alpha1.PodGroupLabel, "gangA").Obj()
+	pod2 := st.MakePod().Name("pod2").UID("pod2").Namespace("default").Label(v1alpha1.PodGroupLabel, "gangA").Obj()
+	mgr.cache.onPodAdd(pod1)
+	mgr.cache.onPodAdd(pod2)
+	// the pending pods which never pass the Permit are not recorded
+	gangSummary, _ := mgr.GetGangSummary("default/gangA")
+	assert.Nil(t, gangSummary.ChildrenPhaseTransitionTime)
+
+	ctx := context.TODO()
+	waitingTime := now
+	mgr.Permit(ctx, pod1)
+	now = now.Add(time.Second)
+	mgr.Permit(ctx, pod2)
+	gangSummary, _ = mgr.GetGangSummary("default/gangA")
+	assert.Equal(t, map[string]time.Time{"default/pod1": waitingTime, "default/pod2": now}, gangSummary.ChildrenPhaseTransitionTime)
+
+	// pod2 is unreserved back to pending, and pod1 is bound
+	now = now.Add(time.Second)
+	mgr.Unreserve(ctx, nil, pod2, "", nil, "Coscheduling")
+	unreservedTime := now
+	now = now.Add(time.Second)
+	boundPod1 := pod1.DeepCopy()
+	boundPod1.Spec.NodeName = "node1"
+	mgr.PostBind(ctx, boundPod1, "node1")
+	// the bound pod updated later doesn't change the transition time
+	boundTime := now
+	now = now.Add(time.Second)
+	mgr.cache.onPodUpdate(pod1, boundPod1)
+	gangSummary, _ = mgr.GetGangSummary("default/gangA")
+	assert.Equal(t, map[string]time.Time{"default/pod1": boundTime, "default/pod2": unreservedTime}, gangSummary.ChildrenPhaseTransitionTime)
+
+	mgr.cache.onPodDelete(pod2)
+	gangSummary, _ = mgr.GetGangSummary("default/gangA")
+	assert.Equal(t, map[string]time.Time{"default/pod1": boundTime}, gangSummary.ChildrenPhaseTransitionTime)
+}
@@ -43,6 +43,7 @@ var (
 const (
 	GangFromPodGroupCrd   string = "GangFromPodGroupCrd"
 	GangFromPodAnnotation string = "GangFromPodAnnotation"
+	GangFromOwner         string = "GangFromOwner"
 )
 
 // Gang  basic podGroup info recorded in gangCache:
@@ -72,6 +73,17 @@ type Gang struct {
 	// once-satisfied, once gang is satisfied, no need to consider any status pods
 	GangMatchPolicy string
 
+	// reject-the-gang-group or requeue-the-pod when a waiting pod times out in Permit stage
+	TimeoutAction string
+	// the last time the children moved between pending, waiting and bound in the scheduling cycles,
+	// it's also used to tell whether the waiting pod is unreserved due to timeout
+	phaseTransitionTime map[string]time.Time
+
+	// the consecutive failed scheduling cycles of the children since the gang group was allowed last time
+	SchedulingFailures int
+	// the gang group held the waiting pods but failed too many scheduling cycles, its waiting pods were released
+	Deadlocked bool
+
 	GangFrom    string
 	HasGangInit bool
 
@@ -87,6 +99,7 @@ func NewGang(gangName string) *Gang {
 		GangGroup:              []string{gangName},
 		Mode:                   extension.GangModeStrict,
 		GangMatchPolicy:        extension.GangMatchPolicyOnceSatisfied,
+		TimeoutAction:          extension.GangTimeoutActionReject,
 		Children:               make(map[string]*v1.Pod),
 		PendingChildren:        make(map[string]*v1.Pod),
 		WaitingForBindChildren: make(map[string]*v1.Pod),
@@ -124,6 +137,41 @@ func (gang *Gang) tryInitByPodConfig(pod *v1.Pod, args *config.CoschedulingArgs)
 	}
 	gang.TotalChildrenNum = int(totalChildrenNum)
 
+	gang.initByPodAnnotations(pod, args)
+	gang.GangFrom = GangFromPodAnnotation
+
+	gang.HasGangInit = true
+
+	klog.Infof("TryInitByPodConfig done, gangName: %v, minRequiredNumber: %v, totalChildrenNum: %v, "+
+		"mode: %v, waitTime: %v, groupSlice: %v", gang.Name, gang.MinRequiredNumber, gang.TotalChildrenNum,
+		gang.Mode, gang.WaitTime, gang.GangGroup)
+	return true
+}
+
+// tryInitByOwner initializes the gang derived from the controller of the pod with the numbers resolved from
+// the controller, the rest are still from the pod's annotations.
+func (gang *Gang) tryInitByOwner(pod *v1.Pod, minRequiredNumber, totalChildrenNum int, args *config.CoschedulingArgs) bool {
+	gang.lock.Lock()
+	defer gang.lock.Unlock()
+	if gang.HasGangInit {
+		return false
+	}
+	gang.MinRequiredNumber = minRequiredNumber
+	gang.TotalChildrenNum = totalChildrenNum
+
+	gang.initByPodAnnotations(pod, args)
+	gang.GangFrom = GangFromOwner
+
+	gang.HasGangInit = true
+
+	klog.Infof("TryInitByOwner done, gangName: %v, minRequiredNumber: %v, totalChildrenNum: %v, "+
+		"mode: %v, waitTime: %v, groupSlice: %v", gang.Name, gang.MinRequiredNumber, gang.TotalChildrenNum,
+		gang.Mode, gang.WaitTime, gang.GangGroup)
+	return true
+}
+
+// initByPodAnnotations initializes the gang except the numbers by the pod's annotations, the lock must be held.
+func (gang *Gang) initByPodAnnotations(pod *v1.Pod, args *config.CoschedulingArgs) {
 	mode := pod.Annotations[extension.AnnotationGangMode]
 	if mode != extension.GangModeStrict && mode != extension.GangModeNonStrict {
 		klog.V(4).Infof("pod's annotation GangModeAnnotation illegal, gangName: %v, value: %v",
@@ -141,6 +189,14 @@ func (gang *Gang) tryInitByPodConfig(pod *v1.Pod, args *config.CoschedulingArgs)
 	}
 	gang.GangMatchPolicy = matchPolicy
 
+	timeoutAction := pod.Annotations[extension.AnnotationGangTimeoutAction]
+	if timeoutAction != extension.GangTimeoutActionReject && timeoutAction != extension.GangTimeoutActionRequeue {
+		klog.V(4).Infof("pod's annotation AnnotationGangTimeoutAction illegal, gangName: %v, value: %v",
+			gang.Name, timeoutAction)
+		timeoutAction = extension.GangTimeoutActionReject
+	}
+	gang.TimeoutAction = timeoutAction
+
 	// here we assume that Coscheduling's CreateTime equal with the pod's CreateTime
 	gang.CreateTime = pod.CreationTimestamp.Time
 
@@ -162,14 +218,6 @@ func (gang *Gang) tryInitByPodConfig(pod *v1.Pod, args *config.CoschedulingArgs)
 	}
 	gang.GangGroup = groupSlice
 	gang.GangGroupId = util.GetGangGroupId(groupSlice)
-	gang.GangFrom = GangFromPodAnnotation
-
-	gang.HasGangInit = true
-
-	klog.Infof("TryInitByPodConfig done, gangName: %v, minRequiredNumber: %v, totalChildrenNum: %v, "+
-		"mode: %v, waitTime: %v, groupSlice: %v", gang.Name, gang.MinRequiredNumber, gang.TotalChildrenNum,
-		gang.Mode, gang.WaitTime, gang.GangGroup)
-	return true
 }
 
 func (gang *Gang) tryInitByPodGroup(pg *v1alpha1.PodGroup, args *config.CoschedulingArgs) {
@@ -207,6 +255,14 @@ func (gang *Gang) tryInitByPodGroup(pg *v1alpha1.PodGroup, args *config.Coschedu
 	}
 	gang.GangMatchPolicy = matchPolicy
 
+	timeoutAction := pg.Annotations[extension.AnnotationGangTimeoutAction]
+	if timeoutAction != extension.GangTimeoutActionReject && timeoutAction != extension.GangTimeoutActionRequeue {
+		klog.V(4).Infof("podGroup's annotation AnnotationGangTimeoutAction illegal, gangName: %v, value: %v",
+			gang.Name, timeoutAction)
+		timeoutAction = extension.GangTimeoutActionReject
+	}
+	gang.TimeoutAction = timeoutAction
+
 	// here we assume that Coscheduling's CreateTime equal with the podGroup CRD CreateTime
 	gang.CreateTime = pg.CreationTimestamp.Time
 
@@ -259,12 +315,13 @@ func (gang *Gang) deletePod(pod *v1.Pod) bool {
 	delete(gang.PendingChildren, podId)
 	gang.GangGroupInfo.DeleteIfRepresentative(pod, ReasonPodDeleted)
 	delete(gang.WaitingForBindChildren, podId)
+	delete(gang.phaseTransitionTime, podId)
 	if len(gang.WaitingForBindChildren) == 0 {
 		gang.GangGroupInfo.RemoveWaitingGang(gang.Name)
 	}
 
 	delete(gang.BoundChildren, podId)
-	if gang.GangFrom == GangFromPodAnnotation {
+	if gang.GangFrom == GangFromPodAnnotation || gang.GangFrom == GangFromOwner {
 		if len(gang.Children) == 0 {
 			return true
 		}
@@ -272,6 +329,13 @@ func (gang *Gang) deletePod(pod *v1.Pod) bool {
 	return false
 }
 
+func (gang *Gang) hasGangInit() bool {
+	gang.lock.Lock()
+	defer gang.lock.Unlock()
+
+	return gang.HasGangInit
+}
+
 func (gang *Gang) getGangWaitTime() time.Duration {
 	gang.lock.Lock()
 	defer gang.lock.Unlock()
@@ -320,6 +384,13 @@ func (gang *Gang) getGangMatchPolicy() string {
 	return gang.GangMatchPolicy
 }
 
+func (gang *Gang) getGangTimeoutAction() string {
+	gang.lock.Lock()
+	defer gang.lock.Unlock()
+
+	return gang.TimeoutAction
+}
+
 func (gang *Gang) getGangAssumedPods() int {
 	gang.lock.Lock()
 	defer gang.lock.Unlock()
@@ -377,19 +448,25 @@ func (gang *Gang) addAssumedPod(pod *v1.Pod) {
 	podId := util.GetId(pod.Namespace, pod.Name)
 	if _, ok := gang.WaitingForBindChildren[podId]; !ok {
 		gang.WaitingForBindChildren[podId] = pod
+		gang.recordPhaseTransition(podId)
 		klog.Infof("AddAssumedPod, gangName: %v, podName: %v", gang.Name, podId)
 	}
 	delete(gang.PendingChildren, podId)
 }
 
-func (gang *Gang) delAssumedPod(pod *v1.Pod) {
+// delAssumedPod returns true if the pod has waited for WaitTime since it was assumed, i.e. it's unreserved due to timeout.
+func (gang *Gang) delAssumedPod(pod *v1.Pod) (timeout bool) {
 	gang.lock.Lock()
 	defer gang.lock.Unlock()
 
 	podId := util.GetId(pod.Namespace, pod.Name)
 	if _, ok := gang.WaitingForBindChildren[podId]; ok {
+		if assumedTime, ok := gang.phaseTransitionTime[podId]; ok {
+			timeout = gang.WaitTime > 0 && timeNowFn().Sub(assumedTime) >= gang.WaitTime
+		}
 		delete(gang.WaitingForBindChildren, podId)
 		gang.PendingChildren[podId] = pod
+		gang.recordPhaseTransition(podId)
 		if len(gang.WaitingForBindChildren) == 0 {
 			gang.GangGroupInfo.RemoveWaitingGang(gang.Name)
 		}
@@ -397,6 +474,14 @@ func (gang *Gang) delAssumedPod(pod *v1.Pod) {
 	}
 }
 
+// recordPhaseTransition must be called with the gang lock held.
+func (gang *Gang) recordPhaseTransition(podId string) {
+	if gang.phaseTransitionTime == nil {
+		gang.phaseTransitionTime = make(map[string]time.Time)
+	}
+	gang.phaseTransitionTime[podId] = timeNowFn()
+}
+
 func (gang *Gang) getChildrenFromGang() (children []*v1.Pod) {
 	gang.lock.Lock()
 	defer gang.lock.Unlock()
@@ -435,6 +520,7 @@ func (gang *Gang) addBoundPod(pod *v1.Pod) {
 	defer gang.lock.Unlock()
 
 	podId := util.GetId(pod.Namespace, pod.Name)
+	_, isBoundBefore := gang.BoundChildren[podId]
 	delete(gang.WaitingForBindChildren, podId)
 	if len(gang.WaitingForBindChildren) == 0 {
 		gang.GangGroupInfo.RemoveWaitingGang(gang.Name)
@@ -442,6 +528,10 @@ func (gang *Gang) addBoundPod(pod *v1.Pod) {
 	delete(gang.PendingChildren, podId)
 	gang.GangGroupInfo.DeleteIfRepresentative(pod, ReasonPodBound)
 	gang.BoundChildren[podId] = pod
+	// only the pods passing the Permit of the scheduler are tracked
+	if _, ok := gang.phaseTransitionTime[podId]; ok && !isBoundBefore {
+		gang.recordPhaseTransition(podId)
+	}
 
 	klog.Infof("AddBoundPod, gangName: %v, podName: %v", gang.Name, podId)
 	if !gang.GangGroupInfo.isGangOnceResourceSatisfied() {
@@ -468,6 +558,41 @@ func (gang *Gang) removeWaitingGang() {
 	gang.GangGroupInfo.RemoveWaitingGang(gang.Name)
 }
 
+// recordSchedulingFailure returns the consecutive failed scheduling cycles of the gang.
+func (gang *Gang) recordSchedulingFailure() int {
+	gang.lock.Lock()
+	defer gang.lock.Unlock()
+
+	gang.SchedulingFailures++
+	return gang.SchedulingFailures
+}
+
+// setDeadlocked marks the gang deadlocked, and the gang has another SchedulingFailures round after its waiting
+// pods are released.
+func (gang *Gang) setDeadlocked() {
+	gang.lock.Lock()
+	defer gang.lock.Unlock()
+
+	gang.Deadlocked = true
+	gang.SchedulingFailures = 0
+	klog.Infof("Gang Deadlocked, gangName: %v", gang.Name)
+}
+
+func (gang *Gang) resetSchedulingFailures() {
+	gang.lock.Lock()
+	defer gang.lock.Unlock()
+
+	gang.SchedulingFailures = 0
+	gang.Deadlocked = false
+}
+
+func (gang *Gang) isGangGroupWaiting() bool {
+	gang.lock.Lock()
+	defer gang.lock.Unlock()
+
+	return gang.GangGroupInfo.isWaiting()
+}
+
 func (gang *Gang) isGangValidForPermit() bool {
 	gang.lock.Lock()
 	defer gang.lock.Unlock()
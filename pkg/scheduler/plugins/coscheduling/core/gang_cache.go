@@ -17,14 +17,22 @@ limitations under the License.
 package core
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/go-logr/logr"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	listerv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
 
+	"github.com/koordinator-sh/koordinator/apis/extension"
 	"github.com/koordinator-sh/koordinator/apis/thirdparty/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
 	pgclientset "github.com/koordinator-sh/koordinator/apis/thirdparty/scheduler-plugins/pkg/generated/clientset/versioned"
 	pglister "github.com/koordinator-sh/koordinator/apis/thirdparty/scheduler-plugins/pkg/generated/listers/scheduling/v1alpha1"
@@ -43,6 +51,8 @@ type GangCache struct {
 	pgLister         pglister.PodGroupLister
 	pgClient         pgclientset.Interface
 	handle           framework.Handle
+	// ownerListers list the controllers which the gangs are derived from by the resource, empty if no GangOwnerKinds
+	ownerListers map[schema.GroupVersionResource]cache.GenericLister
 }
 
 func NewGangCache(args *config.CoschedulingArgs, podLister listerv1.PodLister, pgLister pglister.PodGroupLister, client pgclientset.Interface, handle framework.Handle) *GangCache {
@@ -55,9 +65,23 @@ func NewGangCache(args *config.CoschedulingArgs, podLister listerv1.PodLister, p
 		pgLister:         pgLister,
 		pgClient:         client,
 		handle:           handle,
+		ownerListers:     make(map[schema.GroupVersionResource]cache.GenericLister),
 	}
 }
 
+// getGangOwnerKinds returns the kinds of the controllers which the gangs of their pods are derived from.
+func (gangCache *GangCache) getGangOwnerKinds() []config.GangOwnerKind {
+	if gangCache.pluginArgs == nil {
+		return nil
+	}
+	return gangCache.pluginArgs.GangOwnerKinds
+}
+
+// getGangNameByPod returns the gang name of the pod, including the gang derived from the controller of the pod.
+func (gangCache *GangCache) getGangNameByPod(pod *v1.Pod) string {
+	return util.GetGangNameByPodWithOwner(pod, gangCache.getGangOwnerKinds())
+}
+
 func (gangCache *GangCache) getGangGroupInfo(gangGroupId string, gangGroup []string, createIfNotExist bool) *GangGroupInfo {
 	gangCache.lock.Lock()
 	defer gangCache.lock.Unlock()
@@ -127,7 +151,7 @@ func (gangCache *GangCache) onPodAddInternal(obj interface{}, action string) {
 		return
 	}
 
-	gangName := util.GetGangNameByPod(pod)
+	gangName := gangCache.getGangNameByPod(pod)
 	if gangName == "" {
 		return
 	}
@@ -138,7 +162,9 @@ func (gangCache *GangCache) onPodAddInternal(obj interface{}, action string) {
 
 	// the gang is created in Annotation way
 	if pod.Labels[v1alpha1.PodGroupLabel] == "" {
-		gang.tryInitByPodConfig(pod, gangCache.pluginArgs)
+		if !gangCache.tryInitByOwner(gang, pod) {
+			gang.tryInitByPodConfig(pod, gangCache.pluginArgs)
+		}
 
 		gangGroup := gang.getGangGroup()
 		gangGroupId := util.GetGangGroupId(gangGroup)
@@ -168,13 +194,91 @@ func (gangCache *GangCache) onPodAddInternal(obj interface{}, action string) {
 	klog.Infof("watch pod %v, Name:%v, pgLabel:%v", action, pod.Name, pod.Labels[v1alpha1.PodGroupLabel])
 }
 
+// tryInitByOwner initializes the gang derived from the controller of the pod, it returns false if the gang isn't
+// derived from the controller. The controller is got from the informer until the gang is initialized, after that
+// the numbers are cached by the gang. If the controller isn't in the informer yet, the gang is initialized by the
+// following pods of the gang.
+func (gangCache *GangCache) tryInitByOwner(gang *Gang, pod *v1.Pod) bool {
+	// nolint:staticcheck // SA1019: extension.LabelLightweightCoschedulingPodGroupName is deprecated
+	if extension.GetGangName(pod) != "" || pod.Labels[extension.LabelLightweightCoschedulingPodGroupName] != "" {
+		return false
+	}
+	ownerRef, ownerKind := util.GetGangOwner(pod, gangCache.getGangOwnerKinds())
+	if ownerRef == nil {
+		return false
+	}
+	if gang.hasGangInit() {
+		return true
+	}
+
+	minRequiredNumber, totalChildrenNum, err := gangCache.getGangNumFromOwner(pod, ownerRef, ownerKind)
+	if err != nil {
+		klog.ErrorS(err, "Failed to get the gang numbers from the owner", "gang", gang.Name, "pod", klog.KObj(pod))
+		return true
+	}
+	gang.tryInitByOwner(pod, minRequiredNumber, totalChildrenNum, gangCache.pluginArgs)
+	return true
+}
+
+// getGangNumFromOwner gets the min and total number of the gang from the controller of the pod. The total number is
+// the replicas of the controller, the min number is from the annotation of the pod or the controller, and falls back
+// to the total number.
+func (gangCache *GangCache) getGangNumFromOwner(pod *v1.Pod, ownerRef *metav1.OwnerReference,
+	ownerKind *config.GangOwnerKind) (minRequiredNumber, totalChildrenNum int, err error) {
+	gvr := schema.GroupVersionResource{Group: ownerKind.Group, Version: ownerKind.Version, Resource: ownerKind.Resource}
+	lister, ok := gangCache.ownerListers[gvr]
+	if !ok {
+		return 0, 0, fmt.Errorf("no lister to get the owner %v %v", ownerRef.Kind, ownerRef.Name)
+	}
+	obj, err := lister.ByNamespace(pod.Namespace).Get(ownerRef.Name)
+	if err != nil {
+		return 0, 0, err
+	}
+	owner, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return 0, 0, fmt.Errorf("owner %v %v is not unstructured, got %T", ownerRef.Kind, ownerRef.Name, obj)
+	}
+	if owner.GetUID() != ownerRef.UID {
+		return 0, 0, fmt.Errorf("owner %v %v has been recreated", ownerRef.Kind, ownerRef.Name)
+	}
+
+	replicasPath := ownerKind.ReplicasPath
+	if replicasPath == "" {
+		replicasPath = "spec.replicas"
+	}
+	replicas, found, err := unstructured.NestedInt64(owner.Object, strings.Split(replicasPath, ".")...)
+	if err != nil || !found {
+		return 0, 0, fmt.Errorf("owner %v %v has no valid replicas at %v, err: %v", ownerRef.Kind, ownerRef.Name, replicasPath, err)
+	}
+	totalChildrenNum = int(replicas)
+
+	minRequiredNumber = totalChildrenNum
+	minNum, ok := pod.Annotations[extension.AnnotationGangMinNum]
+	if !ok {
+		minNum, ok = owner.GetAnnotations()[extension.AnnotationGangMinNum]
+	}
+	if ok {
+		num, err := strconv.ParseInt(minNum, 10, 32)
+		if err != nil {
+			return 0, 0, fmt.Errorf("annotation %v illegal, value: %v", extension.AnnotationGangMinNum, minNum)
+		}
+		minRequiredNumber = int(num)
+	}
+	if minRequiredNumber > totalChildrenNum {
+		klog.V(4).Infof("owner's replicas cannot less than minRequiredNumber, owner: %v, replicas: %v, minRequiredNumber: %v",
+			ownerRef.Name, totalChildrenNum, minRequiredNumber)
+		totalChildrenNum = minRequiredNumber
+	}
+	return minRequiredNumber, totalChildrenNum, nil
+}
+
 func (gangCache *GangCache) onPodUpdate(oldObj, newObj interface{}) {
 	pod, ok := newObj.(*v1.Pod)
 	if !ok {
 		return
 	}
 
-	gangName := util.GetGangNameByPod(pod)
+	gangName := gangCache.getGangNameByPod(pod)
 	if gangName == "" {
 		return
 	}
@@ -191,7 +295,7 @@ func (gangCache *GangCache) onPodDelete(obj interface{}) {
 	if !ok {
 		return
 	}
-	gangName := util.GetGangNameByPod(pod)
+	gangName := gangCache.getGangNameByPod(pod)
 	if gangName == "" {
 		return
 	}
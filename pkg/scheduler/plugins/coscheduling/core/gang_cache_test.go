@@ -25,6 +25,10 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/retry"
 	"k8s.io/utils/pointer"
 
@@ -105,6 +109,7 @@ func TestGangCache_OnPodAdd(t *testing.T) {
 					Mode:            extension.GangModeStrict,
 					GangFrom:        GangFromPodAnnotation,
 					GangMatchPolicy: extension.GangMatchPolicyOnceSatisfied,
+					TimeoutAction:   extension.GangTimeoutActionReject,
 					HasGangInit:     false,
 					Children: map[string]*corev1.Pod{
 						"default/crdPod": {
@@ -177,6 +182,7 @@ func TestGangCache_OnPodAdd(t *testing.T) {
 					HasGangInit:       true,
 					GangFrom:          GangFromPodAnnotation,
 					GangMatchPolicy:   extension.GangMatchPolicyOnceSatisfied,
+					TimeoutAction:     extension.GangTimeoutActionReject,
 					Children: map[string]*corev1.Pod{
 						"default/pod1": {
 							ObjectMeta: metav1.ObjectMeta{
@@ -291,6 +297,7 @@ func TestGangCache_OnPodAdd(t *testing.T) {
 					HasGangInit:       true,
 					GangFrom:          GangFromPodAnnotation,
 					GangMatchPolicy:   extension.GangMatchPolicyOnceSatisfied,
+					TimeoutAction:     extension.GangTimeoutActionReject,
 					Children: map[string]*corev1.Pod{
 						"default/pod1": {
 							ObjectMeta: metav1.ObjectMeta{
@@ -402,6 +409,7 @@ func TestGangCache_OnPodAdd(t *testing.T) {
 					HasGangInit:       true,
 					GangFrom:          GangFromPodAnnotation,
 					GangMatchPolicy:   extension.GangMatchPolicyOnceSatisfied,
+					TimeoutAction:     extension.GangTimeoutActionReject,
 					Children: map[string]*corev1.Pod{
 						"default/pod3": {
 							ObjectMeta: metav1.ObjectMeta{
@@ -503,6 +511,7 @@ func TestGangCache_OnPodAdd(t *testing.T) {
 					HasGangInit:       true,
 					GangFrom:          GangFromPodAnnotation,
 					GangMatchPolicy:   extension.GangMatchPolicyOnceSatisfied,
+					TimeoutAction:     extension.GangTimeoutActionReject,
 					Children: map[string]*corev1.Pod{
 						"default/pod5": {
 							ObjectMeta: metav1.ObjectMeta{
@@ -547,6 +556,7 @@ func TestGangCache_OnPodAdd(t *testing.T) {
 					HasGangInit:       true,
 					GangFrom:          GangFromPodAnnotation,
 					GangMatchPolicy:   extension.GangMatchPolicyOnceSatisfied,
+					TimeoutAction:     extension.GangTimeoutActionReject,
 					Children: map[string]*corev1.Pod{
 						"default/pod6": {
 							ObjectMeta: metav1.ObjectMeta{
@@ -685,6 +695,7 @@ func TestGangCache_OnPodUpdate(t *testing.T) {
 					CreateTime:        fakeTimeNowFn(),
 					Mode:              extension.GangModeNonStrict,
 					GangMatchPolicy:   extension.GangMatchPolicyOnceSatisfied,
+					TimeoutAction:     extension.GangTimeoutActionReject,
 					MinRequiredNumber: 2,
 					TotalChildrenNum:  2,
 					GangGroup:         []string{"default/ganga", "default/gangb"},
@@ -913,6 +924,7 @@ func TestGangCache_OnPodDelete(t *testing.T) {
 					HasGangInit:            true,
 					GangFrom:               GangFromPodGroupCrd,
 					GangMatchPolicy:        extension.GangMatchPolicyOnceSatisfied,
+					TimeoutAction:          extension.GangTimeoutActionReject,
 					Children:               map[string]*corev1.Pod{},
 					PendingChildren:        map[string]*corev1.Pod{},
 					WaitingForBindChildren: map[string]*corev1.Pod{},
@@ -1054,6 +1066,7 @@ func TestGangCache_OnPodGroupAdd(t *testing.T) {
 					HasGangInit:            true,
 					GangFrom:               GangFromPodGroupCrd,
 					GangMatchPolicy:        extension.GangMatchPolicyOnceSatisfied,
+					TimeoutAction:          extension.GangTimeoutActionReject,
 					PendingChildren:        map[string]*corev1.Pod{},
 					Children:               map[string]*corev1.Pod{},
 					WaitingForBindChildren: map[string]*corev1.Pod{},
@@ -1094,6 +1107,7 @@ func TestGangCache_OnPodGroupAdd(t *testing.T) {
 					HasGangInit:            true,
 					GangFrom:               GangFromPodGroupCrd,
 					GangMatchPolicy:        extension.GangMatchPolicyOnceSatisfied,
+					TimeoutAction:          extension.GangTimeoutActionReject,
 					PendingChildren:        map[string]*corev1.Pod{},
 					Children:               map[string]*corev1.Pod{},
 					WaitingForBindChildren: map[string]*corev1.Pod{},
@@ -1189,6 +1203,7 @@ func TestGangCache_OnGangDelete(t *testing.T) {
 		HasGangInit:       true,
 		GangFrom:          GangFromPodAnnotation,
 		GangMatchPolicy:   extension.GangMatchPolicyOnceSatisfied,
+		TimeoutAction:     extension.GangTimeoutActionReject,
 		Children: map[string]*corev1.Pod{
 			"default/pod1": {
 				ObjectMeta: metav1.ObjectMeta{
@@ -1468,3 +1483,124 @@ func TestOnPgAdd_OnPgDeleteWithGangGroupInfo(t *testing.T) {
 	gangCache.onPodGroupDelete(pgs[0])
 	assert.Equal(t, 0, len(gangCache.gangGroupInfoMap))
 }
+
+func TestGangCache_OnPodAddWithGangOwner(t *testing.T) {
+	ownerKind := config.GangOwnerKind{Group: "batch.example.com", Version: "v1", Kind: "TrainingJob", Resource: "trainingjobs"}
+
+	makeOwner := func(name string, replicas int64, annotations map[string]string) *unstructured.Unstructured {
+		owner := &unstructured.Unstructured{}
+		owner.SetAPIVersion("batch.example.com/v1")
+		owner.SetKind("TrainingJob")
+		owner.SetNamespace("default")
+		owner.SetName(name)
+		owner.SetUID(types.UID(name))
+		owner.SetAnnotations(annotations)
+		assert.NoError(t, unstructured.SetNestedField(owner.Object, replicas, "spec", "replicas"))
+		return owner
+	}
+	makePod := func(name, ownerName string, annotations map[string]string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "default",
+				Name:        name,
+				Annotations: annotations,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion: "batch.example.com/v1",
+						Kind:       "TrainingJob",
+						Name:       ownerName,
+						UID:        types.UID(ownerName),
+						Controller: pointer.Bool(true),
+					},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		pod          *corev1.Pod
+		wantGangFrom string
+		wantMinNum   int
+		wantTotalNum int
+		wantGangInit bool
+		wantWaitTime time.Duration
+	}{
+		{
+			name:         "min and total from the replicas of the owner",
+			pod:          makePod("pod1", "job1", nil),
+			wantGangFrom: GangFromOwner,
+			wantMinNum:   4,
+			wantTotalNum: 4,
+			wantGangInit: true,
+			wantWaitTime: 600 * time.Second,
+		},
+		{
+			name:         "min from the annotation of the owner",
+			pod:          makePod("pod1", "job2", map[string]string{extension.AnnotationGangWaitTime: "30s"}),
+			wantGangFrom: GangFromOwner,
+			wantMinNum:   2,
+			wantTotalNum: 4,
+			wantGangInit: true,
+			wantWaitTime: 30 * time.Second,
+		},
+		{
+			name:         "min from the annotation of the pod",
+			pod:          makePod("pod1", "job2", map[string]string{extension.AnnotationGangMinNum: "3"}),
+			wantGangFrom: GangFromOwner,
+			wantMinNum:   3,
+			wantTotalNum: 4,
+			wantGangInit: true,
+			wantWaitTime: 600 * time.Second,
+		},
+		{
+			name:         "owner not found",
+			pod:          makePod("pod1", "job3", nil),
+			wantGangFrom: GangFromPodAnnotation,
+		},
+		{
+			name:         "gang annotation takes precedence over the owner",
+			pod:          makePod("pod1", "job1", map[string]string{extension.AnnotationGangName: "job1", extension.AnnotationGangMinNum: "1"}),
+			wantGangFrom: GangFromPodAnnotation,
+			wantMinNum:   1,
+			wantTotalNum: 1,
+			wantGangInit: true,
+			wantWaitTime: 600 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gvr := schema.GroupVersionResource{Group: ownerKind.Group, Version: ownerKind.Version, Resource: ownerKind.Resource}
+			ownerIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+			assert.NoError(t, ownerIndexer.Add(makeOwner("job1", 4, nil)))
+			assert.NoError(t, ownerIndexer.Add(makeOwner("job2", 4, map[string]string{extension.AnnotationGangMinNum: "2"})))
+			pgClientSet := fakepgclientset.NewSimpleClientset()
+			pgInformerFactory := pgformers.NewSharedInformerFactory(pgClientSet, 0)
+			args := getTestDefaultCoschedulingArgs(t)
+			args.GangOwnerKinds = []config.GangOwnerKind{ownerKind}
+			gangCache := NewGangCache(args, nil, pgInformerFactory.Scheduling().V1alpha1().PodGroups().Lister(), pgClientSet, nil)
+			gangCache.ownerListers[gvr] = cache.NewGenericLister(ownerIndexer, gvr.GroupResource())
+
+			gangCache.onPodAdd(tt.pod)
+			if tt.wantGangFrom == GangFromOwner {
+				// the gang derived from the owner is only known by the gang cache configured with the owner kinds
+				assert.Empty(t, util.GetGangNameByPod(tt.pod))
+			}
+			gang := gangCache.getGangFromCacheByGangId(util.GetId(tt.pod.Namespace, gangCache.getGangNameByPod(tt.pod)), false)
+			assert.NotNil(t, gang)
+			summary := gang.GetGangSummary()
+			assert.Equal(t, tt.wantGangFrom, summary.GangFrom)
+			assert.Equal(t, tt.wantGangInit, summary.HasGangInit)
+			assert.Equal(t, tt.wantMinNum, summary.MinRequiredNumber)
+			assert.Equal(t, tt.wantTotalNum, summary.TotalChildrenNum)
+			assert.Equal(t, tt.wantWaitTime, summary.WaitTime)
+			assert.True(t, summary.Children.Has(util.GetId(tt.pod.Namespace, tt.pod.Name)))
+
+			// the gang derived from the owner is deleted with its last pod
+			gangCache.onPodDelete(tt.pod)
+			if tt.wantGangFrom == GangFromOwner {
+				assert.Nil(t, gangCache.getGangFromCacheByGangId(gang.Name, false))
+			}
+		})
+	}
+}
@@ -12,6 +12,7 @@ type GangSummary struct {
 	CreateTime             time.Time        `json:"createTime"`
 	Mode                   string           `json:"mode"`
 	GangMatchPolicy        string           `json:"gangMatchPolicy"`
+	TimeoutAction          string           `json:"timeoutAction"`
 	MinRequiredNumber      int              `json:"minRequiredNumber"`
 	TotalChildrenNum       int              `json:"totalChildrenNum"`
 	GangGroup              []string         `json:"gangGroup"`
@@ -23,6 +24,46 @@ type GangSummary struct {
 	GangGroupInfo          *GangGroupInfo   `json:"gangGroupInfo"`
 	GangFrom               string           `json:"gangFrom"`
 	HasGangInit            bool             `json:"hasGangInit"`
+	Deadlocked             bool             `json:"deadlocked"`
+	LastSchedulingFailures int              `json:"lastSchedulingFailures"`
+
+	// ChildrenPhaseTransitionTime is the last time each child moved between pending, waiting and bound,
+	// which helps find the straggler of the gang. Only the children passing the Permit are recorded.
+	ChildrenPhaseTransitionTime map[string]time.Time `json:"childrenPhaseTransitionTime,omitempty"`
+
+	// GangGroupMembers shows how far each member gang of the gang group is from its own min,
+	// which helps debug a gang group partially satisfied.
+	GangGroupMembers []*GangGroupMemberSummary `json:"gangGroupMembers,omitempty"`
+}
+
+// GangGroupMemberSummary is the satisfaction of a member gang of the gang group.
+type GangGroupMemberSummary struct {
+	Name                 string `json:"name"`
+	Exists               bool   `json:"exists"`
+	MinRequiredNumber    int    `json:"minRequiredNumber"`
+	ChildrenNum          int    `json:"childrenNum"`
+	WaitingForBindNum    int    `json:"waitingForBindNum"`
+	BoundNum             int    `json:"boundNum"`
+	ValidForPermit       bool   `json:"validForPermit"`
+	ChildrenNumSatisfied bool   `json:"childrenNumSatisfied"`
+}
+
+func (gang *Gang) getGangGroupMemberSummary() *GangGroupMemberSummary {
+	validForPermit := gang.isGangValidForPermit()
+
+	gang.lock.Lock()
+	defer gang.lock.Unlock()
+
+	return &GangGroupMemberSummary{
+		Name:                 gang.Name,
+		Exists:               true,
+		MinRequiredNumber:    gang.MinRequiredNumber,
+		ChildrenNum:          len(gang.Children),
+		WaitingForBindNum:    len(gang.WaitingForBindChildren),
+		BoundNum:             len(gang.BoundChildren),
+		ValidForPermit:       validForPermit,
+		ChildrenNumSatisfied: len(gang.Children) >= gang.MinRequiredNumber,
+	}
 }
 
 func (gang *Gang) GetGangSummary() *GangSummary {
@@ -45,12 +86,15 @@ func (gang *Gang) GetGangSummary() *GangSummary {
 	gangSummary.CreateTime = gang.CreateTime
 	gangSummary.Mode = gang.Mode
 	gangSummary.GangMatchPolicy = gang.GangMatchPolicy
+	gangSummary.TimeoutAction = gang.TimeoutAction
 	gangSummary.MinRequiredNumber = gang.MinRequiredNumber
 	gangSummary.TotalChildrenNum = gang.TotalChildrenNum
 	gangSummary.OnceResourceSatisfied = gang.GangGroupInfo.isGangOnceResourceSatisfied()
 	gangSummary.GangGroupInfo = gang.GangGroupInfo
 	gangSummary.GangFrom = gang.GangFrom
 	gangSummary.HasGangInit = gang.HasGangInit
+	gangSummary.Deadlocked = gang.Deadlocked
+	gangSummary.LastSchedulingFailures = gang.SchedulingFailures
 	gangSummary.GangGroup = append(gangSummary.GangGroup, gang.GangGroup...)
 
 	for podName := range gang.Children {
@@ -65,6 +109,12 @@ func (gang *Gang) GetGangSummary() *GangSummary {
 	for podName := range gang.BoundChildren {
 		gangSummary.BoundChildren.Insert(podName)
 	}
+	if len(gang.phaseTransitionTime) > 0 {
+		gangSummary.ChildrenPhaseTransitionTime = make(map[string]time.Time, len(gang.phaseTransitionTime))
+		for podName, transitionTime := range gang.phaseTransitionTime {
+			gangSummary.ChildrenPhaseTransitionTime[podName] = transitionTime
+		}
+	}
 
 	return gangSummary
 }
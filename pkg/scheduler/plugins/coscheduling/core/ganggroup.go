@@ -126,6 +126,12 @@ func (gg *GangGroupInfo) ClearWaitingGang() {
 	}
 }
 
+func (gg *GangGroupInfo) isWaiting() bool {
+	gg.lock.Lock()
+	defer gg.lock.Unlock()
+	return len(gg.WaitingGangIDs) > 0
+}
+
 func (gg *GangGroupInfo) RecordIfNoRepresentatives(pod *corev1.Pod) string {
 	gg.lock.Lock()
 	defer gg.lock.Unlock()
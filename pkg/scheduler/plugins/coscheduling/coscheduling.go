@@ -113,7 +113,7 @@ func (cs *Coscheduling) EventsToRegister() []framework.ClusterEventWithHint {
 					logger.Error(err, "unexpected new object in isSchedulableAfterPodAdd")
 					return framework.QueueAfterBackoff
 				}
-				if util.GetGangNameByPod(pod) == util.GetGangNameByPod(updatedPod) && pod.Namespace == updatedPod.Namespace {
+				if cs.pgMgr.GetGangNameByPod(pod) == cs.pgMgr.GetGangNameByPod(updatedPod) && pod.Namespace == updatedPod.Namespace {
 					return framework.QueueAfterBackoff
 				}
 				return framework.QueueSkip
@@ -136,7 +136,7 @@ func (cs *Coscheduling) EventsToRegister() []framework.ClusterEventWithHint {
 					groupSlice = append(groupSlice, eventGangID)
 				}
 				gangSet := sets.New[string](groupSlice...)
-				podGang := util.GetId(pod.Namespace, util.GetGangNameByPod(pod))
+				podGang := util.GetId(pod.Namespace, cs.pgMgr.GetGangNameByPod(pod))
 				if gangSet.Has(podGang) {
 					return framework.QueueAfterBackoff
 				}
@@ -211,7 +211,7 @@ func (cs *Coscheduling) Permit(ctx context.Context, state *framework.CycleState,
 		return framework.NewStatus(framework.Unschedulable, "Gang not found"), 0
 	case core.Wait:
 		klog.InfoS("Pod is waiting to be scheduled at Permit stage", "gang",
-			util.GetId(pod.Namespace, util.GetGangNameByPod(pod)), "pod", klog.KObj(pod))
+			util.GetId(pod.Namespace, cs.pgMgr.GetGangNameByPod(pod)), "pod", klog.KObj(pod))
 		retStatus = framework.NewStatus(framework.Wait)
 	case core.Success:
 		cs.pgMgr.AllowGangGroup(pod, cs.frameworkHandler, Name)
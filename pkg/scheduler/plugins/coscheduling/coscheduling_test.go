@@ -627,3 +627,122 @@ func TestUnreserve(t *testing.T) {
 		})
 	}
 }
+
+func TestPostFilterDeadlockDetection(t *testing.T) {
+	gangCreatedTime := time.Now()
+	tests := []struct {
+		name               string
+		threshold          int64
+		strict             bool
+		waitingPods        []*corev1.Pod
+		failures           int
+		expectedDeadlocked bool
+		expectedFailures   int
+	}{
+		{
+			name: "detection is disabled",
+			waitingPods: []*corev1.Pod{
+				st.MakePod().Name("pod1").Namespace("gangA_ns").UID("pod1").Label(v1alpha1.PodGroupLabel, "gangA").Obj(),
+			},
+			failures:         3,
+			expectedFailures: 3,
+		},
+		{
+			name:      "gang holding no waiting pods is not deadlocked",
+			threshold: 2,
+			failures:  3,
+			// failures keep counting without the waiting pods
+			expectedFailures: 3,
+		},
+		{
+			name:      "gang failing less than threshold is not deadlocked",
+			threshold: 2,
+			waitingPods: []*corev1.Pod{
+				st.MakePod().Name("pod1").Namespace("gangA_ns").UID("pod1").Label(v1alpha1.PodGroupLabel, "gangA").Obj(),
+			},
+			failures:         1,
+			expectedFailures: 1,
+		},
+		{
+			name:      "gang holding waiting pods and failing threshold times is deadlocked",
+			threshold: 2,
+			waitingPods: []*corev1.Pod{
+				st.MakePod().Name("pod1").Namespace("gangA_ns").UID("pod1").Label(v1alpha1.PodGroupLabel, "gangA").Obj(),
+			},
+			failures:           2,
+			expectedDeadlocked: true,
+			expectedFailures:   0,
+		},
+		{
+			name:      "strict gang is released by the PostFilter instead of the deadlock detection",
+			threshold: 2,
+			strict:    true,
+			waitingPods: []*corev1.Pod{
+				st.MakePod().Name("pod1").Namespace("gangA_ns").UID("pod1").Label(v1alpha1.PodGroupLabel, "gangA").Obj(),
+			},
+			failures:         2,
+			expectedFailures: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pgClientSet := fakepgclientset.NewSimpleClientset()
+			cs := kubefake.NewSimpleClientset()
+			// the NonStrict gang isn't rejected by the PostFilter, but by the deadlock detection
+			pg := makePg("gangA", "gangA_ns", 3, &gangCreatedTime, nil)
+			pg.Annotations = map[string]string{extension.AnnotationGangMode: extension.GangModeNonStrict}
+			if tt.strict {
+				pg.Annotations[extension.AnnotationGangMode] = extension.GangModeStrict
+			}
+			_, err := pgClientSet.SchedulingV1alpha1().PodGroups(pg.Namespace).Create(context.TODO(), pg, metav1.CreateOptions{})
+			assert.NoError(t, err)
+
+			suit := newPluginTestSuit(t, nil, pgClientSet, cs)
+			suit.gangSchedulingArgs.DeadlockDetectionThreshold = tt.threshold
+			gp := suit.plugin.(*Coscheduling)
+			suit.start()
+
+			cycleState := framework.NewCycleState()
+			var wg sync.WaitGroup
+			var rejected int32
+			var lock sync.Mutex
+			for _, pod := range tt.waitingPods {
+				tmpPod := pod
+				suit.Handle.(framework.Framework).RunPermitPlugins(context.Background(), cycleState, tmpPod, "")
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					status := suit.Handle.(framework.Framework).WaitOnPermit(context.Background(), tmpPod)
+					if !status.IsSuccess() {
+						lock.Lock()
+						rejected++
+						lock.Unlock()
+					}
+				}()
+			}
+
+			failedPod := st.MakePod().Name("pod2").Namespace("gangA_ns").UID("pod2").Label(v1alpha1.PodGroupLabel, "gangA").Obj()
+			for i := 0; i < tt.failures; i++ {
+				_, status := gp.PostFilter(context.Background(), cycleState, failedPod, nil)
+				assert.Equal(t, framework.Unschedulable, status.Code())
+			}
+			gangSummary, ok := gp.pgMgr.GetGangSummary("gangA_ns/gangA")
+			assert.True(t, ok)
+			assert.Equal(t, tt.expectedDeadlocked, gangSummary.Deadlocked)
+			assert.Equal(t, tt.expectedFailures, gangSummary.LastSchedulingFailures)
+
+			if !tt.expectedDeadlocked && !tt.strict {
+				// release the waiting pods left by the test
+				suit.Handle.IterateOverWaitingPods(func(waitingPod framework.WaitingPod) {
+					waitingPod.Allow(Name)
+				})
+			}
+			wg.Wait()
+			if tt.expectedDeadlocked || tt.strict {
+				assert.Equal(t, int32(len(tt.waitingPods)), rejected)
+			} else {
+				assert.Equal(t, int32(0), rejected)
+			}
+		})
+	}
+}
@@ -98,6 +98,7 @@ func TestEndpointsQueryGangInfo(t *testing.T) {
 		OnceResourceSatisfied:  false,
 		GangFrom:               core.GangFromPodAnnotation,
 		GangMatchPolicy:        extension.GangMatchPolicyOnceSatisfied,
+		TimeoutAction:          extension.GangTimeoutActionReject,
 		HasGangInit:            true,
 	}
 	{
@@ -128,4 +129,88 @@ func TestEndpointsQueryGangInfo(t *testing.T) {
 		gangMarshalMap["ganga_ns/ganga"].GangGroupInfo = nil
 		assert.Equal(t, &gangExpected, gangMarshalMap["ganga_ns/ganga"])
 	}
+	{
+		// the pod passing the Permit is recorded with its phase transition time
+		beforePermit := time.Now()
+		_, status := gp.pgMgr.Permit(context.TODO(), podToCreateGangA)
+		assert.Equal(t, core.Wait, status)
+		engine := gin.Default()
+		gp.RegisterEndpoints(engine.Group("/"))
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/gang/ganga_ns/ganga", nil)
+		engine.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+		gangMarshal := &core.GangSummary{}
+		err = json.NewDecoder(w.Result().Body).Decode(gangMarshal)
+		assert.NoError(t, err)
+		assert.Equal(t, sets.New[string]("ganga_ns/pod1"), gangMarshal.WaitingForBindChildren)
+		assert.Equal(t, 1, len(gangMarshal.ChildrenPhaseTransitionTime))
+		transitionTime, ok := gangMarshal.ChildrenPhaseTransitionTime["ganga_ns/pod1"]
+		assert.True(t, ok)
+		assert.False(t, transitionTime.Before(beforePermit.Truncate(time.Second)))
+	}
+}
+
+func TestEndpointsQueryGangTimeoutAction(t *testing.T) {
+	tests := []struct {
+		name          string
+		annotations   map[string]string
+		timeoutAction string
+	}{
+		{
+			name:          "reject the gang group by default",
+			timeoutAction: extension.GangTimeoutActionReject,
+		},
+		{
+			name:          "reject the gang group",
+			annotations:   map[string]string{extension.AnnotationGangTimeoutAction: extension.GangTimeoutActionReject},
+			timeoutAction: extension.GangTimeoutActionReject,
+		},
+		{
+			name:          "requeue the timed-out pod",
+			annotations:   map[string]string{extension.AnnotationGangTimeoutAction: extension.GangTimeoutActionRequeue},
+			timeoutAction: extension.GangTimeoutActionRequeue,
+		},
+		{
+			name:          "illegal action falls back to reject",
+			annotations:   map[string]string{extension.AnnotationGangTimeoutAction: "Ignore"},
+			timeoutAction: extension.GangTimeoutActionReject,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suit := newPluginTestSuitForGangAPI(t, nil)
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "gangb_ns",
+					Name:      "pod1",
+					Annotations: map[string]string{
+						extension.AnnotationGangName:   "gangb",
+						extension.AnnotationGangMinNum: "2",
+					},
+				},
+			}
+			for k, v := range tt.annotations {
+				pod.Annotations[k] = v
+			}
+			_, err := suit.Handle.ClientSet().CoreV1().Pods("gangb_ns").Create(context.TODO(), pod, metav1.CreateOptions{})
+			assert.NoError(t, err)
+			p, err := suit.proxyNew(suit.gangSchedulingArgs, suit.Handle)
+			assert.NotNil(t, p)
+			assert.Nil(t, err)
+			suit.start()
+			gp := p.(*Coscheduling)
+
+			engine := gin.Default()
+			gp.RegisterEndpoints(engine.Group("/"))
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/gang/gangb_ns/gangb", nil)
+			engine.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+			gangMarshal := &core.GangSummary{}
+			err = json.NewDecoder(w.Result().Body).Decode(gangMarshal)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.timeoutAction, gangMarshal.TimeoutAction)
+		})
+	}
 }
@@ -25,13 +25,37 @@ import (
 	"time"
 
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/strategicpatch"
 	"k8s.io/klog/v2"
 
 	"github.com/koordinator-sh/koordinator/apis/extension"
 	"github.com/koordinator-sh/koordinator/apis/thirdparty/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+	"github.com/koordinator-sh/koordinator/pkg/scheduler/apis/config"
 )
 
+// GetGangOwner returns the controller reference of the pod and its kind if the pod's gang is derived from
+// the controller of one of the ownerKinds, otherwise nil.
+func GetGangOwner(pod *v1.Pod, ownerKinds []config.GangOwnerKind) (*metav1.OwnerReference, *config.GangOwnerKind) {
+	ownerRef := metav1.GetControllerOf(pod)
+	if ownerRef == nil {
+		return nil, nil
+	}
+	gv, err := schema.ParseGroupVersion(ownerRef.APIVersion)
+	if err != nil {
+		return nil, nil
+	}
+
+	for i := range ownerKinds {
+		kind := &ownerKinds[i]
+		if kind.Group == gv.Group && kind.Version == gv.Version && kind.Kind == ownerRef.Kind {
+			return ownerRef, kind
+		}
+	}
+	return nil, nil
+}
+
 func GetGangGroupId(s []string) string {
 	sort.Strings(s)
 	return strings.Join(s, ",")
@@ -55,6 +79,19 @@ func GetGangNameByPod(pod *v1.Pod) string {
 	return gangName
 }
 
+// GetGangNameByPodWithOwner returns the gang name of the pod as GetGangNameByPod does, and falls back to the name
+// of the controller of the pod if its gang is derived from the controller of one of the ownerKinds.
+func GetGangNameByPodWithOwner(pod *v1.Pod, ownerKinds []config.GangOwnerKind) string {
+	gangName := GetGangNameByPod(pod)
+	if gangName == "" && pod != nil {
+		// the gang is named after the controller of the pod
+		if ownerRef, _ := GetGangOwner(pod, ownerKinds); ownerRef != nil {
+			gangName = ownerRef.Name
+		}
+	}
+	return gangName
+}
+
 func GetGangMinNumFromPod(pod *v1.Pod) (minNum int, err error) {
 	// nolint:staticcheck // SA1019: extension.LabelLightweightCoschedulingPodGroupMinAvailable is deprecated
 	if s := pod.Labels[extension.LabelLightweightCoschedulingPodGroupMinAvailable]; s != "" {
@@ -20,16 +20,21 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
 	quotav1 "k8s.io/apiserver/pkg/quota/v1"
+	"k8s.io/client-go/util/flowcontrol"
 	"k8s.io/klog/v2"
 
 	"github.com/koordinator-sh/koordinator/apis/extension"
@@ -41,16 +46,33 @@ import (
 
 const (
 	ControllerName = "ElasticQuotaController"
+
+	// ElasticQuotaStatusPatchQPS and ElasticQuotaStatusPatchBurst limit the patches of the elastic quota status,
+	// the quotas skipped by the limiter are synced in the next round.
+	ElasticQuotaStatusPatchQPS   = 20
+	ElasticQuotaStatusPatchBurst = 100
 )
 
 // Controller is a controller that update elastic quota crd
 type Controller struct {
 	plugin *Plugin
+	// minNotPreservedQuotas are the quotas whose min can't be preserved since the total resource shrinks,
+	// the event is only emitted when the quota becomes not preserved.
+	minNotPreservedQuotas sets.String
+	// statusPatchLimiter rate limits the patches of the elastic quota status to avoid apiserver churn.
+	statusPatchLimiter flowcontrol.RateLimiter
+	// capacityAlertLevels are the utilization levels of the quotas notified to the capacity alert webhook.
+	capacityAlertLevels map[string]int64
+	capacityAlertClient *http.Client
 }
 
 func NewElasticQuotaController(plugin *Plugin) *Controller {
 	ctrl := &Controller{
-		plugin: plugin,
+		plugin:                plugin,
+		minNotPreservedQuotas: sets.NewString(),
+		statusPatchLimiter:    flowcontrol.NewTokenBucketRateLimiter(ElasticQuotaStatusPatchQPS, ElasticQuotaStatusPatchBurst),
+		capacityAlertLevels:   map[string]int64{},
+		capacityAlertClient:   &http.Client{Timeout: capacityAlertTimeout},
 	}
 	return ctrl
 }
@@ -62,6 +84,9 @@ func (ctrl *Controller) Name() string {
 func (ctrl *Controller) Start() {
 	go wait.Until(ctrl.syncElasticQuotaStatusWorker, 1*time.Second, context.TODO().Done())
 	go wait.Until(ctrl.syncElasticQuotaStatusMetricsWorker, 10*time.Second, context.TODO().Done())
+	go wait.Until(ctrl.syncQuotaTopology, 10*time.Second, context.TODO().Done())
+	go wait.Until(ctrl.reconcileQuotas, QuotaReconcileCycle, context.TODO().Done())
+	go wait.Until(ctrl.syncCapacityAlerts, CapacityAlertCycle, context.TODO().Done())
 }
 
 func (ctrl *Controller) syncElasticQuotaStatusWorker() {
@@ -70,9 +95,23 @@ func (ctrl *Controller) syncElasticQuotaStatusWorker() {
 		klog.V(3).ErrorS(err, "Unable to list elastic quota in syncElasticQuotaStatusWorker")
 		return
 	}
+	// only the quotas managed by the quota managers have the status to write back.
+	managedQuotas := sets.NewString(ctrl.plugin.groupQuotaManager.GetAllQuotaNames()...)
+	for _, mgr := range ctrl.plugin.ListGroupQuotaManagersForQuotaTree() {
+		managedQuotas.Insert(mgr.GetAllQuotaNames()...)
+	}
 	for _, eq := range elasticQuotas {
+		if !managedQuotas.Has(eq.Name) {
+			continue
+		}
 		ctrl.syncElasticQuotaStatus(eq)
 	}
+	// forget the deleted quotas, so the event is emitted again for the quota recreated with the same name.
+	for _, quotaName := range ctrl.minNotPreservedQuotas.UnsortedList() {
+		if !managedQuotas.Has(quotaName) {
+			ctrl.minNotPreservedQuotas.Delete(quotaName)
+		}
+	}
 	return
 }
 
@@ -82,6 +121,8 @@ func (ctrl *Controller) syncElasticQuotaStatus(eq *v1alpha1.ElasticQuota) {
 		klog.Warningf("failed get quota summary for elasticQuota %v", eq.Name)
 		return
 	}
+	ctrl.recordMinPreservation(eq, summary)
+	summary.Used = roundResourceList(summary.Used, ctrl.plugin.pluginArgs.StatusUsedPrecision)
 
 	newEQ, err := updateElasticQuotaStatusIfChanged(eq, summary, klog.V(5).Enabled())
 	if err != nil {
@@ -110,6 +151,16 @@ func (ctrl *Controller) syncElasticQuotaStatus(eq *v1alpha1.ElasticQuota) {
 		klog.InfoS("Try updating elasticQuota since it has changed", "elasticQuota", eq.Name)
 	}
 
+	// the quota may be deleted after the summary is refreshed, there is nothing to patch.
+	if _, err := ctrl.plugin.quotaLister.ElasticQuotas(eq.Namespace).Get(eq.Name); errors.IsNotFound(err) {
+		klog.V(4).InfoS("Skip updating elasticQuota because it has been deleted", "elasticQuota", eq.Name)
+		return
+	}
+	if !ctrl.statusPatchLimiter.TryAccept() {
+		klog.V(4).InfoS("Skip updating elasticQuota because of rate limiting, retry in the next round", "elasticQuota", eq.Name)
+		return
+	}
+
 	patch, err := util.CreateMergePatch(eq, newEQ)
 	if err != nil {
 		klog.ErrorS(err, "Failed to create mergePatch", "elasticQuota", eq.Name)
@@ -126,7 +177,9 @@ func (ctrl *Controller) syncElasticQuotaStatus(eq *v1alpha1.ElasticQuota) {
 			Patch(context.TODO(), eq.Name, types.MergePatchType, patch, metav1.PatchOptions{})
 		return patchErr
 	})
-	if err != nil {
+	if errors.IsNotFound(err) {
+		klog.V(4).InfoS("Skip updating elasticQuota because it has been deleted", "elasticQuota", eq.Name)
+	} else if err != nil {
 		klog.ErrorS(err, "Failed to patch elasticQuota", "elasticQuota", eq.Name)
 	} else {
 		if klog.V(5).Enabled() {
@@ -135,6 +188,23 @@ func (ctrl *Controller) syncElasticQuotaStatus(eq *v1alpha1.ElasticQuota) {
 	}
 }
 
+// recordMinPreservation emits an event when the scaled min of the quota drops below its min,
+// i.e. the quotas with higher min priority take the shrunk total resource first.
+func (ctrl *Controller) recordMinPreservation(eq *v1alpha1.ElasticQuota, summary *core.QuotaInfoSummary) {
+	preserved, dimensions := quotav1.LessThanOrEqual(summary.Min, summary.AutoScaleMin)
+	if preserved {
+		ctrl.minNotPreservedQuotas.Delete(eq.Name)
+		return
+	}
+	if ctrl.minNotPreservedQuotas.Has(eq.Name) {
+		return
+	}
+	ctrl.minNotPreservedQuotas.Insert(eq.Name)
+	ctrl.plugin.handle.EventRecorder().Eventf(eq, nil, v1.EventTypeWarning, "MinQuotaNotPreserved", "ScaleMinQuota",
+		"min of quota can't be preserved on %v since the total resource shrinks, min: %v, scaled min: %v, min priority: %v",
+		dimensions, printResourceList(summary.Min), printResourceList(summary.AutoScaleMin), summary.MinPriority)
+}
+
 var resourceDecorators []func(quota *v1alpha1.ElasticQuota, resource v1.ResourceList)
 
 func decorateResource(quota *v1alpha1.ElasticQuota, resource v1.ResourceList) {
@@ -143,6 +213,26 @@ func decorateResource(quota *v1alpha1.ElasticQuota, resource v1.ResourceList) {
 	}
 }
 
+// roundResourceList rounds each resource to the nearest multiple of its precision, the resources without
+// the precision are kept as is.
+func roundResourceList(resources, precision v1.ResourceList) v1.ResourceList {
+	if len(precision) == 0 || len(resources) == 0 {
+		return resources
+	}
+	rounded := make(v1.ResourceList, len(resources))
+	for resourceName, quantity := range resources {
+		p, ok := precision[resourceName]
+		if !ok || p.MilliValue() <= 0 {
+			rounded[resourceName] = quantity
+			continue
+		}
+		step := p.MilliValue()
+		value := (quantity.MilliValue() + step/2) / step * step
+		rounded[resourceName] = *resource.NewMilliQuantity(value, quantity.Format)
+	}
+	return rounded
+}
+
 type traceChange struct {
 	key      string
 	original v1.ResourceList
@@ -187,6 +277,23 @@ func updateElasticQuotaStatusIfChanged(eq *v1alpha1.ElasticQuota, summary *core.
 		}
 	}
 
+	if isSuspendedAnnotationDiff(eq, summary.Suspended) {
+		if logChanges {
+			klog.InfoS("ElasticQuota suspension changed", "elasticQuota", eq.Name, "suspended", summary.Suspended)
+		}
+		if newElasticQuota == nil {
+			newElasticQuota = eq.DeepCopy()
+			if newElasticQuota.Annotations == nil {
+				newElasticQuota.Annotations = map[string]string{}
+			}
+		}
+		if summary.Suspended {
+			newElasticQuota.Annotations[extension.AnnotationSuspended] = "true"
+		} else {
+			delete(newElasticQuota.Annotations, extension.AnnotationSuspended)
+		}
+	}
+
 	decorateResource(eq, summary.Used)
 	if !quotav1.Equals(quotav1.RemoveZeros(eq.Status.Used), quotav1.RemoveZeros(summary.Used)) {
 		if logChanges {
@@ -215,6 +322,16 @@ func updateElasticQuotaStatusIfChanged(eq *v1alpha1.ElasticQuota, summary *core.
 	return newElasticQuota, nil
 }
 
+// isSuspendedAnnotationDiff returns true if the suspended annotation doesn't reflect the suspension of the quota,
+// the annotation is only present on the suspended quotas.
+func isSuspendedAnnotationDiff(eq *v1alpha1.ElasticQuota, suspended bool) bool {
+	val, ok := eq.Annotations[extension.AnnotationSuspended]
+	if !suspended {
+		return ok
+	}
+	return val != "true"
+}
+
 func isElasticQuotaAnnotationDiff(eq *v1alpha1.ElasticQuota, key string, resourceList v1.ResourceList) (bool, v1.ResourceList, error) {
 	var originalResourceList v1.ResourceList
 	if val := eq.Annotations[key]; val != "" {
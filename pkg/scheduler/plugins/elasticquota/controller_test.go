@@ -31,6 +31,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	quotav1 "k8s.io/apiserver/pkg/quota/v1"
+	"k8s.io/client-go/util/flowcontrol"
 	testing2 "k8s.io/kubernetes/pkg/scheduler/testing"
 
 	"github.com/koordinator-sh/koordinator/apis/extension"
@@ -365,6 +366,38 @@ func Test_updateElasticQuotaStatusIfChanged(t *testing.T) {
 	}
 }
 
+func Test_updateElasticQuotaStatusIfChanged_Suspended(t *testing.T) {
+	eq := &v1alpha1.ElasticQuota{
+		Spec: v1alpha1.ElasticQuotaSpec{
+			Max: MakeResourceList().CPU(4).Mem(200).Obj(),
+		},
+	}
+	summary := &core.QuotaInfoSummary{
+		Max:       MakeResourceList().CPU(4).Mem(200).Obj(),
+		Suspended: true,
+	}
+
+	// the suspension is synced
+	newEQ, err := updateElasticQuotaStatusIfChanged(eq, summary, true)
+	assert.NoError(t, err)
+	assert.NotNil(t, newEQ)
+	assert.Equal(t, "true", newEQ.Annotations[extension.AnnotationSuspended])
+
+	// no change
+	eq = newEQ
+	newEQ, err = updateElasticQuotaStatusIfChanged(eq, summary, true)
+	assert.NoError(t, err)
+	assert.Nil(t, newEQ)
+
+	// the quota is resumed, the annotation is removed
+	summary.Suspended = false
+	newEQ, err = updateElasticQuotaStatusIfChanged(eq, summary, true)
+	assert.NoError(t, err)
+	assert.NotNil(t, newEQ)
+	_, ok := newEQ.Annotations[extension.AnnotationSuspended]
+	assert.False(t, ok)
+}
+
 func Test_syncElasticQuotaMetrics(t *testing.T) {
 	eq := &v1alpha1.ElasticQuota{
 		ObjectMeta: metav1.ObjectMeta{
@@ -577,3 +610,151 @@ func (r *resourceWrapper) GPU(val int64) *resourceWrapper {
 func (r *resourceWrapper) Obj() v1.ResourceList {
 	return r.ResourceList
 }
+
+func TestController_RecordMinPreservation(t *testing.T) {
+	suit := newPluginTestSuit(t, nil)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.NoError(t, err)
+	plugin := p.(*Plugin)
+
+	quotaA := CreateQuota2("a", extension.RootQuotaName, 1000, 1000, 100, 100, 100, 100, false, "")
+	quotaA.Annotations[extension.AnnotationMinPriority] = "10"
+	quotaB := CreateQuota2("b", extension.RootQuotaName, 1000, 1000, 100, 100, 100, 100, false, "")
+	quotaC := CreateQuota2("c", extension.RootQuotaName, 1000, 1000, 100, 100, 100, 100, false, "")
+	quotas := []*v1alpha1.ElasticQuota{quotaA, quotaB, quotaC}
+	for _, quota := range quotas {
+		plugin.OnQuotaAdd(quota)
+	}
+	// the total resource can only satisfy the min of "a" and half of the others
+	plugin.groupQuotaManager.UpdateClusterTotalResource(createResourceList(200, 200))
+
+	ctrl := NewElasticQuotaController(plugin)
+	for i := 0; i < 2; i++ {
+		for _, quota := range quotas {
+			plugin.groupQuotaManager.RefreshRuntime(quota.Name)
+			ctrl.syncElasticQuotaStatus(quota)
+		}
+	}
+	assert.Equal(t, []string{"b", "c"}, ctrl.minNotPreservedQuotas.List())
+	// the event is emitted only once for each quota
+	assert.Equal(t, 2, len(suit.fakeRecorder.Events))
+	for i := 0; i < 2; i++ {
+		assert.Contains(t, <-suit.fakeRecorder.Events, "MinQuotaNotPreserved")
+	}
+
+	// the deleted quota is forgotten
+	plugin.OnQuotaDelete(quotaB)
+	ctrl.syncElasticQuotaStatusWorker()
+	assert.Equal(t, []string{"c"}, ctrl.minNotPreservedQuotas.List())
+
+	// the cluster scales up, all mins are preserved again
+	plugin.groupQuotaManager.UpdateClusterTotalResource(createResourceList(1000, 1000))
+	for _, quota := range []*v1alpha1.ElasticQuota{quotaA, quotaC} {
+		plugin.groupQuotaManager.RefreshRuntime(quota.Name)
+		ctrl.syncElasticQuotaStatus(quota)
+	}
+	assert.Equal(t, 0, ctrl.minNotPreservedQuotas.Len())
+	assert.Equal(t, 0, len(suit.fakeRecorder.Events))
+}
+
+func Test_roundStatusUsed(t *testing.T) {
+	precision := v1.ResourceList{
+		v1.ResourceCPU:    resource.MustParse("100m"),
+		v1.ResourceMemory: resource.MustParse("1Mi"),
+	}
+	tests := []struct {
+		name       string
+		statusUsed v1.ResourceList
+		used       v1.ResourceList
+		precision  v1.ResourceList
+		wantUsed   v1.ResourceList
+	}{
+		{
+			name:       "sub-precision change isn't updated",
+			statusUsed: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1"), v1.ResourceMemory: resource.MustParse("1Gi")},
+			used:       v1.ResourceList{v1.ResourceCPU: resource.MustParse("1040m"), v1.ResourceMemory: resource.MustParse("1073742000")},
+			precision:  precision,
+		},
+		{
+			name:       "sub-precision change is updated without precision",
+			statusUsed: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1"), v1.ResourceMemory: resource.MustParse("1Gi")},
+			used:       v1.ResourceList{v1.ResourceCPU: resource.MustParse("1040m"), v1.ResourceMemory: resource.MustParse("1Gi")},
+			wantUsed:   v1.ResourceList{v1.ResourceCPU: resource.MustParse("1040m"), v1.ResourceMemory: resource.MustParse("1Gi")},
+		},
+		{
+			name:       "change beyond precision is rounded",
+			statusUsed: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1"), v1.ResourceMemory: resource.MustParse("1Gi")},
+			used:       v1.ResourceList{v1.ResourceCPU: resource.MustParse("1260m"), v1.ResourceMemory: resource.MustParse("1Gi")},
+			precision:  precision,
+			wantUsed:   v1.ResourceList{v1.ResourceCPU: resource.MustParse("1300m"), v1.ResourceMemory: resource.MustParse("1Gi")},
+		},
+		{
+			name:       "resource without precision is kept",
+			statusUsed: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")},
+			used:       v1.ResourceList{v1.ResourceCPU: resource.MustParse("1"), "nvidia.com/gpu": resource.MustParse("3")},
+			precision:  precision,
+			wantUsed:   v1.ResourceList{v1.ResourceCPU: resource.MustParse("1"), "nvidia.com/gpu": resource.MustParse("3")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eq := &v1alpha1.ElasticQuota{
+				Status: v1alpha1.ElasticQuotaStatus{Used: tt.statusUsed},
+			}
+			summary := &core.QuotaInfoSummary{Used: roundResourceList(tt.used, tt.precision)}
+			newEQ, err := updateElasticQuotaStatusIfChanged(eq, summary, true)
+			assert.NoError(t, err)
+			if tt.wantUsed == nil {
+				assert.Nil(t, newEQ)
+				return
+			}
+			assert.NotNil(t, newEQ)
+			assert.True(t, quotav1.Equals(tt.wantUsed, newEQ.Status.Used))
+		})
+	}
+}
+
+func TestController_SyncElasticQuotaStatusWriteBack(t *testing.T) {
+	ctx := context.TODO()
+	suit := newPluginTestSuit(t, nil)
+	quotaA := CreateQuota2("a", extension.RootQuotaName, 1000, 1000, 100, 100, 100, 100, false, "")
+	_, err := suit.client.SchedulingV1alpha1().ElasticQuotas(quotaA.Namespace).Create(ctx, quotaA, metav1.CreateOptions{})
+	assert.NoError(t, err)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.NoError(t, err)
+	plugin := p.(*Plugin)
+	assert.NoError(t, plugin.quotaInformer.GetStore().Add(quotaA))
+	plugin.OnQuotaAdd(quotaA)
+	// quotaB is deleted after the summary is refreshed, it's not in the lister
+	quotaB := CreateQuota2("b", extension.RootQuotaName, 1000, 1000, 100, 100, 100, 100, false, "")
+	plugin.OnQuotaAdd(quotaB)
+	for _, quotaName := range []string{"a", "b"} {
+		pod := MakePod("", "pod-"+quotaName).UID("pod-"+quotaName).Label(extension.LabelQuotaName, quotaName).
+			Container(createResourceList(10, 10)).Obj()
+		plugin.OnPodAdd(pod)
+		plugin.groupQuotaManager.RefreshRuntime(quotaName)
+	}
+
+	ctrl := NewElasticQuotaController(plugin)
+	// rate limited, retry in the next round
+	ctrl.statusPatchLimiter = flowcontrol.NewFakeNeverRateLimiter()
+	ctrl.syncElasticQuotaStatusWorker()
+	eq, err := suit.client.SchedulingV1alpha1().ElasticQuotas(quotaA.Namespace).Get(ctx, "a", metav1.GetOptions{})
+	assert.NoError(t, err)
+	request, err := extension.GetRequest(eq)
+	assert.NoError(t, err)
+	assert.True(t, quotav1.IsZero(request))
+
+	ctrl.statusPatchLimiter = flowcontrol.NewFakeAlwaysRateLimiter()
+	ctrl.syncElasticQuotaStatusWorker()
+	eq, err = suit.client.SchedulingV1alpha1().ElasticQuotas(quotaA.Namespace).Get(ctx, "a", metav1.GetOptions{})
+	assert.NoError(t, err)
+	request, err = extension.GetRequest(eq)
+	assert.NoError(t, err)
+	assert.True(t, quotav1.Equals(createResourceList(10, 10), request))
+
+	// the deleted quota is skipped gracefully
+	actions := len(suit.client.Actions())
+	ctrl.syncElasticQuotaStatus(quotaB)
+	assert.Equal(t, actions, len(suit.client.Actions()))
+}
@@ -19,13 +19,17 @@ package core
 import (
 	"fmt"
 	"reflect"
+	"sort"
 	"sync"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/sets"
 	quotav1 "k8s.io/apiserver/pkg/quota/v1"
 	utilfeature "k8s.io/apiserver/pkg/util/feature"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
 
 	"github.com/koordinator-sh/koordinator/apis/extension"
 	"github.com/koordinator-sh/koordinator/apis/thirdparty/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
@@ -61,9 +65,37 @@ type GroupQuotaManager struct {
 
 	// treeID is the quota tree id
 	treeID string
+	// schedulingStrategy is the default scheduling strategy of the tree, quotas inherit it unless overridden.
+	schedulingStrategy extension.QuotaSchedulingStrategy
+	// runtimeRefreshStrategy decides whether the runtime is refreshed on pod events or only when it's read.
+	runtimeRefreshStrategy extension.QuotaRuntimeRefreshStrategy
+	// runtimeDistribution decides how the runtime of the parent quotas is distributed to their children.
+	runtimeDistribution extension.QuotaRuntimeDistribution
+	// defaultNonPreemptibleQuotas are the quotas whose pods are non-preemptible unless the pods declare otherwise.
+	defaultNonPreemptibleQuotas sets.String
+	// guaranteeMinRuntime keeps the runtime of every quota at least its min as if it doesn't lend the resource.
+	guaranteeMinRuntime bool
+	// runtimeShrinkDelay is how long the runtime of a quota stays lower before the admission follows it down.
+	runtimeShrinkDelay time.Duration
+	clock              clock.Clock
+	// ancestorCacheLock guards ancestorCache which is read under the read lock of hierarchyUpdateLock
+	ancestorCacheLock sync.Mutex
+	// ancestorCache caches the ancestors of the quotas until the hierarchy changes
+	ancestorCache map[string][]*QuotaInfo
+	// lenderTrees are the ids of the quota trees which lend their idle capacity to the tree.
+	lenderTrees []string
+	// borrowedResource is borrowed from the lender trees and added to the total resource of the tree.
+	borrowedResource v1.ResourceList
+	// lentResource is lent to the borrower trees and subtracted from the total resource of the tree.
+	lentResource v1.ResourceList
+	// resourceClaimClassGetter resolves the resource classes of the pods' resource claims.
+	resourceClaimClassGetter ResourceClaimClassGetter
 
 	// hookPlugins contains all registered hookPlugins
 	hookPlugins []QuotaHookPlugin
+
+	// changeNotifier batches the quota changes for the subscribed controllers.
+	changeNotifier *quotaChangeNotifier
 }
 
 func NewGroupQuotaManager(treeID string, systemGroupMax, defaultGroupMax v1.ResourceList) *GroupQuotaManager {
@@ -77,6 +109,10 @@ func NewGroupQuotaManager(treeID string, systemGroupMax, defaultGroupMax v1.Reso
 		scaleMinQuotaManager:                    NewScaleMinQuotaManager(),
 		nodeResourceMap:                         make(map[string]struct{}),
 		treeID:                                  treeID,
+		runtimeRefreshStrategy:                  extension.QuotaRuntimeRefreshStrategyLazy,
+		runtimeDistribution:                     extension.QuotaRuntimeDistributionWeighted,
+		changeNotifier:                          newQuotaChangeNotifier(treeID),
+		clock:                                   clock.RealClock{},
 	}
 	// only default GroupQuotaManager need system quota and deault quota.
 	if treeID == "" {
@@ -89,7 +125,7 @@ func NewGroupQuotaManager(treeID string, systemGroupMax, defaultGroupMax v1.Reso
 	rootQuotaInfo := NewQuotaInfo(true, false, extension.RootQuotaName, "")
 	quotaManager.quotaInfoMap[extension.RootQuotaName] = rootQuotaInfo
 	quotaManager.quotaTopoNodeMap[extension.RootQuotaName] = NewQuotaTopoNode(extension.RootQuotaName, rootQuotaInfo)
-	quotaManager.runtimeQuotaCalculatorMap[extension.RootQuotaName] = NewRuntimeQuotaCalculator(extension.RootQuotaName)
+	quotaManager.runtimeQuotaCalculatorMap[extension.RootQuotaName] = quotaManager.newRuntimeQuotaCalculatorNoLock(extension.RootQuotaName)
 	quotaManager.setScaleMinQuotaEnabled(true)
 	return quotaManager
 }
@@ -137,7 +173,10 @@ func (gqm *GroupQuotaManager) updateClusterTotalResourceNoLock(deltaRes v1.Resou
 		sysAndDefaultUsed = quotav1.Add(sysAndDefaultUsed, systemQuota.CalculateInfo.Used.DeepCopy())
 	}
 
-	totalResNoSysOrDefault := quotav1.Subtract(gqm.totalResource, sysAndDefaultUsed)
+	// the resource borrowed from or lent to the other trees moves the capacity between the trees, so that the
+	// shared cluster total isn't counted twice.
+	totalResNoSysOrDefault := quotav1.Subtract(quotav1.Add(gqm.totalResource, gqm.borrowedResource),
+		quotav1.Add(sysAndDefaultUsed, gqm.lentResource))
 
 	diffRes := quotav1.Subtract(totalResNoSysOrDefault, gqm.totalResourceExceptSystemAndDefaultUsed)
 
@@ -157,6 +196,28 @@ func (gqm *GroupQuotaManager) GetClusterTotalResource() v1.ResourceList {
 	return gqm.totalResource.DeepCopy()
 }
 
+// GetUnallocatedRuntime returns the resource which isn't distributed to any quota as runtime, i.e. the cluster total
+// resource except the used of SystemQuotaGroup and DefaultQuotaGroup minus the runtime of the top-level quotas,
+// which is the truly idle capacity of the tree.
+func (gqm *GroupQuotaManager) GetUnallocatedRuntime() v1.ResourceList {
+	gqm.hierarchyUpdateLock.RLock()
+	defer gqm.hierarchyUpdateLock.RUnlock()
+
+	unallocated := gqm.totalResourceExceptSystemAndDefaultUsed.DeepCopy()
+	resourceNames := quotav1.ResourceNames(unallocated)
+	for quotaName := range gqm.quotaTopoNodeMap[extension.RootQuotaName].getChildGroupQuotaInfos() {
+		runtime := gqm.refreshRuntimeNoLock(quotaName)
+		unallocated = quotav1.Subtract(unallocated, quotav1.Mask(runtime, resourceNames))
+	}
+	for resourceName, quantity := range unallocated {
+		// the runtime may exceed the total if the min of the quotas oversubscribes the cluster.
+		if quantity.Sign() < 0 {
+			unallocated[resourceName] = *resource.NewQuantity(0, quantity.Format)
+		}
+	}
+	return unallocated
+}
+
 func (gqm *GroupQuotaManager) SetTotalResourceForTree(total v1.ResourceList) v1.ResourceList {
 	gqm.hierarchyUpdateLock.RLock()
 	defer gqm.hierarchyUpdateLock.RUnlock()
@@ -187,6 +248,7 @@ func (gqm *GroupQuotaManager) updateGroupDeltaRequestNoLock(quotaName string, de
 	}
 
 	defer gqm.scopedLockForQuotaInfo(curToAllParInfos)()
+	gqm.markQuotaInfosChanged(curToAllParInfos)
 
 	gqm.recursiveUpdateGroupTreeWithDeltaRequest(deltaReq, deltaNonPreemptibleRequest, curToAllParInfos, selfQuotaIndex)
 }
@@ -205,7 +267,7 @@ func (gqm *GroupQuotaManager) recursiveUpdateGroupTreeWithDeltaRequest(deltaReq,
 		curQuotaInfo.addChildRequestNonNegativeNoLock(deltaReq)
 		realRequest := curQuotaInfo.CalculateInfo.ChildRequest.DeepCopy()
 		// If the quota not allow to lent resource. we should request for min
-		if !curQuotaInfo.AllowLentResource {
+		if gqm.isMinRuntimeGuaranteedNoLock(curQuotaInfo) {
 			if realRequest == nil {
 				realRequest = v1.ResourceList{}
 			}
@@ -249,6 +311,7 @@ func (gqm *GroupQuotaManager) updateGroupDeltaUsedNoLock(quotaName string, delta
 	}
 
 	defer gqm.scopedLockForQuotaInfo(curToAllParInfos)()
+	gqm.markQuotaInfosChanged(curToAllParInfos)
 	for i := 0; i < allQuotaInfoLen; i++ {
 		quotaInfo := curToAllParInfos[i]
 		quotaInfo.addUsedNonNegativeNoLock(delta, deltaNonPreemptibleUsed, i == selfQuotaIndex)
@@ -328,7 +391,8 @@ func (gqm *GroupQuotaManager) refreshRuntimeNoLock(quotaName string) v1.Resource
 		}
 
 		// 2. update parent's runtimeQuota
-		if quotaInfo.RuntimeVersion != parRuntimeQuotaCalculator.getVersion() {
+		// the siblings whose runtime doesn't change needn't refresh their subtrees
+		if quotaInfo.RuntimeVersion != parRuntimeQuotaCalculator.getGroupRuntimeVersion(quotaInfo.Name) {
 			parRuntimeQuotaCalculator.updateOneGroupRuntimeQuota(quotaInfo)
 		}
 		newSubGroupsTotalRes := quotaInfo.CalculateInfo.Runtime.DeepCopy()
@@ -338,11 +402,17 @@ func (gqm *GroupQuotaManager) refreshRuntimeNoLock(quotaName string) v1.Resource
 			subTreeWrapper.setClusterTotalResource(newSubGroupsTotalRes)
 		}
 
+		if klog.V(RuntimeTraceVerbosity).Enabled() {
+			klog.InfoS("RefreshRuntime step", "quotaName", quotaInfo.Name, "parentName", quotaInfo.ParentName,
+				"totalResource", util.DumpJSON(totalRes), "min", util.DumpJSON(quotaInfo.CalculateInfo.AutoScaleMin),
+				"request", util.DumpJSON(quotaInfo.CalculateInfo.Request), "runtime", util.DumpJSON(newSubGroupsTotalRes))
+		}
+
 		// 4. update totalRes
 		totalRes = newSubGroupsTotalRes
 	}
 
-	return curToAllParInfos[0].getMaskedRuntimeNoLock()
+	return gqm.delayRuntimeShrinkNoLock(curToAllParInfos[0], curToAllParInfos[0].getMaskedRuntimeNoLock())
 }
 
 // updateOneGroupAutoScaleMinQuotaNoLock no need to lock gqm.lock
@@ -390,6 +460,18 @@ func (gqm *GroupQuotaManager) getRuntimeQuotaCalculatorByNameNoLock(quotaName st
 	return gqm.runtimeQuotaCalculatorMap[quotaName]
 }
 
+// markQuotaInfosChanged records the quotas as changed for the subscribed controllers.
+func (gqm *GroupQuotaManager) markQuotaInfosChanged(quotaInfos []*QuotaInfo) {
+	if gqm.changeNotifier == nil {
+		return
+	}
+	names := make([]string, 0, len(quotaInfos))
+	for _, quotaInfo := range quotaInfos {
+		names = append(names, quotaInfo.Name)
+	}
+	gqm.changeNotifier.markChanged(names...)
+}
+
 func (gqm *GroupQuotaManager) scopedLockForQuotaInfo(quotaList []*QuotaInfo) func() {
 	listLen := len(quotaList)
 	for i := listLen - 1; i >= 0; i-- {
@@ -421,6 +503,15 @@ func (gqm *GroupQuotaManager) UpdateQuota(quota *v1alpha1.ElasticQuota) error {
 			!gqm.isQuotaUpdated(localQuotaInfo, newQuotaInfo, quota) {
 			return nil
 		}
+		gqm.changeNotifier.markChanged(quotaName)
+
+		// SystemQuotaGroup and DefaultQuotaGroup are out of the quota tree, update them in place.
+		if quotaName == extension.SystemQuotaName || quotaName == extension.DefaultQuotaName {
+			hookState := gqm.runPreQuotaUpdateHooks(localQuotaInfo, newQuotaInfo, quota)
+			gqm.updateSystemOrDefaultQuotaNoLock(localQuotaInfo, newQuotaInfo)
+			gqm.runPostQuotaUpdateHooks(localQuotaInfo, newQuotaInfo, quota, hookState)
+			return nil
+		}
 
 		// if the quotaMeta doesn't change, only runtime/used/request/min/max/sharedWeight change causes update,
 		// no need to call updateQuotaGroupConfigNoLock.
@@ -436,6 +527,9 @@ func (gqm *GroupQuotaManager) UpdateQuota(quota *v1alpha1.ElasticQuota) error {
 		}
 		localQuotaInfo.updateQuotaInfoFromRemote(newQuotaInfo)
 	} else {
+		gqm.changeNotifier.markChanged(quotaName)
+		// the quota may be the missing ancestor of the quotas loaded before it.
+		gqm.invalidateAncestorCacheNoLock()
 		// update quota internal with pre/post hookPlugins
 		hookState := gqm.runPreQuotaUpdateHooks(localQuotaInfo, newQuotaInfo, quota)
 		gqm.updateQuotaInternalNoLock(newQuotaInfo, nil)
@@ -449,6 +543,20 @@ func (gqm *GroupQuotaManager) UpdateQuota(quota *v1alpha1.ElasticQuota) error {
 	return nil
 }
 
+// updateSystemOrDefaultQuotaNoLock updates SystemQuotaGroup or DefaultQuotaGroup in place. The two quotas
+// are out of the runtime calculation, their runtime is the max, so they have no topo node or runtime
+// calculator to update and always stay under the root.
+func (gqm *GroupQuotaManager) updateSystemOrDefaultQuotaNoLock(localQuotaInfo, newQuotaInfo *QuotaInfo) {
+	klog.Infof("update quota %v, oldMax: %v, newMax: %v", localQuotaInfo.Name,
+		util.DumpJSON(localQuotaInfo.GetMax()), util.DumpJSON(newQuotaInfo.CalculateInfo.Max))
+	localQuotaInfo.updateQuotaInfoFromRemote(newQuotaInfo)
+
+	localQuotaInfo.lock.Lock()
+	defer localQuotaInfo.lock.Unlock()
+	localQuotaInfo.IsParent = false
+	localQuotaInfo.ParentName = extension.RootQuotaName
+}
+
 func (gqm *GroupQuotaManager) DeleteQuota(quota *v1alpha1.ElasticQuota) error {
 	start := time.Now()
 	defer func() {
@@ -477,6 +585,7 @@ func (gqm *GroupQuotaManager) UpdateQuotaInfo(quota *v1alpha1.ElasticQuota) {
 
 	newQuotaInfo := NewQuotaInfoFromQuota(quota)
 	gqm.quotaInfoMap[quota.Name] = newQuotaInfo
+	gqm.invalidateAncestorCacheNoLock()
 }
 
 func (gqm *GroupQuotaManager) ResetQuota() {
@@ -493,10 +602,15 @@ func (gqm *GroupQuotaManager) resetQuotaNoLock() {
 		metrics.RecordElasticQuotaProcessLatency("resetQuotaNoLock", time.Since(start))
 	}()
 
+	gqm.invalidateAncestorCacheNoLock()
 	// rebuild gqm.quotaTopoNodeMap
 	gqm.rebuildQuotaTopoNodeMapNoLock()
 	// reset gqm.runtimeQuotaCalculator
 	gqm.rebuildAllGroupQuotaNoLock()
+	// the whole tree is rebuilt, all the quotas may be changed.
+	for quotaName := range gqm.quotaInfoMap {
+		gqm.changeNotifier.markChanged(quotaName)
+	}
 }
 
 // buildSubParGroupTopoNoLock rebuild a nodeTree from root, no need to lock gqm.lock
@@ -560,7 +674,7 @@ func (gqm *GroupQuotaManager) rebuildAllGroupQuotaNoLock() {
 	// clear old runtimeQuotaCalculator
 	gqm.runtimeQuotaCalculatorMap = make(map[string]*RuntimeQuotaCalculator)
 	// reset runtimeQuotaCalculator
-	gqm.runtimeQuotaCalculatorMap[extension.RootQuotaName] = NewRuntimeQuotaCalculator(extension.RootQuotaName)
+	gqm.runtimeQuotaCalculatorMap[extension.RootQuotaName] = gqm.newRuntimeQuotaCalculatorNoLock(extension.RootQuotaName)
 	gqm.runtimeQuotaCalculatorMap[extension.RootQuotaName].setClusterTotalResource(gqm.totalResourceExceptSystemAndDefaultUsed)
 	rootNode := gqm.quotaTopoNodeMap[extension.RootQuotaName]
 	gqm.resetAllGroupQuotaRecursiveNoLock(rootNode)
@@ -579,7 +693,9 @@ func (gqm *GroupQuotaManager) rebuildAllGroupQuotaNoLock() {
 func (gqm *GroupQuotaManager) resetAllGroupQuotaRecursiveNoLock(rootNode *QuotaTopoNode) {
 	childGroupQuotaInfos := rootNode.getChildGroupQuotaInfos()
 	for subName, topoNode := range childGroupQuotaInfos {
-		gqm.runtimeQuotaCalculatorMap[subName] = NewRuntimeQuotaCalculator(subName)
+		gqm.runtimeQuotaCalculatorMap[subName] = gqm.newRuntimeQuotaCalculatorNoLock(subName)
+		gqm.runtimeQuotaCalculatorMap[subName].setChildrenOrder(topoNode.quotaInfo.ChildrenOrder)
+		gqm.runtimeQuotaCalculatorMap[subName].setSharingPolicies(topoNode.quotaInfo.SharingPolicies)
 
 		gqm.updateOneGroupMaxQuotaNoLock(topoNode.quotaInfo)
 		gqm.updateMinQuotaNoLock(topoNode.quotaInfo)
@@ -603,6 +719,7 @@ func (gqm *GroupQuotaManager) updateMinQuotaNoLock(quotaInfo *QuotaInfo) {
 	gqm.updateOneGroupOriginalMinQuotaNoLock(quotaInfo)
 	gqm.scaleMinQuotaManager.update(quotaInfo.ParentName, quotaInfo.Name,
 		quotaInfo.CalculateInfo.Min.DeepCopy(), gqm.scaleMinQuotaEnabled)
+	gqm.scaleMinQuotaManager.setMinPriority(quotaInfo.Name, quotaInfo.MinPriority)
 }
 
 // updateOneGroupOriginalMinQuotaNoLock no need to lock gqm.lock
@@ -651,15 +768,34 @@ func (gqm *GroupQuotaManager) updateResourceKeyNoLock() {
 	}
 }
 
-func (gqm *GroupQuotaManager) GetAllQuotaNames() map[string]struct{} {
-	quotaInfoMap := make(map[string]struct{})
+// GetAllQuotaNames returns the names of all quotas in the manager, sorted in ascending order
+// so that the callers get a deterministic result.
+func (gqm *GroupQuotaManager) GetAllQuotaNames() []string {
 	gqm.hierarchyUpdateLock.RLock()
 	defer gqm.hierarchyUpdateLock.RUnlock()
 
+	quotaNames := make([]string, 0, len(gqm.quotaInfoMap))
 	for name := range gqm.quotaInfoMap {
-		quotaInfoMap[name] = struct{}{}
+		quotaNames = append(quotaNames, name)
+	}
+	sort.Strings(quotaNames)
+	return quotaNames
+}
+
+// ForEachQuota calls fn with every quotaInfo of the manager. The quotaInfos are deep copied holding the
+// hierarchyUpdateLock exclusively, which keeps out the pod events updating the used and request under the
+// read lock, so fn sees a consistent snapshot of the tree and is free to call back into the manager.
+func (gqm *GroupQuotaManager) ForEachQuota(fn func(*QuotaInfo)) {
+	gqm.hierarchyUpdateLock.Lock()
+	snapshot := make([]*QuotaInfo, 0, len(gqm.quotaInfoMap))
+	for _, quotaInfo := range gqm.quotaInfoMap {
+		snapshot = append(snapshot, quotaInfo.DeepCopy())
+	}
+	gqm.hierarchyUpdateLock.Unlock()
+
+	for _, quotaInfo := range snapshot {
+		fn(quotaInfo)
 	}
-	return quotaInfoMap
 }
 
 func (gqm *GroupQuotaManager) updatePodRequestNoLock(quotaName string, oldPod, newPod *v1.Pod) {
@@ -669,16 +805,16 @@ func (gqm *GroupQuotaManager) updatePodRequestNoLock(quotaName string, oldPod, n
 	}
 
 	var oldPodReq, newPodReq, oldNonPreemptibleRequest, newNonPreemptibleRequest v1.ResourceList
-	if oldPod != nil {
-		oldPodReq = PodRequests(oldPod)
-		if extension.IsPodNonPreemptible(oldPod) {
+	if oldPod != nil && isPodRequestCounted(oldPod) {
+		oldPodReq = gqm.podRequestsNoLock(quotaInfo, oldPod)
+		if gqm.isPodNonPreemptibleNoLock(quotaName, oldPod) {
 			oldNonPreemptibleRequest = oldPodReq
 		}
 	}
 
-	if newPod != nil {
-		newPodReq = PodRequests(newPod)
-		if extension.IsPodNonPreemptible(newPod) {
+	if newPod != nil && isPodRequestCounted(newPod) {
+		newPodReq = gqm.podRequestsNoLock(quotaInfo, newPod)
+		if gqm.isPodNonPreemptibleNoLock(quotaName, newPod) {
 			newNonPreemptibleRequest = newPodReq
 		}
 	}
@@ -705,15 +841,15 @@ func (gqm *GroupQuotaManager) updatePodUsedNoLock(quotaName string, oldPod, newP
 
 	var oldPodUsed, newPodUsed, oldNonPreemptibleUsed, newNonPreemptibleUsed v1.ResourceList
 	if oldPod != nil {
-		oldPodUsed = PodRequests(oldPod)
-		if extension.IsPodNonPreemptible(oldPod) {
+		oldPodUsed = gqm.podRequestsNoLock(quotaInfo, oldPod)
+		if gqm.isPodNonPreemptibleNoLock(quotaName, oldPod) {
 			oldNonPreemptibleUsed = oldPodUsed
 		}
 	}
 
 	if newPod != nil {
-		newPodUsed = PodRequests(newPod)
-		if extension.IsPodNonPreemptible(newPod) {
+		newPodUsed = gqm.podRequestsNoLock(quotaInfo, newPod)
+		if gqm.isPodNonPreemptibleNoLock(quotaName, newPod) {
 			newNonPreemptibleUsed = newPodUsed
 		}
 	}
@@ -731,6 +867,35 @@ func (gqm *GroupQuotaManager) updatePodUsedNoLock(quotaName string, oldPod, newP
 	gqm.runPodUpdateHooks(quotaName, oldPod, newPod)
 }
 
+// PodRequests returns the requests of the pod accounted in the quota, including its resource claims.
+func (gqm *GroupQuotaManager) PodRequests(quotaName string, pod *v1.Pod) v1.ResourceList {
+	gqm.hierarchyUpdateLock.RLock()
+	defer gqm.hierarchyUpdateLock.RUnlock()
+
+	return gqm.podRequestsNoLock(gqm.getQuotaInfoByNameNoLock(quotaName), pod)
+}
+
+// podRequestsNoLock reuses the resource claim requests cached when the pod was added to the quota, so that the
+// pod is accounted the same amount on add, update and delete. The claims of the pod not in the quota are resolved.
+func (gqm *GroupQuotaManager) podRequestsNoLock(quotaInfo *QuotaInfo, pod *v1.Pod) v1.ResourceList {
+	reqs := PodRequests(pod)
+	if len(pod.Spec.ResourceClaims) == 0 {
+		return reqs
+	}
+	var claimRequests v1.ResourceList
+	cached := false
+	if quotaInfo != nil {
+		claimRequests, cached = quotaInfo.getPodClaimRequests(pod)
+	}
+	if !cached {
+		claimRequests = PodResourceClaimRequests(pod, gqm.resourceClaimClassGetter)
+	}
+	if len(claimRequests) == 0 {
+		return reqs
+	}
+	return quotav1.Add(reqs, claimRequests)
+}
+
 func (gqm *GroupQuotaManager) updatePodCacheNoLock(quotaName string, pod *v1.Pod, isAdd bool) {
 	quotaInfo := gqm.getQuotaInfoByNameNoLock(quotaName)
 	if quotaInfo == nil {
@@ -738,7 +903,7 @@ func (gqm *GroupQuotaManager) updatePodCacheNoLock(quotaName string, pod *v1.Pod
 	}
 
 	if isAdd {
-		quotaInfo.addPodIfNotPresent(pod)
+		quotaInfo.addPodIfNotPresent(pod, PodResourceClaimRequests(pod, gqm.resourceClaimClassGetter))
 	} else {
 		quotaInfo.removePodIfPresent(pod)
 	}
@@ -790,9 +955,83 @@ func (gqm *GroupQuotaManager) GetQuotaSummary(quotaName string, includePods bool
 	}
 
 	quotaSummary := quotaInfo.GetQuotaSummary(gqm.treeID, includePods)
+	quotaSummary.Children = gqm.getChildQuotaNamesNoLock(quotaName)
 	return quotaSummary, true
 }
 
+// GetQuotaTopologySummary returns the summaries of all the quotas of the manager in the shape of the quota tree.
+func (gqm *GroupQuotaManager) GetQuotaTopologySummary(includePods bool) *QuotaTopologySummary {
+	gqm.hierarchyUpdateLock.RLock()
+	defer gqm.hierarchyUpdateLock.RUnlock()
+
+	return gqm.getQuotaTopologySummaryNoLock(extension.RootQuotaName, includePods)
+}
+
+func (gqm *GroupQuotaManager) getQuotaTopologySummaryNoLock(quotaName string, includePods bool) *QuotaTopologySummary {
+	quotaInfo := gqm.getQuotaInfoByNameNoLock(quotaName)
+	if quotaInfo == nil {
+		return nil
+	}
+	summary := &QuotaTopologySummary{
+		Quota: quotaInfo.GetQuotaSummary(gqm.treeID, includePods),
+	}
+	summary.Quota.Children = gqm.getChildQuotaNamesNoLock(quotaName)
+	for _, childName := range summary.Quota.Children {
+		if child := gqm.getQuotaTopologySummaryNoLock(childName, includePods); child != nil {
+			summary.Children = append(summary.Children, child)
+		}
+	}
+	return summary
+}
+
+// getChildQuotaNamesNoLock returns the sorted names of the children of the quota.
+func (gqm *GroupQuotaManager) getChildQuotaNamesNoLock(quotaName string) []string {
+	topoNode := gqm.quotaTopoNodeMap[quotaName]
+	if topoNode == nil || len(topoNode.childGroupQuotaInfos) == 0 {
+		return nil
+	}
+	childNames := make([]string, 0, len(topoNode.childGroupQuotaInfos))
+	for childName := range topoNode.childGroupQuotaInfos {
+		childNames = append(childNames, childName)
+	}
+	sort.Strings(childNames)
+	return childNames
+}
+
+// GetQuotaSteadyAndBurstUsed returns the steady-state used and the burst used of the quota,
+// the parent quota sums those of all its descendants.
+func (gqm *GroupQuotaManager) GetQuotaSteadyAndBurstUsed(quotaName string, now time.Time,
+	steadyDuration time.Duration) (v1.ResourceList, v1.ResourceList, bool) {
+	gqm.hierarchyUpdateLock.RLock()
+	defer gqm.hierarchyUpdateLock.RUnlock()
+
+	if gqm.getQuotaInfoByNameNoLock(quotaName) == nil {
+		return nil, nil, false
+	}
+	steadyUsed, burstUsed := gqm.getQuotaSteadyAndBurstUsedNoLock(quotaName, now, steadyDuration)
+	return steadyUsed, burstUsed, true
+}
+
+func (gqm *GroupQuotaManager) getQuotaSteadyAndBurstUsedNoLock(quotaName string, now time.Time,
+	steadyDuration time.Duration) (v1.ResourceList, v1.ResourceList) {
+	topoNode := gqm.quotaTopoNodeMap[quotaName]
+	if topoNode == nil || len(topoNode.childGroupQuotaInfos) == 0 {
+		quotaInfo := gqm.getQuotaInfoByNameNoLock(quotaName)
+		if quotaInfo == nil {
+			return v1.ResourceList{}, v1.ResourceList{}
+		}
+		return quotaInfo.GetSteadyAndBurstUsed(now, steadyDuration)
+	}
+
+	steadyUsed, burstUsed := v1.ResourceList{}, v1.ResourceList{}
+	for childName := range topoNode.childGroupQuotaInfos {
+		childSteadyUsed, childBurstUsed := gqm.getQuotaSteadyAndBurstUsedNoLock(childName, now, steadyDuration)
+		steadyUsed = quotav1.Add(steadyUsed, childSteadyUsed)
+		burstUsed = quotav1.Add(burstUsed, childBurstUsed)
+	}
+	return steadyUsed, burstUsed
+}
+
 func (gqm *GroupQuotaManager) GetQuotaSummaries(includePods bool) map[string]*QuotaInfoSummary {
 	gqm.hierarchyUpdateLock.RLock()
 	defer gqm.hierarchyUpdateLock.RUnlock()
@@ -836,6 +1075,7 @@ func (gqm *GroupQuotaManager) OnPodAdd(quotaName string, pod *v1.Pod) {
 		gqm.updatePodIsAssignedNoLock(quotaName, pod, true)
 		gqm.updatePodUsedNoLock(quotaName, nil, pod)
 	}
+	gqm.refreshRuntimeIfEagerNoLock(quotaName)
 }
 
 func (gqm *GroupQuotaManager) OnPodUpdate(newQuotaName, oldQuotaName string, newPod, oldPod *v1.Pod) {
@@ -864,9 +1104,16 @@ func (gqm *GroupQuotaManager) OnPodUpdate(newQuotaName, oldQuotaName string, new
 
 			isAssigned := gqm.getPodIsAssignedNoLock(newQuotaName, newPod)
 			if isAssigned {
-				// reserve phase will assign the pod. Just update it.
-				// upgrade will change the resource.
-				gqm.updatePodUsedNoLock(newQuotaName, oldPod, newPod)
+				if util.IsPodTerminated(newPod) {
+					// the pod has completed(e.g. Succeeded with restartPolicy Never), release its used.
+					gqm.updatePodUsedNoLock(newQuotaName, oldPod, nil)
+					gqm.updatePodIsAssignedNoLock(newQuotaName, newPod, false)
+				} else {
+					// reserve phase will assign the pod. Just update it.
+					// upgrade will change the resource.
+					// the node may change, the used is replaced rather than added again.
+					gqm.updatePodUsedNoLock(newQuotaName, oldPod, newPod)
+				}
 			} else {
 				if newPod.Spec.NodeName != "" && !util.IsPodTerminated(newPod) {
 					// assign it
@@ -902,7 +1149,9 @@ func (gqm *GroupQuotaManager) OnPodUpdate(newQuotaName, oldQuotaName string, new
 				gqm.updatePodUsedNoLock(newQuotaName, nil, newPod)
 			}
 		}
+		gqm.refreshRuntimeIfEagerNoLock(oldQuotaName)
 	}
+	gqm.refreshRuntimeIfEagerNoLock(newQuotaName)
 }
 
 func (gqm *GroupQuotaManager) OnPodDelete(quotaName string, pod *v1.Pod) {
@@ -922,6 +1171,7 @@ func (gqm *GroupQuotaManager) OnPodDelete(quotaName string, pod *v1.Pod) {
 	gqm.updatePodRequestNoLock(quotaName, pod, nil)
 	gqm.updatePodUsedNoLock(quotaName, pod, nil)
 	gqm.updatePodCacheNoLock(quotaName, pod, false)
+	gqm.refreshRuntimeIfEagerNoLock(quotaName)
 }
 
 func (gqm *GroupQuotaManager) ReservePod(quotaName string, p *v1.Pod) {
@@ -1022,6 +1272,125 @@ func (gqm *GroupQuotaManager) GetTreeID() string {
 	return gqm.treeID
 }
 
+// SetSchedulingStrategy sets the default scheduling strategy of the tree.
+func (gqm *GroupQuotaManager) SetSchedulingStrategy(strategy extension.QuotaSchedulingStrategy) {
+	gqm.hierarchyUpdateLock.Lock()
+	defer gqm.hierarchyUpdateLock.Unlock()
+
+	gqm.schedulingStrategy = strategy
+}
+
+// GetSchedulingStrategy resolves the scheduling strategy of the quota. The strategy declared by the quota wins,
+// otherwise it's inherited from the nearest ancestor which declares one, and at last from the tree default.
+func (gqm *GroupQuotaManager) GetSchedulingStrategy(quotaName string) extension.QuotaSchedulingStrategy {
+	gqm.hierarchyUpdateLock.RLock()
+	defer gqm.hierarchyUpdateLock.RUnlock()
+
+	for _, quotaInfo := range gqm.getCurToAllParentGroupQuotaInfoNoLock(quotaName) {
+		quotaInfo.lock.RLock()
+		strategy := quotaInfo.SchedulingStrategy
+		quotaInfo.lock.RUnlock()
+		if strategy != "" {
+			return strategy
+		}
+	}
+	return gqm.schedulingStrategy
+}
+
+// SetDefaultNonPreemptibleQuotas sets the quotas whose pods are non-preemptible by default. It should be set
+// before any pod is added, since the non-preemptible used isn't recalculated.
+func (gqm *GroupQuotaManager) SetDefaultNonPreemptibleQuotas(quotaNames []string) {
+	gqm.hierarchyUpdateLock.Lock()
+	defer gqm.hierarchyUpdateLock.Unlock()
+
+	gqm.defaultNonPreemptibleQuotas = sets.NewString(quotaNames...)
+}
+
+// IsPodNonPreemptible checks whether the pod of the quota is non-preemptible. The pod follows the default
+// of the quota unless it has the preemptible label.
+func (gqm *GroupQuotaManager) IsPodNonPreemptible(quotaName string, pod *v1.Pod) bool {
+	gqm.hierarchyUpdateLock.RLock()
+	defer gqm.hierarchyUpdateLock.RUnlock()
+
+	return gqm.isPodNonPreemptibleNoLock(quotaName, pod)
+}
+
+func (gqm *GroupQuotaManager) isPodNonPreemptibleNoLock(quotaName string, pod *v1.Pod) bool {
+	return extension.IsPodNonPreemptibleWithDefault(pod, gqm.defaultNonPreemptibleQuotas.Has(quotaName))
+}
+
+// SetResourceClaimClassGetter sets the getter resolving the resource classes of the pods' resource claims,
+// which are accounted in the quotas if ElasticQuotaResourceClaims is enabled.
+func (gqm *GroupQuotaManager) SetResourceClaimClassGetter(getter ResourceClaimClassGetter) {
+	gqm.hierarchyUpdateLock.Lock()
+	defer gqm.hierarchyUpdateLock.Unlock()
+
+	gqm.resourceClaimClassGetter = getter
+}
+
+// SetGuaranteeMinRuntime sets whether the runtime of every quota is kept at least its min regardless of its
+// request. It should be set before any quota is added, since the request of the existing quotas isn't recalculated.
+func (gqm *GroupQuotaManager) SetGuaranteeMinRuntime(guarantee bool) {
+	gqm.hierarchyUpdateLock.Lock()
+	defer gqm.hierarchyUpdateLock.Unlock()
+
+	gqm.guaranteeMinRuntime = guarantee
+}
+
+// isMinRuntimeGuaranteedNoLock returns whether the quota requests at least its min, so that its runtime
+// doesn't drop below the min and the floor is counted in the request of its parents.
+func (gqm *GroupQuotaManager) isMinRuntimeGuaranteedNoLock(quotaInfo *QuotaInfo) bool {
+	return gqm.guaranteeMinRuntime || !quotaInfo.AllowLentResource
+}
+
+// SetRuntimeRefreshStrategy sets the runtime refresh strategy of the tree.
+func (gqm *GroupQuotaManager) SetRuntimeRefreshStrategy(strategy extension.QuotaRuntimeRefreshStrategy) {
+	gqm.hierarchyUpdateLock.Lock()
+	defer gqm.hierarchyUpdateLock.Unlock()
+
+	gqm.runtimeRefreshStrategy = strategy
+}
+
+func (gqm *GroupQuotaManager) GetRuntimeRefreshStrategy() extension.QuotaRuntimeRefreshStrategy {
+	gqm.hierarchyUpdateLock.RLock()
+	defer gqm.hierarchyUpdateLock.RUnlock()
+
+	return gqm.runtimeRefreshStrategy
+}
+
+// SetRuntimeDistribution sets how the runtime of the parent quotas is distributed to their children in the tree.
+func (gqm *GroupQuotaManager) SetRuntimeDistribution(distribution extension.QuotaRuntimeDistribution) {
+	gqm.hierarchyUpdateLock.Lock()
+	defer gqm.hierarchyUpdateLock.Unlock()
+
+	gqm.runtimeDistribution = distribution
+	for _, calculator := range gqm.runtimeQuotaCalculatorMap {
+		calculator.setRuntimeDistribution(distribution)
+	}
+}
+
+func (gqm *GroupQuotaManager) GetRuntimeDistribution() extension.QuotaRuntimeDistribution {
+	gqm.hierarchyUpdateLock.RLock()
+	defer gqm.hierarchyUpdateLock.RUnlock()
+
+	return gqm.runtimeDistribution
+}
+
+func (gqm *GroupQuotaManager) newRuntimeQuotaCalculatorNoLock(treeName string) *RuntimeQuotaCalculator {
+	calculator := NewRuntimeQuotaCalculator(treeName)
+	calculator.setRuntimeDistribution(gqm.runtimeDistribution)
+	return calculator
+}
+
+// refreshRuntimeIfEagerNoLock refreshes the runtime of the quota right after its pods change when the tree
+// refreshes eagerly, the lazy tree defers it until the runtime is read.
+func (gqm *GroupQuotaManager) refreshRuntimeIfEagerNoLock(quotaName string) {
+	if gqm.runtimeRefreshStrategy != extension.QuotaRuntimeRefreshStrategyEager {
+		return
+	}
+	gqm.refreshRuntimeNoLock(quotaName)
+}
+
 func (gqm *GroupQuotaManager) resetRootQuotaUsedAndRequest() {
 	rootQuotaInfo := gqm.getQuotaInfoByNameNoLock(extension.RootQuotaName)
 	rootQuotaInfo.lock.Lock()
@@ -1093,22 +1462,34 @@ func (gqm *GroupQuotaManager) updateQuotaInternalNoLock(newQuotaInfo, oldQuotaIn
 
 	// update quota info map
 	if oldQuotaInfo == nil {
-		gqm.runtimeQuotaCalculatorMap[newQuotaInfo.Name] = NewRuntimeQuotaCalculator(newQuotaInfo.Name)
+		gqm.runtimeQuotaCalculatorMap[newQuotaInfo.Name] = gqm.newRuntimeQuotaCalculatorNoLock(newQuotaInfo.Name)
 		if gqm.runtimeQuotaCalculatorMap[newQuotaInfo.ParentName] == nil {
-			gqm.runtimeQuotaCalculatorMap[newQuotaInfo.ParentName] = NewRuntimeQuotaCalculator(newQuotaInfo.ParentName)
+			gqm.runtimeQuotaCalculatorMap[newQuotaInfo.ParentName] = gqm.newRuntimeQuotaCalculatorNoLock(newQuotaInfo.ParentName)
 		}
 		gqm.quotaInfoMap[newQuotaInfo.Name] = NewQuotaInfo(newQuotaInfo.IsParent, newQuotaInfo.AllowLentResource, newQuotaInfo.Name, newQuotaInfo.ParentName)
 	}
 
+	localQuotaInfo := gqm.quotaInfoMap[newQuotaInfo.Name]
+	localQuotaInfo.lock.Lock()
+	localQuotaInfo.setAttributesNoLock(newQuotaInfo)
+	localQuotaInfo.lock.Unlock()
+	gqm.runtimeQuotaCalculatorMap[newQuotaInfo.Name].setChildrenOrder(newQuotaInfo.ChildrenOrder)
+	gqm.runtimeQuotaCalculatorMap[newQuotaInfo.Name].setSharingPolicies(newQuotaInfo.SharingPolicies)
+	gqm.scaleMinQuotaManager.setMinPriority(newQuotaInfo.Name, newQuotaInfo.MinPriority)
+
 	oldMax := v1.ResourceList{}
+	var oldOvercommitRatios map[v1.ResourceName]float64
 	if oldQuotaInfo != nil {
 		oldMax = oldQuotaInfo.CalculateInfo.Max
+		oldOvercommitRatios = oldQuotaInfo.OvercommitRatios
 	}
-	// max changed
-	if !quotav1.Equals(newQuotaInfo.CalculateInfo.Max, oldMax) {
-		klog.V(4).Infof("[updateQuotaInternalNoLock] quota %v max change, oldMax: %v, newMax: %v",
-			newQuotaInfo.Name, util.DumpJSON(oldMax), util.DumpJSON(newQuotaInfo.CalculateInfo.Max))
-		gqm.doUpdateOneGroupMaxQuotaNoLock(newQuotaInfo.Name, newQuotaInfo.CalculateInfo.Max)
+	// max changed, the overcommit ratios scale the max
+	if !quotav1.Equals(newQuotaInfo.CalculateInfo.Max, oldMax) ||
+		!isSameOvercommitRatios(newQuotaInfo.OvercommitRatios, oldOvercommitRatios) {
+		klog.V(4).Infof("[updateQuotaInternalNoLock] quota %v max change, oldMax: %v, newMax: %v, oldOvercommitRatios: %v, newOvercommitRatios: %v",
+			newQuotaInfo.Name, util.DumpJSON(oldMax), util.DumpJSON(newQuotaInfo.CalculateInfo.Max),
+			util.DumpJSON(oldOvercommitRatios), util.DumpJSON(newQuotaInfo.OvercommitRatios))
+		gqm.doUpdateOneGroupMaxQuotaNoLock(newQuotaInfo.Name, newQuotaInfo.CalculateInfo.Max, newQuotaInfo.OvercommitRatios)
 	}
 
 	// update resource keys
@@ -1170,6 +1551,8 @@ func (gqm *GroupQuotaManager) updateQuotaNoLockWhenParentChange(newQuota *v1alph
 	// copy pod cache
 	newQuotaInfo.PodCache = oldQuotaInfo.PodCache
 	gqm.quotaInfoMap[newQuotaInfo.Name] = newQuotaInfo
+	// the cached ancestors of the quota and its descendants refer to the replaced quota info
+	gqm.invalidateAncestorCacheNoLock()
 
 	// run pre-quota-update hookPlugins
 	hookState = gqm.runPreQuotaUpdateHooks(nil, newQuotaInfo, newQuota)
@@ -1178,10 +1561,12 @@ func (gqm *GroupQuotaManager) updateQuotaNoLockWhenParentChange(newQuota *v1alph
 		// reuse runtimeQuotaCalculator
 		gqm.runtimeQuotaCalculatorMap[newQuotaInfo.Name] = oldRuntimeQuotaCalculator
 	} else {
-		gqm.runtimeQuotaCalculatorMap[newQuotaInfo.Name] = NewRuntimeQuotaCalculator(newQuotaInfo.Name)
+		gqm.runtimeQuotaCalculatorMap[newQuotaInfo.Name] = gqm.newRuntimeQuotaCalculatorNoLock(newQuotaInfo.Name)
 	}
+	gqm.runtimeQuotaCalculatorMap[newQuotaInfo.Name].setChildrenOrder(newQuotaInfo.ChildrenOrder)
+	gqm.runtimeQuotaCalculatorMap[newQuotaInfo.Name].setSharingPolicies(newQuotaInfo.SharingPolicies)
 	if gqm.runtimeQuotaCalculatorMap[newQuotaInfo.ParentName] == nil {
-		gqm.runtimeQuotaCalculatorMap[newQuotaInfo.ParentName] = NewRuntimeQuotaCalculator(newQuotaInfo.ParentName)
+		gqm.runtimeQuotaCalculatorMap[newQuotaInfo.ParentName] = gqm.newRuntimeQuotaCalculatorNoLock(newQuotaInfo.ParentName)
 	}
 
 	gqm.quotaTopoNodeMap[newQuotaInfo.Name] = oldQuotaTopoNode
@@ -1190,7 +1575,7 @@ func (gqm *GroupQuotaManager) updateQuotaNoLockWhenParentChange(newQuota *v1alph
 	// 4. update max/min/shared weight
 	klog.V(4).Infof("[updateQuotaNoLockWhenParentChange] quota %v max change, newMax: %v",
 		newQuotaInfo.Name, util.DumpJSON(newMax))
-	gqm.doUpdateOneGroupMaxQuotaNoLock(newQuotaInfo.Name, newMax)
+	gqm.doUpdateOneGroupMaxQuotaNoLock(newQuotaInfo.Name, newMax, newQuotaInfo.OvercommitRatios)
 
 	gqm.updateResourceKeyNoLock()
 
@@ -1261,7 +1646,8 @@ func (gqm *GroupQuotaManager) updateQuotaTopoNodeNoLock(newQuotaInfo, oldQuotaIn
 	parentNode.childGroupQuotaInfos[newQuotaInfo.Name] = node
 }
 
-func (gqm *GroupQuotaManager) doUpdateOneGroupMaxQuotaNoLock(quotaName string, newMax v1.ResourceList) {
+func (gqm *GroupQuotaManager) doUpdateOneGroupMaxQuotaNoLock(quotaName string, newMax v1.ResourceList,
+	newOvercommitRatios map[v1.ResourceName]float64) {
 	curToAllParInfos := gqm.getCurToAllParentGroupQuotaInfoNoLock(quotaName)
 	quotaInfoLen := len(curToAllParInfos)
 	if quotaInfoLen <= 0 {
@@ -1273,6 +1659,7 @@ func (gqm *GroupQuotaManager) doUpdateOneGroupMaxQuotaNoLock(quotaName string, n
 	curQuotaInfo := curToAllParInfos[0]
 	oldSubLimitReq := curQuotaInfo.getLimitRequestNoLock()
 	curQuotaInfo.setMaxNoLock(newMax)
+	curQuotaInfo.OvercommitRatios = copyOvercommitRatios(newOvercommitRatios)
 
 	if quotaInfoLen > 1 {
 		parentRuntimeCalculator := gqm.getRuntimeQuotaCalculatorByNameNoLock(curQuotaInfo.ParentName)
@@ -1306,7 +1693,7 @@ func (gqm *GroupQuotaManager) doUpdateOneGroupMinQuotaNoLock(quotaName string, n
 	// Update request. If the quota not allow to lent resource, the new min will effect the request.
 	oldSubLimitReq := curQuotaInfo.getLimitRequestNoLock()
 	realRequest := curQuotaInfo.CalculateInfo.ChildRequest.DeepCopy()
-	if !curQuotaInfo.AllowLentResource {
+	if gqm.isMinRuntimeGuaranteedNoLock(curQuotaInfo) {
 		realRequest = quotav1.Max(realRequest, curQuotaInfo.CalculateInfo.Min)
 	}
 	curQuotaInfo.CalculateInfo.Request = realRequest
@@ -1379,6 +1766,8 @@ func (gqm *GroupQuotaManager) deleteQuotaNoLock(quota *v1alpha1.ElasticQuota) er
 		return fmt.Errorf("get quota info failed, quotaName:%v", quota.Name)
 	}
 	delete(gqm.quotaInfoMap, quota.Name)
+	gqm.changeNotifier.markChanged(quota.Name)
+	gqm.invalidateAncestorCacheNoLock()
 
 	// handle runtimeQuotaCalculator.
 	quotaInfo.lock.Lock()
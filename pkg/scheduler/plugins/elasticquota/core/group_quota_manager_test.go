@@ -17,10 +17,15 @@ limitations under the License.
 package core
 
 import (
+	"bytes"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"math"
 	"math/rand"
+	"os"
+	"sort"
+	"sync"
 	"testing"
 	"time"
 
@@ -32,6 +37,7 @@ import (
 	k8sfeature "k8s.io/apiserver/pkg/util/feature"
 	"k8s.io/klog/v2"
 	schetesting "k8s.io/kubernetes/pkg/scheduler/testing"
+	"k8s.io/utils/clock"
 
 	"github.com/koordinator-sh/koordinator/apis/extension"
 	"github.com/koordinator-sh/koordinator/apis/thirdparty/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
@@ -860,6 +866,54 @@ func TestGroupQuotaManager_MultiUpdateQuotaRequest_WithScaledMinQuota1(t *testin
 	assert.Equal(t, createResourceList(100, 100*GigaByte), quotaInfo.CalculateInfo.AutoScaleMin)
 }
 
+// TestGroupQuotaManager_ScaledMinQuotaByPriority test the quotaGroups with higher min priority keep their minQuota
+// when the cluster shrinks and the sum of the minQuota is larger than totalRes.
+func TestGroupQuotaManager_ScaledMinQuotaByPriority(t *testing.T) {
+	gqm := NewGroupQuotaManagerForTest()
+	gqm.scaleMinQuotaEnabled = true
+
+	quotaA := CreateQuota("a", extension.RootQuotaName, 1000, 1000*GigaByte, 100, 100*GigaByte, true, false)
+	quotaA.Annotations[extension.AnnotationMinPriority] = "10"
+	assert.NoError(t, gqm.UpdateQuota(quotaA))
+	AddQuotaToManager(t, gqm, "b", extension.RootQuotaName, 1000, 1000*GigaByte, 100, 100*GigaByte, true, false)
+	AddQuotaToManager(t, gqm, "c", extension.RootQuotaName, 1000, 1000*GigaByte, 100, 100*GigaByte, true, false)
+
+	gqm.UpdateClusterTotalResource(createResourceList(300, 300*GigaByte))
+	for _, quotaName := range []string{"a", "b", "c"} {
+		gqm.RefreshRuntime(quotaName)
+		assert.Equal(t, createResourceList(100, 100*GigaByte), gqm.GetQuotaInfoByName(quotaName).CalculateInfo.AutoScaleMin)
+	}
+
+	// the cluster shrinks, "a" keeps its min, "b" and "c" share the rest
+	gqm.UpdateClusterTotalResource(createResourceList(-150, -150*GigaByte))
+	expected := map[string]v1.ResourceList{
+		"a": createResourceList(100, 100*GigaByte),
+		"b": createResourceList(25, 25*GigaByte),
+		"c": createResourceList(25, 25*GigaByte),
+	}
+	for quotaName, min := range expected {
+		gqm.RefreshRuntime(quotaName)
+		assert.True(t, quotav1.Equals(min, gqm.GetQuotaInfoByName(quotaName).CalculateInfo.AutoScaleMin),
+			"quota %v, expected %v, got %v", quotaName, min, gqm.GetQuotaInfoByName(quotaName).CalculateInfo.AutoScaleMin)
+	}
+
+	// raise the min priority of "b" above "a"
+	quotaB := CreateQuota("b", extension.RootQuotaName, 1000, 1000*GigaByte, 100, 100*GigaByte, true, false)
+	quotaB.Annotations[extension.AnnotationMinPriority] = "20"
+	assert.NoError(t, gqm.UpdateQuota(quotaB))
+	assert.Equal(t, int32(20), gqm.GetQuotaInfoByName("b").MinPriority)
+	expected = map[string]v1.ResourceList{
+		"a": createResourceList(50, 50*GigaByte),
+		"b": createResourceList(100, 100*GigaByte),
+		"c": createResourceList(0, 0),
+	}
+	for quotaName, min := range expected {
+		gqm.RefreshRuntime(quotaName)
+		assert.True(t, quotav1.Equals(min, gqm.GetQuotaInfoByName(quotaName).CalculateInfo.AutoScaleMin),
+			"quota %v, expected %v, got %v", quotaName, min, gqm.GetQuotaInfoByName(quotaName).CalculateInfo.AutoScaleMin)
+	}
+}
+
 // TestGroupQuotaManager_MultiUpdateQuotaRequest_WithScaledMinQuota1 test scaledMinQuota when quotaGroup's sum of the
 // minQuota is larger than totalRes, with one of the quotaGroup's request is zero.
 func TestGroupQuotaManager_MultiUpdateQuotaRequest_WithScaledMinQuota2(t *testing.T) {
@@ -1492,6 +1546,8 @@ func NewGroupQuotaManagerForTest() *GroupQuotaManager {
 		runtimeQuotaCalculatorMap:               make(map[string]*RuntimeQuotaCalculator),
 		scaleMinQuotaManager:                    NewScaleMinQuotaManager(),
 		quotaTopoNodeMap:                        make(map[string]*QuotaTopoNode),
+		changeNotifier:                          newQuotaChangeNotifier(""),
+		clock:                                   clock.RealClock{},
 	}
 	systemQuotaInfo := NewQuotaInfo(false, true, extension.SystemQuotaName, extension.RootQuotaName)
 	systemQuotaInfo.CalculateInfo.Max = v1.ResourceList{
@@ -1538,6 +1594,122 @@ func BenchmarkGroupQuotaManager_RefreshRuntime(b *testing.B) {
 	}
 }
 
+func newGroupQuotaManagerWithLeaves(parents, leavesPerParent int) *GroupQuotaManager {
+	gqm := NewGroupQuotaManagerForTest()
+	for i := 0; i < parents; i++ {
+		parentName := fmt.Sprintf("parent-%v", i)
+		AddQuotaToManager2(gqm, parentName, extension.RootQuotaName, 96000, 160000*GigaByte, 100, 100*GigaByte, true, true)
+		for j := 0; j < leavesPerParent; j++ {
+			AddQuotaToManager2(gqm, fmt.Sprintf("%v-leaf-%v", parentName, j), parentName, 96, 160*GigaByte, 1, GigaByte, true, false)
+		}
+	}
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < parents; i++ {
+		for j := 0; j < leavesPerParent; j++ {
+			request := createResourceList(int64(random.Int()%4), int64(random.Int()%4)*GigaByte)
+			gqm.updateGroupDeltaRequestNoLock(fmt.Sprintf("parent-%v-leaf-%v", i, j), request, request, 0)
+		}
+	}
+	gqm.UpdateClusterTotalResource(createResourceList(int64(parents*leavesPerParent), int64(parents*leavesPerParent)*GigaByte))
+	return gqm
+}
+
+func TestGroupQuotaManager_IncrementalRefreshRuntime(t *testing.T) {
+	gqm := newGroupQuotaManagerWithLeaves(4, 5)
+
+	refreshAll := func() map[string]v1.ResourceList {
+		runtimes := map[string]v1.ResourceList{}
+		for _, name := range gqm.GetAllQuotaNames() {
+			runtimes[name] = gqm.RefreshRuntime(name)
+		}
+		return runtimes
+	}
+	fullRefreshAll := func() map[string]v1.ResourceList {
+		defer utilfeature.SetFeatureGateDuringTest(t, k8sfeature.DefaultFeatureGate, features.ElasticQuotaFullRuntimeRefresh, true)()
+		for _, name := range gqm.GetAllQuotaNames() {
+			gqm.GetQuotaInfoByName(name).RuntimeVersion = 0
+		}
+		return refreshAll()
+	}
+
+	for i := 0; i < 10; i++ {
+		request := createResourceList(int64(i%3), int64(i%3)*GigaByte)
+		gqm.updateGroupDeltaRequestNoLock(fmt.Sprintf("parent-%v-leaf-%v", i%4, i%5), request, request, 0)
+		incremental, full := refreshAll(), fullRefreshAll()
+		for name := range full {
+			assert.True(t, quotav1.Equals(full[name], incremental[name]), "quota %v: full %v, incremental %v",
+				name, full[name], incremental[name])
+		}
+	}
+
+	// pushing down the unchanged runtime of the parent doesn't invalidate its children
+	calculator := gqm.runtimeQuotaCalculatorMap["parent-0"]
+	version := calculator.getVersion()
+	calculator.setClusterTotalResource(gqm.GetQuotaInfoByName("parent-0").CalculateInfo.Runtime)
+	assert.Equal(t, version, calculator.getVersion())
+	assert.Equal(t, version, calculator.calculatedVersion)
+}
+
+func TestGroupQuotaManager_IncrementalRefreshRuntimeSkipsUnchangedSiblings(t *testing.T) {
+	for _, tt := range []struct {
+		name                   string
+		fullRefresh            bool
+		expectSiblingRefreshed bool
+	}{
+		{name: "incremental", fullRefresh: false, expectSiblingRefreshed: false},
+		{name: "full recompute", fullRefresh: true, expectSiblingRefreshed: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			defer utilfeature.SetFeatureGateDuringTest(t, k8sfeature.DefaultFeatureGate, features.ElasticQuotaFullRuntimeRefresh, tt.fullRefresh)()
+			gqm := newGroupQuotaManagerWithLeaves(4, 5)
+			// all the requests are satisfied, the runtime of a parent is its request
+			gqm.UpdateClusterTotalResource(createResourceList(10000, 10000*GigaByte))
+			for _, name := range gqm.GetAllQuotaNames() {
+				gqm.RefreshRuntime(name)
+			}
+
+			sibling := gqm.runtimeQuotaCalculatorMap["parent-1"]
+			siblingVersion := sibling.getVersion()
+			request := createResourceList(2, 2*GigaByte)
+			gqm.updateGroupDeltaRequestNoLock("parent-0-leaf-0", request, request, 0)
+			parentRuntime := gqm.GetQuotaInfoByName("parent-0").CalculateInfo.Runtime.DeepCopy()
+
+			// the path from the changed leaf up to root is refreshed
+			gqm.RefreshRuntime("parent-0-leaf-0")
+			assert.False(t, quotav1.Equals(parentRuntime, gqm.GetQuotaInfoByName("parent-0").CalculateInfo.Runtime))
+			// the sibling parent whose share doesn't change isn't pushed down to its children
+			gqm.RefreshRuntime("parent-1-leaf-0")
+			assert.Equal(t, tt.expectSiblingRefreshed, siblingVersion != sibling.getVersion())
+		})
+	}
+}
+
+func BenchmarkGroupQuotaManager_RefreshRuntimeOnPodAdd(b *testing.B) {
+	for _, tt := range []struct {
+		name        string
+		fullRefresh bool
+	}{
+		{name: "incremental"},
+		{name: "full recompute", fullRefresh: true},
+	} {
+		b.Run(tt.name, func(b *testing.B) {
+			defer utilfeature.SetFeatureGateDuringTest(b, k8sfeature.DefaultFeatureGate, features.ElasticQuotaFullRuntimeRefresh, tt.fullRefresh)()
+			// 50 parents with 100 leaves each, 5000 leaf quotas in total
+			gqm := newGroupQuotaManagerWithLeaves(50, 100)
+			request := createResourceList(1, GigaByte)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				parent, leaf := i%50, i%100
+				// a pod is added to a leaf quota, then the leaf, one of its siblings and a leaf of another parent are scheduled
+				gqm.updateGroupDeltaRequestNoLock(fmt.Sprintf("parent-%v-leaf-%v", parent, leaf), request, request, 0)
+				gqm.RefreshRuntime(fmt.Sprintf("parent-%v-leaf-%v", parent, leaf))
+				gqm.RefreshRuntime(fmt.Sprintf("parent-%v-leaf-%v", parent, (leaf+1)%100))
+				gqm.RefreshRuntime(fmt.Sprintf("parent-%v-leaf-%v", (parent+1)%50, leaf))
+			}
+		})
+	}
+}
+
 func AddQuotaToManager2(gqm *GroupQuotaManager, quotaName string, parent string,
 	maxCpu, maxMem, minCpu, minMem int64, allowLentResource bool, isParent bool) *v1alpha1.ElasticQuota {
 	quota := CreateQuota(quotaName, parent, maxCpu, maxMem, minCpu, minMem, allowLentResource, isParent)
@@ -1557,8 +1729,100 @@ func TestGroupQuotaManager_GetAllQuotaNames(t *testing.T) {
 	gqm.UpdateQuota(qi2)
 	quotaNames := gqm.GetAllQuotaNames()
 
-	assert.NotNil(t, quotaNames["1"])
-	assert.NotNil(t, quotaNames["2"])
+	assert.Contains(t, quotaNames, "1")
+	assert.Contains(t, quotaNames, "2")
+}
+
+func TestGroupQuotaManager_GetAllQuotaNamesSorted(t *testing.T) {
+	gqm := NewGroupQuotaManagerForTest()
+	gqm.UpdateClusterTotalResource(createResourceList(1000, 1000))
+
+	for _, name := range []string{"c", "a", "e", "b", "d"} {
+		AddQuotaToManager2(gqm, name, extension.RootQuotaName, 100, 100, 10, 10, true, false)
+	}
+
+	expected := gqm.GetAllQuotaNames()
+	assert.True(t, sort.StringsAreSorted(expected))
+	assert.Subset(t, expected, []string{"a", "b", "c", "d", "e"})
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, expected, gqm.GetAllQuotaNames())
+	}
+}
+
+func TestGroupQuotaManager_ForEachQuota(t *testing.T) {
+	gqm := NewGroupQuotaManagerForTest()
+	gqm.scaleMinQuotaEnabled = true
+
+	gqm.UpdateClusterTotalResource(createResourceList(1000, 1000))
+
+	qi1 := CreateQuota("1", extension.RootQuotaName, 400, 400, 10, 10, true, true)
+	qi2 := CreateQuota("2", extension.RootQuotaName, 400, 400, 10, 10, true, false)
+	qi11 := CreateQuota("1-1", "1", 200, 200, 5, 5, true, false)
+	qi12 := CreateQuota("1-2", "1", 200, 200, 5, 5, true, false)
+	gqm.UpdateQuota(qi1)
+	gqm.UpdateQuota(qi2)
+	gqm.UpdateQuota(qi11)
+	gqm.UpdateQuota(qi12)
+
+	stopCh := make(chan struct{})
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stopCh:
+				return
+			default:
+			}
+			pod := schetesting.MakePod().Namespace("test").Name(fmt.Sprintf("pod-%d", i)).Obj()
+			pod.Spec.Containers = []v1.Container{
+				{
+					Resources: v1.ResourceRequirements{
+						Requests: createResourceList(1, 1),
+					},
+				},
+			}
+			pod.Spec.NodeName = "node1"
+			childName := fmt.Sprintf("1-%d", i%2+1)
+			gqm.OnPodAdd(childName, pod)
+			if i%3 != 0 {
+				gqm.OnPodDelete(childName, pod)
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stopCh:
+				return
+			default:
+			}
+			quota := CreateQuota(fmt.Sprintf("tmp-%d", i%5), extension.RootQuotaName, 10, 10, 0, 0, true, false)
+			gqm.UpdateQuota(quota)
+			gqm.DeleteQuota(quota)
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		used := make(map[string]v1.ResourceList)
+		gqm.ForEachQuota(func(quotaInfo *QuotaInfo) {
+			used[quotaInfo.Name] = quotaInfo.GetUsed()
+			// call back into the manager must not deadlock.
+			assert.NotNil(t, gqm.GetQuotaInfoByName(extension.RootQuotaName))
+			assert.Empty(t, quotav1.IsNegative(quotaInfo.GetUsed()))
+		})
+		for _, name := range []string{extension.RootQuotaName, extension.SystemQuotaName, extension.DefaultQuotaName, "1", "2", "1-1", "1-2"} {
+			_, ok := used[name]
+			assert.True(t, ok, "quota %v not iterated", name)
+		}
+		// the used of the parent agrees with its children in the snapshot.
+		assert.True(t, quotav1.IsZero(quotav1.Subtract(used["1"], quotav1.Add(used["1-1"], used["1-2"]))),
+			"parent used %v, children used %v and %v", used["1"], used["1-1"], used["1-2"])
+	}
+	close(stopCh)
+	wg.Wait()
 }
 
 func TestGroupQuotaManager_UpdatePodCache_UpdatePodIsAssigned_GetPodIsAssigned_UpdatePodRequest_UpdatePodUsed(t *testing.T) {
@@ -2018,6 +2282,55 @@ func TestGroupQuotaManager_RootQuotaRuntime(t *testing.T) {
 	assert.Equal(t, gqm.RefreshRuntime(extension.RootQuotaName), gqm.totalResourceExceptSystemAndDefaultUsed.DeepCopy())
 }
 
+func TestGroupQuotaManager_RuntimeRefreshStrategy(t *testing.T) {
+	tests := []struct {
+		name                  string
+		strategy              extension.QuotaRuntimeRefreshStrategy
+		expectedBeforeRefresh v1.ResourceList
+	}{
+		{
+			name:                  "lazy tree refreshes only on read",
+			strategy:              extension.QuotaRuntimeRefreshStrategyLazy,
+			expectedBeforeRefresh: v1.ResourceList{},
+		},
+		{
+			name:                  "eager tree refreshes on pod events",
+			strategy:              extension.QuotaRuntimeRefreshStrategyEager,
+			expectedBeforeRefresh: createResourceList(10, 10*GigaByte),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gqm := NewGroupQuotaManagerForTest()
+			gqm.SetRuntimeRefreshStrategy(tt.strategy)
+			assert.Equal(t, tt.strategy, gqm.GetRuntimeRefreshStrategy())
+			gqm.UpdateClusterTotalResource(createResourceList(100, 100*GigaByte))
+			AddQuotaToManager(t, gqm, "1", extension.RootQuotaName, 100, 100*GigaByte, 0, 0, true, false)
+
+			pod1 := schetesting.MakePod().Name("1").Obj()
+			pod1.Spec.Containers = []v1.Container{
+				{
+					Resources: v1.ResourceRequirements{
+						Requests: createResourceList(10, 10*GigaByte),
+					},
+				},
+			}
+			gqm.OnPodAdd("1", pod1)
+			assert.True(t, quotav1.Equals(tt.expectedBeforeRefresh, gqm.GetQuotaInfoByName("1").GetRuntime()))
+
+			assert.True(t, quotav1.Equals(createResourceList(10, 10*GigaByte), gqm.RefreshRuntime("1")))
+			assert.True(t, quotav1.Equals(createResourceList(10, 10*GigaByte), gqm.GetQuotaInfoByName("1").GetRuntime()))
+
+			gqm.OnPodDelete("1", pod1)
+			expectedAfterDelete := createResourceList(10, 10*GigaByte)
+			if tt.strategy == extension.QuotaRuntimeRefreshStrategyEager {
+				expectedAfterDelete = createResourceList(0, 0)
+			}
+			assert.True(t, quotav1.Equals(expectedAfterDelete, gqm.GetQuotaInfoByName("1").GetRuntime()))
+		})
+	}
+}
+
 func TestGroupQuotaManager_OnQuotaResourceKeyUpdateForGuarantee(t *testing.T) {
 	defer utilfeature.SetFeatureGateDuringTest(t, k8sfeature.DefaultFeatureGate, features.ElasticQuotaGuaranteeUsage, true)()
 	gqm := NewGroupQuotaManagerForTest()
@@ -2691,3 +3004,479 @@ func TestGroupQuotaManager_UpdateQuotaNoLockWhenParentChange(t *testing.T) {
 	assert.Equal(t, 0, len(gqm.quotaTopoNodeMap["11"].childGroupQuotaInfos))
 	assert.Equal(t, 2, len(gqm.quotaTopoNodeMap["21"].childGroupQuotaInfos))
 }
+
+func TestGroupQuotaManager_GetSchedulingStrategy(t *testing.T) {
+	gqm := NewGroupQuotaManagerForTest()
+	gqm.UpdateClusterTotalResource(createResourceList(100, 100))
+
+	parent := CreateQuota("parent", extension.RootQuotaName, 100, 100, 10, 10, true, true)
+	child1 := CreateQuota("child1", "parent", 50, 50, 10, 10, true, false)
+	child2 := CreateQuota("child2", "parent", 50, 50, 10, 10, true, false)
+	child2.Annotations[extension.AnnotationSchedulingStrategy] = string(extension.QuotaSchedulingStrategySpread)
+	assert.NoError(t, gqm.UpdateQuota(parent))
+	assert.NoError(t, gqm.UpdateQuota(child1))
+	assert.NoError(t, gqm.UpdateQuota(child2))
+
+	// no strategy declared in the tree.
+	assert.Equal(t, extension.QuotaSchedulingStrategy(""), gqm.GetSchedulingStrategy("child1"))
+
+	// inherit the tree default.
+	gqm.SetSchedulingStrategy(extension.QuotaSchedulingStrategyBinpack)
+	assert.Equal(t, extension.QuotaSchedulingStrategyBinpack, gqm.GetSchedulingStrategy("parent"))
+	assert.Equal(t, extension.QuotaSchedulingStrategyBinpack, gqm.GetSchedulingStrategy("child1"))
+	// override the tree default.
+	assert.Equal(t, extension.QuotaSchedulingStrategySpread, gqm.GetSchedulingStrategy("child2"))
+
+	// inherit the nearest ancestor.
+	parent = parent.DeepCopy()
+	parent.Annotations[extension.AnnotationSchedulingStrategy] = string(extension.QuotaSchedulingStrategySpread)
+	assert.NoError(t, gqm.UpdateQuota(parent))
+	assert.Equal(t, extension.QuotaSchedulingStrategySpread, gqm.GetSchedulingStrategy("child1"))
+
+	// the child overrides the ancestor.
+	child1 = child1.DeepCopy()
+	child1.Annotations[extension.AnnotationSchedulingStrategy] = string(extension.QuotaSchedulingStrategyBinpack)
+	assert.NoError(t, gqm.UpdateQuota(child1))
+	assert.Equal(t, extension.QuotaSchedulingStrategyBinpack, gqm.GetSchedulingStrategy("child1"))
+	assert.Equal(t, extension.QuotaSchedulingStrategyBinpack, gqm.GetQuotaInfoByName("child1").SchedulingStrategy)
+
+	// unknown strategy is ignored.
+	child2 = child2.DeepCopy()
+	child2.Annotations[extension.AnnotationSchedulingStrategy] = "unknown"
+	assert.NoError(t, gqm.UpdateQuota(child2))
+	assert.Equal(t, extension.QuotaSchedulingStrategySpread, gqm.GetSchedulingStrategy("child2"))
+
+	// unknown quota falls back to the tree default.
+	assert.Equal(t, extension.QuotaSchedulingStrategyBinpack, gqm.GetSchedulingStrategy("not-exist"))
+}
+
+func TestGroupQuotaManager_OnPodUpdateCompleted(t *testing.T) {
+	gqm := NewGroupQuotaManagerForTest()
+	gqm.scaleMinQuotaEnabled = true
+
+	gqm.UpdateClusterTotalResource(createResourceList(50, 50))
+
+	qi1 := CreateQuota("1", extension.RootQuotaName, 40, 40, 10, 10, true, false)
+	gqm.UpdateQuota(qi1)
+
+	pod1 := schetesting.MakePod().Name("1").Obj()
+	pod1.Spec.RestartPolicy = v1.RestartPolicyNever
+	pod1.Spec.Containers = []v1.Container{
+		{
+			Resources: v1.ResourceRequirements{
+				Requests: createResourceList(10, 10),
+			},
+		},
+	}
+	pod1.Spec.NodeName = "node1"
+	pod1.Status.Phase = v1.PodRunning
+	gqm.OnPodAdd(qi1.Name, pod1)
+	assert.Equal(t, createResourceList(10, 10), gqm.GetQuotaInfoByName("1").GetRequest())
+	assert.Equal(t, createResourceList(10, 10), gqm.GetQuotaInfoByName("1").GetUsed())
+	assert.Equal(t, createResourceList(10, 10), gqm.GetQuotaInfoByName(extension.RootQuotaName).GetUsed())
+
+	// the pod completes.
+	pod2 := pod1.DeepCopy()
+	pod2.ResourceVersion = "2"
+	pod2.Status.Phase = v1.PodSucceeded
+	gqm.OnPodUpdate("1", "1", pod2, pod1)
+	assert.Equal(t, createResourceList(10, 10), gqm.GetQuotaInfoByName("1").GetRequest())
+	assert.Equal(t, createResourceList(0, 0), gqm.GetQuotaInfoByName("1").GetUsed())
+	assert.Equal(t, createResourceList(0, 0), gqm.GetQuotaInfoByName(extension.RootQuotaName).GetUsed())
+	assert.False(t, gqm.GetQuotaInfoByName("1").CheckPodIsAssigned(pod2))
+
+	// the completed pod updates again.
+	pod3 := pod2.DeepCopy()
+	pod3.ResourceVersion = "3"
+	gqm.OnPodUpdate("1", "1", pod3, pod2)
+	assert.Equal(t, createResourceList(10, 10), gqm.GetQuotaInfoByName("1").GetRequest())
+	assert.Equal(t, createResourceList(0, 0), gqm.GetQuotaInfoByName("1").GetUsed())
+
+	// delete the pod.
+	gqm.OnPodDelete("1", pod3)
+	assert.Equal(t, createResourceList(0, 0), gqm.GetQuotaInfoByName("1").GetRequest())
+	assert.Equal(t, createResourceList(0, 0), gqm.GetQuotaInfoByName("1").GetUsed())
+}
+
+func TestGroupQuotaManager_RefreshRuntimeTrace(t *testing.T) {
+	fs := flag.NewFlagSet("klog", flag.ContinueOnError)
+	klog.InitFlags(fs)
+	oldVerbosity := fs.Lookup("v").Value.String()
+	oldLogToStderr := fs.Lookup("logtostderr").Value.String()
+	buf := &bytes.Buffer{}
+	assert.NoError(t, fs.Set("logtostderr", "false"))
+	klog.SetOutput(buf)
+	defer func() {
+		klog.SetOutput(os.Stderr)
+		fs.Set("v", oldVerbosity)
+		fs.Set("logtostderr", oldLogToStderr)
+	}()
+
+	gqm := NewGroupQuotaManagerForTest()
+	gqm.UpdateClusterTotalResource(createResourceList(100, 100))
+	AddQuotaToManager(t, gqm, "p", extension.RootQuotaName, 60, 60, 20, 20, true, true)
+	AddQuotaToManager(t, gqm, "a", "p", 40, 40, 10, 10, true, false)
+	AddQuotaToManager(t, gqm, "b", "p", 40, 40, 0, 0, true, false)
+
+	// the trace is disabled below the verbosity
+	assert.NoError(t, fs.Set("v", fmt.Sprintf("%d", RuntimeTraceVerbosity-1)))
+	gqm.updateGroupDeltaRequestNoLock("a", createResourceList(15, 15), createResourceList(15, 15), 0)
+	gqm.RefreshRuntime("a")
+	klog.Flush()
+	assert.NotContains(t, buf.String(), "RefreshRuntime step")
+	assert.NotContains(t, buf.String(), "RuntimeQuota redistribution")
+
+	assert.NoError(t, fs.Set("v", fmt.Sprintf("%d", RuntimeTraceVerbosity)))
+	gqm.updateGroupDeltaRequestNoLock("b", createResourceList(30, 30), createResourceList(30, 30), 0)
+	assert.Equal(t, createResourceList(15, 15), gqm.RefreshRuntime("a"))
+	klog.Flush()
+	logs := buf.String()
+	// inputs of each level from the root to the quota
+	assert.Contains(t, logs, fmt.Sprintf(`"RefreshRuntime step" quotaName="p" parentName=%q`, extension.RootQuotaName))
+	assert.Contains(t, logs, `"RefreshRuntime step" quotaName="a" parentName="p"`)
+	// per-child shares and leftovers of both the root tree and the sub tree
+	assert.Contains(t, logs, fmt.Sprintf(`"RuntimeQuota redistribution share" treeName=%q`, extension.RootQuotaName))
+	assert.Contains(t, logs, `"RuntimeQuota redistribution share" treeName="p"`)
+	assert.Contains(t, logs, `quotaName="b"`)
+	assert.Contains(t, logs, `"RuntimeQuota redistribution finish" treeName="p"`)
+	assert.Contains(t, logs, "leftover=")
+}
+
+func TestGroupQuotaManager_OnPodUpdateNodeChange(t *testing.T) {
+	gqm := NewGroupQuotaManagerForTest()
+	gqm.scaleMinQuotaEnabled = true
+	gqm.UpdateClusterTotalResource(createResourceList(50, 50))
+
+	qi1 := CreateQuota("1", extension.RootQuotaName, 40, 40, 10, 10, true, false)
+	gqm.UpdateQuota(qi1)
+
+	pod1 := schetesting.MakePod().Name("1").Node("node1").Obj()
+	pod1.Spec.Containers = []v1.Container{
+		{
+			Resources: v1.ResourceRequirements{
+				Requests: createResourceList(10, 10),
+			},
+		},
+	}
+	gqm.OnPodAdd("1", pod1)
+	assert.Equal(t, createResourceList(10, 10), gqm.GetQuotaInfoByName("1").GetRequest())
+	assert.Equal(t, createResourceList(10, 10), gqm.GetQuotaInfoByName("1").GetUsed())
+
+	// move the pod to another node, the used is not counted twice.
+	pod2 := pod1.DeepCopy()
+	pod2.Spec.NodeName = "node2"
+	gqm.OnPodUpdate("1", "1", pod2, pod1)
+	assert.Equal(t, createResourceList(10, 10), gqm.GetQuotaInfoByName("1").GetRequest())
+	assert.Equal(t, createResourceList(10, 10), gqm.GetQuotaInfoByName("1").GetUsed())
+
+	gqm.OnPodDelete("1", pod2)
+	assert.Equal(t, createResourceList(0, 0), gqm.GetQuotaInfoByName("1").GetRequest())
+	assert.Equal(t, createResourceList(0, 0), gqm.GetQuotaInfoByName("1").GetUsed())
+}
+
+func TestGroupQuotaManager_GetQuotaSteadyAndBurstUsed(t *testing.T) {
+	gqm := NewGroupQuotaManagerForTest()
+	gqm.UpdateClusterTotalResource(createResourceList(1000, 1000))
+	AddQuotaToManager(t, gqm, "parent", extension.RootQuotaName, 100, 100, 50, 50, true, true)
+	AddQuotaToManager(t, gqm, "child1", "parent", 100, 100, 20, 20, true, false)
+	AddQuotaToManager(t, gqm, "child2", "parent", 100, 100, 20, 20, true, false)
+
+	now := time.Now()
+	steadyDuration := 10 * time.Minute
+	newPod := func(name string, cpu, mem int64, startTime *time.Time) *v1.Pod {
+		pod := schetesting.MakePod().Name(name).Node("node1").Obj()
+		pod.Spec.Containers = []v1.Container{
+			{
+				Resources: v1.ResourceRequirements{
+					Requests: createResourceList(cpu, mem),
+				},
+			},
+		}
+		if startTime != nil {
+			pod.Status.StartTime = &metav1.Time{Time: *startTime}
+		}
+		return pod
+	}
+	oldStartTime := now.Add(-time.Hour)
+	boundaryStartTime := now.Add(-steadyDuration)
+	recentStartTime := now.Add(-time.Minute)
+
+	// running for an hour, steady
+	gqm.OnPodAdd("child1", newPod("steady", 10, 10, &oldStartTime))
+	// running for exactly the steady duration, steady
+	gqm.OnPodAdd("child1", newPod("boundary", 1, 1, &boundaryStartTime))
+	// running for a minute, burst
+	gqm.OnPodAdd("child1", newPod("recent", 5, 5, &recentStartTime))
+	// not started yet, burst
+	gqm.OnPodAdd("child2", newPod("pending", 3, 3, nil))
+	// not assigned, neither steady nor burst
+	unassigned := newPod("unassigned", 7, 7, &oldStartTime)
+	unassigned.Spec.NodeName = ""
+	gqm.OnPodAdd("child2", unassigned)
+
+	steadyUsed, burstUsed, exist := gqm.GetQuotaSteadyAndBurstUsed("child1", now, steadyDuration)
+	assert.True(t, exist)
+	assert.True(t, quotav1.Equals(createResourceList(11, 11), steadyUsed))
+	assert.True(t, quotav1.Equals(createResourceList(5, 5), burstUsed))
+
+	steadyUsed, burstUsed, exist = gqm.GetQuotaSteadyAndBurstUsed("child2", now, steadyDuration)
+	assert.True(t, exist)
+	assert.True(t, quotav1.IsZero(steadyUsed))
+	assert.True(t, quotav1.Equals(createResourceList(3, 3), burstUsed))
+
+	// the parent sums its children
+	steadyUsed, burstUsed, exist = gqm.GetQuotaSteadyAndBurstUsed("parent", now, steadyDuration)
+	assert.True(t, exist)
+	assert.True(t, quotav1.Equals(createResourceList(11, 11), steadyUsed))
+	assert.True(t, quotav1.Equals(createResourceList(8, 8), burstUsed))
+
+	// the recent pod becomes steady as time goes by
+	steadyUsed, burstUsed, _ = gqm.GetQuotaSteadyAndBurstUsed("parent", now.Add(steadyDuration), steadyDuration)
+	assert.True(t, quotav1.Equals(createResourceList(16, 16), steadyUsed))
+	assert.True(t, quotav1.Equals(createResourceList(3, 3), burstUsed))
+
+	_, _, exist = gqm.GetQuotaSteadyAndBurstUsed("not-exist", now, steadyDuration)
+	assert.False(t, exist)
+}
+
+func TestGroupQuotaManager_GetUnallocatedRuntime(t *testing.T) {
+	gqm := NewGroupQuotaManagerForTest()
+	gqm.UpdateClusterTotalResource(createResourceList(100, 100))
+	AddQuotaToManager(t, gqm, "p", extension.RootQuotaName, 60, 60, 20, 20, true, true)
+	AddQuotaToManager(t, gqm, "a", "p", 40, 40, 10, 10, true, false)
+	AddQuotaToManager(t, gqm, "b", extension.RootQuotaName, 40, 40, 30, 30, false, false)
+
+	// the quota which doesn't lend its min holds its min as runtime
+	assert.True(t, quotav1.Equals(createResourceList(70, 70), gqm.GetUnallocatedRuntime()))
+
+	// the runtime of the child is counted in its parent only once
+	gqm.updateGroupDeltaRequestNoLock("a", createResourceList(15, 15), createResourceList(15, 15), 0)
+	assert.True(t, quotav1.Equals(createResourceList(15, 15), gqm.RefreshRuntime("a")))
+	assert.True(t, quotav1.Equals(createResourceList(55, 55), gqm.GetUnallocatedRuntime()))
+
+	// the request beyond the max isn't allocated
+	gqm.updateGroupDeltaRequestNoLock("a", createResourceList(50, 50), createResourceList(50, 50), 0)
+	assert.True(t, quotav1.Equals(createResourceList(40, 40), gqm.RefreshRuntime("a")))
+	assert.True(t, quotav1.Equals(createResourceList(30, 30), gqm.GetUnallocatedRuntime()))
+}
+
+func TestGroupQuotaManager_OnSchedulingGatedPod(t *testing.T) {
+	tests := []struct {
+		name           string
+		ignoreGatedPod bool
+		expectRequest  v1.ResourceList
+	}{
+		{
+			name:           "the gated pod is counted in the request by default",
+			ignoreGatedPod: false,
+			expectRequest:  createResourceList(10, 10),
+		},
+		{
+			name:           "the gated pod isn't counted in the request",
+			ignoreGatedPod: true,
+			expectRequest:  createResourceList(0, 0),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer utilfeature.SetFeatureGateDuringTest(t, k8sfeature.DefaultFeatureGate, features.ElasticQuotaIgnoreSchedulingGatedPod, tt.ignoreGatedPod)()
+			gqm := NewGroupQuotaManagerForTest()
+			gqm.UpdateClusterTotalResource(createResourceList(50, 50))
+			AddQuotaToManager(t, gqm, "1", extension.RootQuotaName, 40, 40, 10, 10, true, false)
+
+			// the gated pod is never used before it's scheduled
+			pod1 := schetesting.MakePod().Name("1").Obj()
+			pod1.Spec.SchedulingGates = []v1.PodSchedulingGate{{Name: "gate"}}
+			pod1.Spec.Containers = []v1.Container{
+				{
+					Resources: v1.ResourceRequirements{
+						Requests: createResourceList(10, 10),
+					},
+				},
+			}
+			gqm.OnPodAdd("1", pod1)
+			assert.True(t, quotav1.Equals(tt.expectRequest, gqm.GetQuotaInfoByName("1").GetRequest()))
+			assert.True(t, quotav1.IsZero(gqm.GetQuotaInfoByName("1").GetUsed()))
+
+			// the pod is counted once the gates are removed
+			pod2 := pod1.DeepCopy()
+			pod2.Spec.SchedulingGates = nil
+			gqm.OnPodUpdate("1", "1", pod2, pod1)
+			assert.True(t, quotav1.Equals(createResourceList(10, 10), gqm.GetQuotaInfoByName("1").GetRequest()))
+			assert.True(t, quotav1.IsZero(gqm.GetQuotaInfoByName("1").GetUsed()))
+
+			// schedule the pod
+			pod3 := pod2.DeepCopy()
+			pod3.Spec.NodeName = "node1"
+			gqm.OnPodUpdate("1", "1", pod3, pod2)
+			assert.True(t, quotav1.Equals(createResourceList(10, 10), gqm.GetQuotaInfoByName("1").GetRequest()))
+			assert.True(t, quotav1.Equals(createResourceList(10, 10), gqm.GetQuotaInfoByName("1").GetUsed()))
+
+			// delete the pod
+			gqm.OnPodDelete("1", pod3)
+			assert.True(t, quotav1.IsZero(gqm.GetQuotaInfoByName("1").GetRequest()))
+			assert.True(t, quotav1.IsZero(gqm.GetQuotaInfoByName("1").GetUsed()))
+
+			// delete the gated pod
+			gqm.OnPodAdd("1", pod1)
+			gqm.OnPodDelete("1", pod1)
+			assert.True(t, quotav1.IsZero(gqm.GetQuotaInfoByName("1").GetRequest()))
+		})
+	}
+}
+
+func TestGroupQuotaManager_DefaultNonPreemptibleQuotas(t *testing.T) {
+	gqm := NewGroupQuotaManagerForTest()
+	gqm.SetDefaultNonPreemptibleQuotas([]string{"prod"})
+	gqm.UpdateClusterTotalResource(createResourceList(100, 100))
+	gqm.UpdateQuota(CreateQuota("prod", extension.RootQuotaName, 40, 40, 20, 20, true, false))
+	gqm.UpdateQuota(CreateQuota("dev", extension.RootQuotaName, 40, 40, 20, 20, true, false))
+
+	newPod := func(name, preemptible string) *v1.Pod {
+		pod := schetesting.MakePod().Name(name).Obj()
+		if preemptible != "" {
+			pod.Labels = map[string]string{extension.LabelPreemptible: preemptible}
+		}
+		pod.Spec.NodeName = "node1"
+		pod.Spec.Containers = []v1.Container{
+			{
+				Resources: v1.ResourceRequirements{
+					Requests: createResourceList(2, 2),
+				},
+			},
+		}
+		return pod
+	}
+
+	tests := []struct {
+		name                 string
+		quotaName            string
+		pod                  *v1.Pod
+		expectNonPreemptible bool
+	}{
+		{
+			name:                 "pod of the default non-preemptible quota",
+			quotaName:            "prod",
+			pod:                  newPod("prod-default", ""),
+			expectNonPreemptible: true,
+		},
+		{
+			name:                 "pod overrides to preemptible",
+			quotaName:            "prod",
+			pod:                  newPod("prod-preemptible", "true"),
+			expectNonPreemptible: false,
+		},
+		{
+			name:                 "pod of the other quota",
+			quotaName:            "dev",
+			pod:                  newPod("dev-default", ""),
+			expectNonPreemptible: false,
+		},
+		{
+			name:                 "pod of the other quota overrides to non-preemptible",
+			quotaName:            "dev",
+			pod:                  newPod("dev-non-preemptible", "false"),
+			expectNonPreemptible: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectNonPreemptible, gqm.IsPodNonPreemptible(tt.quotaName, tt.pod))
+
+			quotaInfo := gqm.GetQuotaInfoByName(tt.quotaName)
+			oldNonPreemptibleUsed := quotaInfo.GetNonPreemptibleUsed()
+			oldNonPreemptibleRequest := quotaInfo.GetNonPreemptibleRequest()
+			gqm.OnPodAdd(tt.quotaName, tt.pod)
+			quotaInfo = gqm.GetQuotaInfoByName(tt.quotaName)
+			expectDelta := v1.ResourceList{}
+			if tt.expectNonPreemptible {
+				expectDelta = createResourceList(2, 2)
+			}
+			assert.True(t, quotav1.Equals(quotav1.RemoveZeros(quotav1.Add(oldNonPreemptibleUsed, expectDelta)),
+				quotav1.RemoveZeros(quotaInfo.GetNonPreemptibleUsed())))
+			assert.True(t, quotav1.Equals(quotav1.RemoveZeros(quotav1.Add(oldNonPreemptibleRequest, expectDelta)),
+				quotav1.RemoveZeros(quotaInfo.GetNonPreemptibleRequest())))
+		})
+	}
+}
+
+func TestGroupQuotaManager_GuaranteeMinRuntime(t *testing.T) {
+	newManager := func(guarantee bool) *GroupQuotaManager {
+		gqm := NewGroupQuotaManagerForTest()
+		gqm.SetGuaranteeMinRuntime(guarantee)
+		gqm.UpdateClusterTotalResource(createResourceList(100, 100))
+		gqm.UpdateQuota(CreateQuota("parent", extension.RootQuotaName, 100, 100, 40, 40, true, true))
+		gqm.UpdateQuota(CreateQuota("a", "parent", 100, 100, 20, 20, true, false))
+		gqm.UpdateQuota(CreateQuota("b", "parent", 100, 100, 10, 10, true, false))
+		return gqm
+	}
+
+	// the runtime drops to the request without the floor
+	gqm := newManager(false)
+	assert.Equal(t, createResourceList(0, 0), gqm.RefreshRuntime("a"))
+	assert.Equal(t, createResourceList(0, 0), gqm.RefreshRuntime("b"))
+	gqm.updateGroupDeltaRequestNoLock("b", createResourceList(30, 30), createResourceList(30, 30), 0)
+	assert.Equal(t, createResourceList(0, 0), gqm.RefreshRuntime("a"))
+	assert.Equal(t, createResourceList(30, 30), gqm.RefreshRuntime("b"))
+
+	// the runtime is at least the min, and the floors of the children are counted in the parent
+	gqm = newManager(true)
+	assert.Equal(t, createResourceList(20, 20), gqm.RefreshRuntime("a"))
+	assert.Equal(t, createResourceList(10, 10), gqm.RefreshRuntime("b"))
+	assert.Equal(t, createResourceList(40, 40), gqm.RefreshRuntime("parent"))
+	gqm.updateGroupDeltaRequestNoLock("b", createResourceList(30, 30), createResourceList(30, 30), 0)
+	assert.Equal(t, createResourceList(20, 20), gqm.RefreshRuntime("a"))
+	assert.Equal(t, createResourceList(30, 30), gqm.RefreshRuntime("b"))
+	assert.Equal(t, createResourceList(50, 50), gqm.RefreshRuntime("parent"))
+	assert.Equal(t, createResourceList(50, 50), gqm.GetQuotaInfoByName("parent").CalculateInfo.Request)
+}
+
+func TestGroupQuotaManager_ChildrenOrder(t *testing.T) {
+	gqm := NewGroupQuotaManagerForTest()
+	gqm.UpdateClusterTotalResource(createResourceList(90, 90))
+	parent := CreateQuota("parent", extension.RootQuotaName, 100, 100, 0, 0, true, true)
+	parent.Annotations[extension.AnnotationChildrenOrder] = `["c","a"]`
+	gqm.UpdateQuota(parent)
+	for _, name := range []string{"a", "b", "c"} {
+		gqm.UpdateQuota(CreateQuota(name, "parent", 100, 100, 0, 0, true, false))
+		gqm.updateGroupDeltaRequestNoLock(name, createResourceList(60, 60), createResourceList(60, 60), 0)
+	}
+
+	// the earlier ordered children are satisfied first
+	assert.Equal(t, createResourceList(60, 60), gqm.RefreshRuntime("c"))
+	assert.Equal(t, createResourceList(30, 30), gqm.RefreshRuntime("a"))
+	assert.Equal(t, createResourceList(0, 0), gqm.RefreshRuntime("b"))
+
+	// the children share by weight once the order is removed
+	parent = parent.DeepCopy()
+	delete(parent.Annotations, extension.AnnotationChildrenOrder)
+	gqm.UpdateQuota(parent)
+	assert.Nil(t, gqm.GetQuotaInfoByName("parent").ChildrenOrder)
+	for _, name := range []string{"a", "b", "c"} {
+		assert.Equal(t, createResourceList(30, 30), gqm.RefreshRuntime(name), name)
+	}
+}
+
+func TestGroupQuotaManager_OvercommitRatios(t *testing.T) {
+	gqm := NewGroupQuotaManagerForTest()
+	gqm.UpdateClusterTotalResource(createResourceList(100, 100))
+	quota := CreateQuota("a", extension.RootQuotaName, 10, 10, 0, 0, true, false)
+	quota.Annotations[extension.AnnotationOvercommitRatio] = `{"cpu":1.5}`
+	gqm.UpdateQuota(quota)
+	gqm.updateGroupDeltaRequestNoLock("a", createResourceList(20, 20), createResourceList(20, 20), 0)
+
+	// the runtime of cpu grows up to the overcommitted max, the memory stays at the max
+	assert.Equal(t, createResourceList(15, 10), gqm.GetQuotaInfoByName("a").GetOvercommittedMax())
+	assert.Equal(t, createResourceList(15, 10), gqm.RefreshRuntime("a"))
+
+	// the runtime follows the change of the ratios
+	quota = quota.DeepCopy()
+	quota.Annotations[extension.AnnotationOvercommitRatio] = `{"cpu":2}`
+	gqm.UpdateQuota(quota)
+	assert.Equal(t, createResourceList(20, 10), gqm.RefreshRuntime("a"))
+	quota = quota.DeepCopy()
+	delete(quota.Annotations, extension.AnnotationOvercommitRatio)
+	gqm.UpdateQuota(quota)
+	assert.Nil(t, gqm.GetQuotaInfoByName("a").OvercommitRatios)
+	assert.Equal(t, createResourceList(10, 10), gqm.RefreshRuntime("a"))
+}
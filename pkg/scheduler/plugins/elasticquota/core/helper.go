@@ -18,17 +18,86 @@ package core
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/sets"
 	k8sfeature "k8s.io/apiserver/pkg/util/feature"
+	"k8s.io/klog/v2"
 	apiresource "k8s.io/kubernetes/pkg/api/v1/resource"
 
+	"github.com/koordinator-sh/koordinator/apis/extension"
 	"github.com/koordinator-sh/koordinator/pkg/features"
 )
 
+// ResourceClaimClassGetter returns the resource class name of the pod's resource claim.
+type ResourceClaimClassGetter func(pod *corev1.Pod, podClaim *corev1.PodResourceClaim) (string, error)
+
+// isPodRequestCounted returns whether the request of the pod is counted in the quota request. The pod gated by
+// scheduling gates isn't schedulable yet, its request isn't counted if ElasticQuotaIgnoreSchedulingGatedPod is enabled.
+func isPodRequestCounted(pod *corev1.Pod) bool {
+	return len(pod.Spec.SchedulingGates) == 0 || !k8sfeature.DefaultFeatureGate.Enabled(features.ElasticQuotaIgnoreSchedulingGatedPod)
+}
+
 func PodRequests(pod *corev1.Pod) (reqs corev1.ResourceList) {
 	if k8sfeature.DefaultFeatureGate.Enabled(features.ElasticQuotaIgnorePodOverhead) {
-		return apiresource.PodRequests(pod, apiresource.PodResourcesOptions{
+		reqs = apiresource.PodRequests(pod, apiresource.PodResourcesOptions{
 			ExcludeOverhead: true,
 		})
+	} else {
+		reqs = apiresource.PodRequests(pod, apiresource.PodResourcesOptions{})
+	}
+	return normalizeDeviceResources(reqs)
+}
+
+// normalizeDeviceResources accounts the deprecated device resources, e.g. kubernetes.io/gpu-memory, in the
+// koordinator device resources, e.g. koordinator.sh/gpu-memory, which the quotas declare.
+func normalizeDeviceResources(reqs corev1.ResourceList) corev1.ResourceList {
+	for deprecatedName, resourceName := range extension.DeprecatedDeviceResourcesMapper {
+		quantity, ok := reqs[deprecatedName]
+		if !ok {
+			continue
+		}
+		total := reqs[resourceName]
+		total.Add(quantity)
+		reqs[resourceName] = total
+		delete(reqs, deprecatedName)
+	}
+	return reqs
+}
+
+// PodResourceClaimRequests accounts each resource claim of the pod as one unit of the
+// "<resourceClassName>.resourceclass.resource.k8s.io/claims" dimension. A claim referenced by several
+// entries of the pod is accounted once.
+func PodResourceClaimRequests(pod *corev1.Pod, getter ResourceClaimClassGetter) corev1.ResourceList {
+	if getter == nil || len(pod.Spec.ResourceClaims) == 0 ||
+		!k8sfeature.DefaultFeatureGate.Enabled(features.ElasticQuotaResourceClaims) {
+		return nil
+	}
+
+	reqs := corev1.ResourceList{}
+	claims := sets.NewString()
+	for i := range pod.Spec.ResourceClaims {
+		podClaim := &pod.Spec.ResourceClaims[i]
+		// the pod shares the claim referenced by name, while each template generates a claim of the pod
+		claimKey := "template/" + podClaim.Name
+		if podClaim.Source.ResourceClaimName != nil {
+			claimKey = "claim/" + *podClaim.Source.ResourceClaimName
+		}
+		if claims.Has(claimKey) {
+			continue
+		}
+		className, err := getter(pod, podClaim)
+		if err != nil {
+			klog.V(4).InfoS("failed to get resource class of the resource claim", "pod", klog.KObj(pod), "claim", podClaim.Name, "err", err)
+			continue
+		}
+		if className == "" {
+			continue
+		}
+		claims.Insert(claimKey)
+		resourceName := extension.GetResourceClaimQuotaResourceName(className)
+		quantity := reqs[resourceName]
+		quantity.Add(*resource.NewQuantity(1, resource.DecimalSI))
+		reqs[resourceName] = quantity
 	}
-	return apiresource.PodRequests(pod, apiresource.PodResourcesOptions{})
+	return reqs
 }
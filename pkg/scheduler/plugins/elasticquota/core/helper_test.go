@@ -17,13 +17,18 @@ limitations under the License.
 package core
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	quotav1 "k8s.io/apiserver/pkg/quota/v1"
 	k8sfeature "k8s.io/apiserver/pkg/util/feature"
+	schetesting "k8s.io/kubernetes/pkg/scheduler/testing"
+	"k8s.io/utils/pointer"
 
+	"github.com/koordinator-sh/koordinator/apis/extension"
 	koordfeatures "github.com/koordinator-sh/koordinator/pkg/features"
 	utilfeature "github.com/koordinator-sh/koordinator/pkg/util/feature"
 )
@@ -88,3 +93,186 @@ func TestPodRequestsAndLimits(t *testing.T) {
 	}
 
 }
+
+func TestPodRequestsWithInitContainers(t *testing.T) {
+	sidecarRestartPolicy := corev1.ContainerRestartPolicyAlways
+	newContainer := func(cpu, mem int64) corev1.Container {
+		return corev1.Container{Resources: corev1.ResourceRequirements{Requests: createResourceList(cpu, mem)}}
+	}
+	tests := []struct {
+		name           string
+		initContainers []corev1.Container
+		containers     []corev1.Container
+		wantReqs       corev1.ResourceList
+	}{
+		{
+			name:           "large init container dwarfs the app containers",
+			initContainers: []corev1.Container{newContainer(8, 16*GigaByte)},
+			containers:     []corev1.Container{newContainer(1, 2*GigaByte), newContainer(1, 2*GigaByte)},
+			wantReqs:       createResourceList(8, 16*GigaByte),
+		},
+		{
+			name:           "app containers exceed the init containers",
+			initContainers: []corev1.Container{newContainer(1, 1*GigaByte), newContainer(3, 1*GigaByte)},
+			containers:     []corev1.Container{newContainer(2, 2*GigaByte), newContainer(2, 2*GigaByte)},
+			wantReqs:       createResourceList(4, 4*GigaByte),
+		},
+		{
+			name: "sidecar adds to the containers and the later init containers",
+			initContainers: []corev1.Container{
+				func() corev1.Container {
+					c := newContainer(1, 1*GigaByte)
+					c.RestartPolicy = &sidecarRestartPolicy
+					return c
+				}(),
+				newContainer(4, 4*GigaByte),
+			},
+			containers: []corev1.Container{newContainer(2, 2*GigaByte)},
+			wantReqs:   createResourceList(5, 5*GigaByte),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{
+				Spec: corev1.PodSpec{
+					InitContainers: tt.initContainers,
+					Containers:     tt.containers,
+				},
+			}
+			reqs := PodRequests(pod)
+			assert.True(t, quotav1.Equals(tt.wantReqs, reqs), "reqs: %v", reqs)
+
+			// the quota is charged by the effective requests
+			gqm := NewGroupQuotaManagerForTest()
+			gqm.UpdateClusterTotalResource(createResourceList(100, 100*GigaByte))
+			gqm.UpdateQuota(CreateQuota("1", extension.RootQuotaName, 50, 50*GigaByte, 10, 10*GigaByte, true, false))
+			pod.Name = "pod"
+			pod.Spec.NodeName = "node1"
+			gqm.OnPodAdd("1", pod)
+			assert.True(t, quotav1.Equals(tt.wantReqs, gqm.GetQuotaInfoByName("1").GetRequest()))
+			assert.True(t, quotav1.Equals(tt.wantReqs, gqm.GetQuotaInfoByName("1").GetUsed()))
+		})
+	}
+}
+
+func TestPodRequestsWithDeprecatedDeviceResources(t *testing.T) {
+	pod := &corev1.Pod{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+					corev1.ResourceCPU:            resource.MustParse("1"),
+					extension.DeprecatedGPUMemory: resource.MustParse("8Gi"),
+					extension.DeprecatedGPUCore:   resource.MustParse("50"),
+				}}},
+				{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+					extension.ResourceGPUMemory: resource.MustParse("8Gi"),
+				}}},
+			},
+		},
+	}
+	reqs := PodRequests(pod)
+	assert.True(t, quotav1.Equals(corev1.ResourceList{
+		corev1.ResourceCPU:          resource.MustParse("1"),
+		extension.ResourceGPUMemory: resource.MustParse("16Gi"),
+		extension.ResourceGPUCore:   resource.MustParse("50"),
+	}, reqs), "reqs: %v", reqs)
+}
+
+func TestPodResourceClaimRequests(t *testing.T) {
+	getter := func(pod *corev1.Pod, podClaim *corev1.PodResourceClaim) (string, error) {
+		if podClaim.Source.ResourceClaimName == nil {
+			return "", fmt.Errorf("not found")
+		}
+		return "gpu.example.com", nil
+	}
+	gpuClaims := extension.GetResourceClaimQuotaResourceName("gpu.example.com")
+	pod := &corev1.Pod{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceCPU: *resource.NewMilliQuantity(4000, resource.DecimalSI),
+						},
+					},
+				},
+			},
+			ResourceClaims: []corev1.PodResourceClaim{
+				{Name: "gpu-0", Source: corev1.ClaimSource{ResourceClaimName: pointer.String("gpu-claim-0")}},
+				{Name: "gpu-1", Source: corev1.ClaimSource{ResourceClaimName: pointer.String("gpu-claim-1")}},
+				{Name: "gpu-shared", Source: corev1.ClaimSource{ResourceClaimName: pointer.String("gpu-claim-0")}},
+				{Name: "unknown", Source: corev1.ClaimSource{ResourceClaimTemplateName: pointer.String("unknown")}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		enabled  bool
+		wantReqs corev1.ResourceList
+	}{
+		{
+			name:     "ElasticQuotaResourceClaims=false",
+			enabled:  false,
+			wantReqs: nil,
+		},
+		{
+			name:    "ElasticQuotaResourceClaims=true",
+			enabled: true,
+			wantReqs: corev1.ResourceList{
+				gpuClaims: *resource.NewQuantity(2, resource.DecimalSI),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer utilfeature.SetFeatureGateDuringTest(t, k8sfeature.DefaultMutableFeatureGate, koordfeatures.ElasticQuotaResourceClaims, tt.enabled)()
+			reqs := PodResourceClaimRequests(pod, getter)
+			assert.True(t, quotav1.Equals(tt.wantReqs, reqs), "want %v, got %v", tt.wantReqs, reqs)
+		})
+	}
+}
+
+func TestGroupQuotaManager_ResourceClaimsConsumeQuota(t *testing.T) {
+	defer utilfeature.SetFeatureGateDuringTest(t, k8sfeature.DefaultMutableFeatureGate, koordfeatures.ElasticQuotaResourceClaims, true)()
+	className := "gpu.example.com"
+	gpuClaims := extension.GetResourceClaimQuotaResourceName(className)
+
+	gqm := NewGroupQuotaManagerForTest()
+	gqm.SetResourceClaimClassGetter(func(pod *corev1.Pod, podClaim *corev1.PodResourceClaim) (string, error) {
+		if className == "" {
+			return "", fmt.Errorf("not found")
+		}
+		return className, nil
+	})
+	totalResource := createResourceList(100, 100)
+	totalResource[gpuClaims] = *resource.NewQuantity(8, resource.DecimalSI)
+	gqm.UpdateClusterTotalResource(totalResource)
+	quota := CreateQuota("1", extension.RootQuotaName, 50, 50, 10, 10, true, false)
+	quota.Spec.Max[gpuClaims] = *resource.NewQuantity(4, resource.DecimalSI)
+	quota.Spec.Min[gpuClaims] = *resource.NewQuantity(0, resource.DecimalSI)
+	assert.NoError(t, gqm.UpdateQuota(quota))
+
+	pod := schetesting.MakePod().Namespace("default").Name("pod1").
+		Req(map[corev1.ResourceName]string{corev1.ResourceCPU: "1"}).Node("node1").Obj()
+	pod.Spec.ResourceClaims = []corev1.PodResourceClaim{
+		{Name: "gpu", Source: corev1.ClaimSource{ResourceClaimTemplateName: pointer.String("gpu-template")}},
+	}
+	gqm.OnPodAdd("1", pod)
+
+	quotaInfo := gqm.GetQuotaInfoByName("1")
+	used := quotaInfo.GetUsed()
+	assert.Equal(t, int64(1), used.Name(gpuClaims, resource.DecimalSI).Value())
+	request := quotaInfo.GetRequest()
+	assert.Equal(t, int64(1), request.Name(gpuClaims, resource.DecimalSI).Value())
+	podRequests := gqm.PodRequests("1", pod)
+	assert.Equal(t, int64(1), podRequests.Name(gpuClaims, resource.DecimalSI).Value())
+
+	// the claim is deleted before the pod, the pod still releases what it consumed
+	className = ""
+	gqm.OnPodDelete("1", pod)
+	used = quotaInfo.GetUsed()
+	assert.True(t, used.Name(gpuClaims, resource.DecimalSI).IsZero())
+	request = quotaInfo.GetRequest()
+	assert.True(t, request.Name(gpuClaims, resource.DecimalSI).IsZero())
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+// GetAncestorQuotaInfos returns the ancestors of the quota from its parent up to the top-level quota, the root
+// quota excluded. The ancestors are cached until the hierarchy of the tree changes, so that the callers checking
+// the whole path on every admission don't walk the tree again. The returned slice must not be modified.
+// If an ancestor is missing, e.g. a child is loaded before its parent, the path stops at the last ancestor found.
+func (gqm *GroupQuotaManager) GetAncestorQuotaInfos(quotaName string) []*QuotaInfo {
+	gqm.hierarchyUpdateLock.RLock()
+	defer gqm.hierarchyUpdateLock.RUnlock()
+
+	return gqm.getAncestorQuotaInfosNoLock(quotaName)
+}
+
+func (gqm *GroupQuotaManager) getAncestorQuotaInfosNoLock(quotaName string) []*QuotaInfo {
+	// the read lock of hierarchyUpdateLock is shared by the readers, so the cache needs its own lock.
+	gqm.ancestorCacheLock.Lock()
+	defer gqm.ancestorCacheLock.Unlock()
+
+	if ancestors, ok := gqm.ancestorCache[quotaName]; ok {
+		return ancestors
+	}
+
+	quotaInfo := gqm.quotaInfoMap[quotaName]
+	if quotaInfo == nil {
+		return nil
+	}
+	ancestors := make([]*QuotaInfo, 0)
+	for parentName := quotaInfo.ParentName; parentName != extension.RootQuotaName; {
+		parentInfo := gqm.quotaInfoMap[parentName]
+		if parentInfo == nil {
+			break
+		}
+		ancestors = append(ancestors, parentInfo)
+		parentName = parentInfo.ParentName
+	}
+
+	if gqm.ancestorCache == nil {
+		gqm.ancestorCache = make(map[string][]*QuotaInfo)
+	}
+	gqm.ancestorCache[quotaName] = ancestors
+	return ancestors
+}
+
+// invalidateAncestorCacheNoLock drops the cached ancestors once a quota is added, deleted or reparented.
+func (gqm *GroupQuotaManager) invalidateAncestorCacheNoLock() {
+	gqm.ancestorCacheLock.Lock()
+	defer gqm.ancestorCacheLock.Unlock()
+
+	gqm.ancestorCache = nil
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+func ancestorNames(quotaInfos []*QuotaInfo) []string {
+	names := make([]string, 0, len(quotaInfos))
+	for _, quotaInfo := range quotaInfos {
+		names = append(names, quotaInfo.Name)
+	}
+	return names
+}
+
+func TestGroupQuotaManager_GetAncestorQuotaInfos(t *testing.T) {
+	gqm := NewGroupQuotaManagerForTest()
+	gqm.UpdateClusterTotalResource(createResourceList(100, 100))
+
+	// root
+	//   |-- p1
+	//   |    `-- c1
+	//   `-- p2
+	AddQuotaToManager2(gqm, "p1", extension.RootQuotaName, 100, 100, 10, 10, true, true)
+	AddQuotaToManager2(gqm, "c1", "p1", 100, 100, 10, 10, true, false)
+	AddQuotaToManager2(gqm, "p2", extension.RootQuotaName, 100, 100, 10, 10, true, true)
+
+	assert.Equal(t, []string{"p1"}, ancestorNames(gqm.GetAncestorQuotaInfos("c1")))
+	assert.Empty(t, gqm.GetAncestorQuotaInfos("p1"))
+	assert.Nil(t, gqm.GetAncestorQuotaInfos("unknown"))
+	// the cached path is returned as long as the hierarchy doesn't change
+	cached := gqm.GetAncestorQuotaInfos("c1")
+	AddQuotaToManager2(gqm, "c1", "p1", 100, 100, 20, 20, true, false)
+	assert.Same(t, cached[0], gqm.GetAncestorQuotaInfos("c1")[0])
+
+	// reparent p1 under p2, the cached path of the descendants is invalidated
+	AddQuotaToManager2(gqm, "p1", "p2", 100, 100, 10, 10, true, true)
+	ancestors := gqm.GetAncestorQuotaInfos("c1")
+	assert.Equal(t, []string{"p1", "p2"}, ancestorNames(ancestors))
+	assert.Same(t, gqm.GetQuotaInfoByName("p1"), ancestors[0])
+	assert.Same(t, gqm.GetQuotaInfoByName("p2"), ancestors[1])
+
+	// a child loaded before its parent gets the full path once the parent is added
+	AddQuotaToManager2(gqm, "orphan", "p3", 100, 100, 10, 10, true, false)
+	assert.Empty(t, gqm.GetAncestorQuotaInfos("orphan"))
+	AddQuotaToManager2(gqm, "p3", "p2", 100, 100, 10, 10, true, true)
+	assert.Equal(t, []string{"p3", "p2"}, ancestorNames(gqm.GetAncestorQuotaInfos("orphan")))
+
+	// delete
+	assert.NoError(t, gqm.DeleteQuota(CreateQuota("c1", "p1", 100, 100, 10, 10, true, false)))
+	assert.Nil(t, gqm.GetAncestorQuotaInfos("c1"))
+}
+
+func BenchmarkGroupQuotaManager_AncestorLookup(b *testing.B) {
+	// 10 top-level quotas, each is a chain of 10 parent quotas with 990 leaves, 10000 quotas in total
+	gqm := NewGroupQuotaManagerForTest()
+	leaves := make([]string, 0)
+	for i := 0; i < 10; i++ {
+		parentName := extension.RootQuotaName
+		for depth := 0; depth < 10; depth++ {
+			quotaName := fmt.Sprintf("parent-%v-%v", i, depth)
+			AddQuotaToManager2(gqm, quotaName, parentName, 96000, 160000*GigaByte, 0, 0, true, true)
+			parentName = quotaName
+		}
+		for j := 0; j < 990; j++ {
+			quotaName := fmt.Sprintf("leaf-%v-%v", i, j)
+			AddQuotaToManager2(gqm, quotaName, parentName, 96, 160*GigaByte, 0, 0, true, false)
+			leaves = append(leaves, quotaName)
+		}
+	}
+
+	b.Run("walk", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			// the lookups of the parent walk before the ancestors are cached
+			quotaInfo := gqm.GetQuotaInfoByName(leaves[i%len(leaves)])
+			for quotaInfo != nil && quotaInfo.ParentName != extension.RootQuotaName {
+				quotaInfo = gqm.GetQuotaInfoByName(quotaInfo.ParentName)
+			}
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			gqm.GetAncestorQuotaInfos(leaves[i%len(leaves)])
+		}
+	})
+}
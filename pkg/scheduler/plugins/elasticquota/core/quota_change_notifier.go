@@ -0,0 +1,127 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// QuotaChangeBatch is a coalesced notification of the quotas changed within one batch period.
+type QuotaChangeBatch struct {
+	// TreeID is the quota tree the changed quotas belong to.
+	TreeID string
+	// Quotas are the sorted names of the quotas whose spec, used or request changed.
+	Quotas []string
+	// Changes is the number of raw change events coalesced into the batch.
+	Changes int
+	// Time is when the batch was flushed.
+	Time time.Time
+}
+
+type quotaChangeSubscriber struct {
+	pending sets.String
+	changes int
+	ch      chan QuotaChangeBatch
+}
+
+// quotaChangeNotifier records the changed quotas for every subscriber and flushes them periodically,
+// so that consumers receive one batch per period instead of one notification per event.
+type quotaChangeNotifier struct {
+	lock        sync.Mutex
+	treeID      string
+	subscribers map[*quotaChangeSubscriber]struct{}
+}
+
+func newQuotaChangeNotifier(treeID string) *quotaChangeNotifier {
+	return &quotaChangeNotifier{
+		treeID:      treeID,
+		subscribers: make(map[*quotaChangeSubscriber]struct{}),
+	}
+}
+
+// markChanged records the quotas as changed for all subscribers, it's a no-op without subscribers.
+func (n *quotaChangeNotifier) markChanged(quotaNames ...string) {
+	if n == nil || len(quotaNames) == 0 {
+		return
+	}
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	for sub := range n.subscribers {
+		sub.pending.Insert(quotaNames...)
+		sub.changes++
+	}
+}
+
+func (n *quotaChangeNotifier) subscribe(period time.Duration, stopCh <-chan struct{}) <-chan QuotaChangeBatch {
+	sub := &quotaChangeSubscriber{
+		pending: sets.NewString(),
+		ch:      make(chan QuotaChangeBatch, 1),
+	}
+	n.lock.Lock()
+	n.subscribers[sub] = struct{}{}
+	n.lock.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopCh:
+				n.lock.Lock()
+				delete(n.subscribers, sub)
+				n.lock.Unlock()
+				close(sub.ch)
+				return
+			case <-ticker.C:
+				n.flush(sub)
+			}
+		}
+	}()
+	return sub.ch
+}
+
+// flush sends the pending changes of the subscriber. If the subscriber hasn't consumed the previous
+// batch yet, the changes are kept and coalesced into the next batch instead of blocking.
+func (n *quotaChangeNotifier) flush(sub *quotaChangeSubscriber) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if sub.pending.Len() == 0 {
+		return
+	}
+	batch := QuotaChangeBatch{
+		TreeID:  n.treeID,
+		Quotas:  sub.pending.List(),
+		Changes: sub.changes,
+		Time:    time.Now(),
+	}
+	select {
+	case sub.ch <- batch:
+		sub.pending = sets.NewString()
+		sub.changes = 0
+	default:
+	}
+}
+
+// SubscribeQuotaChanges returns a channel receiving at most one batch of changed quotas per period.
+// Changes happened while the previous batch is not consumed are merged into the next one.
+// The channel is closed after stopCh is closed.
+func (gqm *GroupQuotaManager) SubscribeQuotaChanges(period time.Duration, stopCh <-chan struct{}) <-chan QuotaChangeBatch {
+	return gqm.changeNotifier.subscribe(period, stopCh)
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	schetesting "k8s.io/kubernetes/pkg/scheduler/testing"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+func TestQuotaChangeNotifier_Flush(t *testing.T) {
+	n := newQuotaChangeNotifier("tree-a")
+	stopCh := make(chan struct{})
+	// the period is long enough to flush manually
+	ch := n.subscribe(time.Hour, stopCh)
+	var sub *quotaChangeSubscriber
+	for s := range n.subscribers {
+		sub = s
+	}
+	assert.NotNil(t, sub)
+
+	// nothing changed, nothing sent
+	n.flush(sub)
+	assert.Len(t, ch, 0)
+
+	for i := 0; i < 100; i++ {
+		n.markChanged("a")
+	}
+	n.markChanged("b")
+	n.flush(sub)
+	assert.Len(t, ch, 1)
+
+	// the consumer is busy, the changes are kept for the next batch
+	n.markChanged("c")
+	n.flush(sub)
+	n.markChanged("d")
+	n.flush(sub)
+	assert.Len(t, ch, 1)
+
+	batch := <-ch
+	assert.Equal(t, "tree-a", batch.TreeID)
+	assert.Equal(t, []string{"a", "b"}, batch.Quotas)
+	assert.Equal(t, 101, batch.Changes)
+
+	n.flush(sub)
+	batch = <-ch
+	assert.Equal(t, []string{"c", "d"}, batch.Quotas)
+	assert.Equal(t, 2, batch.Changes)
+
+	close(stopCh)
+	_, ok := <-ch
+	assert.False(t, ok)
+	n.lock.Lock()
+	assert.Len(t, n.subscribers, 0)
+	n.lock.Unlock()
+
+	// no subscribers or nil notifier
+	n.markChanged("e")
+	var nilNotifier *quotaChangeNotifier
+	nilNotifier.markChanged("e")
+}
+
+func TestGroupQuotaManager_SubscribeQuotaChanges(t *testing.T) {
+	gqm := NewGroupQuotaManagerForTest()
+	gqm.UpdateClusterTotalResource(createResourceList(1000000, 1000000))
+	AddQuotaToManager(t, gqm, "1", extension.RootQuotaName, 100000, 100000, 10, 10, true, false)
+	AddQuotaToManager(t, gqm, "2", extension.RootQuotaName, 100000, 100000, 10, 10, true, false)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	ch := gqm.SubscribeQuotaChanges(20*time.Millisecond, stopCh)
+
+	lock := sync.Mutex{}
+	changed := sets.NewString()
+	batches, changes := 0, 0
+	go func() {
+		for batch := range ch {
+			lock.Lock()
+			changed.Insert(batch.Quotas...)
+			batches++
+			changes += batch.Changes
+			lock.Unlock()
+		}
+	}()
+
+	const podCount = 200
+	for i := 0; i < podCount; i++ {
+		pod := schetesting.MakePod().Name(fmt.Sprintf("pod-%d", i)).Obj()
+		pod.Spec.Containers = []v1.Container{
+			{
+				Resources: v1.ResourceRequirements{
+					Requests: createResourceList(1, 1),
+				},
+			},
+		}
+		gqm.OnPodAdd(fmt.Sprintf("%d", i%2+1), pod)
+	}
+
+	assert.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return changed.HasAll("1", "2") && changes >= podCount
+	}, 5*time.Second, 10*time.Millisecond)
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Less(t, batches, podCount)
+}
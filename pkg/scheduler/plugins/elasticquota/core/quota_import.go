@@ -0,0 +1,122 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	"github.com/koordinator-sh/koordinator/apis/thirdparty/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+	"github.com/koordinator-sh/koordinator/pkg/scheduler/metrics"
+)
+
+// ImportQuotas installs a batch of new quotas, e.g. a whole subtree, in one pass. The quotas may be listed in
+// any order, the parent of a quota can be an existing parent quota or a parent quota of the same batch.
+// The topology of the whole batch is validated before anything is installed, and the runtime is rebuilt
+// once at the end instead of once per quota, so the batch is either installed entirely or not at all.
+func (gqm *GroupQuotaManager) ImportQuotas(quotas []*v1alpha1.ElasticQuota) error {
+	start := time.Now()
+	defer func() {
+		metrics.RecordElasticQuotaProcessLatency("ImportQuotas", time.Since(start))
+	}()
+
+	gqm.hierarchyUpdateLock.Lock()
+	defer gqm.hierarchyUpdateLock.Unlock()
+
+	newQuotaInfos, err := gqm.validateImportQuotasNoLock(quotas)
+	if err != nil {
+		return err
+	}
+	if len(newQuotaInfos) == 0 {
+		return nil
+	}
+
+	hookStates := make([]map[string]interface{}, len(quotas))
+	for i, quota := range quotas {
+		hookStates[i] = gqm.runPreQuotaUpdateHooks(nil, newQuotaInfos[i], quota)
+		gqm.quotaInfoMap[quota.Name] = newQuotaInfos[i]
+	}
+
+	klog.Infof("reset quota tree %v, for %v quotas imported", gqm.treeID, len(quotas))
+	gqm.resetQuotaNoLock()
+
+	for i, quota := range quotas {
+		gqm.runPostQuotaUpdateHooks(nil, newQuotaInfos[i], quota, hookStates[i])
+	}
+	return nil
+}
+
+// validateImportQuotasNoLock checks the batch against the current tree without modifying it, and returns the
+// quota infos of the batch in the same order.
+func (gqm *GroupQuotaManager) validateImportQuotasNoLock(quotas []*v1alpha1.ElasticQuota) ([]*QuotaInfo, error) {
+	newQuotaInfos := make([]*QuotaInfo, 0, len(quotas))
+	batch := make(map[string]*QuotaInfo, len(quotas))
+	for _, quota := range quotas {
+		if quota == nil {
+			return nil, fmt.Errorf("quota is nil")
+		}
+		quotaName := quota.Name
+		if quotaName == extension.RootQuotaName || quotaName == extension.SystemQuotaName ||
+			quotaName == extension.DefaultQuotaName {
+			return nil, fmt.Errorf("quota %v is reserved and can't be imported", quotaName)
+		}
+		if _, exist := batch[quotaName]; exist {
+			return nil, fmt.Errorf("quota %v is duplicated in the batch", quotaName)
+		}
+		if _, exist := gqm.quotaInfoMap[quotaName]; exist {
+			return nil, fmt.Errorf("quota %v already exists in the quota tree %v", quotaName, gqm.treeID)
+		}
+		if treeID := extension.GetQuotaTreeID(quota); treeID != gqm.treeID {
+			return nil, fmt.Errorf("quota %v belongs to the quota tree %v, not %v", quotaName, treeID, gqm.treeID)
+		}
+		quotaInfo := NewQuotaInfoFromQuota(quota)
+		batch[quotaName] = quotaInfo
+		newQuotaInfos = append(newQuotaInfos, quotaInfo)
+	}
+
+	for _, quotaInfo := range newQuotaInfos {
+		parentName := quotaInfo.ParentName
+		if parentName == extension.RootQuotaName {
+			continue
+		}
+		parentInfo := batch[parentName]
+		if parentInfo == nil {
+			parentInfo = gqm.quotaInfoMap[parentName]
+		}
+		if parentInfo == nil {
+			return nil, fmt.Errorf("the parent %v of quota %v doesn't exist", parentName, quotaInfo.Name)
+		}
+		if !parentInfo.IsParent {
+			return nil, fmt.Errorf("the parent %v of quota %v isn't a parent quota", parentName, quotaInfo.Name)
+		}
+	}
+
+	// the existing quotas are already rooted, so a cycle can only be formed by the quotas of the batch.
+	for _, quotaInfo := range newQuotaInfos {
+		visited := map[string]bool{quotaInfo.Name: true}
+		for parentInfo := batch[quotaInfo.ParentName]; parentInfo != nil; parentInfo = batch[parentInfo.ParentName] {
+			if visited[parentInfo.Name] {
+				return nil, fmt.Errorf("quota %v is in a cycle of the batch", quotaInfo.Name)
+			}
+			visited[parentInfo.Name] = true
+		}
+	}
+	return newQuotaInfos, nil
+}
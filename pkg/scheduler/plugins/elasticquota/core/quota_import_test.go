@@ -0,0 +1,113 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	"github.com/koordinator-sh/koordinator/apis/thirdparty/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+)
+
+func TestGroupQuotaManager_ImportQuotas(t *testing.T) {
+	gqm := NewGroupQuotaManagerForTest()
+	gqm.UpdateClusterTotalResource(createResourceList(100, 100))
+
+	// root
+	//   `-- p1
+	//        |-- c1
+	//        `-- c2
+	// the children are listed before their parent.
+	err := gqm.ImportQuotas([]*v1alpha1.ElasticQuota{
+		CreateQuota("c1", "p1", 100, 100, 40, 40, true, false),
+		CreateQuota("c2", "p1", 100, 100, 60, 60, true, false),
+		CreateQuota("p1", extension.RootQuotaName, 100, 100, 100, 100, true, true),
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"p1"}, ancestorNames(gqm.GetAncestorQuotaInfos("c1")))
+	assert.Len(t, gqm.quotaTopoNodeMap["p1"].getChildGroupQuotaInfos(), 2)
+	for _, quotaName := range []string{"c1", "c2"} {
+		find, _ := gqm.runtimeQuotaCalculatorMap["p1"].quotaTree[v1.ResourceCPU].find(quotaName)
+		assert.True(t, find, quotaName)
+	}
+
+	// the runtime works right after the import without any further update.
+	request := createResourceList(80, 80)
+	gqm.updateGroupDeltaRequestNoLock("c1", request, request, 0)
+	assert.Equal(t, createResourceList(80, 80), gqm.RefreshRuntime("c1"))
+}
+
+func TestGroupQuotaManager_ImportQuotasRollback(t *testing.T) {
+	tests := []struct {
+		name   string
+		quotas []*v1alpha1.ElasticQuota
+	}{
+		{
+			name: "parent missing",
+			quotas: []*v1alpha1.ElasticQuota{
+				CreateQuota("p2", extension.RootQuotaName, 100, 100, 10, 10, true, true),
+				CreateQuota("c3", "unknown", 100, 100, 10, 10, true, false),
+			},
+		},
+		{
+			name: "parent isn't a parent quota",
+			quotas: []*v1alpha1.ElasticQuota{
+				CreateQuota("p2", extension.RootQuotaName, 100, 100, 10, 10, true, false),
+				CreateQuota("c3", "p2", 100, 100, 10, 10, true, false),
+			},
+		},
+		{
+			name: "cycle in the batch",
+			quotas: []*v1alpha1.ElasticQuota{
+				CreateQuota("p2", "p3", 100, 100, 10, 10, true, true),
+				CreateQuota("p3", "p2", 100, 100, 10, 10, true, true),
+			},
+		},
+		{
+			name: "quota exists",
+			quotas: []*v1alpha1.ElasticQuota{
+				CreateQuota("p2", extension.RootQuotaName, 100, 100, 10, 10, true, true),
+				CreateQuota("p1", extension.RootQuotaName, 100, 100, 10, 10, true, true),
+			},
+		},
+		{
+			name: "duplicated quota",
+			quotas: []*v1alpha1.ElasticQuota{
+				CreateQuota("p2", extension.RootQuotaName, 100, 100, 10, 10, true, true),
+				CreateQuota("p2", extension.RootQuotaName, 100, 100, 10, 10, true, true),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gqm := NewGroupQuotaManagerForTest()
+			gqm.UpdateClusterTotalResource(createResourceList(100, 100))
+			AddQuotaToManager2(gqm, "p1", extension.RootQuotaName, 100, 100, 10, 10, true, true)
+			quotaNum, topoNodeNum := len(gqm.quotaInfoMap), len(gqm.quotaTopoNodeMap)
+
+			assert.Error(t, gqm.ImportQuotas(tt.quotas))
+			assert.Equal(t, quotaNum, len(gqm.quotaInfoMap))
+			assert.Equal(t, topoNodeNum, len(gqm.quotaTopoNodeMap))
+			assert.Nil(t, gqm.GetQuotaInfoByName("p2"))
+			assert.Nil(t, gqm.GetQuotaInfoByName("c3"))
+		})
+	}
+}
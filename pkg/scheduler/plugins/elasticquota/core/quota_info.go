@@ -18,9 +18,14 @@ package core
 
 import (
 	"fmt"
+	"math"
 	"sync"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
 	quotav1 "k8s.io/apiserver/pkg/quota/v1"
 	utilfeature "k8s.io/apiserver/pkg/util/feature"
 	"k8s.io/klog/v2"
@@ -65,6 +70,9 @@ type QuotaCalculateInfo struct {
 	// Allocated is the allocated resource. It's the sum of children quota guarantee. If the quota is leaf, it's
 	// the sum of scheduled pods
 	Allocated v1.ResourceList
+	// Reserved is the resource the quota group keeps for its own system overhead, e.g. monitoring sidecars.
+	// It's always subtracted from the used limit before admitting workloads.
+	Reserved v1.ResourceList
 }
 
 type QuotaInfo struct {
@@ -76,11 +84,39 @@ type QuotaInfo struct {
 	IsParent bool
 	// If runtimeVersion not equal to quotaTree runtimeVersion, means runtime has been updated.
 	RuntimeVersion int64
+	// Revision increases as the max, min or runtime of the quota changes, which tells whether the snapshot
+	// of the quota is stale.
+	Revision int64
 	// Allow lent resource to other quota group
 	AllowLentResource bool
-	CalculateInfo     QuotaCalculateInfo
-	PodCache          map[string]*PodInfo
-	lock              sync.RWMutex
+	// SchedulingStrategy is declared by the quota itself, empty means inheriting from the parent or the tree.
+	SchedulingStrategy extension.QuotaSchedulingStrategy
+	// AntiAffinityQuotas are the quotas whose pods the quota's pods avoid to be co-located with.
+	AntiAffinityQuotas []string
+	// RequiredPodLabels are the labels which the quota's pods must carry, an empty value only requires the key.
+	RequiredPodLabels map[string]string
+	// MinPriority decides which quota keeps its min first when the total resource can't satisfy all the mins.
+	MinPriority int32
+	// ChildrenOrder is the order in which the children of the parent quota receive the shared runtime.
+	ChildrenOrder []string
+	// SharingPolicies decide how the parent quota shares each resource dimension to its children,
+	// the dimensions not set are shared by weight.
+	SharingPolicies map[v1.ResourceName]extension.QuotaSharingPolicy
+	// OvercommitRatios let the quota admit up to its max multiplied by the ratio of each resource dimension,
+	// the dimensions not set aren't overcommitted. They're updated along with the max.
+	OvercommitRatios map[v1.ResourceName]float64
+	// ResourceGroups are the resource groups whose dimensions the quota enforces, empty means all the dimensions.
+	ResourceGroups []extension.QuotaResourceGroup
+	// Suspended quota admits no new pods, its running pods are still accounted.
+	Suspended bool
+	// PreemptionPolicy decides whether the quota's pods may preempt others, Never forbids the preemption.
+	PreemptionPolicy v1.PreemptionPolicy
+	CalculateInfo    QuotaCalculateInfo
+	PodCache         map[string]*PodInfo
+	lock             sync.RWMutex
+
+	// runtimeShrinkState holds the runtime seen by the admission while the runtime shrinks.
+	runtimeShrinkState *runtimeShrinkState
 }
 
 func NewQuotaInfo(isParent, allowLentResource bool, name, parentName string) *QuotaInfo {
@@ -108,6 +144,7 @@ func NewQuotaInfo(isParent, allowLentResource bool, name, parentName string) *Qu
 			SelfUsed:                  v1.ResourceList{},
 			SelfNonPreemptibleRequest: v1.ResourceList{},
 			SelfNonPreemptibleUsed:    v1.ResourceList{},
+			Reserved:                  v1.ResourceList{},
 		},
 	}
 }
@@ -120,12 +157,23 @@ func (qi *QuotaInfo) DeepCopy() *QuotaInfo {
 	defer qi.lock.RUnlock()
 
 	quotaInfo := &QuotaInfo{
-		Name:              qi.Name,
-		ParentName:        qi.ParentName,
-		IsParent:          qi.IsParent,
-		AllowLentResource: qi.AllowLentResource,
-		RuntimeVersion:    qi.RuntimeVersion,
-		PodCache:          make(map[string]*PodInfo),
+		Name:               qi.Name,
+		ParentName:         qi.ParentName,
+		IsParent:           qi.IsParent,
+		AllowLentResource:  qi.AllowLentResource,
+		SchedulingStrategy: qi.SchedulingStrategy,
+		AntiAffinityQuotas: append([]string(nil), qi.AntiAffinityQuotas...),
+		RequiredPodLabels:  copyLabels(qi.RequiredPodLabels),
+		MinPriority:        qi.MinPriority,
+		PreemptionPolicy:   qi.PreemptionPolicy,
+		Suspended:          qi.Suspended,
+		ChildrenOrder:      append([]string(nil), qi.ChildrenOrder...),
+		SharingPolicies:    copySharingPolicies(qi.SharingPolicies),
+		OvercommitRatios:   copyOvercommitRatios(qi.OvercommitRatios),
+		ResourceGroups:     append([]extension.QuotaResourceGroup(nil), qi.ResourceGroups...),
+		RuntimeVersion:     qi.RuntimeVersion,
+		Revision:           qi.Revision,
+		PodCache:           make(map[string]*PodInfo),
 		CalculateInfo: QuotaCalculateInfo{
 			Max:                       qi.CalculateInfo.Max.DeepCopy(),
 			AutoScaleMin:              qi.CalculateInfo.AutoScaleMin.DeepCopy(),
@@ -143,6 +191,7 @@ func (qi *QuotaInfo) DeepCopy() *QuotaInfo {
 			SelfUsed:                  qi.CalculateInfo.SelfUsed.DeepCopy(),
 			SelfNonPreemptibleRequest: qi.CalculateInfo.SelfNonPreemptibleRequest.DeepCopy(),
 			SelfNonPreemptibleUsed:    qi.CalculateInfo.SelfNonPreemptibleUsed.DeepCopy(),
+			Reserved:                  qi.CalculateInfo.Reserved.DeepCopy(),
 		},
 	}
 	for name, pod := range qi.PodCache {
@@ -161,6 +210,12 @@ func (qi *QuotaInfo) GetQuotaSummary(treeID string, includePods bool) *QuotaInfo
 	quotaInfoSummary.IsParent = qi.IsParent
 	quotaInfoSummary.RuntimeVersion = qi.RuntimeVersion
 	quotaInfoSummary.AllowLentResource = qi.AllowLentResource
+	quotaInfoSummary.SchedulingStrategy = qi.SchedulingStrategy
+	quotaInfoSummary.AntiAffinityQuotas = append([]string(nil), qi.AntiAffinityQuotas...)
+	quotaInfoSummary.RequiredPodLabels = copyLabels(qi.RequiredPodLabels)
+	quotaInfoSummary.MinPriority = qi.MinPriority
+	quotaInfoSummary.PreemptionPolicy = qi.PreemptionPolicy
+	quotaInfoSummary.Suspended = qi.Suspended
 	quotaInfoSummary.Tree = treeID
 	quotaInfoSummary.Max = qi.CalculateInfo.Max.DeepCopy()
 	quotaInfoSummary.Min = qi.CalculateInfo.Min.DeepCopy()
@@ -178,6 +233,7 @@ func (qi *QuotaInfo) GetQuotaSummary(treeID string, includePods bool) *QuotaInfo
 	quotaInfoSummary.SelfRequest = qi.CalculateInfo.SelfRequest.DeepCopy()
 	quotaInfoSummary.SelfNonPreemptibleUsed = qi.CalculateInfo.SelfNonPreemptibleUsed.DeepCopy()
 	quotaInfoSummary.SelfNonPreemptibleRequest = qi.CalculateInfo.SelfNonPreemptibleRequest.DeepCopy()
+	quotaInfoSummary.Reserved = qi.CalculateInfo.Reserved.DeepCopy()
 
 	if includePods {
 		for podName, podInfo := range qi.PodCache {
@@ -207,6 +263,123 @@ func (qi *QuotaInfo) updateQuotaInfoFromRemote(quotaInfo *QuotaInfo) {
 	qi.AllowLentResource = quotaInfo.AllowLentResource
 	qi.IsParent = quotaInfo.IsParent
 	qi.ParentName = quotaInfo.ParentName
+	qi.OvercommitRatios = copyOvercommitRatios(quotaInfo.OvercommitRatios)
+	qi.setAttributesNoLock(quotaInfo)
+}
+
+// setAttributesNoLock copies the attributes other than the min, max and sharedWeight.
+func (qi *QuotaInfo) setAttributesNoLock(quotaInfo *QuotaInfo) {
+	qi.SchedulingStrategy = quotaInfo.SchedulingStrategy
+	qi.AntiAffinityQuotas = append([]string(nil), quotaInfo.AntiAffinityQuotas...)
+	qi.RequiredPodLabels = copyLabels(quotaInfo.RequiredPodLabels)
+	qi.MinPriority = quotaInfo.MinPriority
+	qi.PreemptionPolicy = quotaInfo.PreemptionPolicy
+	qi.Suspended = quotaInfo.Suspended
+	qi.ChildrenOrder = append([]string(nil), quotaInfo.ChildrenOrder...)
+	qi.SharingPolicies = copySharingPolicies(quotaInfo.SharingPolicies)
+	qi.ResourceGroups = append([]extension.QuotaResourceGroup(nil), quotaInfo.ResourceGroups...)
+	qi.CalculateInfo.Reserved = quotaInfo.CalculateInfo.Reserved.DeepCopy()
+}
+
+// isAttributesChangeNoLock returns true if the attributes other than the min, max and sharedWeight changed.
+func (qi *QuotaInfo) isAttributesChangeNoLock(quotaInfo *QuotaInfo) bool {
+	return qi.SchedulingStrategy != quotaInfo.SchedulingStrategy || qi.MinPriority != quotaInfo.MinPriority ||
+		qi.PreemptionPolicy != quotaInfo.PreemptionPolicy || qi.Suspended != quotaInfo.Suspended ||
+		!sets.NewString(qi.AntiAffinityQuotas...).Equal(sets.NewString(quotaInfo.AntiAffinityQuotas...)) ||
+		!labels.Equals(qi.RequiredPodLabels, quotaInfo.RequiredPodLabels) ||
+		!isSameOrder(qi.ChildrenOrder, quotaInfo.ChildrenOrder) ||
+		!isSameSharingPolicies(qi.SharingPolicies, quotaInfo.SharingPolicies) ||
+		!isSameResourceGroups(qi.ResourceGroups, quotaInfo.ResourceGroups) ||
+		!quotav1.Equals(qi.CalculateInfo.Reserved, quotaInfo.CalculateInfo.Reserved)
+}
+
+func isSameOrder(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func copySharingPolicies(policies map[v1.ResourceName]extension.QuotaSharingPolicy) map[v1.ResourceName]extension.QuotaSharingPolicy {
+	if policies == nil {
+		return nil
+	}
+	result := make(map[v1.ResourceName]extension.QuotaSharingPolicy, len(policies))
+	for resourceName, policy := range policies {
+		result[resourceName] = policy
+	}
+	return result
+}
+
+func isSameSharingPolicies(a, b map[v1.ResourceName]extension.QuotaSharingPolicy) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for resourceName, policy := range a {
+		if b[resourceName] != policy {
+			return false
+		}
+	}
+	return true
+}
+
+func copyOvercommitRatios(ratios map[v1.ResourceName]float64) map[v1.ResourceName]float64 {
+	if ratios == nil {
+		return nil
+	}
+	result := make(map[v1.ResourceName]float64, len(ratios))
+	for resourceName, ratio := range ratios {
+		result[resourceName] = ratio
+	}
+	return result
+}
+
+func isSameOvercommitRatios(a, b map[v1.ResourceName]float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for resourceName, ratio := range a {
+		if r, ok := b[resourceName]; !ok || r != ratio {
+			return false
+		}
+	}
+	return true
+}
+
+func isSameResourceGroups(a, b []extension.QuotaResourceGroup) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// MaskByResourceGroups returns the dimensions of the resource list which the quota enforces, i.e. the ones
+// in the resource groups of the quota. All the dimensions are kept if the quota declares no resource groups.
+func (qi *QuotaInfo) MaskByResourceGroups(resourceList v1.ResourceList) v1.ResourceList {
+	if len(qi.ResourceGroups) == 0 {
+		return resourceList
+	}
+	masked := v1.ResourceList{}
+	for resourceName, quantity := range resourceList {
+		group := extension.GetQuotaResourceGroup(resourceName)
+		for _, g := range qi.ResourceGroups {
+			if g == group {
+				masked[resourceName] = quantity.DeepCopy()
+				break
+			}
+		}
+	}
+	return masked
 }
 
 // getLimitRequestNoLock returns the min value of request and max, as max is the quotaGroup's upper limit of resources.
@@ -214,10 +387,12 @@ func (qi *QuotaInfo) updateQuotaInfoFromRemote(quotaInfo *QuotaInfo) {
 // max will result in a wrong/invalid runtime distribution. For example, parentQuotaGroup's Max is 20, childGroup's Max
 // is 10, and the childGroup's request is 30. If the child passes 30 request upwards and get a 20 runtime back
 // (limited by the parent's max is 20), the child can only use 10 (limited by its max).
+// The max is scaled by the overcommit ratios, so the runtime can grow up to the overcommitted max.
 func (qi *QuotaInfo) getLimitRequestNoLock() v1.ResourceList {
 	limitRequest := qi.CalculateInfo.Request.DeepCopy()
+	max := qi.getOvercommittedMaxNoLock()
 	for resName, quantity := range limitRequest {
-		if maxQuantity, ok := qi.CalculateInfo.Max[resName]; ok {
+		if maxQuantity, ok := max[resName]; ok {
 			if quantity.Cmp(maxQuantity) == 1 {
 				// req > max, limitRequest = max
 				limitRequest[resName] = maxQuantity.DeepCopy()
@@ -228,11 +403,11 @@ func (qi *QuotaInfo) getLimitRequestNoLock() v1.ResourceList {
 }
 
 func (qi *QuotaInfo) setMaxNoLock(max v1.ResourceList) {
-	qi.CalculateInfo.Max = max.DeepCopy()
+	qi.setMaxQuotaNoLock(max)
 }
 
 func (qi *QuotaInfo) setMinNoLock(min v1.ResourceList) {
-	qi.CalculateInfo.Min = min.DeepCopy()
+	qi.setMinQuotaNoLock(min)
 }
 
 func (qi *QuotaInfo) addRequestNonNegativeNoLock(delta, deltaNonPreemptibleRequest v1.ResourceList, isSelfRequest bool) {
@@ -306,10 +481,16 @@ func (qi *QuotaInfo) addAllocatedQuotaNoLock(delta v1.ResourceList) {
 }
 
 func (qi *QuotaInfo) setMaxQuotaNoLock(res v1.ResourceList) {
+	if !quotav1.Equals(qi.CalculateInfo.Max, res) {
+		qi.Revision++
+	}
 	qi.CalculateInfo.Max = res.DeepCopy()
 }
 
 func (qi *QuotaInfo) setMinQuotaNoLock(res v1.ResourceList) {
+	if !quotav1.Equals(qi.CalculateInfo.Min, res) {
+		qi.Revision++
+	}
 	qi.CalculateInfo.Min = res.DeepCopy()
 }
 
@@ -375,6 +556,12 @@ func (qi *QuotaInfo) GetSelfNonPreemptibleRequest() v1.ResourceList {
 	return qi.CalculateInfo.SelfNonPreemptibleRequest.DeepCopy()
 }
 
+func (qi *QuotaInfo) GetRevision() int64 {
+	qi.lock.RLock()
+	defer qi.lock.RUnlock()
+	return qi.Revision
+}
+
 func (qi *QuotaInfo) GetRuntime() v1.ResourceList {
 	qi.lock.RLock()
 	defer qi.lock.RUnlock()
@@ -387,12 +574,89 @@ func (qi *QuotaInfo) GetMax() v1.ResourceList {
 	return qi.CalculateInfo.Max.DeepCopy()
 }
 
+// GetOvercommittedMax returns the max multiplied by the overcommit ratio of each resource dimension.
+func (qi *QuotaInfo) GetOvercommittedMax() v1.ResourceList {
+	qi.lock.RLock()
+	defer qi.lock.RUnlock()
+	return qi.getOvercommittedMaxNoLock()
+}
+
+func (qi *QuotaInfo) getOvercommittedMaxNoLock() v1.ResourceList {
+	max := qi.CalculateInfo.Max.DeepCopy()
+	for resourceName, ratio := range qi.OvercommitRatios {
+		if quantity, ok := max[resourceName]; ok && ratio > 1 {
+			max[resourceName] = overcommitQuantity(quantity, ratio)
+		}
+	}
+	return max
+}
+
+// overcommitQuantity multiplies the quantity by the ratio, in milli if the result fits in the milli value, and
+// clamped to math.MaxInt64 otherwise, so the overcommitted max of the huge max doesn't overflow.
+func overcommitQuantity(quantity resource.Quantity, ratio float64) resource.Quantity {
+	value := quantity.AsApproximateFloat64() * ratio
+	if value < math.MaxInt64/1000 {
+		return *resource.NewMilliQuantity(int64(float64(quantity.MilliValue())*ratio), quantity.Format)
+	}
+	if value >= math.MaxInt64 {
+		return *resource.NewQuantity(math.MaxInt64, quantity.Format)
+	}
+	return *resource.NewQuantity(int64(value), quantity.Format)
+}
+
+func (qi *QuotaInfo) GetReserved() v1.ResourceList {
+	qi.lock.RLock()
+	defer qi.lock.RUnlock()
+	return qi.CalculateInfo.Reserved.DeepCopy()
+}
+
+func (qi *QuotaInfo) GetAntiAffinityQuotas() []string {
+	qi.lock.RLock()
+	defer qi.lock.RUnlock()
+	return append([]string(nil), qi.AntiAffinityQuotas...)
+}
+
+func (qi *QuotaInfo) GetRequiredPodLabels() map[string]string {
+	qi.lock.RLock()
+	defer qi.lock.RUnlock()
+	return copyLabels(qi.RequiredPodLabels)
+}
+
+func (qi *QuotaInfo) IsSuspended() bool {
+	qi.lock.RLock()
+	defer qi.lock.RUnlock()
+	return qi.Suspended
+}
+
+func (qi *QuotaInfo) GetPreemptionPolicy() v1.PreemptionPolicy {
+	qi.lock.RLock()
+	defer qi.lock.RUnlock()
+	return qi.PreemptionPolicy
+}
+
+func copyLabels(src map[string]string) map[string]string {
+	if src == nil {
+		return nil
+	}
+	dst := make(map[string]string, len(src))
+	for k, v := range src {
+		dst[k] = v
+	}
+	return dst
+}
+
 func (qi *QuotaInfo) GetMin() v1.ResourceList {
 	qi.lock.RLock()
 	defer qi.lock.RUnlock()
 	return qi.CalculateInfo.Min.DeepCopy()
 }
 
+func (qi *QuotaInfo) GetSharedWeight() v1.ResourceList {
+	qi.lock.RLock()
+	defer qi.lock.RUnlock()
+	return qi.CalculateInfo.SharedWeight.DeepCopy()
+}
+
 func NewQuotaInfoFromQuota(quota *v1alpha1.ElasticQuota) *QuotaInfo {
 	isParent := extension.IsParentQuota(quota)
 	parentName := extension.GetParentQuotaName(quota)
@@ -407,6 +671,29 @@ func NewQuotaInfoFromQuota(quota *v1alpha1.ElasticQuota) *QuotaInfo {
 	quotaInfo.setMaxQuotaNoLock(quota.Spec.Max)
 	newSharedWeight := extension.GetSharedWeight(quota)
 	quotaInfo.setSharedWeightNoLock(newSharedWeight)
+	reserved, err := extension.GetReserved(quota)
+	if err != nil {
+		klog.Errorf("failed to get reserved of quota %v, err: %v", quota.Name, err)
+	}
+	quotaInfo.CalculateInfo.Reserved = reserved
+	quotaInfo.SchedulingStrategy = extension.GetSchedulingStrategy(quota)
+	quotaInfo.AntiAffinityQuotas = extension.GetAntiAffinityQuotas(quota)
+	quotaInfo.RequiredPodLabels = extension.GetRequiredPodLabels(quota)
+	quotaInfo.MinPriority = extension.GetMinPriority(quota)
+	quotaInfo.PreemptionPolicy = extension.GetPreemptionPolicy(quota)
+	quotaInfo.Suspended = extension.IsQuotaSuspended(quota)
+	quotaInfo.ChildrenOrder = extension.GetChildrenOrder(quota)
+	sharingPolicies, err := extension.GetSharingPolicies(quota)
+	if err != nil {
+		klog.Errorf("failed to get sharing policies of quota %v, err: %v", quota.Name, err)
+	}
+	quotaInfo.SharingPolicies = sharingPolicies
+	overcommitRatios, err := extension.GetOvercommitRatios(quota)
+	if err != nil {
+		klog.Errorf("failed to get overcommit ratios of quota %v, err: %v", quota.Name, err)
+	}
+	quotaInfo.OvercommitRatios = overcommitRatios
+	quotaInfo.ResourceGroups = extension.GetResourceGroups(quota)
 
 	return quotaInfo
 }
@@ -429,6 +716,7 @@ func (qi *QuotaInfo) clearForResetNoLock() {
 	qi.CalculateInfo.SelfNonPreemptibleUsed = v1.ResourceList{}
 	qi.CalculateInfo.SelfNonPreemptibleRequest = v1.ResourceList{}
 	qi.RuntimeVersion = 0
+	qi.Revision++
 }
 
 func (qi *QuotaInfo) IsQuotaMetaChange(quotaInfo *QuotaInfo) bool {
@@ -464,6 +752,14 @@ func (qi *QuotaInfo) IsQuotaChange(quotaInfo *QuotaInfo) bool {
 	if !quotav1.Equals(qi.CalculateInfo.SharedWeight, quotaInfo.CalculateInfo.SharedWeight) {
 		return true
 	}
+
+	if !isSameOvercommitRatios(qi.OvercommitRatios, quotaInfo.OvercommitRatios) {
+		return true
+	}
+
+	if qi.isAttributesChangeNoLock(quotaInfo) {
+		return true
+	}
 	return false
 }
 
@@ -481,7 +777,7 @@ func (qi *QuotaInfo) IsPodExist(pod *v1.Pod) bool {
 	return exist
 }
 
-func (qi *QuotaInfo) addPodIfNotPresent(pod *v1.Pod) {
+func (qi *QuotaInfo) addPodIfNotPresent(pod *v1.Pod, claimRequests v1.ResourceList) {
 	qi.lock.Lock()
 	defer qi.lock.Unlock()
 
@@ -490,7 +786,24 @@ func (qi *QuotaInfo) addPodIfNotPresent(pod *v1.Pod) {
 		klog.Errorf("pod already exist in PodCache quota:%v, podKey:%v", qi.Name, key)
 		return
 	}
-	qi.PodCache[key] = NewPodInfo(pod)
+	podInfo := NewPodInfo(pod)
+	if len(claimRequests) > 0 {
+		podInfo.claimRequests = claimRequests
+		podInfo.resource = quotav1.Add(podInfo.resource, claimRequests)
+	}
+	qi.PodCache[key] = podInfo
+}
+
+// getPodClaimRequests returns the resource claim requests resolved when the pod was added to the quota.
+func (qi *QuotaInfo) getPodClaimRequests(pod *v1.Pod) (v1.ResourceList, bool) {
+	qi.lock.RLock()
+	defer qi.lock.RUnlock()
+
+	podInfo, exist := qi.PodCache[generatePodCacheKey(pod)]
+	if !exist {
+		return nil, false
+	}
+	return podInfo.claimRequests, true
 }
 
 func (qi *QuotaInfo) removePodIfPresent(pod *v1.Pod) {
@@ -560,6 +873,28 @@ func (qi *QuotaInfo) GetPodThatIsAssigned() []*v1.Pod {
 	return pods
 }
 
+// GetSteadyAndBurstUsed splits the used of the assigned pods by their age. The pods which have been running for
+// at least steadyDuration are counted as steady-state used, the others, e.g. the recently scheduled pods and the pods
+// not started yet, are counted as burst used.
+func (qi *QuotaInfo) GetSteadyAndBurstUsed(now time.Time, steadyDuration time.Duration) (v1.ResourceList, v1.ResourceList) {
+	qi.lock.RLock()
+	defer qi.lock.RUnlock()
+
+	steadyUsed, burstUsed := v1.ResourceList{}, v1.ResourceList{}
+	for _, podInfo := range qi.PodCache {
+		if !podInfo.isAssigned {
+			continue
+		}
+		startTime := podInfo.pod.Status.StartTime
+		if startTime != nil && now.Sub(startTime.Time) >= steadyDuration {
+			steadyUsed = quotav1.Add(steadyUsed, podInfo.resource)
+		} else {
+			burstUsed = quotav1.Add(burstUsed, podInfo.resource)
+		}
+	}
+	return steadyUsed, burstUsed
+}
+
 func (qi *QuotaInfo) Lock() {
 	qi.lock.Lock()
 }
@@ -601,6 +936,9 @@ type PodInfo struct {
 	pod        *v1.Pod
 	isAssigned bool
 	resource   v1.ResourceList
+	// claimRequests are resolved once from the resource claims of the pod, so that the pod
+	// releases the same amount it consumes even if the claims are deleted in between.
+	claimRequests v1.ResourceList
 }
 
 func NewPodInfo(pod *v1.Pod) *PodInfo {
@@ -617,6 +955,9 @@ func (pInfo *PodInfo) DeepCopy() *PodInfo {
 		isAssigned: pInfo.isAssigned,
 		resource:   pInfo.resource.DeepCopy(),
 	}
+	if pInfo.claimRequests != nil {
+		newPodInfo.claimRequests = pInfo.claimRequests.DeepCopy()
+	}
 	return newPodInfo
 }
 
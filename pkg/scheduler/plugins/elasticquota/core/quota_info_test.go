@@ -17,19 +17,25 @@ limitations under the License.
 package core
 
 import (
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	quotav1 "k8s.io/apiserver/pkg/quota/v1"
 	schetesting "k8s.io/kubernetes/pkg/scheduler/testing"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
 )
 
 func TestQuotaInfo_AddPodIfNotPresent_RemovePodIfPresent_GetPodCache(t *testing.T) {
 	qi := NewQuotaInfo(false, true, "qi1", "root")
 	pod := schetesting.MakePod().Name("test").Obj()
-	qi.addPodIfNotPresent(pod)
+	qi.addPodIfNotPresent(pod, nil)
 	assert.Equal(t, 1, len(qi.GetPodCache()))
 	assert.Equal(t, 0, len(qi.GetPodThatIsAssigned()))
-	qi.addPodIfNotPresent(pod)
+	qi.addPodIfNotPresent(pod, nil)
 	assert.False(t, qi.CheckPodIsAssigned(pod))
 	err := qi.UpdatePodIsAssigned(pod, false)
 	assert.NotNil(t, err)
@@ -112,3 +118,56 @@ func TestUpdateQuotaInfoFromRemote(t *testing.T) {
 	assert.NotEqual(t, qi.CalculateInfo.Request, remoteQuotaInfo.CalculateInfo.Request)
 	assert.NotEqual(t, qi.CalculateInfo.Runtime, remoteQuotaInfo.CalculateInfo.Runtime)
 }
+
+func TestNewQuotaInfoFromQuota_Reserved(t *testing.T) {
+	quota := CreateQuota("test", extension.RootQuotaName, 100, 1000, 10, 100, true, false)
+	qi := NewQuotaInfoFromQuota(quota)
+	assert.True(t, quotav1.IsZero(qi.GetReserved()))
+
+	quota.Annotations[extension.AnnotationReserved] = `{"cpu":"2","memory":"20"}`
+	newQi := NewQuotaInfoFromQuota(quota)
+	assert.True(t, quotav1.Equals(createResourceList(2, 20), newQi.GetReserved()))
+	assert.True(t, qi.IsQuotaChange(newQi))
+	assert.False(t, qi.IsQuotaMetaChange(newQi))
+
+	qi.updateQuotaInfoFromRemote(newQi)
+	assert.True(t, quotav1.Equals(newQi.GetReserved(), qi.GetReserved()))
+	assert.False(t, qi.IsQuotaChange(newQi))
+}
+
+func TestQuotaInfo_GetOvercommittedMax(t *testing.T) {
+	tests := []struct {
+		name   string
+		max    resource.Quantity
+		ratio  float64
+		expect resource.Quantity
+	}{
+		{
+			name:   "overcommit in milli",
+			max:    resource.MustParse("10"),
+			ratio:  1.5,
+			expect: resource.MustParse("15"),
+		},
+		{
+			name:   "overcommit beyond the milli value",
+			max:    *resource.NewQuantity(1<<60, resource.BinarySI),
+			ratio:  2,
+			expect: *resource.NewQuantity(1<<61, resource.BinarySI),
+		},
+		{
+			name:   "overcommit near math.MaxInt64 is clamped",
+			max:    *resource.NewQuantity(math.MaxInt64-1, resource.BinarySI),
+			ratio:  4,
+			expect: *resource.NewQuantity(math.MaxInt64, resource.BinarySI),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qi := NewQuotaInfo(false, true, "test", extension.RootQuotaName)
+			qi.CalculateInfo.Max = corev1.ResourceList{corev1.ResourceMemory: tt.max}
+			qi.OvercommitRatios = map[corev1.ResourceName]float64{corev1.ResourceMemory: tt.ratio}
+			got := qi.GetOvercommittedMax()[corev1.ResourceMemory]
+			assert.Equal(t, 0, tt.expect.Cmp(got), "expect %v, got %v", tt.expect.String(), got.String())
+		})
+	}
+}
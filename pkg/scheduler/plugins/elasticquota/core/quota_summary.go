@@ -18,6 +18,8 @@ package core
 
 import (
 	v1 "k8s.io/api/core/v1"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
 )
 
 type SimplePodInfo struct {
@@ -33,6 +35,13 @@ type QuotaInfoSummary struct {
 	AllowLentResource bool   `json:"allowLentResource"`
 	Tree              string `json:"tree"`
 
+	SchedulingStrategy extension.QuotaSchedulingStrategy `json:"schedulingStrategy,omitempty"`
+	AntiAffinityQuotas []string                          `json:"antiAffinityQuotas,omitempty"`
+	RequiredPodLabels  map[string]string                 `json:"requiredPodLabels,omitempty"`
+	MinPriority        int32                             `json:"minPriority,omitempty"`
+	PreemptionPolicy   v1.PreemptionPolicy               `json:"preemptionPolicy,omitempty"`
+	Suspended          bool                              `json:"suspended,omitempty"`
+
 	Max                       v1.ResourceList `json:"max"`
 	Min                       v1.ResourceList `json:"min"`
 	AutoScaleMin              v1.ResourceList `json:"autoScaleMin"`
@@ -49,10 +58,21 @@ type QuotaInfoSummary struct {
 	SelfNonPreemptibleUsed    v1.ResourceList `json:"selfNonPreemptibleUsed"`
 	SelfRequest               v1.ResourceList `json:"selfRequest"`
 	SelfNonPreemptibleRequest v1.ResourceList `json:"selfNonPreemptibleRequest"`
+	Reserved                  v1.ResourceList `json:"reserved,omitempty"`
+	// GangReserved is the quota reserved for the pending members of the gangs.
+	GangReserved v1.ResourceList `json:"gangReserved,omitempty"`
 
+	Children []string                  `json:"children,omitempty"`
 	PodCache map[string]*SimplePodInfo `json:"podCache,omitempty"`
 }
 
+// QuotaTopologySummary is the summary of a quota with the summaries of its children, which
+// dumps the quota tree from the root quota.
+type QuotaTopologySummary struct {
+	Quota    *QuotaInfoSummary       `json:"quota"`
+	Children []*QuotaTopologySummary `json:"children,omitempty"`
+}
+
 func NewQuotaInfoSummary() *QuotaInfoSummary {
 	return &QuotaInfoSummary{
 		Max:          make(v1.ResourceList),
@@ -17,15 +17,24 @@ limitations under the License.
 package core
 
 import (
+	"sort"
 	"sync"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	quotav1 "k8s.io/apiserver/pkg/quota/v1"
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
 	"k8s.io/klog/v2"
 
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	"github.com/koordinator-sh/koordinator/pkg/features"
 	"github.com/koordinator-sh/koordinator/pkg/util"
 )
 
+// RuntimeTraceVerbosity is the log verbosity at which each step of the runtime quota distribution is traced,
+// including the inputs, the per-child shares and the leftovers, to help to find out why a quota got its runtime.
+var RuntimeTraceVerbosity klog.Level = 6
+
 // quotaNode stores the corresponding quotaInfo's information in a specific resource dimension.
 type quotaNode struct {
 	quotaName         string
@@ -49,9 +58,35 @@ func NewQuotaNode(quotaName string, sharedWeight, request, min, guarantee int64,
 	}
 }
 
+// assignBaseRuntime sets the node's runtime to the part which isn't shared with the siblings,
+// and returns whether the node requests more than that and needs adjustQuota.
+func (node *quotaNode) assignBaseRuntime() bool {
+	min := node.min
+	// if guarantee greater than min, min is guarantee.
+	if node.guarantee > min {
+		min = node.guarantee
+	}
+	if node.request > min {
+		// if a node's request > autoScaleMin, the node needs adjustQuota
+		// the node's runtime is autoScaleMin
+		node.runtimeQuota = min
+		return true
+	}
+	if node.allowLentResource {
+		node.runtimeQuota = node.request
+	} else {
+		// if node is not allowLentResource, even if the request is smaller
+		// than autoScaleMin, runtimeQuota is request.
+		node.runtimeQuota = min
+	}
+	return false
+}
+
 // quotaTree abstract the struct to calculate each resource dimension's runtime Quota independently
 type quotaTree struct {
 	quotaNodes map[string]*quotaNode
+	// equalShare shares the resource equally to the nodes instead of by their sharedWeight
+	equalShare bool
 }
 
 func NewQuotaTree() *quotaTree {
@@ -104,6 +139,15 @@ func (qt *quotaTree) erase(groupName string) {
 	}
 }
 
+// weightOf returns the weight by which the node shares the resource with its siblings. A node without
+// sharedWeight never shares the resource, even if the resource is shared equally.
+func (qt *quotaTree) weightOf(node *quotaNode) int64 {
+	if qt.equalShare && node.sharedWeight > 0 {
+		return 1
+	}
+	return node.sharedWeight
+}
+
 func (qt *quotaTree) find(groupName string) (bool, *quotaNode) {
 	if nodeValue, exist := qt.quotaNodes[groupName]; exist {
 		return exist, nodeValue
@@ -118,26 +162,10 @@ func (qt *quotaTree) redistribution(totalResource int64) {
 	toPartitionResource := totalResource
 	totalSharedWeight := int64(0)
 	needAdjustQuotaNodes := make([]*quotaNode, 0)
-	for _, node := range qt.quotaNodes {
-		min := node.min
-		// if guarantee greater than min, min is guarantee.
-		if node.guarantee > min {
-			min = node.guarantee
-		}
-		if node.request > min {
-			// if a node's request > autoScaleMin, the node needs adjustQuota
-			// the node's runtime is autoScaleMin
+	for _, node := range qt.sortedQuotaNodes() {
+		if node.assignBaseRuntime() {
 			needAdjustQuotaNodes = append(needAdjustQuotaNodes, node)
-			totalSharedWeight += node.sharedWeight
-			node.runtimeQuota = min
-		} else {
-			if node.allowLentResource {
-				node.runtimeQuota = node.request
-			} else {
-				// if node is not allowLentResource, even if the request is smaller
-				// than autoScaleMin, runtimeQuota is request.
-				node.runtimeQuota = min
-			}
+			totalSharedWeight += qt.weightOf(node)
 		}
 		toPartitionResource -= node.runtimeQuota
 	}
@@ -147,6 +175,49 @@ func (qt *quotaTree) redistribution(totalResource int64) {
 	}
 }
 
+// redistributionByOrder distributes the resource like redistribution, except that the shared resource goes to
+// the ordered childQuotaGroups one by one until each is satisfied, and the rest childQuotaGroups share what's
+// left by their sharedWeight.
+func (qt *quotaTree) redistributionByOrder(totalResource int64, childrenOrder []string) {
+	toPartitionResource := totalResource
+	needAdjustQuotaNodes := make(map[string]*quotaNode)
+	for _, node := range qt.sortedQuotaNodes() {
+		if node.assignBaseRuntime() {
+			needAdjustQuotaNodes[node.quotaName] = node
+		}
+		toPartitionResource -= node.runtimeQuota
+	}
+
+	for _, quotaName := range childrenOrder {
+		node, exist := needAdjustQuotaNodes[quotaName]
+		if !exist {
+			continue
+		}
+		delete(needAdjustQuotaNodes, quotaName)
+		if toPartitionResource <= 0 {
+			continue
+		}
+		delta := node.request - node.runtimeQuota
+		if delta > toPartitionResource {
+			delta = toPartitionResource
+		}
+		node.runtimeQuota += delta
+		toPartitionResource -= delta
+	}
+
+	if toPartitionResource > 0 {
+		totalSharedWeight := int64(0)
+		nodes := make([]*quotaNode, 0, len(needAdjustQuotaNodes))
+		for _, node := range qt.sortedQuotaNodes() {
+			if _, exist := needAdjustQuotaNodes[node.quotaName]; exist {
+				nodes = append(nodes, node)
+				totalSharedWeight += qt.weightOf(node)
+			}
+		}
+		qt.iterationForRedistribution(toPartitionResource, totalSharedWeight, nodes)
+	}
+}
+
 func (qt *quotaTree) iterationForRedistribution(totalRes, totalSharedWeight int64, nodes []*quotaNode) {
 	if totalSharedWeight <= 0 {
 		// if totalSharedWeight is not larger than 0, no need to iterate anymore.
@@ -154,13 +225,13 @@ func (qt *quotaTree) iterationForRedistribution(totalRes, totalSharedWeight int6
 	}
 	needAdjustQuotaNodes := make([]*quotaNode, 0)
 	toPartitionResource, needAdjustTotalSharedWeight := int64(0), int64(0)
-	for _, node := range nodes {
-		runtimeQuotaDelta := int64(float64(node.sharedWeight)*float64(totalRes)/float64(totalSharedWeight) + 0.5)
-		node.runtimeQuota += runtimeQuotaDelta
+	runtimeQuotaDeltas := qt.divideBySharedWeight(totalRes, totalSharedWeight, nodes)
+	for i, node := range nodes {
+		node.runtimeQuota += runtimeQuotaDeltas[i]
 		if node.runtimeQuota < node.request {
 			// if node's runtime is still less than request, the node still need to iterate.
 			needAdjustQuotaNodes = append(needAdjustQuotaNodes, node)
-			needAdjustTotalSharedWeight += node.sharedWeight
+			needAdjustTotalSharedWeight += qt.weightOf(node)
 		} else {
 			toPartitionResource += node.runtimeQuota - node.request
 			node.runtimeQuota = node.request
@@ -172,6 +243,53 @@ func (qt *quotaTree) iterationForRedistribution(totalRes, totalSharedWeight int6
 	}
 }
 
+// sortedQuotaNodes returns the quotaNodes ordered by the quota name.
+func (qt *quotaTree) sortedQuotaNodes() []*quotaNode {
+	nodes := make([]*quotaNode, 0, len(qt.quotaNodes))
+	for _, node := range qt.quotaNodes {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].quotaName < nodes[j].quotaName
+	})
+	return nodes
+}
+
+// divideBySharedWeight divides totalRes to the nodes in proportion to their weight. Each node gets the
+// integral part of its share first, then the indivisible leftover units are given one by one to the nodes
+// with the largest fractional part, and the ties are broken by the quota name, so the result doesn't depend
+// on the order of the nodes.
+func (qt *quotaTree) divideBySharedWeight(totalRes, totalSharedWeight int64, nodes []*quotaNode) []int64 {
+	deltas := make([]int64, len(nodes))
+	remainders := make([]float64, len(nodes))
+	leftover := totalRes
+	for i, node := range nodes {
+		share := float64(qt.weightOf(node)) * float64(totalRes) / float64(totalSharedWeight)
+		deltas[i] = int64(share)
+		remainders[i] = share - float64(deltas[i])
+		leftover -= deltas[i]
+	}
+
+	order := make([]int, len(nodes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		if remainders[order[i]] != remainders[order[j]] {
+			return remainders[order[i]] > remainders[order[j]]
+		}
+		return nodes[order[i]].quotaName < nodes[order[j]].quotaName
+	})
+	for i := 0; i < len(order) && leftover > 0; i++ {
+		if remainders[order[i]] <= 0 {
+			break
+		}
+		deltas[order[i]]++
+		leftover--
+	}
+	return deltas
+}
+
 type quotaResMapType map[string]v1.ResourceList
 type quotaTreeMapType map[v1.ResourceName]*quotaTree
 
@@ -179,6 +297,9 @@ type quotaTreeMapType map[v1.ResourceName]*quotaTree
 // corresponding quotaInfo(treeName)
 type RuntimeQuotaCalculator struct {
 	globalRuntimeVersion int64                        // increase as the runtimeQuota changed
+	calculatedVersion    int64                        // the globalRuntimeVersion the quotaTree was last redistributed at
+	groupRuntime         quotaResMapType              // all childQuotaInfos' runtimeQuota of the last redistribution
+	groupRuntimeVersion  map[string]int64             // the globalRuntimeVersion each childQuotaInfo's runtimeQuota last changed at
 	resourceKeys         map[v1.ResourceName]struct{} // the resource dimensions
 	groupReqLimit        quotaResMapType              // all childQuotaInfos' limitedRequest
 	quotaTree            quotaTreeMapType             // has all resource dimension's information
@@ -186,6 +307,9 @@ type RuntimeQuotaCalculator struct {
 	lock                 sync.Mutex
 	treeName             string // the same as the parentQuotaInfo's Name
 	groupGuaranteed      quotaResMapType
+	runtimeDistribution  extension.QuotaRuntimeDistribution               // how the totalResource is distributed to the childGroups
+	childrenOrder        []string                                         // the childGroups satisfied first in order, overriding the runtimeDistribution
+	sharingPolicies      map[v1.ResourceName]extension.QuotaSharingPolicy // how each resource dimension is shared to the childGroups
 }
 
 func NewRuntimeQuotaCalculator(treeName string) *RuntimeQuotaCalculator {
@@ -194,9 +318,12 @@ func NewRuntimeQuotaCalculator(treeName string) *RuntimeQuotaCalculator {
 		resourceKeys:         make(map[v1.ResourceName]struct{}),
 		groupReqLimit:        make(quotaResMapType),
 		groupGuaranteed:      make(quotaResMapType),
+		groupRuntime:         make(quotaResMapType),
+		groupRuntimeVersion:  make(map[string]int64),
 		quotaTree:            make(quotaTreeMapType),
 		totalResource:        v1.ResourceList{},
 		treeName:             treeName,
+		runtimeDistribution:  extension.QuotaRuntimeDistributionWeighted,
 	}
 }
 
@@ -211,6 +338,10 @@ func (qtw *RuntimeQuotaCalculator) updateResourceKeys(resourceKeys map[v1.Resour
 
 	qtw.resourceKeys = newResourceKey
 	qtw.updateQuotaTreeDimensionByResourceKeysNoLock()
+	// the new dimensions of the childGroups have never been redistributed
+	if len(qtw.groupReqLimit) > 0 {
+		qtw.globalRuntimeVersion++
+	}
 }
 
 func (qtw *RuntimeQuotaCalculator) updateQuotaTreeDimensionByResourceKeysNoLock() {
@@ -440,6 +571,11 @@ func (qtw *RuntimeQuotaCalculator) setClusterTotalResource(full v1.ResourceList)
 	qtw.lock.Lock()
 	defer qtw.lock.Unlock()
 
+	// the share of the childGroups can't change if the totalResource is unchanged, skip invalidating them
+	if !fullRuntimeRefreshEnabled() && quotav1.Equals(qtw.totalResource, full) {
+		return
+	}
+
 	oldTotalRes := qtw.totalResource.DeepCopy()
 	qtw.totalResource = full.DeepCopy()
 	qtw.globalRuntimeVersion++
@@ -450,6 +586,45 @@ func (qtw *RuntimeQuotaCalculator) setClusterTotalResource(full v1.ResourceList)
 	}
 }
 
+// setRuntimeDistribution switches how the totalResource is distributed to the childGroups, the runtimeQuota
+// of all childGroups will change, then increase globalRuntimeVersion
+func (qtw *RuntimeQuotaCalculator) setRuntimeDistribution(distribution extension.QuotaRuntimeDistribution) {
+	qtw.lock.Lock()
+	defer qtw.lock.Unlock()
+
+	if qtw.runtimeDistribution == distribution {
+		return
+	}
+	qtw.runtimeDistribution = distribution
+	qtw.globalRuntimeVersion++
+}
+
+// setChildrenOrder sets the order in which the childGroups receive the shared resource, the runtimeQuota
+// of all childGroups may change, then increase globalRuntimeVersion
+func (qtw *RuntimeQuotaCalculator) setChildrenOrder(childrenOrder []string) {
+	qtw.lock.Lock()
+	defer qtw.lock.Unlock()
+
+	if isSameOrder(qtw.childrenOrder, childrenOrder) {
+		return
+	}
+	qtw.childrenOrder = append([]string(nil), childrenOrder...)
+	qtw.globalRuntimeVersion++
+}
+
+// setSharingPolicies sets how each resource dimension is shared to the childGroups, the runtimeQuota
+// of all childGroups may change, then increase globalRuntimeVersion
+func (qtw *RuntimeQuotaCalculator) setSharingPolicies(sharingPolicies map[v1.ResourceName]extension.QuotaSharingPolicy) {
+	qtw.lock.Lock()
+	defer qtw.lock.Unlock()
+
+	if isSameSharingPolicies(qtw.sharingPolicies, sharingPolicies) {
+		return
+	}
+	qtw.sharingPolicies = copySharingPolicies(sharingPolicies)
+	qtw.globalRuntimeVersion++
+}
+
 // updateOneGroupRuntimeQuota update the quotaInfo's runtimeQuota as the quotaNode's runtime.
 func (qtw *RuntimeQuotaCalculator) updateOneGroupRuntimeQuota(quotaInfo *QuotaInfo) {
 	qtw.lock.Lock()
@@ -459,20 +634,83 @@ func (qtw *RuntimeQuotaCalculator) updateOneGroupRuntimeQuota(quotaInfo *QuotaIn
 		return
 	}
 
-	qtw.calculateRuntimeNoLock()
+	version := qtw.globalRuntimeVersion
+	if fullRuntimeRefreshEnabled() {
+		qtw.redistributeNoLock()
+	} else {
+		if qtw.calculatedVersion != qtw.globalRuntimeVersion {
+			qtw.redistributeNoLock()
+		}
+		version = qtw.groupRuntimeVersion[quotaInfo.Name]
+		if quotaInfo.RuntimeVersion == version {
+			return
+		}
+	}
 
+	oldRuntime := quotaInfo.CalculateInfo.Runtime.DeepCopy()
 	for resKey := range qtw.resourceKeys {
 		if exist, quotaNode := qtw.quotaTree[resKey].find(quotaInfo.Name); exist {
 			quotaInfo.CalculateInfo.Runtime[resKey] = createQuantity(quotaNode.runtimeQuota, resKey)
 		}
 	}
-	quotaInfo.RuntimeVersion = qtw.globalRuntimeVersion
+	quotaInfo.RuntimeVersion = version
+	if !quotav1.Equals(oldRuntime, quotaInfo.CalculateInfo.Runtime) {
+		quotaInfo.Revision++
+	}
 
 	if klog.V(5).Enabled() {
 		qtw.logQuotaInfoNoLock("UpdateOneGroupRuntimeQuota finish", quotaInfo)
 	}
 }
 
+// getGroupRuntimeVersion returns the version the runtimeQuota of the childGroup last changed at, the quotaTree is
+// redistributed first if any childGroup changed since the last redistribution. The childGroups whose runtimeQuota
+// doesn't change keep their version, so neither they nor their subtrees are refreshed.
+func (qtw *RuntimeQuotaCalculator) getGroupRuntimeVersion(quotaName string) int64 {
+	qtw.lock.Lock()
+	defer qtw.lock.Unlock()
+
+	if fullRuntimeRefreshEnabled() {
+		return qtw.globalRuntimeVersion
+	}
+	if qtw.calculatedVersion != qtw.globalRuntimeVersion {
+		qtw.redistributeNoLock()
+	}
+	return qtw.groupRuntimeVersion[quotaName]
+}
+
+// redistributeNoLock redistributes the totalResource to the childGroups, and stamps the childGroups whose
+// runtimeQuota changed with the globalRuntimeVersion.
+func (qtw *RuntimeQuotaCalculator) redistributeNoLock() {
+	qtw.calculateRuntimeNoLock()
+	qtw.calculatedVersion = qtw.globalRuntimeVersion
+
+	runtimes := make(quotaResMapType, len(qtw.groupRuntime))
+	for resKey := range qtw.resourceKeys {
+		for quotaName, quotaNode := range qtw.quotaTree[resKey].quotaNodes {
+			runtime, ok := runtimes[quotaName]
+			if !ok {
+				runtime = v1.ResourceList{}
+				runtimes[quotaName] = runtime
+			}
+			runtime[resKey] = createQuantity(quotaNode.runtimeQuota, resKey)
+		}
+	}
+	for quotaName, runtime := range runtimes {
+		if oldRuntime, ok := qtw.groupRuntime[quotaName]; ok && quotav1.Equals(oldRuntime, runtime) {
+			continue
+		}
+		qtw.groupRuntime[quotaName] = runtime
+		qtw.groupRuntimeVersion[quotaName] = qtw.globalRuntimeVersion
+	}
+}
+
+// fullRuntimeRefreshEnabled returns whether the runtime is recalculated for every stale read and all the childGroups
+// are refreshed whenever any of them changes, as before the incremental refresh.
+func fullRuntimeRefreshEnabled() bool {
+	return utilfeature.DefaultFeatureGate.Enabled(features.ElasticQuotaFullRuntimeRefresh)
+}
+
 func (qtw *RuntimeQuotaCalculator) getGroupRequestLimitNoLock(quotaName string) v1.ResourceList {
 	res, exist := qtw.groupReqLimit[quotaName]
 	if !exist {
@@ -490,10 +728,43 @@ func (qtw *RuntimeQuotaCalculator) getVersion() int64 {
 
 func (qtw *RuntimeQuotaCalculator) calculateRuntimeNoLock() {
 	//lock outside
+	if len(qtw.childrenOrder) == 0 && qtw.runtimeDistribution == extension.QuotaRuntimeDistributionDRF {
+		qtw.redistributionDRFNoLock()
+	}
 	for resKey := range qtw.resourceKeys {
 		totalResourcePerKey := *qtw.totalResource.Name(resKey, resource.DecimalSI)
-		qtw.quotaTree[resKey].redistribution(getQuantityValue(totalResourcePerKey, resKey))
+		totalValue := getQuantityValue(totalResourcePerKey, resKey)
+		qtw.quotaTree[resKey].equalShare = qtw.sharingPolicies[resKey] == extension.QuotaSharingPolicyEqual
+		if len(qtw.childrenOrder) > 0 {
+			qtw.quotaTree[resKey].redistributionByOrder(totalValue, qtw.childrenOrder)
+		} else if qtw.runtimeDistribution != extension.QuotaRuntimeDistributionDRF {
+			qtw.quotaTree[resKey].redistribution(totalValue)
+		}
+		if klog.V(RuntimeTraceVerbosity).Enabled() {
+			qtw.traceRedistributionNoLock(resKey, totalValue)
+		}
+	}
+}
+
+// traceRedistributionNoLock logs every child's share and the leftover of a resource dimension after redistribution.
+func (qtw *RuntimeQuotaCalculator) traceRedistributionNoLock(resKey v1.ResourceName, totalValue int64) {
+	quotaTree := qtw.quotaTree[resKey]
+	names := make([]string, 0, len(quotaTree.quotaNodes))
+	for name := range quotaTree.quotaNodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	leftover := totalValue
+	for _, name := range names {
+		node := quotaTree.quotaNodes[name]
+		leftover -= node.runtimeQuota
+		klog.InfoS("RuntimeQuota redistribution share", "treeName", qtw.treeName, "resource", resKey,
+			"quotaName", node.quotaName, "min", node.min, "guarantee", node.guarantee, "request", node.request,
+			"sharedWeight", node.sharedWeight, "allowLentResource", node.allowLentResource, "runtime", node.runtimeQuota)
 	}
+	klog.InfoS("RuntimeQuota redistribution finish", "treeName", qtw.treeName, "resource", resKey,
+		"total", totalValue, "children", len(names), "leftover", leftover, "version", qtw.globalRuntimeVersion)
 }
 
 func (qtw *RuntimeQuotaCalculator) logQuotaInfoNoLock(verb string, quotaInfo *QuotaInfo) {
@@ -533,6 +804,8 @@ func (qtw *RuntimeQuotaCalculator) deleteOneGroup(quotaInfo *QuotaInfo) {
 	}
 	delete(qtw.groupReqLimit, quotaInfo.Name)
 	delete(qtw.groupGuaranteed, quotaInfo.Name)
+	delete(qtw.groupRuntime, quotaInfo.Name)
+	delete(qtw.groupRuntimeVersion, quotaInfo.Name)
 
 	qtw.globalRuntimeVersion++
 
@@ -19,6 +19,7 @@ package core
 import (
 	"encoding/json"
 	"fmt"
+	"math/rand"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -266,6 +267,192 @@ func TestRuntimeQuotaCalculator_IterationAdjustQuota(t *testing.T) {
 	}
 }
 
+func TestRuntimeQuotaCalculator_DeterministicTieBreaking(t *testing.T) {
+	testCases := []struct {
+		name              string
+		totalResource     int64
+		names             []string
+		request           int64
+		expectedRuntimeMp map[string]int64
+	}{
+		{
+			name:          "one leftover unit goes to the first name",
+			totalResource: 100,
+			names:         []string{"quota-c", "quota-a", "quota-b"},
+			request:       50,
+			expectedRuntimeMp: map[string]int64{
+				"quota-a": 34,
+				"quota-b": 33,
+				"quota-c": 33,
+			},
+		},
+		{
+			name:          "leftover units go to the names in order",
+			totalResource: 11,
+			names:         []string{"quota-d", "quota-b", "quota-c", "quota-a"},
+			request:       10,
+			expectedRuntimeMp: map[string]int64{
+				"quota-a": 3,
+				"quota-b": 3,
+				"quota-c": 3,
+				"quota-d": 2,
+			},
+		},
+		{
+			name:          "the units fewer than the children",
+			totalResource: 2,
+			names:         []string{"quota-c", "quota-b", "quota-a"},
+			request:       10,
+			expectedRuntimeMp: map[string]int64{
+				"quota-a": 1,
+				"quota-b": 1,
+				"quota-c": 0,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for i := 0; i < 20; i++ {
+				names := append([]string{}, tc.names...)
+				rand.Shuffle(len(names), func(i, j int) { names[i], names[j] = names[j], names[i] })
+
+				qtw := NewRuntimeQuotaCalculator("testTreeName")
+				qtw.updateResourceKeys(map[corev1.ResourceName]struct{}{corev1.ResourceCPU: {}})
+				qtw.totalResource = corev1.ResourceList{
+					corev1.ResourceCPU: *resource.NewMilliQuantity(tc.totalResource, resource.DecimalSI),
+				}
+				for _, name := range names {
+					qtw.quotaTree[corev1.ResourceCPU].insert(name, 10, tc.request, 0, 0, true)
+				}
+				qtw.calculateRuntimeNoLock()
+
+				total := int64(0)
+				for name, expected := range tc.expectedRuntimeMp {
+					runtime := qtw.quotaTree[corev1.ResourceCPU].quotaNodes[name].runtimeQuota
+					assert.Equal(t, expected, runtime, "names: %v", names)
+					total += runtime
+				}
+				assert.Equal(t, tc.totalResource, total)
+			}
+		})
+	}
+}
+
+func TestRuntimeQuotaCalculator_ChildrenOrder(t *testing.T) {
+	testCases := []struct {
+		name              string
+		totalResource     int64
+		childrenOrder     []string
+		min               map[string]int64
+		expectedRuntimeMp map[string]int64
+	}{
+		{
+			name:          "no order shares by weight",
+			totalResource: 90,
+			expectedRuntimeMp: map[string]int64{
+				"quota-a": 30,
+				"quota-b": 30,
+				"quota-c": 30,
+			},
+		},
+		{
+			name:          "the earlier ordered children are satisfied first",
+			totalResource: 100,
+			childrenOrder: []string{"quota-c", "quota-a"},
+			expectedRuntimeMp: map[string]int64{
+				"quota-a": 40,
+				"quota-b": 0,
+				"quota-c": 60,
+			},
+		},
+		{
+			name:          "the unordered children share what's left by weight",
+			totalResource: 100,
+			childrenOrder: []string{"quota-c"},
+			expectedRuntimeMp: map[string]int64{
+				"quota-a": 20,
+				"quota-b": 20,
+				"quota-c": 60,
+			},
+		},
+		{
+			name:          "the min is kept before the order applies",
+			totalResource: 100,
+			childrenOrder: []string{"quota-c", "quota-a", "unknown"},
+			min:           map[string]int64{"quota-b": 30},
+			expectedRuntimeMp: map[string]int64{
+				"quota-a": 10,
+				"quota-b": 30,
+				"quota-c": 60,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			qtw := NewRuntimeQuotaCalculator("testTreeName")
+			qtw.updateResourceKeys(map[corev1.ResourceName]struct{}{corev1.ResourceCPU: {}})
+			qtw.totalResource = corev1.ResourceList{
+				corev1.ResourceCPU: *resource.NewMilliQuantity(tc.totalResource, resource.DecimalSI),
+			}
+			for _, name := range []string{"quota-a", "quota-b", "quota-c"} {
+				qtw.quotaTree[corev1.ResourceCPU].insert(name, 10, 60, tc.min[name], 0, true)
+			}
+			qtw.setChildrenOrder(tc.childrenOrder)
+			qtw.calculateRuntimeNoLock()
+
+			for name, expected := range tc.expectedRuntimeMp {
+				assert.Equal(t, expected, qtw.quotaTree[corev1.ResourceCPU].quotaNodes[name].runtimeQuota, name)
+			}
+		})
+	}
+}
+
+func TestRuntimeQuotaCalculator_SharingPolicies(t *testing.T) {
+	qtw := NewRuntimeQuotaCalculator("testTreeName")
+	qtw.updateResourceKeys(map[corev1.ResourceName]struct{}{corev1.ResourceCPU: {}, corev1.ResourceMemory: {}})
+	qtw.totalResource = corev1.ResourceList{
+		corev1.ResourceCPU:    *resource.NewMilliQuantity(80, resource.DecimalSI),
+		corev1.ResourceMemory: *resource.NewQuantity(80, resource.BinarySI),
+	}
+	for _, resKey := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		qtw.quotaTree[resKey].insert("quota-a", 10, 100, 0, 0, true)
+		qtw.quotaTree[resKey].insert("quota-b", 30, 100, 0, 0, true)
+		qtw.quotaTree[resKey].insert("quota-c", 0, 100, 0, 0, true)
+	}
+	getRuntime := func(resKey corev1.ResourceName, name string) int64 {
+		return qtw.quotaTree[resKey].quotaNodes[name].runtimeQuota
+	}
+
+	// both dimensions are shared by weight by default
+	qtw.calculateRuntimeNoLock()
+	assert.Equal(t, int64(20), getRuntime(corev1.ResourceCPU, "quota-a"))
+	assert.Equal(t, int64(60), getRuntime(corev1.ResourceCPU, "quota-b"))
+	assert.Equal(t, int64(20), getRuntime(corev1.ResourceMemory, "quota-a"))
+	assert.Equal(t, int64(60), getRuntime(corev1.ResourceMemory, "quota-b"))
+
+	// the memory is shared equally, the cpu is still shared by weight
+	version := qtw.getVersion()
+	qtw.setSharingPolicies(map[corev1.ResourceName]extension.QuotaSharingPolicy{
+		corev1.ResourceCPU:    extension.QuotaSharingPolicyWeighted,
+		corev1.ResourceMemory: extension.QuotaSharingPolicyEqual,
+	})
+	assert.Equal(t, version+1, qtw.getVersion())
+	qtw.calculateRuntimeNoLock()
+	assert.Equal(t, int64(20), getRuntime(corev1.ResourceCPU, "quota-a"))
+	assert.Equal(t, int64(60), getRuntime(corev1.ResourceCPU, "quota-b"))
+	assert.Equal(t, int64(40), getRuntime(corev1.ResourceMemory, "quota-a"))
+	assert.Equal(t, int64(40), getRuntime(corev1.ResourceMemory, "quota-b"))
+	// the quota without shared weight never shares the resource
+	assert.Equal(t, int64(0), getRuntime(corev1.ResourceMemory, "quota-c"))
+
+	// the same policies don't bump the version
+	qtw.setSharingPolicies(map[corev1.ResourceName]extension.QuotaSharingPolicy{
+		corev1.ResourceCPU:    extension.QuotaSharingPolicyWeighted,
+		corev1.ResourceMemory: extension.QuotaSharingPolicyEqual,
+	})
+	assert.Equal(t, version+1, qtw.getVersion())
+}
+
 func createQuotaInfoWithRes(name string, max, min corev1.ResourceList) *QuotaInfo {
 	quotaInfo := NewQuotaInfo(true, true, name, "")
 	quotaInfo.CalculateInfo.Max = max.DeepCopy()
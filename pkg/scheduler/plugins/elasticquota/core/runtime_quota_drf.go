@@ -0,0 +1,113 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"math"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// drfDemand is the demand of a childGroup beyond its min in the dominant resource fairness distribution.
+type drfDemand struct {
+	// extra is the request beyond the min of each resource dimension which the childGroup shares.
+	extra map[v1.ResourceName]int64
+	// dominantShare is the largest share of the totalResource among the extra.
+	dominantShare float64
+	// progress is the allocated fraction of the extra, in [0, 1].
+	progress float64
+	frozen   bool
+}
+
+// redistributionDRFNoLock distributes the totalResource to the childGroups by the dominant resource fairness.
+// Each childGroup gets its min first like the weighted distribution, then the rest is filled progressively,
+// keeping the dominant shares of the allocation beyond min equal among the childGroups which still request
+// more, until they are satisfied or any resource they request is exhausted.
+// The childGroup doesn't share the resource dimension whose sharedWeight is zero.
+func (qtw *RuntimeQuotaCalculator) redistributionDRFNoLock() {
+	remaining := make(map[v1.ResourceName]float64, len(qtw.resourceKeys))
+	demands := make(map[string]*drfDemand)
+	for resKey := range qtw.resourceKeys {
+		totalValue := getQuantityValue(*qtw.totalResource.Name(resKey, resource.DecimalSI), resKey)
+		toPartitionResource := totalValue
+		for quotaName, node := range qtw.quotaTree[resKey].quotaNodes {
+			needAdjust := node.assignBaseRuntime()
+			toPartitionResource -= node.runtimeQuota
+			if !needAdjust || node.sharedWeight <= 0 || totalValue <= 0 {
+				continue
+			}
+			demand := demands[quotaName]
+			if demand == nil {
+				demand = &drfDemand{extra: make(map[v1.ResourceName]int64)}
+				demands[quotaName] = demand
+			}
+			demand.extra[resKey] = node.request - node.runtimeQuota
+			demand.dominantShare = math.Max(demand.dominantShare, float64(demand.extra[resKey])/float64(totalValue))
+		}
+		remaining[resKey] = float64(toPartitionResource)
+	}
+
+	// the dominant share of every active childGroup grows by the same step in each round, so the childGroup
+	// consumes extra/dominantShare per step. Each round stops when a childGroup is satisfied or a resource is
+	// exhausted, which freezes at least one childGroup, so it takes at most len(demands) rounds.
+	for {
+		step := math.MaxFloat64
+		consumption := make(map[v1.ResourceName]float64)
+		for _, demand := range demands {
+			if demand.frozen {
+				continue
+			}
+			step = math.Min(step, (1-demand.progress)*demand.dominantShare)
+			for resKey, extra := range demand.extra {
+				consumption[resKey] += float64(extra) / demand.dominantShare
+			}
+		}
+		if len(consumption) == 0 {
+			break
+		}
+		for resKey, consumed := range consumption {
+			step = math.Min(step, math.Max(remaining[resKey], 0)/consumed)
+		}
+
+		for resKey, consumed := range consumption {
+			remaining[resKey] -= step * consumed
+		}
+		for _, demand := range demands {
+			if demand.frozen {
+				continue
+			}
+			demand.progress += step / demand.dominantShare
+			if demand.progress >= 1-1e-9 {
+				demand.progress, demand.frozen = 1, true
+				continue
+			}
+			for resKey := range demand.extra {
+				if remaining[resKey] < 0.5 {
+					demand.frozen = true
+				}
+			}
+		}
+	}
+
+	for quotaName, demand := range demands {
+		for resKey, extra := range demand.extra {
+			node := qtw.quotaTree[resKey].quotaNodes[quotaName]
+			node.runtimeQuota += int64(float64(extra) * demand.progress)
+		}
+	}
+}
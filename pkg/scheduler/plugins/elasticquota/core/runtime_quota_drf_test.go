@@ -0,0 +1,130 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+func TestRuntimeQuotaCalculator_DRF(t *testing.T) {
+	// the cluster has 9 cpus and 18 memory, quota-a is memory heavy (1 cpu : 4 memory) and
+	// quota-b is cpu heavy (3 cpu : 1 memory), both of them request more than the cluster.
+	totalResource := corev1.ResourceList{
+		corev1.ResourceCPU:    *resource.NewMilliQuantity(9000, resource.DecimalSI),
+		corev1.ResourceMemory: *resource.NewQuantity(18, resource.BinarySI),
+	}
+	type quotaNodeRequest struct {
+		name    string
+		min     map[corev1.ResourceName]int64
+		request map[corev1.ResourceName]int64
+	}
+	nodes := []quotaNodeRequest{
+		{
+			name:    "quota-a",
+			request: map[corev1.ResourceName]int64{corev1.ResourceCPU: 9000, corev1.ResourceMemory: 36},
+		},
+		{
+			name:    "quota-b",
+			request: map[corev1.ResourceName]int64{corev1.ResourceCPU: 27000, corev1.ResourceMemory: 9},
+		},
+	}
+	tests := []struct {
+		name              string
+		distribution      extension.QuotaRuntimeDistribution
+		nodes             []quotaNodeRequest
+		expectedRuntimeMp map[string]map[corev1.ResourceName]int64
+	}{
+		{
+			name:         "weighted distributes each dimension independently",
+			distribution: extension.QuotaRuntimeDistributionWeighted,
+			nodes:        nodes,
+			expectedRuntimeMp: map[string]map[corev1.ResourceName]int64{
+				"quota-a": {corev1.ResourceCPU: 4500, corev1.ResourceMemory: 9},
+				"quota-b": {corev1.ResourceCPU: 4500, corev1.ResourceMemory: 9},
+			},
+		},
+		{
+			name:         "drf equalizes the dominant shares",
+			distribution: extension.QuotaRuntimeDistributionDRF,
+			nodes:        nodes,
+			expectedRuntimeMp: map[string]map[corev1.ResourceName]int64{
+				"quota-a": {corev1.ResourceCPU: 3000, corev1.ResourceMemory: 12},
+				"quota-b": {corev1.ResourceCPU: 6000, corev1.ResourceMemory: 2},
+			},
+		},
+		{
+			name:         "drf distributes beyond min and stops when the request is satisfied",
+			distribution: extension.QuotaRuntimeDistributionDRF,
+			nodes: []quotaNodeRequest{
+				{
+					name:    "quota-a",
+					min:     map[corev1.ResourceName]int64{corev1.ResourceCPU: 1000, corev1.ResourceMemory: 2},
+					request: map[corev1.ResourceName]int64{corev1.ResourceCPU: 2000, corev1.ResourceMemory: 4},
+				},
+				{
+					name:    "quota-b",
+					request: map[corev1.ResourceName]int64{corev1.ResourceCPU: 27000, corev1.ResourceMemory: 9},
+				},
+			},
+			expectedRuntimeMp: map[string]map[corev1.ResourceName]int64{
+				"quota-a": {corev1.ResourceCPU: 2000, corev1.ResourceMemory: 4},
+				"quota-b": {corev1.ResourceCPU: 7000, corev1.ResourceMemory: 2},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qtw := NewRuntimeQuotaCalculator("testTreeName")
+			qtw.setRuntimeDistribution(tt.distribution)
+			resourceKey := map[corev1.ResourceName]struct{}{corev1.ResourceCPU: {}, corev1.ResourceMemory: {}}
+			qtw.updateResourceKeys(resourceKey)
+			qtw.totalResource = totalResource
+			for _, node := range tt.nodes {
+				for resKey := range resourceKey {
+					qtw.quotaTree[resKey].insert(node.name, 1, node.request[resKey], node.min[resKey], 0, true)
+				}
+			}
+			qtw.calculateRuntimeNoLock()
+			for node, rq := range tt.expectedRuntimeMp {
+				for resKey, q := range rq {
+					assert.Equal(t, q, qtw.quotaTree[resKey].quotaNodes[node].runtimeQuota, "%v %v", node, resKey)
+				}
+			}
+		})
+	}
+}
+
+func TestGroupQuotaManager_SetRuntimeDistribution(t *testing.T) {
+	gqm := NewGroupQuotaManagerForTest()
+	gqm.UpdateClusterTotalResource(corev1.ResourceList{
+		corev1.ResourceCPU:    *resource.NewMilliQuantity(9000, resource.DecimalSI),
+		corev1.ResourceMemory: *resource.NewQuantity(18, resource.BinarySI),
+	})
+	assert.Equal(t, extension.QuotaRuntimeDistributionWeighted, gqm.GetRuntimeDistribution())
+
+	gqm.SetRuntimeDistribution(extension.QuotaRuntimeDistributionDRF)
+	assert.Equal(t, extension.QuotaRuntimeDistributionDRF, gqm.GetRuntimeDistribution())
+	for _, calculator := range gqm.runtimeQuotaCalculatorMap {
+		assert.Equal(t, extension.QuotaRuntimeDistributionDRF, calculator.runtimeDistribution)
+	}
+}
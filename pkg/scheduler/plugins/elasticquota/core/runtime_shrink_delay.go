@@ -0,0 +1,80 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// runtimeShrinkState is the runtime seen by the admission of a quota while its calculated runtime shrinks.
+type runtimeShrinkState struct {
+	// runtime is the runtime last returned to the admission.
+	runtime v1.ResourceList
+	// shrinkSince is when the calculated runtime of each dimension dropped below runtime.
+	shrinkSince map[v1.ResourceName]time.Time
+}
+
+// SetRuntimeShrinkDelay sets how long the calculated runtime of a quota has to stay lower before RefreshRuntime
+// returns the lower value. Zero disables the delay.
+func (gqm *GroupQuotaManager) SetRuntimeShrinkDelay(delay time.Duration) {
+	gqm.hierarchyUpdateLock.Lock()
+	defer gqm.hierarchyUpdateLock.Unlock()
+
+	gqm.runtimeShrinkDelay = delay
+}
+
+// delayRuntimeShrinkNoLock returns the runtime for the admission of the quota. A dimension grows at once,
+// but only shrinks after it stays lower for runtimeShrinkDelay, and never exceeds the overcommitted max of the quota.
+// The quotaInfo must be locked.
+func (gqm *GroupQuotaManager) delayRuntimeShrinkNoLock(quotaInfo *QuotaInfo, runtime v1.ResourceList) v1.ResourceList {
+	if gqm.runtimeShrinkDelay <= 0 {
+		quotaInfo.runtimeShrinkState = nil
+		return runtime
+	}
+
+	state := quotaInfo.runtimeShrinkState
+	if state == nil {
+		state = &runtimeShrinkState{shrinkSince: map[v1.ResourceName]time.Time{}}
+		quotaInfo.runtimeShrinkState = state
+	}
+	now := gqm.clock.Now()
+	maxQuota := quotaInfo.getOvercommittedMaxNoLock()
+	delayed := make(v1.ResourceList, len(runtime))
+	shrinkSince := make(map[v1.ResourceName]time.Time, len(state.shrinkSince))
+	for resourceName, quantity := range runtime {
+		last, ok := state.runtime[resourceName]
+		if ok && quantity.Cmp(last) < 0 {
+			since, shrinking := state.shrinkSince[resourceName]
+			if !shrinking {
+				since = now
+			}
+			if now.Sub(since) < gqm.runtimeShrinkDelay {
+				quantity = last
+				shrinkSince[resourceName] = since
+			}
+		}
+		if max, ok := maxQuota[resourceName]; ok && quantity.Cmp(max) > 0 {
+			quantity = max
+		}
+		delayed[resourceName] = quantity.DeepCopy()
+	}
+	state.runtime = delayed
+	state.shrinkSince = shrinkSince
+	return delayed.DeepCopy()
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	fakeclock "k8s.io/utils/clock/testing"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+func TestGroupQuotaManager_RuntimeShrinkDelay(t *testing.T) {
+	gqm := NewGroupQuotaManagerForTest()
+	fakeClock := fakeclock.NewFakeClock(time.Now())
+	gqm.clock = fakeClock
+	gqm.SetRuntimeShrinkDelay(time.Minute)
+	gqm.UpdateClusterTotalResource(createResourceList(100, 100))
+	AddQuotaToManager2(gqm, "test1", extension.RootQuotaName, 100, 100, 0, 0, true, false)
+
+	setRequest := func(cpu, mem int64) {
+		quotaInfo := gqm.GetQuotaInfoByName("test1")
+		delta := createResourceList(cpu-quotaInfo.CalculateInfo.Request.Cpu().Value(),
+			mem-quotaInfo.CalculateInfo.Request.Memory().Value())
+		gqm.updateGroupDeltaRequestNoLock("test1", delta, delta, 0)
+	}
+
+	setRequest(80, 80)
+	assert.Equal(t, createResourceList(80, 80), gqm.RefreshRuntime("test1"))
+
+	// the shrinkage is delayed.
+	setRequest(30, 30)
+	assert.Equal(t, createResourceList(80, 80), gqm.RefreshRuntime("test1"))
+	fakeClock.Step(30 * time.Second)
+	assert.Equal(t, createResourceList(80, 80), gqm.RefreshRuntime("test1"))
+
+	// the growth takes effect at once and restarts the delay.
+	setRequest(90, 90)
+	assert.Equal(t, createResourceList(90, 90), gqm.RefreshRuntime("test1"))
+	setRequest(20, 20)
+	assert.Equal(t, createResourceList(90, 90), gqm.RefreshRuntime("test1"))
+	fakeClock.Step(30 * time.Second)
+	assert.Equal(t, createResourceList(90, 90), gqm.RefreshRuntime("test1"))
+	fakeClock.Step(30 * time.Second)
+	assert.Equal(t, createResourceList(20, 20), gqm.RefreshRuntime("test1"))
+
+	// the delayed runtime never exceeds the max.
+	setRequest(90, 90)
+	assert.Equal(t, createResourceList(90, 90), gqm.RefreshRuntime("test1"))
+	setRequest(20, 20)
+	AddQuotaToManager2(gqm, "test1", extension.RootQuotaName, 50, 50, 0, 0, true, false)
+	assert.Equal(t, createResourceList(50, 50), gqm.RefreshRuntime("test1"))
+
+	// disabling the delay returns the calculated runtime.
+	gqm.SetRuntimeShrinkDelay(0)
+	assert.Equal(t, createResourceList(20, 20), gqm.RefreshRuntime("test1"))
+}
@@ -32,7 +32,8 @@ import (
 // nodes will be proportionally reduced. In order to calculate the scaling of the min quota, we will count the sum of
 // the min quota of the child nodes of each node in advance. The quota of the child nodes that allow scaling is stored
 // in enableScaleSubsSumMinQuotaMap, and the quota of child nodes that do not allow scaling is stored in
-// disableScaleSubsSumMinQuotaMap
+// disableScaleSubsSumMinQuotaMap. The enableScale children are further divided by their minPriority, the children with
+// higher minPriority keep their minQuota first, and the children with the same minPriority share the rest proportionally.
 type ScaleMinQuotaManager struct {
 	lock sync.RWMutex
 	// enableScaleSubsSumMinQuotaMap key: quotaName, val: sum of its enableScale children's minQuota
@@ -43,6 +44,10 @@ type ScaleMinQuotaManager struct {
 	// totalRes, just return the originalMinQuota.
 	originalMinQuotaMap         map[string]v1.ResourceList
 	quotaEnableMinQuotaScaleMap map[string]bool
+	// quotaMinPriorityMap key: quotaName, val: the priority to keep its minQuota
+	quotaMinPriorityMap map[string]int32
+	// parentQuotaMap key: quotaName, val: its parent quotaName
+	parentQuotaMap map[string]string
 }
 
 func NewScaleMinQuotaManager() *ScaleMinQuotaManager {
@@ -51,6 +56,8 @@ func NewScaleMinQuotaManager() *ScaleMinQuotaManager {
 		enableScaleSubsSumMinQuotaMap:  make(map[string]v1.ResourceList),
 		disableScaleSubsSumMinQuotaMap: make(map[string]v1.ResourceList),
 		quotaEnableMinQuotaScaleMap:    make(map[string]bool),
+		quotaMinPriorityMap:            make(map[string]int32),
+		parentQuotaMap:                 make(map[string]string),
 	}
 	return info
 }
@@ -94,6 +101,14 @@ func (s *ScaleMinQuotaManager) update(parQuotaName, subQuotaName string, subMinQ
 	// step3: record the newMinQuota
 	s.originalMinQuotaMap[subQuotaName] = subMinQuota
 	s.quotaEnableMinQuotaScaleMap[subQuotaName] = enableScaleMinQuota
+	s.parentQuotaMap[subQuotaName] = parQuotaName
+}
+
+func (s *ScaleMinQuotaManager) setMinPriority(subQuotaName string, minPriority int32) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.quotaMinPriorityMap[subQuotaName] = minPriority
 }
 
 func (s *ScaleMinQuotaManager) remove(parQuotaName, subQuotaName string) {
@@ -114,6 +129,8 @@ func (s *ScaleMinQuotaManager) remove(parQuotaName, subQuotaName string) {
 
 	delete(s.originalMinQuotaMap, subQuotaName)
 	delete(s.quotaEnableMinQuotaScaleMap, subQuotaName)
+	delete(s.quotaMinPriorityMap, subQuotaName)
+	delete(s.parentQuotaMap, subQuotaName)
 
 	if klog.V(5).Enabled() {
 		klog.Infof("ScaleMinQuotaManager remove, parQuota: %v, subQuota: %v  ", parQuotaName, subQuotaName)
@@ -149,27 +166,32 @@ func (s *ScaleMinQuotaManager) getScaledMinQuota(newTotalRes v1.ResourceList, pa
 		return true, s.originalMinQuotaMap[subQuotaName].DeepCopy()
 	}
 
+	// the enableScale children with higher minPriority keep their minQuota before the ones in the same tier
+	higherPriorityTotal, samePriorityTotal := s.getSubsSumMinQuotaByPriorityNoLock(parQuotaName, subQuotaName)
+
 	// ensure the disableScale children's minQuota first
 	newMinQuota := s.originalMinQuotaMap[subQuotaName].DeepCopy()
 	for _, resourceDimension := range needScaleDimensions {
 		needScaleTotal := *newTotalRes.Name(resourceDimension, resource.DecimalSI)
 		disableTotal := s.disableScaleSubsSumMinQuotaMap[parQuotaName]
 		needScaleTotal.Sub(*disableTotal.Name(resourceDimension, resource.DecimalSI))
+		needScaleTotal.Sub(*higherPriorityTotal.Name(resourceDimension, resource.DecimalSI))
 
+		samePriorityTotalValue := samePriorityTotal.Name(resourceDimension, resource.DecimalSI)
 		if needScaleTotal.Value() <= 0 {
 			newMinQuota[resourceDimension] = *resource.NewQuantity(0, resource.DecimalSI)
+		} else if needScaleTotal.Cmp(*samePriorityTotalValue) >= 0 {
+			// the left minQuota is enough for the children in the same tier, keep the original minQuota.
+			continue
 		} else {
 			// if still has minQuota left, enableScaleMinQuota children partition it according to their minQuotaValue.
 			originalMinQuota := s.originalMinQuotaMap[subQuotaName]
 			originalMinQuotaValue := originalMinQuota.Name(resourceDimension, resource.DecimalSI)
 
-			enableTotal := s.enableScaleSubsSumMinQuotaMap[parQuotaName]
-			enableTotalValue := enableTotal.Name(resourceDimension, resource.DecimalSI)
-
 			newMinQuotaValue := int64(0)
-			if enableTotalValue.Value() > 0 {
+			if samePriorityTotalValue.Value() > 0 {
 				newMinQuotaValue = int64(float64(getQuantityValue(needScaleTotal, resourceDimension)) *
-					float64(getQuantityValue(*originalMinQuotaValue, resourceDimension)) / float64(getQuantityValue(*enableTotalValue, resourceDimension)))
+					float64(getQuantityValue(*originalMinQuotaValue, resourceDimension)) / float64(getQuantityValue(*samePriorityTotalValue, resourceDimension)))
 			}
 
 			newMinQuota[resourceDimension] = createQuantity(newMinQuotaValue, resourceDimension)
@@ -182,3 +204,22 @@ func (s *ScaleMinQuotaManager) getScaledMinQuota(newTotalRes v1.ResourceList, pa
 	}
 	return true, newMinQuota
 }
+
+// getSubsSumMinQuotaByPriorityNoLock returns the sum of the enableScale children's minQuota whose minPriority is
+// higher than the subQuota's, and the sum of those whose minPriority equals to the subQuota's.
+func (s *ScaleMinQuotaManager) getSubsSumMinQuotaByPriorityNoLock(parQuotaName, subQuotaName string) (v1.ResourceList, v1.ResourceList) {
+	higherPriorityTotal, samePriorityTotal := v1.ResourceList{}, v1.ResourceList{}
+	subMinPriority := s.quotaMinPriorityMap[subQuotaName]
+	for quotaName, parentName := range s.parentQuotaMap {
+		if parentName != parQuotaName || !s.quotaEnableMinQuotaScaleMap[quotaName] {
+			continue
+		}
+		minPriority := s.quotaMinPriorityMap[quotaName]
+		if minPriority > subMinPriority {
+			higherPriorityTotal = quotav1.Add(higherPriorityTotal, s.originalMinQuotaMap[quotaName])
+		} else if minPriority == subMinPriority {
+			samePriorityTotal = quotav1.Add(samePriorityTotal, s.originalMinQuotaMap[quotaName])
+		}
+	}
+	return higherPriorityTotal, samePriorityTotal
+}
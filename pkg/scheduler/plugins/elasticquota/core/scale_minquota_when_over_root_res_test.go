@@ -19,6 +19,7 @@ package core
 import (
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/api/core/v1"
 	quotav1 "k8s.io/apiserver/pkg/quota/v1"
 
@@ -302,3 +303,59 @@ func TestScaleMinQuotaWhenOverRootResInfo_Remove(t *testing.T) {
 		}
 	}
 }
+
+func TestScaleMinQuotaManager_GetScaledMinQuotaByPriority(t *testing.T) {
+	info := NewScaleMinQuotaManager()
+	info.update("100", "1", createResourceList(50, 50), true)
+	info.setMinPriority("1", 10)
+	info.update("100", "2", createResourceList(50, 50), true)
+	info.update("100", "3", createResourceList(50, 50), true)
+
+	tests := []struct {
+		name          string
+		totalResource v1.ResourceList
+		expected      map[string]v1.ResourceList
+	}{
+		{
+			name:          "enough for all mins",
+			totalResource: createResourceList(200, 200),
+			expected: map[string]v1.ResourceList{
+				"1": createResourceList(50, 50),
+				"2": createResourceList(50, 50),
+				"3": createResourceList(50, 50),
+			},
+		},
+		{
+			name:          "higher priority keeps its min, the same tier shares the rest",
+			totalResource: createResourceList(100, 100),
+			expected: map[string]v1.ResourceList{
+				"1": createResourceList(50, 50),
+				"2": createResourceList(25, 25),
+				"3": createResourceList(25, 25),
+			},
+		},
+		{
+			name:          "only enough for part of the higher priority min",
+			totalResource: createResourceList(40, 40),
+			expected: map[string]v1.ResourceList{
+				"1": createResourceList(40, 40),
+				"2": createResourceList(0, 0),
+				"3": createResourceList(0, 0),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for quotaName, expected := range tt.expected {
+				needScale, newMinQuota := info.getScaledMinQuota(tt.totalResource, "100", quotaName)
+				assert.True(t, needScale)
+				assert.True(t, quotav1.Equals(expected, newMinQuota), "quota %v, expected %v, got %v", quotaName, expected, newMinQuota)
+			}
+		})
+	}
+
+	info.remove("100", "1")
+	needScale, newMinQuota := info.getScaledMinQuota(createResourceList(50, 50), "100", "2")
+	assert.True(t, needScale)
+	assert.True(t, quotav1.Equals(createResourceList(25, 25), newMinQuota))
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	quotav1 "k8s.io/apiserver/pkg/quota/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	"github.com/koordinator-sh/koordinator/pkg/util"
+)
+
+// SetLenderTrees sets the ids of the quota trees which lend their idle capacity to the tree, in the borrowing order.
+func (gqm *GroupQuotaManager) SetLenderTrees(treeIDs []string) {
+	gqm.hierarchyUpdateLock.Lock()
+	defer gqm.hierarchyUpdateLock.Unlock()
+
+	gqm.lenderTrees = append([]string(nil), treeIDs...)
+}
+
+func (gqm *GroupQuotaManager) GetLenderTrees() []string {
+	gqm.hierarchyUpdateLock.RLock()
+	defer gqm.hierarchyUpdateLock.RUnlock()
+
+	return append([]string(nil), gqm.lenderTrees...)
+}
+
+// GetTreeBorrowingState compares the own capacity of the tree, i.e. the total resource except the used of
+// SystemQuotaGroup and DefaultQuotaGroup regardless of what's borrowed or lent, with the demand of the top-level
+// quotas. The demand of a quota is its request limited by the max, and at least its min if the min can't be lent.
+// It returns the idle capacity the tree can lend and the shortage the tree wants to borrow.
+func (gqm *GroupQuotaManager) GetTreeBorrowingState() (idle, shortage v1.ResourceList) {
+	gqm.hierarchyUpdateLock.RLock()
+	defer gqm.hierarchyUpdateLock.RUnlock()
+
+	own := quotav1.Subtract(quotav1.Add(gqm.totalResourceExceptSystemAndDefaultUsed, gqm.lentResource), gqm.borrowedResource)
+	demand := v1.ResourceList{}
+	for quotaName := range gqm.quotaTopoNodeMap[extension.RootQuotaName].getChildGroupQuotaInfos() {
+		quotaInfo := gqm.quotaInfoMap[quotaName]
+		if quotaInfo == nil {
+			continue
+		}
+		quotaInfo.lock.RLock()
+		quotaDemand := quotaInfo.getLimitRequestNoLock()
+		if gqm.isMinRuntimeGuaranteedNoLock(quotaInfo) {
+			quotaDemand = quotav1.Max(quotaDemand, quotaInfo.CalculateInfo.Min)
+		}
+		quotaInfo.lock.RUnlock()
+		demand = quotav1.Add(demand, quotaDemand)
+	}
+
+	idle = clampNegativeToZero(quotav1.Subtract(own, demand))
+	shortage = clampNegativeToZero(quotav1.Subtract(demand, own))
+	return idle, shortage
+}
+
+// SetBorrowedAndLentResource sets the resource the tree borrows from its lender trees and lends to its borrower trees.
+// The borrowed resource is added to and the lent resource is subtracted from the total resource of the tree, so
+// the runtime of the quotas reflects the capacity really available to the tree without counting it twice.
+func (gqm *GroupQuotaManager) SetBorrowedAndLentResource(borrowed, lent v1.ResourceList) {
+	gqm.hierarchyUpdateLock.Lock()
+	defer gqm.hierarchyUpdateLock.Unlock()
+
+	if quotav1.Equals(gqm.borrowedResource, borrowed) && quotav1.Equals(gqm.lentResource, lent) {
+		return
+	}
+	gqm.borrowedResource = borrowed.DeepCopy()
+	gqm.lentResource = lent.DeepCopy()
+	gqm.updateClusterTotalResourceNoLock(nil)
+	if klog.V(4).Enabled() {
+		klog.Infof("SetBorrowedAndLentResource tree: %v, borrowed: %v, lent: %v", gqm.treeID,
+			util.DumpJSON(gqm.borrowedResource), util.DumpJSON(gqm.lentResource))
+	}
+}
+
+func (gqm *GroupQuotaManager) GetBorrowedResource() v1.ResourceList {
+	gqm.hierarchyUpdateLock.RLock()
+	defer gqm.hierarchyUpdateLock.RUnlock()
+
+	return gqm.borrowedResource.DeepCopy()
+}
+
+func (gqm *GroupQuotaManager) GetLentResource() v1.ResourceList {
+	gqm.hierarchyUpdateLock.RLock()
+	defer gqm.hierarchyUpdateLock.RUnlock()
+
+	return gqm.lentResource.DeepCopy()
+}
+
+func clampNegativeToZero(resourceList v1.ResourceList) v1.ResourceList {
+	for resourceName, quantity := range resourceList {
+		if quantity.Sign() < 0 {
+			resourceList[resourceName] = *resource.NewQuantity(0, quantity.Format)
+		}
+	}
+	return resourceList
+}
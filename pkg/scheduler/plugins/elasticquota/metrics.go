@@ -17,8 +17,12 @@ limitations under the License.
 package elasticquota
 
 import (
+	"strconv"
+	"time"
+
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/component-base/metrics"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
 	schedulermetrics "k8s.io/kubernetes/pkg/scheduler/metrics"
 
 	koordschedulermetrics "github.com/koordinator-sh/koordinator/pkg/scheduler/metrics"
@@ -51,6 +55,70 @@ var (
 			Buckets:   metrics.ExponentialBuckets(0.001, 2, 15),
 		},
 	)
+
+	ElasticQuotaAdmissionCounter = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem: schedulermetrics.SchedulerSubsystem,
+			Name:      "elastic_quota_admission_total",
+			Help:      "Number of pods admitted or rejected by ElasticQuota, split by the preemptible classification of the pods",
+		},
+		[]string{"name", "tree", "preemptible", "result"},
+	)
+
+	ElasticQuotaPreFilterLatency = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Subsystem: schedulermetrics.SchedulerSubsystem,
+			Name:      "elastic_quota_prefilter_duration_seconds",
+			Help:      "ElasticQuota PreFilter latency in seconds, split by the status code",
+			Buckets:   metrics.ExponentialBuckets(0.00001, 2, 24),
+		},
+		[]string{"code"},
+	)
+
+	ElasticQuotaRuntimeMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Subsystem: schedulermetrics.SchedulerSubsystem,
+			Name:      "elastic_quota_runtime",
+			Help:      "Runtime of ElasticQuota refreshed in scheduling",
+		},
+		[]string{"name", "resource", "tree"},
+	)
+
+	ElasticQuotaUsedMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Subsystem: schedulermetrics.SchedulerSubsystem,
+			Name:      "elastic_quota_used",
+			Help:      "Used of ElasticQuota refreshed in scheduling",
+		},
+		[]string{"name", "resource", "tree"},
+	)
+
+	ElasticQuotaRequestMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Subsystem: schedulermetrics.SchedulerSubsystem,
+			Name:      "elastic_quota_request",
+			Help:      "Request of ElasticQuota refreshed in scheduling",
+		},
+		[]string{"name", "resource", "tree"},
+	)
+
+	ElasticQuotaMinMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Subsystem: schedulermetrics.SchedulerSubsystem,
+			Name:      "elastic_quota_min",
+			Help:      "Min of ElasticQuota refreshed in scheduling",
+		},
+		[]string{"name", "resource", "tree"},
+	)
+
+	ElasticQuotaMaxMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Subsystem: schedulermetrics.SchedulerSubsystem,
+			Name:      "elastic_quota_max",
+			Help:      "Max of ElasticQuota refreshed in scheduling",
+		},
+		[]string{"name", "resource", "tree"},
+	)
 )
 
 func init() {
@@ -58,6 +126,13 @@ func init() {
 		ElasticQuotaSpecMetric,
 		ElasticQuotaStatusMetric,
 		UpdateElasticQuotaStatusLatency,
+		ElasticQuotaAdmissionCounter,
+		ElasticQuotaPreFilterLatency,
+		ElasticQuotaRuntimeMetric,
+		ElasticQuotaUsedMetric,
+		ElasticQuotaRequestMetric,
+		ElasticQuotaMinMetric,
+		ElasticQuotaMaxMetric,
 	)
 }
 
@@ -78,3 +153,23 @@ func recordResourceMetric(gaugeVec *metrics.GaugeVec, value int64, resourceName,
 
 	gaugeVec.With(labels).Set(float64(value))
 }
+
+const (
+	admissionResultAdmitted = "admitted"
+	admissionResultRejected = "rejected"
+)
+
+// RecordElasticQuotaAdmission counts the admission result of the pod in the quota by its preemptible classification.
+func RecordElasticQuotaAdmission(quotaName, treeID string, nonPreemptible, admitted bool) {
+	result := admissionResultRejected
+	if admitted {
+		result = admissionResultAdmitted
+	}
+	preemptible := strconv.FormatBool(!nonPreemptible)
+	ElasticQuotaAdmissionCounter.WithLabelValues(quotaName, treeID, preemptible, result).Inc()
+}
+
+// RecordElasticQuotaPreFilterLatency observes the latency of one PreFilter call by the code of its status.
+func RecordElasticQuotaPreFilterLatency(status *framework.Status, latency time.Duration) {
+	ElasticQuotaPreFilterLatency.WithLabelValues(status.Code().String()).Observe(latency.Seconds())
+}
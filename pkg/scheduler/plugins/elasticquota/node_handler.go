@@ -18,6 +18,7 @@ package elasticquota
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	quotav1 "k8s.io/apiserver/pkg/quota/v1"
 	"k8s.io/klog/v2"
 )
 
@@ -32,6 +33,7 @@ func (g *Plugin) OnNodeAdd(obj interface{}) {
 	}
 
 	g.groupQuotaManager.OnNodeAdd(node)
+	g.syncTreeBorrowing()
 }
 
 func (g *Plugin) OnNodeUpdate(oldObj, newObj interface{}) {
@@ -48,6 +50,9 @@ func (g *Plugin) OnNodeUpdate(oldObj, newObj interface{}) {
 	}
 
 	g.groupQuotaManager.OnNodeUpdate(oldNode, newNode)
+	if !quotav1.Equals(oldNode.Status.Allocatable, newNode.Status.Allocatable) {
+		g.syncTreeBorrowing()
+	}
 }
 
 func (g *Plugin) OnNodeDelete(obj interface{}) {
@@ -58,4 +63,5 @@ func (g *Plugin) OnNodeDelete(obj interface{}) {
 	}
 
 	g.groupQuotaManager.OnNodeDelete(node)
+	g.syncTreeBorrowing()
 }
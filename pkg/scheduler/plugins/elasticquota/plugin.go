@@ -20,12 +20,17 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
 	quotav1 "k8s.io/apiserver/pkg/quota/v1"
+	k8sfeature "k8s.io/apiserver/pkg/util/feature"
+	appslisters "k8s.io/client-go/listers/apps/v1"
 	v1 "k8s.io/client-go/listers/core/v1"
 	policylisters "k8s.io/client-go/listers/policy/v1"
 	"k8s.io/client-go/tools/cache"
@@ -33,6 +38,7 @@ import (
 	"k8s.io/kubernetes/pkg/scheduler/framework"
 	"k8s.io/kubernetes/pkg/scheduler/framework/preemption"
 	"k8s.io/kubernetes/pkg/scheduler/metrics"
+	"k8s.io/utils/clock"
 
 	"github.com/koordinator-sh/koordinator/apis/extension"
 	"github.com/koordinator-sh/koordinator/apis/thirdparty/scheduler-plugins/pkg/apis/scheduling"
@@ -40,6 +46,7 @@ import (
 	"github.com/koordinator-sh/koordinator/apis/thirdparty/scheduler-plugins/pkg/generated/clientset/versioned"
 	"github.com/koordinator-sh/koordinator/apis/thirdparty/scheduler-plugins/pkg/generated/informers/externalversions"
 	"github.com/koordinator-sh/koordinator/apis/thirdparty/scheduler-plugins/pkg/generated/listers/scheduling/v1alpha1"
+	"github.com/koordinator-sh/koordinator/pkg/features"
 	"github.com/koordinator-sh/koordinator/pkg/scheduler/apis/config"
 	"github.com/koordinator-sh/koordinator/pkg/scheduler/apis/config/validation"
 	"github.com/koordinator-sh/koordinator/pkg/scheduler/frameworkext"
@@ -55,20 +62,26 @@ const (
 )
 
 type PostFilterState struct {
-	skip               bool
-	quotaInfo          *core.QuotaInfo
+	skip      bool
+	quotaInfo *core.QuotaInfo
+	// revision is the revision of the quotaInfo when the snapshot was taken
+	revision           int64
 	used               corev1.ResourceList
 	nonPreemptibleUsed corev1.ResourceList
 	usedLimit          corev1.ResourceList
+	// antiAffinityQuotas are the quotas whose pods the pod should avoid
+	antiAffinityQuotas sets.String
 }
 
 func (p *PostFilterState) Clone() framework.StateData {
 	return &PostFilterState{
 		skip:               p.skip,
 		quotaInfo:          p.quotaInfo,
+		revision:           p.revision,
 		used:               p.used.DeepCopy(),
 		nonPreemptibleUsed: p.nonPreemptibleUsed.DeepCopy(),
 		usedLimit:          p.usedLimit.DeepCopy(),
+		antiAffinityQuotas: p.antiAffinityQuotas,
 	}
 }
 
@@ -81,7 +94,65 @@ type Plugin struct {
 	podLister         v1.PodLister
 	pdbLister         policylisters.PodDisruptionBudgetLister
 	nodeLister        v1.NodeLister
+	replicaSetLister  appslisters.ReplicaSetLister
 	groupQuotaManager *core.GroupQuotaManager
+	clock             clock.Clock
+	// resourceClaimClassGetter resolves the resource classes of the pods' resource claims for every quota manager
+	resourceClaimClassGetter core.ResourceClaimClassGetter
+	// bypassNamespaces are the namespaces whose pods bypass the quota enforcement
+	bypassNamespaces sets.String
+
+	entitlementLock sync.RWMutex
+	// entitlementSource caps the max of quotas by their entitlements
+	entitlementSource EntitlementSource
+	// costAccountant accumulates the resource-hours of quotas
+	costAccountant *quotaCostAccountant
+	// usageCollector records the runtime, used and request of quotas as gauges
+	usageCollector *quotaUsageCollector
+
+	quotaWarmUpLock sync.RWMutex
+	// quotaWarmUpDeadline stores the end of the warm-up of the newly created quotas
+	quotaWarmUpDeadline map[string]time.Time
+
+	podHandoffLock sync.Mutex
+	// podHandoffs keep the quota used of the deleted pods for their replacements, the key is the workload uid
+	podHandoffs map[types.UID]*podHandoff
+
+	terminatingQuotaLock sync.RWMutex
+	// terminatingQuotas are the draining quotas which have a deletion timestamp
+	terminatingQuotas sets.String
+
+	gangMemberLock sync.RWMutex
+	// gangMembers are the pods of the gangs, the key is the gang id and the pod uid
+	gangMembers map[string]map[types.UID]*corev1.Pod
+
+	admissionTokenLock sync.Mutex
+	// admissionTokens hold the quota admitted in PreFilter until the pods are reserved, the key is the pod uid
+	admissionTokens map[types.UID]*admissionToken
+
+	auditSinkLock sync.RWMutex
+	// auditSink receives the admit, reject and preempt decisions
+	auditSink AuditSink
+
+	quotaExceededEventLock sync.Mutex
+	// quotaExceededEvents store when the QuotaExceeded events were recorded, the key is the pod uid and the quota name
+	quotaExceededEvents map[types.UID]map[string]time.Time
+
+	quotaIdleLock sync.Mutex
+	// quotaIdleSince stores since when the quotas with idle min reclamation have no request
+	quotaIdleSince map[string]time.Time
+	// quotaMinReclaimed are the idle quotas whose min is reclaimed
+	quotaMinReclaimed sets.String
+
+	// treeBorrowingLock serializes the redistribution of the idle capacity between the quota trees
+	treeBorrowingLock sync.Mutex
+	// treeBorrowingActive is set if any tree declared lenders at the last redistribution
+	treeBorrowingActive bool
+
+	// admissionCount counts the admission decisions for the sampled logging
+	admissionCount atomic.Int64
+	// quotaReconciled is set once the quotas of the managers are reconciled against the live ElasticQuotas
+	quotaReconciled atomic.Bool
 
 	quotaManagerLock sync.RWMutex
 	// groupQuotaManagersForQuotaTree store the GroupQuotaManager of all quota trees. The key is the quota tree id
@@ -96,6 +167,7 @@ type Plugin struct {
 var (
 	_ framework.EnqueueExtensions = &Plugin{}
 	_ framework.PreFilterPlugin   = &Plugin{}
+	_ framework.FilterPlugin      = &Plugin{}
 	_ framework.PostFilterPlugin  = &Plugin{}
 	_ framework.ReservePlugin     = &Plugin{}
 )
@@ -145,11 +217,37 @@ func New(args runtime.Object, handle framework.Handle) (framework.Plugin, error)
 		quotaLister:                    elasticQuotaInformer.Lister(),
 		pdbLister:                      getPDBLister(handle),
 		nodeLister:                     handle.SharedInformerFactory().Core().V1().Nodes().Lister(),
+		replicaSetLister:               handle.SharedInformerFactory().Apps().V1().ReplicaSets().Lister(),
 		groupQuotaManagersForQuotaTree: make(map[string]*core.GroupQuotaManager),
 		quotaToTreeMap:                 make(map[string]string),
+		quotaWarmUpDeadline:            make(map[string]time.Time),
+		podHandoffs:                    make(map[types.UID]*podHandoff),
+		terminatingQuotas:              sets.NewString(),
+		admissionTokens:                make(map[types.UID]*admissionToken),
+		gangMembers:                    make(map[string]map[types.UID]*corev1.Pod),
+		quotaExceededEvents:            make(map[types.UID]map[string]time.Time),
+		auditSink:                      noopAuditSink{},
+		quotaIdleSince:                 make(map[string]time.Time),
+		quotaMinReclaimed:              sets.NewString(),
+		clock:                          clock.RealClock{},
+		bypassNamespaces:               sets.NewString(pluginArgs.BypassNamespaces...),
+	}
+	if k8sfeature.DefaultFeatureGate.Enabled(features.ElasticQuotaResourceClaims) {
+		resourceInformers := handle.SharedInformerFactory().Resource().V1alpha2()
+		// the claims are resolved once when the pods are added, so the claim informers are synced before the pods
+		frameworkexthelper.ForceSyncFromInformer(context.TODO().Done(), handle.SharedInformerFactory(),
+			resourceInformers.ResourceClaims().Informer(), cache.ResourceEventHandlerFuncs{})
+		frameworkexthelper.ForceSyncFromInformer(context.TODO().Done(), handle.SharedInformerFactory(),
+			resourceInformers.ResourceClaimTemplates().Informer(), cache.ResourceEventHandlerFuncs{})
+		elasticQuota.resourceClaimClassGetter = newResourceClaimClassGetter(resourceInformers.ResourceClaims().Lister(),
+			resourceInformers.ResourceClaimTemplates().Lister())
 	}
 	elasticQuota.groupQuotaManager = core.NewGroupQuotaManager("", pluginArgs.SystemQuotaGroupMax,
 		pluginArgs.DefaultQuotaGroupMax)
+	elasticQuota.groupQuotaManager.SetResourceClaimClassGetter(elasticQuota.resourceClaimClassGetter)
+	elasticQuota.groupQuotaManager.SetDefaultNonPreemptibleQuotas(pluginArgs.DefaultNonPreemptibleQuotas)
+	elasticQuota.groupQuotaManager.SetGuaranteeMinRuntime(pluginArgs.GuaranteeMinRuntime)
+	elasticQuota.groupQuotaManager.SetRuntimeShrinkDelay(pluginArgs.RuntimeShrinkDelay.Duration)
 	err := elasticQuota.groupQuotaManager.InitHookPlugins(pluginArgs)
 	if err != nil {
 		return nil, err
@@ -157,6 +255,8 @@ func New(args runtime.Object, handle framework.Handle) (framework.Plugin, error)
 
 	elasticQuota.quotaToTreeMap[extension.DefaultQuotaName] = ""
 	elasticQuota.quotaToTreeMap[extension.SystemQuotaName] = ""
+	elasticQuota.costAccountant = newQuotaCostAccountant(elasticQuota.clock)
+	elasticQuota.usageCollector = newQuotaUsageCollector(elasticQuota.clock)
 
 	ctx := context.TODO()
 
@@ -203,7 +303,12 @@ func (g *Plugin) Start() {
 func (g *Plugin) NewControllers() ([]frameworkext.Controller, error) {
 	quotaOverUsedRevokeController := NewQuotaOverUsedRevokeController(g)
 	elasticQuotaController := NewElasticQuotaController(g)
-	return []frameworkext.Controller{g, quotaOverUsedRevokeController, elasticQuotaController}, nil
+	quotaMinScheduleController := NewQuotaMinScheduleController(g)
+	quotaIdleMinReclaimController := NewQuotaIdleMinReclaimController(g)
+	quotaEntitlementController := NewQuotaEntitlementController(g)
+	quotaCostController := NewQuotaCostController(g)
+	return []frameworkext.Controller{g, quotaOverUsedRevokeController, elasticQuotaController,
+		quotaMinScheduleController, quotaIdleMinReclaimController, quotaEntitlementController, quotaCostController}, nil
 }
 
 func (g *Plugin) Name() string {
@@ -215,63 +320,58 @@ func (g *Plugin) EventsToRegister() []framework.ClusterEventWithHint {
 	// https://git.k8s.io/kubernetes/pkg/scheduler/eventhandlers.go#L403-L410
 	eqGVK := fmt.Sprintf("elasticquotas.v1alpha1.%v", scheduling.GroupName)
 	return []framework.ClusterEventWithHint{
-		{Event: framework.ClusterEvent{Resource: framework.Pod, ActionType: framework.Delete}},
+		{
+			// the deleted pod frees the used of its quota, which may be shared to the sibling quotas
+			Event:          framework.ClusterEvent{Resource: framework.Pod, ActionType: framework.Delete},
+			QueueingHintFn: g.isSchedulableAfterPodDeletion,
+		},
 		{Event: framework.ClusterEvent{Resource: framework.GVK(eqGVK), ActionType: framework.All}},
 	}
 }
 
 func (g *Plugin) PreFilter(ctx context.Context, cycleState *framework.CycleState, pod *corev1.Pod) (*framework.PreFilterResult, *framework.Status) {
-	quotaName, treeID := g.getPodAssociateQuotaNameAndTreeID(pod)
-	if quotaName == "" {
-		g.skipPostFilterState(cycleState)
-		return nil, framework.NewStatus(framework.Skip)
-	}
+	start := time.Now()
+	result, status := g.preFilter(ctx, cycleState, pod)
+	RecordElasticQuotaPreFilterLatency(status, time.Since(start))
+	return result, status
+}
 
-	mgr := g.GetGroupQuotaManagerForTree(treeID)
-	if mgr == nil {
-		return nil, framework.NewStatus(framework.Error, fmt.Sprintf("Could not find the specified ElasticQuotaManager for quota: %v, tree: %v", quotaName, treeID))
+func (g *Plugin) preFilter(ctx context.Context, cycleState *framework.CycleState, pod *corev1.Pod) (*framework.PreFilterResult, *framework.Status) {
+	admission, status := g.resolvePodAdmission(pod)
+	switch {
+	case status.IsSkip():
+		g.skipPostFilterState(cycleState)
+		return nil, status
+	case status.Code() == framework.UnschedulableAndUnresolvable:
+		g.auditAdmission(pod, "", "", core.PodRequests(pod), nil, status.Message(), false)
+		return nil, status
+	case !status.IsSuccess():
+		return nil, status
 	}
+	mgr, quotaInfo, quotaName, treeID, podRequest := admission.mgr, admission.quotaInfo, admission.quotaName, admission.treeID, admission.podRequest
 	if g.pluginArgs.EnableRuntimeQuota {
-		mgr.RefreshRuntime(quotaName)
-	}
-	quotaInfo := mgr.GetQuotaInfoByName(quotaName)
-	if quotaInfo == nil {
-		return nil, framework.NewStatus(framework.Error, fmt.Sprintf("Could not find the specified ElasticQuota"))
+		g.usageCollector.collect(mgr)
 	}
 	state := g.snapshotPostFilterState(quotaInfo, cycleState)
 
-	podRequest := core.PodRequests(pod)
-	podRequest = quotav1.Mask(podRequest, quotav1.ResourceNames(quotaInfo.CalculateInfo.Max))
-	used := quotav1.Add(podRequest, state.used)
-	if isLessEqual, exceedDimensions := quotav1.LessThanOrEqual(used, state.usedLimit); !isLessEqual {
-		return nil, framework.NewStatus(framework.Unschedulable, fmt.Sprintf("Insufficient quotas, "+
-			"quotaName: %v, runtime: %v, used: %v, pod's request: %v, exceedDimensions: %v",
-			quotaName, printResourceList(state.usedLimit), printResourceList(state.used), printResourceList(podRequest), exceedDimensions))
+	status = g.checkPodAdmission(admission, pod)
+	if status.IsSuccess() && g.pluginArgs.EnableGangGroupQuotaReservation {
+		status = g.checkGangGroupQuotaAndGrantAdmissionTokens(pod)
 	}
-
-	if extension.IsPodNonPreemptible(pod) {
-		quotaMin := state.quotaInfo.CalculateInfo.Min
-		nonPreemptibleUsed := state.nonPreemptibleUsed
-		addNonPreemptibleUsed := quotav1.Add(podRequest, nonPreemptibleUsed)
-		if isLessEqual, exceedDimensions := quotav1.LessThanOrEqual(addNonPreemptibleUsed, quotaMin); !isLessEqual {
-			return nil, framework.NewStatus(framework.Unschedulable, fmt.Sprintf("Insufficient non-preemptible quotas, "+
-				"quotaName: %v, min: %v, nonPreemptibleUsed: %v, pod's request: %v, exceedDimensions: %v",
-				quotaName, printResourceList(quotaMin), printResourceList(nonPreemptibleUsed), printResourceList(podRequest), exceedDimensions))
+	if status.IsSuccess() {
+		var reason *QuotaExceedReason
+		status, reason = g.checkQuotaAndGrantAdmissionToken(mgr, quotaInfo, pod, podRequest, state)
+		if !status.IsSuccess() {
+			g.recordQuotaExceededEvent(pod, quotaName, status)
 		}
-	}
-
-	for _, hookPlugin := range mgr.GetHookPlugins() {
-		if err := hookPlugin.CheckPod(quotaName, pod); err != nil {
-			return nil, framework.NewStatus(framework.Unschedulable,
-				fmt.Sprintf("CheckPod failed for hook plugin %v, err: %v", hookPlugin.GetKey(), err))
+		if reason != nil {
+			cycleState.Write(quotaExceedReasonKey, reason)
 		}
 	}
-
-	if g.pluginArgs.EnableCheckParentQuota {
-		return nil, g.checkQuotaRecursive(mgr, quotaInfo.ParentName, []string{quotaInfo.ParentName, quotaName}, podRequest)
-	}
-
-	return nil, framework.NewStatus(framework.Success, "")
+	g.logSampledAdmission(pod, quotaName, treeID, podRequest, state, status)
+	g.auditAdmission(pod, quotaName, treeID, podRequest, state, status.Message(), status.IsSuccess())
+	RecordElasticQuotaAdmission(quotaName, treeID, mgr.IsPodNonPreemptible(quotaName, pod), status.IsSuccess())
+	return nil, status
 }
 
 func (g *Plugin) PreFilterExtensions() framework.PreFilterExtensions {
@@ -290,7 +390,6 @@ func (g *Plugin) AddPod(ctx context.Context, state *framework.CycleState, podToS
 	if postFilterState.skip {
 		return framework.NewStatus(framework.Success, "")
 	}
-
 	if postFilterState.quotaInfo.IsPodExist(podInfoToAdd.Pod) {
 		podReq := core.PodRequests(podInfoToAdd.Pod)
 		podReq = quotav1.Mask(podReq, quotav1.ResourceNames(postFilterState.quotaInfo.CalculateInfo.Max))
@@ -311,7 +410,6 @@ func (g *Plugin) RemovePod(ctx context.Context, state *framework.CycleState, pod
 	if postFilterState.skip {
 		return framework.NewStatus(framework.Success, "")
 	}
-
 	if postFilterState.quotaInfo.IsPodExist(podInfoToRemove.Pod) {
 		podReq := core.PodRequests(podInfoToRemove.Pod)
 		podReq = quotav1.Mask(podReq, quotav1.ResourceNames(postFilterState.quotaInfo.CalculateInfo.Max))
@@ -320,11 +418,32 @@ func (g *Plugin) RemovePod(ctx context.Context, state *framework.CycleState, pod
 	return framework.NewStatus(framework.Success, "")
 }
 
+// Filter rejects the nodes running pods of the quotas which the pod's quota declares anti-affinity to.
+func (g *Plugin) Filter(ctx context.Context, cycleState *framework.CycleState, pod *corev1.Pod, nodeInfo *framework.NodeInfo) *framework.Status {
+	state, err := getPostFilterState(cycleState)
+	if err != nil {
+		return framework.AsStatus(err)
+	}
+	if state.skip || len(state.antiAffinityQuotas) == 0 {
+		return nil
+	}
+
+	if quotaName, ok := g.getAntiAffinityQuotaOnNode(nodeInfo, state.antiAffinityQuotas); ok {
+		return framework.NewStatus(framework.UnschedulableAndUnresolvable,
+			fmt.Sprintf("node(s) had pods of the anti-affinity quota %v", quotaName))
+	}
+	return nil
+}
+
 // PostFilter modify the defaultPreemption, only allow pods in the same quota can preempt others.
 func (g *Plugin) PostFilter(ctx context.Context, state *framework.CycleState, pod *corev1.Pod, filteredNodeStatusMap framework.NodeToStatusMap) (*framework.PostFilterResult, *framework.Status) {
 	defer func() {
 		metrics.PreemptionAttempts.Inc()
 	}()
+	// the pod failed to fit any node, give back the quota admitted to it in PreFilter
+	g.releaseAdmissionToken(pod)
+	// the pod of a starved quota rejected by its full ancestor preempts the over-sharing siblings instead
+	g.prepareFairSharePreemption(state, pod)
 
 	pe := preemption.Evaluator{
 		PluginName: Name,
@@ -336,6 +455,9 @@ func (g *Plugin) PostFilter(ctx context.Context, state *framework.CycleState, po
 	}
 
 	result, status := pe.Preempt(ctx, pod, filteredNodeStatusMap)
+	if status.IsSuccess() && result != nil && result.NominatingInfo != nil && result.NominatedNodeName != "" {
+		g.auditPreemption(pod, result.NominatedNodeName)
+	}
 	if status.Message() != "" {
 		return result, framework.NewStatus(status.Code(), "preemption: "+status.Message())
 	}
@@ -354,7 +476,7 @@ func (g *Plugin) Reserve(ctx context.Context, state *framework.CycleState, p *co
 		return framework.NewStatus(framework.Error, fmt.Sprintf("quota manager not found, quota: %v, tree: %v", quotaName, treeID))
 	}
 
-	mgr.ReservePod(quotaName, p)
+	g.reservePodWithAdmissionToken(mgr, quotaName, p)
 	return framework.NewStatus(framework.Success, "")
 }
 
@@ -370,6 +492,7 @@ func (g *Plugin) Unreserve(ctx context.Context, state *framework.CycleState, p *
 		return
 	}
 	mgr.UnreservePod(quotaName, p)
+	g.releaseAdmissionToken(p)
 }
 
 func (g *Plugin) GetQuotaInformer() cache.SharedIndexInformer { // expose for extensions
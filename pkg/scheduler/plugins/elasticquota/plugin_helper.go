@@ -24,15 +24,19 @@ import (
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	quotav1 "k8s.io/apiserver/pkg/quota/v1"
 	k8sfeature "k8s.io/apiserver/pkg/util/feature"
 	"k8s.io/klog/v2"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
+	schedutil "k8s.io/kubernetes/pkg/scheduler/util"
 
 	"github.com/koordinator-sh/koordinator/apis/extension"
 	schedulerv1alpha1 "github.com/koordinator-sh/koordinator/apis/thirdparty/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
 	"github.com/koordinator-sh/koordinator/pkg/features"
+	"github.com/koordinator-sh/koordinator/pkg/scheduler/apis/config"
 	"github.com/koordinator-sh/koordinator/pkg/scheduler/plugins/elasticquota/core"
 )
 
@@ -52,12 +56,22 @@ func (g *Plugin) getPodAssociateQuotaName(pod *v1.Pod) string {
 		return quotaName
 	}
 
+	// the quota, e.g. bound to the namespace of the pod, has been deleted.
 	if k8sfeature.DefaultFeatureGate.Enabled(features.DisableDefaultQuota) {
 		return ""
 	}
 	return extension.DefaultQuotaName
 }
 
+// isPodNonPreemptible checks whether the pod is non-preemptible, by its label or by the default of its quota.
+func (g *Plugin) isPodNonPreemptible(pod *v1.Pod) bool {
+	quotaName, treeID := g.getPodAssociateQuotaNameAndTreeID(pod)
+	if mgr := g.GetGroupQuotaManagerForTree(treeID); mgr != nil {
+		return mgr.IsPodNonPreemptible(quotaName, pod)
+	}
+	return extension.IsPodNonPreemptible(pod)
+}
+
 // getPodAssociateQuotaNameAndTreeID will return the quota and tree related the pod
 // If pod's don't have the "quota-name" label, we will return the default quota and tree
 // If pod has a quota label which not exists, we will also return the default quota and tree
@@ -81,36 +95,185 @@ func (g *Plugin) getPodAssociateQuotaNameAndTreeID(pod *v1.Pod) (string, string)
 	return extension.DefaultQuotaName, treeID
 }
 
+// GetQuotaSchedulingStrategy returns the scheduling strategy which the pod's quota resolved from the quota tree.
+func (g *Plugin) GetQuotaSchedulingStrategy(pod *v1.Pod) extension.QuotaSchedulingStrategy {
+	quotaName, treeID := g.getPodAssociateQuotaNameAndTreeID(pod)
+	if quotaName == "" {
+		return ""
+	}
+	mgr := g.GetGroupQuotaManagerForTree(treeID)
+	if mgr == nil {
+		return ""
+	}
+	return mgr.GetSchedulingStrategy(quotaName)
+}
+
+// getPodAssociateQuotaInfo returns the quotaInfo which the pod associated with, nil if not found.
+func (g *Plugin) getPodAssociateQuotaInfo(pod *v1.Pod) *core.QuotaInfo {
+	quotaName, treeID := g.getPodAssociateQuotaNameAndTreeID(pod)
+	if quotaName == "" {
+		return nil
+	}
+	mgr := g.GetGroupQuotaManagerForTree(treeID)
+	if mgr == nil {
+		return nil
+	}
+	return mgr.GetQuotaInfoByName(quotaName)
+}
+
+// GetQuotaTopoForPod returns the quotas which the pod is charged against, ordered from the top-level quota down to
+// the quota of the pod like the quotaNameTopo of the rejection message, nil if the quota of the pod is not found.
+func (g *Plugin) GetQuotaTopoForPod(pod *v1.Pod) []string {
+	quotaName, treeID := g.getPodAssociateQuotaNameAndTreeID(pod)
+	if quotaName == "" {
+		return nil
+	}
+	mgr := g.GetGroupQuotaManagerForTree(treeID)
+	if mgr == nil || mgr.GetQuotaInfoByName(quotaName) == nil {
+		return nil
+	}
+	// the ancestors are cached by the manager from the parent up to the top-level quota.
+	ancestors := mgr.GetAncestorQuotaInfos(quotaName)
+	quotaNameTopo := make([]string, 0, len(ancestors)+1)
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		quotaNameTopo = append(quotaNameTopo, ancestors[i].Name)
+	}
+	return append(quotaNameTopo, quotaName)
+}
+
+// isBypassNamespace returns true if the pods of the namespace bypass the quota enforcement.
+func (g *Plugin) isBypassNamespace(namespace string) bool {
+	return g.bypassNamespaces.Has(namespace)
+}
+
+// isMirrorPod returns true if the pod is the mirror of a static pod, which is created by the kubelet
+// rather than the users.
+func isMirrorPod(pod *v1.Pod) bool {
+	_, ok := pod.Annotations[v1.MirrorPodAnnotationKey]
+	return ok
+}
+
 func (g *Plugin) GetQuotaName(pod *v1.Pod) string {
-	quotaName := extension.GetQuotaName(pod)
+	quotaName, _ := g.resolveQuotaName(pod)
+	return quotaName
+}
+
+// resolveQuotaName resolves the quota of the pod, terminating is true if the pod has no quota label and the quota
+// resolved from its namespace is being deleted. The pods are accounted in the terminating quota as long as it's in
+// the manager, so the pods added before it's deleting are released from the same quota.
+func (g *Plugin) resolveQuotaName(pod *v1.Pod) (quotaName string, terminating bool) {
+	if g.isBypassNamespace(pod.Namespace) {
+		// the pods of the bypassed namespaces are always accounted in the system quota.
+		return extension.SystemQuotaName, false
+	}
+	if isMirrorPod(pod) {
+		// the static pods don't consume the user quotas, they are accounted in the system quota.
+		return extension.SystemQuotaName, false
+	}
+	quotaName = extension.GetQuotaName(pod)
 	if k8sfeature.DefaultFeatureGate.Enabled(features.DisableDefaultQuota) {
-		return quotaName
+		return quotaName, false
 	}
 
 	if quotaName != "" {
-		return quotaName
+		return quotaName, false
 	}
 	eq, err := g.quotaLister.ElasticQuotas(pod.Namespace).Get(pod.Namespace)
 	if err == nil && eq != nil {
-		return eq.Name
+		if eq.DeletionTimestamp == nil {
+			return eq.Name, false
+		}
+		if g.isQuotaInManager(eq.Name) {
+			return eq.Name, true
+		}
 	} else if !errors.IsNotFound(err) {
 		klog.Errorf("Failed to Get ElasticQuota %s, err: %v", pod.Namespace, err)
 	}
 
-	eqList, err := g.quotaInformer.GetIndexer().ByIndex("annotation.namespaces", pod.Namespace)
+	if quotaName, terminating, bound := g.getNamespaceBoundQuotaName(pod.Namespace); bound {
+		return quotaName, terminating
+	}
+	if eq != nil {
+		// the quota named after the namespace has been deleted.
+		return extension.DefaultQuotaName, false
+	}
+	return g.getUnlabeledPodQuotaName(pod.Namespace), false
+}
+
+// getNamespaceBoundQuotaName resolves the quota bound to the namespace by the annotation namespaces of the quotas.
+// If several quotas are bound, the first one by name wins, the terminating ones are still candidates as long as
+// they're in the manager. If all the quotas bound have been deleted, the pods fall back to the DefaultQuotaGroup.
+// bound is false if the namespace isn't bound to any quota.
+func (g *Plugin) getNamespaceBoundQuotaName(namespace string) (quotaName string, terminating, bound bool) {
+	eqList, err := g.quotaInformer.GetIndexer().ByIndex("annotation.namespaces", namespace)
 	if err != nil {
-		return extension.DefaultQuotaName
+		klog.Errorf("Failed to list the ElasticQuotas bound to namespace %s, err: %v", namespace, err)
+		return "", false, false
 	}
 
+	var quotaNames []string
+	terminatingQuotas := sets.NewString()
 	for _, quota := range eqList {
 		eq, ok := quota.(*schedulerv1alpha1.ElasticQuota)
 		if !ok {
 			continue
 		}
-		return eq.Name
+		bound = true
+		if eq.DeletionTimestamp == nil {
+			quotaNames = append(quotaNames, eq.Name)
+		} else if g.isQuotaInManager(eq.Name) {
+			quotaNames = append(quotaNames, eq.Name)
+			terminatingQuotas.Insert(eq.Name)
+		}
 	}
+	if len(quotaNames) == 0 {
+		if bound {
+			return extension.DefaultQuotaName, false, true
+		}
+		return "", false, false
+	}
+	sort.Strings(quotaNames)
+	return quotaNames[0], terminatingQuotas.Has(quotaNames[0]), true
+}
 
-	return extension.DefaultQuotaName
+// isQuotaInManager returns true if the quota is added to a manager and not deleted yet.
+func (g *Plugin) isQuotaInManager(quotaName string) bool {
+	g.quotaToTreeMapLock.RLock()
+	defer g.quotaToTreeMapLock.RUnlock()
+	_, ok := g.quotaToTreeMap[quotaName]
+	return ok
+}
+
+// getAdmissionQuotaNameAndTreeID returns the quota which admits the new pod. The pod resolved to a terminating quota
+// by its namespace is admitted by the DefaultQuotaGroup instead, which the pod falls back to once the quota is
+// deleted, while it's still accounted in the terminating quota.
+func (g *Plugin) getAdmissionQuotaNameAndTreeID(pod *v1.Pod, quotaName, treeID string) (string, string) {
+	if _, terminating := g.resolveQuotaName(pod); !terminating {
+		return quotaName, treeID
+	}
+	return extension.DefaultQuotaName, ""
+}
+
+// getUnlabeledPodQuotaName returns the quota of the pods without the quota label in the namespace which isn't
+// bound to any quota, by the UnlabeledPodPolicies. Empty means the pods are rejected.
+func (g *Plugin) getUnlabeledPodQuotaName(namespace string) string {
+	switch g.pluginArgs.UnlabeledPodPolicies[namespace] {
+	case config.UnlabeledPodPolicySystem:
+		return extension.SystemQuotaName
+	case config.UnlabeledPodPolicyReject:
+		return ""
+	default:
+		return extension.DefaultQuotaName
+	}
+}
+
+// isUnlabeledPodRejected returns true if the pod has no quota label and is rejected by the UnlabeledPodPolicies.
+func (g *Plugin) isUnlabeledPodRejected(pod *v1.Pod) bool {
+	if k8sfeature.DefaultFeatureGate.Enabled(features.DisableDefaultQuota) || g.isBypassNamespace(pod.Namespace) ||
+		isMirrorPod(pod) || extension.GetQuotaName(pod) != "" {
+		return false
+	}
+	return g.GetQuotaName(pod) == ""
 }
 
 // migrateDefaultQuotaGroupsPod traverse all the pods in DefaultQuotaGroup, if the pod's QuotaName is not DefaultQuotaName,
@@ -126,6 +289,11 @@ func (g *Plugin) migrateDefaultQuotaGroupsPod() {
 		if quotaName == extension.DefaultQuotaName {
 			continue
 		}
+		if extension.IsPodQuotaPinned(pod) {
+			klog.V(4).Infof("skip migrating pod %v from quota %v to %v, the pod is pinned to its quota",
+				klog.KObj(pod), extension.DefaultQuotaName, quotaName)
+			continue
+		}
 		curMgr := g.GetGroupQuotaManagerForTree(treeID)
 		if curMgr == nil || curMgr.GetQuotaInfoByName(quotaName) == nil {
 			continue
@@ -250,14 +418,28 @@ func (g *Plugin) createRootQuotaIfNotPresent() {
 func (g *Plugin) snapshotPostFilterState(quotaInfo *core.QuotaInfo, state *framework.CycleState) *PostFilterState {
 	postFilterState := &PostFilterState{
 		quotaInfo:          quotaInfo,
+		revision:           quotaInfo.GetRevision(),
 		used:               quotaInfo.GetUsed(),
 		nonPreemptibleUsed: quotaInfo.GetNonPreemptibleUsed(),
 		usedLimit:          g.getQuotaInfoUsedLimit(quotaInfo),
+		antiAffinityQuotas: sets.NewString(quotaInfo.GetAntiAffinityQuotas()...),
 	}
 	state.Write(postFilterKey, postFilterState)
 	return postFilterState
 }
 
+// checkSnapshotRevision returns Unschedulable if the max, min or runtime of the quota changed since the snapshot
+// was taken, so the victims aren't selected against the stale limits and the pod is retried in the next
+// scheduling cycle. It isn't checked in AddPod and RemovePod, since the framework turns any failure of them
+// into an Error which aborts the whole PostFilter.
+func checkSnapshotRevision(state *PostFilterState) *framework.Status {
+	if revision := state.quotaInfo.GetRevision(); revision != state.revision {
+		return framework.NewStatus(framework.Unschedulable, fmt.Sprintf("Quota %v changed during the scheduling cycle, "+
+			"snapshot revision: %v, current revision: %v, retry later", state.quotaInfo.Name, state.revision, revision))
+	}
+	return nil
+}
+
 func (g *Plugin) skipPostFilterState(state *framework.CycleState) {
 	postFilterState := &PostFilterState{
 		skip: true,
@@ -278,26 +460,221 @@ func getPostFilterState(cycleState *framework.CycleState) (*PostFilterState, err
 	return s, nil
 }
 
-func (g *Plugin) checkQuotaRecursive(mgr *core.GroupQuotaManager, curQuotaName string, quotaNameTopo []string, podRequest v1.ResourceList) *framework.Status {
+// podAdmission is the quota admitting the pod and the pod request on it, resolved by resolvePodAdmission.
+type podAdmission struct {
+	quotaName  string
+	treeID     string
+	mgr        *core.GroupQuotaManager
+	quotaInfo  *core.QuotaInfo
+	podRequest v1.ResourceList
+}
+
+// resolvePodAdmission resolves the quota admitting the pod as PreFilter does, and refreshes its runtime if enabled.
+// It returns Skip if the pod bypasses the quota, and UnschedulableAndUnresolvable if the pod without the quota
+// label is rejected.
+func (g *Plugin) resolvePodAdmission(pod *v1.Pod) (*podAdmission, *framework.Status) {
+	quotaName, treeID := g.getPodAssociateQuotaNameAndTreeID(pod)
+	if quotaName == "" && g.isUnlabeledPodRejected(pod) {
+		return nil, framework.NewStatus(framework.UnschedulableAndUnresolvable,
+			fmt.Sprintf("Pod without the label %v is rejected in namespace %v", extension.LabelQuotaName, pod.Namespace))
+	}
+	if quotaName == "" || g.isBypassNamespace(pod.Namespace) {
+		return nil, framework.NewStatus(framework.Skip)
+	}
+	quotaName, treeID = g.getAdmissionQuotaNameAndTreeID(pod, quotaName, treeID)
+
+	mgr := g.GetGroupQuotaManagerForTree(treeID)
+	if mgr == nil {
+		return nil, framework.NewStatus(framework.Error, fmt.Sprintf("Could not find the specified ElasticQuotaManager for quota: %v, tree: %v", quotaName, treeID))
+	}
+	if g.pluginArgs.EnableRuntimeQuota {
+		mgr.RefreshRuntime(quotaName)
+	}
+	quotaInfo := mgr.GetQuotaInfoByName(quotaName)
+	if quotaInfo == nil {
+		return nil, framework.NewStatus(framework.Error, fmt.Sprintf("Could not find the specified ElasticQuota"))
+	}
+
+	podRequest := mgr.PodRequests(quotaName, pod)
+	podRequest = quotav1.Mask(podRequest, quotav1.ResourceNames(quotaInfo.CalculateInfo.Max))
+	podRequest = quotaInfo.MaskByResourceGroups(podRequest)
+	return &podAdmission{
+		quotaName:  quotaName,
+		treeID:     treeID,
+		mgr:        mgr,
+		quotaInfo:  quotaInfo,
+		podRequest: podRequest,
+	}, nil
+}
+
+// checkPodAdmission runs the read-only checks of PreFilter on the quota of the pod before the quota usage is checked.
+func (g *Plugin) checkPodAdmission(admission *podAdmission, pod *v1.Pod) *framework.Status {
+	if status := checkSuspendedQuota(admission.quotaInfo); !status.IsSuccess() {
+		return status
+	}
+	if status := g.checkTerminatingQuota(admission.quotaName); !status.IsSuccess() {
+		return status
+	}
+	if status := checkRequiredPodLabels(admission.quotaInfo, pod); !status.IsSuccess() {
+		return status
+	}
+	if status := g.checkPodRequests(admission.quotaName, admission.podRequest); !status.IsSuccess() {
+		return status
+	}
+	return g.checkNodeFit(pod)
+}
+
+// checkSuspendedQuota rejects the pods of the suspended quota.
+func checkSuspendedQuota(quotaInfo *core.QuotaInfo) *framework.Status {
+	if !quotaInfo.IsSuspended() {
+		return nil
+	}
+	return framework.NewStatus(framework.Unschedulable, fmt.Sprintf("Quota %v is suspended and doesn't admit new pods", quotaInfo.Name))
+}
+
+// checkRequiredPodLabels rejects the pod if it doesn't carry the labels required by its quota.
+func checkRequiredPodLabels(quotaInfo *core.QuotaInfo, pod *v1.Pod) *framework.Status {
+	var missing []string
+	for key, value := range quotaInfo.GetRequiredPodLabels() {
+		podValue, ok := pod.Labels[key]
+		if !ok || (value != "" && podValue != value) {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	sort.Strings(missing)
+	return framework.NewStatus(framework.UnschedulableAndUnresolvable, fmt.Sprintf("Pod doesn't carry the labels required "+
+		"by quota %v, missing or mismatched labels: %v", quotaInfo.Name, strings.Join(missing, ",")))
+}
+
+// checkPodRequests rejects the pod requesting none of the dimensions tracked by its quota if required,
+// the pods of the system quota are exempt.
+func (g *Plugin) checkPodRequests(quotaName string, podRequest v1.ResourceList) *framework.Status {
+	if !g.pluginArgs.RejectPodsWithoutRequests || quotaName == extension.SystemQuotaName || !quotav1.IsZero(podRequest) {
+		return nil
+	}
+	return framework.NewStatus(framework.Unschedulable, fmt.Sprintf("Pod without the requests "+
+		"is rejected by quota %v, the requests of the dimensions tracked by the quota are required", quotaName))
+}
+
+// checkQuota checks whether the pod request fits the quota with the given used, nonPreemptibleUsed and usedLimit,
+// then runs the hook plugins and checks the parent quotas if enabled. The parents are checked with the pending
+// admission tokens except the ones of excludedUIDs, so the admissionTokenLock must be held.
+// The reason is returned if a quota is exceeded.
+func (g *Plugin) checkQuota(mgr *core.GroupQuotaManager, quotaInfo *core.QuotaInfo, pod *v1.Pod, podRequest,
+	quotaUsed, nonPreemptibleUsed, usedLimit v1.ResourceList, excludedUIDs sets.String) (*framework.Status, *QuotaExceedReason) {
+	quotaName := quotaInfo.Name
+	// the dimensions out of the quota's resource groups are enforced by the quotas of the other groups
+	used := quotaInfo.MaskByResourceGroups(quotav1.Add(podRequest, quotaUsed))
+	if isLessEqual, exceedDimensions := quotav1.LessThanOrEqual(used, g.getToleratedUsedLimit(usedLimit)); !isLessEqual {
+		exceedDimensions = g.sortExceedDimensions(exceedDimensions)
+		return framework.NewStatus(framework.Unschedulable, fmt.Sprintf("Insufficient quotas, "+
+				"quotaName: %v, runtime: %v, used: %v, pod's request: %v, exceedDimensions: %v",
+				quotaName, printResourceList(usedLimit), printResourceList(quotaUsed), printResourceList(podRequest), exceedDimensions)),
+			&QuotaExceedReason{QuotaName: quotaName, TopoPath: []string{quotaName}, Runtime: usedLimit,
+				Used: quotaUsed, Request: podRequest, ExceedDimensions: exceedDimensions}
+	}
+
+	if mgr.IsPodNonPreemptible(quotaName, pod) {
+		// the non-preemptible pods beyond the min are admitted up to the max if allowed, the runtime is checked above.
+		limitName, limit := "min", quotaInfo.CalculateInfo.Min
+		if g.pluginArgs.AllowNonPreemptibleBeyondMin {
+			limitName, limit = "max", quotaInfo.CalculateInfo.Max
+		}
+		addNonPreemptibleUsed := quotaInfo.MaskByResourceGroups(quotav1.Add(podRequest, nonPreemptibleUsed))
+		if isLessEqual, exceedDimensions := quotav1.LessThanOrEqual(addNonPreemptibleUsed, limit); !isLessEqual {
+			exceedDimensions = g.sortExceedDimensions(exceedDimensions)
+			return framework.NewStatus(framework.Unschedulable, fmt.Sprintf("Insufficient non-preemptible quotas, "+
+					"quotaName: %v, %v: %v, nonPreemptibleUsed: %v, pod's request: %v, exceedDimensions: %v",
+					quotaName, limitName, printResourceList(limit), printResourceList(nonPreemptibleUsed), printResourceList(podRequest), exceedDimensions)),
+				&QuotaExceedReason{QuotaName: quotaName, TopoPath: []string{quotaName}, NonPreemptible: true, Runtime: limit,
+					Used: nonPreemptibleUsed, Request: podRequest, ExceedDimensions: exceedDimensions}
+		}
+	}
+
+	for _, hookPlugin := range mgr.GetHookPlugins() {
+		if err := hookPlugin.CheckPod(quotaName, pod); err != nil {
+			return framework.NewStatus(framework.Unschedulable,
+				fmt.Sprintf("CheckPod failed for hook plugin %v, err: %v", hookPlugin.GetKey(), err)), nil
+		}
+	}
+
+	if g.pluginArgs.EnableCheckParentQuota {
+		return g.checkQuotaRecursive(mgr, quotaInfo.ParentName, []string{quotaInfo.ParentName, quotaName}, podRequest, excludedUIDs)
+	}
+
+	return framework.NewStatus(framework.Success, ""), nil
+}
+
+// checkQuotaRecursive checks the pod request against curQuotaName and its ancestors, the used of each quota
+// includes the pending admission tokens charged to it except the ones of excludedUIDs, so that the sibling
+// quotas can't be admitted the same last slot of their parent. The admissionTokenLock must be held.
+func (g *Plugin) checkQuotaRecursive(mgr *core.GroupQuotaManager, curQuotaName string, quotaNameTopo []string,
+	podRequest v1.ResourceList, excludedUIDs sets.String) (*framework.Status, *QuotaExceedReason) {
 	if curQuotaName == extension.RootQuotaName {
-		return framework.NewStatus(framework.Success, "")
+		return framework.NewStatus(framework.Success, ""), nil
 	}
 
 	quotaInfo := mgr.GetQuotaInfoByName(curQuotaName)
 	if quotaInfo == nil {
-		return framework.NewStatus(framework.Error, fmt.Sprintf("Could not find the elasticQuota %v, quotaNameTopo: %v", curQuotaName, quotaNameTopo))
+		return framework.NewStatus(framework.Error, fmt.Sprintf("Could not find the elasticQuota %v, quotaNameTopo: %v", curQuotaName, quotaNameTopo)), nil
+	}
+	// the ancestors are cached by the manager, so the tree isn't walked on every admission.
+	quotaInfos := append([]*core.QuotaInfo{quotaInfo}, mgr.GetAncestorQuotaInfos(curQuotaName)...)
+	// topoOf returns the path from the i-th quota down to the quota of the pod, only built for the message.
+	topoOf := func(i int) []string {
+		topo := make([]string, 0, i+len(quotaNameTopo))
+		for j := i; j > 0; j-- {
+			topo = append(topo, quotaInfos[j].Name)
+		}
+		return append(topo, quotaNameTopo...)
+	}
+
+	for i, info := range quotaInfos {
+		quotaUsed := quotav1.Add(info.GetUsed(), g.getPendingAdmissionUsedExceptNoLock(info.Name, excludedUIDs))
+		quotaUsedLimit := g.getQuotaInfoUsedLimit(info)
+
+		newUsed := quotav1.Mask(quotav1.Add(podRequest, quotaUsed), quotav1.ResourceNames(podRequest))
+		if isLessEqual, exceedDimensions := quotav1.LessThanOrEqual(newUsed, g.getToleratedUsedLimit(quotaUsedLimit)); !isLessEqual {
+			topo := topoOf(i)
+			exceedDimensions = g.sortExceedDimensions(exceedDimensions)
+			return framework.NewStatus(framework.Unschedulable, fmt.Sprintf("Insufficient quotas, "+
+					"quotaNameTopo: %v, runtime: %v, used: %v, pod's request: %v, exceedDimensions: %v", topo,
+					printResourceList(quotaUsedLimit), printResourceList(quotaUsed), printResourceList(podRequest), exceedDimensions)),
+				&QuotaExceedReason{QuotaName: info.Name, TopoPath: topo, Runtime: quotaUsedLimit,
+					Used: quotaUsed, Request: podRequest, ExceedDimensions: exceedDimensions}
+		}
 	}
-	quotaUsed := quotaInfo.GetUsed()
-	quotaUsedLimit := g.getQuotaInfoUsedLimit(quotaInfo)
 
-	newUsed := quotav1.Mask(quotav1.Add(podRequest, quotaUsed), quotav1.ResourceNames(podRequest))
-	if isLessEqual, exceedDimensions := quotav1.LessThanOrEqual(newUsed, quotaUsedLimit); !isLessEqual {
-		return framework.NewStatus(framework.Unschedulable, fmt.Sprintf("Insufficient quotas, "+
-			"quotaNameTopo: %v, runtime: %v, used: %v, pod's request: %v, exceedDimensions: %v", quotaNameTopo,
-			printResourceList(quotaUsedLimit), printResourceList(quotaUsed), printResourceList(podRequest), exceedDimensions))
+	// the path stops early if an ancestor is missing
+	if topName := quotaInfos[len(quotaInfos)-1].ParentName; topName != extension.RootQuotaName {
+		return framework.NewStatus(framework.Error, fmt.Sprintf("Could not find the elasticQuota %v, quotaNameTopo: %v",
+			topName, append([]string{topName}, topoOf(len(quotaInfos)-1)...))), nil
 	}
-	quotaNameTopo = append([]string{quotaInfo.ParentName}, quotaNameTopo...)
-	return g.checkQuotaRecursive(mgr, quotaInfo.ParentName, quotaNameTopo, podRequest)
+	return framework.NewStatus(framework.Success, ""), nil
+}
+
+// sortExceedDimensions sorts the exceeded dimensions by the ExceedDimensionOrder, so the most relevant shortage
+// is surfaced first, and the rest by name so that the message is stable for the log parsers.
+func (g *Plugin) sortExceedDimensions(resourceNames []v1.ResourceName) []v1.ResourceName {
+	priority := make(map[v1.ResourceName]int, len(g.pluginArgs.ExceedDimensionOrder))
+	for i, resourceName := range g.pluginArgs.ExceedDimensionOrder {
+		priority[v1.ResourceName(resourceName)] = i
+	}
+	sort.Slice(resourceNames, func(i, j int) bool {
+		pi, iOrdered := priority[resourceNames[i]]
+		pj, jOrdered := priority[resourceNames[j]]
+		if iOrdered != jOrdered {
+			return iOrdered
+		}
+		if iOrdered && pi != pj {
+			return pi < pj
+		}
+		return resourceNames[i] < resourceNames[j]
+	})
+	return resourceNames
 }
 
 func printResourceList(rl v1.ResourceList) string {
@@ -316,8 +693,86 @@ func printResourceList(rl v1.ResourceList) string {
 }
 
 func (g *Plugin) getQuotaInfoUsedLimit(quotaInfo *core.QuotaInfo) v1.ResourceList {
+	var usedLimit v1.ResourceList
 	if g.pluginArgs.EnableRuntimeQuota {
-		return quotaInfo.GetRuntime()
+		usedLimit = g.getWarmUpUsedLimit(quotaInfo, quotaInfo.GetRuntime())
+		usedLimit = g.getAntiAffinityFeasibleUsedLimit(quotaInfo, usedLimit)
+		usedLimit = g.applyRuntimeDisabledResources(usedLimit, quotaInfo.GetOvercommittedMax())
+	} else {
+		usedLimit = quotaInfo.GetOvercommittedMax()
+	}
+	return subtractReserved(usedLimit, quotaInfo.GetReserved())
+}
+
+// applyRuntimeDisabledResources replaces the runtime of RuntimeQuotaDisabledResources with the max, so those
+// resources are only enforced by the max. The resources absent from the max are left out of the used limit.
+func (g *Plugin) applyRuntimeDisabledResources(usedLimit, max v1.ResourceList) v1.ResourceList {
+	if len(g.pluginArgs.RuntimeQuotaDisabledResources) == 0 {
+		return usedLimit
+	}
+	newUsedLimit := usedLimit.DeepCopy()
+	if newUsedLimit == nil {
+		newUsedLimit = v1.ResourceList{}
+	}
+	for _, resourceName := range g.pluginArgs.RuntimeQuotaDisabledResources {
+		if quantity, ok := max[v1.ResourceName(resourceName)]; ok {
+			newUsedLimit[v1.ResourceName(resourceName)] = quantity.DeepCopy()
+		} else {
+			delete(newUsedLimit, v1.ResourceName(resourceName))
+		}
+	}
+	return newUsedLimit
+}
+
+// subtractReserved takes the reserved system overhead off the top of the used limit, so that
+// the workloads of the quota only see the remaining capacity.
+func subtractReserved(usedLimit, reserved v1.ResourceList) v1.ResourceList {
+	if quotav1.IsZero(reserved) {
+		return usedLimit
+	}
+	usedLimit = quotav1.Subtract(usedLimit, quotav1.Mask(reserved, quotav1.ResourceNames(usedLimit)))
+	for _, resName := range quotav1.IsNegative(usedLimit) {
+		usedLimit[resName] = *resource.NewQuantity(0, usedLimit[resName].Format)
+	}
+	return usedLimit
+}
+
+// getToleratedUsedLimit enlarges the used limit by ExceedTolerancePercent, so the pod which marginally
+// exceeds the limit, e.g. due to rounding, is still admitted.
+func (g *Plugin) getToleratedUsedLimit(usedLimit v1.ResourceList) v1.ResourceList {
+	tolerance := g.pluginArgs.ExceedTolerancePercent
+	if tolerance <= 0 {
+		return usedLimit
+	}
+	toleratedLimit := make(v1.ResourceList, len(usedLimit))
+	for resourceName, quantity := range usedLimit {
+		value := quantity.MilliValue()
+		toleratedLimit[resourceName] = *resource.NewMilliQuantity(value+value*tolerance/100, quantity.Format)
+	}
+	return toleratedLimit
+}
+
+// isSchedulableAfterPodDeletion requeues the pending pod if the deleted pod belongs to a quota of the same tree,
+// since the freed used of any quota in the tree may be shared to the pending pod's quota through the runtime of
+// their common ancestors, e.g. the quota of a cousin or of an ancestor.
+func (g *Plugin) isSchedulableAfterPodDeletion(logger klog.Logger, pod *v1.Pod, oldObj, newObj interface{}) framework.QueueingHint {
+	deletedPod, _, err := schedutil.As[*v1.Pod](oldObj, newObj)
+	if err != nil {
+		// Shouldn't happen.
+		logger.Error(err, "unexpected object in isSchedulableAfterPodDeletion")
+		return framework.QueueAfterBackoff
+	}
+	if deletedPod == nil || deletedPod.Spec.NodeName == "" {
+		// the unassigned pod doesn't occupy the used of its quota
+		return framework.QueueSkip
+	}
+
+	pendingQuotaName, pendingTreeID := g.getPodAssociateQuotaNameAndTreeID(pod)
+	deletedQuotaName, deletedTreeID := g.getPodAssociateQuotaNameAndTreeID(deletedPod)
+	if pendingQuotaName == "" || deletedQuotaName == "" || pendingTreeID != deletedTreeID {
+		return framework.QueueSkip
 	}
-	return quotaInfo.GetMax()
+	logger.V(5).Info("requeue the pod since a pod of the same quota tree is deleted", "pod", klog.KObj(pod),
+		"quota", pendingQuotaName, "deletedPod", klog.KObj(deletedPod), "deletedPodQuota", deletedQuotaName)
+	return framework.QueueAfterBackoff
 }
@@ -24,6 +24,8 @@ import (
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
 
 	"github.com/koordinator-sh/koordinator/apis/extension"
 	schedulerv1alpha1 "github.com/koordinator-sh/koordinator/apis/thirdparty/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
@@ -36,6 +38,7 @@ func TestGetQuotaName(t *testing.T) {
 		name            string
 		pod             *corev1.Pod
 		elasticQuotas   []*schedulerv1alpha1.ElasticQuota
+		quotasInManager []string
 		expectQuotaName string
 	}{
 		{
@@ -89,6 +92,77 @@ func TestGetQuotaName(t *testing.T) {
 			},
 			expectQuotaName: "test-ns1",
 		},
+		{
+			name: "the first quota by name wins if several quotas are bound to the namespace",
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "test-ns",
+					Name:      "test-pod",
+				},
+			},
+			elasticQuotas: []*schedulerv1alpha1.ElasticQuota{
+				MakeEQ("test-ns", "test-ns3").Annotations(map[string]string{extension.AnnotationQuotaNamespaces: "[\"test-ns\"]"}).Obj(),
+				MakeEQ("test-ns", "test-ns2").Annotations(map[string]string{extension.AnnotationQuotaNamespaces: "[\"test-ns\"]"}).Obj(),
+			},
+			expectQuotaName: "test-ns2",
+		},
+		{
+			name: "the quota bound to the namespace is being deleted",
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "test-ns",
+					Name:      "test-pod",
+				},
+			},
+			elasticQuotas: []*schedulerv1alpha1.ElasticQuota{
+				terminatingEQ(MakeEQ("test-ns", "test-ns1").Annotations(map[string]string{extension.AnnotationQuotaNamespaces: "[\"test-ns\"]"}).Obj()),
+			},
+			expectQuotaName: extension.DefaultQuotaName,
+		},
+		{
+			name: "the quota named after the namespace is being deleted",
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "test-ns",
+					Name:      "test-pod",
+				},
+			},
+			elasticQuotas: []*schedulerv1alpha1.ElasticQuota{
+				terminatingEQ(MakeEQ("test-ns", "test-ns").Obj()),
+				MakeEQ("test-ns", "test-ns1").Annotations(map[string]string{extension.AnnotationQuotaNamespaces: "[\"test-ns\"]"}).Obj(),
+			},
+			expectQuotaName: "test-ns1",
+		},
+		{
+			name: "the quota bound to the namespace is being deleted but still in the manager",
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "test-ns",
+					Name:      "test-pod",
+				},
+			},
+			elasticQuotas: []*schedulerv1alpha1.ElasticQuota{
+				terminatingEQ(MakeEQ("test-ns", "test-ns1").Annotations(map[string]string{extension.AnnotationQuotaNamespaces: "[\"test-ns\"]"}).Obj()),
+				MakeEQ("test-ns", "test-ns2").Annotations(map[string]string{extension.AnnotationQuotaNamespaces: "[\"test-ns\"]"}).Obj(),
+			},
+			quotasInManager: []string{"test-ns1"},
+			expectQuotaName: "test-ns1",
+		},
+		{
+			name: "the quota named after the namespace is being deleted but still in the manager",
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "test-ns",
+					Name:      "test-pod",
+				},
+			},
+			elasticQuotas: []*schedulerv1alpha1.ElasticQuota{
+				terminatingEQ(MakeEQ("test-ns", "test-ns").Obj()),
+				MakeEQ("test-ns", "test-ns1").Annotations(map[string]string{extension.AnnotationQuotaNamespaces: "[\"test-ns\"]"}).Obj(),
+			},
+			quotasInManager: []string{"test-ns"},
+			expectQuotaName: "test-ns",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -102,16 +176,91 @@ func TestGetQuotaName(t *testing.T) {
 				assert.NoError(t, err)
 			}
 			time.Sleep(100 * time.Millisecond)
+			for _, quotaName := range tt.quotasInManager {
+				eQP.updateQuotaToTreeMap(quotaName, "")
+			}
 			quotaName := eQP.GetQuotaName(tt.pod)
 			assert.Equal(t, tt.expectQuotaName, quotaName)
 		})
 	}
 }
 
+func TestPlugin_getAdmissionQuotaNameAndTreeID(t *testing.T) {
+	suit := newPluginTestSuit(t, nil)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.Nil(t, err)
+	eQP := p.(*Plugin)
+
+	eq := terminatingEQ(MakeEQ("test-ns", "test-ns1").Annotations(map[string]string{extension.AnnotationQuotaNamespaces: "[\"test-ns\"]"}).Obj())
+	_, err = eQP.client.SchedulingV1alpha1().ElasticQuotas(eq.Namespace).Create(context.TODO(), eq, metav1.CreateOptions{})
+	assert.NoError(t, err)
+	time.Sleep(100 * time.Millisecond)
+	eQP.updateQuotaToTreeMap("test-ns1", "tree-1")
+
+	// the pod is accounted in the terminating quota, while the new admission falls back to the default quota
+	pod := MakePod("test-ns", "test-pod").Obj()
+	quotaName, treeID := eQP.getPodAssociateQuotaNameAndTreeID(pod)
+	assert.Equal(t, "test-ns1", quotaName)
+	assert.Equal(t, "tree-1", treeID)
+	quotaName, treeID = eQP.getAdmissionQuotaNameAndTreeID(pod, quotaName, treeID)
+	assert.Equal(t, extension.DefaultQuotaName, quotaName)
+	assert.Equal(t, "", treeID)
+
+	// the labeled pod isn't affected
+	labeledPod := MakePod("test-ns", "test-pod2").Label(extension.LabelQuotaName, "test-ns1").Obj()
+	quotaName, treeID = eQP.getAdmissionQuotaNameAndTreeID(labeledPod, "test-ns1", "tree-1")
+	assert.Equal(t, "test-ns1", quotaName)
+	assert.Equal(t, "tree-1", treeID)
+}
+
+func TestPlugin_GetQuotaTopoForPod(t *testing.T) {
+	suit := newPluginTestSuit(t, nil)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.Nil(t, err)
+	gp := p.(*Plugin)
+	gp.addQuota("test-parent", extension.RootQuotaName, 100, 100, 50, 50, 100, 100, true, "", "")
+	gp.addQuota("test-child", "test-parent", 100, 100, 50, 50, 100, 100, true, "", "")
+	gp.addQuota("test-leaf", "test-child", 100, 100, 50, 50, 100, 100, false, "", "")
+
+	tests := []struct {
+		name string
+		pod  *corev1.Pod
+		want []string
+	}{
+		{
+			name: "leaf quota",
+			pod:  MakePod("test-ns", "pod1").Label(extension.LabelQuotaName, "test-leaf").Obj(),
+			want: []string{"test-parent", "test-child", "test-leaf"},
+		},
+		{
+			name: "top-level quota",
+			pod:  MakePod("test-ns", "pod2").Label(extension.LabelQuotaName, "test-parent").Obj(),
+			want: []string{"test-parent"},
+		},
+		{
+			name: "fall back to the default quota",
+			pod:  MakePod("test-ns", "pod3").Label(extension.LabelQuotaName, "not-exist").Obj(),
+			want: []string{extension.DefaultQuotaName},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, gp.GetQuotaTopoForPod(tt.pod))
+		})
+	}
+}
+
+func terminatingEQ(eq *schedulerv1alpha1.ElasticQuota) *schedulerv1alpha1.ElasticQuota {
+	eq.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	eq.Finalizers = []string{"test"}
+	return eq
+}
+
 func TestPlugin_getQuotaInfoRuntime(t *testing.T) {
 	type args struct {
-		quotaInfo          *core.QuotaInfo
-		enableRuntimeQuota bool
+		quotaInfo                     *core.QuotaInfo
+		enableRuntimeQuota            bool
+		runtimeQuotaDisabledResources []string
 	}
 	tests := []struct {
 		name string
@@ -144,15 +293,125 @@ func TestPlugin_getQuotaInfoRuntime(t *testing.T) {
 			},
 			want: createResourceList(100, 100),
 		},
+		{
+			name: "get max of the runtime disabled resources",
+			args: args{
+				enableRuntimeQuota:            true,
+				runtimeQuotaDisabledResources: []string{string(corev1.ResourceMemory)},
+				quotaInfo: &core.QuotaInfo{
+					CalculateInfo: core.QuotaCalculateInfo{
+						Max:     createResourceList(100, 100),
+						Runtime: createResourceList(1, 1),
+					},
+				},
+			},
+			want: corev1.ResourceList{
+				corev1.ResourceCPU:    createResourceList(1, 1)[corev1.ResourceCPU],
+				corev1.ResourceMemory: createResourceList(100, 100)[corev1.ResourceMemory],
+			},
+		},
+		{
+			name: "runtime disabled resource absent from max",
+			args: args{
+				enableRuntimeQuota:            true,
+				runtimeQuotaDisabledResources: []string{string(corev1.ResourceMemory)},
+				quotaInfo: &core.QuotaInfo{
+					CalculateInfo: core.QuotaCalculateInfo{
+						Max:     corev1.ResourceList{corev1.ResourceCPU: createResourceList(100, 100)[corev1.ResourceCPU]},
+						Runtime: createResourceList(1, 1),
+					},
+				},
+			},
+			want: corev1.ResourceList{corev1.ResourceCPU: createResourceList(1, 1)[corev1.ResourceCPU]},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			g := &Plugin{
 				pluginArgs: &config.ElasticQuotaArgs{
-					EnableRuntimeQuota: tt.args.enableRuntimeQuota,
+					EnableRuntimeQuota:            tt.args.enableRuntimeQuota,
+					RuntimeQuotaDisabledResources: tt.args.runtimeQuotaDisabledResources,
 				},
 			}
 			assert.Equalf(t, tt.want, g.getQuotaInfoUsedLimit(tt.args.quotaInfo), "getQuotaInfoUsedLimit(%v)", tt.args.quotaInfo)
 		})
 	}
 }
+
+func TestPlugin_IsSchedulableAfterPodDeletion(t *testing.T) {
+	suit := newPluginTestSuit(t, nil)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.Nil(t, err)
+	gp := p.(*Plugin)
+	// root -> parent-a -> a1, a2
+	//      -> parent-b -> b1
+	//      -> c
+	gp.addQuota("parent-a", extension.RootQuotaName, 100, 100, 10, 10, 10, 10, true, "", "")
+	gp.addQuota("a1", "parent-a", 100, 100, 5, 5, 10, 10, false, "", "")
+	gp.addQuota("a2", "parent-a", 100, 100, 5, 5, 10, 10, false, "", "")
+	gp.addQuota("parent-b", extension.RootQuotaName, 100, 100, 10, 10, 10, 10, true, "", "")
+	gp.addQuota("b1", "parent-b", 100, 100, 5, 5, 10, 10, false, "", "")
+	gp.addQuota("c", extension.RootQuotaName, 100, 100, 10, 10, 10, 10, false, "", "")
+
+	newPod := func(name, quotaName, nodeName string) *corev1.Pod {
+		pod := defaultCreatePodWithQuotaAndNonPreemptible(name, quotaName, 10, 1, 1, false)
+		pod.Spec.NodeName = nodeName
+		return pod
+	}
+	tests := []struct {
+		name       string
+		pendingPod *corev1.Pod
+		deletedPod *corev1.Pod
+		want       framework.QueueingHint
+	}{
+		{
+			name:       "pod of the same quota is deleted",
+			pendingPod: newPod("pending", "a1", ""),
+			deletedPod: newPod("deleted", "a1", "node1"),
+			want:       framework.QueueAfterBackoff,
+		},
+		{
+			name:       "pod of the sibling quota is deleted",
+			pendingPod: newPod("pending", "a1", ""),
+			deletedPod: newPod("deleted", "a2", "node1"),
+			want:       framework.QueueAfterBackoff,
+		},
+		{
+			name:       "unassigned pod of the sibling quota is deleted",
+			pendingPod: newPod("pending", "a1", ""),
+			deletedPod: newPod("deleted", "a2", ""),
+			want:       framework.QueueSkip,
+		},
+		{
+			name:       "pod of a cousin quota is deleted",
+			pendingPod: newPod("pending", "a1", ""),
+			deletedPod: newPod("deleted", "b1", "node1"),
+			want:       framework.QueueAfterBackoff,
+		},
+		{
+			name:       "pod of the parent quota is deleted",
+			pendingPod: newPod("pending", "a1", ""),
+			deletedPod: newPod("deleted", "parent-a", "node1"),
+			want:       framework.QueueAfterBackoff,
+		},
+		{
+			name:       "pod of a top level quota is deleted",
+			pendingPod: newPod("pending", "a1", ""),
+			deletedPod: newPod("deleted", "c", "node1"),
+			want:       framework.QueueAfterBackoff,
+		},
+
+		{
+			name:       "pod of the descendant of a sibling quota is deleted",
+			pendingPod: newPod("pending", "c", ""),
+			deletedPod: newPod("deleted", "b1", "node1"),
+			want:       framework.QueueAfterBackoff,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := gp.isSchedulableAfterPodDeletion(klog.Background(), tt.pendingPod, tt.deletedPod, nil)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -18,8 +18,10 @@ package elasticquota
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
 
 	"github.com/koordinator-sh/koordinator/pkg/scheduler/frameworkext/services"
 )
@@ -37,10 +39,77 @@ func (g *Plugin) RegisterEndpoints(group *gin.RouterGroup) {
 		}
 		c.JSON(http.StatusOK, quotaSummary)
 	})
+	group.GET("/quotas/:name/usedByAge", func(c *gin.Context) {
+		quotaName := c.Param("name")
+		steadyDuration := DefaultSteadyStateDuration
+		if raw := c.Query("steadyDuration"); raw != "" {
+			duration, err := time.ParseDuration(raw)
+			if err != nil {
+				services.ResponseErrorMessage(c, http.StatusBadRequest, "invalid steadyDuration %s, err: %v", raw, err)
+				return
+			}
+			steadyDuration = duration
+		}
+		usedByAge, exist := g.GetQuotaUsedByAge(quotaName, steadyDuration)
+		if !exist {
+			services.ResponseErrorMessage(c, http.StatusNotFound, "cannot find quota %s", quotaName)
+			return
+		}
+		c.JSON(http.StatusOK, usedByAge)
+	})
+	group.GET("/quotas/:name/resourceHours", func(c *gin.Context) {
+		quotaName := c.Param("name")
+		window := DefaultQuotaCostWindow
+		if raw := c.Query("window"); raw != "" {
+			duration, err := time.ParseDuration(raw)
+			if err != nil || duration <= 0 || duration > QuotaCostRetention {
+				services.ResponseErrorMessage(c, http.StatusBadRequest, "invalid window %s, it should be in (0, %v]", raw, QuotaCostRetention)
+				return
+			}
+			window = duration
+		}
+		resourceHours, exist := g.GetQuotaResourceHours(quotaName, window)
+		if !exist {
+			services.ResponseErrorMessage(c, http.StatusNotFound, "cannot find quota %s", quotaName)
+			return
+		}
+		c.JSON(http.StatusOK, resourceHours)
+	})
+	group.GET("/gangs/:namespace/:name", func(c *gin.Context) {
+		gangNamespace := c.Param("namespace")
+		gangName := c.Param("name")
+		gangQuotaSummary, exist := g.GetGangQuotaSummary(gangNamespace, gangName)
+		if !exist {
+			services.ResponseErrorMessage(c, http.StatusNotFound, "cannot find gang %s/%s", gangNamespace, gangName)
+			return
+		}
+		c.JSON(http.StatusOK, gangQuotaSummary)
+	})
+	group.POST("/admission", func(c *gin.Context) {
+		pod := &corev1.Pod{}
+		if err := c.ShouldBindJSON(pod); err != nil {
+			services.ResponseErrorMessage(c, http.StatusBadRequest, "invalid pod, err: %v", err)
+			return
+		}
+		c.JSON(http.StatusOK, g.GetQuotaAdmissionVerdict(pod))
+	})
 	group.GET("/quotas", func(c *gin.Context) {
 		tree := c.Query("tree")
 		includePods := c.Query("includePods") == "true"
 		quotaSummaries := g.GetQuotaSummaries(tree, includePods)
 		c.JSON(http.StatusOK, quotaSummaries)
 	})
+	group.GET("/quota/health", func(c *gin.Context) {
+		health := g.GetQuotaHealth()
+		if !health.Ready {
+			c.JSON(http.StatusServiceUnavailable, health)
+			return
+		}
+		c.JSON(http.StatusOK, health)
+	})
+	group.GET("/quotaTopology", func(c *gin.Context) {
+		tree := c.Query("tree")
+		includePods := c.Query("includePods") == "true"
+		c.JSON(http.StatusOK, g.GetQuotaTopologySummaries(tree, includePods))
+	})
 }
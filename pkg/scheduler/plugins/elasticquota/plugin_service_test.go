@@ -17,10 +17,13 @@ limitations under the License.
 package elasticquota
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -28,8 +31,12 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	quotav1 "k8s.io/apiserver/pkg/quota/v1"
 	k8sfeature "k8s.io/apiserver/pkg/util/feature"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	fakeclock "k8s.io/utils/clock/testing"
 
 	"github.com/koordinator-sh/koordinator/apis/extension"
+	"github.com/koordinator-sh/koordinator/apis/thirdparty/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
 	koordfeatures "github.com/koordinator-sh/koordinator/pkg/features"
 	"github.com/koordinator-sh/koordinator/pkg/scheduler/plugins/elasticquota/core"
 	utilfeature "github.com/koordinator-sh/koordinator/pkg/util/feature"
@@ -181,3 +188,289 @@ func TestEndpointsQueryQuotaInfo(t *testing.T) {
 		assert.True(t, quotav1.Equals(quotaSummary.SharedWeight, createResourceList(30, 30)))
 	}
 }
+
+func TestEndpointsQueryQuotaUsedByAge(t *testing.T) {
+	suit := newPluginTestSuit(t, nil)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.Nil(t, err)
+	plugin := p.(*Plugin)
+	now := time.Now()
+	plugin.clock = fakeclock.NewFakeClock(now)
+	plugin.OnQuotaAdd(CreateQuota2("test1", "", 100, 100, 10, 10, 20, 20, false, ""))
+
+	steadyPod := defaultCreatePodWithQuotaAndNonPreemptible("steady", "test1", 10, 10, 10, false)
+	steadyPod.Status.StartTime = &metav1.Time{Time: now.Add(-time.Hour)}
+	plugin.OnPodAdd(steadyPod)
+	burstPod := defaultCreatePodWithQuotaAndNonPreemptible("burst", "test1", 10, 5, 5, false)
+	burstPod.Status.StartTime = &metav1.Time{Time: now.Add(-2 * time.Minute)}
+	plugin.OnPodAdd(burstPod)
+
+	tests := []struct {
+		name           string
+		url            string
+		expectedCode   int
+		expectedSteady corev1.ResourceList
+		expectedBurst  corev1.ResourceList
+	}{
+		{
+			name:           "default steady duration",
+			url:            "/quotas/test1/usedByAge",
+			expectedCode:   http.StatusOK,
+			expectedSteady: createResourceList(10, 10),
+			expectedBurst:  createResourceList(5, 5),
+		},
+		{
+			name:           "custom steady duration",
+			url:            "/quotas/test1/usedByAge?steadyDuration=1m",
+			expectedCode:   http.StatusOK,
+			expectedSteady: createResourceList(15, 15),
+			expectedBurst:  corev1.ResourceList{},
+		},
+		{
+			name:         "invalid steady duration",
+			url:          "/quotas/test1/usedByAge?steadyDuration=abc",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "quota not found",
+			url:          "/quotas/not-exist/usedByAge",
+			expectedCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := gin.Default()
+			plugin.RegisterEndpoints(engine.Group("/"))
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", tt.url, nil)
+			engine.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectedCode, w.Result().StatusCode)
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+			usedByAge := &QuotaUsedByAge{}
+			assert.NoError(t, json.NewDecoder(w.Result().Body).Decode(usedByAge))
+			assert.True(t, quotav1.Equals(tt.expectedSteady, usedByAge.SteadyUsed))
+			assert.True(t, quotav1.Equals(tt.expectedBurst, usedByAge.BurstUsed))
+		})
+	}
+}
+
+func TestEndpointsQueryGangQuotaSummary(t *testing.T) {
+	suit := newPluginTestSuit(t, nil)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.NoError(t, err)
+	plugin := p.(*Plugin)
+	plugin.pluginArgs.EnableRuntimeQuota = false
+	plugin.addQuota("test1", extension.RootQuotaName, 100, 1000, 10, 100, 10, 100, false, "", "")
+
+	newGangPod := func(name string, cpu int64, nodeName string) *corev1.Pod {
+		pod := MakePod("ns", name).Label(extension.LabelQuotaName, "test1").Container(
+			createResourceList(cpu, 10)).Obj()
+		pod.Annotations = map[string]string{
+			extension.AnnotationGangName:   "gang-a",
+			extension.AnnotationGangMinNum: "3",
+		}
+		pod.Spec.NodeName = nodeName
+		return pod
+	}
+	otherPod := MakePod("ns", "other").Label(extension.LabelQuotaName, "test1").Container(
+		createResourceList(10, 10)).Obj()
+	podStore := suit.Handle.SharedInformerFactory().Core().V1().Pods().Informer().GetStore()
+	for _, pod := range []*corev1.Pod{
+		newGangPod("pod1", 40, "node1"),
+		newGangPod("pod2", 30, ""),
+		newGangPod("pod3", 40, ""),
+		otherPod,
+	} {
+		assert.NoError(t, podStore.Add(pod))
+		plugin.OnPodAdd(pod)
+	}
+
+	engine := gin.Default()
+	plugin.RegisterEndpoints(engine.Group("/"))
+	{
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/gangs/ns/gang-a", nil)
+		engine.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+		summary := &GangQuotaSummary{}
+		assert.NoError(t, json.NewDecoder(w.Result().Body).Decode(summary))
+
+		assert.Equal(t, "ns/gang-a", summary.Name)
+		assert.Equal(t, 3, summary.MinRequiredNumber)
+		assert.Equal(t, 3, summary.TotalChildrenNum)
+		assert.Equal(t, []string{"pod2", "pod3"}, summary.PendingChildren)
+		assert.Equal(t, []string{"pod1"}, summary.BoundChildren)
+		assert.Equal(t, 1, len(summary.Quotas))
+		headroom := summary.Quotas["test1"]
+		assert.NotNil(t, headroom)
+		assert.Equal(t, []string{"pod1", "pod2", "pod3"}, headroom.Children)
+		assert.True(t, quotav1.Equals(createResourceList(40, 10), headroom.Used))
+		assert.True(t, quotav1.Equals(createResourceList(100, 1000), headroom.UsedLimit))
+		assert.True(t, quotav1.Equals(createResourceList(60, 990), headroom.Headroom))
+		assert.True(t, quotav1.Equals(createResourceList(70, 20), headroom.PendingRequest))
+		assert.False(t, headroom.Sufficient)
+	}
+	{
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/gangs/ns/not-exist", nil)
+		engine.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+	}
+}
+
+func TestEndpointsQuotaAdmission(t *testing.T) {
+	suit := newPluginTestSuit(t, nil)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.NoError(t, err)
+	plugin := p.(*Plugin)
+	plugin.pluginArgs.EnableRuntimeQuota = false
+	quota := plugin.addQuota("test1", extension.RootQuotaName, 100, 1000, 10, 100, 10, 100, false, "", "")
+
+	engine := gin.Default()
+	plugin.RegisterEndpoints(engine.Group("/"))
+	postPod := func(pod *corev1.Pod) *QuotaAdmissionVerdict {
+		body, err := json.Marshal(pod)
+		assert.NoError(t, err)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/admission", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		engine.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+		verdict := &QuotaAdmissionVerdict{}
+		assert.NoError(t, json.NewDecoder(w.Result().Body).Decode(verdict))
+		return verdict
+	}
+
+	// the pod fits its quota
+	verdict := postPod(MakePod("ns", "pod1").Label(extension.LabelQuotaName, "test1").Container(
+		createResourceList(40, 100)).Obj())
+	assert.True(t, verdict.Admitted)
+	assert.Equal(t, "test1", verdict.Quota)
+	assert.Equal(t, framework.Success.String(), verdict.Code)
+
+	// the pod exceeds its quota
+	verdict = postPod(MakePod("ns", "pod2").Label(extension.LabelQuotaName, "test1").Container(
+		createResourceList(200, 100)).Obj())
+	assert.False(t, verdict.Admitted)
+	assert.Equal(t, "test1", verdict.Quota)
+	assert.Equal(t, framework.Unschedulable.String(), verdict.Code)
+	assert.NotEmpty(t, verdict.Reasons)
+
+	// the pod admitted in PreFilter but not reserved yet holds the quota
+	admittedPod := MakePod("ns", "pod3").UID("pod3").Label(extension.LabelQuotaName, "test1").Container(
+		createResourceList(80, 100)).Obj()
+	_, status := plugin.PreFilter(context.TODO(), framework.NewCycleState(), admittedPod)
+	assert.True(t, status.IsSuccess())
+	verdict = postPod(MakePod("ns", "pod1").Label(extension.LabelQuotaName, "test1").Container(
+		createResourceList(40, 100)).Obj())
+	assert.False(t, verdict.Admitted)
+	plugin.releaseAdmissionToken(admittedPod)
+
+	// the suspended quota doesn't admit the pod
+	suspended := quota.DeepCopy()
+	suspended.Labels[extension.LabelQuotaSuspend] = "true"
+	plugin.OnQuotaUpdate(quota, suspended)
+	verdict = postPod(MakePod("ns", "pod1").Label(extension.LabelQuotaName, "test1").Container(
+		createResourceList(40, 100)).Obj())
+	assert.False(t, verdict.Admitted)
+	assert.Equal(t, framework.Unschedulable.String(), verdict.Code)
+	assert.Equal(t, []string{"Quota test1 is suspended and doesn't admit new pods"}, verdict.Reasons)
+
+	// the request body is not a pod
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/admission", bytes.NewReader([]byte("not-a-pod")))
+	engine.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestEndpointsQuotaHealth(t *testing.T) {
+	getHealth := func(plugin *Plugin) (int, *QuotaHealth) {
+		engine := gin.Default()
+		plugin.RegisterEndpoints(engine.Group("/"))
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/quota/health", nil)
+		engine.ServeHTTP(w, req)
+		health := &QuotaHealth{}
+		assert.NoError(t, json.NewDecoder(w.Result().Body).Decode(health))
+		return w.Result().StatusCode, health
+	}
+
+	// the quotas are not synced yet
+	unsynced := &Plugin{
+		quotaInformer: cache.NewSharedIndexInformer(&cache.ListWatch{}, &v1alpha1.ElasticQuota{}, 0, cache.Indexers{}),
+	}
+	code, health := getHealth(unsynced)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, &QuotaHealth{}, health)
+
+	suit := newPluginTestSuit(t, nil)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.NoError(t, err)
+	plugin := p.(*Plugin)
+
+	// the quotas are synced but not reconciled yet
+	code, health = getHealth(plugin)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, &QuotaHealth{Synced: true}, health)
+
+	// the quotas are reconciled
+	plugin.reconcileQuotas()
+	code, health = getHealth(plugin)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, &QuotaHealth{Synced: true, Reconciled: true, Ready: true}, health)
+}
+
+func TestEndpointsQueryQuotaTopology(t *testing.T) {
+	suit := newPluginTestSuit(t, nil)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.Nil(t, err)
+	plugin := p.(*Plugin)
+	plugin.OnQuotaAdd(CreateQuota2("parent1", extension.RootQuotaName, 100, 100, 10, 10, 20, 20, true, ""))
+	plugin.OnQuotaAdd(CreateQuota2("child2", "parent1", 100, 100, 5, 5, 20, 20, false, ""))
+	plugin.OnQuotaAdd(CreateQuota2("child1", "parent1", 100, 100, 5, 5, 20, 20, false, ""))
+
+	engine := gin.Default()
+	plugin.RegisterEndpoints(engine.Group("/"))
+
+	// the quota summary lists the children of the quota
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/quotas/parent1", nil)
+	engine.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	quotaSummary := &core.QuotaInfoSummary{}
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(quotaSummary))
+	assert.Equal(t, []string{"child1", "child2"}, quotaSummary.Children)
+
+	// the topology dumps the quota tree from the root quota
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/quotaTopology", nil)
+	engine.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	topologies := map[string]*core.QuotaTopologySummary{}
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&topologies))
+	root := topologies[""]
+	assert.NotNil(t, root)
+	assert.Equal(t, extension.RootQuotaName, root.Quota.Name)
+	var parent *core.QuotaTopologySummary
+	for _, child := range root.Children {
+		if child.Quota.Name == "parent1" {
+			parent = child
+		}
+	}
+	assert.NotNil(t, parent)
+	assert.Equal(t, 2, len(parent.Children))
+	assert.Equal(t, "child1", parent.Children[0].Quota.Name)
+	assert.Equal(t, "child2", parent.Children[1].Quota.Name)
+	assert.True(t, quotav1.Equals(createResourceList(5, 5), parent.Children[0].Quota.Min))
+
+	// the unknown tree is empty
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/quotaTopology?tree=not-exist", nil)
+	engine.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	topologies = map[string]*core.QuotaTopologySummary{}
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&topologies))
+	assert.Empty(t, topologies)
+}
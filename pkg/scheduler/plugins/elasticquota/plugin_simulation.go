@@ -0,0 +1,150 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/noderesources"
+)
+
+// SimulationResult is the result of simulating the scheduling of a pod over a candidate node set.
+type SimulationResult struct {
+	// FitsQuota indicates whether the pod would be admitted by its quota.
+	FitsQuota bool
+	// FitsNode indicates whether the pod fits at least one of the candidate nodes.
+	FitsNode bool
+	// NodeName is the first candidate node which can host the pod.
+	NodeName string
+	// Reasons explains why the pod does not fit, empty if it fits both.
+	Reasons []string
+}
+
+// Fits returns true if the pod fits both the quota and some node.
+func (r *SimulationResult) Fits() bool {
+	return r.FitsQuota && r.FitsNode
+}
+
+// WouldAdmit checks whether the pod would be admitted by its quota with the current quota state, without
+// writing any cycle state or granting the admission token. It performs the same checks as PreFilter except the
+// gang group reservation, which grants the tokens of the whole gang group.
+func (g *Plugin) WouldAdmit(pod *corev1.Pod) *framework.Status {
+	admission, status := g.resolvePodAdmission(pod)
+	if status.IsSkip() {
+		return framework.NewStatus(framework.Success, "")
+	}
+	if !status.IsSuccess() {
+		return status
+	}
+	if status = g.checkPodAdmission(admission, pod); !status.IsSuccess() {
+		return status
+	}
+
+	quotaInfo := admission.quotaInfo
+	handoffUsed := g.getQuotaHandoffUsed(quotaInfo, pod)
+	g.admissionTokenLock.Lock()
+	defer g.admissionTokenLock.Unlock()
+	status, _ = g.checkQuotaWithPendingAdmissionNoLock(admission.mgr, quotaInfo, pod, admission.podRequest, handoffUsed,
+		quotaInfo.GetNonPreemptibleUsed(), g.getQuotaInfoUsedLimit(quotaInfo))
+	return status
+}
+
+// QuotaAdmissionVerdict is the quota verdict of a pod, which lets the external schedulers consult the quota.
+type QuotaAdmissionVerdict struct {
+	Admitted bool     `json:"admitted"`
+	Quota    string   `json:"quota,omitempty"`
+	Tree     string   `json:"tree,omitempty"`
+	Code     string   `json:"code"`
+	Reasons  []string `json:"reasons,omitempty"`
+}
+
+// GetQuotaAdmissionVerdict returns whether the pod would be admitted by its quota with the current quota state.
+func (g *Plugin) GetQuotaAdmissionVerdict(pod *corev1.Pod) *QuotaAdmissionVerdict {
+	quotaName, treeID := g.getPodAssociateQuotaNameAndTreeID(pod)
+	status := g.WouldAdmit(pod)
+	return &QuotaAdmissionVerdict{
+		Admitted: status.IsSuccess(),
+		Quota:    quotaName,
+		Tree:     treeID,
+		Code:     status.Code().String(),
+		Reasons:  status.Reasons(),
+	}
+}
+
+// PreviewPostFilterUsed returns the used of the quota in the cycle state after hypothetically adding and then
+// removing the pods in one call, the key is the quota name. The pods are applied as AddPod and RemovePod do to
+// a copy of the post filter state, so the cycle state isn't changed, which lets an external preemption plugin
+// evaluate a set of victims at once. The result is empty if the pod of the cycle skips the quota.
+func (g *Plugin) PreviewPostFilterUsed(cycleState *framework.CycleState, podsToAdd, podsToRemove []*corev1.Pod) (map[string]corev1.ResourceList, error) {
+	postFilterState, err := getPostFilterState(cycleState)
+	if err != nil {
+		return nil, err
+	}
+	if postFilterState.skip {
+		return map[string]corev1.ResourceList{}, nil
+	}
+	if status := checkSnapshotRevision(postFilterState); !status.IsSuccess() {
+		return nil, status.AsError()
+	}
+
+	state := postFilterState.Clone().(*PostFilterState)
+	previewState := framework.NewCycleState()
+	previewState.Write(postFilterKey, state)
+	ctx := context.TODO()
+	for _, pod := range podsToAdd {
+		podInfo, _ := framework.NewPodInfo(pod)
+		if status := g.AddPod(ctx, previewState, nil, podInfo, nil); !status.IsSuccess() {
+			return nil, status.AsError()
+		}
+	}
+	for _, pod := range podsToRemove {
+		podInfo, _ := framework.NewPodInfo(pod)
+		if status := g.RemovePod(ctx, previewState, nil, podInfo, nil); !status.IsSuccess() {
+			return nil, status.AsError()
+		}
+	}
+	return map[string]corev1.ResourceList{state.quotaInfo.Name: state.used}, nil
+}
+
+// SimulateScheduling reports whether the pod fits its quota and fits some node of the candidate node set.
+// It's used for planning and doesn't change the quota or node state.
+func (g *Plugin) SimulateScheduling(pod *corev1.Pod, nodeInfos []*framework.NodeInfo) *SimulationResult {
+	result := &SimulationResult{}
+	if status := g.WouldAdmit(pod); status.IsSuccess() {
+		result.FitsQuota = true
+	} else {
+		result.Reasons = append(result.Reasons, status.Message())
+	}
+
+	for _, nodeInfo := range nodeInfos {
+		if nodeInfo == nil || nodeInfo.Node() == nil {
+			continue
+		}
+		if len(noderesources.Fits(pod, nodeInfo)) == 0 {
+			result.FitsNode = true
+			result.NodeName = nodeInfo.Node().Name
+			break
+		}
+	}
+	if !result.FitsNode {
+		result.Reasons = append(result.Reasons, fmt.Sprintf("no node fits the pod in %d candidate nodes", len(nodeInfos)))
+	}
+	return result
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	quotav1 "k8s.io/apiserver/pkg/quota/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+func newSimulationNodeInfo(name string, cpu, mem int64) *framework.NodeInfo {
+	allocatable := createResourceList(cpu, mem)
+	allocatable[corev1.ResourcePods] = *resource.NewQuantity(110, resource.DecimalSI)
+	nodeInfo := framework.NewNodeInfo()
+	nodeInfo.SetNode(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.NodeStatus{
+			Allocatable: allocatable,
+			Capacity:    allocatable,
+		},
+	})
+	return nodeInfo
+}
+
+func TestPlugin_SimulateScheduling(t *testing.T) {
+	tests := []struct {
+		name      string
+		pod       *corev1.Pod
+		nodeInfos []*framework.NodeInfo
+		fitsQuota bool
+		fitsNode  bool
+		nodeName  string
+	}{
+		{
+			name: "fits quota and node",
+			pod:  defaultCreatePodWithQuotaAndNonPreemptible("pod1", "test1", 10, 5, 10, false),
+			nodeInfos: []*framework.NodeInfo{
+				newSimulationNodeInfo("node1", 2, 100),
+				newSimulationNodeInfo("node2", 50, 100),
+			},
+			fitsQuota: true,
+			fitsNode:  true,
+			nodeName:  "node2",
+		},
+		{
+			name: "fits quota but no node",
+			pod:  defaultCreatePodWithQuotaAndNonPreemptible("pod1", "test1", 10, 5, 10, false),
+			nodeInfos: []*framework.NodeInfo{
+				newSimulationNodeInfo("node1", 2, 100),
+				newSimulationNodeInfo("node2", 4, 100),
+			},
+			fitsQuota: true,
+			fitsNode:  false,
+		},
+		{
+			name: "fits node but not quota",
+			pod:  defaultCreatePodWithQuotaAndNonPreemptible("pod1", "test1", 10, 20, 10, false),
+			nodeInfos: []*framework.NodeInfo{
+				newSimulationNodeInfo("node1", 50, 100),
+			},
+			fitsQuota: false,
+			fitsNode:  true,
+			nodeName:  "node1",
+		},
+		{
+			name:      "no candidate nodes",
+			pod:       defaultCreatePodWithQuotaAndNonPreemptible("pod1", "test1", 10, 5, 10, false),
+			nodeInfos: nil,
+			fitsQuota: true,
+			fitsNode:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suit := newPluginTestSuit(t, nil)
+			p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+			assert.Nil(t, err)
+			gp := p.(*Plugin)
+			gp.pluginArgs.EnableRuntimeQuota = false
+			gp.addQuota("test1", extension.RootQuotaName, 10, 100, 10, 100, 10, 100, false, "", "")
+			tt.pod.Spec.NodeName = ""
+
+			result := gp.SimulateScheduling(tt.pod, tt.nodeInfos)
+			assert.Equal(t, tt.fitsQuota, result.FitsQuota)
+			assert.Equal(t, tt.fitsNode, result.FitsNode)
+			assert.Equal(t, tt.nodeName, result.NodeName)
+			assert.Equal(t, tt.fitsQuota && tt.fitsNode, result.Fits())
+			assert.Equal(t, result.Fits(), len(result.Reasons) == 0)
+			// simulation must not change the quota used
+			assert.True(t, quotav1.IsZero(gp.groupQuotaManager.GetQuotaInfoByName("test1").GetUsed()))
+		})
+	}
+}
+
+func TestPlugin_PreviewPostFilterUsed(t *testing.T) {
+	suit := newPluginTestSuit(t, nil)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.Nil(t, err)
+	gp := p.(*Plugin)
+	gp.OnQuotaAdd(CreateQuota2("test1", extension.RootQuotaName, 100, 1000, 10, 100, 100, 1000, false, ""))
+
+	makeQuotaPod := func(name string, cpu, mem int64, nodeName string) *corev1.Pod {
+		pod := MakePod("t1-ns1", name).UID(name).Label(extension.LabelQuotaName, "test1").Container(
+			createResourceList(cpu, mem)).Obj()
+		pod.Spec.NodeName = nodeName
+		return pod
+	}
+	podA := makeQuotaPod("pod-a", 10, 100, "node1")
+	podB := makeQuotaPod("pod-b", 20, 200, "node1")
+	podC := makeQuotaPod("pod-c", 5, 50, "")
+	for _, pod := range []*corev1.Pod{podA, podB, podC} {
+		gp.OnPodAdd(pod)
+	}
+	unknownPod := makeQuotaPod("pod-unknown", 40, 400, "node1")
+
+	state := framework.NewCycleState()
+	_, err = gp.PreviewPostFilterUsed(state, nil, nil)
+	assert.Error(t, err)
+
+	gp.snapshotPostFilterState(gp.groupQuotaManager.GetQuotaInfoByName("test1"), state)
+	used, err := gp.PreviewPostFilterUsed(state, nil, nil)
+	assert.NoError(t, err)
+	assert.True(t, quotav1.Equals(createResourceList(30, 300), used["test1"]))
+
+	// the pending pod is added, the running pods are removed, the pod unknown to the quota is ignored
+	used, err = gp.PreviewPostFilterUsed(state, []*corev1.Pod{podC}, []*corev1.Pod{podA, podB, unknownPod})
+	assert.NoError(t, err)
+	assert.Len(t, used, 1)
+	assert.True(t, quotav1.Equals(createResourceList(5, 50), used["test1"]))
+
+	// the cycle state isn't changed by the preview
+	postFilterState, err := getPostFilterState(state)
+	assert.NoError(t, err)
+	assert.True(t, quotav1.Equals(createResourceList(30, 300), postFilterState.used))
+
+	used, err = gp.PreviewPostFilterUsed(state, []*corev1.Pod{podC, podC}, []*corev1.Pod{podA})
+	assert.NoError(t, err)
+	assert.True(t, quotav1.Equals(createResourceList(30, 300), used["test1"]))
+
+	// the pod skipping the quota has nothing to preview
+	skipState := framework.NewCycleState()
+	gp.skipPostFilterState(skipState)
+	used, err = gp.PreviewPostFilterUsed(skipState, []*corev1.Pod{podC}, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, used)
+}
@@ -31,6 +31,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -41,6 +43,7 @@ import (
 	"k8s.io/client-go/informers"
 	kubefake "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 	schedulerconfig "k8s.io/kubernetes/pkg/scheduler/apis/config"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
@@ -180,11 +183,13 @@ func newPluginTestSuit(t *testing.T, nodes []*corev1.Node, pluginTestOpts ...Plu
 		flag.StringVar(&token, "token", "mockTest", "")
 		flag.Parse()
 	}
+	fakeRecorder := record.NewFakeRecorder(1024)
 	fh, err := schedulertesting.NewFramework(
 		context.TODO(),
 		registeredPlugins,
 		"koord-scheduler",
 		runtime.WithClientSet(cs),
+		runtime.WithEventRecorder(record.NewEventRecorderAdapter(fakeRecorder)),
 		runtime.WithInformerFactory(informerFactory),
 		runtime.WithSnapshotSharedLister(snapshot),
 		runtime.WithKubeConfig(cfg),
@@ -196,6 +201,7 @@ func newPluginTestSuit(t *testing.T, nodes []*corev1.Node, pluginTestOpts ...Plu
 		proxyNew:                         proxyNew,
 		elasticQuotaArgs:                 &elasticQuotaArgs,
 		client:                           pgClientSet,
+		fakeRecorder:                     fakeRecorder,
 	}
 }
 
@@ -262,11 +268,13 @@ func newPluginTestSuitWithPod(t *testing.T, nodes []*corev1.Node, pods []*corev1
 		flag.StringVar(&token, "token", "mockTest", "")
 		flag.Parse()
 	}
+	fakeRecorder := record.NewFakeRecorder(1024)
 	fh, err := schedulertesting.NewFramework(
 		context.TODO(),
 		registeredPlugins,
 		"koord-scheduler",
 		runtime.WithClientSet(cs),
+		runtime.WithEventRecorder(record.NewEventRecorderAdapter(fakeRecorder)),
 		runtime.WithInformerFactory(informerFactory),
 		runtime.WithSnapshotSharedLister(snapshot),
 		runtime.WithKubeConfig(cfg),
@@ -279,6 +287,7 @@ func newPluginTestSuitWithPod(t *testing.T, nodes []*corev1.Node, pods []*corev1
 		proxyNew:                         proxyNew,
 		elasticQuotaArgs:                 &elasticQuotaArgs,
 		client:                           pgClientSet,
+		fakeRecorder:                     fakeRecorder,
 		Framework:                        fh,
 	}
 }
@@ -354,6 +363,7 @@ type pluginTestSuit struct {
 	proxyNew                         runtime.PluginFactory
 	elasticQuotaArgs                 *config.ElasticQuotaArgs
 	client                           *pgfake.Clientset
+	fakeRecorder                     *record.FakeRecorder
 }
 
 func TestNew(t *testing.T) {
@@ -714,6 +724,29 @@ func TestPlugin_PreFilter(t *testing.T) {
 	}
 }
 
+func TestPlugin_PreFilter_GPUResources(t *testing.T) {
+	suit := newPluginTestSuit(t, nil)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.Nil(t, err)
+	gp := p.(*Plugin)
+	gp.pluginArgs.EnableRuntimeQuota = false
+
+	quota := CreateQuota2("test", extension.RootQuotaName, 10, 20, 0, 0, 10, 20, false, "")
+	quota.Spec.Max[extension.ResourceGPUMemory] = resource.MustParse("8Gi")
+	gp.OnQuotaAdd(quota)
+
+	// the deprecated gpu memory of the pod is checked against the koordinator gpu memory of the quota
+	pod := MakePod("t1-ns1", "pod1").Label(extension.LabelQuotaName, "test").Container(corev1.ResourceList{
+		corev1.ResourceCPU:            *resource.NewMilliQuantity(20*1000, resource.DecimalSI),
+		corev1.ResourceMemory:         *resource.NewQuantity(2, resource.BinarySI),
+		extension.DeprecatedGPUMemory: resource.MustParse("16Gi"),
+	}).Obj()
+	_, status := gp.PreFilter(context.TODO(), framework.NewCycleState(), pod)
+	assert.Equal(t, framework.Unschedulable, status.Code())
+	assert.Contains(t, status.Message(), "koordinator.sh/gpu-memory:16Gi")
+	assert.Contains(t, status.Message(), "exceedDimensions: [cpu koordinator.sh/gpu-memory]")
+}
+
 func TestPlugin_PreFilter_CheckParent(t *testing.T) {
 	test := []struct {
 		name           string
@@ -776,8 +809,8 @@ func TestPlugin_PreFilter_CheckParent(t *testing.T) {
 			qi1.CalculateInfo.Runtime = tt.parentRuntime.DeepCopy()
 			qi1.UnLock()
 			podRequests := core.PodRequests(tt.pod)
-			status := *gp.checkQuotaRecursive(gp.groupQuotaManager, tt.quotaInfo.Name, []string{tt.quotaInfo.Name}, podRequests)
-			assert.Equal(t, tt.expectedStatus, status)
+			status, _ := gp.checkQuotaRecursive(gp.groupQuotaManager, tt.quotaInfo.Name, []string{tt.quotaInfo.Name}, podRequests, nil)
+			assert.Equal(t, tt.expectedStatus, *status)
 		})
 	}
 }
@@ -890,6 +923,55 @@ func TestPlugin_Prefilter_QuotaNonPreempt(t *testing.T) {
 	}
 }
 
+func TestPlugin_Prefilter_QuotaNonPreemptBeyondMin(t *testing.T) {
+	test := []struct {
+		name                         string
+		allowNonPreemptibleBeyondMin bool
+		expectedStatus               *framework.Status
+	}{
+		{
+			name: "non-preemptible pods are limited by min by default",
+			expectedStatus: framework.NewStatus(framework.Unschedulable,
+				fmt.Sprintf("Insufficient non-preemptible quotas, "+
+					"quotaName: %v, min: %v, nonPreemptibleUsed: %v, pod's request: %v, exceedDimensions: [cpu]",
+					"test1", printResourceList(MakeResourceList().CPU(5).Mem(5).Obj()),
+					printResourceList(MakeResourceList().CPU(4).Mem(2).Obj()), printResourceList(MakeResourceList().CPU(2).Mem(2).Obj()))),
+		},
+		{
+			name:                         "non-preemptible pods beyond min are allowed",
+			allowNonPreemptibleBeyondMin: true,
+			expectedStatus:               framework.NewStatus(framework.Success, ""),
+		},
+	}
+	for _, tt := range test {
+		t.Run(tt.name, func(t *testing.T) {
+			suit := newPluginTestSuit(t, nil)
+			suit.elasticQuotaArgs.AllowNonPreemptibleBeyondMin = tt.allowNonPreemptibleBeyondMin
+			p, _ := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+			gp := p.(*Plugin)
+			gp.groupQuotaManager.UpdateClusterTotalResource(createResourceList(8, 5))
+			gp.OnQuotaAdd(&v1alpha1.ElasticQuota{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test1",
+				},
+				Spec: v1alpha1.ElasticQuotaSpec{
+					Max: MakeResourceList().CPU(10).Mem(8).Obj(),
+					Min: MakeResourceList().CPU(5).Mem(5).Obj(),
+				},
+			})
+			gp.OnPodAdd(defaultCreatePodWithQuotaAndNonPreemptible("1", "test1", 10, 2, 1, false))
+			gp.OnPodAdd(defaultCreatePodWithQuotaAndNonPreemptible("2", "test1", 9, 2, 1, true))
+			gp.OnPodAdd(defaultCreatePodWithQuotaAndNonPreemptible("3", "test1", 9, 2, 1, true))
+			pod := defaultCreatePodWithQuotaAndNonPreemptible("4", "test1", 1, 2, 2, true)
+			pod.Spec.NodeName = ""
+			gp.OnPodAdd(pod)
+
+			_, status := gp.PreFilter(context.TODO(), framework.NewCycleState(), pod)
+			assert.Equal(t, tt.expectedStatus, status)
+		})
+	}
+}
+
 func TestPlugin_Reserve(t *testing.T) {
 	test := []struct {
 		name         string
@@ -1015,6 +1097,47 @@ func TestPlugin_AddPod(t *testing.T) {
 	}
 }
 
+func TestPlugin_SnapshotRevision_QuotaChanged(t *testing.T) {
+	suit := newPluginTestSuit(t, nil)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.Nil(t, err)
+	gp := p.(*Plugin)
+	quota := CreateQuota2("test1", extension.RootQuotaName, 100, 1000, 10, 100, 100, 1000, false, "")
+	gp.OnQuotaAdd(quota)
+	podInfo := &framework.PodInfo{
+		Pod: MakePod("t1-ns1", "pod1").Container(createResourceList(1, 10)).
+			Label(extension.LabelQuotaName, "test1").UID("1").Obj(),
+	}
+	gp.OnPodAdd(podInfo.Pod)
+	quotaInfo := gp.groupQuotaManager.GetQuotaInfoByName("test1")
+	preemptor := MakePod("t1-ns1", "pod2").Container(createResourceList(1, 10)).
+		Label(extension.LabelQuotaName, "test1").UID("2").Obj()
+
+	state := framework.NewCycleState()
+	gp.snapshotPostFilterState(quotaInfo, state)
+
+	// the max of the quota changes after the snapshot
+	newQuota := quota.DeepCopy()
+	newQuota.Spec.Max = createResourceList(50, 500)
+	gp.OnQuotaUpdate(quota, newQuota)
+
+	// AddPod and RemovePod never fail, otherwise the framework aborts the whole PostFilter with an Error
+	assert.True(t, gp.AddPod(context.TODO(), state, preemptor, podInfo, nil).IsSuccess())
+	assert.True(t, gp.RemovePod(context.TODO(), state, preemptor, podInfo, nil).IsSuccess())
+	// the stale snapshot only makes the node unschedulable
+	_, _, status := gp.SelectVictimsOnNode(context.TODO(), state, preemptor, framework.NewNodeInfo(), nil)
+	assert.Equal(t, framework.Unschedulable, status.Code())
+	_, err = gp.PreviewPostFilterUsed(state, nil, []*corev1.Pod{podInfo.Pod})
+	assert.ErrorContains(t, err, "changed during the scheduling cycle")
+
+	// the new snapshot is consistent with the quota
+	state = framework.NewCycleState()
+	gp.snapshotPostFilterState(quotaInfo, state)
+	used, err := gp.PreviewPostFilterUsed(state, nil, []*corev1.Pod{podInfo.Pod})
+	assert.NoError(t, err)
+	assert.True(t, quotav1.IsZero(used["test1"]))
+}
+
 func TestPlugin_RemovePod(t *testing.T) {
 	test := []struct {
 		name              string
@@ -1292,6 +1415,30 @@ func TestPlugin_migrateDefaultQuotaGroupsPod(t *testing.T) {
 	assert.Equal(t, 4, len(gqm.GetQuotaInfoByName("test1").PodCache))
 }
 
+func TestPlugin_migrateDefaultQuotaGroupsPod_PinnedPod(t *testing.T) {
+	suit := newPluginTestSuit(t, nil)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.Nil(t, err)
+	plugin := p.(*Plugin)
+	gqm := plugin.groupQuotaManager
+	pinnedPod := defaultCreatePodWithQuotaName("1", "test1", 10, 10, 10)
+	pinnedPod.Annotations = map[string]string{extension.AnnotationPinQuota: "true"}
+	pod := defaultCreatePodWithQuotaName("2", "test1", 10, 10, 10)
+	plugin.OnPodAdd(pinnedPod)
+	plugin.OnPodAdd(pod)
+	assert.Equal(t, 2, len(gqm.GetQuotaInfoByName(extension.DefaultQuotaName).GetPodCache()))
+
+	plugin.addQuota("test1", extension.RootQuotaName, 96, 160, 100, 160, 96, 160, true, "", "")
+	plugin.migrateDefaultQuotaGroupsPod()
+
+	defaultPods := gqm.GetQuotaInfoByName(extension.DefaultQuotaName).GetPodCache()
+	assert.Equal(t, 1, len(defaultPods))
+	assert.NotNil(t, defaultPods[pinnedPod.Namespace+"/"+pinnedPod.Name])
+	test1Pods := gqm.GetQuotaInfoByName("test1").GetPodCache()
+	assert.Equal(t, 1, len(test1Pods))
+	assert.NotNil(t, test1Pods[pod.Namespace+"/"+pod.Name])
+}
+
 func defaultCreatePodWithQuotaNameAndVersion(name, quotaName, version string, priority int32, cpu, mem int64) *corev1.Pod {
 	pod := defaultCreatePod(name, priority, cpu, mem)
 	pod.Labels[extension.LabelQuotaName] = quotaName
@@ -1369,3 +1516,742 @@ func TestPostFilterState(t *testing.T) {
 		assert.Equal(t, got, got1)
 	})
 }
+
+func TestPlugin_FilterAntiAffinityQuotas(t *testing.T) {
+	suit := newPluginTestSuit(t, nil)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.Nil(t, err)
+	gp := p.(*Plugin)
+	gp.pluginArgs.EnableRuntimeQuota = false
+
+	quotaA := CreateQuota2("quota-a", extension.RootQuotaName, 100, 1000, 10, 100, 10, 100, false, "")
+	quotaA.Annotations[extension.AnnotationAntiAffinityQuotas] = `["quota-b"]`
+	gp.OnQuotaAdd(quotaA)
+	gp.addQuota("quota-b", extension.RootQuotaName, 100, 1000, 10, 100, 10, 100, false, "", "")
+	gp.addQuota("quota-c", extension.RootQuotaName, 100, 1000, 10, 100, 10, 100, false, "", "")
+
+	newNodeInfo := func(name string, pods ...*corev1.Pod) *framework.NodeInfo {
+		nodeInfo := framework.NewNodeInfo(pods...)
+		nodeInfo.SetNode(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}})
+		return nodeInfo
+	}
+	nodeWithQuotaB := newNodeInfo("node-b", defaultCreatePodWithQuotaName("pod-b", "quota-b", 10, 1, 1))
+	nodeWithQuotaC := newNodeInfo("node-c", defaultCreatePodWithQuotaName("pod-c", "quota-c", 10, 1, 1))
+	emptyNode := newNodeInfo("node-empty")
+
+	tests := []struct {
+		name         string
+		pod          *corev1.Pod
+		nodeInfo     *framework.NodeInfo
+		expectStatus *framework.Status
+	}{
+		{
+			name:     "avoid the node running the anti-affinity quota's pods",
+			pod:      defaultCreatePodWithQuotaAndNonPreemptible("pod-a", "quota-a", 10, 1, 1, false),
+			nodeInfo: nodeWithQuotaB,
+			expectStatus: framework.NewStatus(framework.UnschedulableAndUnresolvable,
+				"node(s) had pods of the anti-affinity quota quota-b"),
+		},
+		{
+			name:     "node running other quota's pods",
+			pod:      defaultCreatePodWithQuotaAndNonPreemptible("pod-a", "quota-a", 10, 1, 1, false),
+			nodeInfo: nodeWithQuotaC,
+		},
+		{
+			name:     "empty node",
+			pod:      defaultCreatePodWithQuotaAndNonPreemptible("pod-a", "quota-a", 10, 1, 1, false),
+			nodeInfo: emptyNode,
+		},
+		{
+			name:     "quota without anti-affinity",
+			pod:      defaultCreatePodWithQuotaAndNonPreemptible("pod-c2", "quota-c", 10, 1, 1, false),
+			nodeInfo: nodeWithQuotaB,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cycleState := framework.NewCycleState()
+			_, status := gp.PreFilter(context.TODO(), cycleState, tt.pod)
+			assert.True(t, status.IsSuccess())
+			status = gp.Filter(context.TODO(), cycleState, tt.pod, tt.nodeInfo)
+			assert.Equal(t, tt.expectStatus, status)
+		})
+	}
+
+	// the anti-affinity is updated with the quota
+	newQuotaA := quotaA.DeepCopy()
+	newQuotaA.Annotations[extension.AnnotationAntiAffinityQuotas] = `["quota-c"]`
+	gp.OnQuotaUpdate(quotaA, newQuotaA)
+	cycleState := framework.NewCycleState()
+	pod := defaultCreatePodWithQuotaAndNonPreemptible("pod-a", "quota-a", 10, 1, 1, false)
+	_, status := gp.PreFilter(context.TODO(), cycleState, pod)
+	assert.True(t, status.IsSuccess())
+	assert.True(t, gp.Filter(context.TODO(), cycleState, pod, nodeWithQuotaB).IsSuccess())
+	assert.False(t, gp.Filter(context.TODO(), cycleState, pod, nodeWithQuotaC).IsSuccess())
+}
+
+func TestPlugin_PreFilter_ExceedDimensionOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		order    []string
+		expected string
+	}{
+		{
+			name:     "sorted by name by default",
+			expected: "exceedDimensions: [cpu koordinator.sh/gpu-memory memory]",
+		},
+		{
+			name:     "memory first",
+			order:    []string{string(corev1.ResourceMemory)},
+			expected: "exceedDimensions: [memory cpu koordinator.sh/gpu-memory]",
+		},
+		{
+			name:     "fully ordered",
+			order:    []string{string(extension.ResourceGPUMemory), string(corev1.ResourceMemory), string(corev1.ResourceCPU)},
+			expected: "exceedDimensions: [koordinator.sh/gpu-memory memory cpu]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suit := newPluginTestSuit(t, nil)
+			p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+			assert.Nil(t, err)
+			gp := p.(*Plugin)
+			gp.pluginArgs.EnableRuntimeQuota = false
+			gp.pluginArgs.ExceedDimensionOrder = tt.order
+
+			quota := CreateQuota2("test", extension.RootQuotaName, 10, 20, 0, 0, 10, 20, false, "")
+			quota.Spec.Max[extension.ResourceGPUMemory] = resource.MustParse("8Gi")
+			gp.OnQuotaAdd(quota)
+
+			pod := MakePod("t1-ns1", "pod1").Label(extension.LabelQuotaName, "test").Container(corev1.ResourceList{
+				corev1.ResourceCPU:          *resource.NewMilliQuantity(20*1000, resource.DecimalSI),
+				corev1.ResourceMemory:       *resource.NewQuantity(40, resource.BinarySI),
+				extension.ResourceGPUMemory: resource.MustParse("16Gi"),
+			}).Obj()
+			_, status := gp.PreFilter(context.TODO(), framework.NewCycleState(), pod)
+			assert.Equal(t, framework.Unschedulable, status.Code())
+			assert.Contains(t, status.Message(), tt.expected)
+		})
+	}
+}
+
+func TestPlugin_PreFilter_BypassNamespaces(t *testing.T) {
+	suit := newPluginTestSuit(t, nil, func(elasticQuotaArgs *config.ElasticQuotaArgs) {
+		elasticQuotaArgs.BypassNamespaces = []string{"kube-system"}
+	})
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.Nil(t, err)
+	gp := p.(*Plugin)
+	gp.pluginArgs.EnableRuntimeQuota = false
+	gp.addQuota("test1", extension.RootQuotaName, 10, 10, 10, 10, 10, 10, false, "", "")
+
+	// the pod of the bypassed namespace is admitted unconditionally even if it exceeds its quota
+	bypassedPod := MakePod("kube-system", "pod1").Label(extension.LabelQuotaName, "test1").Container(
+		MakeResourceList().CPU(100).Mem(100).Obj()).Obj()
+	_, status := gp.PreFilter(context.TODO(), framework.NewCycleState(), bypassedPod)
+	assert.True(t, status.IsSkip())
+	assert.True(t, gp.WouldAdmit(bypassedPod).IsSuccess())
+
+	// the pod is accounted in the system quota
+	bypassedPod.Spec.NodeName = "node1"
+	gp.OnPodAdd(bypassedPod)
+	assert.Equal(t, extension.SystemQuotaName, gp.GetQuotaName(bypassedPod))
+	systemQuotaInfo := gp.groupQuotaManager.GetQuotaInfoByName(extension.SystemQuotaName)
+	assert.True(t, quotav1.Equals(MakeResourceList().CPU(100).Mem(100).Obj(), systemQuotaInfo.GetUsed()))
+	assert.True(t, quotav1.IsZero(gp.groupQuotaManager.GetQuotaInfoByName("test1").GetUsed()))
+
+	// the pod of other namespaces is still checked by its quota
+	pod := MakePod("t1-ns1", "pod2").Label(extension.LabelQuotaName, "test1").Container(
+		MakeResourceList().CPU(100).Mem(100).Obj()).Obj()
+	_, status = gp.PreFilter(context.TODO(), framework.NewCycleState(), pod)
+	assert.False(t, status.IsSuccess())
+	assert.False(t, status.IsSkip())
+}
+
+func TestPlugin_PreFilter_SuspendedQuota(t *testing.T) {
+	suit := newPluginTestSuit(t, nil)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.Nil(t, err)
+	gp := p.(*Plugin)
+	gp.pluginArgs.EnableRuntimeQuota = false
+	quota := CreateQuota2("test1", extension.RootQuotaName, 100, 1000, 10, 100, 10, 100, false, "")
+	gp.OnQuotaAdd(quota)
+
+	runningPod := MakePod("t1-ns1", "pod1").Label(extension.LabelQuotaName, "test1").UID("pod1").Container(
+		MakeResourceList().CPU(10).Mem(10).Obj()).Obj()
+	runningPod.Spec.NodeName = "node1"
+	gp.OnPodAdd(runningPod)
+	pod := MakePod("t1-ns1", "pod2").Label(extension.LabelQuotaName, "test1").Container(
+		MakeResourceList().CPU(1).Mem(1).Obj()).Obj()
+	_, status := gp.PreFilter(context.TODO(), framework.NewCycleState(), pod)
+	assert.True(t, status.IsSuccess())
+
+	// the suspended quota rejects the new pods
+	suspended := quota.DeepCopy()
+	suspended.Labels[extension.LabelQuotaSuspend] = "true"
+	gp.OnQuotaUpdate(quota, suspended)
+	_, status = gp.PreFilter(context.TODO(), framework.NewCycleState(), pod)
+	assert.Equal(t, framework.Unschedulable, status.Code())
+	assert.Equal(t, "Quota test1 is suspended and doesn't admit new pods", status.Message())
+	// the admission verdict agrees with PreFilter
+	status = gp.WouldAdmit(pod)
+	assert.Equal(t, framework.Unschedulable, status.Code())
+	assert.Equal(t, "Quota test1 is suspended and doesn't admit new pods", status.Message())
+
+	// the running pods are still accounted, and the suspension is visible in the summary
+	summary, ok := gp.GetQuotaSummary("test1", false)
+	assert.True(t, ok)
+	assert.True(t, summary.Suspended)
+	assert.True(t, quotav1.Equals(MakeResourceList().CPU(10).Mem(10).Obj(), summary.Used))
+
+	// the quota is resumed
+	gp.OnQuotaUpdate(suspended, quota)
+	assert.True(t, gp.WouldAdmit(pod).IsSuccess())
+	_, status = gp.PreFilter(context.TODO(), framework.NewCycleState(), pod)
+	assert.True(t, status.IsSuccess())
+}
+
+func TestPlugin_UnlabeledPodPolicies(t *testing.T) {
+	suit := newPluginTestSuit(t, nil, func(elasticQuotaArgs *config.ElasticQuotaArgs) {
+		elasticQuotaArgs.UnlabeledPodPolicies = map[string]config.UnlabeledPodPolicy{
+			"ns-default": config.UnlabeledPodPolicyDefault,
+			"ns-system":  config.UnlabeledPodPolicySystem,
+			"ns-reject":  config.UnlabeledPodPolicyReject,
+		}
+	})
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.Nil(t, err)
+	gp := p.(*Plugin)
+	gp.pluginArgs.EnableRuntimeQuota = false
+	gp.addQuota("test1", extension.RootQuotaName, 10, 10, 10, 10, 10, 10, false, "", "")
+
+	tests := []struct {
+		name            string
+		pod             *corev1.Pod
+		expectQuotaName string
+		expectRejected  bool
+	}{
+		{
+			name:            "namespace without policy goes to the default quota",
+			pod:             MakePod("ns-other", "pod1").Container(MakeResourceList().CPU(1).Mem(1).Obj()).Obj(),
+			expectQuotaName: extension.DefaultQuotaName,
+		},
+		{
+			name:            "default policy",
+			pod:             MakePod("ns-default", "pod1").Container(MakeResourceList().CPU(1).Mem(1).Obj()).Obj(),
+			expectQuotaName: extension.DefaultQuotaName,
+		},
+		{
+			name:            "system policy",
+			pod:             MakePod("ns-system", "pod1").Container(MakeResourceList().CPU(1).Mem(1).Obj()).Obj(),
+			expectQuotaName: extension.SystemQuotaName,
+		},
+		{
+			name:            "reject policy",
+			pod:             MakePod("ns-reject", "pod1").Container(MakeResourceList().CPU(1).Mem(1).Obj()).Obj(),
+			expectQuotaName: "",
+			expectRejected:  true,
+		},
+		{
+			name: "labeled pod ignores the policy",
+			pod: MakePod("ns-reject", "pod2").Label(extension.LabelQuotaName, "test1").Container(
+				MakeResourceList().CPU(1).Mem(1).Obj()).Obj(),
+			expectQuotaName: "test1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectQuotaName, gp.getPodAssociateQuotaName(tt.pod))
+			assert.Equal(t, tt.expectRejected, gp.isUnlabeledPodRejected(tt.pod))
+			_, status := gp.PreFilter(context.TODO(), framework.NewCycleState(), tt.pod)
+			if tt.expectRejected {
+				assert.Equal(t, framework.UnschedulableAndUnresolvable, status.Code())
+			} else {
+				assert.True(t, status.IsSuccess())
+			}
+		})
+	}
+}
+
+func TestPlugin_MirrorPodAccounting(t *testing.T) {
+	suit := newPluginTestSuit(t, nil)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.Nil(t, err)
+	gp := p.(*Plugin)
+	gp.pluginArgs.EnableRuntimeQuota = false
+	gp.addQuota("test1", extension.RootQuotaName, 10, 10, 10, 10, 10, 10, false, "", "")
+
+	// the mirror pod is accounted in the system quota even if it declares a user quota
+	mirrorPod := MakePod("t1-ns1", "static-pod").Label(extension.LabelQuotaName, "test1").Container(
+		MakeResourceList().CPU(5).Mem(5).Obj()).Obj()
+	mirrorPod.Annotations = map[string]string{corev1.MirrorPodAnnotationKey: "mirror"}
+	mirrorPod.Spec.NodeName = "node1"
+	gp.OnPodAdd(mirrorPod)
+	assert.Equal(t, extension.SystemQuotaName, gp.GetQuotaName(mirrorPod))
+	systemQuotaInfo := gp.groupQuotaManager.GetQuotaInfoByName(extension.SystemQuotaName)
+	assert.True(t, quotav1.Equals(MakeResourceList().CPU(5).Mem(5).Obj(), systemQuotaInfo.GetUsed()))
+	assert.True(t, systemQuotaInfo.IsPodExist(mirrorPod))
+	quotaInfo := gp.groupQuotaManager.GetQuotaInfoByName("test1")
+	assert.True(t, quotav1.IsZero(quotaInfo.GetUsed()))
+	assert.False(t, quotaInfo.IsPodExist(mirrorPod))
+
+	// the regular pod of the quota is still counted
+	pod := MakePod("t1-ns1", "pod1").Label(extension.LabelQuotaName, "test1").Container(
+		MakeResourceList().CPU(5).Mem(5).Obj()).Obj()
+	pod.Spec.NodeName = "node1"
+	gp.OnPodAdd(pod)
+	assert.Equal(t, "test1", gp.GetQuotaName(pod))
+	assert.True(t, quotav1.Equals(MakeResourceList().CPU(5).Mem(5).Obj(), gp.groupQuotaManager.GetQuotaInfoByName("test1").GetUsed()))
+}
+
+func TestPlugin_PreFilter_ExceedTolerance(t *testing.T) {
+	tests := []struct {
+		name            string
+		tolerance       int64
+		cpu             int64
+		expectedSuccess bool
+	}{
+		{
+			name:            "no tolerance, within max",
+			tolerance:       0,
+			cpu:             100,
+			expectedSuccess: true,
+		},
+		{
+			name:            "no tolerance, marginally exceed max",
+			tolerance:       0,
+			cpu:             101,
+			expectedSuccess: false,
+		},
+		{
+			name:            "1% tolerance, at the tolerance boundary",
+			tolerance:       1,
+			cpu:             101,
+			expectedSuccess: true,
+		},
+		{
+			name:            "1% tolerance, beyond the tolerance boundary",
+			tolerance:       1,
+			cpu:             102,
+			expectedSuccess: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suit := newPluginTestSuit(t, nil, func(elasticQuotaArgs *config.ElasticQuotaArgs) {
+				elasticQuotaArgs.ExceedTolerancePercent = tt.tolerance
+			})
+			p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+			assert.Nil(t, err)
+			gp := p.(*Plugin)
+			gp.pluginArgs.EnableRuntimeQuota = false
+			gp.addQuota("test1", extension.RootQuotaName, 100, 1000, 10, 100, 10, 100, false, "", "")
+
+			pod := MakePod("t1-ns1", "pod1").Label(extension.LabelQuotaName, "test1").Container(
+				createResourceList(tt.cpu, 100)).Obj()
+			_, status := gp.PreFilter(context.TODO(), framework.NewCycleState(), pod)
+			assert.Equal(t, tt.expectedSuccess, status.IsSuccess(), status.Message())
+		})
+	}
+}
+
+func TestPlugin_PreFilter_RequiredPodLabels(t *testing.T) {
+	tests := []struct {
+		name            string
+		requiredLabels  string
+		podLabels       map[string]string
+		expectedSuccess bool
+	}{
+		{
+			name:            "no required labels",
+			expectedSuccess: true,
+		},
+		{
+			name:            "compliant pod",
+			requiredLabels:  `{"team":"ml","cost-center":""}`,
+			podLabels:       map[string]string{"team": "ml", "cost-center": "cc-1"},
+			expectedSuccess: true,
+		},
+		{
+			name:            "missing required label",
+			requiredLabels:  `{"team":"ml","cost-center":""}`,
+			podLabels:       map[string]string{"team": "ml"},
+			expectedSuccess: false,
+		},
+		{
+			name:            "mismatched label value",
+			requiredLabels:  `{"team":"ml"}`,
+			podLabels:       map[string]string{"team": "web"},
+			expectedSuccess: false,
+		},
+		{
+			name:            "invalid annotation requires nothing",
+			requiredLabels:  `["team"]`,
+			expectedSuccess: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suit := newPluginTestSuit(t, nil)
+			p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+			assert.Nil(t, err)
+			gp := p.(*Plugin)
+			quota := CreateQuota2("test1", extension.RootQuotaName, 100, 1000, 100, 1000, 100, 1000, false, "")
+			if tt.requiredLabels != "" {
+				quota.Annotations[extension.AnnotationRequiredPodLabels] = tt.requiredLabels
+			}
+			gp.OnQuotaAdd(quota)
+
+			pod := MakePod("t1-ns1", "pod1").Label(extension.LabelQuotaName, "test1").Container(
+				createResourceList(10, 100)).Obj()
+			for k, v := range tt.podLabels {
+				pod.Labels[k] = v
+			}
+			_, status := gp.PreFilter(context.TODO(), framework.NewCycleState(), pod)
+			assert.Equal(t, tt.expectedSuccess, status.IsSuccess(), status.Message())
+			if !tt.expectedSuccess {
+				assert.Equal(t, framework.UnschedulableAndUnresolvable, status.Code())
+			}
+		})
+	}
+}
+
+func TestPlugin_PreFilter_RejectPodsWithoutRequests(t *testing.T) {
+	tests := []struct {
+		name                      string
+		rejectPodsWithoutRequests bool
+		quotaName                 string
+		requests                  corev1.ResourceList
+		expectedSuccess           bool
+	}{
+		{
+			name:            "pod without requests is admitted by default",
+			quotaName:       "test1",
+			expectedSuccess: true,
+		},
+		{
+			name:                      "pod with requests",
+			rejectPodsWithoutRequests: true,
+			quotaName:                 "test1",
+			requests:                  createResourceList(10, 100),
+			expectedSuccess:           true,
+		},
+		{
+			name:                      "pod without requests",
+			rejectPodsWithoutRequests: true,
+			quotaName:                 "test1",
+			expectedSuccess:           false,
+		},
+		{
+			name:                      "pod requesting only the dimensions not tracked by the quota",
+			rejectPodsWithoutRequests: true,
+			quotaName:                 "test1",
+			requests:                  corev1.ResourceList{corev1.ResourceEphemeralStorage: resource.MustParse("1Gi")},
+			expectedSuccess:           false,
+		},
+		{
+			name:                      "pod of the system quota is exempt",
+			rejectPodsWithoutRequests: true,
+			quotaName:                 extension.SystemQuotaName,
+			expectedSuccess:           true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suit := newPluginTestSuit(t, nil)
+			suit.elasticQuotaArgs.RejectPodsWithoutRequests = tt.rejectPodsWithoutRequests
+			p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+			assert.Nil(t, err)
+			gp := p.(*Plugin)
+			gp.OnQuotaAdd(CreateQuota2("test1", extension.RootQuotaName, 100, 1000, 100, 1000, 100, 1000, false, ""))
+
+			pod := MakePod("t1-ns1", "pod1").Label(extension.LabelQuotaName, tt.quotaName).Container(tt.requests).Obj()
+			_, status := gp.PreFilter(context.TODO(), framework.NewCycleState(), pod)
+			assert.Equal(t, tt.expectedSuccess, status.IsSuccess(), status.Message())
+			if !tt.expectedSuccess {
+				assert.Equal(t, framework.Unschedulable, status.Code())
+			}
+		})
+	}
+}
+
+func TestPlugin_PreFilter_Reserved(t *testing.T) {
+	tests := []struct {
+		name               string
+		reserved           string
+		enableRuntimeQuota bool
+		cpu                int64
+		expectedSuccess    bool
+	}{
+		{
+			name:            "no reserved, within max",
+			cpu:             100,
+			expectedSuccess: true,
+		},
+		{
+			name:            "reserved, within the remaining max",
+			reserved:        `{"cpu":"20"}`,
+			cpu:             80,
+			expectedSuccess: true,
+		},
+		{
+			name:            "reserved, exceed the remaining max",
+			reserved:        `{"cpu":"20"}`,
+			cpu:             81,
+			expectedSuccess: false,
+		},
+		{
+			name:            "reserved dimension not in max is ignored",
+			reserved:        `{"nvidia.com/gpu":"2"}`,
+			cpu:             100,
+			expectedSuccess: true,
+		},
+		{
+			name:            "reserved larger than max leaves nothing",
+			reserved:        `{"cpu":"200"}`,
+			cpu:             1,
+			expectedSuccess: false,
+		},
+		{
+			name:               "reserved is subtracted from runtime",
+			reserved:           `{"cpu":"20"}`,
+			enableRuntimeQuota: true,
+			cpu:                81,
+			expectedSuccess:    false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suit := newPluginTestSuit(t, nil)
+			p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+			assert.Nil(t, err)
+			gp := p.(*Plugin)
+			gp.pluginArgs.EnableRuntimeQuota = tt.enableRuntimeQuota
+			quota := CreateQuota2("test1", extension.RootQuotaName, 100, 1000, 100, 1000, 100, 1000, false, "")
+			if tt.reserved != "" {
+				quota.Annotations[extension.AnnotationReserved] = tt.reserved
+			}
+			gp.OnQuotaAdd(quota)
+			if tt.enableRuntimeQuota {
+				gp.groupQuotaManager.UpdateClusterTotalResource(createResourceList(1000, 10000))
+				pod := MakePod("t1-ns1", "pending").Label(extension.LabelQuotaName, "test1").Container(
+					createResourceList(100, 1000)).Obj()
+				gp.OnPodAdd(pod)
+			}
+
+			pod := MakePod("t1-ns1", "pod1").Label(extension.LabelQuotaName, "test1").Container(
+				createResourceList(tt.cpu, 100)).Obj()
+			_, status := gp.PreFilter(context.TODO(), framework.NewCycleState(), pod)
+			assert.Equal(t, tt.expectedSuccess, status.IsSuccess(), status.Message())
+		})
+	}
+}
+
+func TestPlugin_PreFilter_ResourceGroups(t *testing.T) {
+	tests := []struct {
+		name            string
+		resourceGroups  string
+		cpu             int64
+		gpu             int64
+		expectedSuccess bool
+	}{
+		{
+			name:            "no resource groups, cpu shortfall",
+			cpu:             50,
+			gpu:             2,
+			expectedSuccess: false,
+		},
+		{
+			name:            "gpu-only quota ignores cpu shortfall",
+			resourceGroups:  `["accelerator"]`,
+			cpu:             50,
+			gpu:             2,
+			expectedSuccess: true,
+		},
+		{
+			name:            "gpu-only quota enforces gpu",
+			resourceGroups:  `["accelerator"]`,
+			cpu:             1,
+			gpu:             8,
+			expectedSuccess: false,
+		},
+		{
+			name:            "compute-only quota ignores gpu shortfall",
+			resourceGroups:  `["compute"]`,
+			cpu:             1,
+			gpu:             8,
+			expectedSuccess: true,
+		},
+		{
+			name:            "unknown resource groups enforce all",
+			resourceGroups:  `["unknown"]`,
+			cpu:             50,
+			gpu:             2,
+			expectedSuccess: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suit := newPluginTestSuit(t, nil)
+			p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+			assert.Nil(t, err)
+			gp := p.(*Plugin)
+			gp.pluginArgs.EnableRuntimeQuota = false
+			quota := CreateQuota2("test1", extension.RootQuotaName, 10, 1000, 10, 1000, 10, 1000, false, "")
+			quota.Spec.Max[extension.ResourceNvidiaGPU] = *resource.NewQuantity(4, resource.DecimalSI)
+			if tt.resourceGroups != "" {
+				quota.Annotations[extension.AnnotationResourceGroups] = tt.resourceGroups
+			}
+			gp.OnQuotaAdd(quota)
+
+			request := createResourceList(tt.cpu, 100)
+			request[extension.ResourceNvidiaGPU] = *resource.NewQuantity(tt.gpu, resource.DecimalSI)
+			pod := MakePod("t1-ns1", "pod1").Label(extension.LabelQuotaName, "test1").Container(request).Obj()
+			// the admission verdict masks the dimensions out of the resource groups as PreFilter does
+			status := gp.WouldAdmit(pod)
+			assert.Equal(t, tt.expectedSuccess, status.IsSuccess(), status.Message())
+			_, status = gp.PreFilter(context.TODO(), framework.NewCycleState(), pod)
+			assert.Equal(t, tt.expectedSuccess, status.IsSuccess(), status.Message())
+		})
+	}
+}
+
+func TestPlugin_PreFilter_AdmissionMetrics(t *testing.T) {
+	suit := newPluginTestSuit(t, nil)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.Nil(t, err)
+	gp := p.(*Plugin)
+	gp.OnQuotaAdd(CreateQuota2("test-admission", extension.RootQuotaName, 100, 1000, 100, 1000, 100, 1000, false, ""))
+
+	pods := []*corev1.Pod{
+		MakePod("t1-ns1", "admitted-preemptible").Label(extension.LabelQuotaName, "test-admission").Container(
+			createResourceList(10, 100)).Obj(),
+		MakePod("t1-ns1", "admitted-non-preemptible").Label(extension.LabelQuotaName, "test-admission").
+			Label(extension.LabelPreemptible, "false").Container(createResourceList(10, 100)).Obj(),
+		MakePod("t1-ns1", "rejected-preemptible").Label(extension.LabelQuotaName, "test-admission").Container(
+			createResourceList(200, 100)).Obj(),
+		MakePod("t1-ns1", "rejected-non-preemptible-1").Label(extension.LabelQuotaName, "test-admission").
+			Label(extension.LabelPreemptible, "false").Container(createResourceList(200, 100)).Obj(),
+		MakePod("t1-ns1", "rejected-non-preemptible-2").Label(extension.LabelQuotaName, "test-admission").
+			Label(extension.LabelPreemptible, "false").Container(createResourceList(200, 100)).Obj(),
+	}
+	for _, pod := range pods {
+		gp.PreFilter(context.TODO(), framework.NewCycleState(), pod)
+	}
+
+	metricsCh := make(chan prometheus.Metric, 100)
+	go func() {
+		ElasticQuotaAdmissionCounter.Collect(metricsCh)
+		close(metricsCh)
+	}()
+	got := map[string]float64{}
+	for metric := range metricsCh {
+		m := dto.Metric{}
+		assert.NoError(t, metric.Write(&m))
+		labels := map[string]string{}
+		for _, l := range m.GetLabel() {
+			labels[l.GetName()] = l.GetValue()
+		}
+		if labels["name"] != "test-admission" {
+			continue
+		}
+		got[labels["preemptible"]+"/"+labels["result"]] = m.GetCounter().GetValue()
+	}
+	expected := map[string]float64{
+		"true/admitted":  1,
+		"false/admitted": 1,
+		"true/rejected":  1,
+		"false/rejected": 2,
+	}
+	assert.Equal(t, expected, got)
+}
+
+func TestPlugin_PreFilter_LatencyMetrics(t *testing.T) {
+	suit := newPluginTestSuit(t, nil)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.Nil(t, err)
+	gp := p.(*Plugin)
+	gp.OnQuotaAdd(CreateQuota2("test-latency", extension.RootQuotaName, 100, 1000, 100, 1000, 100, 1000, false, ""))
+
+	sampleCounts := func() map[string]uint64 {
+		metricsCh := make(chan prometheus.Metric, 100)
+		go func() {
+			ElasticQuotaPreFilterLatency.Collect(metricsCh)
+			close(metricsCh)
+		}()
+		counts := map[string]uint64{}
+		for metric := range metricsCh {
+			m := dto.Metric{}
+			assert.NoError(t, metric.Write(&m))
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "code" {
+					counts[l.GetValue()] = m.GetHistogram().GetSampleCount()
+				}
+			}
+		}
+		return counts
+	}
+
+	before := sampleCounts()
+	pods := []*corev1.Pod{
+		MakePod("t1-ns1", "admitted-1").Label(extension.LabelQuotaName, "test-latency").Container(
+			createResourceList(10, 100)).Obj(),
+		MakePod("t1-ns1", "admitted-2").Label(extension.LabelQuotaName, "test-latency").Container(
+			createResourceList(10, 100)).Obj(),
+		MakePod("t1-ns1", "rejected").Label(extension.LabelQuotaName, "test-latency").Container(
+			createResourceList(200, 100)).Obj(),
+	}
+	for _, pod := range pods {
+		gp.PreFilter(context.TODO(), framework.NewCycleState(), pod)
+	}
+	after := sampleCounts()
+
+	assert.Equal(t, uint64(2), after[framework.Success.String()]-before[framework.Success.String()])
+	assert.Equal(t, uint64(1), after[framework.Unschedulable.String()]-before[framework.Unschedulable.String()])
+}
+
+func TestPlugin_PodOverheadAccounting(t *testing.T) {
+	suit := newPluginTestSuit(t, nil)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.NoError(t, err)
+	gp := p.(*Plugin)
+	gp.pluginArgs.EnableRuntimeQuota = false
+	gp.addQuota("test1", extension.RootQuotaName, 10, 100, 0, 0, 10, 100, false, "", "")
+
+	// the effective request is max(init: 3/30, containers: 2/20) + overhead: 1/5 = 4/35
+	newPod := func(name, nodeName string) *corev1.Pod {
+		pod := defaultCreatePodWithQuotaAndNonPreemptible(name, "test1", 10, 1, 10, false)
+		pod.Spec.NodeName = nodeName
+		pod.Spec.InitContainers = []corev1.Container{
+			{Resources: corev1.ResourceRequirements{Requests: createResourceList(3, 30)}},
+		}
+		pod.Spec.Containers = append(pod.Spec.Containers,
+			corev1.Container{Resources: corev1.ResourceRequirements{Requests: createResourceList(1, 10)}})
+		pod.Spec.Overhead = createResourceList(1, 5)
+		return pod
+	}
+	assertUsed := func(cpu, mem int64) {
+		used := gp.groupQuotaManager.GetQuotaInfoByName("test1").GetUsed()
+		assert.True(t, quotav1.Equals(createResourceList(cpu, mem), used), "used: %v", printResourceList(used))
+	}
+
+	running := newPod("running", "node1")
+	gp.OnPodAdd(running)
+	assertUsed(4, 35)
+
+	updated := running.DeepCopy()
+	updated.ResourceVersion = "2"
+	gp.OnPodUpdate(running, updated)
+	assertUsed(4, 35)
+
+	pending := newPod("pending", "")
+	gp.OnPodAdd(pending)
+	assertUsed(4, 35)
+	assert.True(t, gp.Reserve(context.TODO(), framework.NewCycleState(), pending, "node1").IsSuccess())
+	assertUsed(8, 70)
+
+	_, status := gp.PreFilter(context.TODO(), framework.NewCycleState(), newPod("rejected", ""))
+	assert.Equal(t, framework.Unschedulable, status.Code())
+	assert.Contains(t, status.Message(), "pod's request: cpu:4,memory:35")
+}
@@ -29,15 +29,22 @@ func (g *Plugin) OnPodAdd(obj interface{}) {
 	if !ok {
 		return
 	}
+	g.updateGangMember(nil, pod)
 
 	quotaName, treeID := g.getPodAssociateQuotaNameAndTreeID(pod)
 	if quotaName == "" {
 		return
 	}
+	// the demand of the lender tree revokes the capacity it lent at once
+	defer g.syncTreeBorrowing()
 
 	mgr := g.GetGroupQuotaManagerForTree(treeID)
 	if mgr != nil {
 		mgr.OnPodAdd(quotaName, pod)
+		if pod.Spec.NodeName != "" {
+			g.finishPodHandoff(pod)
+		}
+		g.restoreIdleMin(quotaName)
 		klog.V(5).Infof("OnPodAddFunc %v add success, quota: %v, tree: [%v]", klog.KObj(pod), quotaName, treeID)
 	} else {
 		klog.Warningf("OnPodAddFunc %v add failed, quota: %v, quota manager not found: %v", klog.KObj(pod), quotaName, treeID)
@@ -51,9 +58,18 @@ func (g *Plugin) OnPodUpdate(oldObj, newObj interface{}) {
 	if oldPod.ResourceVersion == newPod.ResourceVersion {
 		return
 	}
+	g.updateGangMember(oldPod, newPod)
+	g.releaseTimedOutGangGroupAdmissionTokens(oldPod, newPod)
 
 	oldQuotaName, oldTree := g.getPodAssociateQuotaNameAndTreeID(oldPod)
 	newQuotaName, newTree := g.getPodAssociateQuotaNameAndTreeID(newPod)
+	// most pod updates, e.g. the status updates, don't change the charge of the quotas and the demand of the trees.
+	if oldQuotaName != newQuotaName || oldTree != newTree || isPodChargeChanged(oldPod, newPod) {
+		defer g.syncTreeBorrowing()
+	}
+	if newQuotaName != "" && oldPod.Spec.NodeName == "" && newPod.Spec.NodeName != "" {
+		g.finishPodHandoff(newPod)
+	}
 
 	if oldTree == newTree {
 		mgr := g.GetGroupQuotaManagerForTree(newTree)
@@ -111,6 +127,9 @@ func (g *Plugin) OnPodDelete(obj interface{}) {
 		return
 	}
 
+	g.updateGangMember(pod, nil)
+	g.releaseAdmissionToken(pod)
+	g.forgetQuotaExceededEvents(pod.UID)
 	g.handlePodDelete(pod)
 }
 
@@ -120,8 +139,12 @@ func (g *Plugin) handlePodDelete(pod *corev1.Pod) {
 		return
 	}
 
+	// the capacity released by the pod is lent at once
+	defer g.syncTreeBorrowing()
+
 	mgr := g.GetGroupQuotaManagerForTree(treeID)
 	if mgr != nil {
+		g.startPodHandoff(quotaName, pod)
 		mgr.OnPodDelete(quotaName, pod)
 		klog.V(5).Infof("OnPodDeleteFunc %v delete success, quota: %v, tree: %v", klog.KObj(pod), quotaName, treeID)
 	} else {
@@ -63,6 +63,11 @@ func (g *Plugin) PodEligibleToPreemptOthers(pod *corev1.Pod, nominatedNodeStatus
 		klog.V(5).InfoS("Pod is not eligible for preemption because of its preemptionPolicy", "pod", klog.KObj(pod), "preemptionPolicy", corev1.PreemptNever)
 		return false, "not eligible due to preemptionPolicy=Never."
 	}
+	if quotaInfo := g.getPodAssociateQuotaInfo(pod); quotaInfo != nil && quotaInfo.GetPreemptionPolicy() == corev1.PreemptNever {
+		klog.V(5).InfoS("Pod is not eligible for preemption because of its quota preemptionPolicy", "pod", klog.KObj(pod),
+			"quota", quotaInfo.Name, "preemptionPolicy", corev1.PreemptNever)
+		return false, "not eligible due to quota preemptionPolicy=Never."
+	}
 
 	nomNodeName := pod.Status.NominatedNodeName
 	nodeInfos := g.handle.SnapshotSharedLister().NodeInfos()
@@ -115,6 +120,24 @@ func (g *Plugin) SelectVictimsOnNode(
 	nodeInfo *framework.NodeInfo,
 	pdbs []*policy.PodDisruptionBudget,
 ) ([]*corev1.Pod, int, *framework.Status) {
+	// the node isn't a candidate if the quota changed since the snapshot, the pod is retried against the new quota.
+	if postFilterState, err := getPostFilterState(state); err == nil && !postFilterState.skip {
+		if status := checkSnapshotRevision(postFilterState); !status.IsSuccess() {
+			return nil, 0, status
+		}
+	}
+	if fairShareState, ok := getFairSharePreemptionState(state); ok {
+		return g.selectFairShareVictimsOnNode(ctx, state, pod, nodeInfo, pdbs, fairShareState)
+	}
+	postFilterState, err := getPostFilterState(state)
+	if err != nil {
+		return nil, 0, framework.AsStatus(err)
+	}
+	podReq := core.PodRequests(pod)
+	if !postFilterState.skip {
+		podReq = quotav1.Mask(podReq, quotav1.ResourceNames(postFilterState.quotaInfo.CalculateInfo.Max))
+	}
+
 	var potentialVictims []*framework.PodInfo
 	removePod := func(rpi *framework.PodInfo) error {
 		if err := nodeInfo.RemovePod(rpi.Pod); err != nil {
@@ -136,9 +159,10 @@ func (g *Plugin) SelectVictimsOnNode(
 	}
 	// As the first step, remove all the lower priority pods from the node and
 	// check if the given pod can be scheduled.
+	// Only the pods charged to the preemptor's quota are candidates, so the preemption
+	// never crosses the quota boundary.
 	for _, pi := range nodeInfo.Pods {
-		// TODO only allow same quotaGroup preemption.
-		if g.canPreempt(pod, pi.Pod) {
+		if g.canPreempt(pod, pi.Pod) && (postFilterState.skip || postFilterState.quotaInfo.IsPodExist(pi.Pod)) {
 			potentialVictims = append(potentialVictims, pi)
 			if err := removePod(pi); err != nil {
 				return nil, 0, framework.AsStatus(err)
@@ -161,6 +185,13 @@ func (g *Plugin) SelectVictimsOnNode(
 	if status := g.handle.RunFilterPluginsWithNominatedPods(ctx, state, pod, nodeInfo); !status.IsSuccess() {
 		return nil, 0, status
 	}
+	// Even all the lower priority pods of the quota on the node are gone, the quota
+	// still can't admit the pod, so evicting them is in vain.
+	if !postFilterState.skip && !fitsQuotaUsedLimit(postFilterState, podReq) {
+		message := fmt.Sprintf("Preempting victims on node %v can't bring quota %v back under its limit for preemptor pod %v",
+			nodeInfo.Node().Name, postFilterState.quotaInfo.Name, pod.Name)
+		return nil, 0, framework.NewStatus(framework.Unschedulable, message)
+	}
 	var victims []*corev1.Pod
 	numViolatingVictim := 0
 	sort.Slice(potentialVictims, func(i, j int) bool { return util.MoreImportantPod(potentialVictims[i].Pod, potentialVictims[j].Pod) })
@@ -169,15 +200,12 @@ func (g *Plugin) SelectVictimsOnNode(
 	// from the highest priority victims.
 	violatingVictims, nonViolatingVictims := filterPodsWithPDBViolation(potentialVictims, pdbs)
 
-	postFilterState, _ := getPostFilterState(state)
-	podReq := core.PodRequests(pod)
-
 	reprievePod := func(pi *framework.PodInfo) (bool, error) {
 		if err := addPod(pi); err != nil {
 			return false, err
 		}
 		status := g.handle.RunFilterPluginsWithNominatedPods(ctx, state, pod, nodeInfo)
-		fits := status.IsSuccess()
+		fits := status.IsSuccess() && (postFilterState.skip || fitsQuotaUsedLimit(postFilterState, podReq))
 		if !fits {
 			if err := removePod(pi); err != nil {
 				return false, err
@@ -186,16 +214,6 @@ func (g *Plugin) SelectVictimsOnNode(
 			victims = append(victims, rpi)
 			klog.V(5).InfoS("Pod is a potential preemption victim on node", "pod", klog.KObj(rpi), "node", klog.KObj(nodeInfo.Node()))
 		}
-
-		newUsed := quotav1.Mask(quotav1.Add(postFilterState.used, podReq), quotav1.ResourceNames(podReq))
-		if isLessEqual, _ := quotav1.LessThanOrEqual(newUsed, postFilterState.usedLimit); !isLessEqual {
-			if err := removePod(pi); err != nil {
-				return false, err
-			}
-			rpi := pi.Pod
-			victims = append(victims, rpi)
-			klog.V(5).InfoS("Pod is a potential preemption victim on node", "pod", klog.KObj(rpi), "node", klog.KObj(nodeInfo.Node()))
-		}
 		return fits, nil
 	}
 	for _, p := range violatingVictims {
@@ -214,6 +232,14 @@ func (g *Plugin) SelectVictimsOnNode(
 	return victims, numViolatingVictim, framework.NewStatus(framework.Success)
 }
 
+// fitsQuotaUsedLimit checks whether the quota used in the state plus the pod request
+// stays under the used limit on the requested resources.
+func fitsQuotaUsedLimit(state *PostFilterState, podReq corev1.ResourceList) bool {
+	newUsed := quotav1.Mask(quotav1.Add(state.used, podReq), quotav1.ResourceNames(podReq))
+	isLessEqual, _ := quotav1.LessThanOrEqual(newUsed, state.usedLimit)
+	return isLessEqual
+}
+
 // filterPodsWithPDBViolation groups the given "pods" into two groups of "violatingPods"
 // and "nonViolatingPods" based on whether their PDBs will be violated if they are
 // preempted.
@@ -281,7 +307,7 @@ func getPDBLister(handle framework.Handle) policylisters.PodDisruptionBudgetList
 }
 
 func (g *Plugin) canPreempt(pod, victim *corev1.Pod) bool {
-	if extension.IsPodNonPreemptible(victim) {
+	if g.isPodNonPreemptible(victim) {
 		return false
 	}
 	podPri := corev1helpers.PodPriority(pod)
@@ -0,0 +1,315 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	policy "k8s.io/api/policy/v1"
+	quotav1 "k8s.io/apiserver/pkg/quota/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"k8s.io/kubernetes/pkg/scheduler/util"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	"github.com/koordinator-sh/koordinator/pkg/scheduler/plugins/elasticquota/core"
+)
+
+const fairSharePreemptionKey = "FairSharePreemption" + Name
+
+// fairSharePreemptionState is the preemption across the child quotas of a full ancestor, which lets the pod of
+// the starved child preempt the pods of its siblings borrowing beyond their min.
+type fairSharePreemptionState struct {
+	mgr *core.GroupQuotaManager
+	// ancestorName is the full ancestor found by checkQuotaRecursive in PreFilter.
+	ancestorName string
+	// used and usedLimit are of the ancestor, the used drops as the victims are selected.
+	used      corev1.ResourceList
+	usedLimit corev1.ResourceList
+	// request is the pod request on the exceeded dimensions.
+	request corev1.ResourceList
+	// victimQuotas are the over-sharing siblings, the most exceeding their fair share first.
+	victimQuotas []*fairShareVictimQuota
+}
+
+// fairShareVictimQuota is a sibling borrowing beyond its min, the victims never bring it below its min.
+type fairShareVictimQuota struct {
+	name        string
+	reclaimable corev1.ResourceList
+	// exceedFairShare is how much the used exceeds the fair share by the shared weight.
+	exceedFairShare float64
+}
+
+func (s *fairSharePreemptionState) Clone() framework.StateData {
+	victimQuotas := make([]*fairShareVictimQuota, 0, len(s.victimQuotas))
+	for _, victimQuota := range s.victimQuotas {
+		victimQuotas = append(victimQuotas, &fairShareVictimQuota{
+			name:            victimQuota.name,
+			reclaimable:     victimQuota.reclaimable.DeepCopy(),
+			exceedFairShare: victimQuota.exceedFairShare,
+		})
+	}
+	return &fairSharePreemptionState{
+		mgr:          s.mgr,
+		ancestorName: s.ancestorName,
+		used:         s.used.DeepCopy(),
+		usedLimit:    s.usedLimit.DeepCopy(),
+		request:      s.request.DeepCopy(),
+		victimQuotas: victimQuotas,
+	}
+}
+
+func getFairSharePreemptionState(cycleState *framework.CycleState) (*fairSharePreemptionState, bool) {
+	c, err := cycleState.Read(fairSharePreemptionKey)
+	if err != nil {
+		return nil, false
+	}
+	s, ok := c.(*fairSharePreemptionState)
+	return s, ok
+}
+
+// prepareFairSharePreemption writes the fair-share preemption into the cycle state if the pod is rejected by a
+// full ancestor while the child of the ancestor on the path of the pod is still below its min.
+func (g *Plugin) prepareFairSharePreemption(cycleState *framework.CycleState, pod *corev1.Pod) {
+	if !g.pluginArgs.EnableFairSharePreemption {
+		return
+	}
+	reason, ok := GetQuotaExceedReason(cycleState)
+	// the quota of the pod itself is full if the path has a single quota
+	if !ok || reason.NonPreemptible || len(reason.TopoPath) < 2 {
+		return
+	}
+	_, treeID := g.getPodAssociateQuotaNameAndTreeID(pod)
+	mgr := g.GetGroupQuotaManagerForTree(treeID)
+	if mgr == nil {
+		return
+	}
+	starvedInfo := mgr.GetQuotaInfoByName(reason.TopoPath[1])
+	if starvedInfo == nil {
+		return
+	}
+	resourceNames := quotav1.ResourceNames(reason.Request)
+	starvedUsed := quotav1.Mask(quotav1.Add(starvedInfo.GetUsed(), reason.Request), resourceNames)
+	if isLessEqual, _ := quotav1.LessThanOrEqual(starvedUsed, starvedInfo.GetMin()); !isLessEqual {
+		return
+	}
+
+	victimQuotas := g.getFairShareVictimQuotas(mgr, reason)
+	if len(victimQuotas) == 0 {
+		return
+	}
+	cycleState.Write(fairSharePreemptionKey, &fairSharePreemptionState{
+		mgr:          mgr,
+		ancestorName: reason.QuotaName,
+		used:         quotav1.Mask(reason.Used, resourceNames),
+		usedLimit:    g.getToleratedUsedLimit(reason.Runtime),
+		request:      reason.Request,
+		victimQuotas: victimQuotas,
+	})
+	klog.V(4).InfoS("Prepare the fair-share preemption", "pod", klog.KObj(pod), "quota", reason.QuotaName,
+		"starvedQuota", starvedInfo.Name, "victimQuotas", len(victimQuotas))
+}
+
+// getFairShareVictimQuotas returns the siblings of the starved child borrowing beyond their min on the exceeded
+// dimensions, sorted by how much their used exceeds their fair share of the ancestor by the shared weight.
+func (g *Plugin) getFairShareVictimQuotas(mgr *core.GroupQuotaManager, reason *QuotaExceedReason) []*fairShareVictimQuota {
+	children := mgr.GetChildGroupQuotaInfos(reason.QuotaName)
+	totalWeight := map[corev1.ResourceName]float64{}
+	for _, child := range children {
+		sharedWeight := child.GetSharedWeight()
+		for _, resourceName := range reason.ExceedDimensions {
+			if weight, ok := sharedWeight[resourceName]; ok {
+				totalWeight[resourceName] += float64(weight.MilliValue())
+			}
+		}
+	}
+
+	var victimQuotas []*fairShareVictimQuota
+	for name, child := range children {
+		if name == reason.TopoPath[1] {
+			continue
+		}
+		used := child.GetUsed()
+		reclaimable := quotav1.Mask(quotav1.SubtractWithNonNegativeResult(used, child.GetMin()), reason.ExceedDimensions)
+		if quotav1.IsZero(reclaimable) {
+			continue
+		}
+		sharedWeight := child.GetSharedWeight()
+		var exceedFairShare float64
+		for _, resourceName := range reason.ExceedDimensions {
+			limit := reason.Runtime[resourceName]
+			if limit.IsZero() || totalWeight[resourceName] == 0 {
+				continue
+			}
+			weight := sharedWeight[resourceName]
+			fairShare := float64(limit.MilliValue()) * float64(weight.MilliValue()) / totalWeight[resourceName]
+			resourceUsed := used[resourceName]
+			exceedFairShare += (float64(resourceUsed.MilliValue()) - fairShare) / float64(limit.MilliValue())
+		}
+		victimQuotas = append(victimQuotas, &fairShareVictimQuota{
+			name:            name,
+			reclaimable:     reclaimable,
+			exceedFairShare: exceedFairShare,
+		})
+	}
+	sort.Slice(victimQuotas, func(i, j int) bool {
+		if victimQuotas[i].exceedFairShare != victimQuotas[j].exceedFairShare {
+			return victimQuotas[i].exceedFairShare > victimQuotas[j].exceedFairShare
+		}
+		return victimQuotas[i].name < victimQuotas[j].name
+	})
+	return victimQuotas
+}
+
+// selectFairShareVictimsOnNode selects the victims from the over-sharing siblings on the node, the sibling most
+// exceeding its fair share first and the less important pods first in a sibling, until the ancestor admits the
+// pod. Then it reprieves as many victims as possible like SelectVictimsOnNode does.
+func (g *Plugin) selectFairShareVictimsOnNode(
+	ctx context.Context,
+	state *framework.CycleState,
+	pod *corev1.Pod,
+	nodeInfo *framework.NodeInfo,
+	pdbs []*policy.PodDisruptionBudget,
+	fairShareState *fairSharePreemptionState,
+) ([]*corev1.Pod, int, *framework.Status) {
+	resourceNames := quotav1.ResourceNames(fairShareState.request)
+	removePod := func(rpi *framework.PodInfo) error {
+		if err := nodeInfo.RemovePod(rpi.Pod); err != nil {
+			return err
+		}
+		fairShareState.used = quotav1.SubtractWithNonNegativeResult(fairShareState.used,
+			quotav1.Mask(core.PodRequests(rpi.Pod), resourceNames))
+		if status := g.handle.RunPreFilterExtensionRemovePod(ctx, state, pod, rpi, nodeInfo); !status.IsSuccess() {
+			return status.AsError()
+		}
+		return nil
+	}
+	addPod := func(api *framework.PodInfo) error {
+		nodeInfo.AddPodInfo(api)
+		fairShareState.used = quotav1.Add(fairShareState.used, quotav1.Mask(core.PodRequests(api.Pod), resourceNames))
+		if status := g.handle.RunPreFilterExtensionAddPod(ctx, state, pod, api, nodeInfo); !status.IsSuccess() {
+			return status.AsError()
+		}
+		return nil
+	}
+	fits := func() bool {
+		newUsed := quotav1.Add(fairShareState.used, fairShareState.request)
+		if isLessEqual, _ := quotav1.LessThanOrEqual(newUsed, fairShareState.usedLimit); !isLessEqual {
+			return false
+		}
+		return g.handle.RunFilterPluginsWithNominatedPods(ctx, state, pod, nodeInfo).IsSuccess()
+	}
+
+	var potentialVictims []*framework.PodInfo
+	for _, victimQuota := range fairShareState.victimQuotas {
+		if fits() {
+			break
+		}
+		candidates := g.getFairShareCandidatesOnNode(fairShareState.mgr, nodeInfo, pod, victimQuota.name)
+		sort.Slice(candidates, func(i, j int) bool { return util.MoreImportantPod(candidates[j].Pod, candidates[i].Pod) })
+		reclaimable := victimQuota.reclaimable
+		for _, pi := range candidates {
+			if fits() {
+				break
+			}
+			podReq := quotav1.Mask(core.PodRequests(pi.Pod), quotav1.ResourceNames(reclaimable))
+			if isLessEqual, _ := quotav1.LessThanOrEqual(podReq, reclaimable); !isLessEqual {
+				continue
+			}
+			reclaimable = quotav1.Subtract(reclaimable, podReq)
+			potentialVictims = append(potentialVictims, pi)
+			if err := removePod(pi); err != nil {
+				return nil, 0, framework.AsStatus(err)
+			}
+		}
+	}
+
+	if len(potentialVictims) == 0 {
+		message := fmt.Sprintf("No victims of the over-sharing quotas found on node %v for preemptor pod %v", nodeInfo.Node().Name, pod.Name)
+		return nil, 0, framework.NewStatus(framework.UnschedulableAndUnresolvable, message)
+	}
+	if !fits() {
+		message := fmt.Sprintf("Preempting the over-sharing quotas on node %v can't bring quota %v back under its limit for preemptor pod %v",
+			nodeInfo.Node().Name, fairShareState.ancestorName, pod.Name)
+		return nil, 0, framework.NewStatus(framework.Unschedulable, message)
+	}
+
+	var victims []*corev1.Pod
+	numViolatingVictim := 0
+	sort.Slice(potentialVictims, func(i, j int) bool { return util.MoreImportantPod(potentialVictims[i].Pod, potentialVictims[j].Pod) })
+	violatingVictims, nonViolatingVictims := filterPodsWithPDBViolation(potentialVictims, pdbs)
+	reprievePod := func(pi *framework.PodInfo) (bool, error) {
+		if err := addPod(pi); err != nil {
+			return false, err
+		}
+		if fits() {
+			return true, nil
+		}
+		if err := removePod(pi); err != nil {
+			return false, err
+		}
+		victims = append(victims, pi.Pod)
+		klog.V(5).InfoS("Pod is a potential fair-share preemption victim on node", "pod", klog.KObj(pi.Pod), "node", klog.KObj(nodeInfo.Node()))
+		return false, nil
+	}
+	for _, p := range violatingVictims {
+		if reprieved, err := reprievePod(p); err != nil {
+			return nil, 0, framework.AsStatus(err)
+		} else if !reprieved {
+			numViolatingVictim++
+		}
+	}
+	for _, p := range nonViolatingVictims {
+		if _, err := reprievePod(p); err != nil {
+			return nil, 0, framework.AsStatus(err)
+		}
+	}
+	return victims, numViolatingVictim, framework.NewStatus(framework.Success)
+}
+
+// getFairShareCandidatesOnNode returns the pods on the node charged to the quota or its descendants, which have
+// lower priority than the preemptor as canPreempt requires, except the non-preemptible pods.
+func (g *Plugin) getFairShareCandidatesOnNode(mgr *core.GroupQuotaManager, nodeInfo *framework.NodeInfo,
+	preemptor *corev1.Pod, quotaName string) []*framework.PodInfo {
+	podPri := corev1helpers.PodPriority(preemptor)
+	var candidates []*framework.PodInfo
+	for _, pi := range nodeInfo.Pods {
+		if g.isPodNonPreemptible(pi.Pod) || corev1helpers.PodPriority(pi.Pod) >= podPri {
+			continue
+		}
+		podQuotaName := g.getPodAssociateQuotaName(pi.Pod)
+		if g.pluginArgs.DisableDefaultQuotaPreemption && podQuotaName == extension.DefaultQuotaName {
+			continue
+		}
+		if podQuotaName == quotaName || isAncestorQuota(mgr, podQuotaName, quotaName) {
+			candidates = append(candidates, pi)
+		}
+	}
+	return candidates
+}
+
+func isAncestorQuota(mgr *core.GroupQuotaManager, quotaName, ancestorName string) bool {
+	for _, ancestor := range mgr.GetAncestorQuotaInfos(quotaName) {
+		if ancestor.Name == ancestorName {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,238 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+func TestPlugin_PodEligibleToPreemptOthers_QuotaPreemptionPolicy(t *testing.T) {
+	tests := []struct {
+		name             string
+		preemptionPolicy string
+		expectEligible   bool
+		expectReason     string
+	}{
+		{
+			name:           "preempt lower priority by default",
+			expectEligible: true,
+		},
+		{
+			name:             "preempt lower priority",
+			preemptionPolicy: string(corev1.PreemptLowerPriority),
+			expectEligible:   true,
+		},
+		{
+			name:             "never preempt",
+			preemptionPolicy: string(corev1.PreemptNever),
+			expectEligible:   false,
+			expectReason:     "not eligible due to quota preemptionPolicy=Never.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suit := newPluginTestSuit(t, nil)
+			p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+			assert.NoError(t, err)
+			gp := p.(*Plugin)
+
+			quota := CreateQuota2("test1", extension.RootQuotaName, 100, 1000, 10, 100, 100, 1000, false, "")
+			if tt.preemptionPolicy != "" {
+				quota.Annotations[extension.AnnotationPreemptionPolicy] = tt.preemptionPolicy
+			}
+			gp.OnQuotaAdd(quota)
+			assert.Equal(t, corev1.PreemptionPolicy(tt.preemptionPolicy) == corev1.PreemptNever,
+				gp.groupQuotaManager.GetQuotaInfoByName("test1").GetPreemptionPolicy() == corev1.PreemptNever)
+
+			// the high priority pod of the quota follows the preemption policy of the quota
+			pod := defaultCreatePodWithQuotaName("pod1", "test1", 10000, 10, 100)
+			eligible, reason := gp.PodEligibleToPreemptOthers(pod, framework.NewStatus(framework.Unschedulable))
+			assert.Equal(t, tt.expectEligible, eligible)
+			assert.Equal(t, tt.expectReason, reason)
+		})
+	}
+}
+
+func TestPlugin_SelectVictimsOnNode(t *testing.T) {
+	tests := []struct {
+		name          string
+		preemptor     *corev1.Pod
+		expectVictims []string
+		expectCode    framework.Code
+	}{
+		{
+			name:          "preempt the lowest priority pod of the same quota",
+			preemptor:     defaultCreatePodWithQuotaAndNonPreemptible("preemptor", "test1", 100, 4, 10, false),
+			expectVictims: []string{"low"},
+			expectCode:    framework.Success,
+		},
+		{
+			name:          "preempt all the preemptible pods of the same quota",
+			preemptor:     defaultCreatePodWithQuotaAndNonPreemptible("preemptor", "test1", 100, 8, 20, false),
+			expectVictims: []string{"middle", "low"},
+			expectCode:    framework.Success,
+		},
+		{
+			name:       "never preempt pods of other quotas or non-preemptible pods",
+			preemptor:  defaultCreatePodWithQuotaAndNonPreemptible("preemptor", "test1", 100, 9, 20, false),
+			expectCode: framework.Unschedulable,
+		},
+		{
+			name:       "no lower priority pods",
+			preemptor:  defaultCreatePodWithQuotaAndNonPreemptible("preemptor", "test1", 5, 4, 10, false),
+			expectCode: framework.UnschedulableAndUnresolvable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suit := newPluginTestSuit(t, nil)
+			p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+			assert.NoError(t, err)
+			gp := p.(*Plugin)
+			gp.pluginArgs.EnableRuntimeQuota = false
+			gp.addQuota("test1", extension.RootQuotaName, 10, 100, 0, 0, 10, 100, false, "", "")
+			gp.addQuota("test2", extension.RootQuotaName, 10, 100, 0, 0, 10, 100, false, "", "")
+
+			pods := []*corev1.Pod{
+				defaultCreatePodWithQuotaAndNonPreemptible("low", "test1", 10, 4, 10, false),
+				defaultCreatePodWithQuotaAndNonPreemptible("middle", "test1", 20, 4, 10, false),
+				defaultCreatePodWithQuotaAndNonPreemptible("non-preemptible", "test1", 1, 2, 10, true),
+				defaultCreatePodWithQuotaAndNonPreemptible("other", "test2", 1, 4, 10, false),
+			}
+			for _, pod := range pods {
+				gp.OnPodAdd(pod)
+			}
+			nodeInfo := framework.NewNodeInfo(pods...)
+			nodeInfo.SetNode(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "test-node"}})
+
+			cycleState := framework.NewCycleState()
+			_, status := gp.PreFilter(context.TODO(), cycleState, tt.preemptor)
+			assert.False(t, status.IsSuccess())
+
+			victims, numViolatingVictim, status := gp.SelectVictimsOnNode(context.TODO(), cycleState, tt.preemptor, nodeInfo, nil)
+			assert.Equal(t, tt.expectCode, status.Code(), status.Message())
+			assert.Equal(t, 0, numViolatingVictim)
+			var victimNames []string
+			for _, victim := range victims {
+				victimNames = append(victimNames, victim.Name)
+			}
+			assert.Equal(t, tt.expectVictims, victimNames)
+		})
+	}
+}
+
+func TestPlugin_SelectVictimsOnNode_FairShare(t *testing.T) {
+	tests := []struct {
+		name             string
+		disableFairShare bool
+		preemptor        *corev1.Pod
+		expectVictims    []string
+		expectCode       framework.Code
+	}{
+		{
+			name:          "preempt the sibling most exceeding its fair share",
+			preemptor:     defaultCreatePodWithQuotaAndNonPreemptible("preemptor", "starved", 100, 4, 10, false),
+			expectVictims: []string{"b-low"},
+			expectCode:    framework.Success,
+		},
+		{
+			name:          "preempt more pods of the sibling most exceeding its fair share",
+			preemptor:     defaultCreatePodWithQuotaAndNonPreemptible("preemptor", "starved", 100, 8, 10, false),
+			expectVictims: []string{"b-middle", "b-low"},
+			expectCode:    framework.Success,
+		},
+		{
+			name:          "preempt the next sibling without the non-preemptible pods or going below the min",
+			preemptor:     defaultCreatePodWithQuotaAndNonPreemptible("preemptor", "starved", 100, 10, 10, false),
+			expectVictims: []string{"b-middle", "b-low", "a-low"},
+			expectCode:    framework.Success,
+		},
+		{
+			name:       "the starved quota beyond its min preempts only in its own quota",
+			preemptor:  defaultCreatePodWithQuotaAndNonPreemptible("preemptor", "starved", 100, 12, 10, false),
+			expectCode: framework.UnschedulableAndUnresolvable,
+		},
+		{
+			name:          "skip the victims with higher priority than the preemptor",
+			preemptor:     defaultCreatePodWithQuotaAndNonPreemptible("preemptor", "starved", 15, 8, 10, false),
+			expectVictims: []string{"b-low", "a-low"},
+			expectCode:    framework.Success,
+		},
+		{
+			name:       "no victims with lower priority than the preemptor",
+			preemptor:  defaultCreatePodWithQuotaAndNonPreemptible("preemptor", "starved", 1, 4, 10, false),
+			expectCode: framework.UnschedulableAndUnresolvable,
+		},
+		{
+			name:             "fair-share preemption disabled",
+			disableFairShare: true,
+			preemptor:        defaultCreatePodWithQuotaAndNonPreemptible("preemptor", "starved", 100, 4, 10, false),
+			expectCode:       framework.UnschedulableAndUnresolvable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suit := newPluginTestSuit(t, nil)
+			p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+			assert.NoError(t, err)
+			gp := p.(*Plugin)
+			gp.pluginArgs.EnableRuntimeQuota = false
+			gp.pluginArgs.EnableCheckParentQuota = true
+			gp.pluginArgs.EnableFairSharePreemption = !tt.disableFairShare
+			gp.addQuota("parent", extension.RootQuotaName, 20, 1000, 20, 1000, 10, 1000, true, "", "")
+			gp.addQuota("starved", "parent", 20, 1000, 10, 100, 10, 100, false, "", "")
+			gp.addQuota("a", "parent", 20, 1000, 2, 100, 10, 100, false, "", "")
+			gp.addQuota("b", "parent", 20, 1000, 2, 100, 10, 100, false, "", "")
+
+			// the parent is full, b exceeds its fair share the most
+			pods := []*corev1.Pod{
+				defaultCreatePodWithQuotaAndNonPreemptible("a-low", "a", 5, 4, 10, false),
+				defaultCreatePodWithQuotaAndNonPreemptible("a-high", "a", 30, 4, 10, false),
+				defaultCreatePodWithQuotaAndNonPreemptible("b-low", "b", 10, 4, 10, false),
+				defaultCreatePodWithQuotaAndNonPreemptible("b-middle", "b", 20, 4, 10, false),
+				defaultCreatePodWithQuotaAndNonPreemptible("b-non-preemptible", "b", 1, 4, 10, true),
+			}
+			for _, pod := range pods {
+				gp.OnPodAdd(pod)
+			}
+			nodeInfo := framework.NewNodeInfo(pods...)
+			nodeInfo.SetNode(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "test-node"}})
+
+			cycleState := framework.NewCycleState()
+			_, status := gp.PreFilter(context.TODO(), cycleState, tt.preemptor)
+			assert.False(t, status.IsSuccess())
+			gp.prepareFairSharePreemption(cycleState, tt.preemptor)
+
+			victims, numViolatingVictim, status := gp.SelectVictimsOnNode(context.TODO(), cycleState, tt.preemptor, nodeInfo, nil)
+			assert.Equal(t, tt.expectCode, status.Code(), status.Message())
+			assert.Equal(t, 0, numViolatingVictim)
+			var victimNames []string
+			for _, victim := range victims {
+				victimNames = append(victimNames, victim.Name)
+			}
+			assert.Equal(t, tt.expectVictims, victimNames)
+		})
+	}
+}
@@ -0,0 +1,45 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// sampleAdmission returns whether the admission decision should be logged in detail,
+// it samples 1 in AdmissionLogSampleRate decisions.
+func (g *Plugin) sampleAdmission() bool {
+	rate := g.pluginArgs.AdmissionLogSampleRate
+	if rate <= 0 {
+		return false
+	}
+	return (g.admissionCount.Add(1)-1)%rate == 0
+}
+
+// logSampledAdmission logs the details of the sampled admission decisions, which retains the insight
+// into the admission without flooding the logs of high-throughput clusters.
+func (g *Plugin) logSampledAdmission(pod *corev1.Pod, quotaName, treeID string, podRequest corev1.ResourceList,
+	state *PostFilterState, status *framework.Status) {
+	if !g.sampleAdmission() {
+		return
+	}
+	klog.InfoS("Sampled quota admission decision", "pod", klog.KObj(pod), "quota", quotaName, "tree", treeID,
+		"admitted", status.IsSuccess(), "reason", status.Message(), "request", printResourceList(podRequest),
+		"used", printResourceList(state.used), "usedLimit", printResourceList(state.usedLimit))
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	"github.com/koordinator-sh/koordinator/pkg/scheduler/apis/config"
+)
+
+func TestPlugin_PreFilter_SampledAdmissionLog(t *testing.T) {
+	fs := flag.NewFlagSet("klog", flag.ContinueOnError)
+	klog.InitFlags(fs)
+	oldLogToStderr := fs.Lookup("logtostderr").Value.String()
+	buf := &bytes.Buffer{}
+	assert.NoError(t, fs.Set("logtostderr", "false"))
+	klog.SetOutput(buf)
+	defer func() {
+		klog.SetOutput(os.Stderr)
+		fs.Set("logtostderr", oldLogToStderr)
+	}()
+
+	tests := []struct {
+		name          string
+		sampleRate    int64
+		decisions     int
+		expectSampled int
+	}{
+		{
+			name:          "sampled logging is disabled",
+			sampleRate:    0,
+			decisions:     100,
+			expectSampled: 0,
+		},
+		{
+			name:          "log every decision",
+			sampleRate:    1,
+			decisions:     20,
+			expectSampled: 20,
+		},
+		{
+			name:          "log 1 in 10 decisions",
+			sampleRate:    10,
+			decisions:     100,
+			expectSampled: 10,
+		},
+		{
+			name:          "log 1 in 7 decisions",
+			sampleRate:    7,
+			decisions:     100,
+			expectSampled: 15,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suit := newPluginTestSuit(t, nil, func(elasticQuotaArgs *config.ElasticQuotaArgs) {
+				elasticQuotaArgs.AdmissionLogSampleRate = tt.sampleRate
+			})
+			p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+			assert.NoError(t, err)
+			gp := p.(*Plugin)
+			gp.pluginArgs.EnableRuntimeQuota = false
+			gp.addQuota("test1", extension.RootQuotaName, 100, 1000, 10, 100, 10, 100, false, "", "")
+
+			klog.Flush()
+			buf.Reset()
+			for i := 0; i < tt.decisions; i++ {
+				pod := defaultCreatePodWithQuotaName(fmt.Sprintf("pod-%d", i), "test1", 10, 10, 10)
+				gp.PreFilter(context.TODO(), framework.NewCycleState(), pod)
+			}
+			klog.Flush()
+			assert.Equal(t, tt.expectSampled, strings.Count(buf.String(), "Sampled quota admission decision"))
+		})
+	}
+}
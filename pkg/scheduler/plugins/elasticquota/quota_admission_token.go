@@ -0,0 +1,144 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	quotav1 "k8s.io/apiserver/pkg/quota/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/koordinator-sh/koordinator/pkg/scheduler/plugins/elasticquota/core"
+)
+
+// admissionTokenTTL bounds how long an admission token holds the quota when its scheduling cycle
+// neither reserves nor fails through PostFilter, e.g. the pod is deleted during the cycle.
+const admissionTokenTTL = 30 * time.Second
+
+// admissionToken is the quota admitted to the pod in PreFilter, which holds the quota until the
+// pod is reserved, so the concurrent scheduling cycles can't admit the same last slot of the quota.
+type admissionToken struct {
+	quotaName string
+	// quotaPath is the quota and its ancestors, the token is charged to all of them.
+	quotaPath sets.String
+	request   corev1.ResourceList
+	deadline  time.Time
+	// gangGroupID is the gang group the token is granted with, the tokens of the gang group are released together.
+	gangGroupID string
+}
+
+// checkQuotaAndGrantAdmissionToken checks the quota of the pod against the used and the requests of the
+// pods admitted but not reserved yet, and grants the pod an admission token if the pod is admitted.
+// The reason is returned if a quota is exceeded.
+func (g *Plugin) checkQuotaAndGrantAdmissionToken(mgr *core.GroupQuotaManager, quotaInfo *core.QuotaInfo, pod *corev1.Pod,
+	podRequest corev1.ResourceList, state *PostFilterState) (*framework.Status, *QuotaExceedReason) {
+	quotaName := quotaInfo.Name
+	handoffUsed := g.getQuotaHandoffUsed(quotaInfo, pod)
+
+	g.admissionTokenLock.Lock()
+	defer g.admissionTokenLock.Unlock()
+	status, reason := g.checkQuotaWithPendingAdmissionNoLock(mgr, quotaInfo, pod, podRequest, handoffUsed,
+		state.nonPreemptibleUsed, state.usedLimit)
+	if !status.IsSuccess() {
+		delete(g.admissionTokens, pod.UID)
+		return status, reason
+	}
+	token := &admissionToken{
+		quotaName: quotaName,
+		quotaPath: getQuotaPath(mgr, quotaName),
+		request:   podRequest,
+		deadline:  g.clock.Now().Add(admissionTokenTTL),
+	}
+	if oldToken, ok := g.admissionTokens[pod.UID]; ok {
+		token.gangGroupID = oldToken.gangGroupID
+	}
+	g.admissionTokens[pod.UID] = token
+	return status, nil
+}
+
+// getQuotaHandoffUsed returns the used handed off to the pod in the quota on the dimensions of the quota.
+func (g *Plugin) getQuotaHandoffUsed(quotaInfo *core.QuotaInfo, pod *corev1.Pod) corev1.ResourceList {
+	return quotav1.Mask(g.getPodHandoffUsed(quotaInfo.Name, pod), quotav1.ResourceNames(quotaInfo.CalculateInfo.Max))
+}
+
+// checkQuotaWithPendingAdmissionNoLock checks the quota of the pod against the used, the handoff used and the
+// requests of the other pods admitted but not reserved yet, without granting the token.
+func (g *Plugin) checkQuotaWithPendingAdmissionNoLock(mgr *core.GroupQuotaManager, quotaInfo *core.QuotaInfo, pod *corev1.Pod,
+	podRequest, handoffUsed, nonPreemptibleUsed, usedLimit corev1.ResourceList) (*framework.Status, *QuotaExceedReason) {
+	// read the used again under the lock, the pods reserved since the snapshot have consumed their tokens
+	used := quotav1.Add(quotaInfo.GetUsed(), handoffUsed)
+	used = quotav1.Add(used, g.getPendingAdmissionUsedNoLock(quotaInfo.Name, pod))
+	return g.checkQuota(mgr, quotaInfo, pod, podRequest, used, nonPreemptibleUsed, usedLimit, sets.NewString(string(pod.UID)))
+}
+
+// getQuotaPath returns the names of the quota and its ancestors.
+func getQuotaPath(mgr *core.GroupQuotaManager, quotaName string) sets.String {
+	quotaPath := sets.NewString(quotaName)
+	for _, info := range mgr.GetAncestorQuotaInfos(quotaName) {
+		quotaPath.Insert(info.Name)
+	}
+	return quotaPath
+}
+
+// getPendingAdmissionUsedNoLock returns the requests of the other pods holding the admission tokens of the quota.
+func (g *Plugin) getPendingAdmissionUsedNoLock(quotaName string, pod *corev1.Pod) corev1.ResourceList {
+	return g.getPendingAdmissionUsedExceptNoLock(quotaName, sets.NewString(string(pod.UID)))
+}
+
+// getPendingAdmissionUsedExceptNoLock returns the requests of the pods holding the admission tokens charged to
+// the quota except the excluded pods, the tokens of the descendant quotas are charged to the quota too.
+func (g *Plugin) getPendingAdmissionUsedExceptNoLock(quotaName string, excludedUIDs sets.String) corev1.ResourceList {
+	now := g.clock.Now()
+	var used corev1.ResourceList
+	for uid, token := range g.admissionTokens {
+		if !now.Before(token.deadline) {
+			delete(g.admissionTokens, uid)
+			continue
+		}
+		if !token.quotaPath.Has(quotaName) || excludedUIDs.Has(string(uid)) {
+			continue
+		}
+		used = quotav1.Add(used, token.request)
+	}
+	return used
+}
+
+// reservePodWithAdmissionToken reserves the pod in the quota and consumes its admission token at once,
+// so the request of the pod is always accounted either in the used or in the token.
+func (g *Plugin) reservePodWithAdmissionToken(mgr *core.GroupQuotaManager, quotaName string, pod *corev1.Pod) {
+	g.admissionTokenLock.Lock()
+	defer g.admissionTokenLock.Unlock()
+	mgr.ReservePod(quotaName, pod)
+	delete(g.admissionTokens, pod.UID)
+}
+
+// releaseAdmissionToken gives back the quota held by the admission token of the pod, the tokens of the other
+// members are released too if the token is granted with a gang group, since the gang group can't proceed.
+func (g *Plugin) releaseAdmissionToken(pod *corev1.Pod) {
+	g.admissionTokenLock.Lock()
+	defer g.admissionTokenLock.Unlock()
+	if token, ok := g.admissionTokens[pod.UID]; ok {
+		delete(g.admissionTokens, pod.UID)
+		if token.gangGroupID != "" {
+			g.releaseGangGroupAdmissionTokensNoLock(token.gangGroupID)
+		}
+		klog.V(5).Infof("pod %v releases its quota admission token", klog.KObj(pod))
+	}
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	quotav1 "k8s.io/apiserver/pkg/quota/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	fakeclock "k8s.io/utils/clock/testing"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+func newPendingPodWithQuotaName(name, quotaName string) *corev1.Pod {
+	return MakePod("ns", name).UID(name).Label(extension.LabelQuotaName, quotaName).Container(
+		createResourceList(10, 100)).Obj()
+}
+
+func TestPlugin_AdmissionToken(t *testing.T) {
+	suit := newPluginTestSuit(t, nil)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.NoError(t, err)
+	gp := p.(*Plugin)
+	gp.pluginArgs.EnableRuntimeQuota = false
+	fakeClock := fakeclock.NewFakeClock(time.Now())
+	gp.clock = fakeClock
+	gp.OnQuotaAdd(CreateQuota2("test1", extension.RootQuotaName, 20, 200, 10, 100, 20, 200, false, ""))
+
+	pod1 := newPendingPodWithQuotaName("pod1", "test1")
+	pod2 := newPendingPodWithQuotaName("pod2", "test1")
+	pod3 := newPendingPodWithQuotaName("pod3", "test1")
+	for _, pod := range []*corev1.Pod{pod1, pod2, pod3} {
+		gp.OnPodAdd(pod)
+	}
+	preFilter := func(pod *corev1.Pod) bool {
+		_, status := gp.PreFilter(context.TODO(), framework.NewCycleState(), pod)
+		return status.IsSuccess()
+	}
+
+	// the admitted pods hold the quota before they are reserved
+	assert.True(t, preFilter(pod1))
+	assert.True(t, preFilter(pod2))
+	assert.False(t, preFilter(pod3))
+	// the pod admitted again doesn't count its own token
+	assert.True(t, preFilter(pod1))
+
+	// the reserved pod consumes its token and takes the quota in used
+	assert.Equal(t, framework.Success, gp.Reserve(context.TODO(), framework.NewCycleState(), pod1, "node1").Code())
+	assert.True(t, quotav1.Equals(createResourceList(10, 100), gp.groupQuotaManager.GetQuotaInfoByName("test1").GetUsed()))
+	assert.False(t, preFilter(pod3))
+
+	// the released token gives back the quota
+	gp.releaseAdmissionToken(pod2)
+	assert.True(t, preFilter(pod3))
+
+	// the expired token gives back the quota
+	fakeClock.Step(admissionTokenTTL)
+	assert.True(t, preFilter(pod2))
+
+	// the unreserved pod gives back both the used and the token
+	gp.Unreserve(context.TODO(), framework.NewCycleState(), pod1, "node1")
+	assert.True(t, quotav1.IsZero(gp.groupQuotaManager.GetQuotaInfoByName("test1").GetUsed()))
+	assert.Equal(t, 1, len(gp.admissionTokens))
+}
+
+func TestPlugin_AdmissionToken_Concurrent(t *testing.T) {
+	suit := newPluginTestSuit(t, nil)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.NoError(t, err)
+	gp := p.(*Plugin)
+	gp.pluginArgs.EnableRuntimeQuota = false
+	gp.OnQuotaAdd(CreateQuota2("test1", extension.RootQuotaName, 100, 1000, 10, 100, 100, 1000, false, ""))
+
+	var pods []*corev1.Pod
+	for i := 0; i < 50; i++ {
+		pod := newPendingPodWithQuotaName(fmt.Sprintf("pod-%d", i), "test1")
+		gp.OnPodAdd(pod)
+		pods = append(pods, pod)
+	}
+
+	// all the scheduling cycles race for the 10 slots of the quota
+	var admitted int64
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for _, pod := range pods {
+		wg.Add(1)
+		go func(pod *corev1.Pod) {
+			defer wg.Done()
+			<-start
+			cycleState := framework.NewCycleState()
+			if _, status := gp.PreFilter(context.TODO(), cycleState, pod); !status.IsSuccess() {
+				return
+			}
+			atomic.AddInt64(&admitted, 1)
+			gp.Reserve(context.TODO(), cycleState, pod, "node1")
+		}(pod)
+	}
+	close(start)
+	wg.Wait()
+
+	assert.Equal(t, int64(10), admitted)
+	assert.True(t, quotav1.Equals(createResourceList(100, 1000), gp.groupQuotaManager.GetQuotaInfoByName("test1").GetUsed()))
+	assert.Equal(t, 0, len(gp.admissionTokens))
+}
+
+func TestPlugin_AdmissionToken_ParentQuota(t *testing.T) {
+	suit := newPluginTestSuit(t, nil)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.NoError(t, err)
+	gp := p.(*Plugin)
+	gp.pluginArgs.EnableRuntimeQuota = false
+	gp.pluginArgs.EnableCheckParentQuota = true
+	gp.OnQuotaAdd(CreateQuota2("parent", extension.RootQuotaName, 10, 100, 10, 100, 10, 100, true, ""))
+	gp.OnQuotaAdd(CreateQuota2("child1", "parent", 20, 200, 0, 0, 20, 200, false, ""))
+	gp.OnQuotaAdd(CreateQuota2("child2", "parent", 20, 200, 0, 0, 20, 200, false, ""))
+
+	pod1 := newPendingPodWithQuotaName("pod1", "child1")
+	pod2 := newPendingPodWithQuotaName("pod2", "child2")
+	for _, pod := range []*corev1.Pod{pod1, pod2} {
+		gp.OnPodAdd(pod)
+	}
+	preFilter := func(pod *corev1.Pod) bool {
+		_, status := gp.PreFilter(context.TODO(), framework.NewCycleState(), pod)
+		return status.IsSuccess()
+	}
+
+	// the token of pod1 is charged to the parent, so the sibling can't take the last slot of the parent
+	assert.True(t, preFilter(pod1))
+	assert.False(t, preFilter(pod2))
+
+	gp.releaseAdmissionToken(pod1)
+	assert.True(t, preFilter(pod2))
+	assert.False(t, preFilter(pod1))
+}
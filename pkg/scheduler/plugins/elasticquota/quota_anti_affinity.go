@@ -0,0 +1,72 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/sets"
+	quotav1 "k8s.io/apiserver/pkg/quota/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/koordinator-sh/koordinator/pkg/scheduler/plugins/elasticquota/core"
+)
+
+// getAntiAffinityQuotaOnNode returns the first anti-affinity quota which has pods on the node.
+func (g *Plugin) getAntiAffinityQuotaOnNode(nodeInfo *framework.NodeInfo, antiAffinityQuotas sets.String) (string, bool) {
+	for _, podInfo := range nodeInfo.Pods {
+		if quotaName := g.GetQuotaName(podInfo.Pod); antiAffinityQuotas.Has(quotaName) {
+			return quotaName, true
+		}
+	}
+	return "", false
+}
+
+// getAntiAffinityFeasibleUsedLimit discounts the used limit of the quota by the capacity its pods can't use
+// since they avoid the nodes running pods of its anti-affinity quotas. The estimate is conservative: the quota
+// is limited by the allocatable of the feasible nodes, regardless of what the other quotas use on them.
+func (g *Plugin) getAntiAffinityFeasibleUsedLimit(quotaInfo *core.QuotaInfo, usedLimit corev1.ResourceList) corev1.ResourceList {
+	if !g.pluginArgs.EnableAntiAffinityAwareRuntime {
+		return usedLimit
+	}
+	antiAffinityQuotas := sets.NewString(quotaInfo.GetAntiAffinityQuotas()...)
+	if antiAffinityQuotas.Len() == 0 {
+		return usedLimit
+	}
+	nodeInfos, err := g.handle.SnapshotSharedLister().NodeInfos().List()
+	if err != nil {
+		klog.Errorf("failed to list nodeInfos for quota %v, err: %v", quotaInfo.Name, err)
+		return usedLimit
+	}
+
+	feasible := corev1.ResourceList{}
+	for resName, quantity := range usedLimit {
+		feasible[resName] = *resource.NewQuantity(0, quantity.Format)
+	}
+	for _, nodeInfo := range nodeInfos {
+		node := nodeInfo.Node()
+		if node == nil {
+			continue
+		}
+		if _, ok := g.getAntiAffinityQuotaOnNode(nodeInfo, antiAffinityQuotas); ok {
+			continue
+		}
+		feasible = quotav1.Add(feasible, quotav1.Mask(node.Status.Allocatable, quotav1.ResourceNames(usedLimit)))
+	}
+	return quotav1.Min(usedLimit, feasible)
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	quotav1 "k8s.io/apiserver/pkg/quota/v1"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+func TestPlugin_GetAntiAffinityFeasibleUsedLimit(t *testing.T) {
+	newNode := func(name string) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status:     corev1.NodeStatus{Allocatable: createResourceList(10, 100)},
+		}
+	}
+	nodes := []*corev1.Node{newNode("node-1"), newNode("node-2"), newNode("node-3")}
+	podB := defaultCreatePodWithQuotaName("pod-b", "quota-b", 10, 1, 1)
+	podB.Spec.NodeName = "node-1"
+
+	tests := []struct {
+		name               string
+		enabled            bool
+		antiAffinityQuotas string
+		usedLimit          corev1.ResourceList
+		expected           corev1.ResourceList
+	}{
+		{
+			name:               "disabled",
+			antiAffinityQuotas: `["quota-b"]`,
+			usedLimit:          createResourceList(30, 300),
+			expected:           createResourceList(30, 300),
+		},
+		{
+			name:      "no anti-affinity quotas",
+			enabled:   true,
+			usedLimit: createResourceList(30, 300),
+			expected:  createResourceList(30, 300),
+		},
+		{
+			name:               "anti-affinity reduces the usable runtime",
+			enabled:            true,
+			antiAffinityQuotas: `["quota-b"]`,
+			usedLimit:          createResourceList(30, 300),
+			expected:           createResourceList(20, 200),
+		},
+		{
+			name:               "runtime smaller than the usable capacity",
+			enabled:            true,
+			antiAffinityQuotas: `["quota-b"]`,
+			usedLimit:          createResourceList(5, 300),
+			expected:           createResourceList(5, 200),
+		},
+		{
+			name:               "anti-affinity quota without pods",
+			enabled:            true,
+			antiAffinityQuotas: `["quota-c"]`,
+			usedLimit:          createResourceList(30, 300),
+			expected:           createResourceList(30, 300),
+		},
+		{
+			name:               "resource no node provides",
+			enabled:            true,
+			antiAffinityQuotas: `["quota-b"]`,
+			usedLimit:          corev1.ResourceList{"nvidia.com/gpu": resource.MustParse("2")},
+			expected:           corev1.ResourceList{"nvidia.com/gpu": resource.MustParse("0")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suit := newPluginTestSuitWithPod(t, nodes, []*corev1.Pod{podB})
+			p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+			assert.Nil(t, err)
+			gp := p.(*Plugin)
+			gp.pluginArgs.EnableAntiAffinityAwareRuntime = tt.enabled
+
+			quotaA := CreateQuota2("quota-a", extension.RootQuotaName, 100, 1000, 10, 100, 10, 100, false, "")
+			if tt.antiAffinityQuotas != "" {
+				quotaA.Annotations[extension.AnnotationAntiAffinityQuotas] = tt.antiAffinityQuotas
+			}
+			gp.OnQuotaAdd(quotaA)
+			quotaInfo := gp.groupQuotaManager.GetQuotaInfoByName("quota-a")
+
+			got := gp.getAntiAffinityFeasibleUsedLimit(quotaInfo, tt.usedLimit)
+			assert.True(t, quotav1.Equals(tt.expected, got), "expected %v, got %v", tt.expected, got)
+		})
+	}
+}
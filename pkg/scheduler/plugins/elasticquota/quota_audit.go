@@ -0,0 +1,120 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// QuotaDecisionType is the kind of the quota decision made on a pod.
+type QuotaDecisionType string
+
+const (
+	// QuotaDecisionAdmit means the pod is admitted by its quota in PreFilter.
+	QuotaDecisionAdmit QuotaDecisionType = "Admit"
+	// QuotaDecisionReject means the pod is rejected by its quota in PreFilter.
+	QuotaDecisionReject QuotaDecisionType = "Reject"
+	// QuotaDecisionPreempt means the pod is nominated to a node by preempting the pods of its quota.
+	QuotaDecisionPreempt QuotaDecisionType = "Preempt"
+)
+
+// QuotaDecision is the structured context of a quota decision made on a pod.
+type QuotaDecision struct {
+	Type         QuotaDecisionType
+	Timestamp    time.Time
+	PodNamespace string
+	PodName      string
+	PodUID       types.UID
+	QuotaName    string
+	TreeID       string
+	// Request is the pod request counted in the quota.
+	Request corev1.ResourceList
+	// Used and UsedLimit are the quota used and the limit the pod was checked against, absent for the preemption.
+	Used      corev1.ResourceList
+	UsedLimit corev1.ResourceList
+	// NominatedNodeName is the node the preemptor is nominated to, only set for the preemption.
+	NominatedNodeName string
+	// Reason is the message of the rejection.
+	Reason string
+}
+
+// AuditSink receives every quota decision, e.g. to ship them to a SIEM. Record is called synchronously
+// in the scheduling cycle, so the implementations should buffer the decisions rather than block.
+type AuditSink interface {
+	Record(decision *QuotaDecision)
+}
+
+type noopAuditSink struct{}
+
+func (noopAuditSink) Record(*QuotaDecision) {}
+
+// SetAuditSink sets the sink which receives the quota decisions, nil restores the default no-op sink.
+func (g *Plugin) SetAuditSink(sink AuditSink) {
+	g.auditSinkLock.Lock()
+	defer g.auditSinkLock.Unlock()
+	if sink == nil {
+		sink = noopAuditSink{}
+	}
+	g.auditSink = sink
+}
+
+func (g *Plugin) getAuditSink() AuditSink {
+	g.auditSinkLock.RLock()
+	defer g.auditSinkLock.RUnlock()
+	return g.auditSink
+}
+
+// auditAdmission records the admit or reject decision of the pod in PreFilter. The state is nil if the pod
+// is rejected before its quota is resolved.
+func (g *Plugin) auditAdmission(pod *corev1.Pod, quotaName, treeID string, podRequest corev1.ResourceList,
+	state *PostFilterState, reason string, admitted bool) {
+	decision := g.newQuotaDecision(pod, quotaName, treeID)
+	decision.Type = QuotaDecisionReject
+	if admitted {
+		decision.Type = QuotaDecisionAdmit
+	}
+	decision.Request = podRequest.DeepCopy()
+	if state != nil {
+		decision.Used = state.used.DeepCopy()
+		decision.UsedLimit = state.usedLimit.DeepCopy()
+	}
+	decision.Reason = reason
+	g.getAuditSink().Record(decision)
+}
+
+// auditPreemption records the preemption which nominates the pod to the node.
+func (g *Plugin) auditPreemption(pod *corev1.Pod, nominatedNodeName string) {
+	quotaName, treeID := g.getPodAssociateQuotaNameAndTreeID(pod)
+	decision := g.newQuotaDecision(pod, quotaName, treeID)
+	decision.Type = QuotaDecisionPreempt
+	decision.NominatedNodeName = nominatedNodeName
+	g.getAuditSink().Record(decision)
+}
+
+func (g *Plugin) newQuotaDecision(pod *corev1.Pod, quotaName, treeID string) *QuotaDecision {
+	return &QuotaDecision{
+		Timestamp:    g.clock.Now(),
+		PodNamespace: pod.Namespace,
+		PodName:      pod.Name,
+		PodUID:       pod.UID,
+		QuotaName:    quotaName,
+		TreeID:       treeID,
+	}
+}
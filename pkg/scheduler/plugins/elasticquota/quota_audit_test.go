@@ -0,0 +1,118 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	quotav1 "k8s.io/apiserver/pkg/quota/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	fakeclock "k8s.io/utils/clock/testing"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	"github.com/koordinator-sh/koordinator/pkg/scheduler/apis/config"
+)
+
+type recordingAuditSink struct {
+	lock      sync.Mutex
+	decisions []*QuotaDecision
+}
+
+func (s *recordingAuditSink) Record(decision *QuotaDecision) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.decisions = append(s.decisions, decision)
+}
+
+func TestPlugin_AuditSink(t *testing.T) {
+	suit := newPluginTestSuit(t, nil, func(elasticQuotaArgs *config.ElasticQuotaArgs) {
+		elasticQuotaArgs.UnlabeledPodPolicies = map[string]config.UnlabeledPodPolicy{
+			"ns-reject": config.UnlabeledPodPolicyReject,
+		}
+	})
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.Nil(t, err)
+	gp := p.(*Plugin)
+	gp.pluginArgs.EnableRuntimeQuota = false
+	now := time.Date(2024, 1, 1, 8, 0, 0, 0, time.Local)
+	gp.clock = fakeclock.NewFakeClock(now)
+	gp.addQuota("test1", extension.RootQuotaName, 10, 10, 10, 10, 10, 10, false, "", "")
+
+	// the default sink drops the decisions
+	pod := MakePod("t1-ns1", "pod1").UID("pod1").Label(extension.LabelQuotaName, "test1").Container(
+		MakeResourceList().CPU(1).Mem(1).Obj()).Obj()
+	_, status := gp.PreFilter(context.TODO(), framework.NewCycleState(), pod)
+	assert.True(t, status.IsSuccess())
+
+	sink := &recordingAuditSink{}
+	gp.SetAuditSink(sink)
+
+	// admitted
+	_, status = gp.PreFilter(context.TODO(), framework.NewCycleState(), pod)
+	assert.True(t, status.IsSuccess())
+	// rejected by the quota
+	bigPod := MakePod("t1-ns1", "pod2").UID("pod2").Label(extension.LabelQuotaName, "test1").Container(
+		MakeResourceList().CPU(100).Mem(100).Obj()).Obj()
+	_, rejectStatus := gp.PreFilter(context.TODO(), framework.NewCycleState(), bigPod)
+	assert.False(t, rejectStatus.IsSuccess())
+	// rejected before the quota is resolved
+	unlabeledPod := MakePod("ns-reject", "pod3").UID("pod3").Container(MakeResourceList().CPU(1).Mem(1).Obj()).Obj()
+	_, status = gp.PreFilter(context.TODO(), framework.NewCycleState(), unlabeledPod)
+	assert.False(t, status.IsSuccess())
+	// preempted
+	gp.auditPreemption(bigPod, "node1")
+
+	assert.Equal(t, 4, len(sink.decisions))
+
+	admit := sink.decisions[0]
+	assert.Equal(t, QuotaDecisionAdmit, admit.Type)
+	assert.Equal(t, now, admit.Timestamp)
+	assert.Equal(t, "t1-ns1", admit.PodNamespace)
+	assert.Equal(t, "pod1", admit.PodName)
+	assert.Equal(t, "test1", admit.QuotaName)
+	assert.True(t, quotav1.Equals(MakeResourceList().CPU(1).Mem(1).Obj(), admit.Request))
+	assert.True(t, quotav1.Equals(MakeResourceList().CPU(10).Mem(10).Obj(), admit.UsedLimit))
+	assert.Empty(t, admit.Reason)
+
+	reject := sink.decisions[1]
+	assert.Equal(t, QuotaDecisionReject, reject.Type)
+	assert.Equal(t, "pod2", reject.PodName)
+	assert.Equal(t, "test1", reject.QuotaName)
+	assert.Equal(t, rejectStatus.Message(), reject.Reason)
+
+	unlabeled := sink.decisions[2]
+	assert.Equal(t, QuotaDecisionReject, unlabeled.Type)
+	assert.Equal(t, "pod3", unlabeled.PodName)
+	assert.Empty(t, unlabeled.QuotaName)
+	assert.NotEmpty(t, unlabeled.Reason)
+
+	preempt := sink.decisions[3]
+	assert.Equal(t, QuotaDecisionPreempt, preempt.Type)
+	assert.Equal(t, "pod2", preempt.PodName)
+	assert.Equal(t, "test1", preempt.QuotaName)
+	assert.Equal(t, "node1", preempt.NominatedNodeName)
+
+	// nil restores the no-op sink
+	gp.SetAuditSink(nil)
+	_, status = gp.PreFilter(context.TODO(), framework.NewCycleState(), pod)
+	assert.True(t, status.IsSuccess())
+	assert.Equal(t, 4, len(sink.decisions))
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	quotav1 "k8s.io/apiserver/pkg/quota/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+const (
+	// CapacityAlertCycle is the interval to check the utilization of the quotas for the capacity alerts.
+	CapacityAlertCycle = 10 * time.Second
+	// capacityAlertTimeout bounds the time of posting a capacity alert to the webhook.
+	capacityAlertTimeout = 5 * time.Second
+)
+
+// QuotaCapacityAlert is the payload posted to the CapacityAlertWebhook when the utilization level of a quota changes.
+type QuotaCapacityAlert struct {
+	Quota string `json:"quota"`
+	Tree  string `json:"tree,omitempty"`
+	// Level is the highest threshold in percent the utilization reaches, 0 means it's under all the thresholds.
+	Level         int64 `json:"level"`
+	PreviousLevel int64 `json:"previousLevel"`
+	// Utilization is the highest utilization in percent among the resources of the max, and Resource is the
+	// resource of the highest utilization.
+	Utilization int64               `json:"utilization"`
+	Resource    corev1.ResourceName `json:"resource,omitempty"`
+	Used        corev1.ResourceList `json:"used,omitempty"`
+	Max         corev1.ResourceList `json:"max,omitempty"`
+	Timestamp   metav1.Time         `json:"timestamp"`
+}
+
+// syncCapacityAlerts checks the utilization of all the quotas and posts the capacity alerts of the quotas whose
+// utilization level changes. The level of a quota is kept unchanged if its alert fails, so it's retried in the
+// next cycle.
+func (ctrl *Controller) syncCapacityAlerts() {
+	pluginArgs := ctrl.plugin.pluginArgs
+	if pluginArgs.CapacityAlertWebhook == "" || len(pluginArgs.CapacityAlertThresholds) == 0 {
+		return
+	}
+	thresholds := append([]int64{}, pluginArgs.CapacityAlertThresholds...)
+	sort.Slice(thresholds, func(i, j int) bool {
+		return thresholds[i] < thresholds[j]
+	})
+
+	seen := sets.NewString()
+	managers := append(ctrl.plugin.ListGroupQuotaManagersForQuotaTree(), ctrl.plugin.groupQuotaManager)
+	for _, mgr := range managers {
+		for _, quotaName := range mgr.GetAllQuotaNames() {
+			if quotaName == extension.RootQuotaName {
+				continue
+			}
+			quotaInfo := mgr.GetQuotaInfoByName(quotaName)
+			if quotaInfo == nil {
+				continue
+			}
+			seen.Insert(quotaName)
+
+			used, maxQuota := quotaInfo.GetUsed(), quotaInfo.GetMax()
+			utilization, resourceName := getQuotaUtilization(used, maxQuota)
+			level := getCapacityAlertLevel(utilization, thresholds)
+			previousLevel := ctrl.capacityAlertLevels[quotaName]
+			if level == previousLevel {
+				continue
+			}
+			alert := &QuotaCapacityAlert{
+				Quota:         quotaName,
+				Tree:          mgr.GetTreeID(),
+				Level:         level,
+				PreviousLevel: previousLevel,
+				Utilization:   utilization,
+				Resource:      resourceName,
+				Used:          used,
+				Max:           maxQuota,
+				Timestamp:     metav1.Now(),
+			}
+			if err := ctrl.sendCapacityAlert(pluginArgs.CapacityAlertWebhook, alert); err != nil {
+				klog.ErrorS(err, "Failed to send quota capacity alert", "quota", quotaName, "level", level)
+				continue
+			}
+			klog.V(4).InfoS("Sent quota capacity alert", "quota", quotaName, "previousLevel", previousLevel, "level", level)
+			if level == 0 {
+				delete(ctrl.capacityAlertLevels, quotaName)
+			} else {
+				ctrl.capacityAlertLevels[quotaName] = level
+			}
+		}
+	}
+	for quotaName := range ctrl.capacityAlertLevels {
+		if !seen.Has(quotaName) {
+			delete(ctrl.capacityAlertLevels, quotaName)
+		}
+	}
+}
+
+func (ctrl *Controller) sendCapacityAlert(webhook string, alert *QuotaCapacityAlert) error {
+	data, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.TODO(), capacityAlertTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := ctrl.capacityAlertClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected status code %v from capacity alert webhook", resp.StatusCode)
+	}
+	return nil
+}
+
+// getQuotaUtilization returns the highest utilization in percent of the used to the max among the resources.
+func getQuotaUtilization(used, maxQuota corev1.ResourceList) (int64, corev1.ResourceName) {
+	resourceNames := quotav1.ResourceNames(maxQuota)
+	sort.Slice(resourceNames, func(i, j int) bool {
+		return resourceNames[i] < resourceNames[j]
+	})
+	var utilization int64
+	var highest corev1.ResourceName
+	for _, resourceName := range resourceNames {
+		maxQuantity := maxQuota[resourceName]
+		if maxQuantity.MilliValue() <= 0 {
+			continue
+		}
+		usedQuantity := used[resourceName]
+		if u := usedQuantity.MilliValue() * 100 / maxQuantity.MilliValue(); u > utilization {
+			utilization, highest = u, resourceName
+		}
+	}
+	return utilization, highest
+}
+
+// getCapacityAlertLevel returns the highest threshold the utilization reaches, the thresholds are sorted ascending.
+func getCapacityAlertLevel(utilization int64, thresholds []int64) int64 {
+	var level int64
+	for _, threshold := range thresholds {
+		if utilization >= threshold {
+			level = threshold
+		}
+	}
+	return level
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+func TestController_syncCapacityAlerts(t *testing.T) {
+	lock := sync.Mutex{}
+	var alerts []*QuotaCapacityAlert
+	failed := false
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		if failed {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		alert := &QuotaCapacityAlert{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(alert))
+		alerts = append(alerts, alert)
+	}))
+	defer receiver.Close()
+	popAlerts := func() []*QuotaCapacityAlert {
+		lock.Lock()
+		defer lock.Unlock()
+		result := alerts
+		alerts = nil
+		return result
+	}
+
+	suit := newPluginTestSuit(t, nil)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.NoError(t, err)
+	gp := p.(*Plugin)
+	gp.pluginArgs.EnableRuntimeQuota = false
+	gp.pluginArgs.CapacityAlertWebhook = receiver.URL
+	gp.pluginArgs.CapacityAlertThresholds = []int64{80, 50}
+	gp.addQuota("test1", extension.RootQuotaName, 100, 1000, 0, 0, 100, 1000, false, "", "")
+	ctrl := NewElasticQuotaController(gp)
+
+	newAssignedPod := func(name string, cpu int64) *corev1.Pod {
+		pod := MakePod("ns", name).UID(name).Label(extension.LabelQuotaName, "test1").Container(
+			createResourceList(cpu, 10)).Obj()
+		pod.Spec.NodeName = "node1"
+		return pod
+	}
+
+	// under all the thresholds, no alert
+	ctrl.syncCapacityAlerts()
+	assert.Len(t, popAlerts(), 0)
+
+	// crosses 50%
+	pod1 := newAssignedPod("pod1", 60)
+	gp.OnPodAdd(pod1)
+	ctrl.syncCapacityAlerts()
+	got := popAlerts()
+	assert.Len(t, got, 1)
+	assert.Equal(t, "test1", got[0].Quota)
+	assert.Equal(t, int64(50), got[0].Level)
+	assert.Equal(t, int64(0), got[0].PreviousLevel)
+	assert.Equal(t, int64(60), got[0].Utilization)
+	assert.Equal(t, corev1.ResourceCPU, got[0].Resource)
+
+	// the level doesn't change, no alert
+	ctrl.syncCapacityAlerts()
+	assert.Len(t, popAlerts(), 0)
+
+	// crosses 80%, the failed alert is retried in the next cycle
+	pod2 := newAssignedPod("pod2", 30)
+	gp.OnPodAdd(pod2)
+	lock.Lock()
+	failed = true
+	lock.Unlock()
+	ctrl.syncCapacityAlerts()
+	assert.Equal(t, int64(50), ctrl.capacityAlertLevels["test1"])
+	lock.Lock()
+	failed = false
+	lock.Unlock()
+	ctrl.syncCapacityAlerts()
+	got = popAlerts()
+	assert.Len(t, got, 1)
+	assert.Equal(t, int64(80), got[0].Level)
+	assert.Equal(t, int64(50), got[0].PreviousLevel)
+	assert.Equal(t, int64(90), got[0].Utilization)
+
+	// falls under all the thresholds
+	gp.OnPodDelete(pod1)
+	gp.OnPodDelete(pod2)
+	ctrl.syncCapacityAlerts()
+	got = popAlerts()
+	assert.Len(t, got, 1)
+	assert.Equal(t, int64(0), got[0].Level)
+	assert.Equal(t, int64(80), got[0].PreviousLevel)
+	assert.Len(t, ctrl.capacityAlertLevels, 0)
+}
+
+func Test_getQuotaUtilization(t *testing.T) {
+	utilization, resourceName := getQuotaUtilization(createResourceList(30, 900), createResourceList(100, 1000))
+	assert.Equal(t, int64(90), utilization)
+	assert.Equal(t, corev1.ResourceMemory, resourceName)
+
+	utilization, resourceName = getQuotaUtilization(createResourceList(30, 900), corev1.ResourceList{})
+	assert.Equal(t, int64(0), utilization)
+	assert.Equal(t, corev1.ResourceName(""), resourceName)
+
+	assert.Equal(t, int64(0), getCapacityAlertLevel(49, []int64{50, 80}))
+	assert.Equal(t, int64(50), getCapacityAlertLevel(50, []int64{50, 80}))
+	assert.Equal(t, int64(80), getCapacityAlertLevel(100, []int64{50, 80}))
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+)
+
+const (
+	QuotaCostControllerName = "QuotaCostController"
+	QuotaCostSyncCycle      = 1 * time.Minute
+	// QuotaCostRetention is the max window of the resource-hours which can be queried.
+	QuotaCostRetention = 7 * 24 * time.Hour
+	// DefaultQuotaCostWindow is the default window of the resource-hours.
+	DefaultQuotaCostWindow = 24 * time.Hour
+)
+
+// QuotaResourceHours is the accumulated resource-hours of a quota over a window, e.g. 2 cpu used for 3 hours
+// is 6 cpu-hours. The memory is in bytes-hours.
+type QuotaResourceHours struct {
+	Name          string                          `json:"name"`
+	Window        string                          `json:"window"`
+	ResourceHours map[corev1.ResourceName]float64 `json:"resourceHours"`
+}
+
+// quotaCostSample is the resource-hours accumulated in the interval ending at the timestamp.
+type quotaCostSample struct {
+	timestamp     time.Time
+	resourceHours map[corev1.ResourceName]float64
+}
+
+// quotaCostAccountant accumulates the used of quotas over time for chargeback.
+type quotaCostAccountant struct {
+	lock         sync.RWMutex
+	clock        clock.Clock
+	lastSyncTime time.Time
+	// samples stores the samples of each quota in time order
+	samples map[string][]quotaCostSample
+}
+
+func newQuotaCostAccountant(clock clock.Clock) *quotaCostAccountant {
+	return &quotaCostAccountant{
+		clock:        clock,
+		lastSyncTime: clock.Now(),
+		samples:      make(map[string][]quotaCostSample),
+	}
+}
+
+// accumulate adds the used of quotas multiplied by the elapsed time since the last accumulation.
+func (a *quotaCostAccountant) accumulate(used map[string]corev1.ResourceList) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	now := a.clock.Now()
+	elapsedHours := now.Sub(a.lastSyncTime).Hours()
+	a.lastSyncTime = now
+	if elapsedHours <= 0 {
+		return
+	}
+
+	for quotaName, quotaUsed := range used {
+		resourceHours := make(map[corev1.ResourceName]float64, len(quotaUsed))
+		for resourceName, quantity := range quotaUsed {
+			if quantity.IsZero() {
+				continue
+			}
+			resourceHours[resourceName] = quantity.AsApproximateFloat64() * elapsedHours
+		}
+		if len(resourceHours) == 0 {
+			continue
+		}
+		a.samples[quotaName] = append(a.samples[quotaName], quotaCostSample{timestamp: now, resourceHours: resourceHours})
+	}
+
+	// drop the samples beyond the retention
+	expired := now.Add(-QuotaCostRetention)
+	for quotaName, samples := range a.samples {
+		i := 0
+		for i < len(samples) && !samples[i].timestamp.After(expired) {
+			i++
+		}
+		if i == len(samples) {
+			delete(a.samples, quotaName)
+		} else if i > 0 {
+			a.samples[quotaName] = append([]quotaCostSample(nil), samples[i:]...)
+		}
+	}
+}
+
+// getResourceHours returns the resource-hours of the quota accumulated within the window until now.
+func (a *quotaCostAccountant) getResourceHours(quotaName string, window time.Duration) map[corev1.ResourceName]float64 {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+
+	since := a.clock.Now().Add(-window)
+	resourceHours := make(map[corev1.ResourceName]float64)
+	for _, sample := range a.samples[quotaName] {
+		if !sample.timestamp.After(since) {
+			continue
+		}
+		for resourceName, hours := range sample.resourceHours {
+			resourceHours[resourceName] += hours
+		}
+	}
+	return resourceHours
+}
+
+// GetQuotaResourceHours returns the resource-hours of the quota over the window.
+func (g *Plugin) GetQuotaResourceHours(quotaName string, window time.Duration) (*QuotaResourceHours, bool) {
+	if _, exist := g.GetQuotaSummary(quotaName, false); !exist {
+		return nil, false
+	}
+	return &QuotaResourceHours{
+		Name:          quotaName,
+		Window:        window.String(),
+		ResourceHours: g.costAccountant.getResourceHours(quotaName, window),
+	}, true
+}
+
+// QuotaCostController samples the used of all quotas periodically to accumulate their resource-hours.
+type QuotaCostController struct {
+	plugin *Plugin
+}
+
+func NewQuotaCostController(plugin *Plugin) *QuotaCostController {
+	return &QuotaCostController{
+		plugin: plugin,
+	}
+}
+
+func (controller *QuotaCostController) Name() string {
+	return QuotaCostControllerName
+}
+
+func (controller *QuotaCostController) Start() {
+	go wait.Until(controller.syncQuotaCost, QuotaCostSyncCycle, nil)
+	klog.Infof("start elasticQuota QuotaCostController")
+}
+
+func (controller *QuotaCostController) syncQuotaCost() {
+	summaries := controller.plugin.GetQuotaSummaries("", false)
+	used := make(map[string]corev1.ResourceList, len(summaries))
+	for quotaName, summary := range summaries {
+		used[quotaName] = summary.Used
+	}
+	controller.plugin.costAccountant.accumulate(used)
+}
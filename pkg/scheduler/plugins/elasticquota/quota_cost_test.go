@@ -0,0 +1,138 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	fakeclock "k8s.io/utils/clock/testing"
+)
+
+func TestQuotaCostAccountant(t *testing.T) {
+	fakeClock := fakeclock.NewFakeClock(time.Now())
+	accountant := newQuotaCostAccountant(fakeClock)
+
+	fakeClock.Step(time.Hour)
+	accountant.accumulate(map[string]corev1.ResourceList{
+		"test1": createResourceList(2, 1024),
+		"test2": createResourceList(0, 0),
+	})
+	fakeClock.Step(2 * time.Hour)
+	accountant.accumulate(map[string]corev1.ResourceList{
+		"test1": createResourceList(4, 0),
+	})
+
+	assert.Equal(t, map[corev1.ResourceName]float64{
+		corev1.ResourceCPU:    2*1 + 4*2,
+		corev1.ResourceMemory: 1024,
+	}, accountant.getResourceHours("test1", DefaultQuotaCostWindow))
+	// only the last sample is in the window
+	assert.Equal(t, map[corev1.ResourceName]float64{
+		corev1.ResourceCPU: 4 * 2,
+	}, accountant.getResourceHours("test1", time.Hour))
+	// the zero used is not accumulated
+	assert.Empty(t, accountant.getResourceHours("test2", DefaultQuotaCostWindow))
+
+	// the samples beyond the retention are dropped
+	fakeClock.Step(QuotaCostRetention)
+	accountant.accumulate(map[string]corev1.ResourceList{
+		"test2": createResourceList(1, 0),
+	})
+	assert.Empty(t, accountant.getResourceHours("test1", QuotaCostRetention))
+	assert.Equal(t, map[corev1.ResourceName]float64{
+		corev1.ResourceCPU: QuotaCostRetention.Hours(),
+	}, accountant.getResourceHours("test2", QuotaCostRetention))
+}
+
+func TestEndpointsQueryQuotaResourceHours(t *testing.T) {
+	suit := newPluginTestSuit(t, nil)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.Nil(t, err)
+	plugin := p.(*Plugin)
+	fakeClock := fakeclock.NewFakeClock(time.Now())
+	plugin.clock = fakeClock
+	plugin.costAccountant = newQuotaCostAccountant(fakeClock)
+	plugin.OnQuotaAdd(CreateQuota2("test1", "", 100, 100, 10, 10, 20, 20, false, ""))
+	plugin.OnPodAdd(defaultCreatePodWithQuotaAndNonPreemptible("pod1", "test1", 10, 10, 10, false))
+
+	controller := NewQuotaCostController(plugin)
+	fakeClock.Step(time.Hour)
+	controller.syncQuotaCost()
+	plugin.OnPodAdd(defaultCreatePodWithQuotaAndNonPreemptible("pod2", "test1", 10, 10, 10, false))
+	fakeClock.Step(30 * time.Minute)
+	controller.syncQuotaCost()
+
+	tests := []struct {
+		name          string
+		url           string
+		expectedCode  int
+		expectedHours map[corev1.ResourceName]float64
+	}{
+		{
+			name:         "default window",
+			url:          "/quotas/test1/resourceHours",
+			expectedCode: http.StatusOK,
+			expectedHours: map[corev1.ResourceName]float64{
+				corev1.ResourceCPU:    10*1 + 20*0.5,
+				corev1.ResourceMemory: 10*1 + 20*0.5,
+			},
+		},
+		{
+			name:         "custom window",
+			url:          "/quotas/test1/resourceHours?window=20m",
+			expectedCode: http.StatusOK,
+			expectedHours: map[corev1.ResourceName]float64{
+				corev1.ResourceCPU:    20 * 0.5,
+				corev1.ResourceMemory: 20 * 0.5,
+			},
+		},
+		{
+			name:         "invalid window",
+			url:          "/quotas/test1/resourceHours?window=-1h",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "quota not found",
+			url:          "/quotas/not-exist/resourceHours",
+			expectedCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := gin.Default()
+			plugin.RegisterEndpoints(engine.Group("/"))
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", tt.url, nil)
+			engine.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectedCode, w.Result().StatusCode)
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+			resourceHours := &QuotaResourceHours{}
+			assert.NoError(t, json.NewDecoder(w.Result().Body).Decode(resourceHours))
+			assert.Equal(t, "test1", resourceHours.Name)
+			assert.InDeltaMapValues(t, tt.expectedHours, resourceHours.ResourceHours, 1e-6)
+		})
+	}
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	"github.com/koordinator-sh/koordinator/apis/thirdparty/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+)
+
+// applyDefaultParent returns a copy of the quota placed under DefaultParentQuotaName if the quota lacks the parent
+// label, the tree id of the parent is inherited as well. The quota itself is returned, i.e. it's placed under the
+// root quota, if the default parent isn't configured or doesn't exist.
+func (g *Plugin) applyDefaultParent(quota *v1alpha1.ElasticQuota) *v1alpha1.ElasticQuota {
+	parentName := g.pluginArgs.DefaultParentQuotaName
+	if parentName == "" || parentName == extension.RootQuotaName || parentName == quota.Name ||
+		quota.Name == extension.RootQuotaName || quota.Name == extension.SystemQuotaName ||
+		quota.Name == extension.DefaultQuotaName || quota.Labels[extension.LabelQuotaParent] != "" {
+		return quota
+	}
+
+	quotas, err := g.quotaLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list quotas to find the default parent of quota %v, err: %v", quota.Name, err)
+		return quota
+	}
+	var parent *v1alpha1.ElasticQuota
+	for _, eq := range quotas {
+		if eq.Name == parentName {
+			parent = eq
+			break
+		}
+	}
+	if parent == nil {
+		klog.V(4).Infof("default parent %v of quota %v doesn't exist, fall back to the root quota", parentName, quota.Name)
+		return quota
+	}
+
+	newQuota := quota.DeepCopy()
+	if newQuota.Labels == nil {
+		newQuota.Labels = map[string]string{}
+	}
+	newQuota.Labels[extension.LabelQuotaParent] = parentName
+	if newQuota.Labels[extension.LabelQuotaTreeID] == "" && parent.Labels[extension.LabelQuotaTreeID] != "" {
+		newQuota.Labels[extension.LabelQuotaTreeID] = parent.Labels[extension.LabelQuotaTreeID]
+	}
+	klog.V(5).Infof("quota %v lacks the parent label, placed under the default parent %v", quota.Name, parentName)
+	return newQuota
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+func TestPlugin_DefaultParent(t *testing.T) {
+	suit := newPluginTestSuit(t, nil)
+	suit.elasticQuotaArgs.DefaultParentQuotaName = "catch-all"
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.Nil(t, err)
+	gp := p.(*Plugin)
+
+	// the default parent doesn't exist yet, the quota falls back to the root quota
+	quota := CreateQuota2("team-a", "", 100, 1000, 10, 100, 10, 100, false, "")
+	assert.Equal(t, quota, gp.applyDefaultParent(quota))
+
+	catchAll := CreateQuota2("catch-all", extension.RootQuotaName, 100, 1000, 10, 100, 10, 100, true, "")
+	assert.NoError(t, gp.quotaInformer.GetStore().Add(catchAll))
+	gp.OnQuotaAdd(catchAll)
+
+	// the quota lacking the parent label is placed under the default parent
+	assert.NoError(t, gp.quotaInformer.GetStore().Add(quota))
+	gp.OnQuotaAdd(quota)
+	quotaInfo := gp.groupQuotaManager.GetQuotaInfoByName("team-a")
+	assert.NotNil(t, quotaInfo)
+	assert.Equal(t, "catch-all", quotaInfo.ParentName)
+	// the quota object is not modified
+	assert.Equal(t, "", quota.Labels[extension.LabelQuotaParent])
+
+	// the quota with the parent label keeps its parent
+	quota2 := CreateQuota2("team-b", extension.RootQuotaName, 100, 1000, 10, 100, 10, 100, false, "")
+	assert.NoError(t, gp.quotaInformer.GetStore().Add(quota2))
+	gp.OnQuotaAdd(quota2)
+	assert.Equal(t, extension.RootQuotaName, gp.groupQuotaManager.GetQuotaInfoByName("team-b").ParentName)
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+
+	"github.com/koordinator-sh/koordinator/apis/thirdparty/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+)
+
+const (
+	QuotaEntitlementControllerName = "QuotaEntitlementController"
+	QuotaEntitlementSyncCycle      = 1 * time.Minute
+)
+
+// EntitlementSource provides the entitled capacity of quotas from an external system, e.g. the licensed GPU count.
+// The max of a quota can't exceed its entitlement.
+type EntitlementSource interface {
+	// GetEntitlement returns the entitled resources of the quota. If the quota has no entitlement, returns false.
+	GetEntitlement(quotaName string) (corev1.ResourceList, bool)
+}
+
+// SetEntitlementSource sets the source which caps the max of quotas. It takes effect on the next quota event
+// or the next sync of QuotaEntitlementController.
+func (g *Plugin) SetEntitlementSource(source EntitlementSource) {
+	g.entitlementLock.Lock()
+	defer g.entitlementLock.Unlock()
+	g.entitlementSource = source
+}
+
+func (g *Plugin) getEntitlementSource() EntitlementSource {
+	g.entitlementLock.RLock()
+	defer g.entitlementLock.RUnlock()
+	return g.entitlementSource
+}
+
+// applyEntitlement returns a copy of the quota whose max is capped by its entitlement,
+// the min is capped as well to keep min <= max. The entitled resource absent from the max, which
+// isn't limited otherwise, is added to the max. The quota itself is returned if it's not capped.
+func (g *Plugin) applyEntitlement(quota *v1alpha1.ElasticQuota) *v1alpha1.ElasticQuota {
+	source := g.getEntitlementSource()
+	if source == nil {
+		return quota
+	}
+	entitlement, ok := source.GetEntitlement(quota.Name)
+	if !ok {
+		return quota
+	}
+
+	var newQuota *v1alpha1.ElasticQuota
+	for resourceName, entitled := range entitlement {
+		max, ok := quota.Spec.Max[resourceName]
+		if ok && max.Cmp(entitled) <= 0 {
+			continue
+		}
+		if newQuota == nil {
+			newQuota = quota.DeepCopy()
+		}
+		if newQuota.Spec.Max == nil {
+			newQuota.Spec.Max = corev1.ResourceList{}
+		}
+		newQuota.Spec.Max[resourceName] = entitled.DeepCopy()
+		if min, ok := newQuota.Spec.Min[resourceName]; ok && min.Cmp(entitled) > 0 {
+			newQuota.Spec.Min[resourceName] = entitled.DeepCopy()
+		}
+	}
+	if newQuota == nil {
+		return quota
+	}
+	klog.V(5).Infof("quota %v is capped by entitlement, max: %v, entitled max: %v", quota.Name, quota.Spec.Max, newQuota.Spec.Max)
+	return newQuota
+}
+
+// QuotaEntitlementController re-evaluates the entitlements of quotas periodically,
+// so the max of quotas follows the changes of the entitlement source.
+type QuotaEntitlementController struct {
+	plugin *Plugin
+}
+
+func NewQuotaEntitlementController(plugin *Plugin) *QuotaEntitlementController {
+	return &QuotaEntitlementController{
+		plugin: plugin,
+	}
+}
+
+func (controller *QuotaEntitlementController) Name() string {
+	return QuotaEntitlementControllerName
+}
+
+func (controller *QuotaEntitlementController) Start() {
+	go wait.Until(controller.syncEntitlement, QuotaEntitlementSyncCycle, nil)
+	klog.Infof("start elasticQuota QuotaEntitlementController")
+}
+
+func (controller *QuotaEntitlementController) syncEntitlement() {
+	if controller.plugin.getEntitlementSource() == nil {
+		return
+	}
+	quotas, err := controller.plugin.quotaLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list elastic quotas in QuotaEntitlementController, err: %v", err)
+		return
+	}
+	for _, quota := range quotas {
+		// OnQuotaUpdate applies the current entitlement and only updates the quota if the max changes.
+		controller.plugin.OnQuotaUpdate(quota, quota)
+	}
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	quotav1 "k8s.io/apiserver/pkg/quota/v1"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+type fakeEntitlementSource struct {
+	entitlements map[string]corev1.ResourceList
+}
+
+func (f *fakeEntitlementSource) GetEntitlement(quotaName string) (corev1.ResourceList, bool) {
+	entitlement, ok := f.entitlements[quotaName]
+	return entitlement, ok
+}
+
+func TestPlugin_EntitlementCapsMax(t *testing.T) {
+	suit := newPluginTestSuit(t, nil)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.Nil(t, err)
+	gp := p.(*Plugin)
+	gp.pluginArgs.EnableRuntimeQuota = false
+
+	source := &fakeEntitlementSource{
+		entitlements: map[string]corev1.ResourceList{
+			"test1": {corev1.ResourceCPU: *resource.NewMilliQuantity(40*1000, resource.DecimalSI)},
+		},
+	}
+	gp.SetEntitlementSource(source)
+
+	quota := gp.addQuota("test1", extension.RootQuotaName, 100, 1000, 50, 500, 10, 100, false, "", "")
+	pod := defaultCreatePodWithQuotaAndNonPreemptible("pod1", "test1", 10, 60, 60, false)
+	pod.Spec.NodeName = ""
+
+	// the max and min are capped by the entitlement, other resources are not affected
+	quotaInfo := gp.groupQuotaManager.GetQuotaInfoByName("test1")
+	assert.True(t, quotav1.Equals(createResourceList(40, 1000), quotaInfo.GetMax()))
+	assert.True(t, quotav1.Equals(createResourceList(40, 500), quotaInfo.GetMin()))
+	assert.False(t, gp.WouldAdmit(pod).IsSuccess())
+
+	// the entitlement is raised, the max follows it on the next sync
+	source.entitlements["test1"] = corev1.ResourceList{corev1.ResourceCPU: *resource.NewMilliQuantity(80*1000, resource.DecimalSI)}
+	gp.OnQuotaUpdate(quota, quota)
+	quotaInfo = gp.groupQuotaManager.GetQuotaInfoByName("test1")
+	assert.True(t, quotav1.Equals(createResourceList(80, 1000), quotaInfo.GetMax()))
+	assert.True(t, quotav1.Equals(createResourceList(50, 500), quotaInfo.GetMin()))
+	assert.True(t, gp.WouldAdmit(pod).IsSuccess())
+
+	// the entitlement larger than max doesn't raise the max
+	source.entitlements["test1"] = corev1.ResourceList{corev1.ResourceCPU: *resource.NewMilliQuantity(200*1000, resource.DecimalSI)}
+	gp.OnQuotaUpdate(quota, quota)
+	assert.True(t, quotav1.Equals(createResourceList(100, 1000), gp.groupQuotaManager.GetQuotaInfoByName("test1").GetMax()))
+
+	// the quota without entitlement is not capped
+	delete(source.entitlements, "test1")
+	gp.OnQuotaUpdate(quota, quota)
+	assert.True(t, quotav1.Equals(createResourceList(100, 1000), gp.groupQuotaManager.GetQuotaInfoByName("test1").GetMax()))
+
+	// the entitled resource absent from the max is added to the max
+	source.entitlements["test1"] = corev1.ResourceList{extension.ResourceNvidiaGPU: *resource.NewQuantity(4, resource.DecimalSI)}
+	gp.OnQuotaUpdate(quota, quota)
+	wantMax := createResourceList(100, 1000)
+	wantMax[extension.ResourceNvidiaGPU] = *resource.NewQuantity(4, resource.DecimalSI)
+	assert.True(t, quotav1.Equals(wantMax, gp.groupQuotaManager.GetQuotaInfoByName("test1").GetMax()))
+
+	// the quota object is not modified
+	assert.True(t, quotav1.Equals(createResourceList(100, 1000), quota.Spec.Max))
+	assert.True(t, quotav1.Equals(createResourceList(50, 500), quota.Spec.Min))
+}
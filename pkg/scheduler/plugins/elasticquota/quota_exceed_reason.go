@@ -0,0 +1,64 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+const quotaExceedReasonKey = "QuotaExceedReason" + Name
+
+// QuotaExceedReason is why the pod is rejected by the quota in PreFilter, which lets the other plugins and
+// the tools act on the rejection without parsing the message of the status.
+type QuotaExceedReason struct {
+	// QuotaName is the quota whose limit is exceeded, which may be an ancestor of the quota of the pod.
+	QuotaName string
+	// TopoPath is the path from the exceeded quota down to the quota of the pod.
+	TopoPath []string
+	// NonPreemptible is true if the non-preemptible used of the quota is exceeded,
+	// Runtime and Used are the limit and the used of the non-preemptible pods then.
+	NonPreemptible bool
+	Runtime        corev1.ResourceList
+	Used           corev1.ResourceList
+	Request        corev1.ResourceList
+	// ExceedDimensions are sorted in the same order as the message of the status.
+	ExceedDimensions []corev1.ResourceName
+}
+
+func (r *QuotaExceedReason) Clone() framework.StateData {
+	return &QuotaExceedReason{
+		QuotaName:        r.QuotaName,
+		TopoPath:         append([]string(nil), r.TopoPath...),
+		NonPreemptible:   r.NonPreemptible,
+		Runtime:          r.Runtime.DeepCopy(),
+		Used:             r.Used.DeepCopy(),
+		Request:          r.Request.DeepCopy(),
+		ExceedDimensions: append([]corev1.ResourceName(nil), r.ExceedDimensions...),
+	}
+}
+
+// GetQuotaExceedReason returns why the pod is rejected by the quota in PreFilter of the scheduling cycle,
+// false if the pod isn't rejected for exceeding a quota.
+func GetQuotaExceedReason(cycleState *framework.CycleState) (*QuotaExceedReason, bool) {
+	c, err := cycleState.Read(quotaExceedReasonKey)
+	if err != nil {
+		return nil, false
+	}
+	r, ok := c.(*QuotaExceedReason)
+	return r, ok
+}
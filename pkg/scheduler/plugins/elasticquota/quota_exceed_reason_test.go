@@ -0,0 +1,83 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+func TestPlugin_PreFilter_QuotaExceedReason(t *testing.T) {
+	suit := newPluginTestSuit(t, nil)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.Nil(t, err)
+	gp := p.(*Plugin)
+	gp.pluginArgs.EnableRuntimeQuota = false
+	gp.pluginArgs.EnableCheckParentQuota = true
+	gp.OnQuotaAdd(CreateQuota2("parent", extension.RootQuotaName, 15, 1000, 10, 100, 10, 1000, true, ""))
+	gp.OnQuotaAdd(CreateQuota2("test1", "parent", 10, 1000, 10, 100, 10, 1000, false, ""))
+	gp.OnQuotaAdd(CreateQuota2("test2", "parent", 10, 1000, 10, 100, 10, 1000, false, ""))
+
+	runningPod := MakePod("t1-ns1", "pod0").UID("pod0").Label(extension.LabelQuotaName, "test1").Container(
+		createResourceList(8, 10)).Obj()
+	runningPod.Spec.NodeName = "n1"
+	gp.OnPodAdd(runningPod)
+
+	// the admitted pod carries no reason
+	cycleState := framework.NewCycleState()
+	fitPod := MakePod("t1-ns1", "pod1").UID("pod1").Label(extension.LabelQuotaName, "test2").Container(
+		createResourceList(1, 10)).Obj()
+	_, status := gp.PreFilter(context.TODO(), cycleState, fitPod)
+	assert.True(t, status.IsSuccess())
+	_, ok := GetQuotaExceedReason(cycleState)
+	assert.False(t, ok)
+
+	// the quota of the pod is exceeded
+	cycleState = framework.NewCycleState()
+	pod := MakePod("t1-ns1", "pod2").UID("pod2").Label(extension.LabelQuotaName, "test1").Container(
+		createResourceList(5, 10)).Obj()
+	_, status = gp.PreFilter(context.TODO(), cycleState, pod)
+	assert.False(t, status.IsSuccess())
+	reason, ok := GetQuotaExceedReason(cycleState)
+	assert.True(t, ok)
+	assert.Equal(t, "test1", reason.QuotaName)
+	assert.Equal(t, []string{"test1"}, reason.TopoPath)
+	assert.False(t, reason.NonPreemptible)
+	assert.Equal(t, []corev1.ResourceName{corev1.ResourceCPU}, reason.ExceedDimensions)
+	assert.True(t, reason.Request.Cpu().Equal(*createResourceList(5, 10).Cpu()))
+	assert.True(t, reason.Used.Cpu().Equal(*createResourceList(8, 10).Cpu()))
+	// the message is kept
+	assert.Contains(t, status.Message(), "exceedDimensions: [cpu]")
+
+	// the parent of the pod's quota is exceeded
+	cycleState = framework.NewCycleState()
+	pod = MakePod("t1-ns1", "pod3").UID("pod3").Label(extension.LabelQuotaName, "test2").Container(
+		createResourceList(8, 10)).Obj()
+	_, status = gp.PreFilter(context.TODO(), cycleState, pod)
+	assert.False(t, status.IsSuccess())
+	reason, ok = GetQuotaExceedReason(cycleState)
+	assert.True(t, ok)
+	assert.Equal(t, "parent", reason.QuotaName)
+	assert.Equal(t, []string{"parent", "test2"}, reason.TopoPath)
+	assert.Equal(t, []corev1.ResourceName{corev1.ResourceCPU}, reason.ExceedDimensions)
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+const (
+	// ReasonQuotaExceeded is the reason of the event recorded on the pod rejected by its quota in PreFilter.
+	ReasonQuotaExceeded = "QuotaExceeded"
+
+	// QuotaExceededEventInterval is the minimal interval between the QuotaExceeded events of the same pod and quota,
+	// which avoids the event storms during the repeated scheduling attempts of the pod.
+	QuotaExceededEventInterval = 1 * time.Minute
+)
+
+// recordQuotaExceededEvent records the quota rejection on the pod, the message carries the quota name and the
+// exceeded dimensions, so the users can tell why the pod is pending without the scheduler logs.
+func (g *Plugin) recordQuotaExceededEvent(pod *corev1.Pod, quotaName string, status *framework.Status) {
+	if !g.shouldRecordQuotaExceededEvent(pod.UID, quotaName) {
+		return
+	}
+	g.handle.EventRecorder().Eventf(pod, nil, corev1.EventTypeWarning, ReasonQuotaExceeded, "Scheduling",
+		"Pod is rejected by quota %v: %v", quotaName, status.Message())
+}
+
+func (g *Plugin) shouldRecordQuotaExceededEvent(podUID types.UID, quotaName string) bool {
+	g.quotaExceededEventLock.Lock()
+	defer g.quotaExceededEventLock.Unlock()
+	now := g.clock.Now()
+	lastRecorded, ok := g.quotaExceededEvents[podUID]
+	if !ok {
+		lastRecorded = make(map[string]time.Time)
+		g.quotaExceededEvents[podUID] = lastRecorded
+	}
+	if last, ok := lastRecorded[quotaName]; ok && now.Sub(last) < QuotaExceededEventInterval {
+		return false
+	}
+	lastRecorded[quotaName] = now
+	return true
+}
+
+func (g *Plugin) forgetQuotaExceededEvents(podUID types.UID) {
+	g.quotaExceededEventLock.Lock()
+	defer g.quotaExceededEventLock.Unlock()
+	delete(g.quotaExceededEvents, podUID)
+}
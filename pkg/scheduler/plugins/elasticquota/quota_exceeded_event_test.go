@@ -0,0 +1,73 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	fakeclock "k8s.io/utils/clock/testing"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+func TestPlugin_PreFilter_QuotaExceededEvent(t *testing.T) {
+	suit := newPluginTestSuit(t, nil)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.Nil(t, err)
+	gp := p.(*Plugin)
+	fakeClock := fakeclock.NewFakeClock(time.Now())
+	gp.clock = fakeClock
+	gp.pluginArgs.EnableRuntimeQuota = false
+	gp.OnQuotaAdd(CreateQuota2("test1", extension.RootQuotaName, 10, 1000, 10, 100, 10, 1000, false, ""))
+
+	// the pod within the quota records no event
+	fitPod := MakePod("t1-ns1", "pod1").UID("pod1").Label(extension.LabelQuotaName, "test1").Container(
+		createResourceList(5, 10)).Obj()
+	_, status := gp.PreFilter(context.TODO(), framework.NewCycleState(), fitPod)
+	assert.True(t, status.IsSuccess())
+	assert.Equal(t, 0, len(suit.fakeRecorder.Events))
+
+	// the rejected pod records the quota and the exceeded dimensions
+	pod := MakePod("t1-ns1", "pod2").UID("pod2").Label(extension.LabelQuotaName, "test1").Container(
+		createResourceList(20, 10)).Obj()
+	_, status = gp.PreFilter(context.TODO(), framework.NewCycleState(), pod)
+	assert.False(t, status.IsSuccess())
+	assert.Equal(t, 1, len(suit.fakeRecorder.Events))
+	event := <-suit.fakeRecorder.Events
+	assert.Contains(t, event, ReasonQuotaExceeded)
+	assert.Contains(t, event, "test1")
+	assert.Contains(t, event, "exceedDimensions: [cpu]")
+
+	// the repeated rejections are throttled
+	_, status = gp.PreFilter(context.TODO(), framework.NewCycleState(), pod)
+	assert.False(t, status.IsSuccess())
+	assert.Equal(t, 0, len(suit.fakeRecorder.Events))
+
+	fakeClock.Step(QuotaExceededEventInterval)
+	_, status = gp.PreFilter(context.TODO(), framework.NewCycleState(), pod)
+	assert.False(t, status.IsSuccess())
+	assert.Equal(t, 1, len(suit.fakeRecorder.Events))
+	assert.Contains(t, <-suit.fakeRecorder.Events, ReasonQuotaExceeded)
+
+	// the records are forgotten once the pod is deleted
+	gp.OnPodDelete(pod)
+	assert.NotContains(t, gp.quotaExceededEvents, pod.UID)
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	quotav1 "k8s.io/apiserver/pkg/quota/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/koordinator-sh/koordinator/pkg/scheduler/plugins/coscheduling/util"
+	"github.com/koordinator-sh/koordinator/pkg/scheduler/plugins/elasticquota/core"
+)
+
+// GangQuotaSummary shows the status of a gang together with the quota headroom of its members,
+// which helps to find out whether a stuck gang is blocked by the quotas.
+type GangQuotaSummary struct {
+	Name              string   `json:"name"`
+	MinRequiredNumber int      `json:"minRequiredNumber"`
+	TotalChildrenNum  int      `json:"totalChildrenNum"`
+	PendingChildren   []string `json:"pendingChildren"`
+	BoundChildren     []string `json:"boundChildren"`
+	// Quotas are the quotas of the gang members, the key is the quota name.
+	Quotas map[string]*GangQuotaHeadroom `json:"quotas"`
+}
+
+type GangQuotaHeadroom struct {
+	Children  []string            `json:"children"`
+	Used      corev1.ResourceList `json:"used"`
+	UsedLimit corev1.ResourceList `json:"usedLimit"`
+	// Headroom is the resource the quota can still admit.
+	Headroom corev1.ResourceList `json:"headroom"`
+	// PendingRequest is the sum of the requests of the pending members in the quota.
+	PendingRequest corev1.ResourceList `json:"pendingRequest"`
+	// Sufficient is true if the headroom covers the pending request.
+	Sufficient bool `json:"sufficient"`
+}
+
+// GetGangQuotaSummary returns the summary of the gang in the namespace, it returns false if the gang has no pods.
+func (g *Plugin) GetGangQuotaSummary(namespace, gangName string) (*GangQuotaSummary, bool) {
+	pods, err := g.podLister.Pods(namespace).List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list pods of gang %v/%v, err: %v", namespace, gangName, err)
+		return nil, false
+	}
+
+	summary := &GangQuotaSummary{
+		Name:            util.GetId(namespace, gangName),
+		PendingChildren: []string{},
+		BoundChildren:   []string{},
+		Quotas:          map[string]*GangQuotaHeadroom{},
+	}
+	for _, pod := range pods {
+		if util.GetGangNameByPod(pod) != gangName {
+			continue
+		}
+		summary.TotalChildrenNum++
+		if minNum, err := util.GetGangMinNumFromPod(pod); err == nil && minNum > summary.MinRequiredNumber {
+			summary.MinRequiredNumber = minNum
+		}
+		isPending := pod.Spec.NodeName == ""
+		if isPending {
+			summary.PendingChildren = append(summary.PendingChildren, pod.Name)
+		} else {
+			summary.BoundChildren = append(summary.BoundChildren, pod.Name)
+		}
+
+		quotaName, treeID := g.getPodAssociateQuotaNameAndTreeID(pod)
+		if quotaName == "" {
+			continue
+		}
+		headroom := summary.Quotas[quotaName]
+		if headroom == nil {
+			headroom = g.newGangQuotaHeadroom(quotaName, treeID)
+			if headroom == nil {
+				continue
+			}
+			summary.Quotas[quotaName] = headroom
+		}
+		headroom.Children = append(headroom.Children, pod.Name)
+		if isPending {
+			podRequest := quotav1.Mask(core.PodRequests(pod), quotav1.ResourceNames(headroom.UsedLimit))
+			headroom.PendingRequest = quotav1.Add(headroom.PendingRequest, podRequest)
+		}
+	}
+	if summary.TotalChildrenNum == 0 {
+		return nil, false
+	}
+
+	sort.Strings(summary.PendingChildren)
+	sort.Strings(summary.BoundChildren)
+	for _, headroom := range summary.Quotas {
+		sort.Strings(headroom.Children)
+		headroom.Sufficient, _ = quotav1.LessThanOrEqual(headroom.PendingRequest, headroom.Headroom)
+	}
+	return summary, true
+}
+
+func (g *Plugin) newGangQuotaHeadroom(quotaName, treeID string) *GangQuotaHeadroom {
+	mgr := g.GetGroupQuotaManagerForTree(treeID)
+	if mgr == nil {
+		return nil
+	}
+	if g.pluginArgs.EnableRuntimeQuota {
+		mgr.RefreshRuntime(quotaName)
+	}
+	quotaInfo := mgr.GetQuotaInfoByName(quotaName)
+	if quotaInfo == nil {
+		return nil
+	}
+	used := quotaInfo.GetUsed()
+	usedLimit := g.getQuotaInfoUsedLimit(quotaInfo)
+	return &GangQuotaHeadroom{
+		Used:           used,
+		UsedLimit:      usedLimit,
+		Headroom:       quotav1.SubtractWithNonNegativeResult(usedLimit, quotav1.Mask(used, quotav1.ResourceNames(usedLimit))),
+		PendingRequest: corev1.ResourceList{},
+	}
+}
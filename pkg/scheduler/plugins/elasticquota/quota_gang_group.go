@@ -0,0 +1,297 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	quotav1 "k8s.io/apiserver/pkg/quota/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	"github.com/koordinator-sh/koordinator/pkg/scheduler/plugins/coscheduling/util"
+	"github.com/koordinator-sh/koordinator/pkg/scheduler/plugins/elasticquota/core"
+)
+
+// defaultGangQuotaReservationTimeout is how long the quota is reserved for the pending members of a gang if
+// the gang doesn't declare its wait time, which is the default wait time of the gangs in coscheduling.
+const defaultGangQuotaReservationTimeout = 600 * time.Second
+
+// gangGroupQuotaDemand is the requests of the pending members of a gang group in one quota.
+type gangGroupQuotaDemand struct {
+	mgr       *core.GroupQuotaManager
+	quotaInfo *core.QuotaInfo
+	members   []*corev1.Pod
+	requests  []corev1.ResourceList
+	total     corev1.ResourceList
+}
+
+// getGangGroup returns the gangs bundled with the gang of the pod, the gang makes up the group by itself
+// if the pod doesn't declare the gang group.
+func getGangGroup(pod *corev1.Pod) []string {
+	gangName := util.GetGangNameByPod(pod)
+	if gangName == "" {
+		return nil
+	}
+	if s := pod.Annotations[extension.AnnotationGangGroups]; s != "" {
+		if gangGroup, err := util.StringToGangGroupSlice(s); err == nil && len(gangGroup) > 0 {
+			return gangGroup
+		}
+		klog.V(4).Infof("pod %v has invalid gang group %v, fall back to its gang", klog.KObj(pod), s)
+	}
+	return []string{util.GetId(pod.Namespace, gangName)}
+}
+
+// getGangQuotaReservationTimeout returns how long the quota is reserved for the pending members of the gang
+// of the pod, which is the wait time of the gang, so the reservation lasts until the gang satisfies or times out.
+func getGangQuotaReservationTimeout(pod *corev1.Pod) time.Duration {
+	waitTime, err := time.ParseDuration(pod.Annotations[extension.AnnotationGangWaitTime])
+	if err != nil || waitTime <= 0 {
+		return defaultGangQuotaReservationTimeout
+	}
+	return waitTime
+}
+
+// getGangID returns the id of the gang the pod belongs to, it's empty if the pod isn't a gang member.
+func getGangID(pod *corev1.Pod) string {
+	gangName := util.GetGangNameByPod(pod)
+	if gangName == "" {
+		return ""
+	}
+	return util.GetId(pod.Namespace, gangName)
+}
+
+// updateGangMember keeps the gang members cache up to date with the pod events, so the members of a gang
+// group are found by their gangs instead of listing all the pods of the namespaces.
+func (g *Plugin) updateGangMember(oldPod, newPod *corev1.Pod) {
+	g.gangMemberLock.Lock()
+	defer g.gangMemberLock.Unlock()
+
+	if oldPod != nil {
+		if gangID := getGangID(oldPod); gangID != "" {
+			delete(g.gangMembers[gangID], oldPod.UID)
+			if len(g.gangMembers[gangID]) == 0 {
+				delete(g.gangMembers, gangID)
+			}
+		}
+	}
+	if newPod != nil {
+		if gangID := getGangID(newPod); gangID != "" {
+			members := g.gangMembers[gangID]
+			if members == nil {
+				members = map[types.UID]*corev1.Pod{}
+				g.gangMembers[gangID] = members
+			}
+			members[newPod.UID] = newPod
+		}
+	}
+}
+
+// listGangGroupPendingMembers lists the members of the gang group not bound to any node yet.
+func (g *Plugin) listGangGroupPendingMembers(gangGroup []string) []*corev1.Pod {
+	g.gangMemberLock.RLock()
+	defer g.gangMemberLock.RUnlock()
+
+	var members []*corev1.Pod
+	for _, gangID := range gangGroup {
+		for _, pod := range g.gangMembers[gangID] {
+			if pod.Spec.NodeName != "" || pod.DeletionTimestamp != nil {
+				continue
+			}
+			members = append(members, pod)
+		}
+	}
+	return members
+}
+
+// checkGangGroupQuotaAndGrantAdmissionTokens checks the quotas of all the pending members of the gang group of
+// the pod together, and grants the admission tokens to all of them at once if every involved quota has the
+// headroom for its members. So the gang group either proceeds with the quotas reserved for all its members,
+// or waits as a whole instead of taking part of the quotas it can't make use of.
+func (g *Plugin) checkGangGroupQuotaAndGrantAdmissionTokens(pod *corev1.Pod) *framework.Status {
+	gangGroup := getGangGroup(pod)
+	if len(gangGroup) == 0 {
+		return nil
+	}
+	gangGroupID := util.GetGangGroupId(gangGroup)
+	members := g.listGangGroupPendingMembers(gangGroup)
+	if !containsPod(members, pod) {
+		members = append(members, pod)
+	}
+
+	demands := map[string]*gangGroupQuotaDemand{}
+	memberUIDs := sets.NewString()
+	for _, member := range members {
+		quotaName, treeID := g.getPodAssociateQuotaNameAndTreeID(member)
+		if quotaName == "" || g.isBypassNamespace(member.Namespace) {
+			continue
+		}
+		demand := demands[quotaName]
+		if demand == nil {
+			mgr := g.GetGroupQuotaManagerForTree(treeID)
+			if mgr == nil {
+				continue
+			}
+			if g.pluginArgs.EnableRuntimeQuota {
+				mgr.RefreshRuntime(quotaName)
+			}
+			quotaInfo := mgr.GetQuotaInfoByName(quotaName)
+			if quotaInfo == nil {
+				continue
+			}
+			demand = &gangGroupQuotaDemand{mgr: mgr, quotaInfo: quotaInfo}
+			demands[quotaName] = demand
+		}
+		// the member reserved already is accounted in the used
+		if demand.quotaInfo.CheckPodIsAssigned(member) {
+			continue
+		}
+		request := quotav1.Mask(demand.mgr.PodRequests(quotaName, member), quotav1.ResourceNames(demand.quotaInfo.CalculateInfo.Max))
+		demand.members = append(demand.members, member)
+		demand.requests = append(demand.requests, request)
+		demand.total = quotav1.Add(demand.total, request)
+		memberUIDs.Insert(string(member.UID))
+	}
+
+	quotaNames := make([]string, 0, len(demands))
+	for quotaName := range demands {
+		quotaNames = append(quotaNames, quotaName)
+	}
+	sort.Strings(quotaNames)
+
+	g.admissionTokenLock.Lock()
+	defer g.admissionTokenLock.Unlock()
+	for _, quotaName := range quotaNames {
+		if status := g.checkGangGroupQuotaDemandNoLock(gangGroupID, quotaName, demands[quotaName], memberUIDs); !status.IsSuccess() {
+			g.releaseGangGroupAdmissionTokensNoLock(gangGroupID)
+			return status
+		}
+	}
+
+	// the members are bound one by one while the gang waits in Permit, so the reservation of the pending members
+	// outlives the admissionTokenTTL and is given back once the gang times out or fails.
+	deadline := g.clock.Now().Add(getGangQuotaReservationTimeout(pod))
+	for quotaName, demand := range demands {
+		quotaPath := getQuotaPath(demand.mgr, quotaName)
+		for i, member := range demand.members {
+			g.admissionTokens[member.UID] = &admissionToken{
+				quotaName:   quotaName,
+				quotaPath:   quotaPath,
+				request:     demand.requests[i],
+				deadline:    deadline,
+				gangGroupID: gangGroupID,
+			}
+		}
+	}
+	klog.V(5).Infof("gang group %v of pod %v is admitted, quota reserved for %v members", gangGroupID, klog.KObj(pod), len(memberUIDs))
+	return nil
+}
+
+// checkGangGroupQuotaDemandNoLock checks the members of the gang group in the quota one by one as checkQuota does
+// for a single pod, each member is checked on top of the used of the members checked before it. The parents are
+// checked with the total request of the members at last, since checkQuota checks them with a single member.
+func (g *Plugin) checkGangGroupQuotaDemandNoLock(gangGroupID, quotaName string, demand *gangGroupQuotaDemand,
+	memberUIDs sets.String) *framework.Status {
+	quotaInfo := demand.quotaInfo
+	maxNames := quotav1.ResourceNames(quotaInfo.CalculateInfo.Max)
+	used := quotav1.Mask(quotaInfo.GetUsed(), maxNames)
+	used = quotav1.Add(used, g.getPendingAdmissionUsedExceptNoLock(quotaName, memberUIDs))
+	nonPreemptibleUsed := quotaInfo.GetNonPreemptibleUsed()
+	usedLimit := g.getQuotaInfoUsedLimit(quotaInfo)
+	for i, member := range demand.members {
+		status, _ := g.checkQuota(demand.mgr, quotaInfo, member, demand.requests[i], used, nonPreemptibleUsed, usedLimit, memberUIDs)
+		if !status.IsSuccess() {
+			return framework.NewStatus(status.Code(), fmt.Sprintf("Insufficient quotas for gang group %v, member: %v, %v",
+				gangGroupID, member.Name, status.Message()))
+		}
+		used = quotav1.Add(used, demand.requests[i])
+		if demand.mgr.IsPodNonPreemptible(quotaName, member) {
+			nonPreemptibleUsed = quotav1.Add(nonPreemptibleUsed, demand.requests[i])
+		}
+	}
+	if g.pluginArgs.EnableCheckParentQuota && len(demand.members) > 1 {
+		status, _ := g.checkQuotaRecursive(demand.mgr, quotaInfo.ParentName, []string{quotaInfo.ParentName, quotaName}, demand.total, memberUIDs)
+		if !status.IsSuccess() {
+			return framework.NewStatus(status.Code(), fmt.Sprintf("Insufficient quotas for gang group %v, %v",
+				gangGroupID, status.Message()))
+		}
+	}
+	return nil
+}
+
+// releaseTimedOutGangGroupAdmissionTokens gives back the quota reserved for the gang group of the pod once
+// coscheduling marks the gang of the pod timed out.
+func (g *Plugin) releaseTimedOutGangGroupAdmissionTokens(oldPod, newPod *corev1.Pod) {
+	if newPod.Annotations[extension.AnnotationGangTimeout] != "true" || oldPod.Annotations[extension.AnnotationGangTimeout] == "true" {
+		return
+	}
+	gangGroup := getGangGroup(newPod)
+	if len(gangGroup) == 0 {
+		return
+	}
+	gangGroupID := util.GetGangGroupId(gangGroup)
+	g.admissionTokenLock.Lock()
+	defer g.admissionTokenLock.Unlock()
+	g.releaseGangGroupAdmissionTokensNoLock(gangGroupID)
+	klog.V(4).Infof("gang group %v of pod %v times out, release its quota reservation", gangGroupID, klog.KObj(newPod))
+}
+
+// getGangReservedNoLock returns the quota reserved for the pending gang members in the quota.
+func (g *Plugin) getGangReservedNoLock(quotaName string) corev1.ResourceList {
+	now := g.clock.Now()
+	reserved := corev1.ResourceList{}
+	for _, token := range g.admissionTokens {
+		if token.gangGroupID == "" || token.quotaName != quotaName || !now.Before(token.deadline) {
+			continue
+		}
+		reserved = quotav1.Add(reserved, token.request)
+	}
+	return reserved
+}
+
+// fillGangReserved sets the quota reserved for the pending gang members in the summary.
+func (g *Plugin) fillGangReserved(summary *core.QuotaInfoSummary) {
+	g.admissionTokenLock.Lock()
+	defer g.admissionTokenLock.Unlock()
+	if reserved := g.getGangReservedNoLock(summary.Name); !quotav1.IsZero(reserved) {
+		summary.GangReserved = reserved
+	}
+}
+
+// releaseGangGroupAdmissionTokensNoLock gives back the quotas reserved for the members of the gang group.
+func (g *Plugin) releaseGangGroupAdmissionTokensNoLock(gangGroupID string) {
+	for uid, token := range g.admissionTokens {
+		if token.gangGroupID == gangGroupID {
+			delete(g.admissionTokens, uid)
+		}
+	}
+}
+
+func containsPod(pods []*corev1.Pod, pod *corev1.Pod) bool {
+	for _, p := range pods {
+		if p.UID == pod.UID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,209 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	quotav1 "k8s.io/apiserver/pkg/quota/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	fakeclock "k8s.io/utils/clock/testing"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+func TestPlugin_GangGroupQuotaReservation(t *testing.T) {
+	newGangPod := func(name, gangName, quotaName string) *corev1.Pod {
+		pod := MakePod("ns", name).UID(name).Label(extension.LabelQuotaName, quotaName).Container(
+			createResourceList(10, 10)).Obj()
+		pod.Annotations = map[string]string{
+			extension.AnnotationGangName:   gangName,
+			extension.AnnotationGangMinNum: "2",
+			extension.AnnotationGangGroups: `["ns/gang-a","ns/gang-b"]`,
+		}
+		return pod
+	}
+
+	tests := []struct {
+		name          string
+		quotaBMaxCPU  int64
+		wantAdmitted  bool
+		wantTokens    int
+		wantMessageOf string
+	}{
+		{
+			name:          "quota b lacks headroom for its members, the gang group waits as a whole",
+			quotaBMaxCPU:  10,
+			wantAdmitted:  false,
+			wantTokens:    0,
+			wantMessageOf: "quotaName: qb",
+		},
+		{
+			name:         "both quotas have headroom, reserved together for all members",
+			quotaBMaxCPU: 40,
+			wantAdmitted: true,
+			wantTokens:   4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suit := newPluginTestSuit(t, nil)
+			p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+			assert.NoError(t, err)
+			gp := p.(*Plugin)
+			gp.pluginArgs.EnableRuntimeQuota = false
+			gp.pluginArgs.EnableGangGroupQuotaReservation = true
+			gp.addQuota("qa", extension.RootQuotaName, 100, 1000, 0, 0, 100, 1000, false, "", "")
+			gp.addQuota("qb", extension.RootQuotaName, tt.quotaBMaxCPU, 1000, 0, 0, tt.quotaBMaxCPU, 1000, false, "", "")
+
+			a1 := newGangPod("a1", "gang-a", "qa")
+			a2 := newGangPod("a2", "gang-a", "qa")
+			b1 := newGangPod("b1", "gang-b", "qb")
+			b2 := newGangPod("b2", "gang-b", "qb")
+			podStore := suit.Handle.SharedInformerFactory().Core().V1().Pods().Informer().GetStore()
+			for _, pod := range []*corev1.Pod{a1, a2, b1, b2} {
+				assert.NoError(t, podStore.Add(pod))
+				gp.OnPodAdd(pod)
+			}
+
+			_, status := gp.PreFilter(context.TODO(), framework.NewCycleState(), a1)
+			assert.Equal(t, tt.wantAdmitted, status.IsSuccess())
+			if tt.wantMessageOf != "" {
+				assert.Contains(t, status.Message(), tt.wantMessageOf)
+			}
+			assert.Equal(t, tt.wantTokens, len(gp.admissionTokens))
+			if !tt.wantAdmitted {
+				return
+			}
+
+			// the quota reserved for the gang group can't be taken by the other pods
+			other := MakePod("ns", "other").UID("other").Label(extension.LabelQuotaName, "qb").Container(
+				createResourceList(30, 10)).Obj()
+			gp.OnPodAdd(other)
+			_, status = gp.PreFilter(context.TODO(), framework.NewCycleState(), other)
+			assert.False(t, status.IsSuccess())
+
+			// the members of the gang group are admitted by their reservations
+			for _, pod := range []*corev1.Pod{a2, b1, b2} {
+				_, status = gp.PreFilter(context.TODO(), framework.NewCycleState(), pod)
+				assert.True(t, status.IsSuccess(), pod.Name)
+			}
+
+			// the gang group fails, all the reservations are given back together
+			gp.releaseAdmissionToken(b1)
+			assert.Equal(t, 0, len(gp.admissionTokens))
+			_, status = gp.PreFilter(context.TODO(), framework.NewCycleState(), other)
+			assert.True(t, status.IsSuccess())
+		})
+	}
+}
+
+func TestPlugin_GangGroupQuotaReservationChecksEachMember(t *testing.T) {
+	newGangPod := func(name string) *corev1.Pod {
+		pod := MakePod("ns", name).UID(name).Label(extension.LabelQuotaName, "qa").
+			Label(extension.LabelPreemptible, "false").Container(createResourceList(10, 10)).Obj()
+		pod.Annotations = map[string]string{
+			extension.AnnotationGangName:   "gang-a",
+			extension.AnnotationGangMinNum: "2",
+		}
+		return pod
+	}
+
+	suit := newPluginTestSuit(t, nil)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.NoError(t, err)
+	gp := p.(*Plugin)
+	gp.pluginArgs.EnableRuntimeQuota = false
+	gp.pluginArgs.EnableGangGroupQuotaReservation = true
+	// the max fits the gang, but the min doesn't fit its non-preemptible members
+	gp.addQuota("qa", extension.RootQuotaName, 100, 1000, 15, 1000, 100, 1000, false, "", "")
+
+	a1 := newGangPod("a1")
+	a2 := newGangPod("a2")
+	for _, pod := range []*corev1.Pod{a1, a2} {
+		gp.OnPodAdd(pod)
+	}
+	_, status := gp.PreFilter(context.TODO(), framework.NewCycleState(), a1)
+	assert.False(t, status.IsSuccess())
+	assert.Contains(t, status.Message(), "Insufficient non-preemptible quotas")
+	assert.Equal(t, 0, len(gp.admissionTokens))
+
+	// the deleted member is removed from the gang, the rest of the gang fits the min
+	gp.OnPodDelete(a2)
+	assert.Equal(t, []*corev1.Pod{a1}, gp.listGangGroupPendingMembers([]string{"ns/gang-a"}))
+	_, status = gp.PreFilter(context.TODO(), framework.NewCycleState(), a1)
+	assert.True(t, status.IsSuccess())
+}
+
+func TestPlugin_GangQuotaReservationLastsForGangWaitTime(t *testing.T) {
+	newGangPod := func(name string) *corev1.Pod {
+		pod := MakePod("ns", name).UID(name).Label(extension.LabelQuotaName, "qa").ResourceVersion("1").
+			Container(createResourceList(10, 10)).Obj()
+		pod.Annotations = map[string]string{
+			extension.AnnotationGangName:     "gang-a",
+			extension.AnnotationGangMinNum:   "2",
+			extension.AnnotationGangWaitTime: "2m",
+		}
+		return pod
+	}
+
+	suit := newPluginTestSuit(t, nil)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.NoError(t, err)
+	gp := p.(*Plugin)
+	fakeClock := fakeclock.NewFakeClock(time.Now())
+	gp.clock = fakeClock
+	gp.pluginArgs.EnableRuntimeQuota = false
+	gp.pluginArgs.EnableGangGroupQuotaReservation = true
+	gp.addQuota("qa", extension.RootQuotaName, 100, 1000, 0, 0, 100, 1000, false, "", "")
+
+	a1 := newGangPod("a1")
+	a2 := newGangPod("a2")
+	for _, pod := range []*corev1.Pod{a1, a2} {
+		gp.OnPodAdd(pod)
+	}
+	getGangReserved := func() corev1.ResourceList {
+		summary, ok := gp.GetQuotaSummary("qa", false)
+		assert.True(t, ok)
+		return summary.GangReserved
+	}
+
+	_, status := gp.PreFilter(context.TODO(), framework.NewCycleState(), a1)
+	assert.True(t, status.IsSuccess())
+	assert.True(t, quotav1.Equals(createResourceList(20, 20), getGangReserved()))
+
+	// the reservation of the pending member outlives the admissionTokenTTL until the gang times out
+	fakeClock.Step(time.Minute)
+	assert.True(t, quotav1.Equals(createResourceList(10, 10), getGangReserved()))
+	fakeClock.Step(time.Minute)
+	assert.Nil(t, getGangReserved())
+
+	// the reservation is given back once coscheduling marks the gang timed out
+	_, status = gp.PreFilter(context.TODO(), framework.NewCycleState(), a1)
+	assert.True(t, status.IsSuccess())
+	assert.True(t, quotav1.Equals(createResourceList(20, 20), getGangReserved()))
+	timedOut := a2.DeepCopy()
+	timedOut.ResourceVersion = "2"
+	timedOut.Annotations[extension.AnnotationGangTimeout] = "true"
+	gp.OnPodUpdate(a2, timedOut)
+	assert.Nil(t, getGangReserved())
+	assert.Equal(t, 0, len(gp.admissionTokens))
+}
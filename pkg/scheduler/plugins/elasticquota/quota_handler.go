@@ -21,6 +21,7 @@ import (
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	quotav1 "k8s.io/apiserver/pkg/quota/v1"
 	k8sfeature "k8s.io/apiserver/pkg/util/feature"
 	"k8s.io/klog/v2"
@@ -29,6 +30,7 @@ import (
 	schedulerv1alpha1 "github.com/koordinator-sh/koordinator/apis/thirdparty/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
 	koordfeatures "github.com/koordinator-sh/koordinator/pkg/features"
 	"github.com/koordinator-sh/koordinator/pkg/scheduler/plugins/elasticquota/core"
+	"github.com/koordinator-sh/koordinator/pkg/util"
 )
 
 func (g *Plugin) OnQuotaAdd(obj interface{}) {
@@ -38,10 +40,12 @@ func (g *Plugin) OnQuotaAdd(obj interface{}) {
 		return
 	}
 
-	if quota.DeletionTimestamp != nil {
+	if quota.DeletionTimestamp != nil && !g.acceptTerminatingQuota(quota) {
 		klog.Errorf("quota is deleting: %v", quota.Name)
 		return
 	}
+	defer g.syncTreeBorrowing()
+	quota = g.applyEntitlement(g.applyIdleMinReclaim(g.applyScheduledMin(g.applyDefaultParent(quota))))
 
 	klog.V(5).Infof("OnQuotaAddFunc add quota: %v", quota.Name)
 	mgr := g.GetOrCreateGroupQuotaManagerForTree(quota.Labels[extension.LabelQuotaTreeID])
@@ -60,16 +64,19 @@ func (g *Plugin) OnQuotaAdd(obj interface{}) {
 		klog.V(5).Infof("OnQuotaAddFunc failed: %v, tree: %v, err: %v", quota.Name, treeID, err)
 		return
 	}
+	g.startQuotaWarmUp(quota)
 	klog.V(5).Infof("OnQuotaAddFunc success: %v, tree: %v", quota.Name, treeID)
 }
 
 func (g *Plugin) OnQuotaUpdate(oldObj, newObj interface{}) {
 	newQuota := newObj.(*schedulerv1alpha1.ElasticQuota)
 
-	if newQuota.DeletionTimestamp != nil {
+	if newQuota.DeletionTimestamp != nil && !g.acceptTerminatingQuota(newQuota) {
 		klog.Warningf("update quota warning, update is deleting: %v", newQuota.Name)
 		return
 	}
+	defer g.syncTreeBorrowing()
+	newQuota = g.applyEntitlement(g.applyIdleMinReclaim(g.applyScheduledMin(g.applyDefaultParent(newQuota))))
 
 	// forbidden change quota tree.
 	klog.V(5).Infof("OnQuotaUpdateFunc update quota: %v", newQuota.Name)
@@ -104,9 +111,22 @@ func (g *Plugin) OnQuotaDelete(obj interface{}) {
 		klog.Errorf("quota is nil")
 		return
 	}
+	// the quota is looked up in the tree of the default parent it was placed under
+	quota = g.applyDefaultParent(quota)
+	defer g.syncTreeBorrowing()
 
 	klog.V(5).Infof("OnQuotaDeleteFunc delete quota: %v", quota.Name)
+	if (quota.Name == extension.SystemQuotaName || quota.Name == extension.DefaultQuotaName) &&
+		g.GetGroupQuotaManagerForTree(quota.Labels[extension.LabelQuotaTreeID]) == g.groupQuotaManager {
+		// the pods still fall into SystemQuotaGroup and DefaultQuotaGroup, they go back to the configured max.
+		g.restoreSystemOrDefaultQuotaMax(quota)
+		return
+	}
 	g.deleteQuotaToTreeMap(quota.Name)
+	g.stopQuotaWarmUp(quota.Name)
+	g.forgetTerminatingQuota(quota.Name)
+	g.usageCollector.forget(quota.Name)
+	g.forgetQuotaIdle(quota.Name)
 	mgr := g.GetGroupQuotaManagerForTree(quota.Labels[extension.LabelQuotaTreeID])
 	if mgr == nil {
 		return
@@ -124,11 +144,34 @@ func (g *Plugin) OnQuotaDelete(obj interface{}) {
 
 }
 
+// restoreSystemOrDefaultQuotaMax restores SystemQuotaGroup or DefaultQuotaGroup to the max of the plugin args
+// once its ElasticQuota is deleted, the other attributes set by the ElasticQuota are dropped.
+func (g *Plugin) restoreSystemOrDefaultQuotaMax(quota *schedulerv1alpha1.ElasticQuota) {
+	configuredMax := g.pluginArgs.DefaultQuotaGroupMax
+	if quota.Name == extension.SystemQuotaName {
+		configuredMax = g.pluginArgs.SystemQuotaGroupMax
+	}
+	restored := &schedulerv1alpha1.ElasticQuota{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      quota.Name,
+			Namespace: quota.Namespace,
+		},
+		Spec: schedulerv1alpha1.ElasticQuotaSpec{
+			Max: configuredMax.DeepCopy(),
+		},
+	}
+	if err := g.groupQuotaManager.UpdateQuota(restored); err != nil {
+		klog.Errorf("failed to restore the max of quota %v, err: %v", quota.Name, err)
+		return
+	}
+	klog.V(4).Infof("OnQuotaDeleteFunc restore the max of quota %v to %v", quota.Name, util.DumpJSON(configuredMax))
+}
+
 func (g *Plugin) ReplaceQuotas(objs []interface{}) error {
 	quotas := make([]*schedulerv1alpha1.ElasticQuota, 0, len(objs))
 	for _, obj := range objs {
 		quota := obj.(*schedulerv1alpha1.ElasticQuota)
-		quotas = append(quotas, quota)
+		quotas = append(quotas, g.applyEntitlement(g.applyIdleMinReclaim(g.applyScheduledMin(g.applyDefaultParent(quota)))))
 	}
 
 	start := time.Now()
@@ -139,6 +182,10 @@ func (g *Plugin) ReplaceQuotas(objs []interface{}) error {
 	g.groupQuotaManagersForQuotaTree = make(map[string]*core.GroupQuotaManager)
 	g.groupQuotaManager = core.NewGroupQuotaManager("", g.pluginArgs.SystemQuotaGroupMax,
 		g.pluginArgs.DefaultQuotaGroupMax)
+	g.groupQuotaManager.SetResourceClaimClassGetter(g.resourceClaimClassGetter)
+	g.groupQuotaManager.SetDefaultNonPreemptibleQuotas(g.pluginArgs.DefaultNonPreemptibleQuotas)
+	g.groupQuotaManager.SetGuaranteeMinRuntime(g.pluginArgs.GuaranteeMinRuntime)
+	g.groupQuotaManager.SetRuntimeShrinkDelay(g.pluginArgs.RuntimeShrinkDelay.Duration)
 	err := g.groupQuotaManager.InitHookPlugins(g.pluginArgs)
 	if err != nil {
 		return err
@@ -149,7 +196,7 @@ func (g *Plugin) ReplaceQuotas(objs []interface{}) error {
 	g.quotaToTreeMap[extension.SystemQuotaName] = ""
 
 	for _, quota := range quotas {
-		if quota.DeletionTimestamp != nil {
+		if quota.DeletionTimestamp != nil && !g.acceptTerminatingQuota(quota) {
 			continue
 		}
 		mgr := g.GetOrCreateGroupQuotaManagerForTree(quota.Labels[extension.LabelQuotaTreeID])
@@ -157,6 +204,7 @@ func (g *Plugin) ReplaceQuotas(objs []interface{}) error {
 		g.updateQuotaToTreeMap(quota.Name, treeID)
 		g.handlerQuotaWhenRoot(quota, mgr, false)
 		mgr.UpdateQuotaInfo(quota)
+		g.startQuotaWarmUp(quota)
 	}
 
 	g.groupQuotaManager.ResetQuota()
@@ -169,7 +217,32 @@ func (g *Plugin) ReplaceQuotas(objs []interface{}) error {
 
 func (g *Plugin) GetQuotaSummary(quotaName string, includePods bool) (*core.QuotaInfoSummary, bool) {
 	mgr := g.GetGroupQuotaManagerForQuota(quotaName)
-	return mgr.GetQuotaSummary(quotaName, includePods)
+	summary, exist := mgr.GetQuotaSummary(quotaName, includePods)
+	if exist {
+		g.fillGangReserved(summary)
+	}
+	return summary, exist
+}
+
+// DefaultSteadyStateDuration is the default duration after which a running pod's used is counted as steady-state used.
+const DefaultSteadyStateDuration = 10 * time.Minute
+
+// QuotaUsedByAge distinguishes the sustained used from the transient used of a quota.
+type QuotaUsedByAge struct {
+	// SteadyUsed is the used of the pods running longer than the steady-state duration.
+	SteadyUsed corev1.ResourceList `json:"steadyUsed"`
+	// BurstUsed is the used of the recently scheduled pods.
+	BurstUsed corev1.ResourceList `json:"burstUsed"`
+}
+
+// GetQuotaUsedByAge returns the steady-state used and the burst used of the quota at the current time.
+func (g *Plugin) GetQuotaUsedByAge(quotaName string, steadyDuration time.Duration) (*QuotaUsedByAge, bool) {
+	mgr := g.GetGroupQuotaManagerForQuota(quotaName)
+	steadyUsed, burstUsed, exist := mgr.GetQuotaSteadyAndBurstUsed(quotaName, g.clock.Now(), steadyDuration)
+	if !exist {
+		return nil, false
+	}
+	return &QuotaUsedByAge{SteadyUsed: steadyUsed, BurstUsed: burstUsed}, true
 }
 
 func (g *Plugin) GetQuotaSummaries(tree string, includePods bool) map[string]*core.QuotaInfoSummary {
@@ -191,6 +264,26 @@ func (g *Plugin) GetQuotaSummaries(tree string, includePods bool) map[string]*co
 		}
 	}
 
+	for _, summary := range summaries {
+		g.fillGangReserved(summary)
+	}
+	return summaries
+}
+
+// GetQuotaTopologySummaries returns the quota trees, the key is the tree id. All the trees are returned if
+// the tree is empty.
+func (g *Plugin) GetQuotaTopologySummaries(tree string, includePods bool) map[string]*core.QuotaTopologySummary {
+	summaries := make(map[string]*core.QuotaTopologySummary)
+
+	managers := append(g.ListGroupQuotaManagersForQuotaTree(), g.groupQuotaManager)
+	for _, mgr := range managers {
+		if tree != "" && mgr.GetTreeID() != tree {
+			continue
+		}
+		if summary := mgr.GetQuotaTopologySummary(includePods); summary != nil {
+			summaries[mgr.GetTreeID()] = summary
+		}
+	}
 	return summaries
 }
 
@@ -218,6 +311,10 @@ func (g *Plugin) GetOrCreateGroupQuotaManagerForTree(treeID string) *core.GroupQ
 	if !ok {
 		mgr = core.NewGroupQuotaManager(treeID, g.pluginArgs.SystemQuotaGroupMax, g.pluginArgs.DefaultQuotaGroupMax)
 		g.groupQuotaManagersForQuotaTree[treeID] = mgr
+		mgr.SetResourceClaimClassGetter(g.resourceClaimClassGetter)
+		mgr.SetDefaultNonPreemptibleQuotas(g.pluginArgs.DefaultNonPreemptibleQuotas)
+		mgr.SetGuaranteeMinRuntime(g.pluginArgs.GuaranteeMinRuntime)
+		mgr.SetRuntimeShrinkDelay(g.pluginArgs.RuntimeShrinkDelay.Duration)
 		err := mgr.InitHookPlugins(g.pluginArgs)
 		if err != nil {
 			klog.Error(err.Error())
@@ -297,13 +394,24 @@ func (g *Plugin) deleteQuotaToTreeMap(quota string) {
 	g.quotaToTreeMapLock.Unlock()
 }
 
-// handlerQuotaForRoot will update quota tree total resource when the quota is root quota and enable MultiQuotaTree
+// handlerQuotaForRoot will update quota tree total resource, default scheduling strategy, runtime refresh strategy and runtime distribution when the quota is root quota
+// and enable MultiQuotaTree
 func (g *Plugin) handlerQuotaWhenRoot(quota *schedulerv1alpha1.ElasticQuota, mgr *core.GroupQuotaManager, isDelete bool) {
 	if !k8sfeature.DefaultFeatureGate.Enabled(koordfeatures.MultiQuotaTree) ||
 		quota.Labels[extension.LabelQuotaIsRoot] != "true" || mgr.GetTreeID() == "" {
 		return
 	}
 
+	if isDelete {
+		// the quotas of the tree stop inheriting the default of the deleted root quota
+		mgr.SetSchedulingStrategy("")
+	} else {
+		mgr.SetSchedulingStrategy(extension.GetSchedulingStrategy(quota))
+		mgr.SetRuntimeRefreshStrategy(extension.GetRuntimeRefreshStrategy(quota))
+		mgr.SetRuntimeDistribution(extension.GetRuntimeDistribution(quota))
+		mgr.SetLenderTrees(extension.GetLenderTrees(quota))
+	}
+
 	totalResource, ok := getTotalResource(quota)
 	if ok {
 		var delta corev1.ResourceList
@@ -17,6 +17,7 @@ limitations under the License.
 package elasticquota
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
@@ -26,6 +27,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	quotav1 "k8s.io/apiserver/pkg/quota/v1"
 	k8sfeature "k8s.io/apiserver/pkg/util/feature"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
 
 	"github.com/koordinator-sh/koordinator/apis/extension"
 	koordfeatures "github.com/koordinator-sh/koordinator/pkg/features"
@@ -366,3 +368,56 @@ func TestPlugin_ReplaceQuotas(t *testing.T) {
 	runtime = plugin.groupQuotaManager.RefreshRuntime("test2")
 	assert.Equal(t, createResourceList(0, 0), runtime)
 }
+
+func TestPlugin_UpdateSystemAndDefaultQuotaMax(t *testing.T) {
+	suit := newPluginTestSuit(t, nil)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.Nil(t, err)
+	gp := p.(*Plugin)
+	gp.pluginArgs.EnableRuntimeQuota = false
+	// wait for the DefaultQuotaGroup and SystemQuotaGroup created by the plugin
+	time.Sleep(100 * time.Millisecond)
+	gqm := gp.groupQuotaManager
+
+	defaultQuota := CreateQuota2(extension.DefaultQuotaName, extension.RootQuotaName, 10, 20, 0, 0, 10, 20, false, "")
+	gp.OnQuotaAdd(defaultQuota)
+	assert.True(t, quotav1.Equals(createResourceList(10, 20), gqm.GetQuotaInfoByName(extension.DefaultQuotaName).GetMax()))
+	assert.True(t, quotav1.Equals(createResourceList(10, 20), gqm.RefreshRuntime(extension.DefaultQuotaName)))
+
+	pod := MakePod("t1-ns1", "pod1").Container(createResourceList(8, 10)).Obj()
+	_, status := gp.PreFilter(context.TODO(), framework.NewCycleState(), pod)
+	assert.True(t, status.IsSuccess(), status.Message())
+
+	// shrink the max, the pods beyond the new max are rejected at once
+	newDefaultQuota := defaultQuota.DeepCopy()
+	newDefaultQuota.ResourceVersion = "2"
+	newDefaultQuota.Spec.Max = createResourceList(5, 20)
+	newDefaultQuota.Spec.Min = createResourceList(2, 4)
+	gp.OnQuotaUpdate(defaultQuota, newDefaultQuota)
+	assert.True(t, quotav1.Equals(createResourceList(5, 20), gqm.RefreshRuntime(extension.DefaultQuotaName)))
+	assert.True(t, quotav1.Equals(createResourceList(2, 4), gqm.GetQuotaInfoByName(extension.DefaultQuotaName).GetMin()))
+	_, status = gp.PreFilter(context.TODO(), framework.NewCycleState(), pod)
+	assert.Equal(t, framework.Unschedulable, status.Code())
+
+	// the attributes other than the max are updated as well
+	suspendedDefaultQuota := newDefaultQuota.DeepCopy()
+	suspendedDefaultQuota.ResourceVersion = "3"
+	suspendedDefaultQuota.Labels[extension.LabelQuotaSuspend] = "true"
+	gp.OnQuotaUpdate(newDefaultQuota, suspendedDefaultQuota)
+	assert.True(t, gqm.GetQuotaInfoByName(extension.DefaultQuotaName).IsSuspended())
+
+	// deleting the quota restores the configured max
+	gp.OnQuotaDelete(suspendedDefaultQuota)
+	quotaInfo := gqm.GetQuotaInfoByName(extension.DefaultQuotaName)
+	assert.NotNil(t, quotaInfo)
+	assert.True(t, quotav1.Equals(gp.pluginArgs.DefaultQuotaGroupMax, quotaInfo.GetMax()))
+	assert.True(t, quotav1.IsZero(quotaInfo.GetMin()))
+	assert.False(t, quotaInfo.IsSuspended())
+	assert.Equal(t, extension.RootQuotaName, quotaInfo.ParentName)
+
+	systemQuota := CreateQuota2(extension.SystemQuotaName, extension.RootQuotaName, 30, 40, 0, 0, 30, 40, false, "")
+	gp.OnQuotaUpdate(systemQuota, systemQuota)
+	assert.True(t, quotav1.Equals(createResourceList(30, 40), gqm.RefreshRuntime(extension.SystemQuotaName)))
+	gp.OnQuotaDelete(systemQuota)
+	assert.True(t, quotav1.Equals(gp.pluginArgs.SystemQuotaGroupMax, gqm.GetQuotaInfoByName(extension.SystemQuotaName).GetMax()))
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	quotav1 "k8s.io/apiserver/pkg/quota/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/koordinator-sh/koordinator/pkg/scheduler/plugins/elasticquota/core"
+	"github.com/koordinator-sh/koordinator/pkg/util"
+)
+
+// podHandoff is the quota used released by the deleted pods of a workload, which is kept for the
+// replacement pods of the workload until they're assigned or the handoff expires.
+type podHandoff struct {
+	quotaName string
+	used      corev1.ResourceList
+	deadline  time.Time
+}
+
+// getPodWorkloadUID returns the uid of the workload the pod belongs to. The pods owned by a ReplicaSet belong to
+// the Deployment controlling the ReplicaSet, so that the new ReplicaSet of a rollout takes over the handoff of the
+// old one. The ReplicaSet itself is the workload if it isn't found or isn't controlled by others.
+func (g *Plugin) getPodWorkloadUID(pod *corev1.Pod) (types.UID, bool) {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return "", false
+	}
+	if owner.Kind != "ReplicaSet" || g.replicaSetLister == nil {
+		return owner.UID, true
+	}
+	replicaSet, err := g.replicaSetLister.ReplicaSets(pod.Namespace).Get(owner.Name)
+	if err != nil || replicaSet.UID != owner.UID {
+		return owner.UID, true
+	}
+	if rsOwner := metav1.GetControllerOf(replicaSet); rsOwner != nil {
+		return rsOwner.UID, true
+	}
+	return owner.UID, true
+}
+
+// startPodHandoff keeps the used of the deleted pod for the replacement pod of the same workload,
+// so the slot isn't grabbed by other workloads before the replacement is scheduled.
+func (g *Plugin) startPodHandoff(quotaName string, pod *corev1.Pod) {
+	duration := g.pluginArgs.PodReplacementHandoffDuration.Duration
+	if duration <= 0 || pod.Spec.NodeName == "" || util.IsPodTerminated(pod) {
+		return
+	}
+	workloadUID, ok := g.getPodWorkloadUID(pod)
+	if !ok {
+		return
+	}
+
+	g.podHandoffLock.Lock()
+	defer g.podHandoffLock.Unlock()
+	deadline := g.clock.Now().Add(duration)
+	handoff := g.podHandoffs[workloadUID]
+	if handoff == nil || handoff.quotaName != quotaName || !g.clock.Now().Before(handoff.deadline) {
+		handoff = &podHandoff{quotaName: quotaName}
+		g.podHandoffs[workloadUID] = handoff
+	}
+	handoff.used = quotav1.Add(handoff.used, core.PodRequests(pod))
+	handoff.deadline = deadline
+	klog.V(5).Infof("pod %v hands off quota %v used %v to its workload %v until %v",
+		klog.KObj(pod), quotaName, printResourceList(handoff.used), workloadUID, deadline)
+}
+
+// finishPodHandoff takes the used of the assigned replacement pod off the handoff of its workload,
+// since the used of the replacement pod is accounted in the quota now.
+func (g *Plugin) finishPodHandoff(pod *corev1.Pod) {
+	workloadUID, ok := g.getPodWorkloadUID(pod)
+	if !ok {
+		return
+	}
+
+	g.podHandoffLock.Lock()
+	defer g.podHandoffLock.Unlock()
+	handoff := g.podHandoffs[workloadUID]
+	if handoff == nil {
+		return
+	}
+	handoff.used = quotav1.SubtractWithNonNegativeResult(handoff.used, core.PodRequests(pod))
+	if quotav1.IsZero(handoff.used) {
+		delete(g.podHandoffs, workloadUID)
+	}
+}
+
+// getPodHandoffUsed returns the used handed off to the other workloads of the quota, which the pod can't take.
+func (g *Plugin) getPodHandoffUsed(quotaName string, pod *corev1.Pod) corev1.ResourceList {
+	g.podHandoffLock.Lock()
+	defer g.podHandoffLock.Unlock()
+	if len(g.podHandoffs) == 0 {
+		return nil
+	}
+
+	workloadUID, _ := g.getPodWorkloadUID(pod)
+	now := g.clock.Now()
+	var used corev1.ResourceList
+	for uid, handoff := range g.podHandoffs {
+		if !now.Before(handoff.deadline) {
+			delete(g.podHandoffs, uid)
+			continue
+		}
+		if handoff.quotaName != quotaName || uid == workloadUID {
+			continue
+		}
+		used = quotav1.Add(used, handoff.used)
+	}
+	return used
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	fakeclock "k8s.io/utils/clock/testing"
+	"k8s.io/utils/pointer"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	"github.com/koordinator-sh/koordinator/pkg/scheduler/apis/config"
+)
+
+func TestPlugin_PodReplacementHandoff(t *testing.T) {
+	newWorkloadPod := func(name, workload, nodeName string) *corev1.Pod {
+		pod := MakePod("t1-ns1", name).UID(name).Label(extension.LabelQuotaName, "test1").Container(
+			createResourceList(6, 60)).Obj()
+		pod.OwnerReferences = []metav1.OwnerReference{
+			{Kind: "ReplicaSet", Name: workload, UID: types.UID(workload), Controller: pointer.Bool(true)},
+		}
+		pod.Spec.NodeName = nodeName
+		return pod
+	}
+	preFilter := func(gp *Plugin, pod *corev1.Pod) bool {
+		_, status := gp.PreFilter(context.TODO(), framework.NewCycleState(), pod)
+		return status.IsSuccess()
+	}
+
+	tests := []struct {
+		name                   string
+		handoffDuration        time.Duration
+		elapsed                time.Duration
+		expectOtherAdmitted    bool
+		expectReplacementAdmit bool
+	}{
+		{
+			name:                   "handoff disabled, the slot is released",
+			expectOtherAdmitted:    true,
+			expectReplacementAdmit: true,
+		},
+		{
+			name:                   "the slot stays with the workload",
+			handoffDuration:        time.Minute,
+			expectOtherAdmitted:    false,
+			expectReplacementAdmit: true,
+		},
+		{
+			name:                   "the handoff expires",
+			handoffDuration:        time.Minute,
+			elapsed:                time.Minute,
+			expectOtherAdmitted:    true,
+			expectReplacementAdmit: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suit := newPluginTestSuit(t, nil, func(elasticQuotaArgs *config.ElasticQuotaArgs) {
+				elasticQuotaArgs.PodReplacementHandoffDuration = metav1.Duration{Duration: tt.handoffDuration}
+			})
+			p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+			assert.Nil(t, err)
+			gp := p.(*Plugin)
+			fakeClock := fakeclock.NewFakeClock(time.Now())
+			gp.clock = fakeClock
+			gp.pluginArgs.EnableRuntimeQuota = false
+			gp.OnQuotaAdd(CreateQuota2("test1", extension.RootQuotaName, 10, 100, 10, 100, 10, 100, false, ""))
+
+			oldPod := newWorkloadPod("old", "workload-a", "node1")
+			gp.OnPodAdd(oldPod)
+			gp.OnPodDelete(oldPod)
+			fakeClock.Step(tt.elapsed)
+
+			otherPod := newWorkloadPod("other", "workload-b", "")
+			assert.Equal(t, tt.expectOtherAdmitted, preFilter(gp, otherPod))
+			replacement := newWorkloadPod("new", "workload-a", "")
+			assert.Equal(t, tt.expectReplacementAdmit, preFilter(gp, replacement))
+
+			// the replacement is assigned, the handoff is finished and its used is accounted
+			assignedReplacement := replacement.DeepCopy()
+			assignedReplacement.Spec.NodeName = "node1"
+			gp.OnPodAdd(assignedReplacement)
+			assert.False(t, preFilter(gp, otherPod))
+			gp.podHandoffLock.Lock()
+			assert.Equal(t, 0, len(gp.podHandoffs))
+			gp.podHandoffLock.Unlock()
+		})
+	}
+}
+
+func TestPlugin_PodReplacementHandoffAcrossRollout(t *testing.T) {
+	suit := newPluginTestSuit(t, nil, func(elasticQuotaArgs *config.ElasticQuotaArgs) {
+		elasticQuotaArgs.PodReplacementHandoffDuration = metav1.Duration{Duration: time.Minute}
+	})
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.Nil(t, err)
+	gp := p.(*Plugin)
+	gp.pluginArgs.EnableRuntimeQuota = false
+	gp.OnQuotaAdd(CreateQuota2("test1", extension.RootQuotaName, 10, 100, 10, 100, 10, 100, false, ""))
+
+	// the old and new ReplicaSets of a rollout are controlled by the same Deployment
+	rsStore := suit.Handle.SharedInformerFactory().Apps().V1().ReplicaSets().Informer().GetStore()
+	for _, name := range []string{"rs-old", "rs-new"} {
+		assert.NoError(t, rsStore.Add(&appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "t1-ns1",
+				Name:      name,
+				UID:       types.UID(name),
+				OwnerReferences: []metav1.OwnerReference{
+					{Kind: "Deployment", Name: "deploy", UID: "deploy", Controller: pointer.Bool(true)},
+				},
+			},
+		}))
+	}
+	newReplicaSetPod := func(name, replicaSet, nodeName string) *corev1.Pod {
+		pod := MakePod("t1-ns1", name).UID(name).Label(extension.LabelQuotaName, "test1").Container(
+			createResourceList(6, 60)).Obj()
+		pod.OwnerReferences = []metav1.OwnerReference{
+			{Kind: "ReplicaSet", Name: replicaSet, UID: types.UID(replicaSet), Controller: pointer.Bool(true)},
+		}
+		pod.Spec.NodeName = nodeName
+		return pod
+	}
+
+	oldPod := newReplicaSetPod("old", "rs-old", "node1")
+	gp.OnPodAdd(oldPod)
+	gp.OnPodDelete(oldPod)
+
+	// the pod of another workload can't take the slot, the pod of the new ReplicaSet can
+	otherPod := MakePod("t1-ns1", "other").UID("other").Label(extension.LabelQuotaName, "test1").Container(
+		createResourceList(6, 60)).Obj()
+	_, status := gp.PreFilter(context.TODO(), framework.NewCycleState(), otherPod)
+	assert.False(t, status.IsSuccess())
+	_, status = gp.PreFilter(context.TODO(), framework.NewCycleState(), newReplicaSetPod("new", "rs-new", ""))
+	assert.True(t, status.IsSuccess())
+}
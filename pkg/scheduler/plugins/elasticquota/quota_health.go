@@ -0,0 +1,34 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+// QuotaHealth reports whether the quota enforcement is reliable. The quotas are enforced reliably only after
+// the ElasticQuotas are synced and the quotas of the managers are reconciled against them.
+type QuotaHealth struct {
+	Synced     bool `json:"synced"`
+	Reconciled bool `json:"reconciled"`
+	Ready      bool `json:"ready"`
+}
+
+func (g *Plugin) GetQuotaHealth() *QuotaHealth {
+	health := &QuotaHealth{
+		Synced:     g.quotaInformer.HasSynced(),
+		Reconciled: g.quotaReconciled.Load(),
+	}
+	health.Ready = health.Synced && health.Reconciled
+	return health
+}
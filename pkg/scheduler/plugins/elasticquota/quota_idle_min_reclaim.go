@@ -0,0 +1,189 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"math"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	quotav1 "k8s.io/apiserver/pkg/quota/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	"github.com/koordinator-sh/koordinator/apis/thirdparty/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+)
+
+const (
+	QuotaIdleMinReclaimControllerName = "QuotaIdleMinReclaimController"
+	QuotaIdleMinReclaimSyncCycle      = 30 * time.Second
+)
+
+// reclaimMin returns the min left to the quota after reclaiming the ratio of it.
+func reclaimMin(min corev1.ResourceList, ratio float64) corev1.ResourceList {
+	result := make(corev1.ResourceList, len(min))
+	for resourceName, quantity := range min {
+		left := int64(math.Ceil(float64(quantity.MilliValue()) * (1 - ratio)))
+		result[resourceName] = *resource.NewMilliQuantity(left, quantity.Format)
+	}
+	return result
+}
+
+// applyIdleMinReclaim returns a copy of the quota whose min is partly reclaimed if the quota has been idle
+// for the threshold, so that the reclaimed min is shared to the active siblings. The quota itself is returned
+// if it's not idle long enough, and the idle state is reset once the quota has new demand.
+func (g *Plugin) applyIdleMinReclaim(quota *v1alpha1.ElasticQuota) *v1alpha1.ElasticQuota {
+	if quota.Annotations[extension.AnnotationIdleMinReclaim] == "" {
+		g.forgetQuotaIdle(quota.Name)
+		return quota
+	}
+	reclaim, err := extension.GetIdleMinReclaim(quota)
+	if err != nil {
+		klog.Errorf("failed to get idle min reclaim of quota %v, err: %v", quota.Name, err)
+		return quota
+	}
+	threshold, err := time.ParseDuration(reclaim.IdleThreshold)
+	if err != nil {
+		klog.Errorf("invalid idle threshold %q of quota %v, err: %v", reclaim.IdleThreshold, quota.Name, err)
+		return quota
+	}
+
+	idleSince, idle := g.updateQuotaIdleSince(quota.Name)
+	if !idle || g.clock.Since(idleSince) < threshold {
+		g.setQuotaMinReclaimed(quota.Name, false)
+		return quota
+	}
+
+	newQuota := quota.DeepCopy()
+	newQuota.Spec.Min = reclaimMin(quota.Spec.Min, reclaim.Ratio)
+	g.setQuotaMinReclaimed(quota.Name, true)
+	klog.V(5).Infof("quota %v has been idle since %v, min: %v, reclaimed min: %v",
+		quota.Name, idleSince, quota.Spec.Min, newQuota.Spec.Min)
+	return newQuota
+}
+
+// updateQuotaIdleSince returns since when the quota has no request. The quota is not idle if it's
+// not managed yet or has any request.
+func (g *Plugin) updateQuotaIdleSince(quotaName string) (time.Time, bool) {
+	idle := false
+	if mgr := g.GetGroupQuotaManagerForQuota(quotaName); mgr != nil {
+		if quotaInfo := mgr.GetQuotaInfoByName(quotaName); quotaInfo != nil {
+			idle = quotav1.IsZero(quotaInfo.GetRequest())
+		}
+	}
+
+	g.quotaIdleLock.Lock()
+	defer g.quotaIdleLock.Unlock()
+	if !idle {
+		delete(g.quotaIdleSince, quotaName)
+		return time.Time{}, false
+	}
+	idleSince, ok := g.quotaIdleSince[quotaName]
+	if !ok {
+		idleSince = g.clock.Now()
+		g.quotaIdleSince[quotaName] = idleSince
+	}
+	return idleSince, true
+}
+
+func (g *Plugin) setQuotaMinReclaimed(quotaName string, reclaimed bool) {
+	g.quotaIdleLock.Lock()
+	defer g.quotaIdleLock.Unlock()
+	if reclaimed {
+		g.quotaMinReclaimed.Insert(quotaName)
+	} else {
+		g.quotaMinReclaimed.Delete(quotaName)
+	}
+}
+
+func (g *Plugin) isQuotaMinReclaimed(quotaName string) bool {
+	g.quotaIdleLock.Lock()
+	defer g.quotaIdleLock.Unlock()
+	return g.quotaMinReclaimed.Has(quotaName)
+}
+
+func (g *Plugin) forgetQuotaIdle(quotaName string) {
+	g.quotaIdleLock.Lock()
+	defer g.quotaIdleLock.Unlock()
+	delete(g.quotaIdleSince, quotaName)
+	g.quotaMinReclaimed.Delete(quotaName)
+}
+
+// restoreIdleMin restores the reclaimed min of the quota on its new demand.
+func (g *Plugin) restoreIdleMin(quotaName string) {
+	if !g.isQuotaMinReclaimed(quotaName) {
+		return
+	}
+	quotas, err := g.quotaLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list quotas to restore the min of quota %v, err: %v", quotaName, err)
+		return
+	}
+	var quota *v1alpha1.ElasticQuota
+	for _, eq := range quotas {
+		if eq.Name == quotaName {
+			quota = eq
+			break
+		}
+	}
+	if quota == nil {
+		klog.Errorf("failed to find quota %v to restore its min", quotaName)
+		return
+	}
+	// OnQuotaUpdate finds the quota has request now and updates the quota with its spec.min.
+	g.OnQuotaUpdate(quota, quota)
+	klog.V(4).Infof("restore the reclaimed min of quota %v on new demand", quotaName)
+}
+
+// QuotaIdleMinReclaimController re-evaluates the idle quotas periodically,
+// so the min of a quota is reclaimed once it has been idle for the threshold.
+type QuotaIdleMinReclaimController struct {
+	plugin *Plugin
+}
+
+func NewQuotaIdleMinReclaimController(plugin *Plugin) *QuotaIdleMinReclaimController {
+	return &QuotaIdleMinReclaimController{
+		plugin: plugin,
+	}
+}
+
+func (controller *QuotaIdleMinReclaimController) Name() string {
+	return QuotaIdleMinReclaimControllerName
+}
+
+func (controller *QuotaIdleMinReclaimController) Start() {
+	go wait.Until(controller.syncIdleMin, QuotaIdleMinReclaimSyncCycle, nil)
+	klog.Infof("start elasticQuota QuotaIdleMinReclaimController")
+}
+
+func (controller *QuotaIdleMinReclaimController) syncIdleMin() {
+	quotas, err := controller.plugin.quotaLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list elastic quotas in QuotaIdleMinReclaimController, err: %v", err)
+		return
+	}
+	for _, quota := range quotas {
+		if quota.Annotations[extension.AnnotationIdleMinReclaim] == "" {
+			continue
+		}
+		// OnQuotaUpdate applies the reclamation and only updates the quota if the min changes.
+		controller.plugin.OnQuotaUpdate(quota, quota)
+	}
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	quotav1 "k8s.io/apiserver/pkg/quota/v1"
+	fakeclock "k8s.io/utils/clock/testing"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+func TestPlugin_IdleMinReclaim(t *testing.T) {
+	suit := newPluginTestSuit(t, nil)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.Nil(t, err)
+	gp := p.(*Plugin)
+	fakeClock := fakeclock.NewFakeClock(time.Date(2024, 1, 1, 8, 0, 0, 0, time.Local))
+	gp.clock = fakeClock
+
+	quota := CreateQuota2("test1", extension.RootQuotaName, 100, 1000, 10, 100, 10, 100, false, "")
+	quota.Annotations[extension.AnnotationIdleMinReclaim] = `{"idleThreshold":"10m","ratio":0.6}`
+	assert.NoError(t, gp.quotaInformer.GetStore().Add(quota))
+	gp.OnQuotaAdd(quota)
+	gp.OnQuotaUpdate(quota, quota)
+	assert.Equal(t, createResourceList(10, 100), gp.groupQuotaManager.GetQuotaInfoByName("test1").GetMin())
+
+	// idle but not long enough
+	fakeClock.Step(5 * time.Minute)
+	gp.OnQuotaUpdate(quota, quota)
+	assert.Equal(t, createResourceList(10, 100), gp.groupQuotaManager.GetQuotaInfoByName("test1").GetMin())
+
+	// idle beyond the threshold, the min is reclaimed
+	fakeClock.Step(6 * time.Minute)
+	gp.OnQuotaUpdate(quota, quota)
+	assert.True(t, quotav1.Equals(createResourceList(4, 40), gp.groupQuotaManager.GetQuotaInfoByName("test1").GetMin()))
+	assert.True(t, gp.isQuotaMinReclaimed("test1"))
+
+	// the new demand restores the min
+	pod := defaultCreatePodWithQuotaAndNonPreemptible("pod1", "test1", 10, 20, 20, true)
+	pod.Spec.NodeName = ""
+	gp.OnPodAdd(pod)
+	assert.True(t, quotav1.Equals(createResourceList(10, 100), gp.groupQuotaManager.GetQuotaInfoByName("test1").GetMin()))
+	assert.False(t, gp.isQuotaMinReclaimed("test1"))
+
+	// the quota is not idle while it has request
+	fakeClock.Step(time.Hour)
+	gp.OnQuotaUpdate(quota, quota)
+	assert.True(t, quotav1.Equals(createResourceList(10, 100), gp.groupQuotaManager.GetQuotaInfoByName("test1").GetMin()))
+
+	// the quota object is not modified
+	assert.Equal(t, createResourceList(10, 100), quota.Spec.Min)
+
+	// an invalid ratio leaves the min untouched
+	quota2 := CreateQuota2("test2", extension.RootQuotaName, 100, 1000, 10, 100, 10, 100, false, "")
+	quota2.Annotations[extension.AnnotationIdleMinReclaim] = `{"idleThreshold":"0s","ratio":1.5}`
+	_, err = extension.GetIdleMinReclaim(quota2)
+	assert.Error(t, err)
+	assert.Equal(t, quota2, gp.applyIdleMinReclaim(quota2))
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/component-base/metrics"
+	"k8s.io/utils/clock"
+
+	"github.com/koordinator-sh/koordinator/pkg/scheduler/plugins/elasticquota/core"
+)
+
+// QuotaUsageCollectInterval is the min interval between the collections of the same quota tree, which keeps
+// the collection off the hot path when the runtime is refreshed for every pod.
+const QuotaUsageCollectInterval = 1 * time.Second
+
+// quotaUsageSeries is the series recorded for a quota, which are deleted with the quota.
+type quotaUsageSeries struct {
+	treeID    string
+	resources sets.String
+}
+
+// quotaUsageCollector records the runtime, used, request, min and max of the quotas as gauges. It's read-only
+// and doesn't affect the scheduling.
+type quotaUsageCollector struct {
+	lock  sync.Mutex
+	clock clock.Clock
+	// lastCollectTime stores the last collection of each quota tree
+	lastCollectTime map[string]time.Time
+	// series stores the series recorded of each quota
+	series map[string]*quotaUsageSeries
+}
+
+func newQuotaUsageCollector(clock clock.Clock) *quotaUsageCollector {
+	return &quotaUsageCollector{
+		clock:           clock,
+		lastCollectTime: make(map[string]time.Time),
+		series:          make(map[string]*quotaUsageSeries),
+	}
+}
+
+func quotaUsageGauges() []*metrics.GaugeVec {
+	return []*metrics.GaugeVec{
+		ElasticQuotaRuntimeMetric,
+		ElasticQuotaUsedMetric,
+		ElasticQuotaRequestMetric,
+		ElasticQuotaMinMetric,
+		ElasticQuotaMaxMetric,
+	}
+}
+
+// collect records the gauges of all the quotas of the manager, at most once per QuotaUsageCollectInterval.
+func (c *quotaUsageCollector) collect(mgr *core.GroupQuotaManager) {
+	treeID := mgr.GetTreeID()
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	now := c.clock.Now()
+	if last, ok := c.lastCollectTime[treeID]; ok && now.Sub(last) < QuotaUsageCollectInterval {
+		return
+	}
+	c.lastCollectTime[treeID] = now
+
+	for _, quotaName := range mgr.GetAllQuotaNames() {
+		quotaInfo := mgr.GetQuotaInfoByName(quotaName)
+		if quotaInfo == nil {
+			continue
+		}
+		series := c.series[quotaName]
+		if series == nil || series.treeID != treeID {
+			c.deleteSeriesNoLock(quotaName)
+			series = &quotaUsageSeries{treeID: treeID, resources: sets.NewString()}
+			c.series[quotaName] = series
+		}
+		c.recordNoLock(ElasticQuotaRuntimeMetric, quotaName, series, quotaInfo.GetRuntime())
+		c.recordNoLock(ElasticQuotaUsedMetric, quotaName, series, quotaInfo.GetUsed())
+		c.recordNoLock(ElasticQuotaRequestMetric, quotaName, series, quotaInfo.GetRequest())
+		c.recordNoLock(ElasticQuotaMinMetric, quotaName, series, quotaInfo.GetMin())
+		c.recordNoLock(ElasticQuotaMaxMetric, quotaName, series, quotaInfo.GetMax())
+	}
+}
+
+func (c *quotaUsageCollector) recordNoLock(gaugeVec *metrics.GaugeVec, quotaName string, series *quotaUsageSeries,
+	resources corev1.ResourceList) {
+	for resourceName, quantity := range resources {
+		value := quantity.Value()
+		if resourceName == corev1.ResourceCPU {
+			value = quantity.MilliValue()
+		}
+		gaugeVec.WithLabelValues(quotaName, string(resourceName), series.treeID).Set(float64(value))
+		series.resources.Insert(string(resourceName))
+	}
+}
+
+// forget deletes the series of the deleted quota, so the stale series aren't exposed anymore.
+func (c *quotaUsageCollector) forget(quotaName string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.deleteSeriesNoLock(quotaName)
+}
+
+func (c *quotaUsageCollector) deleteSeriesNoLock(quotaName string) {
+	series := c.series[quotaName]
+	if series == nil {
+		return
+	}
+	for _, gaugeVec := range quotaUsageGauges() {
+		for resourceName := range series.resources {
+			gaugeVec.DeleteLabelValues(quotaName, resourceName, series.treeID)
+		}
+	}
+	delete(c.series, quotaName)
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/component-base/metrics"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	fakeclock "k8s.io/utils/clock/testing"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+// collectQuotaGauge returns the values of the gauge of the quota, the key is the resource name.
+func collectQuotaGauge(t *testing.T, gaugeVec *metrics.GaugeVec, quotaName string) map[string]float64 {
+	metricsCh := make(chan prometheus.Metric, 100)
+	go func() {
+		gaugeVec.Collect(metricsCh)
+		close(metricsCh)
+	}()
+	got := map[string]float64{}
+	for metric := range metricsCh {
+		m := dto.Metric{}
+		assert.NoError(t, metric.Write(&m))
+		labels := map[string]string{}
+		for _, l := range m.GetLabel() {
+			labels[l.GetName()] = l.GetValue()
+		}
+		if labels["name"] != quotaName {
+			continue
+		}
+		got[labels["resource"]] = m.GetGauge().GetValue()
+	}
+	return got
+}
+
+func TestPlugin_QuotaUsageMetrics(t *testing.T) {
+	suit := newPluginTestSuit(t, nil)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.NoError(t, err)
+	gp := p.(*Plugin)
+	gp.pluginArgs.EnableRuntimeQuota = true
+	fakeClock := fakeclock.NewFakeClock(time.Now())
+	gp.usageCollector.clock = fakeClock
+	quota := CreateQuota2("test-usage-metrics", extension.RootQuotaName, 100, 1000, 10, 100, 100, 1000, false, "")
+	gp.OnQuotaAdd(quota)
+
+	pod := MakePod("ns", "pod1").Label(extension.LabelQuotaName, "test-usage-metrics").Container(
+		createResourceList(10, 100)).Obj()
+	gp.OnPodAdd(pod)
+	gp.PreFilter(context.TODO(), framework.NewCycleState(), pod)
+	assert.Equal(t, map[string]float64{"cpu": 10000, "memory": 100}, collectQuotaGauge(t, ElasticQuotaRequestMetric, "test-usage-metrics"))
+	assert.Equal(t, float64(0), collectQuotaGauge(t, ElasticQuotaUsedMetric, "test-usage-metrics")["cpu"])
+	assert.Equal(t, map[string]float64{"cpu": 10000, "memory": 100}, collectQuotaGauge(t, ElasticQuotaMinMetric, "test-usage-metrics"))
+	assert.Equal(t, map[string]float64{"cpu": 100000, "memory": 1000}, collectQuotaGauge(t, ElasticQuotaMaxMetric, "test-usage-metrics"))
+	assert.Equal(t, 2, len(collectQuotaGauge(t, ElasticQuotaRuntimeMetric, "test-usage-metrics")))
+
+	// the collection is throttled within the interval
+	assignedPod := pod.DeepCopy()
+	assignedPod.ResourceVersion = "2"
+	assignedPod.Spec.NodeName = "node1"
+	gp.OnPodUpdate(pod, assignedPod)
+	gp.PreFilter(context.TODO(), framework.NewCycleState(), pod)
+	assert.Equal(t, float64(0), collectQuotaGauge(t, ElasticQuotaUsedMetric, "test-usage-metrics")["cpu"])
+	fakeClock.Step(QuotaUsageCollectInterval)
+	gp.PreFilter(context.TODO(), framework.NewCycleState(), pod)
+	assert.Equal(t, map[string]float64{"cpu": 10000, "memory": 100}, collectQuotaGauge(t, ElasticQuotaUsedMetric, "test-usage-metrics"))
+
+	// the series of the deleted quota are cleaned up
+	gp.OnQuotaDelete(quota)
+	for _, gaugeVec := range quotaUsageGauges() {
+		assert.Empty(t, collectQuotaGauge(t, gaugeVec, "test-usage-metrics"))
+	}
+}
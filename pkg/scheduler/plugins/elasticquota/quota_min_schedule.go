@@ -0,0 +1,135 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	"github.com/koordinator-sh/koordinator/apis/thirdparty/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+)
+
+const (
+	QuotaMinScheduleControllerName = "QuotaMinScheduleController"
+	QuotaMinScheduleSyncCycle      = 30 * time.Second
+
+	minScheduleTimeLayout = "15:04"
+)
+
+// getScheduledMin returns the min of the first scheduled window which contains now. The windows are in UTC,
+// so that they don't depend on the timezone of the scheduler.
+func getScheduledMin(quota *v1alpha1.ElasticQuota, now time.Time) (corev1.ResourceList, bool, error) {
+	windows, err := extension.GetMinScheduleWindows(quota)
+	if err != nil {
+		return nil, false, err
+	}
+
+	now = now.UTC()
+	minutes := now.Hour()*60 + now.Minute()
+	for _, window := range windows {
+		start, err := time.Parse(minScheduleTimeLayout, window.Start)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid start %q of min schedule window: %v", window.Start, err)
+		}
+		end, err := time.Parse(minScheduleTimeLayout, window.End)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid end %q of min schedule window: %v", window.End, err)
+		}
+		startMinutes := start.Hour()*60 + start.Minute()
+		endMinutes := end.Hour()*60 + end.Minute()
+
+		var inWindow bool
+		if startMinutes <= endMinutes {
+			inWindow = minutes >= startMinutes && minutes < endMinutes
+		} else {
+			// the window crosses midnight
+			inWindow = minutes >= startMinutes || minutes < endMinutes
+		}
+		if inWindow {
+			return window.Min, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// applyScheduledMin returns a copy of the quota whose min is replaced by the current scheduled window,
+// the min of the window is capped by the max of the quota. The quota itself is returned if no window is active.
+func (g *Plugin) applyScheduledMin(quota *v1alpha1.ElasticQuota) *v1alpha1.ElasticQuota {
+	if quota.Annotations[extension.AnnotationMinScheduleWindows] == "" {
+		return quota
+	}
+	min, ok, err := getScheduledMin(quota, g.clock.Now())
+	if err != nil {
+		klog.Errorf("failed to get scheduled min of quota %v, err: %v", quota.Name, err)
+		return quota
+	}
+	if !ok {
+		return quota
+	}
+
+	newQuota := quota.DeepCopy()
+	newQuota.Spec.Min = min.DeepCopy()
+	for resourceName, quantity := range newQuota.Spec.Min {
+		if max, ok := quota.Spec.Max[resourceName]; ok && quantity.Cmp(max) > 0 {
+			klog.V(4).Infof("scheduled min %v of quota %v exceeds the max %v, capped", resourceName, quota.Name, max.String())
+			newQuota.Spec.Min[resourceName] = max.DeepCopy()
+		}
+	}
+	return newQuota
+}
+
+// QuotaMinScheduleController re-evaluates the scheduled min windows of quotas periodically,
+// so the quota's min changes when the window begins or ends.
+type QuotaMinScheduleController struct {
+	plugin *Plugin
+}
+
+func NewQuotaMinScheduleController(plugin *Plugin) *QuotaMinScheduleController {
+	return &QuotaMinScheduleController{
+		plugin: plugin,
+	}
+}
+
+func (controller *QuotaMinScheduleController) Name() string {
+	return QuotaMinScheduleControllerName
+}
+
+func (controller *QuotaMinScheduleController) Start() {
+	go wait.Until(controller.syncScheduledMin, QuotaMinScheduleSyncCycle, nil)
+	klog.Infof("start elasticQuota QuotaMinScheduleController")
+}
+
+func (controller *QuotaMinScheduleController) syncScheduledMin() {
+	quotas, err := controller.plugin.quotaLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list elastic quotas in QuotaMinScheduleController, err: %v", err)
+		return
+	}
+	for _, quota := range quotas {
+		if quota.Annotations[extension.AnnotationMinScheduleWindows] == "" {
+			continue
+		}
+		// OnQuotaUpdate applies the current window and only updates the quota if the min changes.
+		controller.plugin.OnQuotaUpdate(quota, quota)
+	}
+}
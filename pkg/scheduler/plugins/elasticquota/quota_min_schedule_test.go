@@ -0,0 +1,152 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	quotav1 "k8s.io/apiserver/pkg/quota/v1"
+	fakeclock "k8s.io/utils/clock/testing"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	"github.com/koordinator-sh/koordinator/apis/thirdparty/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+)
+
+func setMinScheduleWindows(t *testing.T, quota *v1alpha1.ElasticQuota, windows []extension.QuotaMinScheduleWindow) {
+	data, err := json.Marshal(windows)
+	assert.NoError(t, err)
+	quota.Annotations[extension.AnnotationMinScheduleWindows] = string(data)
+}
+
+func TestGetScheduledMin(t *testing.T) {
+	quota := CreateQuota2("test1", extension.RootQuotaName, 100, 1000, 10, 100, 10, 100, false, "")
+	setMinScheduleWindows(t, quota, []extension.QuotaMinScheduleWindow{
+		{Start: "09:00", End: "18:00", Min: createResourceList(50, 500)},
+		{Start: "22:00", End: "02:00", Min: createResourceList(5, 50)},
+	})
+
+	tests := []struct {
+		name    string
+		now     time.Time
+		wantMin corev1.ResourceList
+		wantOK  bool
+	}{
+		{
+			name:   "before business hours",
+			now:    time.Date(2024, 1, 1, 8, 59, 0, 0, time.UTC),
+			wantOK: false,
+		},
+		{
+			name:    "start of business hours",
+			now:     time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+			wantMin: createResourceList(50, 500),
+			wantOK:  true,
+		},
+		{
+			name:   "end of business hours",
+			now:    time.Date(2024, 1, 1, 18, 0, 0, 0, time.UTC),
+			wantOK: false,
+		},
+		{
+			name:    "window crosses midnight, before midnight",
+			now:     time.Date(2024, 1, 1, 23, 30, 0, 0, time.UTC),
+			wantMin: createResourceList(5, 50),
+			wantOK:  true,
+		},
+		{
+			name:    "window crosses midnight, after midnight",
+			now:     time.Date(2024, 1, 2, 1, 30, 0, 0, time.UTC),
+			wantMin: createResourceList(5, 50),
+			wantOK:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			min, ok, err := getScheduledMin(quota, tt.now)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.True(t, quotav1.Equals(tt.wantMin, min))
+		})
+	}
+
+	// the windows are in UTC whatever the timezone of now is
+	min, ok, err := getScheduledMin(quota, time.Date(2024, 1, 1, 17, 30, 0, 0, time.FixedZone("UTC+8", 8*3600)))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, quotav1.Equals(createResourceList(50, 500), min))
+
+	quota.Annotations[extension.AnnotationMinScheduleWindows] = `[{"start":"9am","end":"18:00"}]`
+	_, _, err = getScheduledMin(quota, time.Now())
+	assert.Error(t, err)
+}
+
+func TestPlugin_ScheduledMinCappedByMax(t *testing.T) {
+	suit := newPluginTestSuit(t, nil)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.Nil(t, err)
+	gp := p.(*Plugin)
+	gp.clock = fakeclock.NewFakeClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC))
+
+	quota := CreateQuota2("test1", extension.RootQuotaName, 100, 1000, 10, 100, 10, 100, false, "")
+	setMinScheduleWindows(t, quota, []extension.QuotaMinScheduleWindow{
+		{Start: "09:00", End: "18:00", Min: createResourceList(200, 500)},
+	})
+	newQuota := gp.applyScheduledMin(quota)
+	assert.True(t, quotav1.Equals(createResourceList(100, 500), newQuota.Spec.Min))
+}
+
+func TestPlugin_ScheduledMinWindowTransitions(t *testing.T) {
+	suit := newPluginTestSuit(t, nil)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.Nil(t, err)
+	gp := p.(*Plugin)
+	gp.pluginArgs.EnableRuntimeQuota = false
+	fakeClock := fakeclock.NewFakeClock(time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC))
+	gp.clock = fakeClock
+
+	quota := CreateQuota2("test1", extension.RootQuotaName, 100, 1000, 10, 100, 10, 100, false, "")
+	setMinScheduleWindows(t, quota, []extension.QuotaMinScheduleWindow{
+		{Start: "09:00", End: "18:00", Min: createResourceList(50, 500)},
+	})
+	gp.OnQuotaAdd(quota)
+	// the non-preemptible pods are guaranteed by the min
+	pod := defaultCreatePodWithQuotaAndNonPreemptible("pod1", "test1", 10, 20, 20, true)
+	pod.Spec.NodeName = ""
+
+	// off-hours, the min is spec.min
+	assert.Equal(t, createResourceList(10, 100), gp.groupQuotaManager.GetQuotaInfoByName("test1").GetMin())
+	assert.False(t, gp.WouldAdmit(pod).IsSuccess())
+
+	// business hours, the min is elevated
+	fakeClock.SetTime(time.Date(2024, 1, 1, 9, 30, 0, 0, time.UTC))
+	gp.OnQuotaUpdate(quota, quota)
+	assert.True(t, quotav1.Equals(createResourceList(50, 500), gp.groupQuotaManager.GetQuotaInfoByName("test1").GetMin()))
+	assert.True(t, gp.WouldAdmit(pod).IsSuccess())
+
+	// off-hours again, the min is lowered
+	fakeClock.SetTime(time.Date(2024, 1, 1, 18, 30, 0, 0, time.UTC))
+	gp.OnQuotaUpdate(quota, quota)
+	assert.Equal(t, createResourceList(10, 100), gp.groupQuotaManager.GetQuotaInfoByName("test1").GetMin())
+	assert.False(t, gp.WouldAdmit(pod).IsSuccess())
+
+	// the quota object is not modified
+	assert.Equal(t, createResourceList(10, 100), quota.Spec.Min)
+}
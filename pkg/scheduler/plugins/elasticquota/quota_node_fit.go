@@ -0,0 +1,69 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	quotav1 "k8s.io/apiserver/pkg/quota/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/koordinator-sh/koordinator/pkg/scheduler/plugins/elasticquota/core"
+)
+
+// checkNodeFit rejects the pod whose request exceeds the max allocatable of the nodes in the snapshot,
+// since the pod can't fit any node even if the quota admits it.
+func (g *Plugin) checkNodeFit(pod *corev1.Pod) *framework.Status {
+	if !g.pluginArgs.EnableNodeFitPreCheck {
+		return nil
+	}
+	nodeInfos, err := g.handle.SnapshotSharedLister().NodeInfos().List()
+	if err != nil {
+		return framework.AsStatus(err)
+	}
+	maxAllocatable := getMaxNodeAllocatable(nodeInfos)
+	if len(maxAllocatable) == 0 {
+		// leave the pod to the filters if there are no nodes
+		return nil
+	}
+
+	podRequest := core.PodRequests(pod)
+	if isLessEqual, exceedDimensions := quotav1.LessThanOrEqual(podRequest, maxAllocatable); !isLessEqual {
+		return framework.NewStatus(framework.UnschedulableAndUnresolvable, fmt.Sprintf("Insufficient node allocatable, "+
+			"max node allocatable: %v, pod's request: %v, exceedDimensions: %v",
+			printResourceList(maxAllocatable), printResourceList(podRequest), g.sortExceedDimensions(exceedDimensions)))
+	}
+	return nil
+}
+
+// getMaxNodeAllocatable returns the max allocatable of the nodes in each resource dimension.
+func getMaxNodeAllocatable(nodeInfos []*framework.NodeInfo) corev1.ResourceList {
+	maxAllocatable := corev1.ResourceList{}
+	for _, nodeInfo := range nodeInfos {
+		node := nodeInfo.Node()
+		if node == nil {
+			continue
+		}
+		for name, quantity := range node.Status.Allocatable {
+			if cur, ok := maxAllocatable[name]; !ok || quantity.Cmp(cur) > 0 {
+				maxAllocatable[name] = quantity.DeepCopy()
+			}
+		}
+	}
+	return maxAllocatable
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	"github.com/koordinator-sh/koordinator/pkg/scheduler/apis/config"
+)
+
+func TestPlugin_PreFilter_NodeFitPreCheck(t *testing.T) {
+	nodes := []*corev1.Node{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+			Status:     corev1.NodeStatus{Allocatable: createResourceList(32, 100)},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "node2"},
+			Status:     corev1.NodeStatus{Allocatable: createResourceList(16, 200)},
+		},
+	}
+	tests := []struct {
+		name         string
+		enable       bool
+		nodes        []*corev1.Node
+		cpu          int64
+		mem          int64
+		expectCode   framework.Code
+		expectReason string
+	}{
+		{
+			name:       "oversized pod admitted if the pre-check is disabled",
+			nodes:      nodes,
+			cpu:        64,
+			mem:        100,
+			expectCode: framework.Success,
+		},
+		{
+			name:       "pod fits the max allocatable",
+			enable:     true,
+			nodes:      nodes,
+			cpu:        32,
+			mem:        200,
+			expectCode: framework.Success,
+		},
+		{
+			name:       "oversized pod rejected",
+			enable:     true,
+			nodes:      nodes,
+			cpu:        64,
+			mem:        100,
+			expectCode: framework.UnschedulableAndUnresolvable,
+			expectReason: "Insufficient node allocatable, max node allocatable: " +
+				printResourceList(createResourceList(32, 200)) + ", pod's request: " +
+				printResourceList(createResourceList(64, 100)) + ", exceedDimensions: [cpu]",
+		},
+		{
+			name:       "no nodes",
+			enable:     true,
+			cpu:        64,
+			mem:        100,
+			expectCode: framework.Success,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suit := newPluginTestSuit(t, tt.nodes, func(elasticQuotaArgs *config.ElasticQuotaArgs) {
+				elasticQuotaArgs.EnableNodeFitPreCheck = tt.enable
+			})
+			p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+			assert.NoError(t, err)
+			gp := p.(*Plugin)
+			gp.pluginArgs.EnableRuntimeQuota = false
+			gp.OnQuotaAdd(CreateQuota2("test1", extension.RootQuotaName, 1000, 10000, 10, 100, 100, 1000, false, ""))
+
+			pod := MakePod("ns", "pod1").Label(extension.LabelQuotaName, "test1").Container(
+				createResourceList(tt.cpu, tt.mem)).Obj()
+			_, status := gp.PreFilter(context.TODO(), framework.NewCycleState(), pod)
+			assert.Equal(t, tt.expectCode, status.Code())
+			if tt.expectReason != "" {
+				assert.Equal(t, tt.expectReason, status.Message())
+			}
+		})
+	}
+}
@@ -47,6 +47,8 @@ type QuotaOverUsedGroupMonitor struct {
 	quotaName                    string
 	lastUnderUsedTime            time.Time
 	overUsedTriggerEvictDuration time.Duration
+	// revokeNonPreemptibleBeyondMin revokes the non-preemptible pods beyond the min first.
+	revokeNonPreemptibleBeyondMin bool
 }
 
 func NewQuotaOverUsedGroupMonitor(quotaName string, manager *core.GroupQuotaManager, overUsedTriggerEvictDuration time.Duration) *QuotaOverUsedGroupMonitor {
@@ -104,6 +106,12 @@ func (monitor *QuotaOverUsedGroupMonitor) getToRevokePodList(quotaName string) [
 
 	sort.Slice(priPodCache, func(i, j int) bool { return !k8sutil.MoreImportantPod(priPodCache[i], priPodCache[j]) })
 
+	// the non-preemptible pods beyond the min go first, so they are revoked first and assigned back last
+	var beyondMinPods map[*v1.Pod]bool
+	if monitor.revokeNonPreemptibleBeyondMin {
+		priPodCache, beyondMinPods = monitor.orderNonPreemptibleBeyondMin(quotaName, quotaInfo, priPodCache)
+	}
+
 	// first try revoke all until used <= runtime
 	tryAssignBackPodCache := make([]*v1.Pod, 0)
 
@@ -111,7 +119,7 @@ func (monitor *QuotaOverUsedGroupMonitor) getToRevokePodList(quotaName string) [
 		if shouldBreak, _ := quotav1.LessThanOrEqual(used, runtime); shouldBreak {
 			break
 		}
-		if extension.IsPodNonPreemptible(pod) {
+		if !beyondMinPods[pod] && monitor.groupQuotaManger.IsPodNonPreemptible(quotaName, pod) {
 			continue
 		}
 		podReq := core.PodRequests(pod)
@@ -146,6 +154,34 @@ func (monitor *QuotaOverUsedGroupMonitor) getToRevokePodList(quotaName string) [
 	return realRevokePodCache
 }
 
+// orderNonPreemptibleBeyondMin picks the non-preemptible pods from low to high priority until the non-preemptible
+// used of the rest is within the min, and moves them ahead of the other pods.
+func (monitor *QuotaOverUsedGroupMonitor) orderNonPreemptibleBeyondMin(quotaName string, quotaInfo *core.QuotaInfo,
+	priPodCache []*v1.Pod) ([]*v1.Pod, map[*v1.Pod]bool) {
+	quotaMin := quotaInfo.GetMin()
+	nonPreemptibleUsed := quotaInfo.GetNonPreemptibleUsed()
+	beyondMinPods := make(map[*v1.Pod]bool)
+	ordered := make([]*v1.Pod, 0, len(priPodCache))
+	for _, pod := range priPodCache {
+		if withinMin, _ := quotav1.LessThanOrEqual(nonPreemptibleUsed, quotaMin); withinMin {
+			break
+		}
+		if !monitor.groupQuotaManger.IsPodNonPreemptible(quotaName, pod) {
+			continue
+		}
+		podReq := core.PodRequests(pod)
+		nonPreemptibleUsed = quotav1.Mask(quotav1.Subtract(nonPreemptibleUsed, podReq), quotav1.ResourceNames(podReq))
+		beyondMinPods[pod] = true
+		ordered = append(ordered, pod)
+	}
+	for _, pod := range priPodCache {
+		if !beyondMinPods[pod] {
+			ordered = append(ordered, pod)
+		}
+	}
+	return ordered, beyondMinPods
+}
+
 type QuotaOverUsedRevokeController struct {
 	monitorsLock                 sync.RWMutex
 	monitors                     map[string]*QuotaOverUsedGroupMonitor
@@ -221,7 +257,7 @@ func (controller *QuotaOverUsedRevokeController) syncQuota() {
 
 	allQuotaNames := make(map[string]struct{})
 	for _, mgr := range managers {
-		for quotaName := range mgr.GetAllQuotaNames() {
+		for _, quotaName := range mgr.GetAllQuotaNames() {
 			if quotaName == extension.SystemQuotaName || quotaName == extension.RootQuotaName {
 				continue
 			}
@@ -241,6 +277,7 @@ func (controller *QuotaOverUsedRevokeController) syncQuota() {
 
 func (controller *QuotaOverUsedRevokeController) addQuota(quotaName string, mgr *core.GroupQuotaManager) {
 	controller.monitors[quotaName] = NewQuotaOverUsedGroupMonitor(quotaName, mgr, controller.overUsedTriggerEvictDuration)
+	controller.monitors[quotaName].revokeNonPreemptibleBeyondMin = controller.plugin.pluginArgs.AllowNonPreemptibleBeyondMin
 	klog.V(5).Infof("QuotaOverUseRescheduleController add quota: %v", quotaName)
 }
 
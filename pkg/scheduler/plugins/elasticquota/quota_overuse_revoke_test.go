@@ -140,6 +140,51 @@ func TestQuotaOverUsedRevokeController_GetToRevokePodList(t *testing.T) {
 	}
 }
 
+func TestQuotaOverUsedRevokeController_GetToRevokePodList_NonPreemptibleBeyondMin(t *testing.T) {
+	tests := []struct {
+		name                         string
+		allowNonPreemptibleBeyondMin bool
+		expectedRevoked              []string
+	}{
+		{
+			name:            "non-preemptible pods are never revoked by default",
+			expectedRevoked: []string{"4"},
+		},
+		{
+			name:                         "non-preemptible pods beyond min are revoked first",
+			allowNonPreemptibleBeyondMin: true,
+			expectedRevoked:              []string{"3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suit := newPluginTestSuit(t, nil)
+			suit.elasticQuotaArgs.AllowNonPreemptibleBeyondMin = tt.allowNonPreemptibleBeyondMin
+			p, _ := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+			plugin := p.(*Plugin)
+			gqm := plugin.groupQuotaManager
+			plugin.addQuota("test1", extension.RootQuotaName, 100, 100, 20, 0, 100, 100, false, "", "")
+			qi := gqm.GetQuotaInfoByName("test1")
+			con := NewQuotaOverUsedRevokeController(plugin)
+			con.syncQuota()
+
+			gqm.OnPodAdd("test1", defaultCreatePodWithQuotaAndNonPreemptible("1", "test1", 5, 10, 0, true))
+			gqm.OnPodAdd("test1", defaultCreatePodWithQuotaAndNonPreemptible("2", "test1", 3, 10, 0, true))
+			gqm.OnPodAdd("test1", defaultCreatePodWithQuotaAndNonPreemptible("3", "test1", 1, 10, 0, true))
+			gqm.OnPodAdd("test1", defaultCreatePodWithQuotaAndNonPreemptible("4", "test1", 2, 10, 0, false))
+			qi.Lock()
+			qi.CalculateInfo.Runtime = createResourceList(30, 0)
+			qi.UnLock()
+
+			var revoked []string
+			for _, pod := range con.monitors["test1"].getToRevokePodList("test1") {
+				revoked = append(revoked, pod.Name)
+			}
+			assert.Equal(t, tt.expectedRevoked, revoked)
+		})
+	}
+}
+
 func TestQuotaOverUsedRevokeController_GetToMonitorQuotas(t *testing.T) {
 	suit := newPluginTestSuit(t, nil)
 	p, _ := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
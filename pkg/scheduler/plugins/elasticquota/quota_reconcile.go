@@ -0,0 +1,152 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"sort"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	schedulerv1alpha1 "github.com/koordinator-sh/koordinator/apis/thirdparty/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+	"github.com/koordinator-sh/koordinator/pkg/scheduler/apis/config"
+	"github.com/koordinator-sh/koordinator/pkg/scheduler/plugins/elasticquota/core"
+)
+
+// QuotaReconcileCycle is the interval to reconcile the quotas of the managers against the live ElasticQuotas.
+const QuotaReconcileCycle = 1 * time.Minute
+
+// reconcileQuotas converges the quotas of the managers to the live ElasticQuotas, which recovers the quotas
+// from the informer events missed. The stale quotas are removed before the missing quotas are added, and
+// the quotas already in the managers are updated in case their updates are missed.
+func (g *Plugin) reconcileQuotas() {
+	quotas, err := g.quotaLister.List(labels.Everything())
+	if err != nil {
+		klog.ErrorS(err, "Unable to list elastic quota in reconcileQuotas")
+		return
+	}
+	liveQuotas := make(map[string]*schedulerv1alpha1.ElasticQuota, len(quotas))
+	for _, quota := range quotas {
+		if quota.DeletionTimestamp != nil && g.pluginArgs.TerminatingQuotaPolicy != config.TerminatingQuotaPolicyDrain {
+			continue
+		}
+		liveQuotas[quota.Name] = quota
+	}
+
+	managers := append(g.ListGroupQuotaManagersForQuotaTree(), g.groupQuotaManager)
+	for _, mgr := range managers {
+		for _, staleQuota := range g.getStaleQuotas(mgr, liveQuotas) {
+			klog.Infof("reconcile removes the stale quota %v from tree %v", staleQuota.Name, mgr.GetTreeID())
+			g.OnQuotaDelete(staleQuota)
+		}
+	}
+
+	for _, quota := range sortQuotasParentFirst(liveQuotas) {
+		mgr := g.GetGroupQuotaManagerForTree(quota.Labels[extension.LabelQuotaTreeID])
+		if mgr == nil || mgr.GetQuotaInfoByName(quota.Name) == nil {
+			klog.Infof("reconcile adds the missing quota %v", quota.Name)
+			g.OnQuotaAdd(quota)
+			continue
+		}
+		g.OnQuotaUpdate(quota, quota)
+	}
+	g.quotaReconciled.Store(true)
+}
+
+// reconcileQuotas reconciles the quotas periodically once the quotas are listed, the quotas not listed yet
+// are not stale.
+func (ctrl *Controller) reconcileQuotas() {
+	if !ctrl.plugin.quotaInformer.HasSynced() {
+		return
+	}
+	ctrl.plugin.reconcileQuotas()
+}
+
+// getStaleQuotas returns the quotas of the manager which aren't live in the tree of the manager, the children
+// are ordered before their parents.
+func (g *Plugin) getStaleQuotas(mgr *core.GroupQuotaManager,
+	liveQuotas map[string]*schedulerv1alpha1.ElasticQuota) []*schedulerv1alpha1.ElasticQuota {
+	var staleQuotas []*schedulerv1alpha1.ElasticQuota
+	depths := map[string]int{}
+	for _, quotaName := range mgr.GetAllQuotaNames() {
+		if quotaName == extension.RootQuotaName || quotaName == extension.SystemQuotaName ||
+			quotaName == extension.DefaultQuotaName {
+			continue
+		}
+		if quota, ok := liveQuotas[quotaName]; ok && g.GetGroupQuotaManagerForTree(quota.Labels[extension.LabelQuotaTreeID]) == mgr {
+			continue
+		}
+		quotaInfo := mgr.GetQuotaInfoByName(quotaName)
+		if quotaInfo == nil {
+			continue
+		}
+		staleQuota := &schedulerv1alpha1.ElasticQuota{}
+		staleQuota.Name = quotaName
+		staleQuota.Labels = map[string]string{
+			extension.LabelQuotaParent: quotaInfo.ParentName,
+			extension.LabelQuotaTreeID: mgr.GetTreeID(),
+		}
+		staleQuotas = append(staleQuotas, staleQuota)
+		depths[quotaName] = getQuotaDepth(quotaName, func(name string) string {
+			if info := mgr.GetQuotaInfoByName(name); info != nil {
+				return info.ParentName
+			}
+			return ""
+		})
+	}
+	sort.SliceStable(staleQuotas, func(i, j int) bool {
+		return depths[staleQuotas[i].Name] > depths[staleQuotas[j].Name]
+	})
+	return staleQuotas
+}
+
+// sortQuotasParentFirst returns the quotas ordered by their depths in the quota tree, the parents are ordered
+// before their children so the children are added under their parents.
+func sortQuotasParentFirst(quotas map[string]*schedulerv1alpha1.ElasticQuota) []*schedulerv1alpha1.ElasticQuota {
+	sorted := make([]*schedulerv1alpha1.ElasticQuota, 0, len(quotas))
+	depths := make(map[string]int, len(quotas))
+	for name, quota := range quotas {
+		sorted = append(sorted, quota)
+		depths[name] = getQuotaDepth(name, func(name string) string {
+			if quota := quotas[name]; quota != nil {
+				return extension.GetParentQuotaName(quota)
+			}
+			return ""
+		})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if depths[sorted[i].Name] != depths[sorted[j].Name] {
+			return depths[sorted[i].Name] < depths[sorted[j].Name]
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
+
+// getQuotaDepth returns the number of the ancestors of the quota found by getParent, which stops at the
+// root quota or the unknown parent, and breaks the cycle.
+func getQuotaDepth(quotaName string, getParent func(string) string) int {
+	depth := 0
+	visited := map[string]bool{quotaName: true}
+	for parent := getParent(quotaName); parent != "" && parent != extension.RootQuotaName && !visited[parent]; parent = getParent(parent) {
+		visited[parent] = true
+		depth++
+	}
+	return depth
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	quotav1 "k8s.io/apiserver/pkg/quota/v1"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	"github.com/koordinator-sh/koordinator/apis/thirdparty/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+)
+
+func TestPlugin_ReconcileQuotas(t *testing.T) {
+	suit := newPluginTestSuit(t, nil)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.NoError(t, err)
+	gp := p.(*Plugin)
+	store := gp.quotaInformer.GetStore()
+
+	// the quotas exist but their add events are missed, the child is listed before its parent
+	parent := CreateQuota2("parent1", extension.RootQuotaName, 100, 1000, 10, 100, 100, 1000, true, "")
+	child := CreateQuota2("child1", "parent1", 100, 1000, 10, 100, 100, 1000, false, "")
+	assert.NoError(t, store.Add(child))
+	assert.NoError(t, store.Add(parent))
+	assert.Nil(t, gp.groupQuotaManager.GetQuotaInfoByName("parent1"))
+	assert.Nil(t, gp.groupQuotaManager.GetQuotaInfoByName("child1"))
+
+	gp.reconcileQuotas()
+	assert.NotNil(t, gp.groupQuotaManager.GetQuotaInfoByName("parent1"))
+	childInfo := gp.groupQuotaManager.GetQuotaInfoByName("child1")
+	assert.NotNil(t, childInfo)
+	assert.Equal(t, "parent1", childInfo.ParentName)
+
+	// the update event of the quota is missed
+	newChild := child.DeepCopy()
+	newChild.Spec.Max = createResourceList(50, 500)
+	assert.NoError(t, store.Update(newChild))
+	gp.reconcileQuotas()
+	assert.True(t, quotav1.Equals(createResourceList(50, 500), gp.groupQuotaManager.GetQuotaInfoByName("child1").GetMax()))
+
+	// the quotas are deleted but their delete events are missed
+	gp.addQuota("stale1", extension.RootQuotaName, 100, 1000, 10, 100, 100, 1000, true, "", "")
+	gp.addQuota("stale2", "stale1", 100, 1000, 10, 100, 100, 1000, false, "", "")
+	assert.NotNil(t, gp.groupQuotaManager.GetQuotaInfoByName("stale2"))
+	gp.reconcileQuotas()
+	assert.Nil(t, gp.groupQuotaManager.GetQuotaInfoByName("stale1"))
+	assert.Nil(t, gp.groupQuotaManager.GetQuotaInfoByName("stale2"))
+	assert.NotNil(t, gp.groupQuotaManager.GetQuotaInfoByName("child1"))
+	assert.NotNil(t, gp.groupQuotaManager.GetQuotaInfoByName(extension.DefaultQuotaName))
+	assert.NotNil(t, gp.groupQuotaManager.GetQuotaInfoByName(extension.SystemQuotaName))
+}
+
+func TestSortQuotasParentFirst(t *testing.T) {
+	quotas := map[string]*v1alpha1.ElasticQuota{
+		"c":    CreateQuota2("c", "b", 100, 1000, 10, 100, 100, 1000, false, ""),
+		"b":    CreateQuota2("b", "a", 100, 1000, 10, 100, 100, 1000, true, ""),
+		"a":    CreateQuota2("a", extension.RootQuotaName, 100, 1000, 10, 100, 100, 1000, true, ""),
+		"d":    CreateQuota2("d", extension.RootQuotaName, 100, 1000, 10, 100, 100, 1000, false, ""),
+		"loop": CreateQuota2("loop", "loop", 100, 1000, 10, 100, 100, 1000, false, ""),
+	}
+	var names []string
+	for _, quota := range sortQuotasParentFirst(quotas) {
+		names = append(names, quota.Name)
+	}
+	assert.Equal(t, []string{"a", "d", "loop", "b", "c"}, names)
+}
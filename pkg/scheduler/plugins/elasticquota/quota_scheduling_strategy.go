@@ -0,0 +1,71 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	"github.com/koordinator-sh/koordinator/pkg/scheduler/plugins/elasticquota/core"
+)
+
+// Score prefers the nodes by the scheduling strategy which the pod's quota resolved from the quota tree,
+// the most allocated nodes for Binpack and the least allocated nodes for Spread. All the nodes score 0
+// if the quota has no strategy, leaving the placement to the other score plugins.
+func (g *Plugin) Score(ctx context.Context, state *framework.CycleState, pod *corev1.Pod, nodeName string) (int64, *framework.Status) {
+	strategy := g.GetQuotaSchedulingStrategy(pod)
+	if strategy == "" {
+		return 0, nil
+	}
+	nodeInfo, err := g.handle.SnapshotSharedLister().NodeInfos().Get(nodeName)
+	if err != nil {
+		return 0, framework.AsStatus(err)
+	}
+	return scoreNodeByStrategy(strategy, nodeInfo, core.PodRequests(pod)), nil
+}
+
+func (g *Plugin) ScoreExtensions() framework.ScoreExtensions {
+	return nil
+}
+
+// scoreNodeByStrategy scores the node by the average allocated ratio of cpu and memory after placing the pod.
+func scoreNodeByStrategy(strategy extension.QuotaSchedulingStrategy, nodeInfo *framework.NodeInfo, podRequest corev1.ResourceList) int64 {
+	cpuRatio := allocatedRatio(nodeInfo.Requested.MilliCPU+podRequest.Cpu().MilliValue(), nodeInfo.Allocatable.MilliCPU)
+	memoryRatio := allocatedRatio(nodeInfo.Requested.Memory+podRequest.Memory().Value(), nodeInfo.Allocatable.Memory)
+	ratio := (cpuRatio + memoryRatio) / 2
+
+	switch strategy {
+	case extension.QuotaSchedulingStrategyBinpack:
+		return int64(ratio * float64(framework.MaxNodeScore))
+	case extension.QuotaSchedulingStrategySpread:
+		return int64((1 - ratio) * float64(framework.MaxNodeScore))
+	}
+	return 0
+}
+
+func allocatedRatio(requested, allocatable int64) float64 {
+	if allocatable <= 0 || requested >= allocatable {
+		return 1
+	}
+	if requested <= 0 {
+		return 0
+	}
+	return float64(requested) / float64(allocatable)
+}
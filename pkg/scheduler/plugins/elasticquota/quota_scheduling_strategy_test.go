@@ -0,0 +1,83 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+func TestPlugin_Score_SchedulingStrategy(t *testing.T) {
+	tests := []struct {
+		name           string
+		strategy       extension.QuotaSchedulingStrategy
+		preferAssigned bool
+		noPreference   bool
+	}{
+		{
+			name:         "no strategy",
+			noPreference: true,
+		},
+		{
+			name:           "binpack prefers the allocated node",
+			strategy:       extension.QuotaSchedulingStrategyBinpack,
+			preferAssigned: true,
+		},
+		{
+			name:     "spread prefers the idle node",
+			strategy: extension.QuotaSchedulingStrategySpread,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assignedPod := defaultCreatePod("assigned", 0, 50, 500)
+			assignedPod.Spec.NodeName = "node1"
+			suit := newPluginTestSuitWithPod(t, []*corev1.Node{defaultCreateNode("node1"), defaultCreateNode("node2")},
+				[]*corev1.Pod{assignedPod})
+			p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+			assert.Nil(t, err)
+			gp := p.(*Plugin)
+
+			quota := CreateQuota2("test", extension.RootQuotaName, 100, 1000, 0, 0, 100, 1000, false, "")
+			if tt.strategy != "" {
+				quota.Annotations[extension.AnnotationSchedulingStrategy] = string(tt.strategy)
+			}
+			gp.OnQuotaAdd(quota)
+
+			pod := defaultCreatePodWithQuotaName("pod", "test", 0, 10, 100)
+			pod.Spec.NodeName = ""
+			score1, status := gp.Score(context.TODO(), framework.NewCycleState(), pod, "node1")
+			assert.True(t, status.IsSuccess())
+			score2, status := gp.Score(context.TODO(), framework.NewCycleState(), pod, "node2")
+			assert.True(t, status.IsSuccess())
+			if tt.noPreference {
+				assert.Equal(t, int64(0), score1)
+				assert.Equal(t, int64(0), score2)
+			} else if tt.preferAssigned {
+				assert.Greater(t, score1, score2)
+			} else {
+				assert.Less(t, score1, score2)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	schedulerv1alpha1 "github.com/koordinator-sh/koordinator/apis/thirdparty/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+	"github.com/koordinator-sh/koordinator/pkg/scheduler/apis/config"
+)
+
+// acceptTerminatingQuota returns whether the quota with a deletion timestamp should still be accounted.
+// With the Drain policy, the terminating quota keeps accounting its pods until it's deleted, but its new pods
+// are rejected, and an event is emitted when the quota starts draining.
+func (g *Plugin) acceptTerminatingQuota(quota *schedulerv1alpha1.ElasticQuota) bool {
+	if g.pluginArgs.TerminatingQuotaPolicy != config.TerminatingQuotaPolicyDrain {
+		return false
+	}
+
+	g.terminatingQuotaLock.Lock()
+	defer g.terminatingQuotaLock.Unlock()
+	if g.terminatingQuotas.Has(quota.Name) {
+		return true
+	}
+	g.terminatingQuotas.Insert(quota.Name)
+	klog.V(4).Infof("quota %v is terminating, drain it", quota.Name)
+	g.handle.EventRecorder().Eventf(quota, nil, corev1.EventTypeNormal, "QuotaDraining", "DrainQuota",
+		"quota is terminating, new pods are rejected until the existing pods drain off")
+	return true
+}
+
+func (g *Plugin) forgetTerminatingQuota(quotaName string) {
+	g.terminatingQuotaLock.Lock()
+	defer g.terminatingQuotaLock.Unlock()
+	g.terminatingQuotas.Delete(quotaName)
+}
+
+func (g *Plugin) checkTerminatingQuota(quotaName string) *framework.Status {
+	g.terminatingQuotaLock.RLock()
+	defer g.terminatingQuotaLock.RUnlock()
+	if !g.terminatingQuotas.Has(quotaName) {
+		return nil
+	}
+	return framework.NewStatus(framework.UnschedulableAndUnresolvable,
+		fmt.Sprintf("Quota %v is terminating and doesn't admit new pods", quotaName))
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	quotav1 "k8s.io/apiserver/pkg/quota/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	"github.com/koordinator-sh/koordinator/pkg/scheduler/apis/config"
+)
+
+func TestPlugin_OnQuotaAdd_Terminating(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       config.TerminatingQuotaPolicy
+		expectExist  bool
+		expectEvents int
+	}{
+		{
+			name:        "ignore the terminating quota by default",
+			expectExist: false,
+		},
+		{
+			name:        "ignore the terminating quota",
+			policy:      config.TerminatingQuotaPolicyIgnore,
+			expectExist: false,
+		},
+		{
+			name:         "drain the terminating quota",
+			policy:       config.TerminatingQuotaPolicyDrain,
+			expectExist:  true,
+			expectEvents: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suit := newPluginTestSuit(t, nil, func(elasticQuotaArgs *config.ElasticQuotaArgs) {
+				elasticQuotaArgs.TerminatingQuotaPolicy = tt.policy
+			})
+			p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+			assert.NoError(t, err)
+			gp := p.(*Plugin)
+			gp.pluginArgs.EnableRuntimeQuota = false
+
+			quota := CreateQuota2("test1", extension.RootQuotaName, 100, 1000, 10, 100, 100, 1000, false, "")
+			quota.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+			gp.OnQuotaAdd(quota)
+			// the update of the terminating quota doesn't emit the event again
+			gp.OnQuotaUpdate(quota, quota.DeepCopy())
+			assert.Equal(t, tt.expectExist, gp.groupQuotaManager.GetQuotaInfoByName("test1") != nil)
+			assert.Equal(t, tt.expectEvents, len(suit.fakeRecorder.Events))
+			if !tt.expectExist {
+				return
+			}
+			assert.Contains(t, <-suit.fakeRecorder.Events, "QuotaDraining")
+
+			// the existing pods are still accounted in the draining quota
+			pod := MakePod("ns", "pod1").Label(extension.LabelQuotaName, "test1").Container(
+				createResourceList(10, 100)).Obj()
+			pod.Spec.NodeName = "node1"
+			gp.OnPodAdd(pod)
+			assert.True(t, quotav1.Equals(createResourceList(10, 100),
+				gp.groupQuotaManager.GetQuotaInfoByName("test1").GetUsed()))
+
+			// the new pods are rejected
+			newPod := MakePod("ns", "pod2").Label(extension.LabelQuotaName, "test1").Container(
+				createResourceList(10, 100)).Obj()
+			_, status := gp.PreFilter(context.TODO(), framework.NewCycleState(), newPod)
+			assert.Equal(t, framework.UnschedulableAndUnresolvable, status.Code())
+			assert.False(t, gp.WouldAdmit(newPod).IsSuccess())
+
+			// the quota is forgotten once deleted
+			gp.OnQuotaDelete(quota)
+			assert.Nil(t, gp.groupQuotaManager.GetQuotaInfoByName("test1"))
+			assert.True(t, gp.checkTerminatingQuota("test1").IsSuccess())
+		})
+	}
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/koordinator-sh/koordinator/pkg/scheduler/plugins/elasticquota/core"
+)
+
+const (
+	// QuotaTopologyConfigMapName is the ConfigMap in the quota group namespace which exports the topology
+	// of all quota trees, so that external tools can read the quota state via the API.
+	QuotaTopologyConfigMapName = "koordinator-quota-topology"
+	// QuotaTopologyConfigMapKey is the key of the exported topology, the value is a json of QuotaTopology.
+	QuotaTopologyConfigMapKey = "topology"
+)
+
+// QuotaTopology is the compact topology of all quota trees, the key is the tree id,
+// and the default tree is keyed by the empty string.
+type QuotaTopology map[string]map[string]*QuotaTopologyNode
+
+type QuotaTopologyNode struct {
+	Parent   string              `json:"parent,omitempty"`
+	Children []string            `json:"children,omitempty"`
+	Runtime  corev1.ResourceList `json:"runtime,omitempty"`
+	Used     corev1.ResourceList `json:"used,omitempty"`
+}
+
+// GetQuotaTopology returns the topology of all quota trees.
+func (g *Plugin) GetQuotaTopology() QuotaTopology {
+	topology := QuotaTopology{}
+	managers := append([]*core.GroupQuotaManager{g.groupQuotaManager}, g.ListGroupQuotaManagersForQuotaTree()...)
+	for _, mgr := range managers {
+		topology[mgr.GetTreeID()] = newQuotaTreeTopology(mgr.GetQuotaSummaries(false))
+	}
+	return topology
+}
+
+func newQuotaTreeTopology(summaries map[string]*core.QuotaInfoSummary) map[string]*QuotaTopologyNode {
+	nodes := make(map[string]*QuotaTopologyNode, len(summaries))
+	for quotaName, summary := range summaries {
+		nodes[quotaName] = &QuotaTopologyNode{
+			Parent:  summary.ParentName,
+			Runtime: summary.Runtime,
+			Used:    summary.Used,
+		}
+	}
+	for quotaName, node := range nodes {
+		if parent, ok := nodes[node.Parent]; ok {
+			parent.Children = append(parent.Children, quotaName)
+		}
+	}
+	for _, node := range nodes {
+		sort.Strings(node.Children)
+	}
+	return nodes
+}
+
+// syncQuotaTopology writes the topology of all quota trees into the topology ConfigMap if it changes.
+func (ctrl *Controller) syncQuotaTopology() {
+	data, err := json.Marshal(ctrl.plugin.GetQuotaTopology())
+	if err != nil {
+		klog.ErrorS(err, "Failed to marshal quota topology")
+		return
+	}
+
+	client := ctrl.plugin.handle.ClientSet().CoreV1().ConfigMaps(ctrl.plugin.pluginArgs.QuotaGroupNamespace)
+	configMap, err := client.Get(context.TODO(), QuotaTopologyConfigMapName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: ctrl.plugin.pluginArgs.QuotaGroupNamespace,
+				Name:      QuotaTopologyConfigMapName,
+			},
+			Data: map[string]string{QuotaTopologyConfigMapKey: string(data)},
+		}
+		if _, err = client.Create(context.TODO(), configMap, metav1.CreateOptions{}); err != nil {
+			klog.ErrorS(err, "Failed to create quota topology", "configMap", QuotaTopologyConfigMapName)
+		}
+		return
+	}
+	if err != nil {
+		klog.ErrorS(err, "Failed to get quota topology", "configMap", QuotaTopologyConfigMapName)
+		return
+	}
+	if configMap.Data[QuotaTopologyConfigMapKey] == string(data) {
+		return
+	}
+
+	configMap = configMap.DeepCopy()
+	if configMap.Data == nil {
+		configMap.Data = map[string]string{}
+	}
+	configMap.Data[QuotaTopologyConfigMapKey] = string(data)
+	if _, err = client.Update(context.TODO(), configMap, metav1.UpdateOptions{}); err != nil {
+		klog.ErrorS(err, "Failed to update quota topology", "configMap", QuotaTopologyConfigMapName)
+	}
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	quotav1 "k8s.io/apiserver/pkg/quota/v1"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+func TestController_SyncQuotaTopology(t *testing.T) {
+	suit := newPluginTestSuit(t, nil)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.NoError(t, err)
+	plugin := p.(*Plugin)
+	plugin.OnQuotaAdd(CreateQuota2("parent", extension.RootQuotaName, 100, 1000, 10, 100, 100, 1000, true, ""))
+	plugin.OnQuotaAdd(CreateQuota2("child-1", "parent", 100, 1000, 10, 100, 100, 1000, false, ""))
+	plugin.OnQuotaAdd(CreateQuota2("child-2", "parent", 100, 1000, 10, 100, 100, 1000, false, ""))
+
+	ctrl := NewElasticQuotaController(plugin)
+	getTopology := func() QuotaTopology {
+		configMap, err := suit.Handle.ClientSet().CoreV1().ConfigMaps(suit.elasticQuotaArgs.QuotaGroupNamespace).
+			Get(context.TODO(), QuotaTopologyConfigMapName, metav1.GetOptions{})
+		assert.NoError(t, err)
+		topology := QuotaTopology{}
+		assert.NoError(t, json.Unmarshal([]byte(configMap.Data[QuotaTopologyConfigMapKey]), &topology))
+		return topology
+	}
+
+	// the topology is created
+	ctrl.syncQuotaTopology()
+	tree := getTopology()[""]
+	assert.Equal(t, []string{"child-1", "child-2"}, tree["parent"].Children)
+	assert.Equal(t, "parent", tree["child-1"].Parent)
+	assert.Contains(t, tree[extension.RootQuotaName].Children, "parent")
+	assert.True(t, quotav1.IsZero(tree["child-1"].Used))
+
+	// the topology is updated when the quota state changes
+	pod := MakePod("t1-ns1", "pod1").Label(extension.LabelQuotaName, "child-1").Container(
+		createResourceList(10, 100)).Obj()
+	pod.Spec.NodeName = "node1"
+	plugin.OnPodAdd(pod)
+	plugin.OnQuotaAdd(CreateQuota2("child-3", "parent", 100, 1000, 10, 100, 100, 1000, false, ""))
+	ctrl.syncQuotaTopology()
+	tree = getTopology()[""]
+	assert.Equal(t, []string{"child-1", "child-2", "child-3"}, tree["parent"].Children)
+	assert.True(t, quotav1.Equals(createResourceList(10, 100), tree["child-1"].Used))
+	assert.True(t, quotav1.Equals(createResourceList(10, 100), tree["parent"].Used))
+}
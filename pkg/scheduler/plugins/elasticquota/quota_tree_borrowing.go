@@ -0,0 +1,120 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	quotav1 "k8s.io/apiserver/pkg/quota/v1"
+	k8sfeature "k8s.io/apiserver/pkg/util/feature"
+
+	koordfeatures "github.com/koordinator-sh/koordinator/pkg/features"
+	"github.com/koordinator-sh/koordinator/pkg/scheduler/plugins/elasticquota/core"
+	"github.com/koordinator-sh/koordinator/pkg/util"
+)
+
+// syncTreeBorrowing redistributes the idle capacity of the quota trees to the trees which declare them as lenders.
+// Every time it's synced, the borrowing starts from scratch, so the capacity borrowed is revoked as soon as the
+// lender tree demands it again, and the over-used pods of the borrower tree become evictable.
+// It's driven by the pod, quota and node events which change the demand or the capacity of the trees, and it's
+// skipped if no tree declares lenders and nothing is borrowed before.
+func (g *Plugin) syncTreeBorrowing() {
+	if !k8sfeature.DefaultFeatureGate.Enabled(koordfeatures.MultiQuotaTree) {
+		return
+	}
+
+	g.treeBorrowingLock.Lock()
+	defer g.treeBorrowingLock.Unlock()
+
+	mgrs := g.ListGroupQuotaManagersForQuotaTree()
+	hasLenders := false
+	for _, mgr := range mgrs {
+		if len(mgr.GetLenderTrees()) > 0 {
+			hasLenders = true
+			break
+		}
+	}
+	if !hasLenders && !g.treeBorrowingActive {
+		return
+	}
+	// the borrowing is reset below once the last lender is removed
+	g.treeBorrowingActive = hasLenders
+
+	managers := map[string]*core.GroupQuotaManager{}
+	idle := map[string]corev1.ResourceList{}
+	shortage := map[string]corev1.ResourceList{}
+	for _, mgr := range mgrs {
+		treeID := mgr.GetTreeID()
+		managers[treeID] = mgr
+		idle[treeID], shortage[treeID] = mgr.GetTreeBorrowingState()
+	}
+
+	treeIDs := make([]string, 0, len(managers))
+	for treeID := range managers {
+		treeIDs = append(treeIDs, treeID)
+	}
+	sort.Strings(treeIDs)
+
+	borrowed := map[string]corev1.ResourceList{}
+	lent := map[string]corev1.ResourceList{}
+	for _, borrower := range treeIDs {
+		for _, lender := range managers[borrower].GetLenderTrees() {
+			if lender == borrower || managers[lender] == nil || quotav1.IsZero(shortage[borrower]) {
+				continue
+			}
+			amount := minResourceList(shortage[borrower], idle[lender])
+			if quotav1.IsZero(amount) {
+				continue
+			}
+			borrowed[borrower] = quotav1.Add(borrowed[borrower], amount)
+			lent[lender] = quotav1.Add(lent[lender], amount)
+			shortage[borrower] = quotav1.Subtract(shortage[borrower], amount)
+			idle[lender] = quotav1.Subtract(idle[lender], amount)
+		}
+	}
+
+	for _, treeID := range treeIDs {
+		managers[treeID].SetBorrowedAndLentResource(borrowed[treeID], lent[treeID])
+	}
+}
+
+// minResourceList returns the smaller quantity of each resource in a which is also in b.
+func minResourceList(a, b corev1.ResourceList) corev1.ResourceList {
+	result := corev1.ResourceList{}
+	for resourceName, quantity := range a {
+		other, ok := b[resourceName]
+		if !ok {
+			continue
+		}
+		if other.Cmp(quantity) < 0 {
+			result[resourceName] = other.DeepCopy()
+		} else {
+			result[resourceName] = quantity.DeepCopy()
+		}
+	}
+	return result
+}
+
+// isPodChargeChanged returns true if the update of the pod may change the request or used charged to its quota,
+// i.e. the pod is assigned, released, terminated, terminating or resized.
+func isPodChargeChanged(oldPod, newPod *corev1.Pod) bool {
+	return oldPod.Spec.NodeName != newPod.Spec.NodeName ||
+		util.IsPodTerminated(oldPod) != util.IsPodTerminated(newPod) ||
+		(oldPod.DeletionTimestamp == nil) != (newPod.DeletionTimestamp == nil) ||
+		!quotav1.Equals(core.PodRequests(oldPod), core.PodRequests(newPod))
+}
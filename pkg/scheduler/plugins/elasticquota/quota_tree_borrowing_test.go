@@ -0,0 +1,206 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	quotav1 "k8s.io/apiserver/pkg/quota/v1"
+	k8sfeature "k8s.io/apiserver/pkg/util/feature"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	koordfeatures "github.com/koordinator-sh/koordinator/pkg/features"
+	utilfeature "github.com/koordinator-sh/koordinator/pkg/util/feature"
+)
+
+func TestPlugin_TreeBorrowing(t *testing.T) {
+	defer utilfeature.SetFeatureGateDuringTest(t, k8sfeature.DefaultMutableFeatureGate, koordfeatures.MultiQuotaTree, true)()
+
+	suit := newPluginTestSuit(t, nil)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.Nil(t, err)
+	gp := p.(*Plugin)
+
+	// tree-a: total[100,200]
+	// root-a Max[200,400] Min[100,200]
+	//   `-- a1 Max[200,400] Min[0,0]
+	// tree-b: total[100,200]
+	// root-b Max[100,200] Min[100,200]
+	//   `-- b1 Max[100,200] Min[60,120]
+	rootA := gp.addRootQuota("root-a", "", 200, 400, 100, 200, 200, 400, true, "", "tree-a")
+	gp.addQuota("a1", "root-a", 200, 400, 0, 0, 200, 400, false, "", "tree-a")
+	gp.addRootQuota("root-b", "", 100, 200, 100, 200, 100, 200, true, "", "tree-b")
+	gp.addQuota("b1", "root-b", 100, 200, 60, 120, 100, 200, false, "", "tree-b")
+	gqmA := gp.GetGroupQuotaManagerForTree("tree-a")
+	gqmB := gp.GetGroupQuotaManagerForTree("tree-b")
+
+	podA1 := makePod2("pod-a1", createResourceList(100, 200))
+	podA1.Labels[extension.LabelQuotaName] = "a1"
+	gp.OnPodAdd(podA1)
+	podA2 := makePod2("pod-a2", createResourceList(50, 100))
+	podA2.Labels[extension.LabelQuotaName] = "a1"
+	podA2.Spec.NodeName = ""
+	gp.OnPodAdd(podA2)
+
+	// tree-a can't admit beyond its own total without the lender
+	_, status := gp.PreFilter(context.TODO(), framework.NewCycleState(), podA2)
+	assert.False(t, status.IsSuccess())
+
+	newRootA := rootA.DeepCopy()
+	newRootA.ResourceVersion = "2"
+	newRootA.Annotations[extension.AnnotationLenderTrees] = `["tree-b"]`
+	gp.OnQuotaUpdate(rootA, newRootA)
+	assert.Equal(t, []string{"tree-b"}, gqmA.GetLenderTrees())
+
+	// tree-b is idle, tree-a borrows its shortage
+	_, status = gp.PreFilter(context.TODO(), framework.NewCycleState(), podA2)
+	assert.True(t, status.IsSuccess(), status.Message())
+	assert.True(t, quotav1.Equals(createResourceList(50, 100), gqmA.GetBorrowedResource()))
+	assert.True(t, quotav1.Equals(createResourceList(50, 100), gqmB.GetLentResource()))
+	assert.True(t, quotav1.Equals(createResourceList(150, 300), gqmA.RefreshRuntime("a1")))
+
+	// the shared total isn't counted twice
+	idleA, _ := gqmA.GetTreeBorrowingState()
+	idleB, _ := gqmB.GetTreeBorrowingState()
+	assert.True(t, quotav1.IsZero(idleA))
+	assert.True(t, quotav1.Equals(createResourceList(100, 200), idleB))
+
+	boundPodA2 := podA2.DeepCopy()
+	boundPodA2.ResourceVersion = "2"
+	boundPodA2.Spec.NodeName = "testNode"
+	gp.OnPodUpdate(podA2, boundPodA2)
+
+	// the lender demands its min again, the borrowed capacity is revoked at once
+	podB := makePod2("pod-b", createResourceList(60, 120))
+	podB.Labels[extension.LabelQuotaName] = "b1"
+	podB.Spec.NodeName = ""
+	gp.OnPodAdd(podB)
+	_, status = gp.PreFilter(context.TODO(), framework.NewCycleState(), podB)
+	assert.True(t, status.IsSuccess(), status.Message())
+	assert.True(t, quotav1.Equals(createResourceList(40, 80), gqmA.GetBorrowedResource()))
+	assert.True(t, quotav1.Equals(createResourceList(40, 80), gqmB.GetLentResource()))
+	assert.True(t, quotav1.Equals(createResourceList(60, 120), gqmB.RefreshRuntime("b1")))
+
+	// tree-a is over-used and its pods become evictable
+	runtimeA1 := gqmA.RefreshRuntime("a1")
+	assert.True(t, quotav1.Equals(createResourceList(140, 280), runtimeA1))
+	usedA1 := gqmA.GetQuotaInfoByName("a1").GetUsed()
+	assert.False(t, quotav1.IsZero(quotav1.SubtractWithNonNegativeResult(usedA1, runtimeA1)))
+
+	// the lender turns idle again and the borrower gets the capacity back
+	gp.OnPodDelete(podB)
+	assert.True(t, quotav1.Equals(createResourceList(50, 100), gqmA.GetBorrowedResource()))
+	assert.True(t, quotav1.Equals(createResourceList(150, 300), gqmA.RefreshRuntime("a1")))
+}
+
+func TestPlugin_TreeBorrowingDrivenByEvents(t *testing.T) {
+	defer utilfeature.SetFeatureGateDuringTest(t, k8sfeature.DefaultMutableFeatureGate, koordfeatures.MultiQuotaTree, true)()
+
+	suit := newPluginTestSuit(t, nil)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.Nil(t, err)
+	gp := p.(*Plugin)
+
+	rootA := gp.addRootQuota("root-a", "", 200, 400, 100, 200, 200, 400, true, "", "tree-a")
+	gp.addQuota("a1", "root-a", 200, 400, 0, 0, 200, 400, false, "", "tree-a")
+	gp.addRootQuota("root-b", "", 100, 200, 100, 200, 100, 200, true, "", "tree-b")
+	gp.addQuota("b1", "root-b", 100, 200, 60, 120, 100, 200, false, "", "tree-b")
+	gqmA := gp.GetGroupQuotaManagerForTree("tree-a")
+	gqmB := gp.GetGroupQuotaManagerForTree("tree-b")
+
+	// no tree declares lenders, the redistribution is skipped
+	podA1 := makePod2("pod-a1", createResourceList(150, 300))
+	podA1.Labels[extension.LabelQuotaName] = "a1"
+	gp.OnPodAdd(podA1)
+	assert.False(t, gp.treeBorrowingActive)
+	assert.True(t, quotav1.IsZero(gqmA.GetBorrowedResource()))
+
+	// the quota event declaring the lender syncs the borrowing without any scheduling cycle
+	newRootA := rootA.DeepCopy()
+	newRootA.ResourceVersion = "2"
+	newRootA.Annotations[extension.AnnotationLenderTrees] = `["tree-b"]`
+	gp.OnQuotaUpdate(rootA, newRootA)
+	assert.True(t, gp.treeBorrowingActive)
+	assert.True(t, quotav1.Equals(createResourceList(50, 100), gqmA.GetBorrowedResource()))
+	assert.True(t, quotav1.Equals(createResourceList(50, 100), gqmB.GetLentResource()))
+
+	// the borrowing is reset once the last lender is removed
+	newRootA2 := newRootA.DeepCopy()
+	newRootA2.ResourceVersion = "3"
+	delete(newRootA2.Annotations, extension.AnnotationLenderTrees)
+	gp.OnQuotaUpdate(newRootA, newRootA2)
+	assert.False(t, gp.treeBorrowingActive)
+	assert.True(t, quotav1.IsZero(gqmA.GetBorrowedResource()))
+	assert.True(t, quotav1.IsZero(gqmB.GetLentResource()))
+}
+
+func TestIsPodChargeChanged(t *testing.T) {
+	pod := makePod2("pod", createResourceList(10, 20))
+	tests := []struct {
+		name   string
+		update func(pod *corev1.Pod)
+		want   bool
+	}{
+		{
+			name: "status only",
+			update: func(pod *corev1.Pod) {
+				pod.Status.Conditions = append(pod.Status.Conditions, corev1.PodCondition{Type: corev1.PodReady})
+			},
+			want: false,
+		},
+		{
+			name: "assigned",
+			update: func(pod *corev1.Pod) {
+				pod.Spec.NodeName = "another-node"
+			},
+			want: true,
+		},
+		{
+			name: "terminated",
+			update: func(pod *corev1.Pod) {
+				pod.Status.Phase = corev1.PodSucceeded
+			},
+			want: true,
+		},
+		{
+			name: "terminating",
+			update: func(pod *corev1.Pod) {
+				pod.DeletionTimestamp = &metav1.Time{}
+			},
+			want: true,
+		},
+		{
+			name: "resized",
+			update: func(pod *corev1.Pod) {
+				pod.Spec.Containers[0].Resources.Requests = createResourceList(20, 20)
+			},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newPod := pod.DeepCopy()
+			tt.update(newPod)
+			assert.Equal(t, tt.want, isPodChargeChanged(pod, newPod))
+		})
+	}
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	quotav1 "k8s.io/apiserver/pkg/quota/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/koordinator-sh/koordinator/apis/thirdparty/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+	"github.com/koordinator-sh/koordinator/pkg/scheduler/plugins/elasticquota/core"
+)
+
+// QuotaWarmUpDuration is the period after the creation of a quota during which its runtime may not be
+// settled yet, e.g. the requests of its pods are not all accounted.
+const QuotaWarmUpDuration = 30 * time.Second
+
+// startQuotaWarmUp records the end of the warm-up of a newly created quota. The quotas created long before,
+// e.g. the ones listed when the scheduler restarts, don't warm up.
+func (g *Plugin) startQuotaWarmUp(quota *v1alpha1.ElasticQuota) {
+	if quota.CreationTimestamp.IsZero() {
+		return
+	}
+	deadline := quota.CreationTimestamp.Add(QuotaWarmUpDuration)
+	if !g.clock.Now().Before(deadline) {
+		return
+	}
+
+	g.quotaWarmUpLock.Lock()
+	defer g.quotaWarmUpLock.Unlock()
+	g.quotaWarmUpDeadline[quota.Name] = deadline
+	klog.V(5).Infof("quota %v warms up until %v", quota.Name, deadline)
+}
+
+func (g *Plugin) stopQuotaWarmUp(quotaName string) {
+	g.quotaWarmUpLock.Lock()
+	defer g.quotaWarmUpLock.Unlock()
+	delete(g.quotaWarmUpDeadline, quotaName)
+}
+
+func (g *Plugin) isQuotaWarmingUp(quotaName string) bool {
+	g.quotaWarmUpLock.RLock()
+	deadline, ok := g.quotaWarmUpDeadline[quotaName]
+	g.quotaWarmUpLock.RUnlock()
+	if !ok {
+		return false
+	}
+	if g.clock.Now().Before(deadline) {
+		return true
+	}
+	g.stopQuotaWarmUp(quotaName)
+	return false
+}
+
+// getWarmUpUsedLimit admits the pods of a warming-up quota conservatively: the runtime may still be zero
+// before the first full refresh, so the quota can use at least its min, which is guaranteed anyway.
+func (g *Plugin) getWarmUpUsedLimit(quotaInfo *core.QuotaInfo, runtime corev1.ResourceList) corev1.ResourceList {
+	if !g.isQuotaWarmingUp(quotaInfo.Name) {
+		return runtime
+	}
+	maxQuota := quotaInfo.GetMax()
+	guaranteed := quotav1.Mask(quotaInfo.GetMin(), quotav1.ResourceNames(maxQuota))
+	return quotav1.Max(runtime, guaranteed)
+}
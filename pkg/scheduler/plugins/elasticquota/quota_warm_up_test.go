@@ -0,0 +1,102 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	fakeclock "k8s.io/utils/clock/testing"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+func TestPlugin_PreFilter_QuotaWarmUp(t *testing.T) {
+	tests := []struct {
+		name            string
+		createdBefore   time.Duration
+		elapsed         time.Duration
+		cpu             int64
+		expectedSuccess bool
+	}{
+		{
+			name:            "admit within min right after creation",
+			cpu:             10,
+			expectedSuccess: true,
+		},
+		{
+			name:            "reject beyond min right after creation",
+			cpu:             11,
+			expectedSuccess: false,
+		},
+		{
+			name:            "reject after warm-up as runtime is zero",
+			elapsed:         QuotaWarmUpDuration,
+			cpu:             10,
+			expectedSuccess: false,
+		},
+		{
+			name:            "quota created long before doesn't warm up",
+			createdBefore:   time.Hour,
+			cpu:             10,
+			expectedSuccess: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suit := newPluginTestSuit(t, nil)
+			p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+			assert.Nil(t, err)
+			gp := p.(*Plugin)
+			fakeClock := fakeclock.NewFakeClock(time.Now())
+			gp.clock = fakeClock
+			gp.pluginArgs.EnableRuntimeQuota = true
+			gp.groupQuotaManager.UpdateClusterTotalResource(createResourceList(1000, 10000))
+
+			quota := CreateQuota2("test1", extension.RootQuotaName, 100, 1000, 10, 100, 100, 1000, false, "")
+			quota.CreationTimestamp = metav1.NewTime(fakeClock.Now().Add(-tt.createdBefore))
+			gp.OnQuotaAdd(quota)
+			fakeClock.Step(tt.elapsed)
+
+			pod := MakePod("t1-ns1", "pod1").Label(extension.LabelQuotaName, "test1").Container(
+				createResourceList(tt.cpu, 10)).Obj()
+			_, status := gp.PreFilter(context.TODO(), framework.NewCycleState(), pod)
+			assert.Equal(t, tt.expectedSuccess, status.IsSuccess(), status.Message())
+		})
+	}
+}
+
+func TestPlugin_QuotaWarmUpStopOnDelete(t *testing.T) {
+	suit := newPluginTestSuit(t, nil)
+	p, err := suit.proxyNew(suit.elasticQuotaArgs, suit.Handle)
+	assert.Nil(t, err)
+	gp := p.(*Plugin)
+	fakeClock := fakeclock.NewFakeClock(time.Now())
+	gp.clock = fakeClock
+
+	quota := CreateQuota2("test1", extension.RootQuotaName, 100, 1000, 10, 100, 100, 1000, false, "")
+	quota.CreationTimestamp = metav1.NewTime(fakeClock.Now())
+	gp.OnQuotaAdd(quota)
+	assert.True(t, gp.isQuotaWarmingUp("test1"))
+
+	gp.OnQuotaDelete(quota)
+	assert.False(t, gp.isQuotaWarmingUp("test1"))
+}
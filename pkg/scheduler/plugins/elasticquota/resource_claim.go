@@ -0,0 +1,48 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	resourcelisters "k8s.io/client-go/listers/resource/v1alpha2"
+
+	"github.com/koordinator-sh/koordinator/pkg/scheduler/plugins/elasticquota/core"
+)
+
+// newResourceClaimClassGetter returns a getter which resolves the resource class of the pod's resource claim
+// from the referenced ResourceClaim or ResourceClaimTemplate.
+func newResourceClaimClassGetter(claimLister resourcelisters.ResourceClaimLister,
+	templateLister resourcelisters.ResourceClaimTemplateLister) core.ResourceClaimClassGetter {
+	return func(pod *corev1.Pod, podClaim *corev1.PodResourceClaim) (string, error) {
+		source := podClaim.Source
+		if source.ResourceClaimName != nil {
+			claim, err := claimLister.ResourceClaims(pod.Namespace).Get(*source.ResourceClaimName)
+			if err != nil {
+				return "", err
+			}
+			return claim.Spec.ResourceClassName, nil
+		}
+		if source.ResourceClaimTemplateName != nil {
+			template, err := templateLister.ResourceClaimTemplates(pod.Namespace).Get(*source.ResourceClaimTemplateName)
+			if err != nil {
+				return "", err
+			}
+			return template.Spec.Spec.ResourceClassName, nil
+		}
+		return "", nil
+	}
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	resourcev1alpha2 "k8s.io/api/resource/v1alpha2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	resourcelisters "k8s.io/client-go/listers/resource/v1alpha2"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/pointer"
+)
+
+func TestResourceClaimClassGetter(t *testing.T) {
+	claimIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	templateIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	assert.NoError(t, claimIndexer.Add(&resourcev1alpha2.ResourceClaim{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "claim-1"},
+		Spec:       resourcev1alpha2.ResourceClaimSpec{ResourceClassName: "gpu.example.com"},
+	}))
+	assert.NoError(t, templateIndexer.Add(&resourcev1alpha2.ResourceClaimTemplate{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "template-1"},
+		Spec: resourcev1alpha2.ResourceClaimTemplateSpec{
+			Spec: resourcev1alpha2.ResourceClaimSpec{ResourceClassName: "fpga.example.com"},
+		},
+	}))
+	getter := newResourceClaimClassGetter(resourcelisters.NewResourceClaimLister(claimIndexer),
+		resourcelisters.NewResourceClaimTemplateLister(templateIndexer))
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pod-1"}}
+	tests := []struct {
+		name      string
+		source    corev1.ClaimSource
+		wantClass string
+		wantErr   bool
+	}{
+		{
+			name:      "resource claim",
+			source:    corev1.ClaimSource{ResourceClaimName: pointer.String("claim-1")},
+			wantClass: "gpu.example.com",
+		},
+		{
+			name:      "resource claim template",
+			source:    corev1.ClaimSource{ResourceClaimTemplateName: pointer.String("template-1")},
+			wantClass: "fpga.example.com",
+		},
+		{
+			name:    "resource claim not found",
+			source:  corev1.ClaimSource{ResourceClaimName: pointer.String("claim-2")},
+			wantErr: true,
+		},
+		{
+			name:   "empty source",
+			source: corev1.ClaimSource{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			className, err := getter(pod, &corev1.PodResourceClaim{Name: "claim", Source: tt.source})
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.wantClass, className)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"flag"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+)
+
+var (
+	// quotaUpdateQPS is the max rate of edits allowed per quota, zero or negative disables the rate limiting.
+	quotaUpdateQPS   float64
+	quotaUpdateBurst = 5
+	// defaultParentQuotaName is the parent filled for new quotas lacking the parent label, defaults to the root quota.
+	defaultParentQuotaName = extension.RootQuotaName
+	// deleteQuotaFailOpen allows deleting the quota when its pods can't be listed, instead of rejecting the deletion.
+	deleteQuotaFailOpen = false
+	// quotaTopologyConfig is the config of the quota topology filled by the flags.
+	quotaTopologyConfig = QuotaTopologyConfig{}
+)
+
+// QuotaTopologyConfig is the config of the limits checked by the quota topology.
+type QuotaTopologyConfig struct {
+	// MaxQuotaDescendants is the max number of descendants of a top-level quota, zero or negative means no limit.
+	MaxQuotaDescendants int
+	// MaxQuotaTreeDepth is the max depth of the quotas, the top-level quotas are at depth 1, zero or negative means no limit.
+	MaxQuotaTreeDepth int
+}
+
+func InitFlags(fs *flag.FlagSet) {
+	fs.Float64Var(&quotaUpdateQPS, "elastic-quota-update-qps", quotaUpdateQPS,
+		"The max QPS of edits allowed per ElasticQuota by the validating webhook, 0 means no limit.")
+	fs.IntVar(&quotaUpdateBurst, "elastic-quota-update-burst", quotaUpdateBurst,
+		"The burst of edits allowed per ElasticQuota by the validating webhook.")
+	fs.StringVar(&defaultParentQuotaName, "elastic-quota-default-parent", defaultParentQuotaName,
+		"The parent filled for new ElasticQuotas lacking the parent label, e.g. a catch-all parent quota. "+
+			"It should match the defaultParentQuotaName of the scheduler's ElasticQuotaArgs, the root quota is filled if it doesn't exist.")
+	fs.BoolVar(&deleteQuotaFailOpen, "elastic-quota-delete-fail-open", deleteQuotaFailOpen,
+		"Whether to allow deleting an ElasticQuota when listing its pods fails, e.g. due to transient client errors.")
+	fs.IntVar(&quotaTopologyConfig.MaxQuotaDescendants, "elastic-quota-max-descendants", quotaTopologyConfig.MaxQuotaDescendants,
+		"The max number of descendants under a top-level ElasticQuota, i.e. the quota right under the root quota, 0 means no limit.")
+	fs.IntVar(&quotaTopologyConfig.MaxQuotaTreeDepth, "elastic-quota-max-tree-depth", quotaTopologyConfig.MaxQuotaTreeDepth,
+		"The max depth of the ElasticQuota tree, the top-level quotas are at depth 1, 0 means no limit.")
+}
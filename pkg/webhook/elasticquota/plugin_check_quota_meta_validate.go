@@ -19,6 +19,7 @@ package elasticquota
 import (
 	"context"
 	"fmt"
+	"reflect"
 
 	v1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -26,6 +27,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	clientcache "k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
@@ -38,11 +40,13 @@ type QuotaMetaChecker struct {
 	*admission.Decoder
 	QuotaTopo     *quotaTopology
 	QuotaInformer cache.Informer
+	rateLimiter   *quotaRateLimiter
 }
 
 var (
 	quotaMetaCheck = &QuotaMetaChecker{
-		QuotaTopo: nil,
+		QuotaTopo:   nil,
+		rateLimiter: newQuotaRateLimiter(clock.RealClock{}),
 	}
 )
 
@@ -54,7 +58,7 @@ func NewPlugin(decoder *admission.Decoder, client client.Client) *QuotaMetaCheck
 	quotaMetaCheck.Client = client
 	quotaMetaCheck.Decoder = decoder
 	if quotaMetaCheck.QuotaTopo == nil {
-		quotaMetaCheck.QuotaTopo = NewQuotaTopology(client)
+		quotaMetaCheck.QuotaTopo = NewQuotaTopology(client, quotaTopologyConfig)
 	}
 	return quotaMetaCheck
 }
@@ -87,9 +91,25 @@ func (c *QuotaMetaChecker) ValidateQuota(ctx context.Context, req admission.Requ
 		if err != nil {
 			return fmt.Errorf("failed to get quota from old object, err:%+v", err)
 		}
+		if !reflect.DeepEqual(quotaFieldsCopy(oldQuota), quotaFieldsCopy(quotaObj)) {
+			refund, err := c.rateLimiter.Allow(quotaRateLimiterKey(quotaObj), quotaUpdateQPS, quotaUpdateBurst)
+			if err != nil {
+				return err
+			}
+			// the rejected edits don't use up the budget of the quota
+			if err := c.QuotaTopo.ValidUpdateQuota(oldQuota, quotaObj); err != nil {
+				refund()
+				return err
+			}
+			return nil
+		}
 		return c.QuotaTopo.ValidUpdateQuota(oldQuota, quotaObj)
 	case v1.Delete:
-		return c.QuotaTopo.ValidDeleteQuota(quotaObj)
+		if err := c.QuotaTopo.ValidDeleteQuota(quotaObj); err != nil {
+			return err
+		}
+		c.rateLimiter.Forget(quotaRateLimiterKey(quotaObj))
+		return nil
 	}
 	return nil
 }
@@ -124,6 +144,30 @@ func (c *QuotaMetaChecker) GetQuotaTopologyInfo() *QuotaTopologySummary {
 	return quotaMetaCheck.QuotaTopo.getQuotaTopologyInfo()
 }
 
+// GetQuotaSubtreeTopologyInfo returns only the named quota's subtree.
+func (c *QuotaMetaChecker) GetQuotaSubtreeTopologyInfo(rootName string) *QuotaTopologySummary {
+	if c.QuotaTopo == nil {
+		return nil
+	}
+	return c.QuotaTopo.getQuotaSubtreeTopologyInfo(rootName)
+}
+
+// CloneQuotaSubtree generates the validated quotas copying the subtree of the source quota.
+func (c *QuotaMetaChecker) CloneQuotaSubtree(opts *QuotaSubtreeCloneOptions) ([]*v1alpha1.ElasticQuota, error) {
+	if c.QuotaTopo == nil {
+		return nil, fmt.Errorf("quota topology is not initialized")
+	}
+	return c.QuotaTopo.CloneQuotaSubtree(opts)
+}
+
+// DryRunValidateQuota validates the proposed quota without applying it to the topology.
+func (c *QuotaMetaChecker) DryRunValidateQuota(quota *v1alpha1.ElasticQuota) error {
+	if c.QuotaTopo == nil {
+		return fmt.Errorf("quota topology is not initialized")
+	}
+	return c.QuotaTopo.DryRunValidQuota(quota)
+}
+
 func (c *QuotaMetaChecker) GetQuotaInfo(name, namespace string) *QuotaInfo {
 	if c.QuotaTopo == nil {
 		return nil
@@ -0,0 +1,283 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	"github.com/koordinator-sh/koordinator/apis/thirdparty/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+)
+
+// clonedQuotaDroppedAnnotations are the annotations not carried over to the cloned quotas, they're either
+// the status written back by the scheduler or bound to the source quota exclusively.
+var clonedQuotaDroppedAnnotations = []string{
+	extension.AnnotationRuntime,
+	extension.AnnotationRequest,
+	extension.AnnotationChildRequest,
+	extension.AnnotationUnschedulableResource,
+	extension.AnnotationGuaranteed,
+	extension.AnnotationAllocated,
+	extension.AnnotationNonPreemptibleRequest,
+	extension.AnnotationNonPreemptibleUsed,
+	extension.AnnotationQuotaNamespaces,
+}
+
+// QuotaSubtreeCloneOptions describes how to clone a quota subtree, e.g. to spin up a staging copy
+// of a production quota layout.
+type QuotaSubtreeCloneOptions struct {
+	// SourceQuota is the root of the subtree to clone.
+	SourceQuota string
+	// NewParent is the parent of the cloned subtree, defaults to the root quota.
+	NewParent string
+	// NewTreeID is the tree id of the cloned quotas, empty means the cloned quotas don't belong to any tree.
+	NewTreeID string
+	// NamePrefix is prepended to the names of the cloned quotas to tell them from the source quotas.
+	NamePrefix string
+	// Scale is the ratio applied to the max, min, shared weight and total resource of the cloned quotas.
+	Scale float64
+}
+
+// CloneQuotaSubtree generates the quotas copying the subtree rooted at the source quota with scaled capacities,
+// the parents come before their children. The quotas are validated as a batch but not created.
+func (qt *quotaTopology) CloneQuotaSubtree(opts *QuotaSubtreeCloneOptions) ([]*v1alpha1.ElasticQuota, error) {
+	if opts == nil || opts.SourceQuota == "" {
+		return nil, fmt.Errorf("CloneQuotaSubtree source quota is empty")
+	}
+	if opts.NamePrefix == "" {
+		return nil, fmt.Errorf("CloneQuotaSubtree name prefix is empty")
+	}
+	if opts.Scale <= 0 {
+		return nil, fmt.Errorf("CloneQuotaSubtree scale should be positive, got %v", opts.Scale)
+	}
+	newParent := opts.NewParent
+	if newParent == "" {
+		newParent = extension.RootQuotaName
+	}
+
+	quotaList := &v1alpha1.ElasticQuotaList{}
+	if err := qt.client.List(context.TODO(), quotaList); err != nil {
+		return nil, fmt.Errorf("CloneQuotaSubtree failed to list quotas, err: %v", err)
+	}
+	quotas := make(map[string]*v1alpha1.ElasticQuota, len(quotaList.Items))
+	children := make(map[string][]string)
+	for i := range quotaList.Items {
+		quota := &quotaList.Items[i]
+		quotas[quota.Name] = quota
+		parentName := extension.GetParentQuotaName(quota)
+		children[parentName] = append(children[parentName], quota.Name)
+	}
+	if _, ok := quotas[opts.SourceQuota]; !ok {
+		return nil, fmt.Errorf("CloneQuotaSubtree source quota %v not found", opts.SourceQuota)
+	}
+
+	var clones []*v1alpha1.ElasticQuota
+	toVisit := []string{opts.SourceQuota}
+	for len(toVisit) > 0 {
+		name := toVisit[0]
+		toVisit = toVisit[1:]
+
+		parentName := newParent
+		if name != opts.SourceQuota {
+			parentName = opts.NamePrefix + extension.GetParentQuotaName(quotas[name])
+		}
+		isTreeRoot := name == opts.SourceQuota && opts.NewTreeID != "" && newParent == extension.RootQuotaName
+		clone, err := cloneQuota(quotas[name], opts, parentName, isTreeRoot)
+		if err != nil {
+			return nil, err
+		}
+		clones = append(clones, clone)
+
+		childNames := children[name]
+		sort.Strings(childNames)
+		toVisit = append(toVisit, childNames...)
+	}
+
+	if err := qt.ValidateBatch(clones); err != nil {
+		return nil, err
+	}
+	return clones, nil
+}
+
+func cloneQuota(quota *v1alpha1.ElasticQuota, opts *QuotaSubtreeCloneOptions, parentName string, isTreeRoot bool) (*v1alpha1.ElasticQuota, error) {
+	clone := &v1alpha1.ElasticQuota{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        opts.NamePrefix + quota.Name,
+			Namespace:   quota.Namespace,
+			Labels:      make(map[string]string, len(quota.Labels)),
+			Annotations: make(map[string]string, len(quota.Annotations)),
+		},
+		Spec: v1alpha1.ElasticQuotaSpec{
+			Max: scaleResourceList(quota.Spec.Max, opts.Scale),
+			Min: scaleResourceList(quota.Spec.Min, opts.Scale),
+		},
+	}
+	for key, value := range quota.Labels {
+		clone.Labels[key] = value
+	}
+	for key, value := range quota.Annotations {
+		clone.Annotations[key] = value
+	}
+	for _, key := range clonedQuotaDroppedAnnotations {
+		delete(clone.Annotations, key)
+	}
+
+	clone.Labels[extension.LabelQuotaParent] = parentName
+	delete(clone.Labels, extension.LabelQuotaTreeID)
+	if opts.NewTreeID != "" {
+		clone.Labels[extension.LabelQuotaTreeID] = opts.NewTreeID
+	}
+	delete(clone.Labels, extension.LabelQuotaIsRoot)
+	if isTreeRoot {
+		clone.Labels[extension.LabelQuotaIsRoot] = "true"
+	}
+
+	if _, exist := quota.Annotations[extension.AnnotationSharedWeight]; exist {
+		if err := setScaledResourceAnnotation(clone, extension.AnnotationSharedWeight, extension.GetSharedWeight(quota), opts.Scale); err != nil {
+			return nil, err
+		}
+	}
+	// the cloned children are renamed with the prefix as well
+	if childrenOrder := extension.GetChildrenOrder(quota); len(childrenOrder) > 0 {
+		clonedOrder := make([]string, 0, len(childrenOrder))
+		for _, childName := range childrenOrder {
+			clonedOrder = append(clonedOrder, opts.NamePrefix+childName)
+		}
+		data, err := json.Marshal(clonedOrder)
+		if err != nil {
+			return nil, err
+		}
+		clone.Annotations[extension.AnnotationChildrenOrder] = string(data)
+	}
+	delete(clone.Annotations, extension.AnnotationTotalResource)
+	if isTreeRoot {
+		totalResource, err := extension.GetTotalResource(quota)
+		if err != nil {
+			return nil, fmt.Errorf("CloneQuotaSubtree failed to get total resource of %v, err: %v", quota.Name, err)
+		}
+		if len(totalResource) > 0 {
+			if err := setScaledResourceAnnotation(clone, extension.AnnotationTotalResource, totalResource, opts.Scale); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return clone, nil
+}
+
+func setScaledResourceAnnotation(quota *v1alpha1.ElasticQuota, key string, resources corev1.ResourceList, scale float64) error {
+	data, err := json.Marshal(scaleResourceList(resources, scale))
+	if err != nil {
+		return fmt.Errorf("CloneQuotaSubtree failed to marshal %v of %v, err: %v", key, quota.Name, err)
+	}
+	quota.Annotations[key] = string(data)
+	return nil
+}
+
+// scaleResourceList multiplies each quantity by the scale, rounded down in milli units.
+func scaleResourceList(resources corev1.ResourceList, scale float64) corev1.ResourceList {
+	if resources == nil {
+		return nil
+	}
+	scaled := make(corev1.ResourceList, len(resources))
+	for name, quantity := range resources {
+		if quantity.Value() < math.MaxInt64/1000 {
+			scaled[name] = *resource.NewMilliQuantity(int64(float64(quantity.MilliValue())*scale), quantity.Format)
+		} else {
+			scaled[name] = *resource.NewQuantity(int64(float64(quantity.Value())*scale), quantity.Format)
+		}
+	}
+	return scaled
+}
+
+// ValidateBatch validates creating the quotas in order against a scratch copy of the topology, so a batch
+// like a cloned subtree is checked as a whole, including the quotas referring to the earlier ones, while
+// the topology itself is untouched.
+func (qt *quotaTopology) ValidateBatch(quotas []*v1alpha1.ElasticQuota) error {
+	qt.lock.Lock()
+	scratch := qt.copyNoLock()
+	qt.lock.Unlock()
+
+	for _, quota := range quotas {
+		quota = quota.DeepCopy()
+		if err := scratch.fillQuotaDefaultInformation(quota); err != nil {
+			return fmt.Errorf("ValidateBatch quota %v failed, err: %v", quota.Name, err)
+		}
+		if err := scratch.ValidAddQuota(quota); err != nil {
+			return fmt.Errorf("ValidateBatch quota %v failed, err: %v", quota.Name, err)
+		}
+	}
+	return nil
+}
+
+// DryRunValidQuota validates the proposed quota as the admission webhook does, as a creation if the quota doesn't
+// exist in the topology or as an update of the live quota otherwise. It validates against a scratch copy of the
+// topology, so the topology itself is untouched.
+func (qt *quotaTopology) DryRunValidQuota(quota *v1alpha1.ElasticQuota) error {
+	if quota == nil {
+		return fmt.Errorf("DryRunQuota param is nil")
+	}
+
+	qt.lock.Lock()
+	scratch := qt.copyNoLock()
+	qt.lock.Unlock()
+
+	quota = quota.DeepCopy()
+	if _, exist := scratch.quotaInfoMap[quota.Name]; !exist {
+		if err := scratch.fillQuotaDefaultInformation(quota); err != nil {
+			return err
+		}
+		return scratch.ValidAddQuota(quota)
+	}
+
+	oldQuota := &v1alpha1.ElasticQuota{}
+	if err := qt.client.Get(context.TODO(), types.NamespacedName{Namespace: quota.Namespace, Name: quota.Name}, oldQuota); err != nil {
+		return fmt.Errorf("DryRunQuota failed to get quota %v, err: %v", quota.Name, err)
+	}
+	return scratch.ValidUpdateQuota(oldQuota, quota)
+}
+
+// copyNoLock copies the maps of the topology, the quotaInfos are shared since the validation doesn't modify them.
+func (qt *quotaTopology) copyNoLock() *quotaTopology {
+	topology := &quotaTopology{
+		quotaInfoMap:        make(map[string]*QuotaInfo, len(qt.quotaInfoMap)),
+		quotaHierarchyInfo:  make(map[string]map[string]struct{}, len(qt.quotaHierarchyInfo)),
+		namespaceToQuotaMap: make(map[string]string, len(qt.namespaceToQuotaMap)),
+		client:              qt.client,
+	}
+	for name, quotaInfo := range qt.quotaInfoMap {
+		topology.quotaInfoMap[name] = quotaInfo
+	}
+	for name, children := range qt.quotaHierarchyInfo {
+		topology.quotaHierarchyInfo[name] = make(map[string]struct{}, len(children))
+		for child := range children {
+			topology.quotaHierarchyInfo[name][child] = struct{}{}
+		}
+	}
+	for namespace, quotaName := range qt.namespaceToQuotaMap {
+		topology.namespaceToQuotaMap[namespace] = quotaName
+	}
+	return topology
+}
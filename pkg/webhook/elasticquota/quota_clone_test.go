@@ -0,0 +1,185 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	quotav1 "k8s.io/apiserver/pkg/quota/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	"github.com/koordinator-sh/koordinator/apis/thirdparty/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+)
+
+func TestQuotaTopology_CloneQuotaSubtree(t *testing.T) {
+	client := fake.NewClientBuilder().Build()
+	v1alpha1.AddToScheme(client.Scheme())
+	qt := newFakeQuotaTopology()
+	qt.client = client
+
+	quotas := []*v1alpha1.ElasticQuota{
+		MakeQuota("prod").ParentName(extension.RootQuotaName).IsParent(true).
+			Max(MakeResourceList().CPU(100).Mem(200).Obj()).Min(MakeResourceList().CPU(40).Mem(80).Obj()).
+			sharedWeight(MakeResourceList().CPU(100).Mem(200).Obj()).
+			Annotations(map[string]string{extension.AnnotationChildrenOrder: `["prod-b","prod-a"]`}).Obj(),
+		MakeQuota("prod-b").ParentName("prod").IsParent(false).
+			Max(MakeResourceList().CPU(40).Mem(80).Obj()).Min(MakeResourceList().CPU(20).Mem(40).Obj()).Obj(),
+		MakeQuota("prod-a").ParentName("prod").IsParent(false).
+			Max(MakeResourceList().CPU(60).Mem(120).Obj()).Min(MakeResourceList().CPU(20).Mem(40).Obj()).
+			Annotations(map[string]string{extension.AnnotationQuotaNamespaces: `["prod-ns"]`}).
+			ChildRequest(MakeResourceList().CPU(10).Mem(10).Obj()).Obj(),
+		MakeQuota("small").ParentName(extension.RootQuotaName).IsParent(true).
+			Max(MakeResourceList().CPU(10).Mem(20).Obj()).Min(MakeResourceList().CPU(10).Mem(20).Obj()).Obj(),
+	}
+	for _, quota := range quotas {
+		assert.NoError(t, client.Create(context.TODO(), quota))
+		qt.OnQuotaAdd(quota)
+	}
+
+	clones, err := qt.CloneQuotaSubtree(&QuotaSubtreeCloneOptions{
+		SourceQuota: "prod",
+		NamePrefix:  "staging-",
+		Scale:       0.5,
+	})
+	assert.NoError(t, err)
+	var names []string
+	for _, clone := range clones {
+		names = append(names, clone.Name)
+	}
+	// the parents come before the children
+	assert.Equal(t, []string{"staging-prod", "staging-prod-a", "staging-prod-b"}, names)
+
+	assert.Equal(t, extension.RootQuotaName, extension.GetParentQuotaName(clones[0]))
+	assert.True(t, extension.IsParentQuota(clones[0]))
+	assert.True(t, quotav1.Equals(MakeResourceList().CPU(50).Mem(100).Obj(), clones[0].Spec.Max))
+	assert.True(t, quotav1.Equals(MakeResourceList().CPU(20).Mem(40).Obj(), clones[0].Spec.Min))
+	assert.True(t, quotav1.Equals(MakeResourceList().CPU(50).Mem(100).Obj(), extension.GetSharedWeight(clones[0])))
+	assert.Equal(t, []string{"staging-prod-b", "staging-prod-a"}, extension.GetChildrenOrder(clones[0]))
+
+	assert.Equal(t, "staging-prod", extension.GetParentQuotaName(clones[1]))
+	assert.True(t, quotav1.Equals(MakeResourceList().CPU(30).Mem(60).Obj(), clones[1].Spec.Max))
+	assert.True(t, quotav1.Equals(MakeResourceList().CPU(10).Mem(20).Obj(), clones[1].Spec.Min))
+	// the namespaces and the status aren't cloned
+	assert.Empty(t, extension.GetAnnotationQuotaNamespaces(clones[1]))
+	assert.NotContains(t, clones[1].Annotations, extension.AnnotationChildRequest)
+
+	assert.Equal(t, "staging-prod", extension.GetParentQuotaName(clones[2]))
+	assert.True(t, quotav1.Equals(MakeResourceList().CPU(20).Mem(40).Obj(), clones[2].Spec.Max))
+
+	// the topology isn't changed by the validation
+	assert.NotContains(t, qt.quotaInfoMap, "staging-prod")
+	assert.NotContains(t, qt.quotaHierarchyInfo[extension.RootQuotaName], "staging-prod")
+
+	// the cloned subtree becomes a tree under the root
+	clones, err = qt.CloneQuotaSubtree(&QuotaSubtreeCloneOptions{
+		SourceQuota: "prod",
+		NewTreeID:   "staging",
+		NamePrefix:  "staging-",
+		Scale:       0.5,
+	})
+	assert.NoError(t, err)
+	assert.True(t, extension.IsTreeRootQuota(clones[0]))
+	for _, clone := range clones {
+		assert.Equal(t, "staging", extension.GetQuotaTreeID(clone))
+	}
+	assert.False(t, extension.IsTreeRootQuota(clones[1]))
+
+	// the cloned mins exceed the max of the new parent
+	_, err = qt.CloneQuotaSubtree(&QuotaSubtreeCloneOptions{
+		SourceQuota: "prod",
+		NewParent:   "small",
+		NamePrefix:  "staging-",
+		Scale:       0.5,
+	})
+	assert.Error(t, err)
+
+	// the source quota doesn't exist
+	_, err = qt.CloneQuotaSubtree(&QuotaSubtreeCloneOptions{
+		SourceQuota: "dev",
+		NamePrefix:  "staging-",
+		Scale:       0.5,
+	})
+	assert.Error(t, err)
+
+	// the cloned names conflict with the existing quotas
+	existing := MakeQuota("staging-prod-b").ParentName(extension.RootQuotaName).IsParent(false).
+		Max(MakeResourceList().CPU(10).Mem(20).Obj()).Obj()
+	assert.NoError(t, client.Create(context.TODO(), existing))
+	qt.OnQuotaAdd(existing)
+	_, err = qt.CloneQuotaSubtree(&QuotaSubtreeCloneOptions{
+		SourceQuota: "prod",
+		NamePrefix:  "staging-",
+		Scale:       0.5,
+	})
+	assert.Error(t, err)
+
+	_, err = qt.CloneQuotaSubtree(&QuotaSubtreeCloneOptions{SourceQuota: "prod", Scale: 0.5})
+	assert.Error(t, err)
+	_, err = qt.CloneQuotaSubtree(&QuotaSubtreeCloneOptions{SourceQuota: "prod", NamePrefix: "staging-"})
+	assert.Error(t, err)
+}
+
+func TestQuotaTopology_DryRunValidQuota(t *testing.T) {
+	client := fake.NewClientBuilder().Build()
+	v1alpha1.AddToScheme(client.Scheme())
+	qt := newFakeQuotaTopology()
+	qt.client = client
+
+	quotas := []*v1alpha1.ElasticQuota{
+		MakeQuota("parent").ParentName(extension.RootQuotaName).IsParent(true).
+			Max(MakeResourceList().CPU(100).Mem(200).Obj()).Min(MakeResourceList().CPU(40).Mem(80).Obj()).Obj(),
+		MakeQuota("child").ParentName("parent").IsParent(false).
+			Max(MakeResourceList().CPU(40).Mem(80).Obj()).Min(MakeResourceList().CPU(20).Mem(40).Obj()).Obj(),
+	}
+	for _, quota := range quotas {
+		assert.NoError(t, client.Create(context.TODO(), quota))
+		qt.OnQuotaAdd(quota)
+	}
+
+	// a valid new quota isn't added to the topology
+	valid := MakeQuota("child2").ParentName("parent").IsParent(false).
+		Max(MakeResourceList().CPU(40).Mem(80).Obj()).Min(MakeResourceList().CPU(10).Mem(20).Obj()).Obj()
+	assert.NoError(t, qt.DryRunValidQuota(valid))
+	assert.NotContains(t, qt.quotaInfoMap, "child2")
+	assert.NotContains(t, qt.quotaHierarchyInfo["parent"], "child2")
+
+	// the rejected new quota gets the message of the admission
+	invalid := MakeQuota("child2").ParentName("parent").IsParent(false).
+		Max(MakeResourceList().CPU(40).Mem(80).Obj()).Min(MakeResourceList().CPU(30).Mem(60).Obj()).Obj()
+	err := qt.DryRunValidQuota(invalid)
+	assert.Error(t, err)
+	admitted := invalid.DeepCopy()
+	assert.NoError(t, qt.fillQuotaDefaultInformation(admitted))
+	assert.Equal(t, qt.ValidAddQuota(admitted).Error(), err.Error())
+
+	// a valid update isn't applied to the topology
+	update := quotas[1].DeepCopy()
+	update.Spec.Min = MakeResourceList().CPU(30).Mem(60).Obj()
+	assert.NoError(t, qt.DryRunValidQuota(update))
+	assert.True(t, quotav1.Equals(MakeResourceList().CPU(20).Mem(40).Obj(), qt.quotaInfoMap["child"].CalculateInfo.Min))
+
+	// the rejected update gets the message of the admission
+	update.Spec.Max = MakeResourceList().CPU(60).Mem(120).Obj()
+	update.Spec.Min = MakeResourceList().CPU(50).Mem(100).Obj()
+	err = qt.DryRunValidQuota(update)
+	assert.Error(t, err)
+	assert.Equal(t, qt.ValidUpdateQuota(quotas[1], update).Error(), err.Error())
+	assert.True(t, quotav1.Equals(MakeResourceList().CPU(20).Mem(40).Obj(), qt.quotaInfoMap["child"].CalculateInfo.Min))
+}
@@ -27,7 +27,7 @@ import (
 
 func TestQuotaHandler(t *testing.T) {
 	client := fake.NewClientBuilder().Build()
-	topology := NewQuotaTopology(client)
+	topology := NewQuotaTopology(client, QuotaTopologyConfig{})
 
 	parentQuota := MakeQuota("parentQuota").Namespace("kube-system").Max(MakeResourceList().CPU(120).Mem(1048576).Obj()).
 		Min(MakeResourceList().CPU(120).Mem(1048576).Obj()).IsParent(true).Obj()
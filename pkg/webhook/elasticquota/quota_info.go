@@ -32,7 +32,9 @@ type QuotaInfo struct {
 	ParentName        string
 	TreeID            string
 	IsTreeRoot        bool
-	CalculateInfo     QuotaCalculateInfo
+	// OvercommitRatios are the overcommit ratios of the resource dimensions, the dimensions not set are 1.0.
+	OvercommitRatios map[v1.ResourceName]float64
+	CalculateInfo    QuotaCalculateInfo
 }
 
 type QuotaCalculateInfo struct {
@@ -44,6 +46,8 @@ type QuotaCalculateInfo struct {
 
 	Guaranteed v1.ResourceList
 	Allocated  v1.ResourceList
+	// TotalResource is the total resource of the quota tree, it's only recorded on the tree root quota.
+	TotalResource v1.ResourceList
 }
 
 func NewQuotaInfo(isParent, allowLentResource bool, name, parentName string) *QuotaInfo {
@@ -75,6 +79,10 @@ func NewQuotaInfoFromQuota(quota *v1alpha1.ElasticQuota) *QuotaInfo {
 	quotaInfo.AllowForceUpdate = extension.IsAllowForceUpdate(quota)
 	quotaInfo.CalculateInfo.Allocated, _ = extension.GetAllocated(quota)
 	quotaInfo.CalculateInfo.Guaranteed, _ = extension.GetGuaranteed(quota)
+	quotaInfo.OvercommitRatios, _ = extension.GetOvercommitRatios(quota)
+	if quotaInfo.IsTreeRoot {
+		quotaInfo.CalculateInfo.TotalResource, _ = extension.GetTotalResource(quota)
+	}
 
 	return quotaInfo
 }
@@ -0,0 +1,88 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/utils/clock"
+
+	"github.com/koordinator-sh/koordinator/apis/thirdparty/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+)
+
+// quotaRateLimiter limits the rate of edits per quota to protect against rapid conflicting quota edits.
+type quotaRateLimiter struct {
+	lock     sync.Mutex
+	limiters map[string]*rate.Limiter
+	clock    clock.Clock
+}
+
+func newQuotaRateLimiter(clock clock.Clock) *quotaRateLimiter {
+	return &quotaRateLimiter{
+		limiters: make(map[string]*rate.Limiter),
+		clock:    clock,
+	}
+}
+
+// Allow returns an error if the quota has been edited faster than qps with the given burst. Otherwise it takes
+// a token and returns the func to give the token back, e.g. when the edit is rejected by the later validation.
+func (l *quotaRateLimiter) Allow(quotaKey string, qps float64, burst int) (func(), error) {
+	if qps <= 0 {
+		return func() {}, nil
+	}
+	if burst <= 0 {
+		burst = 1
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	limit := rate.Limit(qps)
+	limiter := l.limiters[quotaKey]
+	if limiter == nil {
+		limiter = rate.NewLimiter(limit, burst)
+		l.limiters[quotaKey] = limiter
+	} else if limiter.Limit() != limit || limiter.Burst() != burst {
+		limiter.SetLimit(limit)
+		limiter.SetBurst(burst)
+	}
+
+	now := l.clock.Now()
+	reservation := limiter.ReserveN(now, 1)
+	delay := reservation.DelayFrom(now)
+	if delay == 0 {
+		// the reservation is canceled at the time it's made, so the token is fully restored
+		return func() { reservation.CancelAt(now) }, nil
+	}
+	reservation.CancelAt(now)
+	return nil, fmt.Errorf("quota %s is edited too frequently, the max rate is %v per second, please retry after %v",
+		quotaKey, qps, delay.Round(time.Millisecond))
+}
+
+func quotaRateLimiterKey(quota *v1alpha1.ElasticQuota) string {
+	return quota.Namespace + "/" + quota.Name
+}
+
+// Forget removes the limiter of the quota.
+func (l *quotaRateLimiter) Forget(quotaKey string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	delete(l.limiters, quotaKey)
+}
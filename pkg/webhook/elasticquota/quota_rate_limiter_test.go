@@ -0,0 +1,146 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticquota
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakeclock "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/koordinator-sh/koordinator/apis/thirdparty/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+)
+
+func TestQuotaRateLimiter(t *testing.T) {
+	fakeClock := fakeclock.NewFakeClock(time.Now())
+	limiter := newQuotaRateLimiter(fakeClock)
+	allow := func(quotaKey string, qps float64, burst int) error {
+		_, err := limiter.Allow(quotaKey, qps, burst)
+		return err
+	}
+
+	// disabled
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, allow("ns/quota1", 0, 1))
+	}
+
+	// burst 2 then rejected
+	assert.NoError(t, allow("ns/quota1", 1, 2))
+	assert.NoError(t, allow("ns/quota1", 1, 2))
+	err := allow("ns/quota1", 1, 2)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "please retry after")
+
+	// other quotas are not affected
+	assert.NoError(t, allow("ns/quota2", 1, 2))
+
+	// rejected edits don't consume tokens, so one more edit is allowed after 1s
+	fakeClock.Step(time.Second)
+	assert.NoError(t, allow("ns/quota1", 1, 2))
+	assert.Error(t, allow("ns/quota1", 1, 2))
+
+	// the refunded token is available again
+	fakeClock.Step(time.Second)
+	refund, err := limiter.Allow("ns/quota1", 1, 2)
+	assert.NoError(t, err)
+	assert.Error(t, allow("ns/quota1", 1, 2))
+	refund()
+	assert.NoError(t, allow("ns/quota1", 1, 2))
+
+	limiter.Forget("ns/quota1")
+	assert.NoError(t, allow("ns/quota1", 1, 2))
+}
+
+func TestQuotaMetaChecker_ValidateQuotaRateLimit(t *testing.T) {
+	oldQPS, oldBurst := quotaUpdateQPS, quotaUpdateBurst
+	defer func() {
+		quotaUpdateQPS, quotaUpdateBurst = oldQPS, oldBurst
+	}()
+	quotaUpdateQPS, quotaUpdateBurst = 0.01, 1
+
+	client := fake.NewClientBuilder().Build()
+	sche := client.Scheme()
+	sche.AddKnownTypes(schema.GroupVersion{
+		Group:   "scheduling.sigs.k8s.io",
+		Version: "v1alpha1",
+	}, &v1alpha1.ElasticQuota{}, &v1alpha1.ElasticQuotaList{})
+	decoder := admission.NewDecoder(sche)
+	plugin := NewPlugin(decoder, client)
+
+	quota := MakeQuota("rate-limit-quota").Namespace("kube-system").Max(MakeResourceList().CPU(120).Mem(1048576).Obj()).
+		Min(MakeResourceList().CPU(60).Mem(1048576).Obj()).Obj()
+	gvr := metav1.GroupVersionResource{
+		Group:    "scheduling.sigs.k8s.io",
+		Version:  "v1alpha1",
+		Resource: "elasticquotas",
+	}
+	err := plugin.ValidateQuota(context.TODO(), admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Resource:  gvr,
+			Operation: admissionv1.Create,
+		},
+	}, quota)
+	assert.NoError(t, err)
+
+	update := func(minCPU int64) error {
+		oldRaw, err := json.Marshal(quota)
+		assert.NoError(t, err)
+		newQuota := quota.DeepCopy()
+		newQuota.Spec.Min = MakeResourceList().CPU(minCPU).Mem(1048576).Obj()
+		err = plugin.ValidateQuota(context.TODO(), admission.Request{
+			AdmissionRequest: admissionv1.AdmissionRequest{
+				Resource:  gvr,
+				Operation: admissionv1.Update,
+				OldObject: runtime.RawExtension{Raw: oldRaw},
+			},
+		}, newQuota)
+		if err == nil {
+			quota = newQuota
+		}
+		return err
+	}
+
+	// the edit rejected by the validation doesn't use up the budget
+	err = update(200)
+	assert.Error(t, err)
+	assert.NotContains(t, err.Error(), "edited too frequently")
+	assert.NoError(t, update(50))
+	err = update(40)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "edited too frequently")
+
+	// no-op edits are not limited
+	oldRaw, err := json.Marshal(quota)
+	assert.NoError(t, err)
+	err = plugin.ValidateQuota(context.TODO(), admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Resource:  gvr,
+			Operation: admissionv1.Update,
+			OldObject: runtime.RawExtension{Raw: oldRaw},
+		},
+	}, quota.DeepCopy())
+	assert.NoError(t, err)
+}
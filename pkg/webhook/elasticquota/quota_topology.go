@@ -21,12 +21,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"sort"
 	"sync"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	"github.com/koordinator-sh/koordinator/apis/thirdparty/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
 
@@ -43,15 +46,18 @@ type quotaTopology struct {
 	namespaceToQuotaMap map[string]string
 	// quotaHierarchyInfo stores the quota's all children
 	quotaHierarchyInfo map[string]map[string]struct{}
+	// config is the limits checked for the quotas
+	config QuotaTopologyConfig
 
 	client client.Client
 }
 
-func NewQuotaTopology(client client.Client) *quotaTopology {
+func NewQuotaTopology(client client.Client, config QuotaTopologyConfig) *quotaTopology {
 	topology := &quotaTopology{
 		quotaInfoMap:        make(map[string]*QuotaInfo),
 		quotaHierarchyInfo:  make(map[string]map[string]struct{}),
 		namespaceToQuotaMap: make(map[string]string),
+		config:              config,
 		client:              client,
 	}
 	topology.quotaHierarchyInfo[extension.RootQuotaName] = make(map[string]struct{})
@@ -76,6 +82,9 @@ func (qt *quotaTopology) ValidAddQuota(quota *v1alpha1.ElasticQuota) error {
 			return fmt.Errorf("AddQuota quota %s's annotation namespace %s is already bound to quota %s", quota.Name, namespace, quotaName)
 		}
 	}
+	if err := qt.checkNamespaceBoundByName(quota.Name, annotationNamespaces); err != nil {
+		return fmt.Errorf("AddQuota %v", err)
+	}
 
 	if err := qt.validateQuotaSelfItem(quota); err != nil {
 		return err
@@ -87,6 +96,10 @@ func (qt *quotaTopology) ValidAddQuota(quota *v1alpha1.ElasticQuota) error {
 		return err
 	}
 
+	if err := qt.checkSubtreeLimits(quotaInfo.Name, quotaInfo.ParentName); err != nil {
+		return fmt.Errorf("AddQuota %v", err)
+	}
+
 	qt.quotaInfoMap[quotaInfo.Name] = quotaInfo
 	qt.quotaHierarchyInfo[quotaInfo.Name] = make(map[string]struct{})
 	qt.quotaHierarchyInfo[quotaInfo.ParentName][quotaInfo.Name] = struct{}{}
@@ -121,6 +134,9 @@ func (qt *quotaTopology) ValidUpdateQuota(oldQuota, newQuota *v1alpha1.ElasticQu
 				quotaName, namespace, oldQuotaName)
 		}
 	}
+	if err := qt.checkNamespaceBoundByName(quotaName, annotationNamespaces); err != nil {
+		return fmt.Errorf("UpdateQuota %v", err)
+	}
 
 	oldQuotaInfo, exist := qt.quotaInfoMap[quotaName]
 	if !exist {
@@ -133,6 +149,15 @@ func (qt *quotaTopology) ValidUpdateQuota(oldQuota, newQuota *v1alpha1.ElasticQu
 
 	oldAnnotationNamespaces := extension.GetAnnotationQuotaNamespaces(oldQuota)
 	newQuotaInfo := NewQuotaInfoFromQuota(newQuota)
+	if oldQuotaInfo.ParentName != newQuotaInfo.ParentName {
+		if err := qt.checkParentCycle(quotaName, newQuotaInfo.ParentName); err != nil {
+			return err
+		}
+		// the quota is moved together with its subtree.
+		if err := qt.checkSubtreeLimits(quotaName, newQuotaInfo.ParentName); err != nil {
+			return fmt.Errorf("UpdateQuota %v", err)
+		}
+	}
 	if err := qt.validateQuotaTopology(oldQuotaInfo, newQuotaInfo, oldAnnotationNamespaces); err != nil {
 		return err
 	}
@@ -166,24 +191,44 @@ func (qt *quotaTopology) ValidDeleteQuota(quota *v1alpha1.ElasticQuota) error {
 	}
 
 	// check has child quota.
+	var descendants []string
 	if childSet, exist := qt.quotaHierarchyInfo[quotaName]; exist {
 		if len(childSet) > 0 {
-			return fmt.Errorf("delete quota failed, quota%v has child quota", quotaName)
+			if !extension.IsAllowCascadingDelete(quota) {
+				if !controllerutil.ContainsFinalizer(quota, extension.FinalizerQuotaReparentChildren) {
+					return fmt.Errorf("delete quota failed, quota%v has child quota", quotaName)
+				}
+				// the children are moved to its parent by the quota reparent controller before the finalizer is removed,
+				// so the quota is kept in the topology until it's gone, see OnQuotaDelete.
+				return qt.checkQuotaWithoutPods(quota)
+			}
+			descendants = qt.getDescendantQuotaNames(quotaName)
 		}
 	} else {
 		return fmt.Errorf("BUG quotaMap and quotaTree information out of sync, losed :%v", quotaName)
 	}
 
-	podList := &corev1.PodList{}
-	opts := &client.ListOptions{
-		FieldSelector: fields.OneTermEqualSelector("label.quotaName", quota.Name),
+	if err := qt.checkQuotaWithoutPods(quota); err != nil {
+		return err
 	}
-	err := qt.client.List(context.TODO(), podList, opts, utilclient.DisableDeepCopy)
-	if err != nil {
-		return fmt.Errorf("failed list pods for quota %v, err: %v", quota.Name, err)
+	// the subtree is deleted only if every descendant has no pods.
+	for _, descendant := range descendants {
+		if err := qt.checkQuotaNameWithoutPods(descendant); err != nil {
+			return fmt.Errorf("cascading delete quota %v failed, err: %v", quotaName, err)
+		}
 	}
-	if len(podList.Items) > 0 {
-		return fmt.Errorf("delete quota failed, quota %v has child pods", quotaName)
+	if len(descendants) > 0 {
+		deleted := sets.NewString(descendants...)
+		for _, descendant := range descendants {
+			delete(qt.quotaHierarchyInfo, descendant)
+			delete(qt.quotaInfoMap, descendant)
+		}
+		for namespace, boundQuotaName := range qt.namespaceToQuotaMap {
+			if deleted.Has(boundQuotaName) {
+				delete(qt.namespaceToQuotaMap, namespace)
+			}
+		}
+		klog.Infof("cascading delete quota %v with its descendants %v", quotaName, descendants)
 	}
 
 	delete(qt.quotaHierarchyInfo[quotaInfo.ParentName], quotaName)
@@ -196,6 +241,67 @@ func (qt *quotaTopology) ValidDeleteQuota(quota *v1alpha1.ElasticQuota) error {
 	return nil
 }
 
+// checkQuotaWithoutPods checks the quota has no pods before it's deleted.
+func (qt *quotaTopology) checkQuotaWithoutPods(quota *v1alpha1.ElasticQuota) error {
+	return qt.checkQuotaNameWithoutPods(quota.Name)
+}
+
+func (qt *quotaTopology) checkQuotaNameWithoutPods(quotaName string) error {
+	podList := &corev1.PodList{}
+	opts := &client.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("label.quotaName", quotaName),
+	}
+	err := qt.client.List(context.TODO(), podList, opts, utilclient.DisableDeepCopy)
+	if err != nil {
+		if !deleteQuotaFailOpen {
+			return fmt.Errorf("failed list pods for quota %v, err: %v", quotaName, err)
+		}
+		klog.Warningf("failed list pods for quota %v, allow the deletion since fail-open, err: %v", quotaName, err)
+	} else if len(podList.Items) > 0 {
+		return fmt.Errorf("delete quota failed, quota %v has child pods", quotaName)
+	}
+	return nil
+}
+
+// getSubtreeHeight returns the levels of the descendants under the quota, 0 if it has no children.
+func (qt *quotaTopology) getSubtreeHeight(quotaName string) int {
+	height := 0
+	level := []string{quotaName}
+	for len(level) > 0 && height <= len(qt.quotaInfoMap) {
+		var next []string
+		for _, name := range level {
+			for child := range qt.quotaHierarchyInfo[name] {
+				next = append(next, child)
+			}
+		}
+		if len(next) == 0 {
+			break
+		}
+		height++
+		level = next
+	}
+	return height
+}
+
+// getDescendantQuotaNames returns all the descendants of the quota by walking the quotaHierarchyInfo,
+// the parents are listed before their children.
+func (qt *quotaTopology) getDescendantQuotaNames(quotaName string) []string {
+	var descendants []string
+	queue := []string{quotaName}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		children := make([]string, 0, len(qt.quotaHierarchyInfo[current]))
+		for child := range qt.quotaHierarchyInfo[current] {
+			children = append(children, child)
+		}
+		sort.Strings(children)
+		descendants = append(descendants, children...)
+		queue = append(queue, children...)
+	}
+	return descendants
+}
+
 // fillQuotaDefaultInformation fills quota with default information if not be configured
 func (qt *quotaTopology) fillQuotaDefaultInformation(quota *v1alpha1.ElasticQuota) error {
 	if quota.Name == extension.RootQuotaName {
@@ -213,8 +319,13 @@ func (qt *quotaTopology) fillQuotaDefaultInformation(quota *v1alpha1.ElasticQuot
 	}
 
 	if parentName, exist := quota.Labels[extension.LabelQuotaParent]; !exist || len(parentName) == 0 {
-		quota.Labels[extension.LabelQuotaParent] = extension.RootQuotaName
-		klog.V(5).Infof("fill quota %v parent as root", quota.Name)
+		parentName = defaultParentQuotaName
+		if parentName == "" || parentName == quota.Name || qt.quotaInfoMap[parentName] == nil {
+			// the quota falls back to the root quota until the default parent is created
+			parentName = extension.RootQuotaName
+		}
+		quota.Labels[extension.LabelQuotaParent] = parentName
+		klog.V(5).Infof("fill quota %v parent as %v", quota.Name, parentName)
 	}
 
 	// add tree id, if the parent has tree id
@@ -286,6 +397,41 @@ func (qt *quotaTopology) getQuotaTopologyInfo() *QuotaTopologySummary {
 	return result
 }
 
+// getQuotaSubtreeTopologyInfo returns the quotas and hierarchy of the subtree rooted at the given quota,
+// returns nil if the quota doesn't exist.
+func (qt *quotaTopology) getQuotaSubtreeTopologyInfo(rootName string) *QuotaTopologySummary {
+	qt.lock.Lock()
+	defer qt.lock.Unlock()
+
+	_, hasInfo := qt.quotaInfoMap[rootName]
+	_, hasHierarchy := qt.quotaHierarchyInfo[rootName]
+	if !hasInfo && !hasHierarchy {
+		return nil
+	}
+
+	result := NewQuotaTopologySummary()
+	toVisit := []string{rootName}
+	for len(toVisit) > 0 {
+		name := toVisit[0]
+		toVisit = toVisit[1:]
+		if _, visited := result.QuotaHierarchyInfo[name]; visited {
+			continue
+		}
+
+		if info, ok := qt.quotaInfoMap[name]; ok {
+			result.QuotaInfoMap[name] = info.GetQuotaSummary()
+		}
+		children := qt.quotaHierarchyInfo[name]
+		childQuotas := make([]string, 0, len(children))
+		for childName := range children {
+			childQuotas = append(childQuotas, childName)
+			toVisit = append(toVisit, childName)
+		}
+		result.QuotaHierarchyInfo[name] = childQuotas
+	}
+	return result
+}
+
 func (qt *quotaTopology) getQuotaInfo(name, namespace string) *QuotaInfo {
 	qt.lock.Lock()
 	defer qt.lock.Unlock()
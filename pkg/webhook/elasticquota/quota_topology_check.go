@@ -19,10 +19,13 @@ package elasticquota
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/sets"
 	quotav1 "k8s.io/apiserver/pkg/quota/v1"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/cache"
@@ -58,6 +61,14 @@ func (qt *quotaTopology) validateQuotaSelfItem(quota *v1alpha1.ElasticQuota) err
 		}
 	}
 
+	if _, err := extension.GetSharingPolicies(quota); err != nil {
+		return fmt.Errorf("%v quota.Annotation[%v]'s value is invalid: %w", quota.Name, extension.AnnotationSharingPolicy, err)
+	}
+
+	if _, err := extension.GetOvercommitRatios(quota); err != nil {
+		return fmt.Errorf("%v quota.Annotation[%v]'s value is invalid: %w", quota.Name, extension.AnnotationOvercommitRatio, err)
+	}
+
 	// 1. check if all key in min are included in max
 	// 2. check if all quantities in min <= that in max
 	for key, val := range quota.Spec.Min {
@@ -102,6 +113,10 @@ func (qt *quotaTopology) validateQuotaTopology(oldQuotaInfo, newQuotaInfo *Quota
 		return err
 	}
 
+	if err := qt.checkMinQuotaWithTreeTotalResource(oldQuotaInfo, newQuotaInfo); err != nil {
+		return err
+	}
+
 	// if the quotaInfo's parent is root and its IsParent is false, the following checks will be true, just return nil.
 	if newQuotaInfo.ParentName == extension.RootQuotaName && !newQuotaInfo.IsParent {
 		return nil
@@ -119,6 +134,10 @@ func (qt *quotaTopology) validateQuotaTopology(oldQuotaInfo, newQuotaInfo *Quota
 		return err
 	}
 
+	if err := qt.checkOvercommitRatios(newQuotaInfo); err != nil {
+		return err
+	}
+
 	if utilfeature.DefaultFeatureGate.Enabled(features.ElasticQuotaGuaranteeUsage) {
 		if err := qt.checkGuaranteedForMin(newQuotaInfo); err != nil {
 			return fmt.Errorf("%v %v", err.Error(), newQuotaInfo.Name)
@@ -183,6 +202,125 @@ func (qt *quotaTopology) checkIsParentChange(oldQuotaInfo, quotaInfo *QuotaInfo,
 	return nil
 }
 
+// checkNamespaceBoundByName ensures a namespace is bound to exactly one quota across the whole tree.
+// Besides the annotation namespaces, a quota binds the namespace which has the same name as the quota,
+// so the binding is checked against the quotas at all levels, not only the annotation namespaces.
+func (qt *quotaTopology) checkNamespaceBoundByName(quotaName string, annotationNamespaces []string) error {
+	for _, namespace := range annotationNamespaces {
+		if namespace == quotaName {
+			continue
+		}
+		if _, exist := qt.quotaInfoMap[namespace]; exist {
+			return fmt.Errorf("quota %s's annotation namespace %s is already bound to quota %s by name",
+				quotaName, namespace, namespace)
+		}
+	}
+	if boundQuotaName, exist := qt.namespaceToQuotaMap[quotaName]; exist && boundQuotaName != quotaName {
+		return fmt.Errorf("quota %s binds namespace %s by name, but the namespace is already bound to quota %s",
+			quotaName, quotaName, boundQuotaName)
+	}
+	return nil
+}
+
+// checkParentCycle walks up from the new parent to the root, and rejects the parent change if the walk reaches
+// the quota itself, since the cycle breaks the runtime calculation of the whole tree.
+func (qt *quotaTopology) checkParentCycle(quotaName, parentName string) error {
+	path := []string{quotaName}
+	visited := sets.New[string]()
+	for name := parentName; name != "" && name != extension.RootQuotaName; {
+		path = append(path, name)
+		if name == quotaName {
+			return fmt.Errorf("%v has parentName %v which forms a cycle: %v", quotaName, parentName, strings.Join(path, " -> "))
+		}
+		// the existing tree is expected to be acyclic, the walk stops anyway.
+		if visited.Has(name) {
+			return nil
+		}
+		visited.Insert(name)
+		info, exist := qt.quotaInfoMap[name]
+		if !exist {
+			return nil
+		}
+		name = info.ParentName
+	}
+	return nil
+}
+
+// checkSubtreeLimits checks the quota placed under the parent together with its subtree doesn't exceed
+// MaxQuotaTreeDepth, and MaxQuotaDescendants under the top-level quota, i.e. the quota right under the root quota.
+func (qt *quotaTopology) checkSubtreeLimits(quotaName, parentName string) error {
+	maxQuotaDescendants, maxQuotaTreeDepth := qt.config.MaxQuotaDescendants, qt.config.MaxQuotaTreeDepth
+	if maxQuotaDescendants <= 0 && maxQuotaTreeDepth <= 0 {
+		return nil
+	}
+
+	// walk up to the top-level quota, the parents have been checked to be acyclic.
+	topName, parentDepth := quotaName, 0
+	for name := parentName; name != "" && name != extension.RootQuotaName && parentDepth <= len(qt.quotaInfoMap); {
+		topName = name
+		parentDepth++
+		info, exist := qt.quotaInfoMap[name]
+		if !exist {
+			break
+		}
+		name = info.ParentName
+	}
+
+	if maxQuotaTreeDepth > 0 {
+		if depth := parentDepth + 1 + qt.getSubtreeHeight(quotaName); depth > maxQuotaTreeDepth {
+			return fmt.Errorf("quota %v under parent %v exceeds the max tree depth %v, depth: %v",
+				quotaName, parentName, maxQuotaTreeDepth, depth)
+		}
+	}
+
+	// the subtree of the top-level quota itself doesn't grow.
+	if maxQuotaDescendants > 0 && topName != quotaName {
+		descendants := sets.New[string](qt.getDescendantQuotaNames(topName)...)
+		descendants.Insert(quotaName)
+		descendants.Insert(qt.getDescendantQuotaNames(quotaName)...)
+		if descendants.Len() > maxQuotaDescendants {
+			return fmt.Errorf("quota %v under top-level quota %v exceeds the max descendants %v, descendants: %v",
+				quotaName, topName, maxQuotaDescendants, descendants.Len())
+		}
+	}
+	return nil
+}
+
+// checkOvercommitRatios checks the overcommit ratio of each resource dimension of the quota doesn't exceed the
+// ratio of its parent, and isn't exceeded by the ratios of its children.
+func (qt *quotaTopology) checkOvercommitRatios(quotaInfo *QuotaInfo) error {
+	if quotaInfo.ParentName != extension.RootQuotaName {
+		if parentInfo := qt.quotaInfoMap[quotaInfo.ParentName]; parentInfo != nil {
+			if resourceName, exceeded := isOvercommitRatioExceeded(quotaInfo.OvercommitRatios, parentInfo.OvercommitRatios); exceeded {
+				return fmt.Errorf("%v's overcommit ratio of %v exceeds its parent %v's", quotaInfo.Name, resourceName, parentInfo.Name)
+			}
+		}
+	}
+
+	for name := range qt.quotaHierarchyInfo[quotaInfo.Name] {
+		if childInfo := qt.quotaInfoMap[name]; childInfo != nil {
+			if resourceName, exceeded := isOvercommitRatioExceeded(childInfo.OvercommitRatios, quotaInfo.OvercommitRatios); exceeded {
+				return fmt.Errorf("%v's overcommit ratio of %v is exceeded by its child %v's", quotaInfo.Name, resourceName, childInfo.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// isOvercommitRatioExceeded returns the resource whose ratio of the child exceeds the parent's, the ratios not set are 1.0.
+func isOvercommitRatioExceeded(childRatios, parentRatios map[v1.ResourceName]float64) (v1.ResourceName, bool) {
+	for resourceName, ratio := range childRatios {
+		parentRatio, ok := parentRatios[resourceName]
+		if !ok {
+			parentRatio = 1
+		}
+		if ratio > parentRatio {
+			return resourceName, true
+		}
+	}
+	return "", false
+}
+
 // checkParentQuotaInfo check parent exist
 func (qt *quotaTopology) checkParentQuotaInfo(quotaName, parentName string) error {
 	if parentName != extension.RootQuotaName {
@@ -302,6 +440,69 @@ func (qt *quotaTopology) checkMinQuotaValidate(newQuotaInfo *QuotaInfo) error {
 	return nil
 }
 
+// checkMinQuotaWithTreeTotalResource checks the sum of the minquota of the quotas under the root in the same
+// quota tree doesn't exceed the total resource of the tree in any dimension, otherwise the min can't be guaranteed
+// when refreshing the runtime. The quotas under other parents are capped by checkMinQuotaValidate.
+func (qt *quotaTopology) checkMinQuotaWithTreeTotalResource(oldQuotaInfo, newQuotaInfo *QuotaInfo) error {
+	if newQuotaInfo.AllowForceUpdate || newQuotaInfo.ParentName != extension.RootQuotaName {
+		return nil
+	}
+	// skip the quota whose min, parent and total resource don't change, so the quotas created before won't be stuck.
+	if oldQuotaInfo != nil && oldQuotaInfo.ParentName == newQuotaInfo.ParentName &&
+		quotav1.Equals(oldQuotaInfo.CalculateInfo.Min, newQuotaInfo.CalculateInfo.Min) &&
+		quotav1.Equals(oldQuotaInfo.CalculateInfo.TotalResource, newQuotaInfo.CalculateInfo.TotalResource) {
+		return nil
+	}
+
+	totalResource := qt.getTreeTotalResource(newQuotaInfo)
+	if len(totalResource) == 0 {
+		return nil
+	}
+
+	minSum := newQuotaInfo.CalculateInfo.Min.DeepCopy()
+	for childName := range qt.quotaHierarchyInfo[extension.RootQuotaName] {
+		childQuotaInfo, exist := qt.quotaInfoMap[childName]
+		if !exist || childName == newQuotaInfo.Name || childQuotaInfo.TreeID != newQuotaInfo.TreeID {
+			continue
+		}
+		minSum = quotav1.Add(minSum, childQuotaInfo.CalculateInfo.Min)
+	}
+
+	resourceNames := quotav1.ResourceNames(minSum)
+	sort.Slice(resourceNames, func(i, j int) bool {
+		return resourceNames[i] < resourceNames[j]
+	})
+	for _, resourceName := range resourceNames {
+		totalQuantity, exist := totalResource[resourceName]
+		if !exist {
+			continue
+		}
+		if minQuantity := minSum[resourceName]; minQuantity.Cmp(totalQuantity) > 0 {
+			return fmt.Errorf("checkMinQuotaWithTreeTotalResource all MinQuota > TotalResource of tree %v on %v, "+
+				"MinQuota: %v, TotalResource: %v",
+				newQuotaInfo.TreeID, resourceName, minQuantity.String(), totalQuantity.String())
+		}
+	}
+	return nil
+}
+
+// getTreeTotalResource returns the total resource recorded on the root quota of the quota's tree.
+func (qt *quotaTopology) getTreeTotalResource(quotaInfo *QuotaInfo) v1.ResourceList {
+	if quotaInfo.TreeID == "" {
+		return nil
+	}
+	if quotaInfo.IsTreeRoot {
+		return quotaInfo.CalculateInfo.TotalResource
+	}
+	for childName := range qt.quotaHierarchyInfo[extension.RootQuotaName] {
+		if childQuotaInfo, exist := qt.quotaInfoMap[childName]; exist &&
+			childQuotaInfo.IsTreeRoot && childQuotaInfo.TreeID == quotaInfo.TreeID {
+			return childQuotaInfo.CalculateInfo.TotalResource
+		}
+	}
+	return nil
+}
+
 func (qt *quotaTopology) getChildMinQuotaSumExceptSpecificChild(parentName, skipQuota string) (allChildQuotaSum v1.ResourceList, err error) {
 	allChildQuotaSum = v1.ResourceList{}
 	if parentName == extension.RootQuotaName {
@@ -371,6 +572,7 @@ func quotaFieldsCopy(q *v1alpha1.ElasticQuota) v1alpha1.ElasticQuota {
 			},
 			Annotations: map[string]string{
 				extension.AnnotationQuotaNamespaces: q.Annotations[extension.AnnotationQuotaNamespaces],
+				extension.AnnotationOvercommitRatio: q.Annotations[extension.AnnotationOvercommitRatio],
 			},
 		},
 		Spec: *q.Spec.DeepCopy(),
@@ -20,6 +20,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -27,6 +28,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 
 	"github.com/koordinator-sh/koordinator/apis/thirdparty/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
 
@@ -93,6 +95,30 @@ func TestQuotaTopology_basicItemCheck(t *testing.T) {
 			quota: MakeQuota("temp").sharedWeight(MakeResourceList().CPU(-1).Mem(1048576).Obj()).Max(MakeResourceList().CPU(0).Mem(1048576).Obj()).Obj(),
 			err:   fmt.Errorf("%v quota.Annotation[%v]'s value < 0, in dimension :%v", "temp", extension.AnnotationSharedWeight, "[cpu]"),
 		},
+		{
+			name: "annotation sharing policy",
+			quota: MakeQuota("temp").Annotations(map[string]string{extension.AnnotationSharingPolicy: `{"cpu":"weighted","memory":"equal"}`}).
+				Max(MakeResourceList().CPU(10).Mem(1048576).Obj()).Obj(),
+		},
+		{
+			name: "annotation unknown sharing policy",
+			quota: MakeQuota("temp").Annotations(map[string]string{extension.AnnotationSharingPolicy: `{"cpu":"drf"}`}).
+				Max(MakeResourceList().CPU(10).Mem(1048576).Obj()).Obj(),
+			err: fmt.Errorf("temp quota.Annotation[%v]'s value is invalid: %w", extension.AnnotationSharingPolicy,
+				fmt.Errorf("unknown sharing policy %q of resource %v", "drf", "cpu")),
+		},
+		{
+			name: "annotation overcommit ratio",
+			quota: MakeQuota("temp").Annotations(map[string]string{extension.AnnotationOvercommitRatio: `{"cpu":1.5,"memory":1.0}`}).
+				Max(MakeResourceList().CPU(10).Mem(1048576).Obj()).Obj(),
+		},
+		{
+			name: "annotation overcommit ratio < 1",
+			quota: MakeQuota("temp").Annotations(map[string]string{extension.AnnotationOvercommitRatio: `{"cpu":0.5}`}).
+				Max(MakeResourceList().CPU(10).Mem(1048576).Obj()).Obj(),
+			err: fmt.Errorf("temp quota.Annotation[%v]'s value is invalid: %w", extension.AnnotationOvercommitRatio,
+				fmt.Errorf("overcommit ratio %v of resource %v is less than 1", 0.5, "cpu")),
+		},
 		{
 			name: "annotation check max >= used",
 			quota: MakeQuota("temp").Annotations(map[string]string{extension.AnnotationMaxStrictCheckResourceKeys: `["cpu","memory"]`}).
@@ -231,6 +257,41 @@ func TestQuotaTopology_fillQuotaDefaultInformation(t *testing.T) {
 		})
 	}
 }
+func TestQuotaTopology_fillQuotaDefaultInformationWithDefaultParent(t *testing.T) {
+	oldDefaultParent := defaultParentQuotaName
+	defer func() {
+		defaultParentQuotaName = oldDefaultParent
+	}()
+	defaultParentQuotaName = "catch-all"
+
+	qt := newFakeQuotaTopology()
+	// the default parent doesn't exist yet, the quota falls back to the root quota
+	quota := MakeQuota("team-a").Max(MakeResourceList().CPU(120).Mem(1048576).Obj()).Obj()
+	err := qt.fillQuotaDefaultInformation(quota)
+	assert.Nil(t, err)
+	assert.Equal(t, extension.RootQuotaName, quota.Labels[extension.LabelQuotaParent])
+
+	// the default parent itself is filled as a child of the root
+	catchAll := MakeQuota("catch-all").Max(MakeResourceList().CPU(120).Mem(1048576).Obj()).IsParent(true).TreeID("tree-1").Obj()
+	err = qt.fillQuotaDefaultInformation(catchAll)
+	assert.Nil(t, err)
+	assert.Equal(t, extension.RootQuotaName, catchAll.Labels[extension.LabelQuotaParent])
+	qt.OnQuotaAdd(catchAll)
+
+	// quotas lacking the parent label are filled with the default parent
+	quota = MakeQuota("team-a").Max(MakeResourceList().CPU(120).Mem(1048576).Obj()).Obj()
+	err = qt.fillQuotaDefaultInformation(quota)
+	assert.Nil(t, err)
+	assert.Equal(t, "catch-all", quota.Labels[extension.LabelQuotaParent])
+	assert.Equal(t, "tree-1", quota.Labels[extension.LabelQuotaTreeID])
+
+	// quotas with the parent label are not changed
+	quota = MakeQuota("team-b").ParentName(extension.RootQuotaName).Max(MakeResourceList().CPU(120).Mem(1048576).Obj()).Obj()
+	err = qt.fillQuotaDefaultInformation(quota)
+	assert.Nil(t, err)
+	assert.Equal(t, extension.RootQuotaName, quota.Labels[extension.LabelQuotaParent])
+}
+
 func TestQuotaTopology_checkSubAndParentGroupMaxQuotaKeySame(t *testing.T) {
 	tests := []struct {
 		name                     string
@@ -698,6 +759,140 @@ func TestQuotaTopology_ValidUpdateQuota(t *testing.T) {
 	assert.Equal(t, fmt.Sprint("sub-1 tree id changed [] vs [tree-1]"), err.Error())
 }
 
+func TestQuotaTopology_ValidUpdateQuotaParentCycle(t *testing.T) {
+	qt := newFakeQuotaTopology()
+	quotaA := MakeQuota("a").Max(MakeResourceList().CPU(120).Mem(1048576).Obj()).
+		Min(MakeResourceList().CPU(30).Mem(30720).Obj()).IsParent(true).Obj()
+	quotaB := MakeQuota("b").ParentName("a").Max(MakeResourceList().CPU(120).Mem(1048576).Obj()).
+		Min(MakeResourceList().CPU(20).Mem(20480).Obj()).IsParent(true).Obj()
+	quotaC := MakeQuota("c").ParentName("b").Max(MakeResourceList().CPU(120).Mem(1048576).Obj()).
+		Min(MakeResourceList().CPU(10).Mem(10240).Obj()).IsParent(true).Obj()
+	for _, quota := range []*v1alpha1.ElasticQuota{quotaA, quotaB, quotaC} {
+		assert.NoError(t, qt.fillQuotaDefaultInformation(quota))
+		assert.NoError(t, qt.ValidAddQuota(quota))
+	}
+
+	tests := []struct {
+		name       string
+		quota      *v1alpha1.ElasticQuota
+		parentName string
+		wantErr    string
+	}{
+		{
+			name:       "3-node cycle",
+			quota:      quotaA,
+			parentName: "c",
+			wantErr:    "a has parentName c which forms a cycle: a -> c -> b -> a",
+		},
+		{
+			name:       "2-node cycle",
+			quota:      quotaB,
+			parentName: "c",
+			wantErr:    "b has parentName c which forms a cycle: b -> c -> b",
+		},
+		{
+			name:       "self-parent",
+			quota:      quotaB,
+			parentName: "b",
+			wantErr:    "b has parentName b which forms a cycle: b -> b",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newQuota := tt.quota.DeepCopy()
+			newQuota.Labels[extension.LabelQuotaParent] = tt.parentName
+			err := qt.ValidUpdateQuota(tt.quota, newQuota)
+			assert.EqualError(t, err, tt.wantErr)
+			// the topology is kept as it is
+			assert.Equal(t, tt.quota.Labels[extension.LabelQuotaParent], qt.quotaInfoMap[tt.quota.Name].ParentName)
+			assert.Equal(t, 1, len(qt.quotaHierarchyInfo["a"]))
+			assert.Equal(t, 1, len(qt.quotaHierarchyInfo["b"]))
+		})
+	}
+
+	// moving the leaf to another branch is allowed
+	newQuotaC := quotaC.DeepCopy()
+	newQuotaC.Labels[extension.LabelQuotaParent] = "a"
+	assert.NoError(t, qt.ValidUpdateQuota(quotaC, newQuotaC))
+	assert.Equal(t, 2, len(qt.quotaHierarchyInfo["a"]))
+	assert.Equal(t, 0, len(qt.quotaHierarchyInfo["b"]))
+}
+
+func TestQuotaTopology_OvercommitRatios(t *testing.T) {
+	qt := newFakeQuotaTopology()
+	quotaA := MakeQuota("a").Annotations(map[string]string{extension.AnnotationOvercommitRatio: `{"cpu":1.5}`}).
+		Max(MakeResourceList().CPU(120).Mem(1048576).Obj()).Min(MakeResourceList().CPU(30).Mem(30720).Obj()).IsParent(true).Obj()
+	quotaB := MakeQuota("b").Annotations(map[string]string{extension.AnnotationOvercommitRatio: `{"cpu":1.2}`}).ParentName("a").
+		Max(MakeResourceList().CPU(120).Mem(1048576).Obj()).Min(MakeResourceList().CPU(10).Mem(10240).Obj()).Obj()
+	for _, quota := range []*v1alpha1.ElasticQuota{quotaA, quotaB} {
+		assert.NoError(t, qt.fillQuotaDefaultInformation(quota))
+		assert.NoError(t, qt.ValidAddQuota(quota))
+	}
+
+	// the child can't overcommit more than its parent, the dimensions not set on the parent are 1.0.
+	quotaC := MakeQuota("c").Annotations(map[string]string{extension.AnnotationOvercommitRatio: `{"cpu":2}`}).ParentName("a").
+		Max(MakeResourceList().CPU(120).Mem(1048576).Obj()).Min(MakeResourceList().CPU(10).Mem(10240).Obj()).Obj()
+	assert.NoError(t, qt.fillQuotaDefaultInformation(quotaC))
+	assert.EqualError(t, qt.ValidAddQuota(quotaC), "c's overcommit ratio of cpu exceeds its parent a's")
+	quotaD := MakeQuota("d").Annotations(map[string]string{extension.AnnotationOvercommitRatio: `{"memory":1.2}`}).ParentName("a").
+		Max(MakeResourceList().CPU(120).Mem(1048576).Obj()).Min(MakeResourceList().CPU(10).Mem(10240).Obj()).Obj()
+	assert.NoError(t, qt.fillQuotaDefaultInformation(quotaD))
+	assert.EqualError(t, qt.ValidAddQuota(quotaD), "d's overcommit ratio of memory exceeds its parent a's")
+
+	// the parent can't lower its ratio below its children's.
+	newQuotaA := quotaA.DeepCopy()
+	newQuotaA.Annotations[extension.AnnotationOvercommitRatio] = `{"cpu":1.1}`
+	assert.EqualError(t, qt.ValidUpdateQuota(quotaA, newQuotaA), "a's overcommit ratio of cpu is exceeded by its child b's")
+	newQuotaA.Annotations[extension.AnnotationOvercommitRatio] = `{"cpu":1.2}`
+	assert.NoError(t, qt.ValidUpdateQuota(quotaA, newQuotaA))
+}
+
+func TestQuotaTopology_SubtreeLimits(t *testing.T) {
+	qt := newFakeQuotaTopology()
+	qt.config = QuotaTopologyConfig{MaxQuotaDescendants: 3, MaxQuotaTreeDepth: 3}
+	makeQuota := func(name, parentName string, min int64) *v1alpha1.ElasticQuota {
+		quota := MakeQuota(name).ParentName(parentName).Max(MakeResourceList().CPU(120).Mem(1048576).Obj()).
+			Min(MakeResourceList().CPU(min).Mem(min * 1024).Obj()).IsParent(true).Obj()
+		assert.NoError(t, qt.fillQuotaDefaultInformation(quota))
+		return quota
+	}
+	for _, quota := range []*v1alpha1.ElasticQuota{
+		makeQuota("a", extension.RootQuotaName, 40),
+		makeQuota("b", "a", 30),
+		makeQuota("c", "b", 20),
+		makeQuota("x", extension.RootQuotaName, 40),
+		makeQuota("y", "x", 10),
+	} {
+		assert.NoError(t, qt.ValidAddQuota(quota))
+	}
+
+	// depth: a(1) -> b(2) -> c(3) -> d(4)
+	err := qt.ValidAddQuota(makeQuota("d", "c", 10))
+	assert.EqualError(t, err, "AddQuota quota d under parent c exceeds the max tree depth 3, depth: 4")
+
+	// descendants of a: b, c, e
+	assert.NoError(t, qt.ValidAddQuota(makeQuota("e", "a", 5)))
+	err = qt.ValidAddQuota(makeQuota("f", "b", 5))
+	assert.EqualError(t, err, "AddQuota quota f under top-level quota a exceeds the max descendants 3, descendants: 4")
+
+	// the top-level quota isn't limited by the descendants of other trees
+	assert.NoError(t, qt.ValidAddQuota(makeQuota("g", extension.RootQuotaName, 10)))
+
+	// x is moved under a together with y: a(1) -> x(2) -> y(3), but descendants of a: b, c, e, x, y
+	oldX := qt.quotaInfoMap["x"]
+	x := makeQuota("x", extension.RootQuotaName, 40)
+	newX := x.DeepCopy()
+	newX.Labels[extension.LabelQuotaParent] = "a"
+	err = qt.ValidUpdateQuota(x, newX)
+	assert.EqualError(t, err, "UpdateQuota quota x under top-level quota a exceeds the max descendants 3, descendants: 5")
+	// x is moved under b: a(1) -> b(2) -> x(3) -> y(4)
+	newX.Labels[extension.LabelQuotaParent] = "b"
+	qt.config.MaxQuotaDescendants = 0
+	err = qt.ValidUpdateQuota(x, newX)
+	assert.EqualError(t, err, "UpdateQuota quota x under parent b exceeds the max tree depth 3, depth: 4")
+	assert.Equal(t, oldX, qt.quotaInfoMap["x"])
+}
+
 func TestQuotaTopology_ListQuotaPods(t *testing.T) {
 	testCase := []struct {
 		name string
@@ -777,6 +972,165 @@ func TestQuotaTopology_AnnotationNamespaces(t *testing.T) {
 	qt.lock.Unlock()
 }
 
+func TestQuotaTopology_NamespaceBindingsAcrossLevels(t *testing.T) {
+	qt := newFakeQuotaTopology()
+	max := MakeResourceList().CPU(120).Mem(1048576).Obj()
+	min := MakeResourceList().CPU(60).Mem(1024).Obj()
+	childMin := MakeResourceList().CPU(10).Mem(100).Obj()
+
+	parent := MakeQuota("parent").Max(max).Min(min).IsParent(true).
+		Annotations(map[string]string{extension.AnnotationQuotaNamespaces: "[\"ns1\"]"}).Obj()
+	assert.Nil(t, qt.ValidAddQuota(parent))
+
+	// the child can't bind the namespace bound to its parent
+	child := MakeQuota("child").ParentName("parent").Max(max).Min(childMin).
+		Annotations(map[string]string{extension.AnnotationQuotaNamespaces: "[\"ns1\"]"}).Obj()
+	err := qt.ValidAddQuota(child)
+	assert.Equal(t, fmt.Errorf("AddQuota quota child's annotation namespace ns1 is already bound to quota parent"), err)
+
+	// the child named after the namespace bound to its parent is rejected
+	ns1Child := MakeQuota("ns1").ParentName("parent").Max(max).Min(childMin).Obj()
+	err = qt.ValidAddQuota(ns1Child)
+	assert.Equal(t, fmt.Errorf("AddQuota quota ns1 binds namespace ns1 by name, but the namespace is already bound to quota parent"), err)
+
+	// the child can't bind the namespace which is bound to another quota by name
+	ns2Child := MakeQuota("ns2").ParentName("parent").Max(max).Min(childMin).Obj()
+	assert.Nil(t, qt.ValidAddQuota(ns2Child))
+	child.Annotations[extension.AnnotationQuotaNamespaces] = "[\"ns2\"]"
+	err = qt.ValidAddQuota(child)
+	assert.Equal(t, fmt.Errorf("AddQuota quota child's annotation namespace ns2 is already bound to quota ns2 by name"), err)
+
+	// the quota can bind the namespace of its own name by annotation
+	child.Annotations[extension.AnnotationQuotaNamespaces] = "[\"child\",\"ns3\"]"
+	assert.Nil(t, qt.ValidAddQuota(child))
+
+	// update the parent to bind the namespace bound to its child by name
+	newParent := parent.DeepCopy()
+	newParent.Annotations[extension.AnnotationQuotaNamespaces] = "[\"ns1\",\"ns2\"]"
+	err = qt.ValidUpdateQuota(parent, newParent)
+	assert.Equal(t, fmt.Errorf("UpdateQuota quota parent's annotation namespace ns2 is already bound to quota ns2 by name"), err)
+
+	// update the parent to bind the namespace bound to its child by annotation
+	newParent.Annotations[extension.AnnotationQuotaNamespaces] = "[\"ns1\",\"ns3\"]"
+	err = qt.ValidUpdateQuota(parent, newParent)
+	assert.Equal(t, fmt.Errorf("UpdadteQuota, quota parent update namespaces, but namespace ns3 is already bound to quota child"), err)
+
+	newParent.Annotations[extension.AnnotationQuotaNamespaces] = "[\"ns1\",\"ns4\"]"
+	assert.Nil(t, qt.ValidUpdateQuota(parent, newParent))
+	qt.lock.Lock()
+	assert.Equal(t, "parent", qt.namespaceToQuotaMap["ns4"])
+	assert.Equal(t, "child", qt.namespaceToQuotaMap["ns3"])
+	qt.lock.Unlock()
+}
+
+func TestQuotaTopology_ValidDeleteQuota_ListPodsFailed(t *testing.T) {
+	tests := []struct {
+		name        string
+		failOpen    bool
+		expectError bool
+	}{
+		{
+			name:        "fail-closed rejects the deletion",
+			failOpen:    false,
+			expectError: true,
+		},
+		{
+			name:        "fail-open allows the deletion",
+			failOpen:    true,
+			expectError: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldFailOpen := deleteQuotaFailOpen
+			defer func() {
+				deleteQuotaFailOpen = oldFailOpen
+			}()
+			deleteQuotaFailOpen = tt.failOpen
+
+			qt := newFakeQuotaTopology()
+			qt.client = fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+				List: func(ctx context.Context, client client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+					return fmt.Errorf("transient error")
+				},
+			}).Build()
+
+			quota := MakeQuota("temp").Max(MakeResourceList().CPU(120).Mem(1048576).Obj()).
+				Min(MakeResourceList().CPU(64).Mem(51200).Obj()).IsParent(false).Obj()
+			qt.fillQuotaDefaultInformation(quota)
+			assert.NoError(t, qt.ValidAddQuota(quota))
+
+			err := qt.ValidDeleteQuota(quota)
+			if tt.expectError {
+				assert.Error(t, err)
+				assert.Contains(t, qt.quotaInfoMap, "temp")
+			} else {
+				assert.NoError(t, err)
+				assert.NotContains(t, qt.quotaInfoMap, "temp")
+			}
+		})
+	}
+}
+
+func TestQuotaTopology_ValidDeleteQuotaWithReparentFinalizer(t *testing.T) {
+	tests := []struct {
+		name        string
+		finalizers  []string
+		expectError bool
+	}{
+		{
+			name:        "the deletion of the parent with children is rejected",
+			expectError: true,
+		},
+		{
+			name:        "the deletion of the parent with children is allowed with the reparent finalizer",
+			finalizers:  []string{extension.FinalizerQuotaReparentChildren},
+			expectError: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qt := newFakeQuotaTopology()
+			fakeClient := fake.NewClientBuilder().WithIndex(&v1.Pod{}, "label.quotaName", func(object client.Object) []string {
+				return []string{object.(*v1.Pod).Labels[extension.LabelQuotaName]}
+			}).Build()
+			v1alpha1.AddToScheme(fakeClient.Scheme())
+			qt.client = fakeClient
+
+			parent := MakeQuota("temp").Max(MakeResourceList().CPU(120).Mem(1048576).Obj()).
+				Min(MakeResourceList().CPU(64).Mem(51200).Obj()).IsParent(true).Obj()
+			sub1 := MakeQuota("sub-1").ParentName("temp").Max(MakeResourceList().CPU(120).Mem(1048576).Obj()).
+				Min(MakeResourceList().CPU(30).Mem(12800).Obj()).IsParent(false).Obj()
+			sub2 := MakeQuota("sub-2").ParentName("temp").Max(MakeResourceList().CPU(120).Mem(1048576).Obj()).
+				Min(MakeResourceList().CPU(30).Mem(12800).Obj()).IsParent(false).Obj()
+			parent.Finalizers = tt.finalizers
+			for _, quota := range []*v1alpha1.ElasticQuota{parent, sub1, sub2} {
+				assert.NoError(t, qt.fillQuotaDefaultInformation(quota))
+				assert.NoError(t, qt.ValidAddQuota(quota))
+			}
+
+			err := qt.ValidDeleteQuota(parent)
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			// the parent is kept until its children are moved by the controller
+			assert.Contains(t, qt.quotaInfoMap, "temp")
+			assert.Equal(t, 2, len(qt.quotaHierarchyInfo["temp"]))
+			// the children aren't touched by the webhook
+			for _, name := range []string{"sub-1", "sub-2"} {
+				assert.Equal(t, "temp", qt.quotaInfoMap[name].ParentName)
+			}
+
+			// the parent with pods can't be deleted anyway
+			pod := MakePod("temp", "pod1").Label(extension.LabelQuotaName, "temp").Obj()
+			assert.NoError(t, qt.client.Create(context.TODO(), pod))
+			assert.Error(t, qt.ValidDeleteQuota(parent))
+		})
+	}
+}
+
 func TestQuotaTopology_ValidDeleteQuota(t *testing.T) {
 	qt := newFakeQuotaTopology()
 
@@ -1081,3 +1435,171 @@ func TestQuotaTopology_checkGuaranteeForMin(t *testing.T) {
 		})
 	}
 }
+
+func TestQuotaTopology_getQuotaSubtreeTopologyInfo(t *testing.T) {
+	qt := newFakeQuotaTopology()
+	quotas := []*v1alpha1.ElasticQuota{
+		MakeQuota("a").ParentName(extension.RootQuotaName).IsParent(true).Max(MakeResourceList().CPU(120).Mem(1048576).Obj()).Obj(),
+		MakeQuota("a-1").ParentName("a").IsParent(true).Max(MakeResourceList().CPU(120).Mem(1048576).Obj()).Obj(),
+		MakeQuota("a-1-1").ParentName("a-1").Max(MakeResourceList().CPU(120).Mem(1048576).Obj()).Obj(),
+		MakeQuota("a-2").ParentName("a").Max(MakeResourceList().CPU(120).Mem(1048576).Obj()).Obj(),
+		MakeQuota("b").ParentName(extension.RootQuotaName).IsParent(true).Max(MakeResourceList().CPU(120).Mem(1048576).Obj()).Obj(),
+		MakeQuota("b-1").ParentName("b").Max(MakeResourceList().CPU(120).Mem(1048576).Obj()).Obj(),
+	}
+	for _, quota := range quotas {
+		qt.OnQuotaAdd(quota)
+	}
+
+	summary := qt.getQuotaSubtreeTopologyInfo("a")
+	assert.NotNil(t, summary)
+	quotaNames := make([]string, 0, len(summary.QuotaInfoMap))
+	for name := range summary.QuotaInfoMap {
+		quotaNames = append(quotaNames, name)
+	}
+	sort.Strings(quotaNames)
+	assert.Equal(t, []string{"a", "a-1", "a-1-1", "a-2"}, quotaNames)
+	assert.Equal(t, 4, len(summary.QuotaHierarchyInfo))
+	children := summary.QuotaHierarchyInfo["a"]
+	sort.Strings(children)
+	assert.Equal(t, []string{"a-1", "a-2"}, children)
+	assert.Equal(t, []string{"a-1-1"}, summary.QuotaHierarchyInfo["a-1"])
+	assert.Equal(t, []string{}, summary.QuotaHierarchyInfo["a-2"])
+	assert.Nil(t, summary.QuotaHierarchyInfo["b"])
+
+	// leaf quota
+	summary = qt.getQuotaSubtreeTopologyInfo("b-1")
+	assert.NotNil(t, summary)
+	assert.Equal(t, 1, len(summary.QuotaInfoMap))
+	assert.NotNil(t, summary.QuotaInfoMap["b-1"])
+
+	// the root includes the whole tree
+	summary = qt.getQuotaSubtreeTopologyInfo(extension.RootQuotaName)
+	assert.Equal(t, len(quotas), len(summary.QuotaInfoMap))
+
+	// not exist
+	assert.Nil(t, qt.getQuotaSubtreeTopologyInfo("c"))
+}
+
+func TestQuotaTopology_checkMinQuotaWithTreeTotalResource(t *testing.T) {
+	treeRoot := MakeQuota("tree-root").Max(MakeResourceList().CPU(100).Mem(1000).Obj()).
+		Min(MakeResourceList().CPU(40).Mem(100).Obj()).IsParent(true).IsRoot(true).TreeID("tree-a").
+		Annotations(map[string]string{extension.AnnotationTotalResource: `{"cpu":"50","memory":"1000"}`}).Obj()
+	child := MakeQuota("child").ParentName("tree-root").Max(MakeResourceList().CPU(100).Mem(1000).Obj()).
+		Min(MakeResourceList().CPU(40).Mem(100).Obj()).IsParent(false).TreeID("tree-a").Obj()
+
+	tests := []struct {
+		name     string
+		oldQuota *v1alpha1.ElasticQuota
+		quota    *v1alpha1.ElasticQuota
+		wantErr  string
+	}{
+		{
+			name:     "tree root min exceeds the total resource of the tree",
+			oldQuota: treeRoot,
+			quota: MakeQuota("tree-root").Max(MakeResourceList().CPU(100).Mem(1000).Obj()).
+				Min(MakeResourceList().CPU(60).Mem(100).Obj()).IsParent(true).IsRoot(true).TreeID("tree-a").
+				Annotations(map[string]string{extension.AnnotationTotalResource: `{"cpu":"50","memory":"1000"}`}).Obj(),
+			wantErr: "TotalResource of tree tree-a on cpu",
+		},
+		{
+			name:     "total resource of the tree shrinks below the tree root min",
+			oldQuota: treeRoot,
+			quota: MakeQuota("tree-root").Max(MakeResourceList().CPU(100).Mem(1000).Obj()).
+				Min(MakeResourceList().CPU(40).Mem(100).Obj()).IsParent(true).IsRoot(true).TreeID("tree-a").
+				Annotations(map[string]string{extension.AnnotationTotalResource: `{"cpu":"30","memory":"1000"}`}).Obj(),
+			wantErr: "TotalResource of tree tree-a on cpu",
+		},
+		{
+			name:     "tree root min within the total resource of the tree",
+			oldQuota: treeRoot,
+			quota: MakeQuota("tree-root").Max(MakeResourceList().CPU(100).Mem(1000).Obj()).
+				Min(MakeResourceList().CPU(50).Mem(100).Obj()).IsParent(true).IsRoot(true).TreeID("tree-a").
+				Annotations(map[string]string{extension.AnnotationTotalResource: `{"cpu":"50","memory":"1000"}`}).Obj(),
+		},
+		{
+			name:     "the quota under other parents is not capped",
+			oldQuota: child,
+			quota: MakeQuota("child").ParentName("tree-root").Max(MakeResourceList().CPU(100).Mem(1000).Obj()).
+				Min(MakeResourceList().CPU(80).Mem(100).Obj()).IsParent(false).TreeID("tree-a").Obj(),
+		},
+		{
+			name: "the quota under the root without tree is not capped",
+			quota: MakeQuota("no-tree").Max(MakeResourceList().CPU(1000).Mem(1000).Obj()).
+				Min(MakeResourceList().CPU(1000).Mem(100).Obj()).IsParent(false).Obj(),
+		},
+		{
+			name:     "force update",
+			oldQuota: treeRoot,
+			quota: func() *v1alpha1.ElasticQuota {
+				quota := MakeQuota("tree-root").Max(MakeResourceList().CPU(100).Mem(1000).Obj()).
+					Min(MakeResourceList().CPU(60).Mem(100).Obj()).IsParent(true).IsRoot(true).TreeID("tree-a").
+					Annotations(map[string]string{extension.AnnotationTotalResource: `{"cpu":"50","memory":"1000"}`}).Obj()
+				quota.Labels[extension.LabelAllowForceUpdate] = "true"
+				return quota
+			}(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qt := newFakeQuotaTopology()
+			qt.OnQuotaAdd(treeRoot)
+			qt.OnQuotaAdd(child)
+			var oldQuotaInfo *QuotaInfo
+			if tt.oldQuota != nil {
+				oldQuotaInfo = NewQuotaInfoFromQuota(tt.oldQuota)
+			}
+			err := qt.checkMinQuotaWithTreeTotalResource(oldQuotaInfo, NewQuotaInfoFromQuota(tt.quota))
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestQuotaTopology_ValidDeleteQuota_Cascading(t *testing.T) {
+	qt := newFakeQuotaTopology()
+	client := fake.NewClientBuilder().WithIndex(&v1.Pod{}, "label.quotaName", func(object client.Object) []string {
+		return []string{object.(*v1.Pod).Labels[extension.LabelQuotaName]}
+	}).Build()
+	v1alpha1.AddToScheme(client.Scheme())
+	qt.client = client
+
+	parent := MakeQuota("parent").Max(MakeResourceList().CPU(120).Mem(1048576).Obj()).
+		Min(MakeResourceList().CPU(64).Mem(51200).Obj()).IsParent(true).Obj()
+	child1 := MakeQuota("child-1").ParentName("parent").Max(MakeResourceList().CPU(120).Mem(1048576).Obj()).
+		Min(MakeResourceList().CPU(30).Mem(12800).Obj()).IsParent(true).Obj()
+	child2 := MakeQuota("child-2").ParentName("parent").Max(MakeResourceList().CPU(120).Mem(1048576).Obj()).
+		Min(MakeResourceList().CPU(10).Mem(12800).Obj()).IsParent(false).Obj()
+	grandchild := MakeQuota("grandchild").ParentName("child-1").Max(MakeResourceList().CPU(120).Mem(1048576).Obj()).
+		Min(MakeResourceList().CPU(10).Mem(12800).Obj()).IsParent(false).
+		Annotations(map[string]string{extension.AnnotationQuotaNamespaces: "[\"ns-grandchild\"]"}).Obj()
+	for _, quota := range []*v1alpha1.ElasticQuota{parent, child1, child2, grandchild} {
+		assert.NoError(t, qt.fillQuotaDefaultInformation(quota))
+		assert.NoError(t, qt.ValidAddQuota(quota))
+	}
+	assert.Equal(t, []string{"child-1", "child-2", "grandchild"}, qt.getDescendantQuotaNames("parent"))
+
+	// rejected without the cascading annotation
+	err := qt.ValidDeleteQuota(parent)
+	assert.ErrorContains(t, err, "has child quota")
+
+	// rejected if any descendant has pods
+	cascadingParent := parent.DeepCopy()
+	cascadingParent.Annotations[extension.AnnotationAllowCascadingDelete] = "true"
+	pod := MakePod("ns-grandchild", "pod1").Label(extension.LabelQuotaName, "grandchild").Obj()
+	assert.NoError(t, qt.client.Create(context.TODO(), pod))
+	err = qt.ValidDeleteQuota(cascadingParent)
+	assert.ErrorContains(t, err, "quota grandchild has child pods")
+	assert.Equal(t, 4, len(qt.quotaInfoMap))
+	assert.Equal(t, "grandchild", qt.namespaceToQuotaMap["ns-grandchild"])
+
+	// the empty subtree is deleted together
+	assert.NoError(t, qt.client.Delete(context.TODO(), pod))
+	assert.NoError(t, qt.ValidDeleteQuota(cascadingParent))
+	assert.Equal(t, 0, len(qt.quotaInfoMap))
+	assert.Equal(t, 1, len(qt.quotaHierarchyInfo))
+	assert.Equal(t, 0, len(qt.quotaHierarchyInfo[extension.RootQuotaName]))
+	assert.Equal(t, 0, len(qt.namespaceToQuotaMap))
+}
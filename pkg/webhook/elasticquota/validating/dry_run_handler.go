@@ -0,0 +1,69 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/koordinator-sh/koordinator/apis/thirdparty/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+	"github.com/koordinator-sh/koordinator/pkg/webhook/elasticquota"
+)
+
+// QuotaDryRunResult is the verdict of a proposed quota, the message is the one the admission webhook rejects with.
+type QuotaDryRunResult struct {
+	Allowed bool   `json:"allowed"`
+	Message string `json:"message,omitempty"`
+}
+
+// ElasticQuotaDryRunHandler validates a proposed ElasticQuota against the current quota topology without
+// applying it, so the quota changes can be pre-checked before they're submitted.
+type ElasticQuotaDryRunHandler struct {
+	Client client.Client
+
+	// Decoder decodes objects
+	Decoder *admission.Decoder
+}
+
+var _ http.Handler = &ElasticQuotaDryRunHandler{}
+
+func (h *ElasticQuotaDryRunHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	quota := &v1alpha1.ElasticQuota{}
+	if err := json.NewDecoder(r.Body).Decode(quota); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(fmt.Sprintf("invalid quota, err: %v", err)))
+		return
+	}
+
+	plugin := elasticquota.NewPlugin(h.Decoder, h.Client)
+	result := &QuotaDryRunResult{Allowed: true}
+	if err := plugin.DryRunValidateQuota(quota); err != nil {
+		result.Allowed = false
+		result.Message = err.Error()
+	}
+	resultJson, _ := json.Marshal(result)
+	w.WriteHeader(http.StatusOK)
+	w.Write(resultJson)
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2022 The Koordinator Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validating
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/koordinator-sh/koordinator/apis/extension"
+	"github.com/koordinator-sh/koordinator/apis/thirdparty/scheduler-plugins/pkg/apis/scheduling/v1alpha1"
+)
+
+func TestElasticQuotaDryRunHandler_ServeHTTP(t *testing.T) {
+	validatingHandler := makeTestHandler()
+	handler := &ElasticQuotaDryRunHandler{
+		Client:  validatingHandler.Client,
+		Decoder: validatingHandler.Decoder,
+	}
+	dryRun := func(method string, body []byte) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, "/elasticQuota/dryRun", bytes.NewReader(body))
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := dryRun(http.MethodGet, nil)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+	w = dryRun(http.MethodPost, []byte("not-a-quota"))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	quota := &v1alpha1.ElasticQuota{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "dry-run-quota",
+			Labels: map[string]string{extension.LabelQuotaParent: extension.RootQuotaName},
+		},
+		Spec: v1alpha1.ElasticQuotaSpec{
+			Max: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("-1")},
+		},
+	}
+	body, err := json.Marshal(quota)
+	assert.NoError(t, err)
+	w = dryRun(http.MethodPost, body)
+	assert.Equal(t, http.StatusOK, w.Code)
+	result := &QuotaDryRunResult{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), result))
+	assert.False(t, result.Allowed)
+	assert.Contains(t, result.Message, "quota.Spec.Max's value < 0")
+}
@@ -131,6 +131,17 @@ var _ http.Handler = &ElasticQuotaValidatingHandler{}
 
 func (h *ElasticQuotaValidatingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	plugin := elasticquota.NewPlugin(h.Decoder, h.Client)
+	if rootName := r.URL.Query().Get("quota"); rootName != "" {
+		subtreeSummary := plugin.GetQuotaSubtreeTopologyInfo(rootName)
+		if subtreeSummary == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		subtreeSummaryJson, _ := json.Marshal(subtreeSummary)
+		w.WriteHeader(200)
+		w.Write(subtreeSummaryJson)
+		return
+	}
 	allQuotaTopologySummary := plugin.GetQuotaTopologyInfo()
 	allQuotaTopologySummaryJson, _ := json.Marshal(allQuotaTopologySummary)
 